@@ -15,30 +15,82 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/prometheus-operator/poctl/internal/analyzers"
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
 	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/output"
+	"github.com/prometheus-operator/poctl/pkg/builder"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type AnalyzeKind string
 
 const (
-	ServiceMonitor  AnalyzeKind = "servicemonitor"
-	Operator        AnalyzeKind = "operator"
-	Prometheus      AnalyzeKind = "prometheus"
-	Alertmanager    AnalyzeKind = "alertmanager"
-	PrometheusAgent AnalyzeKind = "prometheusagent"
+	ServiceMonitor     AnalyzeKind = "servicemonitor"
+	Operator           AnalyzeKind = "operator"
+	Prometheus         AnalyzeKind = "prometheus"
+	Alertmanager       AnalyzeKind = "alertmanager"
+	PrometheusAgent    AnalyzeKind = "prometheusagent"
+	ScrapeConfig       AnalyzeKind = "scrapeconfig"
+	Overlapping        AnalyzeKind = "overlapping"
+	Workload           AnalyzeKind = "workload"
+	Thanos             AnalyzeKind = "thanos"
+	PrometheusRule     AnalyzeKind = "prometheusrule"
+	PodMonitor         AnalyzeKind = "podmonitor"
+	Probe              AnalyzeKind = "probe"
+	AlertmanagerConfig AnalyzeKind = "alertmanagerconfig"
+	ThanosRuler        AnalyzeKind = "thanosruler"
 )
 
+// analyzerFunc runs the analyzer of a kind on an object.
+type analyzerFunc func(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]analyzers.Finding, error)
+
+// kindAnalyzers are the analyzers of the kinds supported by the analyze
+// command.
+var kindAnalyzers = map[AnalyzeKind]analyzerFunc{
+	ServiceMonitor:  analyzers.RunServiceMonitorAnalyzer,
+	Operator:        analyzers.RunOperatorAnalyzer,
+	Prometheus:      analyzers.RunPrometheusAnalyzer,
+	Alertmanager:    analyzers.RunAlertmanagerAnalyzer,
+	PrometheusAgent: analyzers.RunPrometheusAgentAnalyzer,
+	ScrapeConfig:    analyzers.RunScrapeConfigAnalyzer,
+	Overlapping: func(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]analyzers.Finding, error) {
+		return analyzers.RunOverlappingAnalyzer(ctx, clientSets, name, namespace, analyzerFlags.ClusterDomain)
+	},
+	Workload:       analyzers.RunWorkloadAnalyzer,
+	Thanos:         analyzers.RunThanosAnalyzer,
+	PrometheusRule: analyzers.RunPrometheusRuleAnalyzer,
+	PodMonitor:     analyzers.RunPodMonitorAnalyzer,
+	Probe: func(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]analyzers.Finding, error) {
+		return analyzers.RunProbeAnalyzer(ctx, clientSets, name, namespace, analyzerFlags.ClusterDomain)
+	},
+	AlertmanagerConfig: analyzers.RunAlertmanagerConfigAnalyzer,
+	ThanosRuler:        analyzers.RunThanosRulerAnalyzer,
+}
+
 type AnalyzeFlags struct {
-	Kind      string
-	Name      string
-	Namespace string
+	Kind          string
+	Name          string
+	Namespace     string
+	Selector      string
+	Timeout       time.Duration
+	ClusterDomain string
+	Filename      string
+	Output        string
+	Snapshot      bool
+	All           bool
+	AllNamespaces bool
 }
 
 var (
@@ -47,49 +99,340 @@ var (
 		Use:   "analyze",
 		Short: "The analyze command performs an in-depth analysis of Prometheus Operator resources, identifying potential issues and misconfigurations in your monitoring setup. It helps ensure your resources are optimized and error-free.",
 		Long:  `The analyze command in poctl is a powerful tool that assesses the health of Prometheus Operator resources in Kubernetes. It detects misconfigurations, issues, and inefficiencies in Prometheus, Alertmanager, and ServiceMonitor resources. By offering actionable insights and recommendations, it helps administrators quickly resolve problems and optimize their monitoring setup for better performance.`,
-		RunE:  run,
+		Example: `  # Analyze a Prometheus
+  poctl analyze -k prometheus -n k8s -s monitoring
+
+  # Analyze the ServiceMonitors of a team
+  poctl analyze -k servicemonitor -s payments --selector team=payments
+
+  # Analyze all the monitoring objects of a namespace
+  poctl analyze --all -s monitoring
+
+  # Analyze the objects listed in a file and write a JUnit report
+  poctl analyze -f post-upgrade.yaml -s default -o junit > analyze.xml
+
+  # Analyze all the monitoring objects of the cluster and write the findings as JSON
+  poctl analyze --all -A -o json > analyze.json`,
+		RunE: run,
 	}
 )
 
 func run(cmd *cobra.Command, _ []string) error {
+	format := strings.ToLower(analyzerFlags.Output)
+	if !slices.Contains(analyzeOutputs, format) {
+		return fmt.Errorf("unknown output %s, must be one of: %s", analyzerFlags.Output, strings.Join(analyzeOutputs, ", "))
+	}
+	// The table is the text output.
+	if format == outputTable {
+		format = outputText
+	}
+
+	var targets []analyzeTarget
+	if analyzerFlags.All {
+		if err := validateAnalyzeAllFlags(); err != nil {
+			return err
+		}
+	} else if analyzerFlags.AllNamespaces {
+		return fmt.Errorf("all-namespaces is only supported with all")
+	} else if analyzerFlags.Filename != "" {
+		if analyzerFlags.Kind != "" || analyzerFlags.Name != "" || analyzerFlags.Selector != "" {
+			return fmt.Errorf("filename is mutually exclusive with kind, name and selector")
+		}
+
+		var err error
+		targets, err = loadAnalyzeTargets(analyzerFlags.Filename, analyzerFlags.Namespace)
+		if err != nil {
+			if format == outputGitHub {
+				return targetsFileAnnotation(cmd.OutOrStdout(), err)
+			}
+			return err
+		}
+	} else if err := validateAnalyzeFlags(); err != nil {
+		return err
+	}
+
+	domain, err := parseClusterDomain()
+	if err != nil {
+		return err
+	}
+	// The analyzers compare the names of the Services in the default domain
+	// when the flag isn't set.
+	analyzerFlags.ClusterDomain = cmp.Or(domain, builder.DefaultClusterDomain)
+
+	if format != outputText && targets == nil && !analyzerFlags.All {
+		targets = []analyzeTarget{{Kind: analyzerFlags.Kind, Name: analyzerFlags.Name, Namespace: analyzerFlags.Namespace, Selector: analyzerFlags.Selector}}
+	}
+
+	switch format {
+	case outputJUnit:
+		return runJUnit(cmd, targets)
+	case outputGitHub:
+		return runGitHub(cmd, targets, analyzerFlags.Filename)
+	case outputJSON, outputYAML:
+		return runOutput(cmd, targets, output.Format(format))
+	}
+
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(_ *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), analyzerFlags.Timeout)
+		defer cancel()
+
+		clientSets, err := snapshotClientSets(ctx, clientSets)
+		if err != nil {
+			return err
+		}
+
+		targets, err := contextTargets(ctx, clientSets, targets)
+		if err != nil {
+			return err
+		}
+
+		if targets != nil {
+			return analyzeTargets(ctx, cmd.OutOrStdout(), clientSets, targets)
+		}
+
+		if analyzerFlags.Selector != "" {
+			return analyzeSelected(ctx, cmd.OutOrStdout(), clientSets)
+		}
+		return analyze(ctx, clientSets, analyzerFlags.Kind, analyzerFlags.Name, analyzerFlags.Namespace)
+	})
+}
+
+// snapshotClientSets returns the clientsets serving the reads of the
+// analysis from a snapshot of the cluster with --snapshot, the given ones
+// otherwise.
+func snapshotClientSets(ctx context.Context, clientSets *k8sutil.ClientSets) (*k8sutil.ClientSets, error) {
+	if !analyzerFlags.Snapshot {
+		return clientSets, nil
+	}
+
+	snapshot, err := k8sutil.NewSnapshot(ctx, clientSets)
+	if err != nil {
+		return nil, fmt.Errorf("error while taking snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// validateAnalyzeFlags checks the flags selecting the objects to analyze
+// when they aren't read from a file.
+func validateAnalyzeFlags() error {
 	if analyzerFlags.Kind == "" {
 		return fmt.Errorf("kind is required")
 	}
 
-	if analyzerFlags.Name == "" {
-		return fmt.Errorf("name is required")
+	if analyzerFlags.Name == "" && analyzerFlags.Selector == "" {
+		return fmt.Errorf("name or selector is required")
+	}
+
+	if analyzerFlags.Name != "" && analyzerFlags.Selector != "" {
+		return fmt.Errorf("name and selector are mutually exclusive")
 	}
 
 	if analyzerFlags.Namespace == "" {
 		return fmt.Errorf("namespace is required")
 	}
 
-	logger, err := log.NewLogger()
+	return nil
+}
+
+// analyze runs the analyzer of the kind on an object, logs its warnings and
+// informational findings and returns its error findings as an error.
+func analyze(ctx context.Context, clientSets *k8sutil.ClientSets, kind, name, namespace string) error {
+	_, err := analyzeObject(ctx, clientSets, kind, name, namespace)
+	return err
+}
+
+// analyzeObject is analyze returning the findings as well.
+func analyzeObject(ctx context.Context, clientSets *k8sutil.ClientSets, kind, name, namespace string) ([]analyzers.Finding, error) {
+	findings, err := runAnalyzer(ctx, clientSets, kind, name, namespace)
+	for _, f := range findings {
+		switch f.Severity {
+		case analyzers.SeverityWarning:
+			slog.Warn(f.Message, "id", f.Check, "name", f.Name, "namespace", f.Namespace, "hint", f.Fix)
+		case analyzers.SeverityInfo:
+			slog.Info(f.Message, "id", f.Check, "name", f.Name, "namespace", f.Namespace)
+		}
+	}
+
 	if err != nil {
-		return fmt.Errorf("error while creating logger: %v", err)
+		return findings, err
 	}
+	return findings, analyzers.Err(findings)
+}
 
-	slog.SetDefault(logger)
+func runAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, kind, name, namespace string) ([]analyzers.Finding, error) {
+	run, ok := kindAnalyzers[AnalyzeKind(strings.ToLower(kind))]
+	if !ok {
+		return nil, fmt.Errorf("kind %s not supported", kind)
+	}
+	return run(ctx, clientSets, name, namespace)
+}
 
-	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+// analyzeSelected analyzes every object of the kind matching --selector in
+// the namespace and prints the findings of each object. The analysis of an
+// object doesn't stop at the failure of another one, while an interruption
+// leaves the remaining objects not run.
+func analyzeSelected(ctx context.Context, out io.Writer, clientSets *k8sutil.ClientSets) error {
+	names, err := selectedObjects(ctx, clientSets, analyzerFlags.Kind, analyzerFlags.Namespace, analyzerFlags.Selector)
 	if err != nil {
-		return fmt.Errorf("error while getting clientsets: %v", err)
+		return err
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("no %s matches the selector %s in namespace %s", analyzerFlags.Kind, analyzerFlags.Selector, analyzerFlags.Namespace)
+	}
+
+	targets := make([]analyzeTarget, 0, len(names))
+	for _, name := range names {
+		targets = append(targets, analyzeTarget{Kind: analyzerFlags.Kind, Name: name, Namespace: analyzerFlags.Namespace})
 	}
+	results := analyzeObjects(ctx, clientSets, targets, nil)
 
-	switch AnalyzeKind(strings.ToLower(analyzerFlags.Kind)) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tRESULT\tID\tFINDING")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.name, r.status, r.id(), r.finding())
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return resultsErr(ctx, results)
+}
+
+// selectedObjects returns the names of the objects of the kind matching the
+// selector in the namespace.
+func selectedObjects(ctx context.Context, clientSets *k8sutil.ClientSets, kind, namespace, selector string) ([]string, error) {
+	opts := metav1.ListOptions{LabelSelector: selector}
+
+	var names []string
+	switch AnalyzeKind(strings.ToLower(kind)) {
 	case ServiceMonitor:
-		return analyzers.RunServiceMonitorAnalyzer(cmd.Context(), clientSets, analyzerFlags.Name, analyzerFlags.Namespace)
+		list, err := clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing ServiceMonitor objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
 	case Operator:
-		return analyzers.RunOperatorAnalyzer(cmd.Context(), clientSets, analyzerFlags.Name, analyzerFlags.Namespace)
-	case Prometheus:
-		return analyzers.RunPrometheusAnalyzer(cmd.Context(), clientSets, analyzerFlags.Name, analyzerFlags.Namespace)
+		list, err := clientSets.KClient.AppsV1().Deployments(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing Deployments: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case Prometheus, Overlapping, Thanos:
+		list, err := clientSets.MClient.MonitoringV1().Prometheuses(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing Prometheus objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
 	case Alertmanager:
-		return analyzers.RunAlertmanagerAnalyzer(cmd.Context(), clientSets, analyzerFlags.Name, analyzerFlags.Namespace)
+		list, err := clientSets.MClient.MonitoringV1().Alertmanagers(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing Alertmanager objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
 	case PrometheusAgent:
-		return analyzers.RunPrometheusAgentAnalyzer(cmd.Context(), clientSets, analyzerFlags.Name, analyzerFlags.Namespace)
+		list, err := clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing PrometheusAgent objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case ScrapeConfig:
+		list, err := clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing ScrapeConfig objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case PrometheusRule:
+		list, err := clientSets.MClient.MonitoringV1().PrometheusRules(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing PrometheusRule objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case PodMonitor:
+		list, err := clientSets.MClient.MonitoringV1().PodMonitors(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing PodMonitor objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case Probe:
+		list, err := clientSets.MClient.MonitoringV1().Probes(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing Probe objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case AlertmanagerConfig:
+		list, err := clientSets.MClient.MonitoringV1alpha1().AlertmanagerConfigs(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing AlertmanagerConfig objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case ThanosRuler:
+		list, err := clientSets.MClient.MonitoringV1().ThanosRulers(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing ThanosRuler objects: %w", err)
+		}
+		for _, o := range list.Items {
+			names = append(names, o.Name)
+		}
+	case Workload:
+		deployments, err := clientSets.KClient.AppsV1().Deployments(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing Deployments: %w", err)
+		}
+		for _, o := range deployments.Items {
+			names = append(names, o.Name)
+		}
+		statefulSets, err := clientSets.KClient.AppsV1().StatefulSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing StatefulSets: %w", err)
+		}
+		for _, o := range statefulSets.Items {
+			names = append(names, o.Name)
+		}
+		daemonSets, err := clientSets.KClient.AppsV1().DaemonSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing DaemonSets: %w", err)
+		}
+		for _, o := range daemonSets.Items {
+			names = append(names, o.Name)
+		}
+		// The analyzer looks up the workloads by name, a name shared by
+		// several kinds is analyzed once.
+		slices.Sort(names)
+		names = slices.Compact(names)
 	default:
-		return fmt.Errorf("kind %s not supported", analyzerFlags.Kind)
+		return nil, fmt.Errorf("kind %s not supported", kind)
 	}
+
+	return names, nil
 }
 
 func init() {
@@ -97,4 +440,13 @@ func init() {
 	analyzeCmd.PersistentFlags().StringVarP(&analyzerFlags.Kind, "kind", "k", "", "The kind of object to analyze. For example, ServiceMonitor")
 	analyzeCmd.PersistentFlags().StringVarP(&analyzerFlags.Name, "name", "n", "", "The name of the object to analyze")
 	analyzeCmd.PersistentFlags().StringVarP(&analyzerFlags.Namespace, "namespace", "s", "", "The namespace of the object to analyze")
+	analyzeCmd.PersistentFlags().DurationVar(&analyzerFlags.Timeout, "timeout", time.Minute, "Maximum duration of the analysis of each cluster")
+	analyzeCmd.PersistentFlags().BoolVar(&analyzerFlags.Snapshot, "snapshot", false, "List the monitoring objects, Services and RBAC objects of the cluster once and analyze them from memory, to reduce the requests when analyzing many objects. The Secrets aren't part of the snapshot and are read from the cluster")
+	analyzeCmd.PersistentFlags().StringVarP(&analyzerFlags.Selector, "selector", "l", "", "Label selector of the objects to analyze instead of --name. For example, team=payments")
+	analyzeCmd.PersistentFlags().StringVarP(&analyzerFlags.Output, "output", "o", outputText, fmt.Sprintf("Output format of the results, one of: %s. %s is an alias of %s. With the other formats, the report is written to the standard output and the logs to the standard error", strings.Join(analyzeOutputs, ", "), outputTable, outputText))
+	analyzeCmd.PersistentFlags().BoolVar(&analyzerFlags.All, "all", false, "Analyze all the monitoring objects of the namespace matching --selector, if any, instead of --kind and --name")
+	analyzeCmd.PersistentFlags().BoolVarP(&analyzerFlags.AllNamespaces, "all-namespaces", "A", false, "With --all, analyze the objects of all the namespaces instead of --namespace")
+	analyzeCmd.PersistentFlags().StringVarP(&analyzerFlags.Filename, "filename", "f", "", "File listing the objects to analyze instead of --kind, --name and --selector, --namespace being the default namespace of the listed objects")
+	registerContextsFlag(analyzeCmd)
+	registerClusterDomainFlag(analyzeCmd)
 }
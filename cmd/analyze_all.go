@@ -0,0 +1,153 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// validateAnalyzeAllFlags checks the flags of the analysis of all the
+// objects of a namespace or of the cluster.
+func validateAnalyzeAllFlags() error {
+	if analyzerFlags.Kind != "" || analyzerFlags.Name != "" || analyzerFlags.Filename != "" {
+		return fmt.Errorf("all is mutually exclusive with kind, name and filename")
+	}
+
+	if analyzerFlags.AllNamespaces && analyzerFlags.Namespace != "" {
+		return fmt.Errorf("namespace and all-namespaces are mutually exclusive")
+	}
+
+	if !analyzerFlags.AllNamespaces && analyzerFlags.Namespace == "" {
+		return fmt.Errorf("namespace or all-namespaces is required")
+	}
+
+	return nil
+}
+
+// contextTargets returns the targets to analyze in a cluster: the objects
+// discovered in the cluster with --all, the given targets otherwise.
+func contextTargets(ctx context.Context, clientSets *k8sutil.ClientSets, targets []analyzeTarget) ([]analyzeTarget, error) {
+	if !analyzerFlags.All {
+		return targets, nil
+	}
+
+	namespace := analyzerFlags.Namespace
+	if analyzerFlags.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	return discoverTargets(ctx, clientSets, namespace, analyzerFlags.Selector)
+}
+
+// discoverTargets returns a target for each analyzer applying to the
+// monitoring objects matching the selector in the namespace, or in all the
+// namespaces when it's empty. The Prometheus resources are also checked for
+// overlapping targets, and for their Thanos sidecar when they run one. The
+// resources whose CRD isn't installed are skipped.
+func discoverTargets(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, selector string) ([]analyzeTarget, error) {
+	opts := metav1.ListOptions{LabelSelector: selector}
+	mClient := clientSets.MClient
+
+	var targets []analyzeTarget
+	if err := discover(ctx, &targets, "Prometheuses", mClient.MonitoringV1().Prometheuses(namespace).List, opts, func(p *monitoringv1.Prometheus) []AnalyzeKind {
+		if p.Spec.Thanos != nil {
+			return []AnalyzeKind{Prometheus, Overlapping, Thanos}
+		}
+		return []AnalyzeKind{Prometheus, Overlapping}
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "PrometheusAgents", mClient.MonitoringV1alpha1().PrometheusAgents(namespace).List, opts, kinds[monitoringv1alpha1.PrometheusAgent](PrometheusAgent)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "Alertmanagers", mClient.MonitoringV1().Alertmanagers(namespace).List, opts, kinds[monitoringv1.Alertmanager](Alertmanager)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "ThanosRulers", mClient.MonitoringV1().ThanosRulers(namespace).List, opts, kinds[monitoringv1.ThanosRuler](ThanosRuler)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "ServiceMonitors", mClient.MonitoringV1().ServiceMonitors(namespace).List, opts, kinds[monitoringv1.ServiceMonitor](ServiceMonitor)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "PodMonitors", mClient.MonitoringV1().PodMonitors(namespace).List, opts, kinds[monitoringv1.PodMonitor](PodMonitor)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "Probes", mClient.MonitoringV1().Probes(namespace).List, opts, kinds[monitoringv1.Probe](Probe)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "ScrapeConfigs", mClient.MonitoringV1alpha1().ScrapeConfigs(namespace).List, opts, kinds[monitoringv1alpha1.ScrapeConfig](ScrapeConfig)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "PrometheusRules", mClient.MonitoringV1().PrometheusRules(namespace).List, opts, kinds[monitoringv1.PrometheusRule](PrometheusRule)); err != nil {
+		return nil, err
+	}
+
+	if err := discover(ctx, &targets, "AlertmanagerConfigs", mClient.MonitoringV1alpha1().AlertmanagerConfigs(namespace).List, opts, kinds[monitoringv1alpha1.AlertmanagerConfig](AlertmanagerConfig)); err != nil {
+		return nil, err
+	}
+
+	if len(targets) == 0 {
+		if namespace == metav1.NamespaceAll {
+			return nil, fmt.Errorf("no monitoring object to analyze in the cluster")
+		}
+		return nil, fmt.Errorf("no monitoring object to analyze in namespace %s", namespace)
+	}
+
+	return targets, nil
+}
+
+// discover appends to the targets the objects listed with the list
+// function, once for each of the kinds of analysis returned by fn. A
+// resource not served by the API server lists no objects.
+func discover[T any, L runtime.Object](ctx context.Context, targets *[]analyzeTarget, resource string, list func(context.Context, metav1.ListOptions) (L, error), opts metav1.ListOptions, fn func(*T) []AnalyzeKind) error {
+	err := k8sutil.EachListItem(ctx, resource, list, opts, func(obj *T) error {
+		meta, ok := any(obj).(metav1.Object)
+		if !ok {
+			return fmt.Errorf("unexpected %T item while listing %s", obj, resource)
+		}
+
+		for _, kind := range fn(obj) {
+			*targets = append(*targets, analyzeTarget{Kind: string(kind), Name: meta.GetName(), Namespace: meta.GetNamespace()})
+		}
+		return nil
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// kinds returns a function analyzing every object with the given kinds.
+func kinds[T any](k ...AnalyzeKind) func(*T) []AnalyzeKind {
+	return func(*T) []AnalyzeKind {
+		return k
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/junit"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withAnalyzeFlags sets the flags of the analyze command for the test.
+func withAnalyzeFlags(t *testing.T, flags AnalyzeFlags) {
+	t.Helper()
+
+	previous := analyzerFlags
+	analyzerFlags = flags
+	t.Cleanup(func() { analyzerFlags = previous })
+}
+
+func TestValidateAnalyzeAllFlags(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		flags    AnalyzeFlags
+		expected string
+	}{
+		{
+			name:  "Namespace",
+			flags: AnalyzeFlags{All: true, Namespace: "default"},
+		},
+		{
+			name:  "AllNamespaces",
+			flags: AnalyzeFlags{All: true, AllNamespaces: true},
+		},
+		{
+			name:  "Selector",
+			flags: AnalyzeFlags{All: true, Namespace: "default", Selector: "team=payments"},
+		},
+		{
+			name:     "Kind",
+			flags:    AnalyzeFlags{All: true, Namespace: "default", Kind: "servicemonitor"},
+			expected: "all is mutually exclusive with kind, name and filename",
+		},
+		{
+			name:     "Filename",
+			flags:    AnalyzeFlags{All: true, Namespace: "default", Filename: "targets.yaml"},
+			expected: "all is mutually exclusive with kind, name and filename",
+		},
+		{
+			name:     "NamespaceAndAllNamespaces",
+			flags:    AnalyzeFlags{All: true, Namespace: "default", AllNamespaces: true},
+			expected: "namespace and all-namespaces are mutually exclusive",
+		},
+		{
+			name:     "NoNamespace",
+			flags:    AnalyzeFlags{All: true},
+			expected: "namespace or all-namespaces is required",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			withAnalyzeFlags(t, tc.flags)
+
+			err := validateAnalyzeAllFlags()
+			if tc.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestDiscoverTargets(t *testing.T) {
+	objects := k8stesting.WithObjects(
+		&monitoringv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"}},
+		&monitoringv1.Prometheus{
+			ObjectMeta: metav1.ObjectMeta{Name: "thanos", Namespace: "monitoring"},
+			Spec:       monitoringv1.PrometheusSpec{Thanos: &monitoringv1.ThanosSpec{}},
+		},
+		&monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": "payments"}}},
+		&monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}},
+		&monitoringv1alpha1.AlertmanagerConfig{ObjectMeta: metav1.ObjectMeta{Name: "routes", Namespace: "default", Labels: map[string]string{"team": "payments"}}},
+	)
+
+	for _, tc := range []struct {
+		name      string
+		opts      []k8stesting.Option
+		namespace string
+		selector  string
+		expected  []analyzeTarget
+		err       string
+	}{
+		{
+			name:      "Namespace",
+			opts:      []k8stesting.Option{objects},
+			namespace: "default",
+			expected: []analyzeTarget{
+				{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"},
+				{Kind: string(ServiceMonitor), Name: "other", Namespace: "default"},
+				{Kind: string(AlertmanagerConfig), Name: "routes", Namespace: "default"},
+			},
+		},
+		{
+			name:      "Selector",
+			opts:      []k8stesting.Option{objects},
+			namespace: "default",
+			selector:  "team=payments",
+			expected: []analyzeTarget{
+				{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"},
+				{Kind: string(AlertmanagerConfig), Name: "routes", Namespace: "default"},
+			},
+		},
+		{
+			name:      "PrometheusWithThanosSidecar",
+			opts:      []k8stesting.Option{objects},
+			namespace: "monitoring",
+			expected: []analyzeTarget{
+				{Kind: string(Prometheus), Name: "k8s", Namespace: "monitoring"},
+				{Kind: string(Overlapping), Name: "k8s", Namespace: "monitoring"},
+				{Kind: string(Prometheus), Name: "thanos", Namespace: "monitoring"},
+				{Kind: string(Overlapping), Name: "thanos", Namespace: "monitoring"},
+				{Kind: string(Thanos), Name: "thanos", Namespace: "monitoring"},
+			},
+		},
+		{
+			name:      "AllNamespaces",
+			opts:      []k8stesting.Option{objects},
+			namespace: metav1.NamespaceAll,
+			selector:  "team=payments",
+			expected: []analyzeTarget{
+				{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"},
+				{Kind: string(AlertmanagerConfig), Name: "routes", Namespace: "default"},
+			},
+		},
+		{
+			name: "CRDNotInstalled",
+			opts: []k8stesting.Option{
+				objects,
+				k8stesting.WithMonitoringReactor("list", "alertmanagerconfigs", k8stesting.NotFound()),
+			},
+			namespace: "default",
+			expected: []analyzeTarget{
+				{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"},
+				{Kind: string(ServiceMonitor), Name: "other", Namespace: "default"},
+			},
+		},
+		{
+			name: "ListError",
+			opts: []k8stesting.Option{
+				objects,
+				k8stesting.WithMonitoringReactor("list", "servicemonitors", k8stesting.Forbidden()),
+			},
+			namespace: "default",
+			err:       "error while listing ServiceMonitors",
+		},
+		{
+			name:      "NoObjectInNamespace",
+			opts:      []k8stesting.Option{objects},
+			namespace: "empty",
+			err:       "no monitoring object to analyze in namespace empty",
+		},
+		{
+			name:      "NoObjectInCluster",
+			namespace: metav1.NamespaceAll,
+			err:       "no monitoring object to analyze in the cluster",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(tc.opts...)
+
+			targets, err := discoverTargets(context.Background(), clientSets, tc.namespace, tc.selector)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.expected, targets)
+		})
+	}
+}
+
+func TestContextTargets(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}},
+	))
+	given := []analyzeTarget{{Kind: string(Prometheus), Name: "k8s", Namespace: "monitoring"}}
+
+	t.Run("Targets", func(t *testing.T) {
+		withAnalyzeFlags(t, AnalyzeFlags{Namespace: "default"})
+
+		targets, err := contextTargets(context.Background(), clientSets, given)
+		require.NoError(t, err)
+		assert.Equal(t, given, targets)
+	})
+
+	t.Run("All", func(t *testing.T) {
+		withAnalyzeFlags(t, AnalyzeFlags{All: true, Namespace: "default"})
+
+		targets, err := contextTargets(context.Background(), clientSets, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []analyzeTarget{{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"}}, targets)
+	})
+
+	t.Run("AllNamespaces", func(t *testing.T) {
+		withAnalyzeFlags(t, AnalyzeFlags{All: true, AllNamespaces: true})
+
+		targets, err := contextTargets(context.Background(), clientSets, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []analyzeTarget{{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"}}, targets)
+	})
+}
+
+// newMonitoringServer returns an API server listing the ServiceMonitor
+// default/app and answering the other requests with a 404.
+func newMonitoringServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/apis/monitoring.coreos.com/v1/namespaces/default/servicemonitors" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"apiVersion":"monitoring.coreos.com/v1","kind":"ServiceMonitorList","metadata":{},"items":[{"apiVersion":"monitoring.coreos.com/v1","kind":"ServiceMonitor","metadata":{"name":"app","namespace":"default"}}]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// TestAnalyzeAllReports checks that the reports hold the objects discovered
+// with --all, and the failure of the cluster when no object is found.
+func TestAnalyzeAllReports(t *testing.T) {
+	t.Run("junit", func(t *testing.T) {
+		withKubeconfig(t, newMonitoringServer(t).URL, "kind")
+		withAnalyzeFlags(t, AnalyzeFlags{All: true, Namespace: "default", Timeout: time.Minute})
+
+		var stdout, stderr bytes.Buffer
+		// The ServiceMonitor can't be read back, its analysis fails.
+		require.Error(t, runJUnit(newReportCommand(t, &stdout, &stderr), nil))
+
+		var report junit.TestSuites
+		require.NoError(t, xml.Unmarshal(stdout.Bytes(), &report))
+		require.Len(t, report.Suites, 1)
+		assert.Equal(t, "kind", report.Suites[0].Name)
+		require.Len(t, report.Suites[0].Cases, 1)
+		assert.Equal(t, "default/app", report.Suites[0].Cases[0].Name)
+		assert.NotNil(t, report.Suites[0].Cases[0].Failure)
+	})
+
+	t.Run("junit without objects", func(t *testing.T) {
+		withKubeconfig(t, newMonitoringServer(t).URL, "kind")
+		withAnalyzeFlags(t, AnalyzeFlags{All: true, Namespace: "monitoring", Timeout: time.Minute})
+
+		var stdout, stderr bytes.Buffer
+		require.ErrorContains(t, runJUnit(newReportCommand(t, &stdout, &stderr), nil), "no monitoring object to analyze in namespace monitoring")
+
+		var report junit.TestSuites
+		require.NoError(t, xml.Unmarshal(stdout.Bytes(), &report))
+		require.Len(t, report.Suites, 1)
+		require.Len(t, report.Suites[0].Cases, 1)
+		assert.Equal(t, "cluster", report.Suites[0].Cases[0].ClassName)
+	})
+
+	t.Run("github", func(t *testing.T) {
+		withKubeconfig(t, newMonitoringServer(t).URL, "kind")
+		withAnalyzeFlags(t, AnalyzeFlags{All: true, Namespace: "default", Timeout: time.Minute})
+
+		var stdout, stderr bytes.Buffer
+		require.Error(t, runGitHub(newReportCommand(t, &stdout, &stderr), nil, ""))
+
+		assert.Equal(t, "::error title=kind%3A servicemonitor default/app::PO001: ServiceMonitor app not found in namespace default\n", stdout.String())
+	})
+
+	t.Run("github without objects", func(t *testing.T) {
+		withKubeconfig(t, newMonitoringServer(t).URL, "kind")
+		withAnalyzeFlags(t, AnalyzeFlags{All: true, Namespace: "monitoring", Timeout: time.Minute})
+
+		var stdout, stderr bytes.Buffer
+		require.Error(t, runGitHub(newReportCommand(t, &stdout, &stderr), nil, ""))
+
+		assert.Equal(t, "::error title=kind::no monitoring object to analyze in namespace monitoring\n", stdout.String())
+	})
+}
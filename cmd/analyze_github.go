@@ -0,0 +1,134 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/annotations"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/spf13/cobra"
+)
+
+// runGitHub analyzes the objects of the targets in each cluster and writes
+// the findings as GitHub Actions annotations, attached to the lines of the
+// targets file when the targets were read from one.
+func runGitHub(cmd *cobra.Command, targets []analyzeTarget, filename string) error {
+	logger, recorder, err := newReportLogger(cmd)
+	if err != nil {
+		return err
+	}
+
+	var found []annotations.Annotation
+	// A cluster which can't be analyzed is an error annotation titled with
+	// the cluster.
+	failed := func(logger *slog.Logger, err error) {
+		found = append(found, annotations.Annotation{
+			Level:   annotations.LevelError,
+			File:    filename,
+			Title:   cmp.Or(kubeContext(logger), "current-context"),
+			Message: err.Error(),
+		})
+	}
+	// The annotations name the clusters, the section headers would corrupt
+	// them.
+	err = forEachContextReport(io.Discard, logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), analyzerFlags.Timeout)
+		defer cancel()
+
+		clientSets, err := snapshotClientSets(ctx, clientSets)
+		if err != nil {
+			failed(logger, err)
+			return err
+		}
+
+		targets, err := contextTargets(ctx, clientSets, targets)
+		if err != nil {
+			failed(logger, err)
+			return err
+		}
+
+		results := analyzeObjects(ctx, clientSets, targets, recorder)
+		found = append(found, githubAnnotations(filename, kubeContext(logger), results)...)
+
+		return resultsErr(ctx, results)
+	}, failed)
+
+	if writeErr := annotations.Write(cmd.OutOrStdout(), found); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// targetsFileAnnotation writes the error of an invalid targets file as an
+// error annotation at the position of the faulty node, and returns the
+// error.
+func targetsFileAnnotation(w io.Writer, err error) error {
+	e, ok := err.(*targetsFileError)
+	if !ok {
+		return err
+	}
+
+	a := annotations.Annotation{
+		Level:   annotations.LevelError,
+		File:    e.filename,
+		Line:    e.pos.line,
+		Col:     e.pos.col,
+		Title:   cmp.Or(e.path, "targets file"),
+		Message: e.msg,
+	}
+	if writeErr := annotations.Write(w, []annotations.Annotation{a}); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// githubAnnotations returns an error annotation per failed object, a
+// warning annotation per warning and a notice per object not analyzed. The
+// title names the object, preceded by its cluster when it isn't the current
+// context.
+func githubAnnotations(filename, kubeContext string, results []analyzeResult) []annotations.Annotation {
+	var found []annotations.Annotation
+	for _, r := range results {
+		title := fmt.Sprintf("%s %s/%s", r.kind, r.namespace, r.name)
+		if kubeContext != "" {
+			title = kubeContext + ": " + title
+		}
+
+		annotation := func(level annotations.Level, message string) annotations.Annotation {
+			return annotations.Annotation{Level: level, File: filename, Line: r.pos.line, Col: r.pos.col, Title: title, Message: message}
+		}
+
+		switch r.status {
+		case analyzeFailed:
+			message := r.finding()
+			if id := r.id(); id != "-" {
+				message = id + ": " + message
+			}
+			found = append(found, annotation(annotations.LevelError, message))
+		case analyzeNotRun:
+			found = append(found, annotation(annotations.LevelNotice, "analysis interrupted"))
+		}
+
+		for _, w := range r.warnings {
+			found = append(found, annotation(annotations.LevelWarning, w))
+		}
+	}
+	return found
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/junit"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/spf13/cobra"
+)
+
+// The output formats of the analyze command.
+const (
+	outputText   = "text"
+	outputTable  = "table"
+	outputJUnit  = "junit"
+	outputGitHub = "github"
+	outputJSON   = "json"
+	outputYAML   = "yaml"
+)
+
+var analyzeOutputs = []string{outputText, outputTable, outputJUnit, outputGitHub, outputJSON, outputYAML}
+
+// runJUnit analyzes the objects of the targets in each cluster and writes a
+// JUnit report with a test suite per cluster, even when the analysis fails.
+func runJUnit(cmd *cobra.Command, targets []analyzeTarget) error {
+	logger, recorder, err := newReportLogger(cmd)
+	if err != nil {
+		return err
+	}
+
+	report := junit.TestSuites{Name: "poctl analyze"}
+	// A cluster which can't be analyzed is a suite with a failed test case.
+	failed := func(logger *slog.Logger, err error) {
+		report.Suites = append(report.Suites, junitClusterSuite(cmp.Or(kubeContext(logger), "current-context"), err))
+	}
+	// The report names the clusters, the section headers would corrupt it.
+	err = forEachContextReport(io.Discard, logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), analyzerFlags.Timeout)
+		defer cancel()
+
+		clientSets, err := snapshotClientSets(ctx, clientSets)
+		if err != nil {
+			failed(logger, err)
+			return err
+		}
+
+		targets, err := contextTargets(ctx, clientSets, targets)
+		if err != nil {
+			failed(logger, err)
+			return err
+		}
+
+		results := analyzeObjects(ctx, clientSets, targets, recorder)
+
+		name := cmp.Or(kubeContext(logger), "current-context")
+		report.Suites = append(report.Suites, junitSuite(name, results))
+
+		return resultsErr(ctx, results)
+	}, failed)
+
+	if writeErr := junit.Write(cmd.OutOrStdout(), report); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// newReportLogger returns the logger of the analyses writing a report: the
+// logs go to the standard error, so that the standard output only holds the
+// report, and the warnings are recorded for the report.
+func newReportLogger(cmd *cobra.Command) (*slog.Logger, *warningRecorder, error) {
+	logger, err := log.NewLoggerWithOutput(cmd.ErrOrStderr())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	recorder := &warningRecorder{}
+	logger = slog.New(newRecordingHandler(logger.Handler(), recorder))
+	slog.SetDefault(logger)
+	return logger, recorder, nil
+}
+
+// kubeContext returns the context of the cluster of a logger created by
+// forEachContext, empty for the current context.
+func kubeContext(logger *slog.Logger) string {
+	if h, ok := logger.Handler().(*recordingHandler); ok {
+		return h.kubeContext()
+	}
+	return ""
+}
+
+// warningRecorder records the warnings logged by the analyzers, so that the
+// reports list them along with the result of the analyzed object.
+type warningRecorder struct {
+	warnings []string
+}
+
+// take returns the warnings recorded since the previous call.
+func (r *warningRecorder) take() []string {
+	if r == nil {
+		return nil
+	}
+
+	warnings := r.warnings
+	r.warnings = nil
+	return warnings
+}
+
+// recordingHandler passes the records to its handler and records the
+// warnings, whatever the log level.
+type recordingHandler struct {
+	slog.Handler
+	recorder *warningRecorder
+	attrs    []slog.Attr
+}
+
+func newRecordingHandler(handler slog.Handler, recorder *warningRecorder) *recordingHandler {
+	return &recordingHandler{Handler: handler, recorder: recorder}
+}
+
+func (h *recordingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level == slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelWarn {
+		h.recorder.warnings = append(h.recorder.warnings, formatWarning(r))
+	}
+
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordingHandler{
+		Handler:  h.Handler.WithAttrs(attrs),
+		recorder: h.recorder,
+		attrs:    append(slices.Clip(h.attrs), attrs...),
+	}
+}
+
+func (h *recordingHandler) WithGroup(name string) slog.Handler {
+	return &recordingHandler{
+		Handler:  h.Handler.WithGroup(name),
+		recorder: h.recorder,
+		attrs:    h.attrs,
+	}
+}
+
+// kubeContext returns the context attribute of the logger created by
+// forEachContext for each cluster, empty for the current context.
+func (h *recordingHandler) kubeContext() string {
+	for _, attr := range h.attrs {
+		if attr.Key == "context" {
+			return attr.Value.String()
+		}
+	}
+	return ""
+}
+
+// formatWarning returns the warning as its ID, its message and its hint.
+func formatWarning(r slog.Record) string {
+	var id, hint string
+	r.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "id":
+			id = attr.Value.String()
+		case "hint":
+			hint = attr.Value.String()
+		}
+		return true
+	})
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "%s: ", id)
+	}
+	b.WriteString(r.Message)
+	if hint != "" {
+		fmt.Fprintf(&b, " (hint: %s)", hint)
+	}
+	return b.String()
+}
+
+// junitSuite returns the JUnit test suite of the results of a cluster, each
+// analyzed object being a test case.
+func junitSuite(name string, results []analyzeResult) junit.TestSuite {
+	suite := junit.TestSuite{Name: name}
+	for _, r := range results {
+		c := junit.TestCase{
+			Name:      r.namespace + "/" + r.name,
+			ClassName: strings.ToLower(r.kind),
+			SystemOut: strings.Join(r.warnings, "\n"),
+		}
+
+		switch r.status {
+		case analyzeFailed:
+			id, _ := messages.IDOf(r.err)
+			c.Failure = &junit.Failure{Message: r.err.Error(), Type: string(id), Text: r.err.Error()}
+		case analyzeNotRun:
+			c.Skipped = &junit.Skipped{Message: "analysis interrupted"}
+		}
+
+		suite.Add(c)
+	}
+	return suite
+}
+
+// junitClusterSuite returns the JUnit test suite of a cluster which couldn't
+// be analyzed, its test case failing with the error.
+func junitClusterSuite(name string, err error) junit.TestSuite {
+	suite := junit.TestSuite{Name: name}
+	suite.Add(junit.TestCase{
+		Name:      name,
+		ClassName: "cluster",
+		Failure:   &junit.Failure{Message: err.Error(), Text: err.Error()},
+	})
+	return suite
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/analyzers"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// runOutput analyzes the objects of the targets in each cluster and writes
+// their findings as a JSON or YAML document, even when the analysis fails.
+func runOutput(cmd *cobra.Command, targets []analyzeTarget, format output.Format) error {
+	logger, recorder, err := newReportLogger(cmd)
+	if err != nil {
+		return err
+	}
+
+	var report output.Report
+	// A cluster which can't be analyzed is a failed result naming only the
+	// cluster.
+	failed := func(logger *slog.Logger, err error) {
+		report.Results = append(report.Results, output.Result{Context: kubeContext(logger), Status: output.StatusFailed, Error: err.Error()})
+	}
+	// The document names the clusters, the section headers would corrupt it.
+	err = forEachContextReport(io.Discard, logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), analyzerFlags.Timeout)
+		defer cancel()
+
+		clientSets, err := snapshotClientSets(ctx, clientSets)
+		if err != nil {
+			failed(logger, err)
+			return err
+		}
+
+		targets, err := contextTargets(ctx, clientSets, targets)
+		if err != nil {
+			failed(logger, err)
+			return err
+		}
+
+		results := analyzeObjects(ctx, clientSets, targets, recorder)
+		report.Results = append(report.Results, outputResults(kubeContext(logger), results)...)
+
+		return resultsErr(ctx, results)
+	}, failed)
+
+	if writeErr := output.Write(cmd.OutOrStdout(), format, report); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// outputResults returns the results of a cluster for the document. The
+// failures which aren't reported by an error finding, such as a selector
+// matching nothing, are reported by the error of the result.
+func outputResults(kubeContext string, results []analyzeResult) []output.Result {
+	found := make([]output.Result, 0, len(results))
+	for _, r := range results {
+		result := output.Result{
+			Context:   kubeContext,
+			Kind:      r.kind,
+			Namespace: r.namespace,
+			Name:      r.name,
+			Findings:  r.findings,
+		}
+
+		switch r.status {
+		case analyzeCompliant:
+			result.Status = output.StatusCompliant
+		case analyzeFailed:
+			result.Status = output.StatusFailed
+			if analyzers.Err(r.findings) == nil {
+				result.Error = r.err.Error()
+			}
+		case analyzeNotRun:
+			result.Status = output.StatusNotRun
+		}
+
+		found = append(found, result)
+	}
+	return found
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/junit"
+	"github.com/prometheus-operator/poctl/internal/output"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withKubeconfig points --kubeconfig to a kubeconfig whose kind context
+// is served by the server, and --contexts to the contexts.
+func withKubeconfig(t *testing.T, server string, contexts ...string) {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(filename, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: `+server+`
+contexts:
+- name: kind
+  context:
+    cluster: kind
+current-context: kind
+`), 0o600))
+
+	previousKubeconfig, previousContexts := kubeconfig, kubeContexts
+	kubeconfig, kubeContexts = filename, contexts
+	t.Cleanup(func() {
+		kubeconfig, kubeContexts = previousKubeconfig, previousContexts
+	})
+}
+
+// unanalyzableContexts points --contexts to contexts missing from the
+// kubeconfig, so that the clients of their clusters can't be created.
+func unanalyzableContexts(t *testing.T, contexts ...string) {
+	t.Helper()
+	withKubeconfig(t, "https://127.0.0.1:6443", contexts...)
+}
+
+// newReportCommand returns a command writing the report to stdout and the
+// logs to stderr, restoring the default logger set by the report writers.
+func newReportCommand(t *testing.T, stdout, stderr *bytes.Buffer) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+
+	logger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	return cmd
+}
+
+// runReport runs the report writer of an output against the contexts and
+// returns what it wrote to the standard output.
+func runReport(t *testing.T, fn func(cmd *cobra.Command, targets []analyzeTarget) error) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cmd := newReportCommand(t, &stdout, &stderr)
+
+	targets := []analyzeTarget{{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"}}
+	err := fn(cmd, targets)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `context "alpha" does not exist`)
+	assert.Contains(t, err.Error(), `context "beta" does not exist`)
+
+	return stdout.String()
+}
+
+// TestReportUnanalyzableClusters checks that the reports list each cluster
+// whose clients can't be created, named after its context.
+func TestReportUnanalyzableClusters(t *testing.T) {
+	t.Run("junit", func(t *testing.T) {
+		unanalyzableContexts(t, "alpha", "beta")
+
+		var report junit.TestSuites
+		require.NoError(t, xml.Unmarshal([]byte(runReport(t, runJUnit)), &report))
+
+		require.Len(t, report.Suites, 2)
+		for i, name := range []string{"alpha", "beta"} {
+			suite := report.Suites[i]
+			assert.Equal(t, name, suite.Name)
+			assert.Equal(t, 1, suite.Failures)
+			require.Len(t, suite.Cases, 1)
+			assert.Equal(t, "cluster", suite.Cases[0].ClassName)
+			require.NotNil(t, suite.Cases[0].Failure)
+			assert.Contains(t, suite.Cases[0].Failure.Message, "error while getting clientsets")
+		}
+	})
+
+	t.Run("github", func(t *testing.T) {
+		unanalyzableContexts(t, "alpha", "beta")
+
+		lines := strings.Split(strings.TrimSpace(runReport(t, func(cmd *cobra.Command, targets []analyzeTarget) error {
+			return runGitHub(cmd, targets, "targets.yaml")
+		})), "\n")
+
+		require.Len(t, lines, 2)
+		for i, name := range []string{"alpha", "beta"} {
+			assert.True(t, strings.HasPrefix(lines[i], "::error file=targets.yaml,title="+name+"::error while getting clientsets"), lines[i])
+		}
+	})
+
+	t.Run("document", func(t *testing.T) {
+		unanalyzableContexts(t, "alpha", "beta")
+
+		var report output.Report
+		require.NoError(t, json.Unmarshal([]byte(runReport(t, func(cmd *cobra.Command, targets []analyzeTarget) error {
+			return runOutput(cmd, targets, output.JSON)
+		})), &report))
+
+		assert.Equal(t, 2, report.Summary.Failed)
+		require.Len(t, report.Results, 2)
+		for i, name := range []string{"alpha", "beta"} {
+			result := report.Results[i]
+			assert.Equal(t, name, result.Context)
+			assert.Equal(t, output.StatusFailed, result.Status)
+			assert.Empty(t, result.Kind)
+			assert.Contains(t, result.Error, "error while getting clientsets")
+		}
+	})
+}
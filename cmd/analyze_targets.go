@@ -0,0 +1,359 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/analyzers"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"go.yaml.in/yaml/v3"
+)
+
+// analyzeTarget is an object to analyze, or the objects of the kind matching
+// the selector.
+type analyzeTarget struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Selector  string
+	// pos is the position of the target in the targets file, zero when the
+	// target wasn't read from a file.
+	pos position
+}
+
+// position is a position in a file, the line and the column starting at 1.
+type position struct {
+	line int
+	col  int
+}
+
+// targetsFileError is an error found in a targets file, at the position of
+// the faulty node.
+type targetsFileError struct {
+	filename string
+	pos      position
+	// path is the path of the faulty node, such as targets[1].kind, empty for
+	// the document.
+	path string
+	msg  string
+}
+
+func (e *targetsFileError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.filename)
+	if e.pos.line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", e.pos.line, e.pos.col)
+	}
+	b.WriteString(": ")
+	if e.path != "" {
+		b.WriteString(e.path + ": ")
+	}
+	b.WriteString(e.msg)
+	return b.String()
+}
+
+// loadAnalyzeTargets returns the targets listed in the file, the targets
+// without namespace defaulting to the given one. The file is decoded as YAML
+// nodes, so that the errors point to the position of the faulty node.
+func loadAnalyzeTargets(filename, defaultNamespace string) ([]analyzeTarget, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading targets file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error while parsing targets file %s: %w", filename, err)
+	}
+
+	targets, err := decodeAnalyzeTargets(&doc, defaultNamespace)
+	if err != nil {
+		if e, ok := err.(*targetsFileError); ok {
+			e.filename = filename
+		}
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// decodeAnalyzeTargets decodes the targets of the document of a targets
+// file, rejecting the unknown fields.
+func decodeAnalyzeTargets(doc *yaml.Node, defaultNamespace string) ([]analyzeTarget, error) {
+	if len(doc.Content) == 0 {
+		return nil, &targetsFileError{msg: "no targets found"}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nodeError(root, "", "expected a mapping")
+	}
+
+	var items *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "targets" {
+			return nil, nodeError(key, key.Value, "unknown field")
+		}
+
+		if items != nil {
+			return nil, nodeError(key, key.Value, "duplicate field")
+		}
+
+		if value.Kind != yaml.SequenceNode {
+			return nil, nodeError(value, key.Value, "expected a list")
+		}
+		items = value
+	}
+
+	if items == nil || len(items.Content) == 0 {
+		return nil, nodeError(root, "", "no targets found")
+	}
+
+	targets := make([]analyzeTarget, 0, len(items.Content))
+	for i, item := range items.Content {
+		t, err := decodeAnalyzeTarget(item, fmt.Sprintf("targets[%d]", i), defaultNamespace)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// decodeAnalyzeTarget decodes and validates a target, the errors pointing
+// to the faulty field when there is one.
+func decodeAnalyzeTarget(node *yaml.Node, path, defaultNamespace string) (analyzeTarget, error) {
+	t := analyzeTarget{pos: position{line: node.Line, col: node.Column}}
+	if node.Kind != yaml.MappingNode {
+		return t, nodeError(node, path, "expected a mapping")
+	}
+
+	fields := map[string]*string{
+		"kind":      &t.Kind,
+		"name":      &t.Name,
+		"namespace": &t.Namespace,
+		"selector":  &t.Selector,
+	}
+	values := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		field, ok := fields[key.Value]
+		if !ok {
+			return t, nodeError(key, path+"."+key.Value, "unknown field")
+		}
+
+		if _, ok := values[key.Value]; ok {
+			return t, nodeError(key, path+"."+key.Value, "duplicate field")
+		}
+
+		if value.Kind != yaml.ScalarNode {
+			return t, nodeError(value, path+"."+key.Value, "expected a string")
+		}
+
+		if value.Tag != "!!null" {
+			*field = value.Value
+		}
+		values[key.Value] = value
+	}
+
+	t.Namespace = cmp.Or(t.Namespace, defaultNamespace)
+	if field, err := t.validate(); err != nil {
+		// The missing fields are reported at the position of the target.
+		if value, ok := values[field]; ok {
+			return t, nodeError(value, path+"."+field, err.Error())
+		}
+		return t, nodeError(node, path, err.Error())
+	}
+
+	return t, nil
+}
+
+// nodeError returns the error of a node of a targets file, the name of the
+// file being set by loadAnalyzeTargets.
+func nodeError(node *yaml.Node, path, msg string) *targetsFileError {
+	return &targetsFileError{pos: position{line: node.Line, col: node.Column}, path: path, msg: msg}
+}
+
+// validate checks the fields of the target, returning the field at fault
+// along with the error.
+func (t analyzeTarget) validate() (string, error) {
+	if t.Kind == "" {
+		return "kind", fmt.Errorf("kind is required")
+	}
+
+	if _, ok := kindAnalyzers[AnalyzeKind(strings.ToLower(t.Kind))]; !ok {
+		return "kind", fmt.Errorf("kind %s not supported", t.Kind)
+	}
+
+	if t.Name == "" && t.Selector == "" {
+		return "name", fmt.Errorf("name or selector is required")
+	}
+
+	if t.Name != "" && t.Selector != "" {
+		return "selector", fmt.Errorf("name and selector are mutually exclusive")
+	}
+
+	if t.Namespace == "" {
+		return "namespace", fmt.Errorf("namespace is required")
+	}
+
+	return "", nil
+}
+
+// analyzeStatus is the outcome of the analysis of an object.
+type analyzeStatus string
+
+const (
+	analyzeCompliant analyzeStatus = "compliant"
+	analyzeFailed    analyzeStatus = "failed"
+	analyzeNotRun    analyzeStatus = "not run"
+)
+
+// analyzeResult is the outcome of the analysis of an object, or of a target
+// whose selector failed to be expanded or was interrupted before.
+type analyzeResult struct {
+	kind      string
+	namespace string
+	name      string
+	status    analyzeStatus
+	err       error
+	// warnings are the warnings logged by the analyzer, only recorded for
+	// the reports.
+	warnings []string
+	findings []analyzers.Finding
+	// pos is the position of the target of the object in the targets file,
+	// zero when unknown.
+	pos position
+}
+
+// id returns the message ID of the failure, - when there is none.
+func (r analyzeResult) id() string {
+	id, _ := messages.IDOf(r.err)
+	return cmp.Or(string(id), "-")
+}
+
+// finding returns the failure, - when there is none.
+func (r analyzeResult) finding() string {
+	if r.err == nil {
+		return "-"
+	}
+	return r.err.Error()
+}
+
+// analyzeObjects analyzes the objects of the targets in turn. The targets
+// with a selector are expanded to the matching objects, a selector matching
+// nothing failing the target. The analysis doesn't stop at the failure of an
+// object, while an interruption leaves the remaining targets not run. The
+// warnings logged during the analysis of each object are taken from the
+// recorder when it isn't nil.
+func analyzeObjects(ctx context.Context, clientSets *k8sutil.ClientSets, targets []analyzeTarget, recorder *warningRecorder) []analyzeResult {
+	var results []analyzeResult
+	for i, t := range targets {
+		if ctx.Err() != nil {
+			for _, t := range targets[i:] {
+				results = append(results, analyzeResult{kind: t.Kind, namespace: t.Namespace, name: t.displayName(), status: analyzeNotRun, pos: t.pos})
+			}
+			return results
+		}
+
+		names := []string{t.Name}
+		if t.Selector != "" {
+			var err error
+			names, err = selectedObjects(ctx, clientSets, t.Kind, t.Namespace, t.Selector)
+			if err == nil && len(names) == 0 {
+				err = fmt.Errorf("no %s matches the selector %s in namespace %s", t.Kind, t.Selector, t.Namespace)
+			}
+
+			if err != nil {
+				results = append(results, analyzeResult{kind: t.Kind, namespace: t.Namespace, name: t.displayName(), status: analyzeFailed, err: err, pos: t.pos})
+				continue
+			}
+		}
+
+		for _, name := range names {
+			r := analyzeResult{kind: t.Kind, namespace: t.Namespace, name: name, status: analyzeCompliant, pos: t.pos}
+			findings, err := analyzeObject(ctx, clientSets, t.Kind, name, t.Namespace)
+			if err != nil {
+				r.status = analyzeFailed
+				r.err = err
+			}
+			r.findings = findings
+			r.warnings = recorder.take()
+			results = append(results, r)
+		}
+	}
+
+	return results
+}
+
+// resultsErr returns an error when the analysis was interrupted or when any
+// of the objects failed it.
+func resultsErr(ctx context.Context, results []analyzeResult) error {
+	var run, failed int
+	for _, r := range results {
+		switch r.status {
+		case analyzeNotRun:
+			continue
+		case analyzeFailed:
+			failed++
+		}
+		run++
+	}
+
+	if err := ctx.Err(); err != nil && run < len(results) {
+		return fmt.Errorf("analysis interrupted after %d of %d objects: %w", run, len(results), err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d objects failed the analysis", failed, len(results))
+	}
+	return nil
+}
+
+// analyzeTargets analyzes the objects of the targets and prints the findings
+// of each object.
+func analyzeTargets(ctx context.Context, out io.Writer, clientSets *k8sutil.ClientSets, targets []analyzeTarget) error {
+	results := analyzeObjects(ctx, clientSets, targets, nil)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tRESULT\tID\tFINDING")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.kind, r.namespace, r.name, r.status, r.id(), r.finding())
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return resultsErr(ctx, results)
+}
+
+// displayName returns the name of the target, or its selector.
+func (t analyzeTarget) displayName() string {
+	if t.Selector != "" {
+		return "selector " + t.Selector
+	}
+	return t.Name
+}
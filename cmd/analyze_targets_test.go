@@ -0,0 +1,176 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAnalyzeTargets(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		expected []analyzeTarget
+		// err is the expected error, following the name of the file.
+		err string
+	}{
+		{
+			name: "Valid",
+			content: `targets:
+- kind: ServiceMonitor
+  name: api
+- kind: thanosruler
+  namespace: thanos
+  selector: team=a
+`,
+			expected: []analyzeTarget{
+				{Kind: "ServiceMonitor", Name: "api", Namespace: "default", pos: position{line: 2, col: 3}},
+				{Kind: "thanosruler", Namespace: "thanos", Selector: "team=a", pos: position{line: 4, col: 3}},
+			},
+		},
+		{
+			name:    "Empty",
+			content: "",
+			err:     ": no targets found",
+		},
+		{
+			name:    "UnknownField",
+			content: "objects: []\n",
+			err:     ":1:1: objects: unknown field",
+		},
+		{
+			name: "DuplicateTargets",
+			content: `targets:
+- kind: prometheus
+  name: k8s
+targets:
+- kind: alertmanager
+  name: main
+`,
+			err: ":4:1: targets: duplicate field",
+		},
+		{
+			name: "DuplicateTargetField",
+			content: `targets:
+- kind: prometheus
+  name: k8s
+  name: main
+`,
+			err: ":4:3: targets[0].name: duplicate field",
+		},
+		{
+			name: "UnknownTargetField",
+			content: `targets:
+- kind: prometheus
+  nmae: k8s
+`,
+			err: ":3:3: targets[0].nmae: unknown field",
+		},
+		{
+			// The kinds are checked against the analyzers.
+			name: "UnsupportedKind",
+			content: `targets:
+- kind: deployment
+  name: k8s
+`,
+			err: ":2:9: targets[0].kind: kind deployment not supported",
+		},
+		{
+			name: "MissingName",
+			content: `targets:
+- kind: prometheus
+`,
+			err: ":2:3: targets[0]: name or selector is required",
+		},
+		{
+			name: "NameAndSelector",
+			content: `targets:
+- kind: prometheus
+  name: k8s
+  selector: team=a
+`,
+			err: ":4:13: targets[0].selector: name and selector are mutually exclusive",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "targets.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(tc.content), 0o600))
+
+			targets, err := loadAnalyzeTargets(file, "default")
+			if tc.err != "" {
+				assert.EqualError(t, err, file+tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, targets)
+		})
+	}
+}
+
+func TestAnalyzeTargetsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	targets := []analyzeTarget{
+		{Kind: string(ServiceMonitor), Name: "app", Namespace: "default"},
+		{Kind: string(PodMonitor), Selector: "team=a", Namespace: "default"},
+	}
+
+	var out bytes.Buffer
+	err := analyzeTargets(ctx, &out, k8stesting.NewFakeClientSets(), targets)
+	require.EqualError(t, err, "analysis interrupted after 0 of 2 objects: context canceled")
+
+	assert.Equal(t, `KIND             NAMESPACE   NAME              RESULT    ID   FINDING
+servicemonitor   default     app               not run   -    -
+podmonitor       default     selector team=a   not run   -    -
+`, out.String())
+}
+
+// TestAnalyzeTimeout checks that --timeout bounds the analysis of a cluster,
+// the objects left once it expired being reported as not analyzed.
+func TestAnalyzeTimeout(t *testing.T) {
+	// The API server never answers.
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	withKubeconfig(t, server.URL, "kind")
+	withAnalyzeFlags(t, AnalyzeFlags{Timeout: 100 * time.Millisecond})
+
+	targets := []analyzeTarget{
+		{Kind: string(ServiceMonitor), Name: "a", Namespace: "default"},
+		{Kind: string(ServiceMonitor), Name: "b", Namespace: "default"},
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := runGitHub(newReportCommand(t, &stdout, &stderr), targets, "")
+	require.ErrorContains(t, err, "analysis interrupted after 1 of 2 objects: context deadline exceeded")
+
+	assert.Contains(t, stdout.String(), "::error title=kind%3A servicemonitor default/a::")
+	assert.Contains(t, stdout.String(), "::notice title=kind%3A servicemonitor default/b::analysis interrupted\n")
+}
@@ -0,0 +1,33 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// auditCmd represents the audit command.
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "The audit command reviews the security of the monitoring resources of a namespace.",
+	Long:  `The audit command in poctl reviews the workloads and the RBAC permissions of the monitoring resources of a namespace. Unlike analyze, which checks that a single object works as expected, audit looks for settings which work but weaken the security of the cluster, and reports them by priority.`,
+}
+
+var auditNamespace string
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.PersistentFlags().StringVarP(&auditNamespace, "namespace", "n", "default", "Namespace to audit")
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/audit"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	auditServiceAccount string
+	auditApply          bool
+
+	auditRBACCmd = &cobra.Command{
+		Use:   "rbac",
+		Short: "Suggest minimized ClusterRole rules for a ServiceAccount of the Prometheus Operator or of Prometheus.",
+		Long:  `Suggest minimized ClusterRole rules for a ServiceAccount of the Prometheus Operator or of Prometheus. The rules granted by the ClusterRoles bound to the ServiceAccount are compared with the permissions the component needs: the granted rules exceeding them are reported, and the needed permissions which are granted are printed as a ClusterRole. With --apply, the rules of the bound ClusterRole are replaced by the minimized ones.`,
+		Example: `  # Suggest minimized rules for the ServiceAccount of the operator
+  poctl audit rbac --service-account prometheus-operator -n monitoring
+
+  # Replace the rules of its ClusterRole with the minimized ones
+  poctl audit rbac --service-account prometheus-operator -n monitoring --apply`,
+		Args: cobra.NoArgs,
+		RunE: runAuditRBAC,
+	}
+)
+
+func runAuditRBAC(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	if auditServiceAccount == "" {
+		return fmt.Errorf("--service-account is required")
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	report, err := audit.RBAC(cmd.Context(), clientSets, auditServiceAccount, auditNamespace)
+	if err != nil {
+		return err
+	}
+
+	for _, excess := range report.Excess {
+		slog.Warn("rule grants more than the component needs", "clusterRole", excess.ClusterRole, "component", report.Component, "rule", ruleString(excess.Rule))
+	}
+
+	for _, rule := range report.Missing {
+		slog.Warn("permission needed by the component isn't granted", "component", report.Component, "rule", ruleString(rule))
+	}
+
+	if auditApply {
+		if err := audit.ApplyRBAC(cmd.Context(), clientSets, report); err != nil {
+			return err
+		}
+		slog.Info("ClusterRole updated with the minimized rules", "name", report.ClusterRoles[0])
+		return nil
+	}
+
+	name := report.ClusterRoles[0]
+	if len(report.ClusterRoles) > 1 {
+		name = auditServiceAccount
+	}
+
+	out, err := yaml.Marshal(&rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterRole",
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Rules: report.Minimized,
+	})
+	if err != nil {
+		return fmt.Errorf("error while marshaling ClusterRole: %w", err)
+	}
+
+	_, err = cmd.OutOrStdout().Write(out)
+	return err
+}
+
+func ruleString(rule rbacv1.PolicyRule) string {
+	if len(rule.NonResourceURLs) > 0 {
+		return fmt.Sprintf("nonResourceURLs=%s verbs=%s", strings.Join(rule.NonResourceURLs, ","), strings.Join(rule.Verbs, ","))
+	}
+	return fmt.Sprintf("apiGroups=%s resources=%s verbs=%s", strings.Join(rule.APIGroups, ","), strings.Join(rule.Resources, ","), strings.Join(rule.Verbs, ","))
+}
+
+func init() {
+	auditCmd.AddCommand(auditRBACCmd)
+	auditRBACCmd.Flags().StringVar(&auditServiceAccount, "service-account", "", "Name of the ServiceAccount to audit")
+	auditRBACCmd.Flags().BoolVar(&auditApply, "apply", false, "Replace the rules of the ClusterRole bound to the ServiceAccount with the minimized rules")
+}
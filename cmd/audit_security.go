@@ -0,0 +1,117 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/audit"
+	"github.com/prometheus-operator/poctl/internal/junit"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var auditSecurityCmd = &cobra.Command{
+	Use:   "security",
+	Short: "Report the security issues of the workloads and roles of a namespace.",
+	Long:  `Report the security issues of the workloads and roles of a namespace. The Deployments, StatefulSets and DaemonSets are checked for privileged containers, missing seccomp profiles, writable root filesystems and world-readable Secret volumes, and the Roles and ClusterRoles bound to the ServiceAccounts of the namespace for wildcard verbs and resources.`,
+	Example: `  # Report the security issues of a namespace
+  poctl audit security -n monitoring
+
+  # Write the findings as a JUnit report
+  poctl audit security -n monitoring -o junit > audit-security.xml`,
+	Args: cobra.NoArgs,
+	RunE: runAuditSecurity,
+}
+
+var auditSecurityOutput string
+
+func runAuditSecurity(cmd *cobra.Command, _ []string) error {
+	output := strings.ToLower(auditSecurityOutput)
+	if output != outputText && output != outputJUnit {
+		return fmt.Errorf("unknown output %s, must be one of: %s, %s", auditSecurityOutput, outputText, outputJUnit)
+	}
+
+	logOutput := cmd.OutOrStdout()
+	if output == outputJUnit {
+		logOutput = cmd.ErrOrStderr()
+	}
+
+	logger, err := log.NewLoggerWithOutput(logOutput)
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	findings, err := audit.Security(cmd.Context(), clientSets, auditNamespace)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJUnit {
+		return junit.Write(cmd.OutOrStdout(), junit.TestSuites{
+			Name:   "poctl audit security",
+			Suites: []junit.TestSuite{securityJUnitSuite(auditNamespace, findings)},
+		})
+	}
+
+	if len(findings) == 0 {
+		slog.Info("no security issues found", "namespace", auditNamespace)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tKIND\tNAME\tFINDING")
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Severity, f.Kind, f.Name, f.Message)
+	}
+
+	return w.Flush()
+}
+
+// securityJUnitSuite returns the JUnit test suite of the findings of the
+// namespace, each finding being a failed test case. A namespace without
+// findings has a single passing test case.
+func securityJUnitSuite(namespace string, findings []audit.Finding) junit.TestSuite {
+	suite := junit.TestSuite{Name: namespace}
+	if len(findings) == 0 {
+		suite.Add(junit.TestCase{Name: namespace, ClassName: "namespace"})
+		return suite
+	}
+
+	for _, f := range findings {
+		suite.Add(junit.TestCase{
+			Name:      fmt.Sprintf("%s: %s", f.Name, f.Message),
+			ClassName: strings.ToLower(f.Kind),
+			Failure:   &junit.Failure{Message: f.Message, Type: f.Severity.String(), Text: f.Message},
+		})
+	}
+	return suite
+}
+
+func init() {
+	auditCmd.AddCommand(auditSecurityCmd)
+	auditSecurityCmd.Flags().StringVarP(&auditSecurityOutput, "output", "o", outputText, fmt.Sprintf("Output format of the findings, one of: %s, %s. With %s, each finding is a failed test case of the report", outputText, outputJUnit, outputJUnit))
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// checkCmd represents the check command.
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "The check command looks for the resources of the monitoring stack about to break.",
+	Long:  `The check command in poctl looks for the resources of the monitoring stack which work today but are about to break, such as the certificates close to their expiry, so that they can be renewed before the scrapes and the webhooks start failing.`,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/certificates"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	certificatesNamespace     string
+	certificatesAllNamespaces bool
+	certificatesThreshold     time.Duration
+
+	certificatesCmd = &cobra.Command{
+		Use:   "certificates",
+		Short: "Report the expiry of the certificates used by the monitoring resources.",
+		Long:  `Report the expiry of the certificates used by the monitoring resources: the certificates served by the Prometheus, PrometheusAgent and Alertmanager web servers, the certificates used to reach the remote write endpoints, the Alertmanagers and the targets of the monitors, and the CA bundles and serving certificates of the admission and conversion webhooks of the monitoring.coreos.com resources. The command fails when a certificate is expired or expires within the threshold.`,
+		Example: `  # Check the certificates of a namespace
+  poctl check certificates -n monitoring
+
+  # Check the certificates of all the namespaces, reporting those expiring within 2 weeks
+  poctl check certificates -A --threshold 336h`,
+		Args: cobra.NoArgs,
+		RunE: runCheckCertificates,
+	}
+)
+
+func init() {
+	checkCmd.AddCommand(certificatesCmd)
+	certificatesCmd.Flags().StringVarP(&certificatesNamespace, "namespace", "n", metav1.NamespaceDefault, "Namespace of the resources")
+	certificatesCmd.Flags().BoolVarP(&certificatesAllNamespaces, "all-namespaces", "A", false, "Check the resources of all the namespaces")
+	certificatesCmd.Flags().DurationVar(&certificatesThreshold, "threshold", 30*24*time.Hour, "Duration before the expiry under which a certificate is reported as expiring")
+	registerContextsFlag(certificatesCmd)
+}
+
+func runCheckCertificates(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	opts := certificates.Options{
+		Namespace: certificatesNamespace,
+		Threshold: certificatesThreshold,
+	}
+	if certificatesAllNamespaces {
+		opts.Namespace = metav1.NamespaceAll
+	}
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		certs, err := certificates.Check(cmd.Context(), clientSets, opts)
+		if err != nil {
+			return err
+		}
+
+		if len(certs) == 0 {
+			logger.Info("no certificate found", "namespace", opts.Namespace)
+			return nil
+		}
+
+		var failing int
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tDAYS\tEXPIRES\tSOURCE\tOBJECT\tKEY\tSUBJECT")
+		for _, c := range certs {
+			if c.Status == certificates.Expired || c.Status == certificates.Expiring {
+				failing++
+			}
+
+			if c.Status == certificates.Unreadable {
+				fmt.Fprintf(w, "%s\t-\t-\t%s\t%s\t%s\t%v\n", c.Status, c.Source, c.Object, c.Key, c.Err)
+				continue
+			}
+
+			object, key := c.Object, c.Key
+			if object == "" {
+				object, key = "-", "-"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", c.Status, c.DaysLeft, c.NotAfter.Format(time.RFC3339), c.Source, object, key, c.Subject)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if failing > 0 {
+			return fmt.Errorf("%d certificates are expired or expire within %s", failing, certificatesThreshold)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var clusterDomain string
+
+// registerClusterDomainFlag adds the --cluster-domain flag to commands
+// which address Services by their DNS names.
+func registerClusterDomainFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&clusterDomain, "cluster-domain", "", "DNS domain of the Services of the cluster, as set in the clusterDomain of the kubelet configuration. The Services are addressed as <service>.<namespace>.svc, relative to the search domains of the Pods, when unset")
+}
+
+// parseClusterDomain returns the cluster domain given by --cluster-domain,
+// without its trailing dot, empty when the flag isn't set.
+func parseClusterDomain() (string, error) {
+	domain := strings.TrimSuffix(clusterDomain, ".")
+	if domain == "" {
+		return "", nil
+	}
+
+	if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+		return "", fmt.Errorf("invalid cluster domain %s: %s", clusterDomain, strings.Join(errs, ", "))
+	}
+	return domain, nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// configureCmd represents the configure command.
+var configureCmd = &cobra.Command{
+	Use:   "configure",
+	Short: "The configure command sets up the scraping of components which need more than a ServiceMonitor and the labeling of the alerts.",
+	Long:  `The configure command in poctl guides the setup of the scraping of components which need more than a ServiceMonitor, such as the TLS-protected control plane components, creating the Services, Secrets and ServiceMonitors they need, and the labels identifying the tenant or the cluster across the Prometheus resources.`,
+}
+
+func init() {
+	rootCmd.AddCommand(configureCmd)
+}
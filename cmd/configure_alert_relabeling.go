@@ -0,0 +1,117 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/labeling"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	relabelingNamespace      string
+	relabelingAllNamespaces  bool
+	relabelingSelector       string
+	relabelingExternalLabels map[string]string
+	relabelingAlertLabels    map[string]string
+	relabelingDryRun         bool
+
+	alertRelabelingCmd = &cobra.Command{
+		Use:   "alert-relabeling",
+		Short: "Set the labels identifying the tenant or the cluster on the Prometheus resources.",
+		Long:  `Set the external labels and the alert labels identifying the tenant or the cluster on every Prometheus matching the label selector, so that the Alertmanager routes relying on them apply to all of them. The external labels are added to every series and alert leaving Prometheus, the alert labels only to the alerts sent to the Alertmanagers of spec.alerting, by alert relabelings which require Prometheus v2.51.0 or later. The diff of each Prometheus is printed.`,
+		Example: `  # Identify the cluster of all the Prometheus resources
+  poctl configure alert-relabeling -A --external-labels cluster=prod-eu
+
+  # Route the alerts of the Prometheus resources of a team to its receiver
+  poctl configure alert-relabeling -A -l team=payments --alert-labels tenant=payments`,
+		RunE: runAlertRelabeling,
+	}
+)
+
+func init() {
+	configureCmd.AddCommand(alertRelabelingCmd)
+	alertRelabelingCmd.Flags().StringVarP(&relabelingNamespace, "namespace", "n", "default", "Namespace of the Prometheus resources")
+	alertRelabelingCmd.Flags().BoolVarP(&relabelingAllNamespaces, "all-namespaces", "A", false, "Configure the Prometheus resources of all the namespaces")
+	alertRelabelingCmd.Flags().StringVarP(&relabelingSelector, "selector", "l", "", "Label selector of the Prometheus resources, all of them are configured when not set. For example, team=payments")
+	alertRelabelingCmd.Flags().StringToStringVar(&relabelingExternalLabels, "external-labels", nil, "External labels to set in the name=value,... format")
+	alertRelabelingCmd.Flags().StringToStringVar(&relabelingAlertLabels, "alert-labels", nil, "Labels to set on the alerts sent to Alertmanager in the name=value,... format")
+	alertRelabelingCmd.Flags().BoolVar(&relabelingDryRun, "dry-run", false, "Print the diffs without updating the Prometheus resources")
+}
+
+func runAlertRelabeling(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	opts := labeling.Options{
+		ExternalLabels: relabelingExternalLabels,
+		AlertLabels:    relabelingAlertLabels,
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	namespace := relabelingNamespace
+	if relabelingAllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	results, err := labeling.Run(cmd.Context(), clientSets, namespace, relabelingSelector, opts, relabelingDryRun)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		slog.Warn("no Prometheus matches the selector", "namespace", namespace, "selector", relabelingSelector)
+		return nil
+	}
+
+	for _, r := range results {
+		for _, warning := range r.Warnings {
+			slog.Warn(warning, "name", r.Name, "namespace", r.Namespace)
+		}
+
+		if r.Diff == "" {
+			slog.Info("the Prometheus already has the labels", "name", r.Name, "namespace", r.Namespace)
+			continue
+		}
+
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "# Prometheus %s/%s\n%s", r.Namespace, r.Name, r.Diff); err != nil {
+			return err
+		}
+
+		if relabelingDryRun {
+			slog.Info("the Prometheus isn't updated in dry-run mode", "name", r.Name, "namespace", r.Namespace)
+			continue
+		}
+		slog.Info("Prometheus labeled", "name", r.Name, "namespace", r.Namespace)
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// kubeadmConfigMap is created by kubeadm in the kube-system namespace of the
+// clusters it sets up.
+const kubeadmConfigMap = "kubeadm-config"
+
+var (
+	controlPlaneNamespace   string
+	controlPlaneStack       string
+	controlPlaneCASecret    string
+	controlPlaneCAFile      string
+	controlPlaneCertFile    string
+	controlPlaneKeyFile     string
+	controlPlanePodSelector string
+
+	controlPlaneScrapeCmd = &cobra.Command{
+		Use:   "control-plane-scrape COMPONENT",
+		Short: "Configure the scraping of a TLS-protected control plane component.",
+		Long:  fmt.Sprintf(`Configure the scraping of a TLS-protected control plane component, one of: %s. A headless Service selects the Pods of the component, the ones set up by kubeadm by default, and a ServiceMonitor scrapes them over https. etcd authenticates Prometheus with the client certificates of a Secret, which is created from the given files. The other components authenticate Prometheus with the token of its ServiceAccount.`, strings.Join(builder.ControlPlaneComponentNames(), ", ")),
+		Example: `  # Scrape the kube-scheduler
+  poctl configure control-plane-scrape kube-scheduler
+
+  # Scrape etcd with the client certificates of kubeadm
+  poctl configure control-plane-scrape etcd --ca-secret etcd-client \
+    --ca-file /etc/kubernetes/pki/etcd/ca.crt \
+    --cert-file /etc/kubernetes/pki/etcd/healthcheck-client.crt \
+    --key-file /etc/kubernetes/pki/etcd/healthcheck-client.key`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: builder.ControlPlaneComponentNames(),
+		RunE:      runControlPlaneScrape,
+	}
+)
+
+func init() {
+	configureCmd.AddCommand(controlPlaneScrapeCmd)
+	controlPlaneScrapeCmd.Flags().StringVarP(&controlPlaneNamespace, "namespace", "n", metav1.NamespaceSystem, "Namespace of the Pods of the component")
+	controlPlaneScrapeCmd.Flags().StringVar(&controlPlaneStack, "stack", "", "Name of the stack whose Prometheus scrapes the component")
+	controlPlaneScrapeCmd.Flags().StringVar(&controlPlaneCASecret, "ca-secret", "", fmt.Sprintf("Secret holding the CA of etcd and the client certificate and key signed by it, under the %s, %s and %s keys", builder.ClientCASecretKey, builder.ClientCertSecretKey, builder.ClientKeySecretKey))
+	controlPlaneScrapeCmd.Flags().StringVar(&controlPlaneCAFile, "ca-file", "", "File of the CA of etcd, creating the --ca-secret Secret along with --cert-file and --key-file. For example, /etc/kubernetes/pki/etcd/ca.crt")
+	controlPlaneScrapeCmd.Flags().StringVar(&controlPlaneCertFile, "cert-file", "", "File of the client certificate signed by the CA of etcd. For example, /etc/kubernetes/pki/etcd/healthcheck-client.crt")
+	controlPlaneScrapeCmd.Flags().StringVar(&controlPlaneKeyFile, "key-file", "", "File of the key of the client certificate. For example, /etc/kubernetes/pki/etcd/healthcheck-client.key")
+	controlPlaneScrapeCmd.Flags().StringVar(&controlPlanePodSelector, "pod-selector", "", "Labels of the Pods of the component in the key=value,... format, required when the cluster wasn't set up by kubeadm")
+}
+
+func runControlPlaneScrape(cmd *cobra.Command, args []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	name := args[0]
+	component, ok := builder.ControlPlaneComponents[name]
+	if !ok {
+		return fmt.Errorf("unknown control plane component %q, one of: %s", name, strings.Join(builder.ControlPlaneComponentNames(), ", "))
+	}
+
+	files := []string{controlPlaneCAFile, controlPlaneCertFile, controlPlaneKeyFile}
+	withFiles := slices.ContainsFunc(files, func(f string) bool { return f != "" })
+	if component.ClientCertificates {
+		if controlPlaneCASecret == "" {
+			return fmt.Errorf("%s authenticates its clients with certificates, --ca-secret is required", name)
+		}
+		if withFiles && slices.Contains(files, "") {
+			return errors.New("--ca-file, --cert-file and --key-file must be set together")
+		}
+	} else if controlPlaneCASecret != "" || withFiles {
+		return fmt.Errorf("%s authenticates Prometheus with the token of its ServiceAccount, --ca-secret, --ca-file, --cert-file and --key-file only apply to etcd", name)
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		logger.Error("error while getting client sets", "err", err)
+		return err
+	}
+
+	ctx := cmd.Context()
+	b := builder.NewControlPlaneScrapeBuilder(controlPlaneNamespace, name).
+		WithStack(controlPlaneStack)
+
+	if controlPlanePodSelector != "" {
+		podLabels, err := labels.ConvertSelectorToLabelsMap(controlPlanePodSelector)
+		if err != nil {
+			return fmt.Errorf("invalid --pod-selector: %w", err)
+		}
+		b = b.WithPodLabels(podLabels)
+	} else {
+		kubeadm, err := isKubeadmCluster(ctx, clientSets)
+		if err != nil {
+			return err
+		}
+		if !kubeadm {
+			return fmt.Errorf("the cluster wasn't set up by kubeadm, set --pod-selector to the labels of the %s Pods", name)
+		}
+	}
+
+	if component.ClientCertificates {
+		if withFiles {
+			ca, cert, key, err := readClientCertificates(controlPlaneCAFile, controlPlaneCertFile, controlPlaneKeyFile)
+			if err != nil {
+				return err
+			}
+			b = b.WithClientCertificates(controlPlaneCASecret, ca, cert, key)
+		} else {
+			if err := checkClientCertificatesSecret(ctx, clientSets, controlPlaneNamespace, controlPlaneCASecret); err != nil {
+				return err
+			}
+			b = b.WithClientCertificatesSecret(controlPlaneCASecret)
+		}
+	}
+
+	manifests := b.WithService().
+		WithServiceMonitor().
+		Build()
+
+	if err := crds.NewValidator(clientSets.APIExtensionsClient).ValidateObjects(ctx, manifests.ServiceMonitor); err != nil {
+		return err
+	}
+
+	// The Secret is only built with the client certificates read from files.
+	err = k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).
+		Apply(ctx, manifests.Secret, manifests.Service, manifests.ServiceMonitor))
+	if err != nil {
+		return err
+	}
+
+	logger.Info("the control plane component is scraped", "component", name, "service", *manifests.Service.Name, "servicemonitor", *manifests.ServiceMonitor.Name, "port", component.Port)
+	if !component.ClientCertificates {
+		logger.Warn("kubeadm binds the component to 127.0.0.1, set its --bind-address flag to 0.0.0.0 so that Prometheus reaches it", "component", name)
+	}
+
+	return nil
+}
+
+// isKubeadmCluster reports whether the cluster was set up by kubeadm.
+func isKubeadmCluster(ctx context.Context, clientSets *k8sutil.ClientSets) (bool, error) {
+	_, err := clientSets.KClient.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, kubeadmConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error while getting ConfigMap %s: %w", kubeadmConfigMap, err)
+	}
+	return true, nil
+}
+
+// readClientCertificates reads the PEM-encoded CA, client certificate and
+// key, checking that the certificate matches the key so that a mix-up of
+// the files doesn't surface as failing scrapes.
+func readClientCertificates(caFile, certFile, keyFile string) ([]byte, []byte, []byte, error) {
+	var contents [3][]byte
+	for i, file := range []string{caFile, certFile, keyFile} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error while reading %s: %w", file, err)
+		}
+		contents[i] = data
+	}
+	ca, cert, key := contents[0], contents[1], contents[2]
+
+	if !x509.NewCertPool().AppendCertsFromPEM(ca) {
+		return nil, nil, nil, fmt.Errorf("%s holds no PEM-encoded certificate", caFile)
+	}
+
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid client certificate %s and key %s: %w", certFile, keyFile, err)
+	}
+
+	return ca, cert, key, nil
+}
+
+// checkClientCertificatesSecret checks that the Secret holds the client
+// certificates under the keys referenced by the ServiceMonitor.
+func checkClientCertificatesSecret(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, name string) error {
+	secret, err := clientSets.KClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("secret %s/%s doesn't exist, set --ca-file, --cert-file and --key-file to create it", namespace, name)
+	}
+	if err != nil {
+		return fmt.Errorf("error while getting secret %s: %w", name, err)
+	}
+
+	var missing []string
+	for _, key := range []string{builder.ClientCASecretKey, builder.ClientCertSecretKey, builder.ClientKeySecretKey} {
+		if len(secret.Data[key]) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("secret %s/%s is missing the keys: %s", namespace, name, strings.Join(missing, ", "))
+	}
+	return nil
+}
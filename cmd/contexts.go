@@ -0,0 +1,86 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/spf13/cobra"
+)
+
+var kubeContexts []string
+
+// registerContextsFlag adds the --contexts flag to commands which can run
+// against several clusters.
+func registerContextsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&kubeContexts, "contexts", nil, "Comma-separated kubeconfig contexts to run against, defaults to the current context")
+}
+
+// forEachContext runs fn with the clients of each context given by
+// --contexts, or of the current context when none is given. The output of
+// each cluster starts with a section header and the logs carry the context
+// name. A failing cluster doesn't stop the others, the errors are returned
+// together.
+func forEachContext(out io.Writer, logger *slog.Logger, fn func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error) error {
+	return forEachContextReport(out, logger, fn, nil)
+}
+
+// forEachContextReport is forEachContext calling failed, when not nil, with
+// the error of each cluster whose clients can't be created, so that the
+// reports list every cluster.
+func forEachContextReport(out io.Writer, logger *slog.Logger, fn func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error, failed func(logger *slog.Logger, err error)) error {
+	if len(kubeContexts) == 0 {
+		clientSets, err := k8sutil.GetClientSets(kubeconfig)
+		if err != nil {
+			err = fmt.Errorf("error while getting clientsets: %w", err)
+			if failed != nil {
+				failed(logger, err)
+			}
+			return err
+		}
+		return fn(logger, clientSets)
+	}
+
+	var errs []error
+	for _, kubeContext := range kubeContexts {
+		fmt.Fprintf(out, "=== Context: %s ===\n", kubeContext)
+
+		contextLogger := logger.With("context", kubeContext)
+		// The analyzers log through the default logger.
+		slog.SetDefault(contextLogger)
+
+		clientSets, err := k8sutil.GetClientSetsForContext(kubeconfig, kubeContext)
+		if err != nil {
+			err = fmt.Errorf("error while getting clientsets: %w", err)
+			if failed != nil {
+				failed(contextLogger, err)
+			}
+			errs = append(errs, fmt.Errorf("context %s: %w", kubeContext, err))
+			continue
+		}
+
+		if err := fn(contextLogger, clientSets); err != nil {
+			contextLogger.Error("error while running against context", "error", err)
+			errs = append(errs, fmt.Errorf("context %s: %w", kubeContext, err))
+		}
+	}
+
+	slog.SetDefault(logger)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/convert"
+	"github.com/spf13/cobra"
+)
+
+// convertCmd represents the convert command.
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "The convert command rewrites Prometheus Operator manifests from one API version or format to another.",
+	Long:  `The convert command in poctl rewrites Prometheus Operator manifests stored on disk, without requiring access to a Kubernetes cluster. It is meant to be used on GitOps repositories ahead of an upgrade of the Prometheus Operator CRDs, so that the manifests are already using the API versions served by the cluster.`,
+}
+
+var convertOutput string
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.PersistentFlags().StringVarP(&convertOutput, "output", "o", "", "Output format of the converted manifests, one of: yaml, json. Defaults to the format of the input, another format requires --dry-run since the files are rewritten in place")
+}
+
+// manifestFiles returns the list of YAML and JSON files found at the given path. If
+// path is a directory, it is walked recursively.
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// convertFiles applies convertFn to the manifests found at --filename. The
+// converted files are rewritten in place, or printed with --dry-run. The
+// files aren't rewritten in place in another format than theirs, since their
+// extension wouldn't match their content anymore.
+func convertFiles(cmd *cobra.Command, logger *slog.Logger, format convert.Format, convertFn func(data []byte) ([]byte, bool, error), message string) error {
+	files, err := manifestFiles(convertFilename)
+	if err != nil {
+		return fmt.Errorf("error while listing manifests: %w", err)
+	}
+
+	contents := make([][]byte, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error while reading %s: %w", file, err)
+		}
+
+		// The files are checked before any of them is rewritten.
+		if !convertDryRun && format != convert.FormatAuto && format != convert.DetectFormat(data) {
+			return fmt.Errorf("can't rewrite %s in place as %s, use --dry-run to print the converted manifests", file, format)
+		}
+		contents[i] = data
+	}
+
+	for i, file := range files {
+		data := contents[i]
+		out, converted, err := convertFn(data)
+		if err != nil {
+			return fmt.Errorf("error while converting %s: %w", file, err)
+		}
+
+		if !converted {
+			continue
+		}
+
+		if convertDryRun {
+			// JSON has no comments, the JSON manifests are printed as a
+			// stream of objects.
+			if cmp.Or(format, convert.DetectFormat(data)) == convert.FormatYAML {
+				fmt.Fprintf(cmd.OutOrStdout(), "# %s\n", file)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s", out)
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("error while reading %s: %w", file, err)
+		}
+
+		if err := os.WriteFile(file, out, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("error while writing %s: %w", file, err)
+		}
+
+		logger.Info(message, "file", file)
+	}
+
+	return nil
+}
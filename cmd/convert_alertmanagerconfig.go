@@ -0,0 +1,62 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/convert"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertFilename string
+	convertDryRun   bool
+
+	convertAlertmanagerConfigCmd = &cobra.Command{
+		Use:   "alertmanagerconfig",
+		Short: "Convert v1alpha1 AlertmanagerConfig manifests to v1beta1.",
+		Long:  `Convert v1alpha1 AlertmanagerConfig manifests to v1beta1. The files are rewritten in place, documents which aren't v1alpha1 AlertmanagerConfig objects are left untouched.`,
+		RunE:  runConvertAlertmanagerConfig,
+	}
+)
+
+func runConvertAlertmanagerConfig(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	if convertFilename == "" {
+		return errors.New("filename is required")
+	}
+
+	format, err := convert.ParseFormat(convertOutput)
+	if err != nil {
+		return err
+	}
+
+	return convertFiles(cmd, logger, format, func(data []byte) ([]byte, bool, error) {
+		return convert.AlertmanagerConfig(data, format)
+	}, "converted AlertmanagerConfig to v1beta1")
+}
+
+func init() {
+	convertCmd.AddCommand(convertAlertmanagerConfigCmd)
+	convertAlertmanagerConfigCmd.Flags().StringVarP(&convertFilename, "filename", "f", "", "File or directory containing the manifests to convert")
+	convertAlertmanagerConfigCmd.Flags().BoolVar(&convertDryRun, "dry-run", false, "Print the converted manifests instead of rewriting the files")
+}
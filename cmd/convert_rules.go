@@ -0,0 +1,88 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/convert"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertRulesTo                      string
+	convertRulesPartialResponseStrategy string
+	convertRulesTenantLabel             string
+	convertRulesTenant                  string
+
+	convertRulesCmd = &cobra.Command{
+		Use:   "rules",
+		Short: "Convert rules between Prometheus and Thanos Ruler evaluation.",
+		Long:  `Convert the rule groups of PrometheusRule manifests and plain rule files between Prometheus and Thanos Ruler evaluation. Converting to Thanos sets the partial response strategy of the groups and the tenant label of the rules, converting to Prometheus removes them. The files are rewritten in place, documents which don't contain rule groups are left untouched.`,
+		Example: `  # Convert the rules of a directory to Thanos Ruler
+  poctl convert rules -f rules/ --to thanos --partial-response-strategy warn
+
+  # Print the rules converted back to Prometheus
+  poctl convert rules -f rules/ --to prometheus --dry-run`,
+		RunE: runConvertRules,
+	}
+)
+
+func runConvertRules(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	if convertFilename == "" {
+		return errors.New("filename is required")
+	}
+
+	format, err := convert.ParseFormat(convertOutput)
+	if err != nil {
+		return err
+	}
+
+	target, err := convert.ParseRuleTarget(convertRulesTo)
+	if err != nil {
+		return err
+	}
+
+	opts := convert.RuleOptions{
+		Target:                  target,
+		PartialResponseStrategy: convertRulesPartialResponseStrategy,
+		TenantLabel:             convertRulesTenantLabel,
+		Tenant:                  convertRulesTenant,
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	return convertFiles(cmd, logger, format, func(data []byte) ([]byte, bool, error) {
+		return convert.Rules(data, format, opts)
+	}, fmt.Sprintf("converted rules for %s", target))
+}
+
+func init() {
+	convertCmd.AddCommand(convertRulesCmd)
+	convertRulesCmd.Flags().StringVarP(&convertFilename, "filename", "f", "", "File or directory containing the manifests to convert")
+	convertRulesCmd.Flags().BoolVar(&convertDryRun, "dry-run", false, "Print the converted manifests instead of rewriting the files")
+	convertRulesCmd.Flags().StringVar(&convertRulesTo, "to", "", "Rule evaluator to convert the rules for, one of: prometheus, thanos")
+	convertRulesCmd.Flags().StringVar(&convertRulesPartialResponseStrategy, "partial-response-strategy", "", "Partial response strategy set on the groups which don't define one when converting to thanos, one of: warn, abort")
+	convertRulesCmd.Flags().StringVar(&convertRulesTenantLabel, "tenant-label", "", "Name of the label identifying the tenant of the rules, added when converting to thanos and removed when converting to prometheus")
+	convertRulesCmd.Flags().StringVar(&convertRulesTenant, "tenant", "", "Value of the tenant label added to the rules when converting to thanos")
+}
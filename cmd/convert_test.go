@@ -0,0 +1,91 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/convert"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const convertTestManifest = `apiVersion: monitoring.coreos.com/v1alpha1
+kind: AlertmanagerConfig
+metadata:
+  name: test
+spec:
+  route:
+    receiver: "null"
+  receivers:
+  - name: "null"
+`
+
+// runConvertFiles converts the AlertmanagerConfigs of dir to the format and
+// returns the output of the command.
+func runConvertFiles(t *testing.T, dir string, format convert.Format, dryRun bool) (string, error) {
+	t.Helper()
+
+	defer func(filename string, dryRun bool) { convertFilename, convertDryRun = filename, dryRun }(convertFilename, convertDryRun)
+	convertFilename, convertDryRun = dir, dryRun
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+
+	err := convertFiles(cmd, slog.New(slog.NewTextHandler(io.Discard, nil)), format, func(data []byte) ([]byte, bool, error) {
+		return convert.AlertmanagerConfig(data, format)
+	}, "converted")
+	return out.String(), err
+}
+
+func TestConvertFilesFormatChange(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(convertTestManifest), 0o600))
+
+	// The YAML file isn't rewritten in place as JSON.
+	_, err := runConvertFiles(t, dir, convert.FormatJSON, false)
+	require.Error(t, err)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, convertTestManifest, string(data))
+
+	// The JSON printed with --dry-run has no comment header.
+	out, err := runConvertFiles(t, dir, convert.FormatJSON, true)
+	require.NoError(t, err)
+	assert.True(t, json.Valid([]byte(out)), "invalid JSON output: %s", out)
+
+	// The YAML printed with --dry-run names the file.
+	out, err = runConvertFiles(t, dir, convert.FormatAuto, true)
+	require.NoError(t, err)
+	assert.Contains(t, out, "# "+file+"\n")
+
+	// The file is rewritten in place in its own format.
+	_, err = runConvertFiles(t, dir, convert.FormatYAML, false)
+	require.NoError(t, err)
+
+	data, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "monitoring.coreos.com/v1beta1")
+}
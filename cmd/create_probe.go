@@ -0,0 +1,131 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	probeName      string
+	probeNamespace string
+	probeTargets   []string
+	probeModule    string
+	probeStack     string
+	probeProberURL string
+	probeInterval  string
+
+	probeCmd = &cobra.Command{
+		Use:   "probe",
+		Short: "Create a probe object",
+		Long:  `Create a probe object probing static targets through a blackbox exporter, by default the one deployed with poctl create stack --with-blackbox-exporter.`,
+		Example: `  # Probe websites over HTTP
+  poctl create probe --name website --target https://example.com --target https://example.org
+
+  # Ping a gateway every minute
+  poctl create probe --name gateway --module icmp --target 10.0.0.1 --interval 1m`,
+		RunE: runProbe,
+	}
+)
+
+func init() {
+	createCmd.AddCommand(probeCmd)
+	probeCmd.Flags().StringVar(&probeName, "name", "", "Name of the probe")
+	probeCmd.Flags().StringVarP(&probeNamespace, "namespace", "n", metav1.NamespaceDefault, "Namespace of the probe")
+	probeCmd.Flags().StringSliceVar(&probeTargets, "target", nil, "Target to probe, a URL for the http_2xx module, a host:port address for the tcp_connect module or a host for the icmp module, can be repeated")
+	probeCmd.Flags().StringVar(&probeModule, "module", builder.BlackboxExporterDefaultModule, fmt.Sprintf("Module of the blackbox exporter probing the targets, one of: %s when probing through the blackbox exporter of the stack", strings.Join(builder.BlackboxExporterModules(), ", ")))
+	probeCmd.Flags().StringVar(&probeStack, "stack", "", "Name of the stack whose Prometheus scrapes the probe and whose blackbox exporter probes the targets")
+	probeCmd.Flags().StringVar(&probeProberURL, "prober-url", "", "Address of the blackbox exporter probing the targets, defaults to the blackbox exporter of the stack")
+	probeCmd.Flags().StringVar(&probeInterval, "interval", "", "Interval between the probes, defaults to the scrape interval of Prometheus")
+	registerClusterDomainFlag(probeCmd)
+}
+
+func runProbe(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	if probeName == "" {
+		logger.Error("probe name is required")
+		return errors.New("probe name is required")
+	}
+
+	if len(probeTargets) == 0 {
+		logger.Error("at least one target is required")
+		return errors.New("at least one target is required")
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		logger.Error("error while getting client sets", "err", err)
+		return err
+	}
+
+	ctx := cmd.Context()
+	proberURL := probeProberURL
+	if proberURL == "" {
+		// The blackbox exporter of the stack only serves its own modules.
+		if !slices.Contains(builder.BlackboxExporterModules(), probeModule) {
+			return fmt.Errorf("the blackbox exporter of the stack has no module %q, use one of: %s", probeModule, strings.Join(builder.BlackboxExporterModules(), ", "))
+		}
+
+		name := builder.BlackboxExporterName(probeStack)
+		_, err := clientSets.KClient.CoreV1().Services(metav1.NamespaceDefault).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("the stack has no blackbox exporter, create it with poctl create stack --with-blackbox-exporter or set --prober-url")
+		}
+		if err != nil {
+			return fmt.Errorf("error while getting service %s: %w", name, err)
+		}
+
+		domain, err := parseClusterDomain()
+		if err != nil {
+			return err
+		}
+		proberURL = builder.BlackboxExporterProberURL(metav1.NamespaceDefault, probeStack, domain)
+	}
+
+	probe := builder.NewProbeBuilder(probeNamespace, probeName, proberURL, probeTargets...).
+		WithStack(probeStack).
+		WithModule(probeModule).
+		WithInterval(monitoringv1.Duration(probeInterval)).
+		Build()
+
+	if err := crds.NewValidator(clientSets.APIExtensionsClient).ValidateObjects(ctx, probe); err != nil {
+		logger.Error("invalid probe", "err", err)
+		return err
+	}
+
+	if err := k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, probe)); err != nil {
+		logger.Error("error while creating probe", "err", err)
+		return err
+	}
+
+	logger.Info("probe created", "probe", probeName, "namespace", probeNamespace, "prober", proberURL, "module", probeModule)
+	return nil
+}
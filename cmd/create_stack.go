@@ -15,14 +15,24 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/google/go-github/v62/github"
 	"github.com/prometheus-operator/poctl/internal/create"
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
 	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/pkg/builder"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 var (
@@ -30,12 +40,86 @@ var (
 		Use:   "stack",
 		Short: "create a stack of Prometheus Operator resources.",
 		Long:  `create a stack of Prometheus Operator resources.`,
-		RunE:  runStack,
+		Example: `  # Create the default stack
+  poctl create stack
+
+  # Create a highly available stack writing to a remote storage
+  poctl create stack --profile ha --remote-write-url=https://metrics.example.com/api/v1/write
+
+  # Create a second stack for a team
+  poctl create stack --name team-b --profile minimal`,
+		RunE: runStack,
 	}
 )
 
+var (
+	stackProfile             string
+	stackAgentMode           string
+	stackNamespaced          bool
+	stackWatchedNamespaces   []string
+	stackVerifySignatures    string
+	stackSignatureKey        string
+	stackSignatureBundle     string
+	stackName                string
+	stackAutoSize            bool
+	stackRemoteWriteURL      string
+	stackOperatorVersion     string
+	stackPrometheusVersion   string
+	stackAlertmanagerVersion string
+	stackImagePullSecrets    []string
+	stackPriorityClassName   string
+	stackTopologySpreadKey   string
+	stackAntiAffinity        string
+	stackIPFamilyPolicy      string
+	stackOverrides           []string
+	stackCRDMetrics          bool
+	stackSelfMonitoring      bool
+	stackPushgateway         bool
+	stackBlackboxExporter    bool
+	stackOTLPEndpoint        string
+	stackOTelCollectorMode   string
+	stackKubeVersion         string
+
+	stackNodeExporterCollectors         []string
+	stackNodeExporterDisabledCollectors []string
+	stackNodeExporterTextfileDir        string
+	stackNodeExporterArgs               []string
+)
+
 func init() {
 	createCmd.AddCommand(stackCmd)
+	stackCmd.Flags().StringVar(&stackAgentMode, "agent-mode", "StatefulSet", "Workload type of the PrometheusAgent, one of: StatefulSet, DaemonSet. Setting it with a profile deploying a Prometheus replaces the Prometheus by a PrometheusAgent and removes the Alertmanager")
+	stackCmd.Flags().StringVar(&stackRemoteWriteURL, "remote-write-url", "", "Remote write endpoint receiving the samples of Prometheus or of the PrometheusAgent")
+	stackCmd.Flags().BoolVar(&stackNamespaced, "namespaced", false, "Restrict the Prometheus Operator to the watched namespaces, using Roles instead of ClusterRoles")
+	stackCmd.Flags().StringSliceVar(&stackWatchedNamespaces, "watched-namespaces", []string{metav1.NamespaceDefault}, "Namespaces watched by the Prometheus Operator with --namespaced")
+	stackCmd.Flags().StringVar(&stackVerifySignatures, "verify-signatures", string(create.VerifyWarn), "Verification of the signature of the release files downloaded from GitHub, one of: warn, enforce, skip")
+	stackCmd.Flags().StringVar(&stackSignatureKey, "signature-key", "", fmt.Sprintf("PEM-encoded ECDSA or Ed25519 public key of the release, such as a cosign.pub file, verifying the signature of the %s asset of the release", create.BundleAsset))
+	stackCmd.Flags().StringVar(&stackSignatureBundle, "signature-bundle", "", fmt.Sprintf("Cosign bundle holding the signature of the %s asset of the release made with --signature-key", create.BundleAsset))
+	stackCmd.Flags().StringVar(&stackName, "name", "", "Name of the stack, prefixing the names of its objects to run several stacks in the same cluster")
+	stackCmd.Flags().BoolVar(&stackAutoSize, "auto-size", false, "Size Prometheus and kube-state-metrics from the number of nodes and Pods of the cluster, overriding the profile")
+	stackCmd.Flags().StringVar(&stackOperatorVersion, "operator-version", "", "Prometheus Operator version, overriding --version")
+	stackCmd.Flags().StringVar(&stackPrometheusVersion, "prometheus-version", "", "Version of Prometheus or of the PrometheusAgent, defaults to the version of the operator")
+	stackCmd.Flags().StringVar(&stackAlertmanagerVersion, "alertmanager-version", "", "Version of Alertmanager, defaults to the version of the operator")
+	stackCmd.Flags().StringSliceVar(&stackImagePullSecrets, "image-pull-secret", nil, "Image pull secret attached to the ServiceAccounts and the Pods of all the components, can be repeated")
+	stackCmd.Flags().StringVar(&stackPriorityClassName, "priority-class-name", "", "PriorityClass of the Pods of all the components")
+	stackCmd.Flags().StringVar(&stackTopologySpreadKey, "topology-spread-key", "", "Node label across which the replicas of each component are spread evenly. For example, topology.kubernetes.io/zone")
+	stackCmd.Flags().StringVar(&stackAntiAffinity, "anti-affinity", "", "Anti-affinity placing the replicas of each component on different nodes, one of: soft, hard")
+	stackCmd.Flags().StringVar(&stackIPFamilyPolicy, "ip-family-policy", "", "IP family policy of the Services of all the components, one of: SingleStack, PreferDualStack, RequireDualStack. Defaults to the policy of the cluster")
+	stackCmd.Flags().BoolVar(&stackCRDMetrics, "with-crd-metrics", false, "Expose the state of the Prometheus, PrometheusAgent and Alertmanager resources with kube-state-metrics, along with alerts on these metrics")
+	stackCmd.Flags().BoolVar(&stackSelfMonitoring, "with-self-monitoring-alerts", false, "Alert on the reconcile errors and the failing node syncs of the Prometheus Operator, and on the failures of the config-reloaders")
+	stackCmd.Flags().BoolVar(&stackPushgateway, "with-pushgateway", false, "Deploy a Pushgateway receiving the metrics of the batch jobs, scraped with honorLabels and alerting on the stale push groups")
+	stackCmd.Flags().BoolVar(&stackBlackboxExporter, "with-blackbox-exporter", false, "Deploy a blackbox exporter probing the targets of the Probes created with poctl create probe over HTTP, TCP and ICMP")
+	stackCmd.Flags().StringSliceVar(&stackNodeExporterCollectors, "node-exporter-collectors", nil, "Collectors of the node exporter to enable, such as systemd or processes, can be repeated. The systemd collector gets the D-Bus socket of the nodes")
+	stackCmd.Flags().StringSliceVar(&stackNodeExporterDisabledCollectors, "node-exporter-disable-collectors", nil, "Collectors of the node exporter to disable, can be repeated")
+	stackCmd.Flags().StringVar(&stackNodeExporterTextfileDir, "node-exporter-textfile-dir", "", "Directory of the nodes from which the textfile collector of the node exporter reads the *.prom files, mounted into its Pods")
+	stackCmd.Flags().StringArrayVar(&stackNodeExporterArgs, "node-exporter-arg", nil, "Argument appended to the arguments of the node exporter, such as --collector.systemd.unit-include=kubelet.service, can be repeated")
+	stackCmd.Flags().StringVar(&stackOTLPEndpoint, "otlp-endpoint", "", "Deploy an OpenTelemetry Collector exporting the samples of the stack to this OTLP endpoint, an http or https URL for OTLP over HTTP or a host:port address for OTLP over gRPC")
+	stackCmd.Flags().StringVar(&stackOTelCollectorMode, "otel-collector-mode", "remote-write", "How the OpenTelemetry Collector gets the samples of the stack, one of: remote-write, federate")
+	stackCmd.Flags().StringArrayVar(&stackOverrides, "set", nil, fmt.Sprintf("Override of a field of a component in the <component>.<path>=<value> format, such as prometheus.spec.retention=30d, can be repeated. The component is one of: %s", strings.Join(create.OverrideComponents, ", ")))
+	stackCmd.Flags().StringVar(&stackKubeVersion, "kube-version", "", fmt.Sprintf("Kubernetes version targeted by the manifests, such as 1.22, defaults to the version of each cluster. The oldest supported version is %s", builder.MinKubeVersion))
+	registerContextsFlag(stackCmd)
+	registerClusterDomainFlag(stackCmd)
+	stackCmd.Flags().StringVar(&stackProfile, "profile", create.DefaultProfile, fmt.Sprintf("Profile of the stack, one of: %s", strings.Join(create.ProfileNames(), ", ")))
 
 	// Here you will define your flags and configuration settings.
 
@@ -51,7 +135,7 @@ func init() {
 func runStack(cmd *cobra.Command, _ []string) error {
 	logger, err := log.NewLogger()
 	if err != nil {
-		return fmt.Errorf("error while creating logger: %v", err)
+		return fmt.Errorf("error while creating logger: %w", err)
 	}
 
 	version, err := cmd.Flags().GetString("version")
@@ -60,20 +144,271 @@ func runStack(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	if stackOperatorVersion != "" {
+		if cmd.Flags().Changed("version") && version != stackOperatorVersion {
+			return fmt.Errorf("--version %s and --operator-version %s are conflicting", version, stackOperatorVersion)
+		}
+		version = stackOperatorVersion
+	}
+
+	if version, err = create.ParseVersion(version); err != nil {
+		return fmt.Errorf("invalid operator version: %w", err)
+	}
+
 	logger.Info(version)
 
-	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	profile, err := create.GetProfile(stackProfile)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(stackAgentMode) {
+	case "statefulset":
+	case "daemonset":
+		profile.AgentDaemonSet = true
+	default:
+		return fmt.Errorf("unknown agent mode %s, must be one of: StatefulSet, DaemonSet", stackAgentMode)
+	}
+
+	if cmd.Flags().Changed("agent-mode") && !profile.Agent {
+		if stackRemoteWriteURL == "" {
+			return fmt.Errorf("--agent-mode with the %s profile requires --remote-write-url, the PrometheusAgent doesn't store samples", profile.Name)
+		}
+		profile = profile.AsAgent()
+	}
+
+	if stackRemoteWriteURL != "" {
+		u, err := url.Parse(stackRemoteWriteURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid remote write URL %s, must be an http or https URL", stackRemoteWriteURL)
+		}
+		profile.RemoteWriteURL = stackRemoteWriteURL
+	}
+
+	if stackPrometheusVersion != "" {
+		if profile.PrometheusVersion, err = create.ParseVersion(stackPrometheusVersion); err != nil {
+			return fmt.Errorf("invalid Prometheus version: %w", err)
+		}
+	}
+
+	if stackAlertmanagerVersion != "" {
+		if !profile.Alertmanager {
+			return fmt.Errorf("--alertmanager-version is set but the stack has no Alertmanager")
+		}
+		if profile.AlertmanagerVersion, err = create.ParseVersion(stackAlertmanagerVersion); err != nil {
+			return fmt.Errorf("invalid Alertmanager version: %w", err)
+		}
+	}
+
+	if stackCRDMetrics {
+		if !profile.KubeStateMetrics {
+			return fmt.Errorf("--with-crd-metrics is set but the stack has no kube-state-metrics")
+		}
+		profile.KubeStateMetricsCRDMetrics = true
+	}
+
+	if stackSelfMonitoring {
+		if profile.Agent {
+			return fmt.Errorf("--with-self-monitoring-alerts requires a Prometheus, the PrometheusAgent doesn't evaluate rules")
+		}
+		profile.SelfMonitoringAlerts = true
+	}
+
+	if slices.ContainsFunc([]string{"node-exporter-collectors", "node-exporter-disable-collectors", "node-exporter-textfile-dir", "node-exporter-arg"}, cmd.Flags().Changed) {
+		if !profile.NodeExporter {
+			return fmt.Errorf("--node-exporter-* flags are set but the %s profile has no node exporter", profile.Name)
+		}
+
+		profile.NodeExporterCollectors = builder.NodeExporterCollectors{
+			Enable:            stackNodeExporterCollectors,
+			Disable:           stackNodeExporterDisabledCollectors,
+			TextfileDirectory: stackNodeExporterTextfileDir,
+		}
+		if err := profile.NodeExporterCollectors.Validate(); err != nil {
+			return err
+		}
+		profile.NodeExporterArgs = stackNodeExporterArgs
+	}
+
+	profile.Pushgateway = stackPushgateway
+	profile.BlackboxExporter = stackBlackboxExporter
+
+	if stackOTLPEndpoint != "" {
+		if err := validateOTLPEndpoint(stackOTLPEndpoint); err != nil {
+			return err
+		}
+		profile.OTLPEndpoint = stackOTLPEndpoint
+	} else if cmd.Flags().Changed("otel-collector-mode") {
+		return fmt.Errorf("--otel-collector-mode requires --otlp-endpoint")
+	}
+
+	switch stackOTelCollectorMode {
+	case "remote-write":
+	case "federate":
+		if profile.Agent {
+			return fmt.Errorf("--otel-collector-mode federate requires a Prometheus, the PrometheusAgent doesn't serve the /federate endpoint")
+		}
+		profile.OTelCollectorFederation = stackOTLPEndpoint != ""
+	default:
+		return fmt.Errorf("unknown OpenTelemetry Collector mode %s, must be one of: remote-write, federate", stackOTelCollectorMode)
+	}
+
+	for _, name := range stackImagePullSecrets {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid image pull secret %s: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	profile.ImagePullSecrets = stackImagePullSecrets
+
+	antiAffinity, err := builder.ParseAntiAffinity(strings.ToLower(stackAntiAffinity))
 	if err != nil {
-		logger.Error("error while getting client sets", "err", err)
 		return err
 	}
 
+	if errs := validation.IsDNS1123Subdomain(stackPriorityClassName); stackPriorityClassName != "" && len(errs) > 0 {
+		return fmt.Errorf("invalid priority class name %s: %s", stackPriorityClassName, strings.Join(errs, ", "))
+	}
+
+	if errs := validation.IsQualifiedName(stackTopologySpreadKey); stackTopologySpreadKey != "" && len(errs) > 0 {
+		return fmt.Errorf("invalid topology spread key %s: %s", stackTopologySpreadKey, strings.Join(errs, ", "))
+	}
+
+	profile.Scheduling = builder.Scheduling{
+		PriorityClassName: stackPriorityClassName,
+		TopologySpreadKey: stackTopologySpreadKey,
+		AntiAffinity:      antiAffinity,
+	}
+
+	if profile.IPFamilyPolicy, err = builder.ParseIPFamilyPolicy(stackIPFamilyPolicy); err != nil {
+		return err
+	}
+
+	if stackNamespaced {
+		if len(stackWatchedNamespaces) == 0 {
+			return fmt.Errorf("--namespaced requires at least one watched namespace")
+		}
+		profile.WatchedNamespaces = stackWatchedNamespaces
+	}
+
+	if errs := validation.IsDNS1123Label(stackName); stackName != "" && len(errs) > 0 {
+		return fmt.Errorf("invalid stack name %s: %s", stackName, strings.Join(errs, ", "))
+	}
+	profile.Stack = stackName
+
+	if profile.ClusterDomain, err = parseClusterDomain(); err != nil {
+		return err
+	}
+
+	if profile.Overrides, err = create.ParseOverrides(stackOverrides); err != nil {
+		return err
+	}
+
+	if profile.KubeVersion, err = builder.ParseKubeVersion(stackKubeVersion); err != nil {
+		return err
+	}
+
+	verification := create.Verification{}
+	if verification.Mode, err = create.ParseVerifyMode(stackVerifySignatures); err != nil {
+		return err
+	}
+
+	if stackSignatureKey != "" {
+		data, err := os.ReadFile(stackSignatureKey)
+		if err != nil {
+			return fmt.Errorf("error while reading signature key: %w", err)
+		}
+
+		if verification.Key, err = create.ParseSignatureKey(data); err != nil {
+			return fmt.Errorf("invalid signature key %s: %w", stackSignatureKey, err)
+		}
+	}
+
+	if (stackSignatureKey == "") != (stackSignatureBundle == "") {
+		return fmt.Errorf("--signature-key and --signature-bundle must be given together")
+	}
+
+	if stackSignatureBundle != "" {
+		data, err := os.ReadFile(stackSignatureBundle)
+		if err != nil {
+			return fmt.Errorf("error while reading signature bundle: %w", err)
+		}
+
+		if verification.Signature, err = create.ParseSignatureBundle(data); err != nil {
+			return fmt.Errorf("invalid signature bundle %s: %w", stackSignatureBundle, err)
+		}
+	}
+
 	gitHubClient := github.NewClient(nil)
 
-	if err := create.RunCreateStack(context.Background(), logger, clientSets, gitHubClient, version); err != nil {
-		logger.Error("error while creating Prometheus Operator stack", "err", err)
+	return forEachContext(cmd.OutOrStdout(), logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		// Each cluster is sized and targeted on its own.
+		profile := profile
+		if profile.KubeVersion == "" {
+			info, err := clientSets.KClient.Discovery().ServerVersion()
+			if err != nil {
+				logger.Error("error while getting the Kubernetes version", "err", err)
+				return err
+			}
+
+			if profile.KubeVersion, err = builder.ParseKubeVersion(info.GitVersion); err != nil {
+				return err
+			}
+		}
+
+		if stackAutoSize {
+			size, err := create.MeasureCluster(cmd.Context(), clientSets)
+			if err != nil {
+				logger.Error("error while measuring the cluster", "err", err)
+				return err
+			}
+
+			sizing := create.RecommendSizing(size)
+			logger.Info("sizing the stack", "nodes", size.Nodes, "pods", size.Pods, "series", sizing.Series, "cpu", sizing.PrometheusResources.Cpu(), "memory", sizing.PrometheusResources.Memory(), "retention", sizing.PrometheusRetention, "kube-state-metrics-shards", sizing.KubeStateMetricsShards)
+			profile.ApplySizing(sizing)
+		}
+
+		summary, err := create.RunCreateStack(cmd.Context(), logger, clientSets, gitHubClient, version, verification, profile)
+		if printErr := printStackSummary(cmd.OutOrStdout(), summary); printErr != nil {
+			return printErr
+		}
+
+		if err != nil {
+			logger.Error("error while creating Prometheus Operator stack", "err", err)
+			return err
+		}
+
+		logger.Info("Prometheus Operator stack created successfully.")
+		return nil
+	})
+}
+
+// validateOTLPEndpoint checks that the OTLP endpoint is either an http or
+// https URL, for OTLP over HTTP, or a host:port address, for OTLP over gRPC.
+func validateOTLPEndpoint(endpoint string) error {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid OTLP endpoint %s, must be an http or https URL or a host:port address", endpoint)
+		}
+		return nil
 	}
 
-	logger.Info("Prometheus Operator stack created successfully.")
+	if host, port, err := net.SplitHostPort(endpoint); err != nil || host == "" || port == "" {
+		return fmt.Errorf("invalid OTLP endpoint %s, must be an http or https URL or a host:port address", endpoint)
+	}
 	return nil
 }
+
+// printStackSummary prints the outcome of each component of the stack.
+func printStackSummary(out io.Writer, summary *create.Summary) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tSTATUS\tERROR")
+	for _, c := range summary.Components {
+		errMsg := "-"
+		if c.Err != nil {
+			errMsg = c.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Status, errMsg)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var deleteStackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "delete the stack of Prometheus Operator resources.",
+	Long:  `delete the stack of Prometheus Operator resources created by the create stack command. The CRDs are left in place since deleting them would delete every custom resource of the cluster.`,
+	RunE:  runDeleteStack,
+}
+
+var deleteStackName string
+
+func init() {
+	deleteCmd.AddCommand(deleteStackCmd)
+	deleteStackCmd.Flags().StringVar(&deleteStackName, "name", "", "Name of the stack to delete, defaults to the unnamed stack")
+}
+
+func runDeleteStack(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		logger.Error("error while getting client sets", "err", err)
+		return err
+	}
+
+	if err := create.RunDeleteStack(cmd.Context(), logger, clientSets, deleteStackName); err != nil {
+		logger.Error("error while deleting Prometheus Operator stack", "err", err)
+		return err
+	}
+
+	logger.Info("Prometheus Operator stack deleted successfully.")
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/diffenv"
+	"github.com/prometheus-operator/poctl/internal/explain"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var diffEnvCmd = &cobra.Command{
+	Use:   "diff-env",
+	Short: "Compare the spec of the same object across namespaces and clusters.",
+	Long:  `Compare the spec of the same Prometheus Operator object across namespaces and kubeconfig contexts, for example to find why production behaves differently from staging. The fields set to the default value of the CRD schema are ignored, only the fields whose values differ are printed.`,
+	Example: `  # Compare a Prometheus across namespaces
+  poctl diff-env --kind prometheus --name prom --namespaces staging,production
+
+  # Compare an Alertmanager across clusters
+  poctl diff-env --kind alertmanager --name main --namespaces monitoring --contexts staging,production`,
+	RunE: runDiffEnv,
+}
+
+var diffEnvFlags struct {
+	Kind       string
+	Name       string
+	Namespaces []string
+	Contexts   []string
+}
+
+func init() {
+	rootCmd.AddCommand(diffEnvCmd)
+	diffEnvCmd.Flags().StringVarP(&diffEnvFlags.Kind, "kind", "k", "", "The kind of object to compare. For example, Prometheus")
+	diffEnvCmd.Flags().StringVarP(&diffEnvFlags.Name, "name", "n", "", "The name of the object to compare")
+	diffEnvCmd.Flags().StringSliceVar(&diffEnvFlags.Namespaces, "namespaces", nil, "Comma-separated namespaces of the object")
+	diffEnvCmd.Flags().StringSliceVar(&diffEnvFlags.Contexts, "contexts", nil, "Comma-separated kubeconfig contexts of the object, defaults to the current context")
+	_ = diffEnvCmd.MarkFlagRequired("kind")
+	_ = diffEnvCmd.MarkFlagRequired("name")
+	_ = diffEnvCmd.MarkFlagRequired("namespaces")
+}
+
+func runDiffEnv(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	crdName, err := explain.ResolveCRDName(diffEnvFlags.Kind)
+	if err != nil {
+		return err
+	}
+
+	contexts := diffEnvFlags.Contexts
+	if len(contexts) == 0 {
+		contexts = []string{""}
+	}
+
+	var environments []diffenv.Environment
+	for _, kubeContext := range contexts {
+		for _, namespace := range diffEnvFlags.Namespaces {
+			environments = append(environments, diffenv.Environment{Context: kubeContext, Namespace: namespace})
+		}
+	}
+
+	if len(environments) < 2 {
+		return fmt.Errorf("at least two namespaces or contexts are required")
+	}
+
+	clientSets := map[string]*k8sutil.ClientSets{}
+	specs := make([]map[string]any, 0, len(environments))
+	for _, env := range environments {
+		cs, ok := clientSets[env.Context]
+		if !ok {
+			cs, err = k8sutil.GetClientSetsForContext(kubeconfig, env.Context)
+			if err != nil {
+				return fmt.Errorf("error while getting clientsets: %w", err)
+			}
+			clientSets[env.Context] = cs
+		}
+
+		spec, err := diffenv.GetSpec(cmd.Context(), cs, crdName, diffEnvFlags.Name, env.Namespace)
+		if err != nil {
+			return fmt.Errorf("%s: %w", env, err)
+		}
+		specs = append(specs, spec)
+	}
+
+	differences := diffenv.Compare(specs)
+	if len(differences) == 0 {
+		slog.Info("specs are identical", "kind", diffEnvFlags.Kind, "name", diffEnvFlags.Name)
+		return nil
+	}
+
+	header := []string{"FIELD"}
+	for _, env := range environments {
+		header = append(header, env.String())
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, d := range differences {
+		fmt.Fprintln(w, strings.Join(append([]string{d.Path}, d.Values...), "\t"))
+	}
+
+	return w.Flush()
+}
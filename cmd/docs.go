@@ -0,0 +1,72 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/clidocs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	docsDir    string
+	docsFormat string
+
+	docsCmd = &cobra.Command{
+		Use:    "docs",
+		Short:  "Generate the man pages or the Markdown reference of the commands.",
+		Long:   `Generate the man pages or the Markdown reference of the commands of poctl in a directory, a file per command, so that the distributions can package the manuals along with the binary.`,
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return clidocs.GenTree(rootCmd, docsDir, docsFormat)
+		},
+	}
+
+	completionManDir string
+
+	completionManCmd = &cobra.Command{
+		Use:   "man",
+		Short: "Generate the man pages of the commands",
+		Long:  `Generate the man pages of the commands of poctl in a directory, a page per command in section 1.`,
+		Example: `  # Install the man pages of poctl
+  poctl completion man --dir /usr/local/share/man/man1`,
+		Args: cobra.NoArgs,
+		RunE: func(*cobra.Command, []string) error {
+			return clidocs.GenTree(rootCmd, completionManDir, clidocs.FormatMan)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+	docsCmd.Flags().StringVar(&docsDir, "dir", "docs", "Directory in which the documentation is written")
+	docsCmd.Flags().StringVar(&docsFormat, "format", clidocs.FormatMan, fmt.Sprintf("Format of the documentation, one of: %s, %s", clidocs.FormatMan, clidocs.FormatMarkdown))
+
+	completionManCmd.Flags().StringVar(&completionManDir, "dir", "man", "Directory in which the man pages are written")
+}
+
+// addCompletionManCmd adds the man command to the completion command,
+// which cobra only creates once all the commands are added.
+func addCompletionManCmd() {
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == "completion" {
+			c.AddCommand(completionManCmd)
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/doctor"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Run quick checks of the monitoring stack and print a summary.",
+	Long:  `The doctor command runs a curated set of fast checks of the monitoring stack: the cluster is reachable, the Prometheus Operator CRDs are installed and served, the operator is running, its version matches the CRDs, it reconciles the Prometheus objects and at least one Prometheus is available. It is the first command to run when monitoring is broken, the analyze command then gives the details of a given object.`,
+	Example: `  # Check the monitoring stack of the current context
+  poctl doctor`,
+	RunE: runDoctor,
+}
+
+var doctorTimeout time.Duration
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 10*time.Second, "Maximum duration of the checks of each cluster")
+	registerContextsFlag(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(_ *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), doctorTimeout)
+		defer cancel()
+
+		checks := doctor.Run(ctx, clientSets)
+
+		failed := 0
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tCHECK\tDETAIL")
+		for _, check := range checks {
+			if check.Status == doctor.Fail {
+				failed++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Status, check.Name, check.Detail)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d checks failed", failed)
+		}
+		return nil
+	})
+}
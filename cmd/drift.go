@@ -0,0 +1,101 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Detect the manual changes of a stack created by poctl.",
+	Long:  `The drift command renders the desired state of a stack with the parameters recorded when it was created, and compares it with the live objects. It reports the objects which are missing and the fields which have been changed manually, for example replicas bumped directly in the cluster. With --repair, the desired state of the drifted objects is applied again.`,
+	Example: `  # Detect the manual changes of the default stack
+  poctl drift
+
+  # Apply again the desired state of the drifted objects of a stack
+  poctl drift --name team-a --repair`,
+	RunE: runDrift,
+}
+
+var (
+	driftStack  string
+	driftRepair bool
+)
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+	driftCmd.Flags().StringVar(&driftStack, "name", "", "Name of the stack, the default stack when not set")
+	driftCmd.Flags().BoolVar(&driftRepair, "repair", false, "Apply the desired state of the drifted objects")
+	registerContextsFlag(driftCmd)
+}
+
+func runDrift(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		drifts, err := create.RunDrift(cmd.Context(), clientSets, driftStack)
+		if err != nil {
+			return err
+		}
+
+		if len(drifts) == 0 {
+			logger.Info("no drift detected", "stack", create.StackAnchor(driftStack))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tFIELD\tDESIRED\tLIVE")
+		for _, drift := range drifts {
+			namespace := drift.Namespace
+			if namespace == "" {
+				namespace = "-"
+			}
+
+			if drift.Missing {
+				fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\t%s\n", drift.Kind, namespace, drift.Name, create.Missing)
+				continue
+			}
+
+			for _, field := range drift.Fields {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", drift.Kind, namespace, drift.Name, field.Path, field.Desired, field.Live)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if !driftRepair {
+			return fmt.Errorf("%d objects drifted", len(drifts))
+		}
+
+		if err := create.RepairDrift(cmd.Context(), clientSets, drifts); err != nil {
+			return err
+		}
+
+		logger.Info("repaired drifted objects", "count", len(drifts))
+		return nil
+	})
+}
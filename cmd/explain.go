@@ -0,0 +1,89 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/prometheus-operator/poctl/internal/explain"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain RESOURCE[.FIELD...]",
+	Short: "Show the documentation of the fields of the Prometheus Operator resources.",
+	Long:  `Show the documentation and the type of the fields of the Prometheus Operator resources, for example prometheus.spec.remoteWrite.queueConfig. The documentation is read from the CRD schema installed in the cluster, which matches the version of the operator in use, or from the CRD released with the operator version given by --version.`,
+	Example: `  # Show the documentation of the queue configuration of remote write
+  poctl explain prometheus.spec.remoteWrite.queueConfig
+
+  # Show the documentation of a field as released with an operator version
+  poctl explain servicemonitor.spec.endpoints --version 0.75.1`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExplain,
+}
+
+var (
+	explainVersion    string
+	explainAPIVersion string
+)
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	explainCmd.Flags().StringVar(&explainVersion, "version", "", "Prometheus Operator version of the released CRD to read instead of the installed one, for example 0.75.1")
+	explainCmd.Flags().StringVar(&explainAPIVersion, "api-version", "", "Version of the resource API, defaults to the storage version of the CRD")
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	path := strings.Split(strings.Trim(args[0], "."), ".")
+	name, err := explain.ResolveCRDName(path[0])
+	if err != nil {
+		return err
+	}
+
+	var crd *apiextensionsv1.CustomResourceDefinition
+	if explainVersion != "" {
+		crd, err = explain.ReleasedCRD(cmd.Context(), github.NewClient(nil), name, explainVersion)
+	} else {
+		var clientSets *k8sutil.ClientSets
+		clientSets, err = k8sutil.GetClientSets(kubeconfig)
+		if err != nil {
+			return fmt.Errorf("error while getting clientsets: %w", err)
+		}
+		crd, err = explain.InstalledCRD(cmd.Context(), clientSets, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	field, err := explain.Explain(crd, explainAPIVersion, path[1:])
+	if err != nil {
+		return err
+	}
+
+	return explain.Render(cmd.OutOrStdout(), field)
+}
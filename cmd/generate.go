@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// generateCmd represents the generate command.
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "The generate command produces documents from the Prometheus Operator resources.",
+	Long:  `The generate command in poctl reads the Prometheus Operator resources of the cluster and produces documents out of them, to be reviewed or published.`,
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/catalog"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/spf13/cobra"
+)
+
+var (
+	generateDocsNamespace string
+	generateDocsOutput    string
+
+	generateDocsCmd = &cobra.Command{
+		Use:   "docs",
+		Short: "Generate a catalog of the alerts and scrape targets of a namespace.",
+		Long:  `Generate a human-readable catalog of the alerts defined by the PrometheusRules of a namespace, with their severity, expression and runbook, and of the scrape targets of its ServiceMonitors, PodMonitors, Probes and ScrapeConfigs. The catalog is written to the standard output in Markdown or HTML, to be shared in review meetings or published on a runbook site.`,
+		RunE:  runGenerateDocs,
+	}
+)
+
+func init() {
+	generateCmd.AddCommand(generateDocsCmd)
+	generateDocsCmd.Flags().StringVarP(&generateDocsNamespace, "namespace", "n", "default", "Namespace of the resources to document")
+	generateDocsCmd.Flags().StringVarP(&generateDocsOutput, "output", "o", catalog.FormatMarkdown, fmt.Sprintf("Output format of the catalog, one of: %s, %s", catalog.FormatMarkdown, catalog.FormatHTML))
+}
+
+func runGenerateDocs(cmd *cobra.Command, _ []string) error {
+	if generateDocsOutput != catalog.FormatMarkdown && generateDocsOutput != catalog.FormatHTML {
+		return fmt.Errorf("invalid --output %q, one of: %s, %s", generateDocsOutput, catalog.FormatMarkdown, catalog.FormatHTML)
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	c, err := catalog.Build(cmd.Context(), clientSets, generateDocsNamespace)
+	if err != nil {
+		return err
+	}
+
+	return catalog.Render(cmd.OutOrStdout(), c, generateDocsOutput)
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// getCmd represents the get command.
+var getCmd = &cobra.Command{
+	Use:   "get",
+	Short: "The get command displays the Prometheus Operator resources managed by poctl and the firing alerts.",
+	Long:  `The get command in poctl displays the Prometheus Operator resources created by the create command, relying on the labels set at creation time to find them, and the alerts currently firing along with the PrometheusRules defining them.`,
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+}
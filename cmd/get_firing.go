@@ -0,0 +1,136 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	"github.com/prometheus-operator/poctl/internal/report"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var (
+	firingAlertmanager    string
+	firingAlertmanagerURL string
+	firingPrometheus      string
+	firingPrometheusURL   string
+	firingNamespace       string
+
+	firingCmd = &cobra.Command{
+		Use:   "firing",
+		Short: "List the alerts currently firing.",
+		Long:  `List the alerts currently firing, from the Alertmanager API along with their silence status, or from the Prometheus API when Alertmanager can't be reached. Each alert is listed with the PrometheusRules defining it.`,
+		Example: `  # List the firing alerts of the default Alertmanager
+  poctl get firing
+
+  # List the firing alerts through a port-forward to Alertmanager
+  poctl get firing --alertmanager-url http://localhost:9093`,
+		RunE: runGetFiring,
+	}
+)
+
+func init() {
+	getCmd.AddCommand(firingCmd)
+	firingCmd.Flags().StringVar(&firingAlertmanager, "alertmanager", builder.AlertManagerName, "Name of the Alertmanager to get the alerts from")
+	firingCmd.Flags().StringVar(&firingAlertmanagerURL, "alertmanager-url", "", "URL of the Alertmanager API, for instance through a port-forward, instead of the API server proxy")
+	firingCmd.Flags().StringVar(&firingPrometheus, "prometheus", "prometheus", "Name of the Prometheus to get the alerts from when Alertmanager can't be reached")
+	firingCmd.Flags().StringVar(&firingPrometheusURL, "prometheus-url", "", "URL of the Prometheus API, for instance through a port-forward, instead of the API server proxy")
+	firingCmd.Flags().StringVarP(&firingNamespace, "namespace", "n", "default", "Namespace of the Prometheus and the Alertmanager")
+}
+
+func runGetFiring(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	var prometheus, alertmanager promapi.Client
+	if firingAlertmanagerURL != "" {
+		alertmanager = promapi.NewURLClient(firingAlertmanagerURL)
+	} else {
+		alertmanager, err = promapi.AlertmanagerClient(cmd.Context(), clientSets, firingAlertmanager, firingNamespace)
+		if err != nil {
+			slog.Warn("the alerts are taken from Prometheus and their silence status is unknown", "err", err)
+			alertmanager = nil
+		}
+	}
+
+	if alertmanager == nil {
+		prometheus = promapi.NewURLClient(firingPrometheusURL)
+		if firingPrometheusURL == "" {
+			clients, err := promapi.PrometheusClients(cmd.Context(), clientSets, firingPrometheus, firingNamespace)
+			if err != nil {
+				return err
+			}
+
+			if len(clients) > 1 {
+				slog.Warn("Prometheus is sharded, the alerts are evaluated by each shard and only one is queried", "name", firingPrometheus, "namespace", firingNamespace)
+			}
+			prometheus = clients[0]
+		}
+	}
+
+	var prometheusRules []monitoringv1.PrometheusRule
+	err = k8sutil.EachListItem(cmd.Context(), "PrometheusRules", clientSets.MClient.MonitoringV1().PrometheusRules(metav1.NamespaceAll).List, metav1.ListOptions{}, func(pr *monitoringv1.PrometheusRule) error {
+		prometheusRules = append(prometheusRules, *pr)
+		return nil
+	})
+	if err != nil {
+		slog.Warn("the PrometheusRules defining the alerts are unknown", "err", err)
+	}
+
+	alerts, err := report.Firing(cmd.Context(), prometheus, alertmanager, prometheusRules)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ALERT\tNAMESPACE\tSEVERITY\tRULES\tSTATE\tAGE\tLABELS")
+	for _, a := range alerts {
+		state := a.State
+		if len(a.SilencedBy) > 0 {
+			state = fmt.Sprintf("%s by %s", a.State, strings.Join(a.SilencedBy, ","))
+		}
+
+		age := "-"
+		if !a.Since.IsZero() {
+			age = duration.HumanDuration(now.Sub(a.Since))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, cmp.Or(a.Namespace, "-"), cmp.Or(a.Severity, "-"), cmp.Or(strings.Join(a.Rules, ","), "-"), state, age, cmp.Or(report.FormatLabels(a.Labels), "-"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/inventory"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inventoryStack string
+
+	inventoryCmd = &cobra.Command{
+		Use:   "inventory",
+		Short: "List the resources of the stacks created by poctl.",
+		Long:  `List the resources of the stacks created by poctl, with the version of the component they run, the last time poctl applied them and whether they drifted: another client changed them since poctl applied them.`,
+		Example: `  # List the resources of all the stacks
+  poctl get inventory
+
+  # List the resources of a stack
+  poctl get inventory --stack team-a`,
+		RunE: runGetInventory,
+	}
+)
+
+func init() {
+	getCmd.AddCommand(inventoryCmd)
+	inventoryCmd.Flags().StringVar(&inventoryStack, "stack", "", "Name of the stack to list, all the stacks are listed when not set")
+	registerContextsFlag(inventoryCmd)
+}
+
+func runGetInventory(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	allStacks := !cmd.Flags().Changed("stack")
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(_ *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		resources, err := inventory.List(cmd.Context(), clientSets, inventoryStack, allStacks)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "STACK\tKIND\tNAMESPACE\tNAME\tVERSION\tAPPLIED\tDRIFT")
+		for _, r := range resources {
+			applied := "-"
+			if !r.Applied.IsZero() {
+				applied = r.Applied.Format(time.RFC3339)
+			}
+
+			drift := "-"
+			if len(r.DriftedBy) > 0 {
+				drift = fmt.Sprintf("modified by %s", strings.Join(r.DriftedBy, ", "))
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Stack, r.Kind, cmp.Or(r.Namespace, "-"), r.Name, cmp.Or(r.Version, "-"), applied, drift)
+		}
+		return w.Flush()
+	})
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// installCmd represents the install command.
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "The install command deploys poctl components in the cluster.",
+	Long:  `The install command deploys poctl components in the cluster, such as the verifier which runs the analyzers on a schedule.`,
+}
+
+func init() {
+	rootCmd.AddCommand(installCmd)
+}
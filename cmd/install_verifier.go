@@ -0,0 +1,73 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	verifierNamespace string
+	verifierImage     string
+	verifierSchedule  string
+	verifierSecrets   bool
+	verifierPushURL   string
+
+	installVerifierCmd = &cobra.Command{
+		Use:   "verifier",
+		Short: "Deploy poctl as a CronJob running the analyzers on a schedule.",
+		Long:  `Deploy poctl as a CronJob running the analyzers on a schedule. The CronJob runs the verify command with a ServiceAccount which can only read the analyzed objects and record Events, the objects failing the analysis get a Warning Event. The ServiceAccount can't read the Secrets unless --read-secrets is set, the checks of the content of the Secrets being skipped with a warning. With --pushgateway-url, the metrics of the runs are pushed to a Pushgateway.`,
+		Example: `  # Analyze the cluster every hour
+  poctl install verifier --image registry.example.com/poctl:latest --schedule '0 * * * *' -n monitoring
+
+  # Check the Alertmanager and Thanos Secrets too, and push the metrics of the runs
+  poctl install verifier --image registry.example.com/poctl:latest -n monitoring --read-secrets --pushgateway-url http://pushgateway.monitoring.svc:9091`,
+		Args: cobra.NoArgs,
+		RunE: runInstallVerifier,
+	}
+)
+
+func runInstallVerifier(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	if verifierImage == "" {
+		return fmt.Errorf("--image is required")
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	return create.RunInstallVerifier(cmd.Context(), logger, clientSets, verifierNamespace, verifierImage, verifierSchedule, verifierSecrets, verifierPushURL)
+}
+
+func init() {
+	installCmd.AddCommand(installVerifierCmd)
+	installVerifierCmd.Flags().StringVarP(&verifierNamespace, "namespace", "n", metav1.NamespaceDefault, "Namespace of the CronJob")
+	installVerifierCmd.Flags().StringVar(&verifierImage, "image", "", "Container image of poctl run by the CronJob")
+	installVerifierCmd.Flags().StringVar(&verifierSchedule, "schedule", "0 * * * *", "Schedule of the CronJob, in cron format")
+	installVerifierCmd.Flags().BoolVar(&verifierSecrets, "read-secrets", false, "Allow the verifier to get the Secrets of the cluster, to check the configuration Secrets of Alertmanager and the object storage Secrets of Thanos")
+	installVerifierCmd.Flags().StringVar(&verifierPushURL, "pushgateway-url", "", "URL of the Pushgateway the metrics of the runs are pushed to")
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/onboard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	offboardYes bool
+
+	offboardNamespaceCmd = &cobra.Command{
+		Use:   "namespace NAME",
+		Short: "Offboard the namespace of a team from the Prometheus resources.",
+		Long: `Offboard the namespace of a team from the Prometheus resources:
+
+- the monitoring.coreos.com objects of the namespace are deleted, along with the RBAC created by the onboard namespace command,
+- the labels of the namespace matching the namespace selectors of the Prometheus, PrometheusAgent, Alertmanager and ThanosRuler resources of the other namespaces are removed.
+
+The objects of the other namespaces and the cluster-scoped objects referencing the namespace are reported and left untouched. The command asks for a confirmation before deleting anything, unless --yes is set.`,
+		Example: `  # Offboard the team-a namespace
+  poctl offboard namespace team-a`,
+		Args: cobra.ExactArgs(1),
+		RunE: runOffboardNamespace,
+	}
+)
+
+func init() {
+	offboardCmd.AddCommand(offboardNamespaceCmd)
+	offboardNamespaceCmd.Flags().BoolVarP(&offboardYes, "yes", "y", false, "Offboard the namespace without asking for a confirmation")
+}
+
+func runOffboardNamespace(cmd *cobra.Command, args []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	namespace := args[0]
+	o, err := onboard.PlanOffboarding(cmd.Context(), clientSets, namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range o.Warnings {
+		slog.Warn(warning+", the namespace stays selected", "namespace", namespace)
+	}
+
+	if err := printOffboarding(cmd.OutOrStdout(), o); err != nil {
+		return err
+	}
+
+	if len(o.Objects) == 0 && len(o.Labels) == 0 {
+		slog.Info("nothing to offboard", "namespace", namespace)
+		return nil
+	}
+
+	if !offboardYes {
+		confirmed, err := confirm(cmd.InOrStdin(), cmd.OutOrStdout(), "Offboard namespace "+namespace+"?")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			slog.Info("offboarding cancelled", "namespace", namespace)
+			return nil
+		}
+	}
+
+	if err := onboard.Offboard(cmd.Context(), clientSets, o); err != nil {
+		return err
+	}
+
+	slog.Info("namespace offboarded", "namespace", namespace, "deleted", len(o.Objects), "labels", strings.Join(o.Labels, ","))
+	return nil
+}
+
+// printOffboarding prints the objects and the labels the offboarding
+// removes, and the objects it leaves behind.
+func printOffboarding(w io.Writer, o *onboard.Offboarding) error {
+	var b strings.Builder
+	if len(o.Objects) > 0 {
+		fmt.Fprintf(&b, "Objects to delete in namespace %s:\n", o.Namespace)
+		for _, obj := range o.Objects {
+			fmt.Fprintf(&b, "  %s\n", obj)
+		}
+	}
+
+	if len(o.Labels) > 0 {
+		fmt.Fprintf(&b, "Labels to remove from namespace %s:\n", o.Namespace)
+		for _, label := range o.Labels {
+			fmt.Fprintf(&b, "  %s\n", label)
+		}
+	}
+
+	if len(o.Leftovers) > 0 {
+		fmt.Fprintf(&b, "Objects referencing namespace %s left untouched:\n", o.Namespace)
+		for _, leftover := range o.Leftovers {
+			fmt.Fprintf(&b, "  %s\n", leftover)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// confirm asks the question and reports whether the answer is yes, an empty
+// answer being no.
+func confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	if _, err := fmt.Fprintf(out, "%s [y/N] ", question); err != nil {
+		return false, err
+	}
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("error while reading the confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
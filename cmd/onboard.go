@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// onboardCmd represents the onboard command.
+var onboardCmd = &cobra.Command{
+	Use:   "onboard",
+	Short: "The onboard command sets up the monitoring of the namespace of a team.",
+	Long:  `The onboard command in poctl sets up the monitoring of the namespace of a team by a Prometheus, labeling the namespace so that the Prometheus selects its monitors and rules, creating starter monitors and rules and granting the team the management of its monitors.`,
+}
+
+func init() {
+	rootCmd.AddCommand(onboardCmd)
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/onboard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	onboardPrometheus string
+	onboardGroups     []string
+	onboardUsers      []string
+	onboardDryRun     bool
+
+	onboardNamespaceCmd = &cobra.Command{
+		Use:   "namespace NAME",
+		Short: "Onboard the namespace of a team on a Prometheus.",
+		Long: `Onboard the namespace of a team on a Prometheus:
+
+- the namespace is labeled to match the namespace selectors of the ServiceMonitors, PodMonitors, Probes and PrometheusRules of the Prometheus, and created when missing,
+- a starter ServiceMonitor and PrometheusRule named after the namespace are created when missing, labeled so that the Prometheus selects them,
+- the monitoring-editor Role grants the management of the ServiceMonitors, PodMonitors, Probes and PrometheusRules of the namespace to the groups and users.
+
+The current labels of the namespace are never overwritten: the command fails when they conflict with the namespace selectors.`,
+		Example: `  # Onboard the team-a namespace on the main Prometheus of the monitoring namespace
+  poctl onboard namespace team-a --prometheus main/monitoring --group team-a
+
+  # Print the objects without applying them
+  poctl onboard namespace team-a --prometheus main/monitoring --group team-a --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: runOnboardNamespace,
+	}
+)
+
+func init() {
+	onboardCmd.AddCommand(onboardNamespaceCmd)
+	onboardNamespaceCmd.Flags().StringVar(&onboardPrometheus, "prometheus", "", "Prometheus monitoring the namespace, in the name/namespace format")
+	onboardNamespaceCmd.Flags().StringSliceVar(&onboardGroups, "group", nil, "Groups granted the management of the monitors and the rules of the namespace")
+	onboardNamespaceCmd.Flags().StringSliceVar(&onboardUsers, "user", nil, "Users granted the management of the monitors and the rules of the namespace")
+	onboardNamespaceCmd.Flags().BoolVar(&onboardDryRun, "dry-run", false, "Print the objects without applying them")
+	_ = onboardNamespaceCmd.MarkFlagRequired("prometheus")
+}
+
+func runOnboardNamespace(cmd *cobra.Command, args []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	name, namespace, ok := strings.Cut(onboardPrometheus, "/")
+	if !ok || name == "" || namespace == "" {
+		return fmt.Errorf("invalid Prometheus %q, expected the name/namespace format", onboardPrometheus)
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	opts := onboard.Options{
+		Namespace:           args[0],
+		PrometheusName:      name,
+		PrometheusNamespace: namespace,
+		Groups:              onboardGroups,
+		Users:               onboardUsers,
+	}
+	result, err := onboard.Run(cmd.Context(), clientSets, opts, onboardDryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		slog.Warn(warning, "namespace", opts.Namespace)
+	}
+
+	for _, object := range result.Existing {
+		slog.Info("the starter object already exists and is left unchanged", "object", object, "namespace", opts.Namespace)
+	}
+
+	if onboardDryRun {
+		return result.Manifests.EncodeYAML(cmd.OutOrStdout())
+	}
+
+	for _, object := range result.Applied {
+		slog.Info("object applied", "object", object, "namespace", opts.Namespace)
+	}
+	return nil
+}
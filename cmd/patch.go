@@ -0,0 +1,134 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/patch"
+	"github.com/spf13/cobra"
+)
+
+var patchCmd = &cobra.Command{
+	Use:   "patch RESOURCE/NAME",
+	Short: "Patch a Prometheus Operator resource after validating it against the CRD schema.",
+	Long:  `Patch a Prometheus Operator resource, for example prometheus/my-prom, with a JSON merge patch or a JSON patch. The patch is applied locally and the patched object is validated against the schema of the installed CRD before being sent to the API server, reporting the JSON paths of the invalid fields. The diff of the object is printed, and with --dry-run the object is left untouched.`,
+	Example: `  # Raise the log level of a Prometheus
+  poctl patch prometheus/my-prom --type merge -p '{"spec":{"logLevel":"debug"}}'
+
+  # Print the diff of a JSON patch without applying it
+  poctl patch servicemonitor/app -n team-a --type json -p '[{"op":"replace","path":"/spec/endpoints/0/interval","value":"15s"}]' --dry-run`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runPatch,
+}
+
+var (
+	patchNamespace string
+	patchType      string
+	patchData      string
+	patchFile      string
+	patchDryRun    bool
+)
+
+func init() {
+	rootCmd.AddCommand(patchCmd)
+	patchCmd.Flags().StringVarP(&patchNamespace, "namespace", "n", "default", "Namespace of the resource")
+	patchCmd.Flags().StringVar(&patchType, "type", string(patch.Merge), fmt.Sprintf("Type of the patch, one of: %s, %s", patch.Merge, patch.JSON))
+	patchCmd.Flags().StringVarP(&patchData, "patch", "p", "", "Patch to apply, in JSON or YAML")
+	patchCmd.Flags().StringVar(&patchFile, "patch-file", "", "File holding the patch to apply, - for the standard input")
+	patchCmd.Flags().BoolVar(&patchDryRun, "dry-run", false, "Print the diff without updating the resource")
+}
+
+func runPatch(cmd *cobra.Command, args []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	resource, name, found := strings.Cut(args[0], "/")
+	if len(args) == 2 {
+		if found {
+			return fmt.Errorf("the resource must be given as RESOURCE/NAME or RESOURCE NAME")
+		}
+		name = args[1]
+	} else if !found || name == "" {
+		return fmt.Errorf("the name of the resource is missing, expected RESOURCE/NAME")
+	}
+
+	typ, err := patch.ParseType(patchType)
+	if err != nil {
+		return err
+	}
+
+	data, err := readPatch(cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	result, err := patch.Run(cmd.Context(), clientSets, resource, name, patchNamespace, typ, data, patchDryRun)
+	if err != nil {
+		return err
+	}
+
+	if result.Diff == "" {
+		slog.Info("the patch doesn't change the resource", "kind", result.Kind, "name", result.Name, "namespace", result.Namespace)
+		return nil
+	}
+
+	if _, err := io.WriteString(cmd.OutOrStdout(), result.Diff); err != nil {
+		return err
+	}
+
+	if patchDryRun {
+		slog.Info("the resource is valid, not patched in dry-run mode", "kind", result.Kind, "name", result.Name, "namespace", result.Namespace)
+		return nil
+	}
+
+	slog.Info("resource patched", "kind", result.Kind, "name", result.Name, "namespace", result.Namespace)
+	return nil
+}
+
+// readPatch returns the patch given by --patch or --patch-file.
+func readPatch(stdin io.Reader) ([]byte, error) {
+	switch {
+	case patchData != "" && patchFile != "":
+		return nil, fmt.Errorf("--patch and --patch-file are mutually exclusive")
+	case patchData != "":
+		return []byte(patchData), nil
+	case patchFile == "-":
+		return io.ReadAll(stdin)
+	case patchFile != "":
+		data, err := os.ReadFile(patchFile)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading patch file: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("one of --patch or --patch-file is required")
+	}
+}
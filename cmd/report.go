@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command.
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "The report command summarizes the behavior of the monitoring stack over time.",
+	Long:  `The report command in poctl queries Prometheus and Alertmanager for the history of the monitoring stack and relates it to the Prometheus Operator objects, pointing at what needs attention.`,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/backtest"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	"github.com/prometheus-operator/poctl/internal/report"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	reportAlertmanager      string
+	reportAlertmanagerURL   string
+	reportPrometheus        string
+	reportPrometheusURL     string
+	reportNamespace         string
+	reportSince             string
+	reportStep              string
+	reportFlappingThreshold int
+
+	reportAlertsCmd = &cobra.Command{
+		Use:   "alerts",
+		Short: "Report the flapping and permanently firing alerts.",
+		Long:  `Report the alerts which fired over a period, from the ALERTS series recorded by Prometheus, grouped by alert name along with their receivers in Alertmanager and the PrometheusRules defining them. Alerts firing over and over are reported as flapping, alerts firing most of the period as permanently firing: both are sources of alert fatigue.`,
+		RunE:  runReportAlerts,
+	}
+)
+
+func init() {
+	reportCmd.AddCommand(reportAlertsCmd)
+	reportAlertsCmd.Flags().StringVar(&reportAlertmanager, "alertmanager", builder.AlertManagerName, "Name of the Alertmanager to get the receivers of the alerts from")
+	reportAlertsCmd.Flags().StringVar(&reportAlertmanagerURL, "alertmanager-url", "", "URL of the Alertmanager API, for instance through a port-forward, instead of the API server proxy")
+	reportAlertsCmd.Flags().StringVar(&reportPrometheus, "prometheus", "prometheus", "Name of the Prometheus to get the alerts history from")
+	reportAlertsCmd.Flags().StringVar(&reportPrometheusURL, "prometheus-url", "", "URL of the Prometheus API, for instance through a port-forward, instead of the API server proxy")
+	reportAlertsCmd.Flags().StringVarP(&reportNamespace, "namespace", "n", "default", "Namespace of the Prometheus and the Alertmanager")
+	reportAlertsCmd.Flags().StringVar(&reportSince, "since", "7d", "Period of history to report on, ending now")
+	reportAlertsCmd.Flags().StringVar(&reportStep, "step", "1m", "Resolution of the alerts history, raised when the period would return too many points")
+	reportAlertsCmd.Flags().IntVar(&reportFlappingThreshold, "flapping-threshold", 10, "Number of firings of a series over the period from which its alert is reported as flapping")
+}
+
+func runReportAlerts(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	since, err := backtest.ParseDuration(reportSince)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+
+	step, err := backtest.ParseDuration(reportStep)
+	if err != nil {
+		return fmt.Errorf("invalid --step: %w", err)
+	}
+
+	if since <= 0 || step <= 0 {
+		return errors.New("--since and --step must be positive")
+	}
+
+	if reportFlappingThreshold < 2 {
+		return errors.New("--flapping-threshold must be at least 2")
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	prometheus := promapi.NewURLClient(reportPrometheusURL)
+	if reportPrometheusURL == "" {
+		clients, err := promapi.PrometheusClients(cmd.Context(), clientSets, reportPrometheus, reportNamespace)
+		if err != nil {
+			return err
+		}
+
+		if len(clients) > 1 {
+			slog.Warn("Prometheus is sharded, the alerts are evaluated by each shard and only one is queried", "name", reportPrometheus, "namespace", reportNamespace)
+		}
+		prometheus = clients[0]
+	}
+
+	alertmanager := promapi.NewURLClient(reportAlertmanagerURL)
+	if reportAlertmanagerURL == "" {
+		alertmanager, err = promapi.AlertmanagerClient(cmd.Context(), clientSets, reportAlertmanager, reportNamespace)
+		if err != nil {
+			slog.Warn("the receivers of the alerts are unknown", "err", err)
+		}
+	}
+
+	var prometheusRules []monitoringv1.PrometheusRule
+	list, err := clientSets.MClient.MonitoringV1().PrometheusRules(metav1.NamespaceAll).List(cmd.Context(), metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("the PrometheusRules defining the alerts are unknown", "err", err)
+	} else {
+		for _, pr := range list.Items {
+			prometheusRules = append(prometheusRules, *pr)
+		}
+	}
+
+	end := time.Now().UTC()
+	reports, err := report.Alerts(cmd.Context(), prometheus, alertmanager, prometheusRules, report.AlertOptions{
+		Start:             end.Add(-since),
+		End:               end,
+		Step:              backtest.Step(since, step),
+		FlappingThreshold: reportFlappingThreshold,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ALERT\tRECEIVERS\tRULES\tSERIES\tFIRINGS\tFIRING TIME\tSTATUS")
+	for _, r := range reports {
+		var status []string
+		if r.Flapping {
+			status = append(status, "flapping")
+		}
+		if r.Permanent {
+			status = append(status, "permanently firing")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", r.Name, cmp.Or(strings.Join(r.Receivers, ","), "-"), cmp.Or(strings.Join(r.Rules, ","), "-"), r.Series, r.Firings, r.FiringTime, cmp.Or(strings.Join(status, ","), "-"))
+	}
+
+	return w.Flush()
+}
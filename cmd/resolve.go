@@ -0,0 +1,33 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// resolveCmd represents the resolve command.
+var resolveCmd = &cobra.Command{
+	Use:   "resolve",
+	Short: "The resolve command shows the targets that Prometheus would discover for a monitor, computed from the Kubernetes API.",
+	Long:  `The resolve command in poctl computes the scrape targets selected by Prometheus Operator monitors using only the Kubernetes API. It applies the namespace selector, the label selector and the port matching of the monitor to the objects of the cluster, which helps understand why a target is missing without requiring access to Prometheus.`,
+}
+
+var resolveNamespace string
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+	resolveCmd.PersistentFlags().StringVarP(&resolveNamespace, "namespace", "n", "default", "Namespace of the monitor")
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/resolve"
+	"github.com/spf13/cobra"
+)
+
+var resolveServiceMonitorCmd = &cobra.Command{
+	Use:   "servicemonitor NAME",
+	Short: "Show the targets selected by a ServiceMonitor.",
+	Long:  `Show the targets selected by a ServiceMonitor. The Services are selected with the namespace selector and the label selector of the ServiceMonitor, then the addresses of their EndpointSlices are matched against the endpoints ports.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runResolveServiceMonitor,
+}
+
+func runResolveServiceMonitor(cmd *cobra.Command, args []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	targets, err := resolve.ServiceMonitorTargets(cmd.Context(), clientSets, args[0], resolveNamespace)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 0 {
+		slog.Warn("ServiceMonitor doesn't select any target", "name", args[0], "namespace", resolveNamespace)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tSERVICE\tPOD\tURL\tREADY")
+	for _, t := range targets {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", t.Namespace, t.Service, t.Pod, t.URL, t.Ready)
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	resolveCmd.AddCommand(resolveServiceMonitorCmd)
+}
@@ -15,8 +15,12 @@
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
 	"github.com/prometheus-operator/poctl/internal/log"
 	"github.com/spf13/cobra"
 )
@@ -31,17 +35,54 @@ By providing an intuitive interface, poctl allows users to efficiently manage ke
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		k8sutil.UseFieldManager(fieldManager(cmd))
+	},
+}
+
+// fieldManager returns the field manager of the objects written by cmd,
+// named after its top-level command so that the subcommands of a command,
+// such as the ones of rules, share their fields. The drift command repairs
+// the objects of the stacks applied by the create command, under the same
+// field manager.
+func fieldManager(cmd *cobra.Command) string {
+	if !cmd.HasParent() {
+		return k8sutil.FieldManager
+	}
+	for cmd.Parent().HasParent() {
+		cmd = cmd.Parent()
+	}
+
+	name := cmd.Name()
+	if name == driftCmd.Name() {
+		name = createCmd.Name()
+	}
+	return k8sutil.FieldManager + "-" + name
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The context of the commands is cancelled on SIGINT and SIGTERM, letting
+// long-running operations stop and report their progress.
 func Execute() {
-	err := rootCmd.Execute()
+	addCompletionManCmd()
+
+	ctx, stop := notifyContext()
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	stop()
+
+	recordUsage(cmd, err)
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// notifyContext returns a context cancelled on SIGINT and SIGTERM, the
+// signals being handled as usual again once stopped.
+func notifyContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
 var kubeconfig string
 
 func init() {
@@ -55,4 +96,5 @@ func init() {
 	// when this action is called directly.
 	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "path to the kubeconfig file, defaults to $KUBECONFIG")
 	log.RegisterFlags(rootCmd.PersistentFlags())
+	k8sutil.RegisterFlags(rootCmd.PersistentFlags())
 }
@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// rulesCmd represents the rules command.
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "The rules command helps tuning Prometheus rules before deploying them.",
+	Long:  `The rules command in poctl works on candidate Prometheus rules, given as PrometheusRule manifests or plain rule files, against the data of a running Prometheus, and imports them once the API server accepts them.`,
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/backtest"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backtestFilename   string
+	backtestPrometheus string
+	backtestNamespace  string
+	backtestURL        string
+	backtestRange      string
+	backtestStep       string
+
+	rulesBacktestCmd = &cobra.Command{
+		Use:   "backtest",
+		Short: "Evaluate alerting rules over the historical data of Prometheus.",
+		Long:  `Evaluate the alerting rules of PrometheusRule manifests or plain rule files over the historical data of Prometheus with the query_range API, and report how often and when each alert would have fired. The for duration of the rules is taken into account, which helps tuning the thresholds before deploying the rules.`,
+		RunE:  runRulesBacktest,
+	}
+)
+
+func init() {
+	rulesCmd.AddCommand(rulesBacktestCmd)
+	rulesBacktestCmd.Flags().StringVarP(&backtestFilename, "filename", "f", "", "File containing the rules to backtest")
+	rulesBacktestCmd.Flags().StringVar(&backtestPrometheus, "prometheus", "prometheus", "Name of the Prometheus to query")
+	rulesBacktestCmd.Flags().StringVarP(&backtestNamespace, "namespace", "n", "default", "Namespace of the Prometheus")
+	rulesBacktestCmd.Flags().StringVar(&backtestURL, "prometheus-url", "", "URL of the Prometheus API, for instance through a port-forward, instead of the API server proxy")
+	rulesBacktestCmd.Flags().StringVar(&backtestRange, "range", "7d", "Period of history to evaluate the rules over, ending now")
+	rulesBacktestCmd.Flags().StringVar(&backtestStep, "step", "1m", "Resolution of the evaluation, raised when the range would return too many points")
+}
+
+func runRulesBacktest(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	if backtestFilename == "" {
+		return errors.New("filename is required")
+	}
+
+	queryRange, err := backtest.ParseDuration(backtestRange)
+	if err != nil {
+		return fmt.Errorf("invalid --range: %w", err)
+	}
+
+	step, err := backtest.ParseDuration(backtestStep)
+	if err != nil {
+		return fmt.Errorf("invalid --step: %w", err)
+	}
+
+	if queryRange <= 0 || step <= 0 {
+		return errors.New("--range and --step must be positive")
+	}
+
+	data, err := os.ReadFile(backtestFilename)
+	if err != nil {
+		return fmt.Errorf("error while reading %s: %w", backtestFilename, err)
+	}
+
+	rules, err := backtest.LoadRules(data)
+	if err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		return fmt.Errorf("no alerting rules found in %s", backtestFilename)
+	}
+
+	client, err := backtestClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	if adjusted := backtest.Step(queryRange, step); adjusted != step {
+		slog.Info("raising the step to stay within the points limit of Prometheus", "step", adjusted)
+		step = adjusted
+	}
+
+	end := time.Now().UTC()
+	results, err := backtest.Run(cmd.Context(), client, rules, end.Add(-queryRange), end, step)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ALERT\tSOURCE\tFOR\tFIRINGS\tSERIES\tFIRING TIME\tFIRST\tLAST")
+	for _, r := range results {
+		first, last := "-", "-"
+		if len(r.Periods) > 0 {
+			first = r.Periods[0].Start.Format(time.RFC3339)
+			last = r.Periods[len(r.Periods)-1].Start.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n", r.Rule.Alert, cmp.Or(r.Rule.Source, "-"), r.Rule.For, len(r.Periods), r.Series(), r.FiringTime(), first, last)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var fired bool
+	for _, r := range results {
+		if len(r.Periods) > 0 {
+			fired = true
+			break
+		}
+	}
+	if !fired {
+		return nil
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout())
+	w = tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ALERT\tSTART\tEND\tDURATION\tLABELS")
+	for _, r := range results {
+		for _, p := range r.Periods {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Rule.Alert, p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339), p.End.Sub(p.Start), p.FormatLabels())
+		}
+	}
+	return w.Flush()
+}
+
+// backtestClient returns the client of the Prometheus to query. A sharded
+// Prometheus only holds the series of its shard, the first one is queried.
+func backtestClient(cmd *cobra.Command) (promapi.Client, error) {
+	if backtestURL != "" {
+		return promapi.NewURLClient(backtestURL), nil
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	clients, err := promapi.PrometheusClients(cmd.Context(), clientSets, backtestPrometheus, backtestNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(clients) > 1 {
+		slog.Warn("Prometheus is sharded, only the series of one shard are evaluated", "name", backtestPrometheus, "namespace", backtestNamespace)
+	}
+	return clients[0], nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/admission"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	importFilename  string
+	importNamespace string
+	importDryRun    bool
+
+	rulesImportCmd = &cobra.Command{
+		Use:   "import",
+		Short: "Apply PrometheusRule manifests once the API server accepts all of them.",
+		Long:  `Apply the PrometheusRules of the manifests found in a file or a directory. The PrometheusRules are first submitted with a server-side dry-run, which runs the admission webhook of the Prometheus Operator when it is installed: the rules whose expressions don't parse are rejected and reported along with the messages of the webhook, and nothing is applied unless all the PrometheusRules are accepted.`,
+		Example: `  # Check the rules of a directory without applying them
+  poctl rules import -f rules/ --dry-run
+
+  # Apply the rules in the monitoring namespace
+  poctl rules import -f rules/ -n monitoring`,
+		RunE: runRulesImport,
+	}
+)
+
+func init() {
+	rulesCmd.AddCommand(rulesImportCmd)
+	rulesImportCmd.Flags().StringVarP(&importFilename, "filename", "f", "", "File or directory containing the PrometheusRule manifests to import")
+	rulesImportCmd.Flags().StringVarP(&importNamespace, "namespace", "n", "default", "Namespace of the PrometheusRules which don't set one")
+	rulesImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Only submit the PrometheusRules with a server-side dry-run, without applying them")
+}
+
+func runRulesImport(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	if importFilename == "" {
+		return errors.New("filename is required")
+	}
+
+	files, err := manifestFiles(importFilename)
+	if err != nil {
+		return fmt.Errorf("error while listing manifests: %w", err)
+	}
+
+	var rules []*unstructured.Unstructured
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error while reading %s: %w", file, err)
+		}
+
+		fileRules, err := admission.LoadPrometheusRules(data, importNamespace)
+		if err != nil {
+			return fmt.Errorf("error while loading %s: %w", file, err)
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	if len(rules) == 0 {
+		return fmt.Errorf("no PrometheusRules found in %s", importFilename)
+	}
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	results, err := admission.DryRunPrometheusRules(cmd.Context(), clientSets, rules)
+	if err != nil {
+		return err
+	}
+
+	rejected := 0
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tRESULT\tID\tFINDING")
+	for _, r := range results {
+		if r.Err == nil {
+			fmt.Fprintf(w, "%s\t%s\taccepted\t-\t-\n", r.Namespace, r.Name)
+			continue
+		}
+
+		rejected++
+		id, _ := messages.IDOf(r.Err)
+		fmt.Fprintf(w, "%s\t%s\trejected\t%s\t%s\n", r.Namespace, r.Name, id, r.Err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if rejected > 0 {
+		return fmt.Errorf("%d of %d PrometheusRules rejected, none applied", rejected, len(results))
+	}
+
+	if importDryRun {
+		return nil
+	}
+
+	if err := admission.ApplyPrometheusRules(cmd.Context(), clientSets, rules); err != nil {
+		return err
+	}
+
+	logger.Info("PrometheusRules applied", "count", len(rules))
+	return nil
+}
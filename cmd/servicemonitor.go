@@ -15,14 +15,21 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/prometheus-operator/poctl/internal/crds"
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
 	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/pkg/builder"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
 	"github.com/spf13/cobra"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
 
@@ -33,15 +40,24 @@ var (
 	serviceName       string
 	namespace         string
 	port              string
+	preset            string
+	withExporter      bool
+	imagePullSecrets  []string
+	secure            bool
+	tokenAudiences    []string
+	tokenExpiration   time.Duration
+	caConfigMap       string
 	servicemonitorCmd = &cobra.Command{
 		Use:   "servicemonitor",
 		Short: "Create a service monitor object",
 		Long:  `Create a service monitor object based on user input parameters or taking as source of truth a kubernetes service`,
-		RunE:  runServiceMonitor,
+		Example: `  # Monitor a Redis Service with its exporter
+  poctl create servicemonitor --service my-redis --preset redis --with-exporter`,
+		RunE: runServiceMonitor,
 	}
 )
 
-func runServiceMonitor(_ *cobra.Command, _ []string) error {
+func runServiceMonitor(cmd *cobra.Command, _ []string) error {
 	logger, err := log.NewLogger()
 	if err != nil {
 		fmt.Println(err)
@@ -59,7 +75,58 @@ func runServiceMonitor(_ *cobra.Command, _ []string) error {
 		return errors.New("service name is required")
 	}
 
-	err = createFromService(context.Background(), clientSets, namespace, serviceName, port)
+	if withExporter && preset == "" {
+		logger.Error("--with-exporter requires --preset")
+		return errors.New("--with-exporter requires --preset")
+	}
+
+	if len(imagePullSecrets) > 0 && !withExporter {
+		logger.Error("--image-pull-secret requires --with-exporter")
+		return errors.New("--image-pull-secret requires --with-exporter")
+	}
+
+	if secure && withExporter {
+		logger.Error("--secure can't be used with --with-exporter, the deployed exporter isn't protected")
+		return errors.New("--secure can't be used with --with-exporter")
+	}
+
+	if len(tokenAudiences) > 0 && !secure {
+		logger.Error("--token-audience requires --secure")
+		return errors.New("--token-audience requires --secure")
+	}
+
+	if caConfigMap != "" && !secure {
+		logger.Error("--ca-configmap requires --secure")
+		return errors.New("--ca-configmap requires --secure")
+	}
+
+	domain, err := parseClusterDomain()
+	if err != nil {
+		logger.Error("invalid cluster domain", "err", err)
+		return err
+	}
+
+	var reader *builder.MetricsReaderManifests
+	if secure {
+		// The cluster-scoped RBAC of the metrics reader isn't created for a
+		// missing service.
+		if _, err := clientSets.KClient.CoreV1().Services(namespace).Get(cmd.Context(), serviceName, metav1.GetOptions{}); err != nil {
+			logger.Error("error while getting service", "service", serviceName, "err", err)
+			return fmt.Errorf("error while getting service %s: %w", serviceName, err)
+		}
+
+		reader, err = createMetricsReader(cmd.Context(), logger, clientSets, namespace, serviceName, tokenAudiences, tokenExpiration, caConfigMap)
+		if err != nil {
+			logger.Error("error while creating metrics reader", "err", err)
+			return err
+		}
+	}
+
+	if preset != "" {
+		err = createFromPreset(cmd.Context(), clientSets, namespace, serviceName, preset, withExporter, domain, imagePullSecrets, reader)
+	} else {
+		err = createFromService(cmd.Context(), clientSets, namespace, serviceName, port, reader)
+	}
 	if err != nil {
 		logger.Error("error while creating service monitor", "err", err)
 		return err
@@ -73,11 +140,12 @@ func createFromService(
 	clientSets *k8sutil.ClientSets,
 	namespace string,
 	serviceName string,
-	port string) error {
+	port string,
+	reader *builder.MetricsReaderManifests) error {
 
 	service, err := clientSets.KClient.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("error while getting service %s: %v", serviceName, err)
+		return fmt.Errorf("error while getting service %s: %w", serviceName, err)
 	}
 
 	svcMonitor := &monitoringv1.ServiceMonitorApplyConfiguration{
@@ -108,12 +176,158 @@ func createFromService(
 		})
 	}
 
-	_, err = clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Apply(ctx, svcMonitor, k8sutil.ApplyOption)
+	secureEndpoints(svcMonitor, reader)
+
+	if err := crds.NewValidator(clientSets.APIExtensionsClient).ValidateObjects(ctx, svcMonitor); err != nil {
+		return err
+	}
+
+	return k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, svcMonitor))
+}
+
+// createFromPreset creates a ServiceMonitor for a third-party application
+// using the exporter preset. With withExporter, the exporter is deployed next
+// to the application and reaches its Service in the cluster domain, otherwise
+// it is expected to run as a sidecar exposed by the Service of the
+// application.
+func createFromPreset(
+	ctx context.Context,
+	clientSets *k8sutil.ClientSets,
+	namespace string,
+	serviceName string,
+	presetName string,
+	withExporter bool,
+	clusterDomain string,
+	imagePullSecrets []string,
+	reader *builder.MetricsReaderManifests) error {
+
+	p, err := builder.GetExporterPreset(presetName)
 	if err != nil {
-		return fmt.Errorf("error while creating service monitor %s: %v", serviceName, err)
+		return err
 	}
 
-	return nil
+	b := builder.NewExporterBuilder(namespace, serviceName, p).
+		WithClusterDomain(clusterDomain).
+		WithImagePullSecrets(imagePullSecrets...)
+
+	if withExporter {
+		manifests := b.WithDeployment().
+			WithService().
+			WithServiceMonitor().
+			Build()
+
+		if err := crds.NewValidator(clientSets.APIExtensionsClient).ValidateObjects(ctx, manifests.ServiceMonitor); err != nil {
+			return err
+		}
+
+		return k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).
+			WithConcurrency(1).
+			Apply(ctx, manifests.Deployment, manifests.Service, manifests.ServiceMonitor))
+	}
+
+	service, err := clientSets.KClient.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error while getting service %s: %w", serviceName, err)
+	}
+
+	portName := ""
+	for _, sp := range service.Spec.Ports {
+		if sp.Port == p.Port || sp.TargetPort.IntVal == p.Port {
+			portName = sp.Name
+			break
+		}
+	}
+
+	if portName == "" {
+		return fmt.Errorf("service %s doesn't expose the %s exporter port %d, use --with-exporter to deploy the exporter", serviceName, p.Name, p.Port)
+	}
+
+	manifests := b.WithSidecarServiceMonitor(service.Labels, portName).Build()
+	secureEndpoints(manifests.ServiceMonitor, reader)
+
+	if err := crds.NewValidator(clientSets.APIExtensionsClient).ValidateObjects(ctx, manifests.ServiceMonitor); err != nil {
+		return err
+	}
+
+	return k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, manifests.ServiceMonitor))
+}
+
+// createMetricsReader creates the ServiceAccount whose token authenticates
+// Prometheus against the protected endpoints of the service, along with its
+// permission to get /metrics and the Secret holding the token. The token is
+// requested for the expiration with the TokenRequest API, bound to the
+// audiences or to the audience of the API server without audiences. The
+// certificate of the endpoints is verified with the CA of the ConfigMap,
+// given as name or name/key, or with the CAs of the Prometheus container. The
+// token isn't renewed, the Secret is annotated with its expiry.
+func createMetricsReader(
+	ctx context.Context,
+	logger *slog.Logger,
+	clientSets *k8sutil.ClientSets,
+	namespace string,
+	serviceName string,
+	audiences []string,
+	expiration time.Duration,
+	caConfigMap string) (*builder.MetricsReaderManifests, error) {
+
+	b := builder.NewMetricsReader(namespace, serviceName).
+		WithServiceAccount().
+		WithClusterRole().
+		WithClusterRoleBinding()
+	manifests := b.Build()
+
+	err := k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).
+		Apply(ctx, manifests.ServiceAccount, manifests.ClusterRole, manifests.ClusterRoleBinding))
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, *manifests.ServiceAccount.Name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         audiences,
+			ExpirationSeconds: ptr.To(int64(expiration.Seconds())),
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while requesting token: %w", err)
+	}
+
+	// The API server may shorten the requested expiration. Nothing renews
+	// the token: the Secret records its expiry, which poctl analyze reports.
+	expiry := request.Status.ExpirationTimestamp.Time
+	logger.Warn("the bound token isn't renewed, the scrapes fail with 401 once it expires, run the command again before it expires to request a new one",
+		"audiences", strings.Join(audiences, ","), "expiration", expiry)
+	b = b.WithToken(request.Status.Token, expiry)
+
+	if caConfigMap != "" {
+		name, key, _ := strings.Cut(caConfigMap, "/")
+		b = b.WithCA(name, cmp.Or(key, "ca.crt"))
+	}
+	manifests = b.Build()
+
+	if err := k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, manifests.Secret)); err != nil {
+		return nil, err
+	}
+
+	logger.Info("the endpoints authenticate with the token of the ServiceAccount, the proxy must authorize it to get /metrics",
+		"serviceaccount", *manifests.ServiceAccount.Name, "secret", *manifests.Secret.Name, "clusterrole", *manifests.ClusterRole.Name)
+	if manifests.CA == nil {
+		logger.Info("the certificate of the endpoints is verified with the CAs of the Prometheus container, use --ca-configmap to verify it with another CA", "servername", manifests.ServerName)
+	}
+
+	return &manifests, nil
+}
+
+// secureEndpoints scrapes all the endpoints of the ServiceMonitor with the
+// token of the metrics reader, when there is one.
+func secureEndpoints(serviceMonitor *monitoringv1.ServiceMonitorApplyConfiguration, reader *builder.MetricsReaderManifests) {
+	if reader == nil {
+		return
+	}
+
+	for i := range serviceMonitor.Spec.Endpoints {
+		reader.SecureEndpoint(&serviceMonitor.Spec.Endpoints[i])
+	}
 }
 
 func init() {
@@ -121,4 +335,12 @@ func init() {
 	servicemonitorCmd.Flags().StringVarP(&serviceName, "service", "s", "", "Service name to create the service monitor from")
 	servicemonitorCmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "Namespace of the service")
 	servicemonitorCmd.Flags().StringVarP(&port, "port", "p", "", "Port of the service")
+	servicemonitorCmd.Flags().StringVar(&preset, "preset", "", fmt.Sprintf("Exporter preset of the application exposed by the service, one of: %s", strings.Join(builder.ExporterPresetNames(), ", ")))
+	servicemonitorCmd.Flags().BoolVar(&withExporter, "with-exporter", false, "Deploy the exporter of the preset instead of expecting it to run as a sidecar of the service")
+	servicemonitorCmd.Flags().BoolVar(&secure, "secure", false, "Scrape the endpoints over https with the token of a ServiceAccount allowed to get /metrics, for endpoints protected by kube-rbac-proxy")
+	servicemonitorCmd.Flags().StringSliceVar(&tokenAudiences, "token-audience", nil, "Audiences of the token requested with --secure, defaults to the audience of the API server")
+	servicemonitorCmd.Flags().DurationVar(&tokenExpiration, "token-expiration", 365*24*time.Hour, "Requested expiration of the token requested with --secure, which isn't renewed: run the command again before it expires")
+	servicemonitorCmd.Flags().StringVar(&caConfigMap, "ca-configmap", "", "ConfigMap of the namespace holding the CA verifying the certificate of the endpoints scraped with --secure, as name or name/key (key defaults to ca.crt)")
+	servicemonitorCmd.Flags().StringSliceVar(&imagePullSecrets, "image-pull-secret", nil, "Image pull secret of the exporter deployed with --with-exporter, can be repeated")
+	registerClusterDomainFlag(servicemonitorCmd)
 }
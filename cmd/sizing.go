@@ -0,0 +1,65 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var sizingCmd = &cobra.Command{
+	Use:   "sizing",
+	Short: "Recommend the resource sizing of the monitoring stack for the cluster.",
+	Long:  `The sizing command counts the nodes and Pods of the cluster, estimates the number of series Prometheus would scrape and recommends the Prometheus CPU and memory requests, its retention and the number of kube-state-metrics shards. The create stack command applies these recommendations with --auto-size.`,
+	RunE:  runSizing,
+}
+
+func init() {
+	rootCmd.AddCommand(sizingCmd)
+	registerContextsFlag(sizingCmd)
+}
+
+func runSizing(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(_ *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		size, err := create.MeasureCluster(cmd.Context(), clientSets)
+		if err != nil {
+			return err
+		}
+
+		sizing := create.RecommendSizing(size)
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "SETTING\tVALUE")
+		fmt.Fprintf(w, "nodes\t%d\n", size.Nodes)
+		fmt.Fprintf(w, "pods\t%d\n", size.Pods)
+		fmt.Fprintf(w, "estimated series\t%d\n", sizing.Series)
+		fmt.Fprintf(w, "prometheus cpu request\t%s\n", sizing.PrometheusResources.Cpu())
+		fmt.Fprintf(w, "prometheus memory request\t%s\n", sizing.PrometheusResources.Memory())
+		fmt.Fprintf(w, "prometheus retention\t%s\n", sizing.PrometheusRetention)
+		fmt.Fprintf(w, "kube-state-metrics shards\t%d\n", sizing.KubeStateMetricsShards)
+		return w.Flush()
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show and configure the opt-in usage telemetry.",
+	Long:  `Show and configure the opt-in usage telemetry. Once enabled, each run of a command records the command and the class of its error, if any, in a local file. No names, namespaces or error messages are recorded. The events can also be sent to a configurable endpoint, to let the maintainers know which commands and analyzers matter most.`,
+	Example: `  # Show the recorded usage statistics
+  poctl stats
+
+  # Enable the telemetry and send the events to an endpoint
+  poctl stats --enable --endpoint https://telemetry.example.com/poctl`,
+	RunE: runStats,
+}
+
+var (
+	statsEnable   bool
+	statsDisable  bool
+	statsReset    bool
+	statsEndpoint string
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().BoolVar(&statsEnable, "enable", false, "Enable the usage telemetry")
+	statsCmd.Flags().BoolVar(&statsDisable, "disable", false, "Disable the usage telemetry")
+	statsCmd.Flags().BoolVar(&statsReset, "reset", false, "Reset the recorded usage statistics")
+	statsCmd.Flags().StringVar(&statsEndpoint, "endpoint", "", "URL the usage events are posted to, an empty value keeps them local")
+	statsCmd.MarkFlagsMutuallyExclusive("enable", "disable")
+}
+
+func runStats(cmd *cobra.Command, _ []string) error {
+	path, err := telemetry.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	state, err := telemetry.Load(path)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if statsEnable || statsDisable {
+		state.Enabled = statsEnable
+		changed = true
+	}
+	if cmd.Flags().Changed("endpoint") {
+		state.Endpoint = statsEndpoint
+		changed = true
+	}
+	if statsReset {
+		state.Commands = nil
+		changed = true
+	}
+
+	if changed {
+		if err := state.Save(path); err != nil {
+			return err
+		}
+	}
+
+	if !state.Enabled {
+		fmt.Fprintln(cmd.OutOrStdout(), "Telemetry is disabled, enable it with poctl stats --enable.")
+		return nil
+	}
+
+	if state.Endpoint != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Telemetry is enabled, events are sent to %s.\n\n", state.Endpoint)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Telemetry is enabled, events are only recorded in %s.\n\n", path)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "COMMAND\tRUNS\tERRORS")
+	for _, command := range slices.Sorted(maps.Keys(state.Commands)) {
+		stats := state.Commands[command]
+
+		var errs []string
+		for _, class := range slices.Sorted(maps.Keys(stats.Errors)) {
+			errs = append(errs, fmt.Sprintf("%s=%d", class, stats.Errors[class]))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", command, stats.Runs, strings.Join(errs, ","))
+	}
+
+	return w.Flush()
+}
+
+// recordUsage records the run of a command in the usage telemetry, when
+// enabled. Failures are only logged as they must not affect the command.
+func recordUsage(cmd *cobra.Command, runErr error) {
+	if cmd == nil || cmd == rootCmd || cmd == statsCmd {
+		return
+	}
+
+	path, err := telemetry.DefaultPath()
+	if err != nil {
+		slog.Debug("error while recording usage", "err", err)
+		return
+	}
+
+	state, err := telemetry.Load(path)
+	if err != nil {
+		slog.Debug("error while recording usage", "err", err)
+		return
+	}
+
+	event, ok := state.Record(usageCommand(cmd), runErr)
+	if !ok {
+		return
+	}
+
+	if err := state.Save(path); err != nil {
+		slog.Debug("error while recording usage", "err", err)
+		return
+	}
+
+	if state.Endpoint != "" {
+		if err := telemetry.Send(context.Background(), state.Endpoint, event); err != nil {
+			slog.Debug("error while sending usage", "err", err)
+		}
+	}
+}
+
+// usageCommand returns the command path without the binary name, along with
+// the kind of the analyzed object for the analyze command.
+func usageCommand(cmd *cobra.Command) string {
+	command := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+
+	if cmd == analyzeCmd && analyzerFlags.Kind != "" {
+		command += " " + strings.ToLower(analyzerFlags.Kind)
+	}
+
+	return command
+}
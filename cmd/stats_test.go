@@ -0,0 +1,71 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/telemetry"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestAnalyzeErrorClass checks that the errors of the API server keep their
+// class through the wrapping of the analyzers and of the command.
+func TestAnalyzeErrorClass(t *testing.T) {
+	serviceMonitor := &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		opts     []k8stesting.Option
+		expected string
+	}{
+		{
+			name:     "forbidden get",
+			opts:     []k8stesting.Option{k8stesting.WithMonitoringReactor("get", "servicemonitors", k8stesting.Forbidden())},
+			expected: telemetry.ErrorClassForbidden,
+		},
+		{
+			name: "forbidden list",
+			opts: []k8stesting.Option{
+				k8stesting.WithObjects(serviceMonitor),
+				k8stesting.WithKubeReactor("list", "services", k8stesting.Forbidden()),
+			},
+			expected: telemetry.ErrorClassForbidden,
+		},
+		{
+			name:     "internal error",
+			opts:     []k8stesting.Option{k8stesting.WithMonitoringReactor("get", "servicemonitors", k8stesting.InternalError())},
+			expected: telemetry.ErrorClassOther,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(tc.opts...)
+
+			err := analyze(context.Background(), clientSets, "servicemonitor", "app", "default")
+			require.Error(t, err)
+			assert.Equal(t, tc.expected, telemetry.ErrorClass(err))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// topCmd represents the top command.
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "The top command shows the heaviest monitoring objects, as measured by Prometheus.",
+	Long:  `The top command in poctl queries Prometheus for the load generated by the Prometheus Operator objects and ranks them, so that their owners can be asked to reduce it.`,
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	"github.com/prometheus-operator/poctl/internal/top"
+	"github.com/spf13/cobra"
+)
+
+var (
+	topMonitorsPrometheus string
+	topMonitorsNamespace  string
+	topMonitorsURL        string
+	topMonitorsLimit      int
+	topMonitorsSortBy     string
+
+	topMonitorsCmd = &cobra.Command{
+		Use:   "monitors",
+		Short: "Show the monitors with the heaviest scrape load.",
+		Long:  `Show the ServiceMonitors, PodMonitors, Probes and ScrapeConfigs with the heaviest scrape load. Prometheus is queried for the samples scraped from each target and the scrape durations, then the targets are mapped back to the monitor generating their scrape pool. Each shard of the Prometheus is reached through the Kubernetes API server proxy, unless --prometheus-url is set.`,
+		RunE:  runTopMonitors,
+	}
+)
+
+func init() {
+	topCmd.AddCommand(topMonitorsCmd)
+	topMonitorsCmd.Flags().StringVar(&topMonitorsPrometheus, "prometheus", "prometheus", "Name of the Prometheus to query")
+	topMonitorsCmd.Flags().StringVarP(&topMonitorsNamespace, "namespace", "n", "default", "Namespace of the Prometheus")
+	topMonitorsCmd.Flags().StringVar(&topMonitorsURL, "prometheus-url", "", "URL of the Prometheus API, for instance through a port-forward, instead of the API server proxy")
+	topMonitorsCmd.Flags().IntVar(&topMonitorsLimit, "limit", 10, "Number of monitors to show, all of them when 0")
+	topMonitorsCmd.Flags().StringVar(&topMonitorsSortBy, "sort-by", top.SortBySamples, fmt.Sprintf("Order of the monitors, one of: %s", strings.Join(top.SortOrders, ", ")))
+}
+
+func runTopMonitors(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	if !slices.Contains(top.SortOrders, topMonitorsSortBy) {
+		return fmt.Errorf("invalid --sort-by %q, must be one of: %s", topMonitorsSortBy, strings.Join(top.SortOrders, ", "))
+	}
+
+	if topMonitorsLimit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	var clients []promapi.Client
+	if topMonitorsURL != "" {
+		clients = []promapi.Client{promapi.NewURLClient(topMonitorsURL)}
+	} else {
+		clientSets, err := k8sutil.GetClientSets(kubeconfig)
+		if err != nil {
+			return fmt.Errorf("error while getting clientsets: %w", err)
+		}
+
+		clients, err = promapi.PrometheusClients(cmd.Context(), clientSets, topMonitorsPrometheus, topMonitorsNamespace)
+		if err != nil {
+			return err
+		}
+	}
+
+	monitors, err := top.Monitors(cmd.Context(), clients)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tJOBS\tTARGETS\tSAMPLES\tDURATION")
+	for _, m := range top.Top(monitors, topMonitorsSortBy, topMonitorsLimit) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%.0f\t%s\n", cmp.Or(m.Kind, "-"), cmp.Or(m.Namespace, "-"), m.Name, strings.Join(m.Jobs, ","), m.Targets, m.Samples, m.Duration.Round(time.Millisecond))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	uiTimeout time.Duration
+	uiRefresh time.Duration
+
+	uiCmd = &cobra.Command{
+		Use:   "ui",
+		Short: "Browse the monitoring components of the cluster interactively.",
+		Long:  `Browse the Prometheus, PrometheusAgent, Alertmanager and ThanosRuler components of all the namespaces in a terminal UI with their health, refreshed periodically, drill down into the findings of the analyzers and act on them: pause or resume their reconciliation, scale them or forward a local port to their web port. The keys are listed at the bottom of the screen.`,
+		Example: `  # Browse the components of the current context
+  poctl ui`,
+		Args: cobra.NoArgs,
+		RunE: runUI,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+	uiCmd.Flags().DurationVar(&uiTimeout, "timeout", time.Minute, "Maximum duration of each analysis")
+	uiCmd.Flags().DurationVar(&uiRefresh, "refresh", ui.DefaultInterval, "Interval between the refreshes of the health of the components")
+}
+
+func runUI(cmd *cobra.Command, _ []string) error {
+	// The logs would draw over the screen, only the warnings of the
+	// analyzers are recorded to be shown with their results.
+	logger, err := log.NewLoggerWithOutput(io.Discard)
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	recorder := &warningRecorder{}
+	slog.SetDefault(slog.New(newRecordingHandler(logger.Handler(), recorder)))
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	config, err := k8sutil.GetRestConfig(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting k8s client config: %w", err)
+	}
+
+	s := &ui.Session{
+		ClientSets: clientSets,
+		Analyze:    uiAnalyzer(clientSets, recorder),
+		PortForward: func(ctx context.Context, c ui.Component) (*ui.Forward, error) {
+			return ui.PortForward(ctx, config, clientSets, c)
+		},
+		Interval: uiRefresh,
+		In:       cmd.InOrStdin(),
+		Out:      cmd.OutOrStdout(),
+	}
+	return s.Run(cmd.Context())
+}
+
+// uiAnalyzeKinds are the analyzers of the kinds of components.
+var uiAnalyzeKinds = map[string]AnalyzeKind{
+	ui.KindPrometheus:      Prometheus,
+	ui.KindPrometheusAgent: PrometheusAgent,
+	ui.KindAlertmanager:    Alertmanager,
+	ui.KindThanosRuler:     ThanosRuler,
+}
+
+// uiAnalyzer returns the analysis of the components drilled into, returning
+// the warnings taken from the recorder along with the failure.
+func uiAnalyzer(clientSets *k8sutil.ClientSets, recorder *warningRecorder) func(ctx context.Context, c ui.Component) ([]string, error) {
+	return func(ctx context.Context, c ui.Component) ([]string, error) {
+		ctx, cancel := context.WithTimeout(ctx, uiTimeout)
+		defer cancel()
+
+		kind, ok := uiAnalyzeKinds[c.Kind]
+		if !ok {
+			return nil, fmt.Errorf("no analyzer for %s", c.Kind)
+		}
+
+		err := analyze(ctx, clientSets, string(kind), c.Name, c.Namespace)
+		return recorder.take(), err
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/prometheus-operator/poctl/internal/ui"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestUIAnalyzer drills into every kind of component listed by the UI and
+// checks that its analyzer runs, the ThanosRuler one failing on its missing
+// ServiceAccount.
+func TestUIAnalyzer(t *testing.T) {
+	defer func(timeout time.Duration) { uiTimeout = timeout }(uiTimeout)
+	uiTimeout = time.Minute
+
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "monitoring"}
+	}
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.Prometheus{ObjectMeta: meta("k8s")},
+		&monitoringv1alpha1.PrometheusAgent{ObjectMeta: meta("agent")},
+		&monitoringv1.Alertmanager{ObjectMeta: meta("main")},
+		&monitoringv1.ThanosRuler{ObjectMeta: meta("ruler"), Spec: monitoringv1.ThanosRulerSpec{ServiceAccountName: "thanos-ruler"}},
+	))
+
+	components, err := ui.ListComponents(context.Background(), clientSets)
+	require.NoError(t, err)
+	require.Len(t, components, 4)
+
+	analyze := uiAnalyzer(clientSets, &warningRecorder{})
+	for _, c := range components {
+		t.Run(c.Kind, func(t *testing.T) {
+			_, err := analyze(context.Background(), c)
+			if err != nil {
+				assert.NotContains(t, err.Error(), "no analyzer")
+			}
+
+			if c.Kind == ui.KindThanosRuler {
+				id, ok := messages.IDOf(err)
+				require.True(t, ok, "error: %v", err)
+				assert.Equal(t, messages.ThanosRulerServiceAccount, id)
+			}
+		})
+	}
+}
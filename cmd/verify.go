@@ -0,0 +1,83 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/prometheus-operator/poctl/internal/verify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Analyze the Prometheus Operator objects of the whole cluster and record Events on the failing ones.",
+	Long:  `Analyze the Prometheus Operator deployments and the Prometheus, PrometheusAgent, Alertmanager, ServiceMonitor and ScrapeConfig objects of the whole cluster. A Warning Event with the PoctlAnalysisFailed reason is recorded on every object failing the analysis, and the command fails. The warnings are recorded as Warning Events with the PoctlAnalysisWarning reason. With --pushgateway-url, the metrics of the run are pushed to a Pushgateway under the poctl-verifier job. This is the command run by the verifier CronJob.`,
+	Args:  cobra.NoArgs,
+	RunE:  runVerify,
+}
+
+var (
+	verifySnapshot       bool
+	verifyPushgatewayURL string
+)
+
+func runVerify(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	slog.SetDefault(logger)
+
+	clientSets, err := k8sutil.GetClientSets(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error while getting clientsets: %w", err)
+	}
+
+	if verifySnapshot {
+		// The Events recorded on the failing objects are sent to the
+		// cluster.
+		clientSets, err = k8sutil.NewSnapshot(cmd.Context(), clientSets)
+		if err != nil {
+			return fmt.Errorf("error while taking snapshot: %w", err)
+		}
+	}
+
+	if verifyPushgatewayURL == "" {
+		return verify.Run(cmd.Context(), clientSets, nil)
+	}
+
+	// The metrics are pushed even when the analysis fails, replacing the
+	// ones of the previous run.
+	reg := prometheus.NewRegistry()
+	err = verify.Run(cmd.Context(), clientSets, reg)
+	if pushErr := push.New(verifyPushgatewayURL, "poctl-verifier").Gatherer(reg).PushContext(cmd.Context()); pushErr != nil {
+		return errors.Join(err, fmt.Errorf("error while pushing metrics: %w", pushErr))
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().BoolVar(&verifySnapshot, "snapshot", false, "List the monitoring objects, Services and RBAC objects of the cluster once and analyze them from memory. The Secrets aren't part of the snapshot and are read from the cluster")
+	verifyCmd.Flags().StringVar(&verifyPushgatewayURL, "pushgateway-url", "", "URL of the Pushgateway the metrics of the run are pushed to")
+}
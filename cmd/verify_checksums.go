@@ -0,0 +1,77 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var verifyChecksumsCmd = &cobra.Command{
+	Use:   "checksums",
+	Short: "Verify the checksums of the objects of a stack created by poctl.",
+	Long:  `Render the manifests of a stack with the parameters recorded when it was created and verify the checksum annotation of each live object. An object is missing when the stack has been partially applied, outdated when its checksum isn't the one of the rendered manifest, and tampered when the fields applied by poctl don't match its checksum anymore. The command fails when any object isn't valid.`,
+	Args:  cobra.NoArgs,
+	RunE:  runVerifyChecksums,
+}
+
+var verifyStack string
+
+func init() {
+	verifyCmd.AddCommand(verifyChecksumsCmd)
+	verifyChecksumsCmd.Flags().StringVar(&verifyStack, "name", "", "Name of the stack, the default stack when not set")
+	registerContextsFlag(verifyChecksumsCmd)
+}
+
+func runVerifyChecksums(cmd *cobra.Command, _ []string) error {
+	logger, err := log.NewLogger()
+	if err != nil {
+		return fmt.Errorf("error while creating logger: %w", err)
+	}
+
+	return forEachContext(cmd.OutOrStdout(), logger, func(logger *slog.Logger, clientSets *k8sutil.ClientSets) error {
+		checksums, err := create.RunVerifyChecksums(cmd.Context(), clientSets, verifyStack)
+		if err != nil {
+			return err
+		}
+
+		var invalid int
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tSTATUS")
+		for _, c := range checksums {
+			if c.Status != create.ChecksumValid {
+				invalid++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Kind, cmp.Or(c.Namespace, "-"), c.Name, c.Status)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if invalid > 0 {
+			return fmt.Errorf("%d objects failed the checksum verification", invalid)
+		}
+
+		logger.Info("all the objects match their checksums", "stack", create.StackAnchor(verifyStack), "objects", len(checksums))
+		return nil
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admission submits the objects to the admission of the API server
+// with a server-side dry-run before they're applied, so that the validating
+// webhooks, such as the one of the Prometheus Operator checking the
+// expressions of the PrometheusRules, reject the invalid objects before any
+// of them is applied.
+package admission
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DryRunOptions returns the options applying the objects with a server-side
+// dry-run: the objects go through the admission webhooks and the validation
+// of the API server without being persisted.
+func DryRunOptions() metav1.ApplyOptions {
+	opts := k8sutil.ApplyOption
+	opts.DryRun = []string{metav1.DryRunAll}
+	return opts
+}
+
+// Rejection returns the finding of an object rejected by an admission
+// webhook or by the validation of the API server, with the causes given by
+// the webhook. It returns nil for the other errors, such as a connection
+// error, which don't tell anything about the object.
+func Rejection(kind, namespace, name string, err error) error {
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		return nil
+	}
+
+	status := apiStatus.Status()
+	if status.Reason != metav1.StatusReasonInvalid && !strings.Contains(status.Message, "denied the request") {
+		return nil
+	}
+
+	return messages.New(messages.ObjectRejected, kind, name, namespace, reason(status))
+}
+
+// reason returns the message of the status along with its causes, the
+// webhook of the Prometheus Operator listing the invalid rules as causes.
+func reason(status metav1.Status) string {
+	msg := status.Message
+	if status.Details == nil {
+		return msg
+	}
+
+	for _, cause := range status.Details.Causes {
+		if cause.Message != "" && !strings.Contains(msg, cause.Message) {
+			msg += "; " + cause.Message
+		}
+	}
+	return msg
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// webhookDenial is the error returned by the API server when the webhook of
+// the Prometheus Operator rejects a PrometheusRule.
+var webhookDenial = &apierrors.StatusError{ErrStatus: metav1.Status{
+	Status:  metav1.StatusFailure,
+	Code:    400,
+	Reason:  metav1.StatusReasonBadRequest,
+	Message: `admission webhook "prometheusrulevalidate.monitoring.coreos.com" denied the request: Rules are not valid`,
+	Details: &metav1.StatusDetails{
+		Causes: []metav1.StatusCause{
+			{Message: `group "api", rule 1, "HighLatency": could not parse expression: 1:47: parse error: unclosed left parenthesis`},
+		},
+	},
+}}
+
+func TestRejection(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "webhook",
+			err:      webhookDenial,
+			expected: `PrometheusRule api in namespace default is rejected by the API server: admission webhook "prometheusrulevalidate.monitoring.coreos.com" denied the request: Rules are not valid; group "api", rule 1, "HighLatency": could not parse expression: 1:47: parse error: unclosed left parenthesis`,
+		},
+		{
+			name: "schema",
+			err: apierrors.NewInvalid(schema.GroupKind{Group: "monitoring.coreos.com", Kind: "PrometheusRule"}, "api", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "groups").Index(0).Child("interval"), "1x", "must match the duration format"),
+			}),
+			expected: `PrometheusRule api in namespace default is rejected by the API server: PrometheusRule.monitoring.coreos.com "api" is invalid: spec.groups[0].interval: Invalid value: "1x": must match the duration format`,
+		},
+		{
+			name: "forbidden",
+			err:  apierrors.NewForbidden(schema.GroupResource{Group: "monitoring.coreos.com", Resource: "prometheusrules"}, "api", errors.New("access denied")),
+		},
+		{
+			name: "connection",
+			err:  errors.New("connection refused"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Rejection("PrometheusRule", "default", "api", tc.err)
+			if tc.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tc.expected)
+			id, _ := messages.IDOf(err)
+			assert.Equal(t, messages.ObjectRejected, id)
+		})
+	}
+}
+
+func TestLoadPrometheusRules(t *testing.T) {
+	rules, err := LoadPrometheusRules([]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: api
+spec:
+  groups:
+  - name: api
+    rules:
+    - alert: HighLatency
+      expr: histogram_quantile(0.99, rate(http_request_duration_seconds_bucket[5m])) > 1
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: db
+  namespace: storage
+spec: {}
+`), "monitoring")
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "monitoring/api", rules[0].GetNamespace()+"/"+rules[0].GetName())
+	assert.Equal(t, "storage/db", rules[1].GetNamespace()+"/"+rules[1].GetName())
+
+	_, err = LoadPrometheusRules([]byte("apiVersion: monitoring.coreos.com/v1\nkind: PrometheusRule\nspec: {}\n"), "default")
+	require.ErrorContains(t, err, "has no name")
+}
+
+func TestDryRunOptions(t *testing.T) {
+	opts := DryRunOptions()
+	assert.Equal(t, []string{metav1.DryRunAll}, opts.DryRun)
+	assert.Equal(t, k8sutil.ApplyOption.FieldManager, opts.FieldManager)
+	assert.Empty(t, k8sutil.ApplyOption.DryRun)
+}
+
+func TestDryRunPrometheusRules(t *testing.T) {
+	rules, err := LoadPrometheusRules([]byte(`apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: api
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: db
+`), "default")
+	require.NoError(t, err)
+
+	newClientSets := func(reaction clienttesting.ReactionFunc) *k8sutil.ClientSets {
+		client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+		client.PrependReactor("patch", "prometheusrules", reaction)
+		return &k8sutil.ClientSets{DClient: client}
+	}
+
+	clientSets := newClientSets(func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.PatchActionImpl).Name == "api" {
+			return true, nil, webhookDenial
+		}
+		return true, rules[1], nil
+	})
+	results, err := DryRunPrometheusRules(context.Background(), clientSets, rules)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Error(t, results[0].Err)
+	assert.Contains(t, results[0].Err.Error(), "unclosed left parenthesis")
+	assert.NoError(t, results[1].Err)
+
+	// The errors which aren't rejections stop the dry-run.
+	clientSets = newClientSets(func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	_, err = DryRunPrometheusRules(context.Background(), clientSets, rules)
+	require.ErrorContains(t, err, "connection refused")
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+var prometheusRules = monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.PrometheusRuleName)
+
+// Result is the result of the dry-run of a PrometheusRule, Err holding the
+// finding of a rejected one.
+type Result struct {
+	Namespace string
+	Name      string
+	Err       error
+}
+
+// LoadPrometheusRules decodes the PrometheusRules of a YAML or JSON manifest
+// stream, the other objects being skipped. The PrometheusRules without
+// namespace are set in the given namespace.
+func LoadPrometheusRules(data []byte, namespace string) ([]*unstructured.Unstructured, error) {
+	var rules []*unstructured.Unstructured
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for i := 0; ; i++ {
+		var obj map[string]any
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return rules, nil
+			}
+			return nil, fmt.Errorf("error while decoding document %d: %w", i, err)
+		}
+
+		rule := &unstructured.Unstructured{Object: obj}
+		if obj == nil || rule.GroupVersionKind() != monitoringv1.SchemeGroupVersion.WithKind(monitoringv1.PrometheusRuleKind) {
+			continue
+		}
+
+		if rule.GetName() == "" {
+			return nil, fmt.Errorf("document %d: PrometheusRule has no name", i)
+		}
+
+		if rule.GetNamespace() == "" {
+			rule.SetNamespace(namespace)
+		}
+		rules = append(rules, rule)
+	}
+}
+
+// DryRunPrometheusRules submits the PrometheusRules to the API server with a
+// server-side dry-run, and returns the result of each of them. The rejected
+// PrometheusRules are reported in their result, an error is only returned
+// when the dry-run couldn't be performed.
+func DryRunPrometheusRules(ctx context.Context, clientSets *k8sutil.ClientSets, rules []*unstructured.Unstructured) ([]Result, error) {
+	results := make([]Result, 0, len(rules))
+	for _, rule := range rules {
+		result := Result{Namespace: rule.GetNamespace(), Name: rule.GetName()}
+
+		_, err := clientSets.DClient.Resource(prometheusRules).Namespace(rule.GetNamespace()).Apply(ctx, rule.GetName(), rule, DryRunOptions())
+		if err != nil {
+			result.Err = Rejection(monitoringv1.PrometheusRuleKind, rule.GetNamespace(), rule.GetName(), err)
+			if result.Err == nil {
+				return nil, fmt.Errorf("error while submitting PrometheusRule %s/%s: %v", rule.GetNamespace(), rule.GetName(), err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ApplyPrometheusRules applies the PrometheusRules.
+func ApplyPrometheusRules(ctx context.Context, clientSets *k8sutil.ClientSets, rules []*unstructured.Unstructured) error {
+	objects := make([]any, 0, len(rules))
+	for _, rule := range rules {
+		objects = append(objects, rule)
+	}
+
+	return k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, objects...))
+}
@@ -17,42 +17,49 @@ package analyzers
 import (
 	"context"
 	"fmt"
-	"log/slog"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 )
 
-func RunAlertmanagerAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) error {
+func RunAlertmanagerAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("Alertmanager", name, namespace)
+	return r.result(analyzeAlertmanager(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeAlertmanager(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
 	alertmanager, err := clientSets.MClient.MonitoringV1().Alertmanagers(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return fmt.Errorf("alertmanager %s not found in namespace %s", name, namespace)
+			return messages.New(messages.ObjectNotFound, "alertmanager", name, namespace)
 		}
-		return fmt.Errorf("error while getting Alertmanager: %v", err)
+		return fmt.Errorf("error while getting Alertmanager: %w", err)
 	}
 
 	_, err = clientSets.KClient.CoreV1().ServiceAccounts(namespace).Get(ctx, alertmanager.Spec.ServiceAccountName, metav1.GetOptions{})
 	if err != nil {
-		if errors.IsNotFound(err) {
-			return fmt.Errorf("alertmanager serviceaccount not found in namespace %s", namespace)
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error while getting ServiceAcounts: %w", err)
 		}
-		return fmt.Errorf("error while getting ServiceAcounts: %w", err)
+		r.fail(messages.ServiceAccountNotFound, namespace)
 	}
 
 	if alertmanager.Spec.AlertmanagerConfigSelector == nil && alertmanager.Spec.AlertmanagerConfiguration == nil {
 		if alertmanager.Spec.ConfigSecret != "" {
 			// use provided config secret
-			if err := checkAlertmanagerSecret(ctx, clientSets, alertmanager.Spec.ConfigSecret, namespace, "alertmanager.yaml"); err != nil {
+			if err := r.check(checkAlertmanagerSecret(ctx, clientSets, r, alertmanager.Spec.ConfigSecret, namespace, "alertmanager.yaml")); err != nil {
 				return fmt.Errorf("error checking Alertmanager secret: %w", err)
 			}
 		}
 		if alertmanager.Spec.ConfigSecret == "" {
 			// use the default generated secret from pkg/alertmanager/statefulset.go
 			amConfigSecretName := fmt.Sprintf("alertmanager-%s-generated", alertmanager.Name)
-			if err := checkAlertmanagerSecret(ctx, clientSets, amConfigSecretName, namespace, "alertmanager.yaml.gz"); err != nil {
+			if err := r.check(checkAlertmanagerSecret(ctx, clientSets, r, amConfigSecretName, namespace, "alertmanager.yaml.gz")); err != nil {
 				return fmt.Errorf("error checking Alertmanager secret: %w", err)
 			}
 		}
@@ -60,44 +67,81 @@ func RunAlertmanagerAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets
 	// If 'AlertmanagerConfigNamespaceSelector' is nil, only check own namespace.
 	if alertmanager.Spec.AlertmanagerConfigNamespaceSelector != nil {
 		if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, alertmanager.Spec.AlertmanagerConfigNamespaceSelector); err != nil {
-			return fmt.Errorf("alertmanagerConfigNamespaceSelector is not properly defined: %s", err)
+			r.fail(messages.SelectorNotProperlyDefined, "alertmanagerConfigNamespaceSelector", err)
 		}
 	}
 
 	if alertmanager.Spec.AlertmanagerConfigSelector != nil {
 		if err := checkAlertmanagerConfigs(ctx, clientSets, alertmanager.Spec.AlertmanagerConfigSelector, namespace); err != nil {
-			return fmt.Errorf("alertmanagerConfigSelectors is not properly defined: %s", err)
+			r.fail(messages.SelectorNotProperlyDefined, "alertmanagerConfigSelectors", err)
 		}
 	}
 
 	if alertmanager.Spec.AlertmanagerConfiguration != nil {
 		_, err := clientSets.MClient.MonitoringV1alpha1().AlertmanagerConfigs(namespace).Get(ctx, alertmanager.Spec.AlertmanagerConfiguration.Name, metav1.GetOptions{})
 		if err != nil {
-			if errors.IsNotFound(err) {
-				return fmt.Errorf("alertmanagerConfigs not found in namespace %s", namespace)
+			if !errors.IsNotFound(err) {
+				return fmt.Errorf("error while getting AlertmanagerConfig: %w", err)
 			}
-			return fmt.Errorf("error while getting AlertmanagerConfig: %w", err)
+			r.fail(messages.AlertmanagerConfigNotFound, namespace)
 		}
 	}
 
-	slog.Info("Alertmanager is compliant, no issues found", "name", name, "namespace", namespace)
+	config, err := loadEffectiveConfig(ctx, clientSets, r, name, namespace)
+	if err != nil {
+		return err
+	}
+
+	if config != nil {
+		produced, err := loadProducedLabels(ctx, clientSets)
+		if err != nil {
+			return err
+		}
+
+		r.warn(routeWarnings(*config, produced)...)
+	}
+
+	if ptr.Deref(alertmanager.Spec.Replicas, 1) > 1 {
+		warnings, err := replicasPlacementWarnings(ctx, clientSets, "Alertmanager", name, namespace, "app.kubernetes.io/name=alertmanager,alertmanager="+name)
+		if err != nil {
+			return err
+		}
+
+		r.warn(warnings...)
+	}
+
 	return nil
 }
 
-func checkAlertmanagerSecret(ctx context.Context, clientSets *k8sutil.ClientSets, secretName, namespace string, secretData string) error {
-	alertmanagerSecret, err := clientSets.KClient.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+// getSecret returns the Secret, or nil with a warning when reading it is
+// forbidden, so that an identity without access to the Secrets, such as the
+// verifier, skips the checks of their content.
+func getSecret(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) (*corev1.Secret, error) {
+	secret, err := clientSets.KClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsForbidden(err) {
+		r.warn(newWarning(messages.SecretNotReadable, name, namespace))
+		return nil, nil
+	}
+	return secret, err
+}
+
+func checkAlertmanagerSecret(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, secretName, namespace string, secretData string) error {
+	alertmanagerSecret, err := getSecret(ctx, clientSets, r, secretName, namespace)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return fmt.Errorf("failed to get alertmanager secret %s not found in namespace %s", secretName, namespace)
+			return messages.New(messages.AlertmanagerSecretNotFound, secretName, namespace)
 		}
-		return fmt.Errorf("error while getting alertmanager secret%s %v", secretName, err)
+		return fmt.Errorf("error while getting alertmanager secret%s %w", secretName, err)
+	}
+	if alertmanagerSecret == nil {
+		return nil
 	}
 	if len(alertmanagerSecret.Data) == 0 {
-		return fmt.Errorf("alertmanager Secret %s is empty", secretName)
+		return messages.New(messages.AlertmanagerSecretEmpty, secretName)
 	}
 	_, found := alertmanagerSecret.Data[secretData]
 	if !found {
-		return fmt.Errorf("the %s key not found in Secret %s", secretData, secretName)
+		return messages.New(messages.AlertmanagerSecretKeyMissing, secretData, secretName)
 	}
 	return nil
 }
@@ -109,15 +153,15 @@ func checkAlertmanagerConfigs(ctx context.Context, clientSets *k8sutil.ClientSet
 
 	labelMap, err := metav1.LabelSelectorAsMap(labelSelector)
 	if err != nil {
-		return fmt.Errorf("invalid label selector format in %v", err)
+		return fmt.Errorf("invalid label selector format in %w", err)
 	}
 
 	alertmamagerConfigs, err := clientSets.MClient.MonitoringV1alpha1().AlertmanagerConfigs(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
 	if err != nil {
-		return fmt.Errorf("failed to list AlertmanagerConfigs in %s: %v", namespace, err)
+		return fmt.Errorf("failed to list AlertmanagerConfigs in %s: %w", namespace, err)
 	}
 	if len(alertmamagerConfigs.Items) == 0 {
-		return fmt.Errorf("no AlertmanagerConfigs match the provided selector in %s", namespace)
+		return messages.New(messages.AlertmanagerConfigsNoMatch, namespace)
 	}
 
 	return nil
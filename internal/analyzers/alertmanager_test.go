@@ -18,156 +18,99 @@ import (
 	"testing"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
-	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes/fake"
-	clienttesting "k8s.io/client-go/testing"
 )
 
 func TestAlertmanagerAnalyzer(t *testing.T) {
 	type testCase struct {
 		name                string
 		namespace           string
-		getMockedClientSets func(tc testCase) k8sutil.ClientSets
+		getMockedClientSets func(tc testCase) *k8sutil.ClientSets
 		shouldFail          bool
 	}
 
+	alertmanager := func(tc testCase, spec monitoringv1.AlertmanagerSpec) *monitoringv1.Alertmanager {
+		return &monitoringv1.Alertmanager{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      tc.name,
+				Namespace: tc.namespace,
+			},
+			Spec: spec,
+		}
+	}
+
 	tests := []testCase{
 		{
 			name:       "AlertmanagerNotFound",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, nil, errors.NewNotFound(monitoringv1.Resource("alertmanagers"), tc.name)
-				})
-
-				kClient := fake.NewSimpleClientset()
-				return k8sutil.ClientSets{
-					MClient: mClient,
-					KClient: kClient,
-				}
+			getMockedClientSets: func(_ testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.NotFound()),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerMissingServiceAccount",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							ServiceAccountName: "test-sa",
-						},
-					}, nil
-				})
-
-				kClient := fake.NewSimpleClientset(&corev1.ServiceAccount{})
-				kClient.PrependReactor("get", "serviceaccount", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, nil, errors.NewInternalError(nil)
-				})
-				return k8sutil.ClientSets{
-					MClient: mClient,
-					KClient: kClient,
-				}
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						ServiceAccountName: "test-sa",
+					}))),
+					k8stesting.WithKubeReactor("get", "serviceaccount", k8stesting.InternalError()),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerFailToGetConfigSecret",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							ConfigSecret: "test-secret",
-						},
-					}, nil
-				})
-
-				kClient := fake.NewSimpleClientset(&corev1.Secret{})
-				kClient.PrependReactor("get", "secret", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, nil, errors.NewInternalError(nil)
-				})
-				return k8sutil.ClientSets{
-					MClient: mClient,
-					KClient: kClient,
-				}
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						ConfigSecret: "test-secret",
+					}))),
+					k8stesting.WithKubeReactor("get", "secret", k8stesting.InternalError()),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerSecretEmptyData",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							ConfigSecret: "test-secret",
-						},
-					}, nil
-				})
-
-				kClient := fake.NewSimpleClientset(&corev1.Secret{})
-				kClient.PrependReactor("get", "secrets", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &corev1.Secret{
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						ConfigSecret: "test-secret",
+					}))),
+					k8stesting.WithKubeReactor("get", "secrets", k8stesting.ReturnObject(&corev1.Secret{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      "test-secret",
 							Namespace: tc.namespace,
 						},
 						Data: map[string][]byte{},
-					}, nil
-				})
-
-				return k8sutil.ClientSets{
-					MClient: mClient,
-					KClient: kClient,
-				}
+					})),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerSecretKeyNotFound",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							ConfigSecret: "test-secret",
-						},
-					}, nil
-				})
-
-				kClient := fake.NewSimpleClientset(&corev1.Secret{})
-				kClient.PrependReactor("get", "secrets", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &corev1.Secret{
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						ConfigSecret: "test-secret",
+					}))),
+					k8stesting.WithKubeReactor("get", "secrets", k8stesting.ReturnObject(&corev1.Secret{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      "test-secret",
 							Namespace: tc.namespace,
@@ -175,38 +118,22 @@ func TestAlertmanagerAnalyzer(t *testing.T) {
 						Data: map[string][]byte{
 							"some-other-key": []byte("value"),
 						},
-					}, nil
-				})
-
-				return k8sutil.ClientSets{
-					MClient: mClient,
-					KClient: kClient,
-				}
+					})),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerNamespaceSelectorWithoutMatchLabels",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							AlertmanagerConfigNamespaceSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{"environment": "test"},
-							},
-						},
-					}, nil
-				})
-
-				kClient := fake.NewSimpleClientset(&corev1.Namespace{})
-				kClient.PrependReactor("get", "namespace", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &corev1.NamespaceList{
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						AlertmanagerConfigNamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"environment": "test"},
+						},
+					}))),
+					k8stesting.WithKubeReactor("get", "namespace", k8stesting.ReturnObject(&corev1.NamespaceList{
 						Items: []corev1.Namespace{
 							{
 								ObjectMeta: metav1.ObjectMeta{
@@ -215,36 +142,22 @@ func TestAlertmanagerAnalyzer(t *testing.T) {
 								},
 							},
 						},
-					}, nil
-				})
-				return k8sutil.ClientSets{
-					MClient: mClient,
-					KClient: kClient,
-				}
+					})),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerSelectorWithoutMatchLabels",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							AlertmanagerConfigNamespaceSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{"amconfig": "test"},
-							},
-						},
-					}, nil
-				})
-
-				mClient.PrependReactor("get", "alertmanagerconfigs", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1alpha1.AlertmanagerConfigList{
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						AlertmanagerConfigNamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"amconfig": "test"},
+						},
+					}))),
+					k8stesting.WithMonitoringReactor("get", "alertmanagerconfigs", k8stesting.ReturnObject(&monitoringv1alpha1.AlertmanagerConfigList{
 						Items: []*monitoringv1alpha1.AlertmanagerConfig{
 							{
 								ObjectMeta: metav1.ObjectMeta{
@@ -254,47 +167,30 @@ func TestAlertmanagerAnalyzer(t *testing.T) {
 								},
 							},
 						},
-					}, nil
-				})
-				return k8sutil.ClientSets{
-					MClient: mClient,
-				}
+					})),
+				)
 			},
 		},
 		{
 			name:       "AlertmanagerFailedGetAMConfigs",
 			namespace:  "test",
 			shouldFail: true,
-			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
-				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.AlertmanagerList{})
-				mClient.PrependReactor("get", "alertmanagers", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, &monitoringv1.Alertmanager{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      tc.name,
-							Namespace: tc.namespace,
-						},
-						Spec: monitoringv1.AlertmanagerSpec{
-							AlertmanagerConfiguration: &monitoringv1.AlertmanagerConfiguration{
-								Name: "test-amconfig",
-							},
-						},
-					}, nil
-				})
-
-				mClient.PrependReactor("get", "alertmanagerconfigs", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, nil, errors.NewNotFound(monitoringv1alpha1.Resource("alertmanagerconfigs"), tc.name)
-				})
-				return k8sutil.ClientSets{
-					MClient: mClient,
-				}
+			getMockedClientSets: func(tc testCase) *k8sutil.ClientSets {
+				return k8stesting.NewFakeClientSets(
+					k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(alertmanager(tc, monitoringv1.AlertmanagerSpec{
+						AlertmanagerConfiguration: &monitoringv1.AlertmanagerConfiguration{
+							Name: "test-amconfig",
+						},
+					}))),
+					k8stesting.WithMonitoringReactor("get", "alertmanagerconfigs", k8stesting.NotFound()),
+				)
 			},
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			clientSets := tc.getMockedClientSets(tc)
-			err := RunAlertmanagerAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace)
+			err := analysisErr(RunAlertmanagerAnalyzer(context.Background(), tc.getMockedClientSets(tc), tc.name, tc.namespace))
 			if tc.shouldFail {
 				assert.Error(t, err)
 			} else {
@@ -303,3 +199,26 @@ func TestAlertmanagerAnalyzer(t *testing.T) {
 		})
 	}
 }
+
+func TestAlertmanagerAnalyzerSecretNotReadable(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(
+		k8stesting.WithMonitoringReactor("get", "alertmanagers", k8stesting.ReturnObject(&monitoringv1.Alertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+			Spec:       monitoringv1.AlertmanagerSpec{ServiceAccountName: "alertmanager", ConfigSecret: "alertmanager-config"},
+		})),
+		k8stesting.WithObjects(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "alertmanager", Namespace: "monitoring"}}),
+		k8stesting.WithKubeReactor("get", "secrets", k8stesting.Forbidden()),
+	)
+
+	findings, err := RunAlertmanagerAnalyzer(context.Background(), clientSets, "main", "monitoring")
+	assert.NoError(t, err)
+	assert.NoError(t, Err(findings))
+
+	var ids []messages.ID
+	for _, f := range findings {
+		if f.Severity == SeverityWarning {
+			ids = append(ids, f.Check)
+		}
+	}
+	assert.Equal(t, []messages.ID{messages.SecretNotReadable, messages.SecretNotReadable}, ids)
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func RunAlertmanagerConfigAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("AlertmanagerConfig", name, namespace)
+	return r.result(analyzeAlertmanagerConfig(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeAlertmanagerConfig(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	amConfig, err := clientSets.MClient.MonitoringV1alpha1().AlertmanagerConfigs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "AlertmanagerConfig", name, namespace)
+		}
+		return fmt.Errorf("error while getting AlertmanagerConfig: %w", err)
+	}
+
+	receivers := make(map[string]bool, len(amConfig.Spec.Receivers))
+	for _, receiver := range amConfig.Spec.Receivers {
+		if _, ok := receivers[receiver.Name]; ok {
+			r.fail(messages.AMConfigDuplicateReceiver, receiver.Name, name, namespace)
+		}
+		receivers[receiver.Name] = false
+	}
+
+	var (
+		warnings []analyzerWarning
+		// The receivers used by a route are only known when the walk
+		// of the route tree completed.
+		routed = amConfig.Spec.Route != nil
+	)
+	if amConfig.Spec.Route == nil {
+		warnings = append(warnings, newWarning(messages.AMConfigWithoutRoute))
+	} else if err := checkConfigRoute(amConfig, receivers); err != nil {
+		if err := r.check(err); err != nil {
+			return err
+		}
+		routed = false
+	}
+
+	for _, receiver := range amConfig.Spec.Receivers {
+		if routed && !receivers[receiver.Name] {
+			warnings = append(warnings, newWarning(messages.AMConfigUnusedReceiver, receiver.Name))
+		}
+
+		for _, ref := range receiverReferences(receiver) {
+			if err := k8sutil.CheckSecretOrConfigMap(ctx, *clientSets, namespace, ref.ref); err != nil {
+				r.fail(messages.AMConfigInvalidReference, ref.field, name, namespace, err)
+			}
+		}
+	}
+
+	r.warn(warnings...)
+
+	selected, err := isAlertmanagerConfigSelected(ctx, clientSets, amConfig)
+	if err != nil {
+		return err
+	}
+
+	if !selected {
+		r.fail(messages.AMConfigNotSelected, name, namespace)
+	}
+
+	return nil
+}
+
+// checkConfigRoute walks the route tree of the AlertmanagerConfig, checking
+// that the routes reference defined receivers and time intervals, and marks
+// the referenced receivers. The top route must set a receiver while the
+// child routes inherit the receiver of their parent.
+func checkConfigRoute(amConfig *monitoringv1alpha1.AlertmanagerConfig, receivers map[string]bool) error {
+	name, namespace := amConfig.Name, amConfig.Namespace
+
+	intervals := make(map[string]bool, len(amConfig.Spec.MuteTimeIntervals))
+	for _, interval := range amConfig.Spec.MuteTimeIntervals {
+		intervals[interval.Name] = true
+	}
+
+	var walk func(path string, route *monitoringv1alpha1.Route, top bool) error
+	walk = func(path string, route *monitoringv1alpha1.Route, top bool) error {
+		if route.Receiver != "" || top {
+			if _, ok := receivers[route.Receiver]; !ok {
+				return messages.New(messages.AMConfigUnknownReceiver, path, name, namespace, route.Receiver)
+			}
+			receivers[route.Receiver] = true
+		}
+
+		for _, interval := range append(route.MuteTimeIntervals, route.ActiveTimeIntervals...) {
+			if !intervals[interval] {
+				return messages.New(messages.AMConfigUnknownTimeInterval, path, name, namespace, interval)
+			}
+		}
+
+		children, err := route.ChildRoutes()
+		if err != nil {
+			return messages.New(messages.AMConfigInvalidRoute, path, name, namespace, err)
+		}
+
+		for i := range children {
+			if err := walk(fmt.Sprintf("%s.routes[%d]", path, i), &children[i], false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return walk("route", amConfig.Spec.Route, true)
+}
+
+// receiverReferences returns the Secrets and the ConfigMaps referenced by the
+// integrations of a receiver, which the operator reads from the namespace of
+// the AlertmanagerConfig.
+func receiverReferences(receiver monitoringv1alpha1.Receiver) []secretReference {
+	var refs []secretReference
+	secret := func(field string, selector *corev1.SecretKeySelector) {
+		if selector != nil && selector.Name != "" {
+			refs = append(refs, secretReference{field: field, ref: monitoringv1.SecretOrConfigMap{Secret: selector}})
+		}
+	}
+	httpConfig := func(prefix string, c *monitoringv1alpha1.HTTPConfig) {
+		if c == nil {
+			return
+		}
+		refs = append(refs, httpClientReferences(prefix+"httpConfig.", c.BasicAuth, c.Authorization, c.OAuth2, c.TLSConfig)...)
+		secret(prefix+"httpConfig.bearerTokenSecret", c.BearerTokenSecret)
+	}
+	prefix := func(integration string, i int) string {
+		return fmt.Sprintf("receivers[%s].%s[%d].", receiver.Name, integration, i)
+	}
+
+	for i, c := range receiver.OpsGenieConfigs {
+		p := prefix("opsgenieConfigs", i)
+		secret(p+"apiKey", c.APIKey)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.PagerDutyConfigs {
+		p := prefix("pagerdutyConfigs", i)
+		secret(p+"routingKey", c.RoutingKey)
+		secret(p+"serviceKey", c.ServiceKey)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.DiscordConfigs {
+		p := prefix("discordConfigs", i)
+		secret(p+"apiURL", &c.APIURL)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.SlackConfigs {
+		p := prefix("slackConfigs", i)
+		secret(p+"apiURL", c.APIURL)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.WebhookConfigs {
+		p := prefix("webhookConfigs", i)
+		secret(p+"urlSecret", c.URLSecret)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.WeChatConfigs {
+		p := prefix("wechatConfigs", i)
+		secret(p+"apiSecret", c.APISecret)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.EmailConfigs {
+		p := prefix("emailConfigs", i)
+		secret(p+"authPassword", c.AuthPassword)
+		secret(p+"authSecret", c.AuthSecret)
+		refs = append(refs, httpClientReferences(p, nil, nil, nil, c.TLSConfig)...)
+	}
+	for i, c := range receiver.VictorOpsConfigs {
+		p := prefix("victoropsConfigs", i)
+		secret(p+"apiKey", c.APIKey)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.PushoverConfigs {
+		p := prefix("pushoverConfigs", i)
+		secret(p+"userKey", c.UserKey)
+		secret(p+"token", c.Token)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.SNSConfigs {
+		p := prefix("snsConfigs", i)
+		if c.Sigv4 != nil {
+			secret(p+"sigv4.accessKey", c.Sigv4.AccessKey)
+			secret(p+"sigv4.secretKey", c.Sigv4.SecretKey)
+		}
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.TelegramConfigs {
+		p := prefix("telegramConfigs", i)
+		secret(p+"botToken", c.BotToken)
+		httpConfig(p, c.HTTPConfig)
+	}
+	for i, c := range receiver.WebexConfigs {
+		httpConfig(prefix("webexConfigs", i), c.HTTPConfig)
+	}
+	for i, c := range receiver.MSTeamsConfigs {
+		p := prefix("msteamsConfigs", i)
+		secret(p+"webhookUrl", &c.WebhookURL)
+		httpConfig(p, c.HTTPConfig)
+	}
+
+	return refs
+}
+
+// isAlertmanagerConfigSelected reports whether an Alertmanager selects the
+// AlertmanagerConfig through its alertmanagerConfigSelector and
+// alertmanagerConfigNamespaceSelector, or uses it as its global
+// configuration.
+func isAlertmanagerConfigSelected(ctx context.Context, clientSets *k8sutil.ClientSets, amConfig *monitoringv1alpha1.AlertmanagerConfig) (bool, error) {
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return false, err
+	}
+
+	var selected bool
+	err = k8sutil.EachListItem(ctx, "Alertmanagers", clientSets.MClient.MonitoringV1().Alertmanagers(metav1.NamespaceAll).List, metav1.ListOptions{}, func(am *monitoringv1.Alertmanager) error {
+		global := am.Spec.AlertmanagerConfiguration != nil && am.Spec.AlertmanagerConfiguration.Name == amConfig.Name && am.Namespace == amConfig.Namespace
+		selected = selected || global || selectsObject(am.Namespace, am.Spec.AlertmanagerConfigSelector, am.Spec.AlertmanagerConfigNamespaceSelector, amConfig.ObjectMeta, namespaces)
+		return nil
+	})
+	return selected, err
+}
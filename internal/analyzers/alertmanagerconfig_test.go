@@ -0,0 +1,182 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAlertmanagerConfigAnalyzer(t *testing.T) {
+	namespaces := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+	}
+	alertmanager := &monitoringv1.Alertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+		Spec: monitoringv1.AlertmanagerSpec{
+			AlertmanagerConfigSelector:          &metav1.LabelSelector{},
+			AlertmanagerConfigNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		},
+	}
+	slackSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "slack", Namespace: "team-a"},
+		Data:       map[string][]byte{"url": []byte("https://hooks.slack.com/services/x")},
+	}
+	slack := monitoringv1alpha1.Receiver{
+		Name: "slack",
+		SlackConfigs: []monitoringv1alpha1.SlackConfig{{
+			APIURL: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "slack"}, Key: "url"},
+		}},
+	}
+	child := func(raw string) []apiextensionsv1.JSON {
+		return []apiextensionsv1.JSON{{Raw: []byte(raw)}}
+	}
+
+	newConfig := func(route *monitoringv1alpha1.Route, receivers ...monitoringv1alpha1.Receiver) *monitoringv1alpha1.AlertmanagerConfig {
+		return &monitoringv1alpha1.AlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a", Namespace: "team-a"},
+			Spec: monitoringv1alpha1.AlertmanagerConfigSpec{
+				Route:             route,
+				Receivers:         receivers,
+				MuteTimeIntervals: []monitoringv1alpha1.MuteTimeInterval{{Name: "weekends"}},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		objects    []runtime.Object
+		expectedID messages.ID
+	}{
+		{
+			name:       "NotFound",
+			objects:    []runtime.Object{alertmanager},
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name:       "DuplicateReceiver",
+			objects:    []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "slack"}, slack, slack)},
+			expectedID: messages.AMConfigDuplicateReceiver,
+		},
+		{
+			name:       "UnknownReceiver",
+			objects:    []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "pagerduty"}, slack)},
+			expectedID: messages.AMConfigUnknownReceiver,
+		},
+		{
+			name:       "MissingTopReceiver",
+			objects:    []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{}, slack)},
+			expectedID: messages.AMConfigUnknownReceiver,
+		},
+		{
+			name:       "UnknownChildReceiver",
+			objects:    []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "slack", Routes: child(`{"receiver":"pagerduty"}`)}, slack)},
+			expectedID: messages.AMConfigUnknownReceiver,
+		},
+		{
+			name:       "InvalidChildRoute",
+			objects:    []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "slack", Routes: child(`{"receiver":`)}, slack)},
+			expectedID: messages.AMConfigInvalidRoute,
+		},
+		{
+			name:       "UnknownTimeInterval",
+			objects:    []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "slack", Routes: child(`{"muteTimeIntervals":["holidays"]}`)}, slack)},
+			expectedID: messages.AMConfigUnknownTimeInterval,
+		},
+		{
+			name:       "SecretNotFound",
+			objects:    []runtime.Object{alertmanager, newConfig(&monitoringv1alpha1.Route{Receiver: "slack"}, slack)},
+			expectedID: messages.AMConfigInvalidReference,
+		},
+		{
+			name:       "NotSelected",
+			objects:    []runtime.Object{slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "slack"}, slack)},
+			expectedID: messages.AMConfigNotSelected,
+		},
+		{
+			name:    "Valid",
+			objects: []runtime.Object{alertmanager, slackSecret, newConfig(&monitoringv1alpha1.Route{Receiver: "slack", Routes: child(`{"muteTimeIntervals":["weekends"]}`)}, slack)},
+		},
+		{
+			// The unused receivers and the missing route are only warnings.
+			name:    "WithoutRoute",
+			objects: []runtime.Object{alertmanager, slackSecret, newConfig(nil, slack)},
+		},
+		{
+			name: "GlobalConfiguration",
+			objects: []runtime.Object{
+				&monitoringv1.Alertmanager{
+					ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "team-a"},
+					Spec: monitoringv1.AlertmanagerSpec{
+						AlertmanagerConfiguration: &monitoringv1.AlertmanagerConfiguration{Name: "team-a"},
+					},
+				},
+				slackSecret,
+				newConfig(&monitoringv1alpha1.Route{Receiver: "slack"}, slack),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(append(namespaces, tc.objects...)...))
+
+			err := analysisErr(RunAlertmanagerConfigAnalyzer(context.Background(), clientSets, "team-a", "team-a"))
+			if tc.expectedID == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			id, ok := messages.IDOf(err)
+			require.True(t, ok, "unexpected error %v", err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
+
+func TestReceiverReferences(t *testing.T) {
+	selector := func(name string) *corev1.SecretKeySelector {
+		return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "key"}
+	}
+
+	refs := receiverReferences(monitoringv1alpha1.Receiver{
+		Name: "oncall",
+		PagerDutyConfigs: []monitoringv1alpha1.PagerDutyConfig{{
+			RoutingKey: selector("pagerduty"),
+			HTTPConfig: &monitoringv1alpha1.HTTPConfig{BearerTokenSecret: selector("token")},
+		}},
+		MSTeamsConfigs: []monitoringv1alpha1.MSTeamsConfig{{WebhookURL: *selector("msteams")}},
+	})
+
+	var fields []string
+	for _, r := range refs {
+		fields = append(fields, r.field)
+	}
+	assert.Equal(t, []string{
+		"receivers[oncall].pagerdutyConfigs[0].routingKey",
+		"receivers[oncall].pagerdutyConfigs[0].httpConfig.bearerTokenSecret",
+		"receivers[oncall].msteamsConfigs[0].webhookUrl",
+	}, fields)
+}
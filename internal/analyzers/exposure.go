@@ -0,0 +1,129 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"maps"
+
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// prometheusWebPort is the port of the Prometheus web server.
+const prometheusWebPort = 9090
+
+// prometheusExposureWarnings returns the security-sensitive settings of a
+// Prometheus which are exposed to the network: the admin API and the remote
+// write receiver without client authentication nor NetworkPolicy, and
+// Services exposing the web port while Prometheus only listens on localhost.
+func prometheusExposureWarnings(prometheus *monitoringv1.Prometheus, services []corev1.Service, policies []networkingv1.NetworkPolicy) []analyzerWarning {
+	var warnings []analyzerWarning
+
+	podLabels := prometheusPodLabels(prometheus)
+	protected := hasClientAuthentication(prometheus.Spec.Web) || isIngressRestricted(podLabels, policies)
+
+	if prometheus.Spec.EnableAdminAPI && !protected {
+		warnings = append(warnings, newWarning(messages.AdminAPIExposed))
+	}
+
+	if prometheus.Spec.EnableRemoteWriteReceiver && !protected {
+		warnings = append(warnings, newWarning(messages.RemoteWriteReceiverExposed))
+	}
+
+	if prometheus.Spec.ListenLocal {
+		for _, svc := range services {
+			if exposesPort(svc, podLabels, prometheusWebPort, "web") {
+				warnings = append(warnings, newWarning(messages.ListenLocalExposed, svc.Name))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// prometheusPodLabels returns the labels set by the operator on the pods of a
+// Prometheus, along with the labels from its podMetadata.
+func prometheusPodLabels(prometheus *monitoringv1.Prometheus) labels.Set {
+	podLabels := labels.Set{
+		"app.kubernetes.io/name":      "prometheus",
+		"app.kubernetes.io/instance":  prometheus.Name,
+		"prometheus":                  prometheus.Name,
+		"operator.prometheus.io/name": prometheus.Name,
+	}
+
+	if prometheus.Spec.PodMetadata != nil {
+		maps.Copy(podLabels, prometheus.Spec.PodMetadata.Labels)
+	}
+
+	return podLabels
+}
+
+// hasClientAuthentication reports whether the web server requires client
+// certificates.
+func hasClientAuthentication(web *monitoringv1.PrometheusWebSpec) bool {
+	if web == nil || web.TLSConfig == nil {
+		return false
+	}
+
+	return web.TLSConfig.ClientAuthType == "RequireAndVerifyClientCert"
+}
+
+// isIngressRestricted reports whether a NetworkPolicy restricting the ingress
+// traffic selects the pods.
+func isIngressRestricted(podLabels labels.Set, policies []networkingv1.NetworkPolicy) bool {
+	for _, policy := range policies {
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil || !selector.Matches(podLabels) {
+			continue
+		}
+
+		// Policies without policyTypes always apply to the ingress traffic.
+		if len(policy.Spec.PolicyTypes) == 0 {
+			return true
+		}
+
+		for _, policyType := range policy.Spec.PolicyTypes {
+			if policyType == networkingv1.PolicyTypeIngress {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// exposesPort reports whether the Service selects the pods and routes traffic
+// to the given container port, referenced by number or by name.
+func exposesPort(svc corev1.Service, podLabels labels.Set, port int32, portName string) bool {
+	if len(svc.Spec.Selector) == 0 || !labels.SelectorFromSet(svc.Spec.Selector).Matches(podLabels) {
+		return false
+	}
+
+	for _, p := range svc.Spec.Ports {
+		if p.TargetPort.String() == portName || p.TargetPort.IntValue() == int(port) {
+			return true
+		}
+
+		if p.TargetPort.String() == "0" && p.Port == port {
+			return true
+		}
+	}
+
+	return false
+}
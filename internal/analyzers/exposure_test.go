@@ -0,0 +1,159 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPrometheusExposureWarnings(t *testing.T) {
+	type testCase struct {
+		name             string
+		spec             monitoringv1.PrometheusSpec
+		services         []corev1.Service
+		policies         []networkingv1.NetworkPolicy
+		expectedMessages []string
+	}
+
+	webService := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "prometheus",
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"prometheus": "prometheus",
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "web",
+					Port:       9090,
+					TargetPort: intstr.FromString("web"),
+				},
+			},
+		},
+	}
+
+	ingressPolicy := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "prometheus",
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name": "prometheus",
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+
+	tests := []testCase{
+		{
+			name: "DefaultSettings",
+			spec: monitoringv1.PrometheusSpec{},
+		},
+		{
+			name: "AdminAPIWithoutProtection",
+			spec: monitoringv1.PrometheusSpec{
+				EnableAdminAPI: true,
+			},
+			expectedMessages: []string{"enableAdminAPI is enabled without authentication nor NetworkPolicy"},
+		},
+		{
+			name: "RemoteWriteReceiverWithoutProtection",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					EnableRemoteWriteReceiver: true,
+				},
+			},
+			expectedMessages: []string{"enableRemoteWriteReceiver is enabled without authentication nor NetworkPolicy"},
+		},
+		{
+			name: "AdminAPIWithNetworkPolicy",
+			spec: monitoringv1.PrometheusSpec{
+				EnableAdminAPI: true,
+			},
+			policies: []networkingv1.NetworkPolicy{ingressPolicy},
+		},
+		{
+			name: "RemoteWriteReceiverWithClientCertificates",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					EnableRemoteWriteReceiver: true,
+					Web: &monitoringv1.PrometheusWebSpec{
+						WebConfigFileFields: monitoringv1.WebConfigFileFields{
+							TLSConfig: &monitoringv1.WebTLSConfig{
+								ClientAuthType: "RequireAndVerifyClientCert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "EgressOnlyNetworkPolicy",
+			spec: monitoringv1.PrometheusSpec{
+				EnableAdminAPI: true,
+			},
+			policies: []networkingv1.NetworkPolicy{
+				{
+					Spec: networkingv1.NetworkPolicySpec{
+						PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+					},
+				},
+			},
+			expectedMessages: []string{"enableAdminAPI is enabled without authentication nor NetworkPolicy"},
+		},
+		{
+			name: "ListenLocalWithService",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					ListenLocal: true,
+				},
+			},
+			services:         []corev1.Service{webService},
+			expectedMessages: []string{"listenLocal is enabled but Service prometheus exposes the web port"},
+		},
+		{
+			name:     "ServiceWithoutListenLocal",
+			spec:     monitoringv1.PrometheusSpec{},
+			services: []corev1.Service{webService},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prometheus := &monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "prometheus",
+				},
+				Spec: tc.spec,
+			}
+
+			var messages []string
+			for _, w := range prometheusExposureWarnings(prometheus, tc.services, tc.policies) {
+				messages = append(messages, w.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"errors"
+
+	"github.com/prometheus-operator/poctl/internal/messages"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+const (
+	// SeverityError is the severity of the findings failing the analysis.
+	SeverityError Severity = "error"
+	// SeverityWarning is the severity of the configurations which are valid
+	// but likely to cause problems, which don't fail the analysis.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo is the severity of the informational findings, such as
+	// the compliance of an object.
+	SeverityInfo Severity = "info"
+)
+
+// Finding is the outcome of a check of an analyzer on an object.
+type Finding struct {
+	// Kind, Namespace and Name identify the analyzed object.
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Check is the ID of the message of the catalog reporting the finding,
+	// which identifies the check.
+	Check    messages.ID `json:"check"`
+	Severity Severity    `json:"severity"`
+	Message  string      `json:"message"`
+	// Fix is the suggested fix, empty when there is none.
+	Fix string `json:"fix,omitempty"`
+
+	// err is the error of the error findings.
+	err error
+}
+
+// Err returns the error findings as an error, nil when there are none. The
+// error carries the IDs of the messages of the findings.
+func Err(findings []Finding) error {
+	var errs []error
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// analyzerWarning describes a configuration which is valid but likely to cause
+// problems, along with guidance on how to address it.
+type analyzerWarning struct {
+	ID      messages.ID
+	Message string
+	Hint    string
+}
+
+// newWarning returns the warning of a message of the catalog.
+func newWarning(id messages.ID, args ...any) analyzerWarning {
+	return analyzerWarning{
+		ID:      id,
+		Message: messages.Text(id, args...),
+		Hint:    messages.Hint(id, args...),
+	}
+}
+
+// report collects the findings of the analysis of an object.
+type report struct {
+	kind      string
+	name      string
+	namespace string
+	findings  []Finding
+}
+
+func newReport(kind, name, namespace string) *report {
+	return &report{kind: kind, name: name, namespace: namespace}
+}
+
+// warn reports the warnings.
+func (r *report) warn(warnings ...analyzerWarning) {
+	for _, w := range warnings {
+		r.add(w.ID, SeverityWarning, w.Message, w.Hint, nil)
+	}
+}
+
+func (r *report) add(id messages.ID, severity Severity, message, fix string, err error) {
+	r.findings = append(r.findings, Finding{
+		Kind:      r.kind,
+		Namespace: r.namespace,
+		Name:      r.name,
+		Check:     id,
+		Severity:  severity,
+		Message:   message,
+		Fix:       fix,
+		err:       err,
+	})
+}
+
+// fail reports the error of a check of the catalog, the analysis going on
+// with the other checks.
+func (r *report) fail(id messages.ID, args ...any) {
+	err := messages.New(id, args...)
+	r.add(id, SeverityError, err.Error(), messages.Hint(id, args...), err)
+}
+
+// check reports the error of a check of the catalog, the analysis going on
+// with the other checks, and returns the other errors, such as the failures
+// of the requests to the API server, which end the analysis.
+func (r *report) check(err error) error {
+	var e *messages.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+
+	r.add(e.ID, SeverityError, err.Error(), messages.Hint(e.ID, e.Args...), err)
+	return nil
+}
+
+// failed reports whether an error was reported.
+func (r *report) failed() bool {
+	for _, f := range r.findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// result returns the findings of the analysis ended with err. The errors of
+// the catalog are reported as error findings while the other ones, such as
+// the failures of the requests to the API server, are returned along with
+// the findings reported so far. An analysis which reported no error reports
+// the object as compliant.
+func (r *report) result(err error) ([]Finding, error) {
+	if err := r.check(err); err != nil {
+		return r.findings, err
+	}
+
+	if !r.failed() {
+		r.add(messages.ObjectCompliant, SeverityInfo, messages.Text(messages.ObjectCompliant, r.kind), "", nil)
+	}
+	return r.findings, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// analysisErr returns the error of an analysis, either returned by the
+// analyzer or reported by its error findings.
+func analysisErr(findings []Finding, err error) error {
+	if err != nil {
+		return err
+	}
+	return Err(findings)
+}
+
+func TestReportResult(t *testing.T) {
+	t.Run("compliant", func(t *testing.T) {
+		r := newReport("Prometheus", "k8s", "monitoring")
+		r.warn(newWarning(messages.EndpointHonorLabels, "web"))
+
+		findings, err := r.result(nil)
+		require.NoError(t, err)
+		require.Len(t, findings, 2)
+
+		assert.Equal(t, Finding{
+			Kind:      "Prometheus",
+			Namespace: "monitoring",
+			Name:      "k8s",
+			Check:     messages.EndpointHonorLabels,
+			Severity:  SeverityWarning,
+			Message:   messages.Text(messages.EndpointHonorLabels, "web"),
+			Fix:       "labels exposed by the target override the target labels (job, instance, namespace...), only enable honorLabels for trusted sources such as federation or the Pushgateway",
+		}, findings[0])
+		assert.Equal(t, messages.ObjectCompliant, findings[1].Check)
+		assert.Equal(t, SeverityInfo, findings[1].Severity)
+		assert.Equal(t, "Prometheus is compliant, no issues found", findings[1].Message)
+		assert.NoError(t, Err(findings))
+	})
+
+	t.Run("catalog error", func(t *testing.T) {
+		r := newReport("Prometheus", "k8s", "monitoring")
+		r.warn(newWarning(messages.EndpointHonorLabels, "web"))
+
+		findings, err := r.result(fmt.Errorf("error while analyzing: %w", messages.New(messages.ObjectNotFound, "prometheus", "k8s", "monitoring")))
+		require.NoError(t, err)
+		require.Len(t, findings, 2)
+
+		assert.Equal(t, SeverityWarning, findings[0].Severity)
+		assert.Equal(t, messages.ObjectNotFound, findings[1].Check)
+		assert.Equal(t, SeverityError, findings[1].Severity)
+		assert.Equal(t, "error while analyzing: prometheus k8s not found in namespace monitoring", findings[1].Message)
+
+		err = Err(findings)
+		id, ok := messages.IDOf(err)
+		require.True(t, ok)
+		assert.Equal(t, messages.ObjectNotFound, id)
+	})
+
+	t.Run("several errors", func(t *testing.T) {
+		r := newReport("ThanosRuler", "main", "monitoring")
+		r.fail(messages.ThanosRulerNoQuery, "main", "monitoring")
+		require.NoError(t, r.check(messages.New(messages.SelectorNotDefined, "PrometheusRule")))
+
+		findings, err := r.result(nil)
+		require.NoError(t, err)
+		require.Len(t, findings, 2)
+		assert.Equal(t, messages.ThanosRulerNoQuery, findings[0].Check)
+		assert.Equal(t, messages.SelectorNotDefined, findings[1].Check)
+		for _, f := range findings {
+			assert.Equal(t, SeverityError, f.Severity)
+		}
+		assert.Len(t, Err(findings).(interface{ Unwrap() []error }).Unwrap(), 2)
+	})
+
+	t.Run("other error after a catalog error", func(t *testing.T) {
+		r := newReport("Prometheus", "k8s", "monitoring")
+		r.fail(messages.ServiceAccountNotBound, "prometheus")
+
+		requestErr := errors.New("connection refused")
+		require.ErrorIs(t, r.check(requestErr), requestErr)
+
+		findings, err := r.result(requestErr)
+		assert.ErrorIs(t, err, requestErr)
+		require.Len(t, findings, 1)
+		assert.Equal(t, messages.ServiceAccountNotBound, findings[0].Check)
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		r := newReport("Prometheus", "k8s", "monitoring")
+		r.warn(newWarning(messages.EndpointHonorLabels, "web"))
+
+		requestErr := errors.New("connection refused")
+		findings, err := r.result(requestErr)
+		assert.ErrorIs(t, err, requestErr)
+		require.Len(t, findings, 1)
+		assert.Equal(t, SeverityWarning, findings[0].Severity)
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/backtest"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// stalenessWindow is the delay after which Prometheus considers a
+	// series without new samples as stale.
+	stalenessWindow = 5 * time.Minute
+
+	// The defaults of Prometheus when the fields are empty.
+	defaultScrapeInterval = "30s"
+	defaultRetention      = "24h"
+)
+
+// scrapeInterval is the interval and the timeout set by a scrape endpoint,
+// empty when inherited from Prometheus.
+type scrapeInterval struct {
+	source   string
+	interval monitoringv1.Duration
+	timeout  monitoringv1.Duration
+}
+
+// intervalWarnings returns the warnings about the scrape intervals of
+// Prometheus and of the endpoints it selects: intervals beyond the staleness
+// window or the retention, timeouts longer than the intervals, and endpoint
+// intervals too long for the rate() windows sized for the global interval.
+// Invalid durations are left to the validation of the CRDs.
+func intervalWarnings(prometheus *monitoringv1.Prometheus, intervals []scrapeInterval) []analyzerWarning {
+	globalInterval := cmp.Or(prometheus.Spec.ScrapeInterval, defaultScrapeInterval)
+	global, err := backtest.ParseDuration(string(globalInterval))
+	if err != nil {
+		return nil
+	}
+
+	// The retention is only bounded by the size when only the size is set.
+	var retention time.Duration
+	retentionValue := prometheus.Spec.Retention
+	if retentionValue == "" && prometheus.Spec.RetentionSize == "" {
+		retentionValue = defaultRetention
+	}
+	if retentionValue != "" {
+		retention, _ = backtest.ParseDuration(string(retentionValue))
+	}
+
+	source := fmt.Sprintf("Prometheus %s/%s", prometheus.Namespace, prometheus.Name)
+	warnings := durationWarnings(source, globalInterval, global, retention, retentionValue)
+	// Prometheus caps the default timeout to the interval, only the timeouts
+	// which are set can exceed it.
+	if timeout, err := backtest.ParseDuration(string(prometheus.Spec.ScrapeTimeout)); prometheus.Spec.ScrapeTimeout != "" && err == nil && timeout > global {
+		warnings = append(warnings, newWarning(messages.ScrapeTimeoutAboveInterval, source, prometheus.Spec.ScrapeTimeout, globalInterval))
+	}
+
+	for _, i := range intervals {
+		if i.interval == "" {
+			continue
+		}
+
+		interval, err := backtest.ParseDuration(string(i.interval))
+		if err != nil {
+			continue
+		}
+
+		warnings = append(warnings, durationWarnings(i.source, i.interval, interval, retention, retentionValue)...)
+
+		if interval > 2*global {
+			warnings = append(warnings, newWarning(messages.ScrapeIntervalInconsistent, i.source, i.interval, globalInterval))
+		}
+
+		if timeout, err := backtest.ParseDuration(string(i.timeout)); i.timeout != "" && err == nil && timeout > interval {
+			warnings = append(warnings, newWarning(messages.ScrapeTimeoutAboveInterval, i.source, i.timeout, i.interval))
+		}
+	}
+
+	return warnings
+}
+
+// durationWarnings returns the warnings of an interval beyond the staleness
+// window or the retention, a zero retention being unbounded.
+func durationWarnings(source string, value monitoringv1.Duration, interval, retention time.Duration, retentionValue monitoringv1.Duration) []analyzerWarning {
+	var warnings []analyzerWarning
+	if interval > stalenessWindow {
+		warnings = append(warnings, newWarning(messages.ScrapeIntervalAboveStaleness, source, value))
+	}
+
+	if retention > 0 && interval > retention {
+		warnings = append(warnings, newWarning(messages.ScrapeIntervalAboveRetention, source, value, retentionValue))
+	}
+	return warnings
+}
+
+// selectedScrapeIntervals returns the intervals of the endpoints of the
+// ServiceMonitors, PodMonitors and Probes selected by the Prometheus.
+func selectedScrapeIntervals(ctx context.Context, clientSets *k8sutil.ClientSets, prometheus *monitoringv1.Prometheus) ([]scrapeInterval, error) {
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return nil, err
+	}
+
+	var intervals []scrapeInterval
+
+	err = k8sutil.EachListItem(ctx, "ServiceMonitors", clientSets.MClient.MonitoringV1().ServiceMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(sm *monitoringv1.ServiceMonitor) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.ServiceMonitorSelector, prometheus.Spec.ServiceMonitorNamespaceSelector, sm.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		for i, endpoint := range sm.Spec.Endpoints {
+			intervals = append(intervals, scrapeInterval{
+				source:   fmt.Sprintf("ServiceMonitor %s/%s endpoints[%d]", sm.Namespace, sm.Name, i),
+				interval: endpoint.Interval,
+				timeout:  endpoint.ScrapeTimeout,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PodMonitors", clientSets.MClient.MonitoringV1().PodMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(pm *monitoringv1.PodMonitor) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.PodMonitorSelector, prometheus.Spec.PodMonitorNamespaceSelector, pm.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		for i, endpoint := range pm.Spec.PodMetricsEndpoints {
+			intervals = append(intervals, scrapeInterval{
+				source:   fmt.Sprintf("PodMonitor %s/%s podMetricsEndpoints[%d]", pm.Namespace, pm.Name, i),
+				interval: endpoint.Interval,
+				timeout:  endpoint.ScrapeTimeout,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Probes", clientSets.MClient.MonitoringV1().Probes(metav1.NamespaceAll).List, metav1.ListOptions{}, func(probe *monitoringv1.Probe) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.ProbeSelector, prometheus.Spec.ProbeNamespaceSelector, probe.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		intervals = append(intervals, scrapeInterval{
+			source:   fmt.Sprintf("Probe %s/%s", probe.Namespace, probe.Name),
+			interval: probe.Spec.Interval,
+			timeout:  probe.Spec.ScrapeTimeout,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return intervals, nil
+}
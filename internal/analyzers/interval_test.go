@@ -0,0 +1,120 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIntervalWarnings(t *testing.T) {
+	type testCase struct {
+		name             string
+		spec             monitoringv1.PrometheusSpec
+		intervals        []scrapeInterval
+		expectedMessages []string
+	}
+
+	tests := []testCase{
+		{
+			name: "DefaultIntervals",
+			intervals: []scrapeInterval{
+				{source: "ServiceMonitor default/app endpoints[0]"},
+				{source: "Probe default/blackbox", interval: "1m", timeout: "10s"},
+			},
+		},
+		{
+			name: "EndpointIntervalAboveStaleness",
+			intervals: []scrapeInterval{
+				{source: "ServiceMonitor default/app endpoints[0]", interval: "10m"},
+			},
+			expectedMessages: []string{
+				"ServiceMonitor default/app endpoints[0] scrapes every 10m, beyond the 5m staleness window",
+				"ServiceMonitor default/app endpoints[0] scrapes every 10m, more than twice the scrapeInterval 30s of Prometheus",
+			},
+		},
+		{
+			name: "GlobalIntervalAboveRetention",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					ScrapeInterval: "2h",
+				},
+				Retention: "1h",
+			},
+			expectedMessages: []string{
+				"Prometheus default/k8s scrapes every 2h, beyond the 5m staleness window",
+				"Prometheus default/k8s scrapes every 2h, beyond the retention 1h of Prometheus",
+			},
+		},
+		{
+			name: "RetentionSizeOnly",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					ScrapeInterval: "5m",
+				},
+				RetentionSize: "10GB",
+			},
+			intervals: []scrapeInterval{
+				{source: "PodMonitor default/app podMetricsEndpoints[0]", interval: "48h"},
+			},
+			expectedMessages: []string{
+				"PodMonitor default/app podMetricsEndpoints[0] scrapes every 48h, beyond the 5m staleness window",
+				"PodMonitor default/app podMetricsEndpoints[0] scrapes every 48h, more than twice the scrapeInterval 5m of Prometheus",
+			},
+		},
+		{
+			name: "EndpointTimeoutAboveInterval",
+			intervals: []scrapeInterval{
+				{source: "Probe default/blackbox", interval: "15s", timeout: "20s"},
+			},
+			expectedMessages: []string{
+				"Probe default/blackbox has a scrapeTimeout of 20s, longer than its interval 15s",
+			},
+		},
+		{
+			name: "GlobalTimeoutAboveInterval",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					ScrapeInterval: "10s",
+					ScrapeTimeout:  "30s",
+				},
+			},
+			expectedMessages: []string{
+				"Prometheus default/k8s has a scrapeTimeout of 30s, longer than its interval 10s",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prometheus := &monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "k8s",
+					Namespace: "default",
+				},
+				Spec: tc.spec,
+			}
+
+			var messages []string
+			for _, w := range intervalWarnings(prometheus, tc.intervals) {
+				messages = append(messages, w.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+}
@@ -17,19 +17,31 @@ package analyzers
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/prometheus-operator/poctl/internal/crds"
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func RunOperatorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) error {
+func RunOperatorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("Prometheus Operator", name, namespace)
+	return r.result(analyzeOperator(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeOperator(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
 	op, err := clientSets.KClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to get Prometheus Operator deployment: %w", err)
 	}
 
+	if namespaces := watchedNamespaces(op); len(namespaces) > 0 {
+		return analyzeNamespacedRBAC(ctx, clientSets, r, op, namespaces)
+	}
+
 	cRb, err := clientSets.KClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{
 		LabelSelector: "app.kubernetes.io/name=prometheus-operator",
 	})
@@ -39,17 +51,109 @@ func RunOperatorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, na
 	}
 
 	if !k8sutil.IsServiceAccountBoundToRoleBindingList(cRb, op.Spec.Template.Spec.ServiceAccountName) {
-		return fmt.Errorf("ServiceAccount %s is not bound to any RoleBindings", op.Spec.Template.Spec.ServiceAccountName)
+		r.fail(messages.ServiceAccountNotBound, op.Spec.Template.Spec.ServiceAccountName)
 	}
 
+	var rules []v1.PolicyRule
 	for _, crb := range cRb.Items {
 		cr, err := clientSets.KClient.RbacV1().ClusterRoles().Get(ctx, crb.RoleRef.Name, metav1.GetOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to get ClusterRole %s", crb.RoleRef.Name)
 		}
 
-		err = analyzeClusterRoleAndCRDRules(ctx, clientSets, crb, cr)
+		if err := r.check(analyzeRoleAndCRDRules(ctx, clientSets, "ClusterRole", crb.RoleRef.Name, cr.Rules)); err != nil {
+			return err
+		}
+
+		if isServiceAccountSubject(crb.Subjects, op.Namespace, op.Spec.Template.Spec.ServiceAccountName) {
+			rules = append(rules, cr.Rules...)
+		}
+	}
+
+	return analyzeOperatorVerbs(op, rules, metav1.NamespaceAll)
+}
+
+// watchedNamespaces returns the namespaces passed to the --namespaces argument
+// of the operator, which is empty for cluster-wide installs.
+func watchedNamespaces(op *appsv1.Deployment) []string {
+	var namespaces []string
+	for _, container := range op.Spec.Template.Spec.Containers {
+		for i, arg := range container.Args {
+			var value string
+			switch {
+			case strings.HasPrefix(arg, "--namespaces="):
+				value = strings.TrimPrefix(arg, "--namespaces=")
+			case arg == "--namespaces" && i+1 < len(container.Args):
+				value = container.Args[i+1]
+			default:
+				continue
+			}
+
+			for _, ns := range strings.Split(value, ",") {
+				if ns = strings.TrimSpace(ns); ns != "" {
+					namespaces = append(namespaces, ns)
+				}
+			}
+		}
+	}
+	return namespaces
+}
+
+// analyzeNamespacedRBAC checks that the ServiceAccount of a namespaced
+// operator is granted access to the CRDs and the resources it manages in each
+// watched namespace, through RoleBindings to either a Role or a ClusterRole.
+// Each namespace is reported on its own.
+func analyzeNamespacedRBAC(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, op *appsv1.Deployment, namespaces []string) error {
+	serviceAccountName := op.Spec.Template.Spec.ServiceAccountName
+	for _, ns := range namespaces {
+		rbs, err := clientSets.KClient.RbacV1().RoleBindings(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=prometheus-operator",
+		})
 		if err != nil {
+			return fmt.Errorf("failed to list RoleBindings in namespace %s: %w", ns, err)
+		}
+
+		var (
+			bound      bool
+			boundRules []v1.PolicyRule
+		)
+		for _, rb := range rbs.Items {
+			if !isServiceAccountSubject(rb.Subjects, op.Namespace, serviceAccountName) {
+				continue
+			}
+			bound = true
+
+			var (
+				kind  = rb.RoleRef.Kind
+				rules []v1.PolicyRule
+			)
+			if kind == "ClusterRole" {
+				cr, err := clientSets.KClient.RbacV1().ClusterRoles().Get(ctx, rb.RoleRef.Name, metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("failed to get ClusterRole %s", rb.RoleRef.Name)
+				}
+				rules = cr.Rules
+			} else {
+				kind = "Role"
+				role, err := clientSets.KClient.RbacV1().Roles(ns).Get(ctx, rb.RoleRef.Name, metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("failed to get Role %s in namespace %s", rb.RoleRef.Name, ns)
+				}
+				rules = role.Rules
+			}
+
+			if err := r.check(analyzeRoleAndCRDRules(ctx, clientSets, kind, rb.RoleRef.Name, rules)); err != nil {
+				return err
+			}
+			boundRules = append(boundRules, rules...)
+		}
+
+		if !bound {
+			r.fail(messages.OperatorNotBoundInNamespace, serviceAccountName, ns)
+			continue
+		}
+
+		if err := r.check(analyzeOperatorVerbs(op, boundRules, ns)); err != nil {
 			return err
 		}
 	}
@@ -57,13 +161,24 @@ func RunOperatorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, na
 	return nil
 }
 
-func analyzeClusterRoleAndCRDRules(ctx context.Context, clientSets *k8sutil.ClientSets, crb v1.ClusterRoleBinding, cr *v1.ClusterRole) error {
+// isServiceAccountSubject reports whether the subjects include the
+// ServiceAccount with the given namespace and name.
+func isServiceAccountSubject(subjects []v1.Subject, namespace, serviceAccountName string) bool {
+	for _, subject := range subjects {
+		if subject.Kind == "ServiceAccount" && subject.Namespace == namespace && subject.Name == serviceAccountName {
+			return true
+		}
+	}
+	return false
+}
+
+func analyzeRoleAndCRDRules(ctx context.Context, clientSets *k8sutil.ClientSets, kind, roleName string, rules []v1.PolicyRule) error {
 	foundAPIGroup := false
-	for _, rule := range cr.Rules {
+	for _, rule := range rules {
 		for _, apiGroup := range rule.APIGroups {
 			if apiGroup == "monitoring.coreos.com" {
 				foundAPIGroup = true
-				err := analyzeCRDRules(ctx, clientSets, crb, rule)
+				err := analyzeCRDRules(ctx, clientSets, kind, roleName, rule)
 				if err != nil {
 					return err
 				}
@@ -73,13 +188,13 @@ func analyzeClusterRoleAndCRDRules(ctx context.Context, clientSets *k8sutil.Clie
 	}
 
 	if !foundAPIGroup {
-		return fmt.Errorf("ClusterRole %s does not have monitoring.coreos.com APIGroup in its rules", crb.RoleRef.Name)
+		return messages.New(messages.OperatorMissingAPIGroup, kind, roleName)
 	}
 
 	return nil
 }
 
-func analyzeCRDRules(ctx context.Context, clientSets *k8sutil.ClientSets, crb v1.ClusterRoleBinding, rule v1.PolicyRule) error {
+func analyzeCRDRules(ctx context.Context, clientSets *k8sutil.ClientSets, kind, roleName string, rule v1.PolicyRule) error {
 	for _, crdName := range crds.List {
 		crd, err := clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
 		if err != nil {
@@ -95,7 +210,7 @@ func analyzeCRDRules(ctx context.Context, clientSets *k8sutil.ClientSets, crb v1
 		}
 
 		if !found {
-			return fmt.Errorf("ClusterRole %s does not have %s in its rules", crb.RoleRef.Name, crd.Spec.Names.Plural)
+			return messages.New(messages.OperatorMissingResource, kind, roleName, crd.Spec.Names.Plural)
 		}
 	}
 	return nil
@@ -0,0 +1,186 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/messages"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// allVerbs are the verbs required for the resources the operator fully
+// manages, granted by the "*" verb in its released rules.
+var allVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+
+// operatorRBACRequirement is a permission the operator needs on a resource
+// besides its CRDs.
+type operatorRBACRequirement struct {
+	// Groups are the API groups the resource can be granted in, any of them
+	// is enough.
+	Groups   []string
+	Resource string
+	Verbs    []string
+	// Cluster marks the cluster-scoped resources, which an operator
+	// restricted to namespaces doesn't use.
+	Cluster bool
+	// Since is the first operator version needing the permission, all
+	// versions need it when empty.
+	Since string
+	// FeatureGate is the feature gate of the operator needing the
+	// permission, it's always needed when empty.
+	FeatureGate string
+}
+
+// operatorRBACRequirements are the permissions needed by the operator, which
+// follow the rules released with each operator version.
+var operatorRBACRequirements = []operatorRBACRequirement{
+	{Groups: []string{"apps"}, Resource: "statefulsets", Verbs: allVerbs},
+	{Groups: []string{"apps"}, Resource: "daemonsets", Verbs: allVerbs, FeatureGate: "PrometheusAgentDaemonSet"},
+	{Groups: []string{""}, Resource: "configmaps", Verbs: allVerbs},
+	{Groups: []string{""}, Resource: "secrets", Verbs: allVerbs},
+	{Groups: []string{""}, Resource: "pods", Verbs: []string{"list", "delete"}},
+	{Groups: []string{""}, Resource: "services", Verbs: []string{"get", "create", "update", "delete"}},
+	{Groups: []string{""}, Resource: "services/finalizers", Verbs: []string{"get", "create", "update", "delete"}},
+	{Groups: []string{""}, Resource: "endpoints", Verbs: []string{"get", "create", "update", "delete"}},
+	{Groups: []string{"", "events.k8s.io"}, Resource: "events", Verbs: []string{"create", "patch"}, Since: "0.70.0"},
+	{Groups: []string{"networking.k8s.io"}, Resource: "ingresses", Verbs: []string{"get", "list", "watch"}},
+	{Groups: []string{""}, Resource: "nodes", Verbs: []string{"list", "watch"}, Cluster: true},
+	{Groups: []string{""}, Resource: "namespaces", Verbs: []string{"get", "list", "watch"}, Cluster: true},
+	{Groups: []string{"storage.k8s.io"}, Resource: "storageclasses", Verbs: []string{"get"}, Cluster: true, Since: "0.73.0"},
+}
+
+// analyzeOperatorVerbs checks that the rules granted to the ServiceAccount of
+// the operator allow each verb it needs on each resource, cluster-wide or
+// in the namespace. It reports the first resource with missing verbs.
+func analyzeOperatorVerbs(op *appsv1.Deployment, rules []rbacv1.PolicyRule, namespace string) error {
+	version := operatorVersion(op)
+	gates := operatorFeatureGates(op)
+
+	for _, req := range operatorRBACRequirements {
+		if req.Cluster && namespace != metav1.NamespaceAll {
+			continue
+		}
+		if req.Since != "" && version != "" && !versionAtLeast(version, req.Since) {
+			continue
+		}
+		if req.FeatureGate != "" && !slices.Contains(gates, req.FeatureGate) {
+			continue
+		}
+
+		if missing := missingVerbs(rules, req); len(missing) > 0 {
+			shownVersion := version
+			if shownVersion == "" {
+				shownVersion = "unknown"
+			}
+			return messages.New(messages.OperatorMissingVerbs, op.Spec.Template.Spec.ServiceAccountName, shownVersion, strings.Join(missing, ", "), req.Resource, scopeSuffix(namespace))
+		}
+	}
+
+	return nil
+}
+
+// missingVerbs returns the verbs of the requirement which none of the rules
+// allow.
+func missingVerbs(rules []rbacv1.PolicyRule, req operatorRBACRequirement) []string {
+	var missing []string
+	for _, verb := range req.Verbs {
+		allowed := slices.ContainsFunc(rules, func(r rbacv1.PolicyRule) bool {
+			return len(r.ResourceNames) == 0 &&
+				slices.ContainsFunc(req.Groups, func(group string) bool { return matchesRuleValue(r.APIGroups, group) }) &&
+				matchesRuleValue(r.Resources, req.Resource) &&
+				matchesRuleValue(r.Verbs, verb)
+		})
+		if !allowed {
+			missing = append(missing, verb)
+		}
+	}
+	return missing
+}
+
+// operatorVersion returns the version of the operator, from the version label
+// of the Deployment or else from the tag of its image. It's empty when
+// unknown.
+func operatorVersion(op *appsv1.Deployment) string {
+	if version := op.Labels["app.kubernetes.io/version"]; version != "" {
+		return strings.TrimPrefix(version, "v")
+	}
+
+	for _, container := range op.Spec.Template.Spec.Containers {
+		if !strings.Contains(container.Image, "prometheus-operator:") {
+			continue
+		}
+		return strings.TrimPrefix(container.Image[strings.LastIndex(container.Image, ":")+1:], "v")
+	}
+
+	return ""
+}
+
+// operatorFeatureGates returns the feature gates enabled by the
+// --feature-gates argument of the operator.
+func operatorFeatureGates(op *appsv1.Deployment) []string {
+	var gates []string
+	for _, container := range op.Spec.Template.Spec.Containers {
+		for _, arg := range container.Args {
+			value, ok := strings.CutPrefix(arg, "--feature-gates=")
+			if !ok {
+				continue
+			}
+
+			for _, gate := range strings.Split(value, ",") {
+				name, enabled, _ := strings.Cut(gate, "=")
+				if enabled == "true" {
+					gates = append(gates, strings.TrimSpace(name))
+				}
+			}
+		}
+	}
+	return gates
+}
+
+// versionAtLeast reports whether the version is greater than or equal to
+// minVersion, comparing the major, minor and patch numbers. Unparsable
+// versions are considered recent.
+func versionAtLeast(version, minVersion string) bool {
+	v, ok := parseVersion(version)
+	if !ok {
+		return true
+	}
+	m, _ := parseVersion(minVersion)
+
+	return slices.Compare(v, m) >= 0
+}
+
+func parseVersion(version string) ([]int, bool) {
+	version, _, _ = strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	numbers := make([]int, 0, 3)
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
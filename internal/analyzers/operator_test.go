@@ -89,6 +89,91 @@ func getDefaultClusterRoleBinding(namespace string) []rbacv1.ClusterRoleBinding
 	}
 }
 
+func getNamespacedDeployment(name, namespace string, watchedNamespaces string) *appsv1.Deployment {
+	deployment := getDefaultDeployment(name, namespace)
+	deployment.Spec.Template.Spec.Containers[0].Args = []string{
+		"--namespaces=" + watchedNamespaces,
+	}
+	return deployment
+}
+
+func getDefaultRoleBinding(namespace, serviceAccountNamespace string) rbacv1.RoleBinding {
+	return rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus-operator",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name": "prometheus-operator",
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Kind: "Role",
+			Name: "prometheus-operator",
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      "prometheus-operator",
+				Namespace: serviceAccountNamespace,
+			},
+		},
+	}
+}
+
+func getAlertmanagerCRD() *apiextensions.CustomResourceDefinition {
+	return &apiextensions.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "alertmanager.monitoring.coreos.com",
+		},
+		Spec: apiextensions.CustomResourceDefinitionSpec{
+			Names: apiextensions.CustomResourceDefinitionNames{
+				Singular: "alertmanager",
+				Plural:   "alertmanagers",
+			},
+		},
+	}
+}
+
+func getOperatorWorkloadRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{"apps"},
+			Resources: []string{"statefulsets"},
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps", "secrets"},
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"pods"},
+			Verbs:     []string{"list", "delete"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"services", "services/finalizers", "endpoints"},
+			Verbs:     []string{"get", "create", "update", "delete"},
+		},
+		{
+			APIGroups: []string{"networking.k8s.io"},
+			Resources: []string{"ingresses"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"nodes"},
+			Verbs:     []string{"list", "watch"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"namespaces"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+	}
+}
+
 func TestOperatorAnalyzer(t *testing.T) {
 	type testCase struct {
 		name                string
@@ -264,12 +349,12 @@ func TestOperatorAnalyzer(t *testing.T) {
 						ObjectMeta: metav1.ObjectMeta{
 							Name: "prometheus-operator",
 						},
-						Rules: []rbacv1.PolicyRule{
+						Rules: append([]rbacv1.PolicyRule{
 							{
 								APIGroups: []string{"monitoring.coreos.com"},
 								Resources: []string{"prometheuses", "prometheusrules", "servicemonitors", "podmonitors", "thanosrulers", "alertmanagers"},
 							},
-						},
+						}, getOperatorWorkloadRules()...),
 					}, nil
 				})
 
@@ -287,6 +372,144 @@ func TestOperatorAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				return k8sutil.ClientSets{
+					KClient:             kClient,
+					APIExtensionsClient: apiExtensionsClient,
+				}
+			},
+		},
+		{
+			name:       "NamespacedRoleFound",
+			namespace:  "test",
+			shouldFail: false,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				kClient := fake.NewSimpleClientset(getNamespacedDeployment(tc.name, tc.namespace, "team-a,team-b"))
+				for _, ns := range []string{"team-a", "team-b"} {
+					rb := getDefaultRoleBinding(ns, tc.namespace)
+					_ = kClient.Tracker().Add(&rb)
+				}
+
+				kClient.PrependReactor("get", "roles", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &rbacv1.Role{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "prometheus-operator",
+						},
+						Rules: append([]rbacv1.PolicyRule{
+							{
+								APIGroups: []string{"monitoring.coreos.com"},
+								Resources: []string{"alertmanagers", "prometheuses", "servicemonitors"},
+							},
+						}, getOperatorWorkloadRules()...),
+					}, nil
+				})
+
+				apiExtensionsClient := fakeApiExtensions.NewSimpleClientset(getAlertmanagerCRD())
+				apiExtensionsClient.PrependReactor("get", "customresourcedefinitions", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, getAlertmanagerCRD(), nil
+				})
+
+				return k8sutil.ClientSets{
+					KClient:             kClient,
+					APIExtensionsClient: apiExtensionsClient,
+				}
+			},
+		},
+		{
+			name:       "NamespacedRoleBindingToServiceAccountOfOtherNamespace",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				kClient := fake.NewSimpleClientset(getNamespacedDeployment(tc.name, tc.namespace, "team-a"))
+				rb := getDefaultRoleBinding("team-a", "default")
+				_ = kClient.Tracker().Add(&rb)
+
+				kClient.PrependReactor("get", "roles", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &rbacv1.Role{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "prometheus-operator",
+						},
+						Rules: append([]rbacv1.PolicyRule{
+							{
+								APIGroups: []string{"monitoring.coreos.com"},
+								Resources: []string{"alertmanagers", "prometheuses", "servicemonitors"},
+							},
+						}, getOperatorWorkloadRules()...),
+					}, nil
+				})
+
+				apiExtensionsClient := fakeApiExtensions.NewSimpleClientset(getAlertmanagerCRD())
+				apiExtensionsClient.PrependReactor("get", "customresourcedefinitions", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, getAlertmanagerCRD(), nil
+				})
+
+				return k8sutil.ClientSets{
+					KClient:             kClient,
+					APIExtensionsClient: apiExtensionsClient,
+				}
+			},
+		},
+		{
+			name:       "NamespacedRoleBindingMissing",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				kClient := fake.NewSimpleClientset(getNamespacedDeployment(tc.name, tc.namespace, "team-a,team-b"))
+				rb := getDefaultRoleBinding("team-a", tc.namespace)
+				_ = kClient.Tracker().Add(&rb)
+
+				kClient.PrependReactor("get", "roles", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &rbacv1.Role{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "prometheus-operator",
+						},
+						Rules: []rbacv1.PolicyRule{
+							{
+								APIGroups: []string{"monitoring.coreos.com"},
+								Resources: []string{"alertmanagers"},
+							},
+						},
+					}, nil
+				})
+
+				apiExtensionsClient := fakeApiExtensions.NewSimpleClientset()
+				apiExtensionsClient.PrependReactor("get", "customresourcedefinitions", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, getAlertmanagerCRD(), nil
+				})
+
+				return k8sutil.ClientSets{
+					KClient:             kClient,
+					APIExtensionsClient: apiExtensionsClient,
+				}
+			},
+		},
+		{
+			name:       "NamespacedRoleWithoutCRD",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				kClient := fake.NewSimpleClientset(getNamespacedDeployment(tc.name, tc.namespace, "team-a"))
+				rb := getDefaultRoleBinding("team-a", tc.namespace)
+				_ = kClient.Tracker().Add(&rb)
+
+				kClient.PrependReactor("get", "roles", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &rbacv1.Role{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "prometheus-operator",
+						},
+						Rules: []rbacv1.PolicyRule{
+							{
+								APIGroups: []string{"monitoring.coreos.com"},
+								Resources: []string{"prometheuses"},
+							},
+						},
+					}, nil
+				})
+
+				apiExtensionsClient := fakeApiExtensions.NewSimpleClientset()
+				apiExtensionsClient.PrependReactor("get", "customresourcedefinitions", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, getAlertmanagerCRD(), nil
+				})
+
 				return k8sutil.ClientSets{
 					KClient:             kClient,
 					APIExtensionsClient: apiExtensionsClient,
@@ -298,7 +521,7 @@ func TestOperatorAnalyzer(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			clientSets := tc.getMockedClientSets(tc)
-			err := RunOperatorAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace)
+			err := analysisErr(RunOperatorAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace))
 			if tc.shouldFail {
 				assert.Error(t, err)
 			} else {
@@ -307,3 +530,73 @@ func TestOperatorAnalyzer(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalyzeOperatorVerbs(t *testing.T) {
+	type testCase struct {
+		name            string
+		image           string
+		args            []string
+		rules           []rbacv1.PolicyRule
+		namespace       string
+		expectedMessage string
+	}
+
+	withoutPodsDelete := getOperatorWorkloadRules()
+	withoutPodsDelete[2].Verbs = []string{"list"}
+
+	tests := []testCase{
+		{
+			name:  "AllVerbsGranted",
+			image: "quay.io/prometheus-operator/prometheus-operator:v0.65.0",
+			rules: getOperatorWorkloadRules(),
+		},
+		{
+			name:            "MissingPodsDelete",
+			image:           "quay.io/prometheus-operator/prometheus-operator:v0.65.0",
+			rules:           withoutPodsDelete,
+			expectedMessage: "ServiceAccount prometheus-operator of operator version 0.65.0 is not allowed to delete pods cluster-wide",
+		},
+		{
+			name:            "MissingEventsSince070",
+			image:           "quay.io/prometheus-operator/prometheus-operator:v0.70.0",
+			rules:           getOperatorWorkloadRules(),
+			expectedMessage: "ServiceAccount prometheus-operator of operator version 0.70.0 is not allowed to create, patch events cluster-wide",
+		},
+		{
+			name:            "MissingDaemonSetsWithFeatureGate",
+			image:           "quay.io/prometheus-operator/prometheus-operator:v0.65.0",
+			args:            []string{"--feature-gates=PrometheusAgentDaemonSet=true"},
+			rules:           getOperatorWorkloadRules(),
+			expectedMessage: "ServiceAccount prometheus-operator of operator version 0.65.0 is not allowed to get, list, watch, create, update, patch, delete daemonsets cluster-wide",
+		},
+		{
+			name:      "ClusterResourcesIgnoredInNamespace",
+			image:     "quay.io/prometheus-operator/prometheus-operator:v0.65.0",
+			rules:     getOperatorWorkloadRules()[:5],
+			namespace: "team-a",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			op := getDefaultDeployment("prometheus-operator", "default")
+			op.Spec.Template.Spec.Containers[0].Image = tc.image
+			op.Spec.Template.Spec.Containers[0].Args = tc.args
+
+			err := analyzeOperatorVerbs(op, tc.rules, tc.namespace)
+			if tc.expectedMessage == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expectedMessage)
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("0.73.0", "0.73.0"))
+	assert.True(t, versionAtLeast("0.75.1", "0.73.0"))
+	assert.True(t, versionAtLeast("1.0.0-rc.0", "0.73.0"))
+	assert.False(t, versionAtLeast("0.72.9", "0.73.0"))
+	assert.True(t, versionAtLeast("main", "0.73.0"))
+}
@@ -0,0 +1,412 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/prometheus-operator/poctl/internal/resolve"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// scrapeTarget is an address scraped or probed by Prometheus on behalf of a
+// monitoring object. An empty path matches any path of the address.
+type scrapeTarget struct {
+	address string
+	path    string
+	source  string
+}
+
+// recordingRule is a recording rule defined in a PrometheusRule.
+type recordingRule struct {
+	series string
+	source string
+}
+
+// RunOverlappingAnalyzer checks that the objects selected by a Prometheus
+// don't produce the same series twice: the targets of the ServiceMonitors,
+// PodMonitors, Probes and ScrapeConfig static configurations must not scrape
+// the same address, and the recording rules of the PrometheusRules must not
+// record the same series. The names of the Services in the cluster domain
+// are compared regardless of their spelling.
+func RunOverlappingAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace, clusterDomain string) ([]Finding, error) {
+	r := newReport("Prometheus", name, namespace)
+	return r.result(analyzeOverlapping(ctx, clientSets, r, name, namespace, clusterDomain))
+}
+
+func analyzeOverlapping(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace, clusterDomain string) error {
+	prometheus, err := clientSets.MClient.MonitoringV1().Prometheuses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "prometheus", name, namespace)
+		}
+		return fmt.Errorf("error while getting Prometheus: %w", err)
+	}
+
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return err
+	}
+
+	targets, err := selectedTargets(ctx, clientSets, prometheus, namespaces)
+	if err != nil {
+		return err
+	}
+
+	for i := range targets {
+		targets[i].address = canonicalAddress(targets[i].address, clusterDomain)
+	}
+
+	rules, err := selectedRecordingRules(ctx, clientSets, prometheus, namespaces)
+	if err != nil {
+		return err
+	}
+
+	overlaps := append(overlappingTargets(targets), overlappingRules(rules)...)
+	if len(overlaps) > 0 {
+		return messages.New(messages.OverlappingConfigurations, name, namespace, strings.Join(overlaps, "; "))
+	}
+
+	return nil
+}
+
+// namespaceLabels returns the labels of the namespaces of the cluster, by
+// name, to match the namespace selectors of Prometheus.
+func namespaceLabels(ctx context.Context, clientSets *k8sutil.ClientSets) (map[string]labels.Set, error) {
+	namespaces := make(map[string]labels.Set)
+	err := k8sutil.EachListItem(ctx, "namespaces", clientSets.KClient.CoreV1().Namespaces().List, metav1.ListOptions{}, func(ns *corev1.Namespace) error {
+		namespaces[ns.Name] = labels.Set(ns.Labels)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+// selectedTargets returns the targets of the monitoring objects selected by
+// the Prometheus.
+func selectedTargets(ctx context.Context, clientSets *k8sutil.ClientSets, prometheus *monitoringv1.Prometheus, namespaces map[string]labels.Set) ([]scrapeTarget, error) {
+	var targets []scrapeTarget
+
+	err := k8sutil.EachListItem(ctx, "ServiceMonitors", clientSets.MClient.MonitoringV1().ServiceMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(sm *monitoringv1.ServiceMonitor) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.ServiceMonitorSelector, prometheus.Spec.ServiceMonitorNamespaceSelector, sm.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		smTargets, err := resolve.ServiceMonitorTargets(ctx, clientSets, sm.Name, sm.Namespace)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range smTargets {
+			address, path := splitTarget(t.URL)
+			targets = append(targets, scrapeTarget{address: address, path: path, source: fmt.Sprintf("ServiceMonitor %s/%s", sm.Namespace, sm.Name)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PodMonitors", clientSets.MClient.MonitoringV1().PodMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(pm *monitoringv1.PodMonitor) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.PodMonitorSelector, prometheus.Spec.PodMonitorNamespaceSelector, pm.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		pmTargets, err := podMonitorTargets(ctx, clientSets, pm)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, pmTargets...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Probes", clientSets.MClient.MonitoringV1().Probes(metav1.NamespaceAll).List, metav1.ListOptions{}, func(probe *monitoringv1.Probe) error {
+		if probe.Spec.Targets.StaticConfig == nil || !selectsObject(prometheus.Namespace, prometheus.Spec.ProbeSelector, prometheus.Spec.ProbeNamespaceSelector, probe.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		for _, static := range probe.Spec.Targets.StaticConfig.Targets {
+			address, path := splitTarget(static)
+			targets = append(targets, scrapeTarget{address: address, path: path, source: fmt.Sprintf("Probe %s/%s", probe.Namespace, probe.Name)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ScrapeConfigs", clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(metav1.NamespaceAll).List, metav1.ListOptions{}, func(sc *monitoringv1alpha1.ScrapeConfig) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.ScrapeConfigSelector, prometheus.Spec.ScrapeConfigNamespaceSelector, sc.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		path := "/metrics"
+		if sc.Spec.MetricsPath != nil {
+			path = *sc.Spec.MetricsPath
+		}
+
+		for _, static := range sc.Spec.StaticConfigs {
+			for _, target := range static.Targets {
+				targets = append(targets, scrapeTarget{address: string(target), path: path, source: fmt.Sprintf("ScrapeConfig %s/%s", sc.Namespace, sc.Name)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// podMonitorTargets returns the addresses of the pods selected by the
+// PodMonitor whose container ports match its endpoints.
+func podMonitorTargets(ctx context.Context, clientSets *k8sutil.ClientSets, pm *monitoringv1.PodMonitor) ([]scrapeTarget, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&pm.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector in PodMonitor %s: %w", pm.Name, err)
+	}
+
+	var targets []scrapeTarget
+	for _, ns := range resolve.SelectedNamespaces(pm.Namespace, pm.Spec.NamespaceSelector) {
+		pods, err := clientSets.KClient.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("error while listing pods: %w", err)
+		}
+
+		for _, pod := range pods.Items {
+			podIPs := podIPs(pod)
+			if len(podIPs) == 0 {
+				continue
+			}
+
+			for _, endpoint := range pm.Spec.PodMetricsEndpoints {
+				path := endpoint.Path
+				if path == "" {
+					path = "/metrics"
+				}
+
+				for _, container := range pod.Spec.Containers {
+					for _, port := range container.Ports {
+						if port.Name != endpoint.Port {
+							continue
+						}
+
+						// Prometheus scrapes the primary IP of the Pod, while
+						// other objects can target the IP of the other family
+						// of a dual-stack Pod.
+						for _, ip := range podIPs {
+							targets = append(targets, scrapeTarget{
+								address: net.JoinHostPort(ip, strconv.Itoa(int(port.ContainerPort))),
+								path:    path,
+								source:  fmt.Sprintf("PodMonitor %s/%s", pm.Namespace, pm.Name),
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// podIPs returns the IP addresses of the Pod, one per IP family on dual-stack
+// clusters.
+func podIPs(pod corev1.Pod) []string {
+	if len(pod.Status.PodIPs) == 0 && pod.Status.PodIP != "" {
+		return []string{pod.Status.PodIP}
+	}
+
+	var ips []string
+	for _, ip := range pod.Status.PodIPs {
+		ips = append(ips, ip.IP)
+	}
+	return ips
+}
+
+// selectedRecordingRules returns the recording rules of the PrometheusRules
+// selected by the Prometheus.
+func selectedRecordingRules(ctx context.Context, clientSets *k8sutil.ClientSets, prometheus *monitoringv1.Prometheus, namespaces map[string]labels.Set) ([]recordingRule, error) {
+	var rules []recordingRule
+	err := k8sutil.EachListItem(ctx, "PrometheusRules", clientSets.MClient.MonitoringV1().PrometheusRules(metav1.NamespaceAll).List, metav1.ListOptions{}, func(pr *monitoringv1.PrometheusRule) error {
+		if !selectsObject(prometheus.Namespace, prometheus.Spec.RuleSelector, prometheus.Spec.RuleNamespaceSelector, pr.ObjectMeta, namespaces) {
+			return nil
+		}
+
+		for _, group := range pr.Spec.Groups {
+			for _, rule := range group.Rules {
+				if rule.Record == "" {
+					continue
+				}
+
+				series := rule.Record
+				if len(rule.Labels) > 0 {
+					series = fmt.Sprintf("%s{%s}", rule.Record, labels.Set(rule.Labels))
+				}
+
+				rules = append(rules, recordingRule{
+					series: series,
+					source: fmt.Sprintf("PrometheusRule %s/%s (group %s)", pr.Namespace, pr.Name, group.Name),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// selectsObject reports whether a Prometheus or a ThanosRuler of the
+// namespace selects an object through the label selector and the namespace
+// selector of its kind. A nil label selector selects nothing and a nil
+// namespace selector only selects the namespace of the selecting resource.
+func selectsObject(namespace string, selector, namespaceSelector *metav1.LabelSelector, object metav1.ObjectMeta, namespaces map[string]labels.Set) bool {
+	if selector == nil {
+		return false
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil || !s.Matches(labels.Set(object.Labels)) {
+		return false
+	}
+
+	if namespaceSelector == nil {
+		return object.Namespace == namespace
+	}
+
+	nsSelector, err := metav1.LabelSelectorAsSelector(namespaceSelector)
+	return err == nil && nsSelector.Matches(namespaces[object.Namespace])
+}
+
+// splitTarget splits a target, given either as a URL or as an address, into
+// its address and its path.
+func splitTarget(target string) (string, string) {
+	if !strings.Contains(target, "://") {
+		return target, ""
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target, ""
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "http":
+			address = net.JoinHostPort(u.Hostname(), "80")
+		case "https":
+			address = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+
+	return address, u.Path
+}
+
+// canonicalAddress returns the address in the form compared between the
+// targets: the IP addresses in their shortest form, IPv6 included, and the
+// names of the Services in the cluster domain shortened to
+// <service>.<namespace>.svc, the form relative to the search domains of the
+// Pods. Other addresses are left unchanged.
+func canonicalAddress(address, clusterDomain string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]"), ""
+	}
+
+	clusterDomain = strings.Trim(clusterDomain, ".")
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	} else if name, ok := strings.CutSuffix(strings.TrimSuffix(host, "."), ".svc."+clusterDomain); ok && clusterDomain != "" {
+		host = name + ".svc"
+	} else {
+		return address
+	}
+
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// overlappingTargets describes the targets scraped by several objects, or
+// several times by the same object.
+func overlappingTargets(targets []scrapeTarget) []string {
+	var overlaps []string
+	seen := make(map[string]bool)
+	for i, a := range targets {
+		for _, b := range targets[i+1:] {
+			if a.address != b.address || (a.path != b.path && a.path != "" && b.path != "") {
+				continue
+			}
+
+			overlap := fmt.Sprintf("%s%s is scraped by %s and %s", a.address, sharedPath(a, b), a.source, b.source)
+			if !seen[overlap] {
+				seen[overlap] = true
+				overlaps = append(overlaps, overlap)
+			}
+		}
+	}
+
+	return overlaps
+}
+
+// sharedPath returns the path shared by two overlapping targets.
+func sharedPath(a, b scrapeTarget) string {
+	if a.path != "" {
+		return a.path
+	}
+	return b.path
+}
+
+// overlappingRules describes the series recorded by several recording rules.
+func overlappingRules(rules []recordingRule) []string {
+	sources := make(map[string][]string)
+	for _, r := range rules {
+		sources[r.series] = append(sources[r.series], r.source)
+	}
+
+	var overlaps []string
+	for series, s := range sources {
+		if len(s) > 1 {
+			overlaps = append(overlaps, fmt.Sprintf("series %s is recorded by %s", series, strings.Join(s, " and ")))
+		}
+	}
+	sort.Strings(overlaps)
+
+	return overlaps
+}
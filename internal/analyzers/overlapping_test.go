@@ -0,0 +1,210 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOverlappingAnalyzer(t *testing.T) {
+	type testCase struct {
+		name          string
+		objects       []runtime.Object
+		expectedError string
+	}
+
+	tests := []testCase{
+		{
+			name:    "NoOverlap",
+			objects: []runtime.Object{getOverlappingServiceMonitor(), getOverlappingScrapeConfig("app", "10.0.0.1:9100")},
+		},
+		{
+			name:          "ScrapeConfigDuplicatesServiceMonitor",
+			objects:       []runtime.Object{getOverlappingServiceMonitor(), getOverlappingScrapeConfig("app", "10.0.0.1:8080")},
+			expectedError: "prometheus prometheus in namespace default has overlapping configurations: 10.0.0.1:8080/metrics is scraped by ServiceMonitor default/app and ScrapeConfig default/app",
+		},
+		{
+			name:    "UnselectedScrapeConfig",
+			objects: []runtime.Object{getOverlappingServiceMonitor(), getOverlappingScrapeConfig("other", "10.0.0.1:8080")},
+		},
+		{
+			name: "ProbeDuplicatesPodMonitor",
+			objects: []runtime.Object{
+				&monitoringv1.PodMonitor{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": "app"}},
+					Spec: monitoringv1.PodMonitorSpec{
+						Selector:            metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+						PodMetricsEndpoints: []monitoringv1.PodMetricsEndpoint{{Port: "metrics"}},
+					},
+				},
+				&monitoringv1.Probe{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": "app"}},
+					Spec: monitoringv1.ProbeSpec{
+						Targets: monitoringv1.ProbeTargets{
+							StaticConfig: &monitoringv1.ProbeTargetStaticConfig{Targets: []string{"http://10.0.0.2:9090"}},
+						},
+					},
+				},
+			},
+			expectedError: "prometheus prometheus in namespace default has overlapping configurations: 10.0.0.2:9090/metrics is scraped by PodMonitor default/app and Probe default/app",
+		},
+		{
+			name: "ProbeDuplicatesScrapeConfigInClusterDomain",
+			objects: []runtime.Object{
+				getOverlappingScrapeConfig("app", "app.default.svc.example.org.:9115"),
+				&monitoringv1.Probe{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": "app"}},
+					Spec: monitoringv1.ProbeSpec{
+						Targets: monitoringv1.ProbeTargets{
+							StaticConfig: &monitoringv1.ProbeTargetStaticConfig{Targets: []string{"app.default.svc:9115"}},
+						},
+					},
+				},
+			},
+			expectedError: "prometheus prometheus in namespace default has overlapping configurations: app.default.svc:9115/metrics is scraped by Probe default/app and ScrapeConfig default/app",
+		},
+		{
+			name: "DuplicateRecordingRule",
+			objects: []runtime.Object{
+				getOverlappingPrometheusRule("a", map[string]string{"env": "prod"}),
+				getOverlappingPrometheusRule("b", map[string]string{"env": "prod"}),
+			},
+			expectedError: "prometheus prometheus in namespace default has overlapping configurations: series job:up:sum{env=prod} is recorded by PrometheusRule default/a (group app) and PrometheusRule default/b (group app)",
+		},
+		{
+			name: "RecordingRulesWithDifferentLabels",
+			objects: []runtime.Object{
+				getOverlappingPrometheusRule("a", map[string]string{"env": "prod"}),
+				getOverlappingPrometheusRule("b", map[string]string{"env": "staging"}),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prometheus := &monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: "default"},
+				Spec: monitoringv1.PrometheusSpec{
+					CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+						ServiceMonitorSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+						PodMonitorSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+						ProbeSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+						ScrapeConfigSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+					},
+					RuleSelector: &metav1.LabelSelector{},
+				},
+			}
+
+			kClient := fake.NewSimpleClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"app": "app"}},
+					Spec: corev1.ServiceSpec{
+						Ports: []corev1.ServicePort{{Name: "web", Port: 8080, TargetPort: intstr.FromInt32(8080)}},
+					},
+				},
+				&corev1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					Subsets: []corev1.EndpointSubset{{
+						Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+						Ports:     []corev1.EndpointPort{{Name: "web", Port: 8080}},
+					}},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"app": "app"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:  "app",
+							Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090}},
+						}},
+					},
+					Status: corev1.PodStatus{PodIP: "10.0.0.2"},
+				},
+			)
+
+			mClient := monitoringclient.NewSimpleClientset(append(tc.objects, prometheus)...)
+
+			err := analysisErr(RunOverlappingAnalyzer(context.Background(), &k8sutil.ClientSets{KClient: kClient, MClient: mClient}, "prometheus", "default", "example.org"))
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func getOverlappingServiceMonitor() *monitoringv1.ServiceMonitor {
+	return &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": "app"}},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+			Endpoints: []monitoringv1.Endpoint{{Port: "web"}},
+		},
+	}
+}
+
+func getOverlappingScrapeConfig(team, target string) *monitoringv1alpha1.ScrapeConfig {
+	return &monitoringv1alpha1.ScrapeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"team": team}},
+		Spec: monitoringv1alpha1.ScrapeConfigSpec{
+			StaticConfigs: []monitoringv1alpha1.StaticConfig{{Targets: []monitoringv1alpha1.Target{monitoringv1alpha1.Target(target)}}},
+		},
+	}
+}
+
+func getOverlappingPrometheusRule(name string, ruleLabels map[string]string) *monitoringv1.PrometheusRule {
+	return &monitoringv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{{
+				Name: "app",
+				Rules: []monitoringv1.Rule{{
+					Record: "job:up:sum",
+					Expr:   intstr.FromString("sum by (job) (up)"),
+					Labels: ruleLabels,
+				}},
+			}},
+		},
+	}
+}
+
+func TestCanonicalAddress(t *testing.T) {
+	for address, expected := range map[string]string{
+		"10.0.0.1:8080":                     "10.0.0.1:8080",
+		"[fd00:0:0::1]:8080":                "[fd00::1]:8080",
+		"fd00:0::1":                         "fd00::1",
+		"[fd00:0::1]":                       "fd00::1",
+		"app.default.svc.cluster.local:80":  "app.default.svc:80",
+		"app.default.svc.cluster.local.:80": "app.default.svc:80",
+		"app.default.svc.example.org:80":    "app.default.svc.example.org:80",
+		"example.com:443":                   "example.com:443",
+	} {
+		assert.Equal(t, expected, canonicalAddress(address, "cluster.local"), address)
+	}
+}
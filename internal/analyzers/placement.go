@@ -0,0 +1,136 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// shardLabel is the label set by the operator on the pods of each
+	// Prometheus shard.
+	shardLabel = "operator.prometheus.io/shard"
+	// zoneLabel is the well-known label holding the zone of a node.
+	zoneLabel = "topology.kubernetes.io/zone"
+	// legacyZoneLabel is the deprecated label holding the zone of a node.
+	legacyZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+)
+
+// replicasPlacementWarnings lists the pods of a highly-available workload
+// matching the selector along with the nodes of the cluster, and returns the
+// nodes and zones running more of its replicas than necessary.
+func replicasPlacementWarnings(ctx context.Context, clientSets *k8sutil.ClientSets, kind, name, namespace, selector string) ([]analyzerWarning, error) {
+	pods, err := clientSets.KClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing pods: %w", err)
+	}
+
+	nodes, err := clientSets.KClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing nodes: %w", err)
+	}
+
+	return colocatedReplicasWarnings(kind, name, pods.Items, nodes.Items), nil
+}
+
+// colocatedReplicasWarnings returns the nodes and the zones running more
+// replicas of the same workload than an even spread over the schedulable
+// nodes and their zones would. Pods are grouped by shard since the replicas of
+// different shards don't hold the same data.
+func colocatedReplicasWarnings(kind, name string, pods []corev1.Pod, nodes []corev1.Node) []analyzerWarning {
+	var (
+		schedulable int
+		nodeZones   = map[string]string{}
+		zones       = map[string]struct{}{}
+	)
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		schedulable++
+
+		if zone := nodeZone(node); zone != "" {
+			nodeZones[node.Name] = zone
+			zones[zone] = struct{}{}
+		}
+	}
+
+	shards := map[string][]corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		shards[pod.Labels[shardLabel]] = append(shards[pod.Labels[shardLabel]], pod)
+	}
+
+	var warnings []analyzerWarning
+	for _, shard := range slices.Sorted(maps.Keys(shards)) {
+		replicas := shards[shard]
+		if len(replicas) < 2 {
+			continue
+		}
+
+		perNode := map[string]int{}
+		perZone := map[string]int{}
+		for _, pod := range replicas {
+			perNode[pod.Spec.NodeName]++
+			if zone, ok := nodeZones[pod.Spec.NodeName]; ok {
+				perZone[zone]++
+			}
+		}
+
+		for _, node := range slices.Sorted(maps.Keys(perNode)) {
+			if perNode[node] > evenSpread(len(replicas), schedulable) {
+				warnings = append(warnings, newWarning(messages.ReplicasOnSameNode, perNode[node], kind, name, node))
+			}
+		}
+
+		for _, zone := range slices.Sorted(maps.Keys(perZone)) {
+			if perZone[zone] > evenSpread(len(replicas), len(zones)) {
+				warnings = append(warnings, newWarning(messages.ReplicasInSameZone, perZone[zone], kind, name, zone))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// evenSpread returns the maximum number of replicas per domain when they are
+// evenly spread over the domains.
+func evenSpread(replicas, domains int) int {
+	if domains < 2 {
+		return replicas
+	}
+	return (replicas + domains - 1) / domains
+}
+
+// nodeZone returns the zone of the node from its topology labels, empty when
+// unknown.
+func nodeZone(node corev1.Node) string {
+	if zone := node.Labels[zoneLabel]; zone != "" {
+		return zone
+	}
+	return node.Labels[legacyZoneLabel]
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func replicaPod(name, node, shard string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{shardLabel: shard},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+		},
+	}
+}
+
+func zoneNode(name, zone string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{zoneLabel: zone},
+		},
+	}
+}
+
+func TestColocatedReplicasWarnings(t *testing.T) {
+	type testCase struct {
+		name             string
+		pods             []corev1.Pod
+		nodes            []corev1.Node
+		expectedMessages []string
+	}
+
+	tests := []testCase{
+		{
+			name: "SpreadOverNodesAndZones",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-1", "node-b", "0"),
+			},
+			nodes: []corev1.Node{zoneNode("node-a", "zone-a"), zoneNode("node-b", "zone-b")},
+		},
+		{
+			name: "SameNode",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-1", "node-a", "0"),
+			},
+			nodes: []corev1.Node{zoneNode("node-a", "zone-a"), zoneNode("node-b", "zone-b")},
+			expectedMessages: []string{
+				"2 replicas of Prometheus k8s run on node node-a",
+				"2 replicas of Prometheus k8s run in zone zone-a",
+			},
+		},
+		{
+			name: "SameZone",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-1", "node-b", "0"),
+			},
+			nodes:            []corev1.Node{zoneNode("node-a", "zone-a"), zoneNode("node-b", "zone-a"), zoneNode("node-c", "zone-b")},
+			expectedMessages: []string{"2 replicas of Prometheus k8s run in zone zone-a"},
+		},
+		{
+			name: "SingleNodeCluster",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-1", "node-a", "0"),
+			},
+			nodes: []corev1.Node{zoneNode("node-a", "zone-a")},
+		},
+		{
+			name: "MoreReplicasThanZones",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-1", "node-b", "0"),
+				replicaPod("prometheus-k8s-2", "node-c", "0"),
+			},
+			nodes: []corev1.Node{zoneNode("node-a", "zone-a"), zoneNode("node-b", "zone-a"), zoneNode("node-c", "zone-b")},
+		},
+		{
+			name: "DifferentShards",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-shard-1-0", "node-a", "1"),
+			},
+			nodes: []corev1.Node{zoneNode("node-a", "zone-a"), zoneNode("node-b", "zone-b")},
+		},
+		{
+			name: "PendingPod",
+			pods: []corev1.Pod{
+				replicaPod("prometheus-k8s-0", "node-a", "0"),
+				replicaPod("prometheus-k8s-1", "", "0"),
+			},
+			nodes: []corev1.Node{zoneNode("node-a", "zone-a"), zoneNode("node-b", "zone-b")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var messages []string
+			for _, w := range colocatedReplicasWarnings("Prometheus", "k8s", tc.pods, tc.nodes) {
+				messages = append(messages, w.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+}
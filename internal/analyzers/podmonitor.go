@@ -0,0 +1,182 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/prometheus-operator/poctl/internal/resolve"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// RunPodMonitorAnalyzer checks that the PodMonitor has a selector matching
+// pods, that the port of each endpoint is a container port of the pods, and
+// that a Prometheus or a PrometheusAgent selects it.
+func RunPodMonitorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("PodMonitor", name, namespace)
+	return r.result(analyzePodMonitor(ctx, clientSets, r, name, namespace))
+}
+
+func analyzePodMonitor(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	podMonitor, err := clientSets.MClient.MonitoringV1().PodMonitors(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "PodMonitor", name, namespace)
+		}
+		return fmt.Errorf("error while getting PodMonitor: %w", err)
+	}
+
+	if len(podMonitor.Spec.Selector.MatchLabels) == 0 && len(podMonitor.Spec.Selector.MatchExpressions) == 0 {
+		return messages.New(messages.PodMonitorNoSelector, name, namespace)
+	}
+
+	namespaces := resolve.SelectedNamespaces(namespace, podMonitor.Spec.NamespaceSelector)
+
+	var pods []v1.Pod
+	for _, ns := range namespaces {
+		err := k8sutil.EachListItem(ctx, "pods", clientSets.KClient.CoreV1().Pods(ns).List, metav1.ListOptions{
+			LabelSelector: metav1.FormatLabelSelector(&podMonitor.Spec.Selector),
+		}, func(pod *v1.Pod) error {
+			pods = append(pods, *pod)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(pods) == 0 {
+		r.fail(messages.PodMonitorNoPods, name, namespace, namespacesString(namespaces))
+	} else if err := r.check(evaluatePodPortMatches(podMonitor, pods)); err != nil {
+		return err
+	}
+
+	selected, err := isPodMonitorSelected(ctx, clientSets, podMonitor)
+	if err != nil {
+		return err
+	}
+
+	if !selected {
+		r.fail(messages.PodMonitorNotSelected, name, namespace)
+	}
+
+	return nil
+}
+
+// evaluatePodPortMatches checks that each endpoint selects a container port
+// of the pods, by name with port or by number or name with targetPort. The
+// container ports of the pods are reported on failure. An endpoint without
+// port nor targetPort scrapes every container port.
+func evaluatePodPortMatches(podMonitor *monitoringv1.PodMonitor, pods []v1.Pod) error {
+	var candidates []string
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			for _, port := range container.Ports {
+				candidate := fmt.Sprintf("%s/%s %d", pod.Name, container.Name, port.ContainerPort)
+				if port.Name != "" {
+					candidate = fmt.Sprintf("%s/%s %s:%d", pod.Name, container.Name, port.Name, port.ContainerPort)
+				}
+				if !slices.Contains(candidates, candidate) {
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+
+	for _, endpoint := range podMonitor.Spec.PodMetricsEndpoints {
+		if endpoint.Port == "" && endpoint.TargetPort == nil {
+			continue
+		}
+
+		if slices.ContainsFunc(pods, func(pod v1.Pod) bool { return podHasPort(pod, endpoint) }) {
+			continue
+		}
+
+		if len(candidates) == 0 {
+			candidates = []string{"none"}
+		}
+		return messages.New(messages.PodMonitorNoPort, podMonitor.Name, podMonitor.Namespace, podEndpointPortString(endpoint), strings.Join(candidates, ", "))
+	}
+	return nil
+}
+
+// podHasPort reports whether a container of the pod exposes the port
+// selected by the endpoint.
+func podHasPort(pod v1.Pod, endpoint monitoringv1.PodMetricsEndpoint) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			switch {
+			case endpoint.Port != "":
+				if port.Name == endpoint.Port {
+					return true
+				}
+			case endpoint.TargetPort.Type == intstr.String:
+				if port.Name == endpoint.TargetPort.StrVal {
+					return true
+				}
+			case port.ContainerPort == endpoint.TargetPort.IntVal:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// podEndpointPortString describes the port selected by a PodMonitor
+// endpoint.
+func podEndpointPortString(endpoint monitoringv1.PodMetricsEndpoint) string {
+	if endpoint.Port != "" {
+		return "port " + endpoint.Port
+	}
+	if endpoint.TargetPort.Type == intstr.Int {
+		return "targetPort " + strconv.Itoa(int(endpoint.TargetPort.IntVal))
+	}
+	return "targetPort " + endpoint.TargetPort.StrVal
+}
+
+// isPodMonitorSelected reports whether a Prometheus or a PrometheusAgent
+// selects the PodMonitor.
+func isPodMonitorSelected(ctx context.Context, clientSets *k8sutil.ClientSets, podMonitor *monitoringv1.PodMonitor) (bool, error) {
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return false, err
+	}
+
+	var selected bool
+	err = k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		selected = selected || selectsObject(p.Namespace, p.Spec.PodMonitorSelector, p.Spec.PodMonitorNamespaceSelector, podMonitor.ObjectMeta, namespaces)
+		return nil
+	})
+	if err != nil || selected {
+		return selected, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PrometheusAgents", clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1alpha1.PrometheusAgent) error {
+		selected = selected || selectsObject(p.Namespace, p.Spec.PodMonitorSelector, p.Spec.PodMonitorNamespaceSelector, podMonitor.ObjectMeta, namespaces)
+		return nil
+	})
+	return selected, err
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestPodMonitorAnalyzer(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "team-a", Labels: map[string]string{"app": "api"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "api",
+			Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 8080}},
+		}}},
+	}
+	selector := metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}
+	prometheus := &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+		Spec: monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+			PodMonitorSelector:          &metav1.LabelSelector{},
+			PodMonitorNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		}},
+	}
+
+	newPodMonitor := func(selector metav1.LabelSelector, endpoints ...monitoringv1.PodMetricsEndpoint) *monitoringv1.PodMonitor {
+		return &monitoringv1.PodMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team-a"},
+			Spec: monitoringv1.PodMonitorSpec{
+				Selector:            selector,
+				PodMetricsEndpoints: endpoints,
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		objects    []runtime.Object
+		expectedID messages.ID
+	}{
+		{
+			name:       "NotFound",
+			objects:    []runtime.Object{prometheus},
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name:       "EmptySelector",
+			objects:    []runtime.Object{prometheus, newPodMonitor(metav1.LabelSelector{})},
+			expectedID: messages.PodMonitorNoSelector,
+		},
+		{
+			name:       "NoPods",
+			objects:    []runtime.Object{prometheus, newPodMonitor(metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}})},
+			expectedID: messages.PodMonitorNoPods,
+		},
+		{
+			name:       "NoPort",
+			objects:    []runtime.Object{prometheus, pod, newPodMonitor(selector, monitoringv1.PodMetricsEndpoint{Port: "http"})},
+			expectedID: messages.PodMonitorNoPort,
+		},
+		{
+			name:       "NoTargetPort",
+			objects:    []runtime.Object{prometheus, pod, newPodMonitor(selector, monitoringv1.PodMetricsEndpoint{TargetPort: ptr.To(intstr.FromInt32(9090))})},
+			expectedID: messages.PodMonitorNoPort,
+		},
+		{
+			name:       "NotSelected",
+			objects:    []runtime.Object{pod, newPodMonitor(selector, monitoringv1.PodMetricsEndpoint{Port: "metrics"})},
+			expectedID: messages.PodMonitorNotSelected,
+		},
+		{
+			name:    "SelectedByPrometheus",
+			objects: []runtime.Object{prometheus, pod, newPodMonitor(selector, monitoringv1.PodMetricsEndpoint{Port: "metrics"})},
+		},
+		{
+			name: "SelectedByPrometheusAgent",
+			objects: []runtime.Object{
+				&monitoringv1alpha1.PrometheusAgent{
+					ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "team-a"},
+					Spec: monitoringv1alpha1.PrometheusAgentSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+						PodMonitorSelector: &metav1.LabelSelector{},
+					}},
+				},
+				pod,
+				newPodMonitor(selector, monitoringv1.PodMetricsEndpoint{TargetPort: ptr.To(intstr.FromInt32(8080))}),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := append([]runtime.Object{namespace}, tc.objects...)
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(objects...))
+
+			err := analysisErr(RunPodMonitorAnalyzer(context.Background(), clientSets, "api", "team-a"))
+			if tc.expectedID == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			id, ok := messages.IDOf(err)
+			require.True(t, ok, "unexpected error %v", err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
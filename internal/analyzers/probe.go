@@ -0,0 +1,243 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/prometheus-operator/poctl/internal/resolve"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunProbeAnalyzer checks that the prober of the Probe is a Service of the
+// cluster exposing the port of its URL, that the Probe has targets, and that
+// a Prometheus or a PrometheusAgent selects it. A prober out of the cluster
+// is reported as a warning.
+func RunProbeAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace, clusterDomain string) ([]Finding, error) {
+	r := newReport("Probe", name, namespace)
+	return r.result(analyzeProbe(ctx, clientSets, r, name, namespace, clusterDomain))
+}
+
+func analyzeProbe(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace, clusterDomain string) error {
+	probe, err := clientSets.MClient.MonitoringV1().Probes(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "Probe", name, namespace)
+		}
+		return fmt.Errorf("error while getting Probe: %w", err)
+	}
+
+	selectedBy, err := probeSelectedBy(ctx, clientSets, probe)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := checkProber(ctx, clientSets, probe, selectedBy, clusterDomain)
+	if err := r.check(err); err != nil {
+		return err
+	}
+
+	if err := r.check(checkProbeTargets(ctx, clientSets, probe)); err != nil {
+		return err
+	}
+
+	if len(selectedBy) == 0 {
+		r.fail(messages.ProbeNotSelected, name, namespace)
+	}
+
+	r.warn(warnings...)
+
+	return nil
+}
+
+// checkProber checks that the prober is a Service exposing the port of the
+// URL. A short Service name is resolved in the namespaces of the Prometheus
+// resources scraping the Probe, or in the namespace of the Probe when none
+// does. A name of two labels is a Service when its second label is a
+// namespace, and a name out of the cluster otherwise.
+func checkProber(ctx context.Context, clientSets *k8sutil.ClientSets, probe *monitoringv1.Probe, selectedBy []string, clusterDomain string) ([]analyzerWarning, error) {
+	prober := probe.Spec.ProberSpec.URL
+	address, ok := parseProber(prober, probe.Spec.ProberSpec.Scheme, clusterDomain)
+	if !ok {
+		return []analyzerWarning{newWarning(messages.ProbeProberOutsideCluster, prober)}, nil
+	}
+
+	namespaces := []string{address.namespace}
+	switch {
+	case address.namespace == "" && len(selectedBy) > 0:
+		namespaces = selectedBy
+	case address.namespace == "":
+		namespaces = []string{probe.Namespace}
+	case !address.qualified:
+		_, err := clientSets.KClient.CoreV1().Namespaces().Get(ctx, address.namespace, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return []analyzerWarning{newWarning(messages.ProbeProberOutsideCluster, prober)}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error while getting namespace %s: %w", address.namespace, err)
+		}
+	}
+
+	for _, ns := range namespaces {
+		svc, err := clientSets.KClient.CoreV1().Services(ns).Get(ctx, address.service, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error while getting Service %s/%s: %w", ns, address.service, err)
+		}
+
+		// The port of an ExternalName Service is the one of the external
+		// name.
+		if svc.Spec.Type == v1.ServiceTypeExternalName || slices.ContainsFunc(svc.Spec.Ports, func(p v1.ServicePort) bool { return p.Port == address.port }) {
+			return nil, nil
+		}
+		return nil, messages.New(messages.ProbeProberNoPort, probe.Name, probe.Namespace, prober, address.service, ns, address.port)
+	}
+
+	return nil, messages.New(messages.ProbeProberNotFound, probe.Name, probe.Namespace, prober, address.service, namespacesString(namespaces))
+}
+
+// proberAddress is the Service designated by the URL of a prober.
+type proberAddress struct {
+	service string
+	// namespace is empty for a short Service name.
+	namespace string
+	port      int32
+	// qualified is set when the name ends with .svc, which only the Services
+	// do.
+	qualified bool
+}
+
+// parseProber parses the URL of a prober, a host:port address whose port
+// defaults to the one of the scheme. It reports false for the IP addresses
+// and the names which can't be a Service.
+func parseProber(prober, scheme, clusterDomain string) (proberAddress, bool) {
+	host, port, err := net.SplitHostPort(prober)
+	if err != nil {
+		host, port = prober, ""
+	}
+
+	address := proberAddress{port: 80}
+	if strings.EqualFold(scheme, "https") {
+		address.port = 443
+	}
+	if port != "" {
+		p, err := strconv.ParseInt(port, 10, 32)
+		if err != nil {
+			return proberAddress{}, false
+		}
+		address.port = int32(p)
+	}
+
+	if net.ParseIP(host) != nil {
+		return proberAddress{}, false
+	}
+
+	host = strings.TrimSuffix(host, ".")
+	if clusterDomain = strings.Trim(clusterDomain, "."); clusterDomain != "" {
+		host = strings.TrimSuffix(host, "."+clusterDomain)
+	}
+	host, address.qualified = strings.CutSuffix(host, ".svc")
+
+	labels := strings.Split(host, ".")
+	switch {
+	case len(labels) == 1 && !address.qualified:
+		address.service = labels[0]
+	case len(labels) == 2:
+		address.service, address.namespace = labels[0], labels[1]
+	default:
+		return proberAddress{}, false
+	}
+	return address, true
+}
+
+// checkProbeTargets checks that the static targets or the Ingresses selected
+// by the Probe give at least one target.
+func checkProbeTargets(ctx context.Context, clientSets *k8sutil.ClientSets, probe *monitoringv1.Probe) error {
+	targets := probe.Spec.Targets
+	if targets.StaticConfig != nil && len(targets.StaticConfig.Targets) > 0 {
+		return nil
+	}
+
+	if targets.Ingress == nil {
+		return messages.New(messages.ProbeNoTargets, probe.Name, probe.Namespace, "no static targets nor Ingress selector")
+	}
+
+	namespaces := resolve.SelectedNamespaces(probe.Namespace, targets.Ingress.NamespaceSelector)
+	var rules int
+	for _, ns := range namespaces {
+		err := k8sutil.EachListItem(ctx, "Ingresses", clientSets.KClient.NetworkingV1().Ingresses(ns).List, metav1.ListOptions{
+			LabelSelector: metav1.FormatLabelSelector(&targets.Ingress.Selector),
+		}, func(ingress *networkingv1.Ingress) error {
+			rules += len(ingress.Spec.Rules)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if rules == 0 {
+		return messages.New(messages.ProbeNoTargets, probe.Name, probe.Namespace, "no Ingress rules matching the selector in "+namespacesString(namespaces))
+	}
+	return nil
+}
+
+// probeSelectedBy returns the namespaces of the Prometheus and
+// PrometheusAgent resources selecting the Probe.
+func probeSelectedBy(ctx context.Context, clientSets *k8sutil.ClientSets, probe *monitoringv1.Probe) ([]string, error) {
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return nil, err
+	}
+
+	var selectedBy []string
+	add := func(namespace string, selector, namespaceSelector *metav1.LabelSelector) {
+		if selectsObject(namespace, selector, namespaceSelector, probe.ObjectMeta, namespaces) && !slices.Contains(selectedBy, namespace) {
+			selectedBy = append(selectedBy, namespace)
+		}
+	}
+
+	err = k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		add(p.Namespace, p.Spec.ProbeSelector, p.Spec.ProbeNamespaceSelector)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PrometheusAgents", clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1alpha1.PrometheusAgent) error {
+		add(p.Namespace, p.Spec.ProbeSelector, p.Spec.ProbeNamespaceSelector)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return selectedBy, nil
+}
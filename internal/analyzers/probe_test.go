@@ -0,0 +1,160 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseProber(t *testing.T) {
+	for _, tc := range []struct {
+		prober   string
+		scheme   string
+		expected proberAddress
+		ok       bool
+	}{
+		{prober: "blackbox-exporter:9115", expected: proberAddress{service: "blackbox-exporter", port: 9115}, ok: true},
+		{prober: "blackbox-exporter.monitoring:9115", expected: proberAddress{service: "blackbox-exporter", namespace: "monitoring", port: 9115}, ok: true},
+		{prober: "blackbox-exporter.monitoring.svc:9115", expected: proberAddress{service: "blackbox-exporter", namespace: "monitoring", port: 9115, qualified: true}, ok: true},
+		{prober: "blackbox-exporter.monitoring.svc.cluster.local.", expected: proberAddress{service: "blackbox-exporter", namespace: "monitoring", port: 80, qualified: true}, ok: true},
+		{prober: "blackbox-exporter.monitoring.svc", scheme: "https", expected: proberAddress{service: "blackbox-exporter", namespace: "monitoring", port: 443, qualified: true}, ok: true},
+		{prober: "prober.example.org:9115"},
+		{prober: "10.0.0.1:9115"},
+		{prober: "[2001:db8::1]:9115"},
+		{prober: "blackbox-exporter:http"},
+	} {
+		t.Run(tc.prober, func(t *testing.T) {
+			address, ok := parseProber(tc.prober, tc.scheme, "cluster.local")
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, address)
+		})
+	}
+}
+
+func TestProbeAnalyzer(t *testing.T) {
+	namespaces := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+	}
+	prober := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "blackbox-exporter", Namespace: "monitoring"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 9115}}},
+	}
+	prometheus := &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+		Spec: monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+			ProbeSelector:          &metav1.LabelSelector{},
+			ProbeNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		}},
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a", Labels: map[string]string{"probe": "true"}},
+		Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "web.example.org"}}},
+	}
+	static := monitoringv1.ProbeTargets{StaticConfig: &monitoringv1.ProbeTargetStaticConfig{Targets: []string{"https://example.org"}}}
+
+	newProbe := func(url string, targets monitoringv1.ProbeTargets) *monitoringv1.Probe {
+		return &monitoringv1.Probe{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+			Spec: monitoringv1.ProbeSpec{
+				ProberSpec: monitoringv1.ProberSpec{URL: url},
+				Targets:    targets,
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		objects    []runtime.Object
+		expectedID messages.ID
+	}{
+		{
+			name:       "NotFound",
+			objects:    []runtime.Object{prometheus},
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name:       "ProberNotFound",
+			objects:    []runtime.Object{prometheus, newProbe("blackbox-exporter.monitoring.svc:9115", static)},
+			expectedID: messages.ProbeProberNotFound,
+		},
+		{
+			name:       "ProberNoPort",
+			objects:    []runtime.Object{prometheus, prober, newProbe("blackbox-exporter.monitoring.svc:9116", static)},
+			expectedID: messages.ProbeProberNoPort,
+		},
+		{
+			name:       "NoTargets",
+			objects:    []runtime.Object{prometheus, prober, newProbe("blackbox-exporter.monitoring.svc:9115", monitoringv1.ProbeTargets{})},
+			expectedID: messages.ProbeNoTargets,
+		},
+		{
+			name: "NoIngressRules",
+			objects: []runtime.Object{prometheus, prober, newProbe("blackbox-exporter.monitoring.svc:9115", monitoringv1.ProbeTargets{
+				Ingress: &monitoringv1.ProbeTargetIngress{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"probe": "false"}}},
+			})},
+			expectedID: messages.ProbeNoTargets,
+		},
+		{
+			name:       "NotSelected",
+			objects:    []runtime.Object{prober, newProbe("blackbox-exporter.monitoring.svc:9115", static)},
+			expectedID: messages.ProbeNotSelected,
+		},
+		{
+			name:    "StaticTargets",
+			objects: []runtime.Object{prometheus, prober, newProbe("blackbox-exporter.monitoring.svc:9115", static)},
+		},
+		{
+			name: "IngressTargets",
+			objects: []runtime.Object{prometheus, prober, ingress, newProbe("blackbox-exporter.monitoring:9115", monitoringv1.ProbeTargets{
+				Ingress: &monitoringv1.ProbeTargetIngress{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"probe": "true"}}},
+			})},
+		},
+		{
+			// The short names are resolved in the namespace of Prometheus.
+			name:    "ShortName",
+			objects: []runtime.Object{prometheus, prober, newProbe("blackbox-exporter:9115", static)},
+		},
+		{
+			name:    "OutsideCluster",
+			objects: []runtime.Object{prometheus, newProbe("prober.example:9115", static)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(append(namespaces, tc.objects...)...))
+
+			err := analysisErr(RunProbeAnalyzer(context.Background(), clientSets, "web", "team-a", "cluster.local"))
+			if tc.expectedID == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			id, ok := messages.IDOf(err)
+			require.True(t, ok, "unexpected error %v", err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
@@ -17,20 +17,26 @@ package analyzers
 import (
 	"context"
 	"fmt"
-	"log/slog"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
-func RunPrometheusAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) error {
+func RunPrometheusAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("Prometheus", name, namespace)
+	return r.result(analyzePrometheus(ctx, clientSets, r, name, namespace))
+}
+
+func analyzePrometheus(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
 	prometheus, err := clientSets.MClient.MonitoringV1().Prometheuses(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return fmt.Errorf("prometheus %s not found in namespace %s", name, namespace)
+			return messages.New(messages.ObjectNotFound, "prometheus", name, namespace)
 		}
-		return fmt.Errorf("error while getting Prometheus: %v", err)
+		return fmt.Errorf("error while getting Prometheus: %w", err)
 	}
 
 	cRb, err := clientSets.KClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{
@@ -41,7 +47,7 @@ func RunPrometheusAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets,
 	}
 
 	if !k8sutil.IsServiceAccountBoundToRoleBindingList(cRb, prometheus.Spec.ServiceAccountName) {
-		return fmt.Errorf("serviceAccount %s is not bound to any RoleBindings", prometheus.Spec.ServiceAccountName)
+		r.fail(messages.ServiceAccountNotBound, prometheus.Spec.ServiceAccountName)
 	}
 
 	for _, crb := range cRb.Items {
@@ -50,52 +56,78 @@ func RunPrometheusAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets,
 			return fmt.Errorf("failed to get ClusterRole %s", crb.RoleRef.Name)
 		}
 
-		err = k8sutil.CheckPrometheusClusterRoleRules(crb, cr)
-		if err != nil {
+		if err := r.check(k8sutil.CheckPrometheusClusterRoleRules(crb, cr)); err != nil {
 			return err
 		}
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheus.Spec.PodMonitorNamespaceSelector); err != nil {
-		return fmt.Errorf("podMonitorNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "podMonitorNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheus.Spec.ProbeNamespaceSelector); err != nil {
-		return fmt.Errorf("probeNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "probeNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheus.Spec.ServiceMonitorNamespaceSelector); err != nil {
-		return fmt.Errorf("serviceMonitorNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "serviceMonitorNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheus.Spec.ScrapeConfigNamespaceSelector); err != nil {
-		return fmt.Errorf("scrapeConfigNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "scrapeConfigNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheus.Spec.RuleNamespaceSelector); err != nil {
-		return fmt.Errorf("ruleNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "ruleNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheus.Spec.ServiceMonitorSelector, k8sutil.ServiceMonitor, namespace); err != nil {
-		return fmt.Errorf("serviceMonitorSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "serviceMonitorSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheus.Spec.PodMonitorSelector, k8sutil.PodMonitor, namespace); err != nil {
-		return fmt.Errorf("podMonitorSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "podMonitorSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheus.Spec.ProbeSelector, k8sutil.Probe, namespace); err != nil {
-		return fmt.Errorf("probeSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "probeSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheus.Spec.ScrapeConfigSelector, k8sutil.ScrapeConfig, namespace); err != nil {
-		return fmt.Errorf("scrapeConfigSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "scrapeConfigSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheus.Spec.RuleSelector, k8sutil.PrometheusRule, namespace); err != nil {
-		return fmt.Errorf("ruleSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "ruleSelector", err)
+	}
+
+	services, err := clientSets.KClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing services: %w", err)
+	}
+
+	policies, err := clientSets.KClient.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing network policies: %w", err)
+	}
+
+	r.warn(prometheusExposureWarnings(prometheus, services.Items, policies.Items)...)
+
+	intervals, err := selectedScrapeIntervals(ctx, clientSets, prometheus)
+	if err != nil {
+		return err
+	}
+
+	r.warn(intervalWarnings(prometheus, intervals)...)
+
+	if ptr.Deref(prometheus.Spec.Replicas, 1) > 1 {
+		warnings, err := replicasPlacementWarnings(ctx, clientSets, "Prometheus", name, namespace, "app.kubernetes.io/name=prometheus,operator.prometheus.io/name="+name)
+		if err != nil {
+			return err
+		}
+
+		r.warn(warnings...)
 	}
 
-	slog.Info("Prometheus is compliant, no issues found", "name", name, "namespace", namespace)
 	return nil
 }
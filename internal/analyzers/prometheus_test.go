@@ -16,6 +16,7 @@ package analyzers
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
@@ -55,6 +56,41 @@ func getPrometheusClusterRoleBinding(namespace string) []rbacv1.ClusterRoleBindi
 	}
 }
 
+// addPrometheusRBACReactors makes the client return the given
+// ClusterRoleBindings and a ClusterRole granting all the permissions needed by
+// Prometheus.
+func addPrometheusRBACReactors(kClient *fake.Clientset, clusterRoleBindings []rbacv1.ClusterRoleBinding) {
+	kClient.PrependReactor("list", "clusterrolebindings", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+		return true, &rbacv1.ClusterRoleBindingList{
+			Items: clusterRoleBindings,
+		}, nil
+	})
+
+	kClient.PrependReactor("get", "clusterroles", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+		return true, &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "prometheus",
+			},
+			Rules: []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: []string{"nodes", "nodes/metrics", "services", "endpoints", "pods"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"configmaps"},
+					Verbs:     []string{"get"},
+				},
+				{
+					NonResourceURLs: []string{"/metrics"},
+					Verbs:           []string{"get"},
+				},
+			},
+		}, nil
+	})
+}
+
 func TestPrometheusAnalyzer(t *testing.T) {
 	type testCase struct {
 		name                string
@@ -81,7 +117,7 @@ func TestPrometheusAnalyzer(t *testing.T) {
 
 				kClient := fake.NewSimpleClientset(&rbacv1.ClusterRoleBindingList{})
 				kClient.PrependReactor("list", "clusterrolebindings", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, nil, errors.NewInternalError(nil)
+					return true, nil, errors.NewInternalError(fmt.Errorf("internal error"))
 				})
 
 				return k8sutil.ClientSets{
@@ -258,14 +294,25 @@ func TestPrometheusAnalyzer(t *testing.T) {
 							Namespace: tc.namespace,
 						},
 						Spec: monitoringv1.PrometheusSpec{
+							RuleSelector: &metav1.LabelSelector{},
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:            "prometheus",
+								ServiceMonitorSelector:        &metav1.LabelSelector{},
+								PodMonitorSelector:            &metav1.LabelSelector{},
+								ProbeSelector:                 &metav1.LabelSelector{},
+								ScrapeConfigSelector:          &metav1.LabelSelector{},
 								ScrapeConfigNamespaceSelector: nil,
 							},
 						},
 					}, nil
 				})
+
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -282,7 +329,13 @@ func TestPrometheusAnalyzer(t *testing.T) {
 							Namespace: tc.namespace,
 						},
 						Spec: monitoringv1.PrometheusSpec{
+							RuleSelector: &metav1.LabelSelector{},
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
+								ServiceMonitorSelector: &metav1.LabelSelector{},
+								PodMonitorSelector:     &metav1.LabelSelector{},
+								ProbeSelector:          &metav1.LabelSelector{},
+								ScrapeConfigSelector:   &metav1.LabelSelector{},
 								PodMonitorNamespaceSelector: &metav1.LabelSelector{
 									MatchLabels:      map[string]string{},
 									MatchExpressions: []metav1.LabelSelectorRequirement{},
@@ -291,8 +344,13 @@ func TestPrometheusAnalyzer(t *testing.T) {
 						},
 					}, nil
 				})
+
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -351,7 +409,13 @@ func TestPrometheusAnalyzer(t *testing.T) {
 							Namespace: tc.namespace,
 						},
 						Spec: monitoringv1.PrometheusSpec{
+							RuleSelector: &metav1.LabelSelector{},
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
+								ServiceMonitorSelector: &metav1.LabelSelector{},
+								PodMonitorSelector:     &metav1.LabelSelector{},
+								ProbeSelector:          &metav1.LabelSelector{},
+								ScrapeConfigSelector:   &metav1.LabelSelector{},
 								ProbeNamespaceSelector: &metav1.LabelSelector{
 									MatchLabels: map[string]string{"environment": "test"},
 								},
@@ -374,6 +438,8 @@ func TestPrometheusAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
 					KClient: kClient,
@@ -393,7 +459,12 @@ func TestPrometheusAnalyzer(t *testing.T) {
 							Namespace: tc.namespace,
 						},
 						Spec: monitoringv1.PrometheusSpec{
+							RuleSelector: &metav1.LabelSelector{},
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
+								ServiceMonitorSelector: &metav1.LabelSelector{},
+								ProbeSelector:          &metav1.LabelSelector{},
+								ScrapeConfigSelector:   &metav1.LabelSelector{},
 								PodMonitorSelector: &metav1.LabelSelector{
 									MatchLabels:      map[string]string{},
 									MatchExpressions: []metav1.LabelSelectorRequirement{},
@@ -403,8 +474,12 @@ func TestPrometheusAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -422,14 +497,19 @@ func TestPrometheusAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1.PrometheusSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
 								ServiceMonitorSelector: nil,
 							},
 						},
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -447,6 +527,7 @@ func TestPrometheusAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1.PrometheusSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName: "prometheus",
 								ScrapeConfigSelector: &metav1.LabelSelector{
 									MatchLabels: map[string]string{"app": "label"},
 								},
@@ -469,8 +550,12 @@ func TestPrometheusAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -488,6 +573,7 @@ func TestPrometheusAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1.PrometheusSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName: "prometheus",
 								ProbeSelector: &metav1.LabelSelector{
 									MatchLabels: map[string]string{"app": "label"},
 								},
@@ -510,8 +596,12 @@ func TestPrometheusAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -520,7 +610,7 @@ func TestPrometheusAnalyzer(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			clientSets := tc.getMockedClientSets(tc)
-			err := RunPrometheusAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace)
+			err := analysisErr(RunPrometheusAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace))
 			if tc.shouldFail {
 				assert.Error(t, err)
 			} else {
@@ -17,20 +17,26 @@ package analyzers
 import (
 	"context"
 	"fmt"
-	"log/slog"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func RunPrometheusAgentAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) error {
+func RunPrometheusAgentAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("PrometheusAgent", name, namespace)
+	return r.result(analyzePrometheusAgent(ctx, clientSets, r, name, namespace))
+}
+
+func analyzePrometheusAgent(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
 	prometheusagent, err := clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return fmt.Errorf("prometheus %s not found in namespace %s", name, namespace)
+			return messages.New(messages.ObjectNotFound, "prometheus", name, namespace)
 		}
-		return fmt.Errorf("error while getting Prometheus: %v", err)
+		return fmt.Errorf("error while getting Prometheus: %w", err)
 	}
 
 	cRb, err := clientSets.KClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{
@@ -41,7 +47,7 @@ func RunPrometheusAgentAnalyzer(ctx context.Context, clientSets *k8sutil.ClientS
 	}
 
 	if !k8sutil.IsServiceAccountBoundToRoleBindingList(cRb, prometheusagent.Spec.ServiceAccountName) {
-		return fmt.Errorf("serviceAccount %s is not bound to any RoleBindings", prometheusagent.Spec.ServiceAccountName)
+		r.fail(messages.ServiceAccountNotBound, prometheusagent.Spec.ServiceAccountName)
 	}
 
 	for _, crb := range cRb.Items {
@@ -50,44 +56,78 @@ func RunPrometheusAgentAnalyzer(ctx context.Context, clientSets *k8sutil.ClientS
 			return fmt.Errorf("failed to get ClusterRole %s", crb.RoleRef.Name)
 		}
 
-		err = k8sutil.CheckPrometheusClusterRoleRules(crb, cr)
-		if err != nil {
+		if err := r.check(k8sutil.CheckPrometheusClusterRoleRules(crb, cr)); err != nil {
 			return err
 		}
 	}
 
+	if prometheusagent.Spec.Mode != nil && *prometheusagent.Spec.Mode == "DaemonSet" {
+		return analyzePrometheusAgentDaemonSet(ctx, clientSets, r, prometheusagent, name, namespace)
+	}
+
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheusagent.Spec.PodMonitorNamespaceSelector); err != nil {
-		return fmt.Errorf("podMonitorNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "podMonitorNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheusagent.Spec.ProbeNamespaceSelector); err != nil {
-		return fmt.Errorf("probeNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "probeNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheusagent.Spec.ServiceMonitorNamespaceSelector); err != nil {
-		return fmt.Errorf("serviceMonitorNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "serviceMonitorNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheusagent.Spec.ScrapeConfigNamespaceSelector); err != nil {
-		return fmt.Errorf("scrapeConfigNamespaceSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "scrapeConfigNamespaceSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheusagent.Spec.ServiceMonitorSelector, k8sutil.ServiceMonitor, namespace); err != nil {
-		return fmt.Errorf("serviceMonitorSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "serviceMonitorSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheusagent.Spec.PodMonitorSelector, k8sutil.PodMonitor, namespace); err != nil {
-		return fmt.Errorf("podMonitorSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "podMonitorSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheusagent.Spec.ProbeSelector, k8sutil.Probe, namespace); err != nil {
-		return fmt.Errorf("probeSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "probeSelector", err)
 	}
 
 	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheusagent.Spec.ScrapeConfigSelector, k8sutil.ScrapeConfig, namespace); err != nil {
-		return fmt.Errorf("scrapeConfigSelector is not properly defined: %s", err)
+		r.fail(messages.SelectorNotProperlyDefined, "scrapeConfigSelector", err)
+	}
+
+	return nil
+}
+
+// analyzePrometheusAgentDaemonSet checks a PrometheusAgent running in
+// DaemonSet mode. Such agents only discover PodMonitors, and the operator
+// generates a DaemonSet instead of a StatefulSet.
+func analyzePrometheusAgentDaemonSet(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, prometheusagent *monitoringv1alpha1.PrometheusAgent, name, namespace string) error {
+	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, prometheusagent.Spec.PodMonitorNamespaceSelector); err != nil {
+		r.fail(messages.SelectorNotProperlyDefined, "podMonitorNamespaceSelector", err)
+	}
+
+	if err := k8sutil.CheckResourceLabelSelectors(ctx, *clientSets, prometheusagent.Spec.PodMonitorSelector, k8sutil.PodMonitor, namespace); err != nil {
+		r.fail(messages.SelectorNotProperlyDefined, "podMonitorSelector", err)
+	}
+
+	if prometheusagent.Spec.ServiceMonitorSelector != nil || prometheusagent.Spec.ProbeSelector != nil || prometheusagent.Spec.ScrapeConfigSelector != nil {
+		r.warn(newWarning(messages.AgentSelectorsIgnored))
+	}
+
+	daemonSetName := fmt.Sprintf("prom-agent-%s", name)
+	daemonSet, err := clientSets.KClient.AppsV1().DaemonSets(namespace).Get(ctx, daemonSetName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.AgentDaemonSetNotFound, daemonSetName, namespace)
+		}
+		return fmt.Errorf("error while getting DaemonSet %s: %w", daemonSetName, err)
+	}
+
+	if daemonSet.Status.NumberReady < daemonSet.Status.DesiredNumberScheduled {
+		r.warn(newWarning(messages.AgentPodsNotReady, daemonSet.Status.NumberReady, daemonSet.Status.DesiredNumberScheduled))
 	}
 
-	slog.Info("prometheusagent Agent is compliant, no issues found", "name", name, "namespace", namespace)
 	return nil
 }
@@ -16,6 +16,7 @@ package analyzers
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
@@ -23,6 +24,7 @@ import (
 	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -30,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 )
 
 func getPrometheusAgentClusterRoleBinding(namespace string) []rbacv1.ClusterRoleBinding {
@@ -38,7 +41,7 @@ func getPrometheusAgentClusterRoleBinding(namespace string) []rbacv1.ClusterRole
 			ObjectMeta: metav1.ObjectMeta{
 				Name: "prometheus",
 				Labels: map[string]string{
-					"prometheus": "prometheus",
+					"name": "prometheus-agent",
 				},
 			},
 			RoleRef: rbacv1.RoleRef{
@@ -70,7 +73,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -81,7 +84,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 
 				kClient := fake.NewSimpleClientset(&rbacv1.ClusterRoleBindingList{})
 				kClient.PrependReactor("list", "clusterrolebindings", func(_ clienttesting.Action) (bool, runtime.Object, error) {
-					return true, nil, errors.NewInternalError(nil)
+					return true, nil, errors.NewInternalError(fmt.Errorf("internal error"))
 				})
 
 				return k8sutil.ClientSets{
@@ -96,7 +99,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -124,7 +127,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -166,7 +169,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -209,7 +212,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -251,7 +254,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: false,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -259,13 +262,23 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:            "prometheus",
+								ServiceMonitorSelector:        &metav1.LabelSelector{},
+								PodMonitorSelector:            &metav1.LabelSelector{},
+								ProbeSelector:                 &metav1.LabelSelector{},
+								ScrapeConfigSelector:          &metav1.LabelSelector{},
 								ScrapeConfigNamespaceSelector: nil,
 							},
 						},
 					}, nil
 				})
+
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -275,7 +288,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: false,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -283,6 +296,11 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
+								ServiceMonitorSelector: &metav1.LabelSelector{},
+								PodMonitorSelector:     &metav1.LabelSelector{},
+								ProbeSelector:          &metav1.LabelSelector{},
+								ScrapeConfigSelector:   &metav1.LabelSelector{},
 								PodMonitorNamespaceSelector: &metav1.LabelSelector{
 									MatchLabels:      map[string]string{},
 									MatchExpressions: []metav1.LabelSelectorRequirement{},
@@ -291,8 +309,13 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 					}, nil
 				})
+
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -302,7 +325,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -344,7 +367,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: false,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -352,6 +375,11 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
+								ServiceMonitorSelector: &metav1.LabelSelector{},
+								PodMonitorSelector:     &metav1.LabelSelector{},
+								ProbeSelector:          &metav1.LabelSelector{},
+								ScrapeConfigSelector:   &metav1.LabelSelector{},
 								ProbeNamespaceSelector: &metav1.LabelSelector{
 									MatchLabels: map[string]string{"environment": "test"},
 								},
@@ -374,6 +402,8 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
 					KClient: kClient,
@@ -386,7 +416,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: false,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -394,6 +424,10 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
+								ServiceMonitorSelector: &metav1.LabelSelector{},
+								ProbeSelector:          &metav1.LabelSelector{},
+								ScrapeConfigSelector:   &metav1.LabelSelector{},
 								PodMonitorSelector: &metav1.LabelSelector{
 									MatchLabels:      map[string]string{},
 									MatchExpressions: []metav1.LabelSelectorRequirement{},
@@ -403,8 +437,12 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -414,7 +452,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -422,14 +460,19 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName:     "prometheus",
 								ServiceMonitorSelector: nil,
 							},
 						},
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -439,7 +482,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -447,6 +490,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName: "prometheus",
 								ScrapeConfigSelector: &metav1.LabelSelector{
 									MatchLabels: map[string]string{"app": "label"},
 								},
@@ -469,8 +513,12 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -480,7 +528,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 			shouldFail: true,
 			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
 				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
-				mClient.PrependReactor("get", "prometheusesagent", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
 					return true, &monitoringv1alpha1.PrometheusAgent{
 						ObjectMeta: metav1.ObjectMeta{
 							Name:      tc.name,
@@ -488,6 +536,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 						},
 						Spec: monitoringv1alpha1.PrometheusAgentSpec{
 							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName: "prometheus",
 								ProbeSelector: &metav1.LabelSelector{
 									MatchLabels: map[string]string{"app": "label"},
 								},
@@ -510,8 +559,79 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 					}, nil
 				})
 
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:       "PromAgentDaemonSetMode",
+			namespace:  "test",
+			shouldFail: false,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1alpha1.PrometheusAgent{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1alpha1.PrometheusAgentSpec{
+							Mode: ptr.To("DaemonSet"),
+							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName: "prometheus",
+								PodMonitorSelector: &metav1.LabelSelector{},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset(&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "prom-agent-" + tc.name,
+						Namespace: tc.namespace,
+					},
+				})
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:       "PromAgentDaemonSetModeWithoutDaemonSet",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1alpha1.PrometheusAgentList{})
+				mClient.PrependReactor("get", "prometheusagents", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1alpha1.PrometheusAgent{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1alpha1.PrometheusAgentSpec{
+							Mode: ptr.To("DaemonSet"),
+							CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+								ServiceAccountName: "prometheus",
+								PodMonitorSelector: &metav1.LabelSelector{},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset()
+				addPrometheusRBACReactors(kClient, getPrometheusAgentClusterRoleBinding(tc.namespace))
+
 				return k8sutil.ClientSets{
 					MClient: mClient,
+					KClient: kClient,
 				}
 			},
 		},
@@ -520,7 +640,7 @@ func TestPrometheusAgentAnalyzer(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			clientSets := tc.getMockedClientSets(tc)
-			err := RunPrometheusAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace)
+			err := analysisErr(RunPrometheusAgentAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace))
 			if tc.shouldFail {
 				assert.Error(t, err)
 			} else {
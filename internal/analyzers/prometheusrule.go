@@ -0,0 +1,164 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/template"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// templateDefs are the variables Prometheus defines before expanding the
+// labels and the annotations of the alerts.
+const templateDefs = "{{$labels := .Labels}}{{$externalLabels := .ExternalLabels}}{{$externalURL := .ExternalURL}}{{$value := .Value}}"
+
+// RunPrometheusRuleAnalyzer checks that the expressions of the rules of the
+// PrometheusRule are valid PromQL, that the templates of the labels and
+// annotations of its alerts parse, and that a Prometheus or a ThanosRuler
+// selects it. The rules of a group sharing their name and their labels are
+// reported as warnings.
+func RunPrometheusRuleAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("PrometheusRule", name, namespace)
+	return r.result(analyzePrometheusRule(ctx, clientSets, r, name, namespace))
+}
+
+func analyzePrometheusRule(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	prometheusRule, err := clientSets.MClient.MonitoringV1().PrometheusRules(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "PrometheusRule", name, namespace)
+		}
+		return fmt.Errorf("error while getting PrometheusRule: %w", err)
+	}
+
+	for _, err := range checkRules(prometheusRule) {
+		if err := r.check(err); err != nil {
+			return err
+		}
+	}
+
+	selected, err := isPrometheusRuleSelected(ctx, clientSets, prometheusRule)
+	if err != nil {
+		return err
+	}
+
+	if !selected {
+		r.fail(messages.RuleNotSelected, name, namespace)
+	}
+
+	warnings := duplicatedRulesWarnings(prometheusRule)
+	r.warn(warnings...)
+
+	return nil
+}
+
+// ruleSource returns the designation of a rule in the findings.
+func ruleSource(group monitoringv1.RuleGroup, i int, rule monitoringv1.Rule) string {
+	return fmt.Sprintf("rule %s of group %s (rules[%d])", cmp.Or(rule.Alert, rule.Record), group.Name, i)
+}
+
+// checkRules returns the errors of the rules with an invalid expression or,
+// for the alerts, an invalid template in their labels or annotations. The
+// templates of the recording rules aren't expanded by Prometheus.
+func checkRules(prometheusRule *monitoringv1.PrometheusRule) []error {
+	var errs []error
+	for _, group := range prometheusRule.Spec.Groups {
+		for i, rule := range group.Rules {
+			source := ruleSource(group, i, rule)
+			if _, err := parser.ParseExpr(rule.Expr.String()); err != nil {
+				errs = append(errs, messages.New(messages.RuleInvalidExpression, source, err))
+			}
+
+			if rule.Alert == "" {
+				continue
+			}
+
+			for _, field := range []struct {
+				name   string
+				values map[string]string
+			}{
+				{"label", rule.Labels},
+				{"annotation", rule.Annotations},
+			} {
+				for _, key := range slices.Sorted(maps.Keys(field.values)) {
+					if err := checkTemplate(rule.Alert, field.values[key]); err != nil {
+						errs = append(errs, messages.New(messages.RuleInvalidTemplate, source, field.name, key, err))
+					}
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// checkTemplate parses the template of a label or an annotation with the
+// functions Prometheus provides when expanding it.
+func checkTemplate(alert, text string) error {
+	return template.NewTemplateExpander(context.Background(), templateDefs+text, "__alert_"+alert, nil, model.Now(), nil, nil, nil).ParseTest()
+}
+
+// duplicatedRulesWarnings returns the warnings of the rules of a group with
+// the same name and the same labels as a previous rule of the group.
+func duplicatedRulesWarnings(prometheusRule *monitoringv1.PrometheusRule) []analyzerWarning {
+	var warnings []analyzerWarning
+	for _, group := range prometheusRule.Spec.Groups {
+		seen := map[string]string{}
+		for i, rule := range group.Rules {
+			key := fmt.Sprintf("%s/%s{%s}", rule.Alert, rule.Record, labels.Set(rule.Labels))
+			if previous, ok := seen[key]; ok {
+				warnings = append(warnings, newWarning(messages.RuleDuplicated, ruleSource(group, i, rule), previous))
+				continue
+			}
+			seen[key] = ruleSource(group, i, rule)
+		}
+	}
+	return warnings
+}
+
+// isPrometheusRuleSelected reports whether a Prometheus or a ThanosRuler
+// selects the PrometheusRule.
+func isPrometheusRuleSelected(ctx context.Context, clientSets *k8sutil.ClientSets, prometheusRule *monitoringv1.PrometheusRule) (bool, error) {
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return false, err
+	}
+
+	var selected bool
+	err = k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		selected = selected || selectsObject(p.Namespace, p.Spec.RuleSelector, p.Spec.RuleNamespaceSelector, prometheusRule.ObjectMeta, namespaces)
+		return nil
+	})
+	if err != nil || selected {
+		return selected, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ThanosRulers", clientSets.MClient.MonitoringV1().ThanosRulers(metav1.NamespaceAll).List, metav1.ListOptions{}, func(tr *monitoringv1.ThanosRuler) error {
+		selected = selected || selectsObject(tr.Namespace, tr.Spec.RuleSelector, tr.Spec.RuleNamespaceSelector, prometheusRule.ObjectMeta, namespaces)
+		return nil
+	})
+	return selected, err
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newPrometheusRule(rules ...monitoringv1.Rule) *monitoringv1.PrometheusRule {
+	return &monitoringv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "team-a",
+			Labels:    map[string]string{"role": "alert-rules"},
+		},
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{{Name: "app", Rules: rules}},
+		},
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		rule       monitoringv1.Rule
+		expectedID messages.ID
+	}{
+		{
+			name: "Valid",
+			rule: monitoringv1.Rule{
+				Alert:       "AppDown",
+				Expr:        intstr.FromString(`up{job="app"} == 0`),
+				Labels:      map[string]string{"severity": "critical", "team": "{{ $labels.namespace }}"},
+				Annotations: map[string]string{"summary": "{{ $labels.instance }} is down since {{ $value | humanizeDuration }}, see {{ $externalURL }}."},
+			},
+		},
+		{
+			name:       "InvalidExpression",
+			rule:       monitoringv1.Rule{Record: "job:up:sum", Expr: intstr.FromString(`sum by (job) (up`)},
+			expectedID: messages.RuleInvalidExpression,
+		},
+		{
+			name:       "UnknownPromQLFunction",
+			rule:       monitoringv1.Rule{Alert: "ErrorsHigh", Expr: intstr.FromString(`rates(errors_total[5m]) > 1`)},
+			expectedID: messages.RuleInvalidExpression,
+		},
+		{
+			name: "NumericExpression",
+			rule: monitoringv1.Rule{Alert: "Watchdog", Expr: intstr.FromInt32(1)},
+		},
+		{
+			name: "UnclosedAction",
+			rule: monitoringv1.Rule{
+				Alert:       "AppDown",
+				Expr:        intstr.FromString("up == 0"),
+				Annotations: map[string]string{"summary": "{{ $labels.instance is down"},
+			},
+			expectedID: messages.RuleInvalidTemplate,
+		},
+		{
+			name: "UndefinedVariable",
+			rule: monitoringv1.Rule{
+				Alert:  "AppDown",
+				Expr:   intstr.FromString("up == 0"),
+				Labels: map[string]string{"instance": "{{ $label.instance }}"},
+			},
+			expectedID: messages.RuleInvalidTemplate,
+		},
+		{
+			name: "UnknownFunction",
+			rule: monitoringv1.Rule{
+				Alert:       "AppDown",
+				Expr:        intstr.FromString("up == 0"),
+				Annotations: map[string]string{"summary": "{{ $value | humanise }}"},
+			},
+			expectedID: messages.RuleInvalidTemplate,
+		},
+		{
+			name: "RecordingRuleLabelsNotTemplated",
+			rule: monitoringv1.Rule{
+				Record: "job:up:sum",
+				Expr:   intstr.FromString("sum by (job) (up)"),
+				Labels: map[string]string{"note": "{{ not a template"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := checkRules(newPrometheusRule(tc.rule))
+			if tc.expectedID == "" {
+				require.Empty(t, errs)
+				return
+			}
+
+			require.Len(t, errs, 1)
+			id, ok := messages.IDOf(errs[0])
+			require.True(t, ok, "unexpected error %v", errs[0])
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
+
+func TestDuplicatedRulesWarnings(t *testing.T) {
+	prometheusRule := newPrometheusRule(
+		monitoringv1.Rule{Alert: "AppDown", Expr: intstr.FromString("up == 0"), Labels: map[string]string{"severity": "warning"}},
+		monitoringv1.Rule{Alert: "AppDown", Expr: intstr.FromString("up == 0"), Labels: map[string]string{"severity": "critical"}},
+		monitoringv1.Rule{Record: "job:up:sum", Expr: intstr.FromString("sum by (job) (up)")},
+		monitoringv1.Rule{Record: "job:up:sum", Expr: intstr.FromString("sum by (job) (up == 1)")},
+	)
+	prometheusRule.Spec.Groups = append(prometheusRule.Spec.Groups, monitoringv1.RuleGroup{
+		Name:  "other",
+		Rules: []monitoringv1.Rule{{Record: "job:up:sum", Expr: intstr.FromString("sum by (job) (up)")}},
+	})
+
+	warnings := duplicatedRulesWarnings(prometheusRule)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, messages.RuleDuplicated, warnings[0].ID)
+	assert.Equal(t, "rule job:up:sum of group app (rules[3]) has the same name and labels as rule job:up:sum of group app (rules[2])", warnings[0].Message)
+}
+
+func TestPrometheusRuleAnalyzer(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"role": "alert-rules"}}
+	namespaceSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+
+	for _, tc := range []struct {
+		name       string
+		objects    []runtime.Object
+		shouldFail bool
+	}{
+		{
+			name:       "NotSelected",
+			objects:    []runtime.Object{&monitoringv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"}}},
+			shouldFail: true,
+		},
+		{
+			name: "SelectedOnlyInItsNamespace",
+			objects: []runtime.Object{&monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec:       monitoringv1.PrometheusSpec{RuleSelector: selector},
+			}},
+			shouldFail: true,
+		},
+		{
+			name: "SelectedByPrometheus",
+			objects: []runtime.Object{&monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec:       monitoringv1.PrometheusSpec{RuleSelector: selector, RuleNamespaceSelector: namespaceSelector},
+			}},
+		},
+		{
+			name: "SelectedByThanosRuler",
+			objects: []runtime.Object{&monitoringv1.ThanosRuler{
+				ObjectMeta: metav1.ObjectMeta{Name: "ruler", Namespace: "team-a"},
+				Spec:       monitoringv1.ThanosRulerSpec{RuleSelector: selector},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := append([]runtime.Object{namespace, newPrometheusRule(monitoringv1.Rule{Alert: "AppDown", Expr: intstr.FromString("up == 0")})}, tc.objects...)
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(objects...))
+
+			err := analysisErr(RunPrometheusRuleAnalyzer(context.Background(), clientSets, "app", "team-a"))
+			if tc.shouldFail {
+				id, ok := messages.IDOf(err)
+				require.True(t, ok, "unexpected error %v", err)
+				assert.Equal(t, messages.RuleNotSelected, id)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
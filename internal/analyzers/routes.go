@@ -0,0 +1,349 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// commonAlertLabels are the labels the alerts commonly get from the scraped
+// series and from the external labels set by the operator, which the
+// expressions of the rules don't name.
+var commonAlertLabels = []string{
+	"alertname", "job", "instance", "namespace", "service", "endpoint", "pod", "container", "node",
+	"prometheus", "prometheus_replica",
+}
+
+var (
+	// matcherRegexp parses a matcher of a route, such as severity="critical".
+	matcherRegexp = regexp.MustCompile(`^\s*\{?\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*(.*?)\s*\}?\s*$`)
+
+	// exprSelectorRegexp and exprGroupingRegexp find the label names of a
+	// PromQL expression, in the selectors and in the grouping and matching
+	// clauses.
+	exprSelectorRegexp = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)\s*(?:=~|!~|!=|=)`)
+	exprGroupingRegexp = regexp.MustCompile(`\b(?:by|without|on|ignoring|group_left|group_right)\s*\(([^)]*)\)`)
+	exprLabelRegexp    = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// alertmanagerConfig holds the parts of the Alertmanager configuration
+// relevant to the routing of the alerts.
+type alertmanagerConfig struct {
+	Route     *alertmanagerRoute `json:"route"`
+	Receivers []map[string]any   `json:"receivers"`
+}
+
+type alertmanagerRoute struct {
+	Receiver string               `json:"receiver"`
+	Matchers []string             `json:"matchers"`
+	Match    map[string]string    `json:"match"`
+	MatchRE  map[string]string    `json:"match_re"`
+	Continue bool                 `json:"continue"`
+	Routes   []*alertmanagerRoute `json:"routes"`
+}
+
+// routeMatcher is a parsed matcher of a route.
+type routeMatcher struct {
+	name  string
+	op    string
+	value string
+}
+
+func (m routeMatcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.name, m.op, m.value)
+}
+
+// matchers returns the matchers of the route, in the matchers list and in
+// the deprecated match and match_re maps. The matchers which can't be parsed
+// are left out.
+func (r *alertmanagerRoute) matchers() []routeMatcher {
+	var matchers []routeMatcher
+	for _, m := range r.Matchers {
+		parts := matcherRegexp.FindStringSubmatch(m)
+		if parts == nil {
+			continue
+		}
+
+		value := parts[3]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		matchers = append(matchers, routeMatcher{name: parts[1], op: parts[2], value: value})
+	}
+	for name, value := range r.Match {
+		matchers = append(matchers, routeMatcher{name: name, op: "=", value: value})
+	}
+	for name, value := range r.MatchRE {
+		matchers = append(matchers, routeMatcher{name: name, op: "=~", value: value})
+	}
+	return matchers
+}
+
+// producedLabels are the labels of the alerts the rules can produce. The
+// labels set by the rules are known with their values, the other labels,
+// coming from the series, only by their names.
+type producedLabels struct {
+	names  map[string]bool
+	values map[string]map[string]bool
+}
+
+func newProducedLabels() producedLabels {
+	p := producedLabels{
+		names:  map[string]bool{},
+		values: map[string]map[string]bool{},
+	}
+	for _, name := range commonAlertLabels {
+		p.names[name] = true
+	}
+	return p
+}
+
+// addRule adds the labels an alerting rule produces: its name, its labels
+// and the labels named by its expression.
+func (p producedLabels) addRule(alert, expr string, labels map[string]string) {
+	p.addValue("alertname", alert)
+	for name, value := range labels {
+		p.addValue(name, value)
+	}
+	p.addExpr(expr)
+}
+
+func (p producedLabels) addValue(name, value string) {
+	if p.values[name] == nil {
+		p.values[name] = map[string]bool{}
+	}
+	p.values[name][value] = true
+}
+
+func (p producedLabels) addExpr(expr string) {
+	for _, m := range exprSelectorRegexp.FindAllStringSubmatchIndex(expr, -1) {
+		// Skip the == comparison operator.
+		if m[1] < len(expr) && expr[m[1]-1] == '=' && expr[m[1]] == '=' {
+			continue
+		}
+		p.names[expr[m[2]:m[3]]] = true
+	}
+	for _, m := range exprGroupingRegexp.FindAllStringSubmatch(expr, -1) {
+		for _, name := range exprLabelRegexp.FindAllString(m[1], -1) {
+			p.names[name] = true
+		}
+	}
+}
+
+// routeWarnings returns the warnings about the routing of the configuration:
+// routes pointing at undefined receivers, child routes shadowed by a
+// previous sibling, matchers on labels or values which the rules don't
+// produce and receivers without integration. The matchers are only checked
+// when the produced labels are known.
+func routeWarnings(config alertmanagerConfig, produced *producedLabels) []analyzerWarning {
+	var warnings []analyzerWarning
+
+	receivers := map[string]bool{}
+	for _, receiver := range config.Receivers {
+		name, _ := receiver["name"].(string)
+		receivers[name] = true
+
+		if !hasIntegration(receiver) {
+			warnings = append(warnings, newWarning(messages.ReceiverWithoutIntegration, name))
+		}
+	}
+
+	if config.Route == nil {
+		return warnings
+	}
+
+	var walk func(path string, route *alertmanagerRoute)
+	walk = func(path string, route *alertmanagerRoute) {
+		if route.Receiver != "" && !receivers[route.Receiver] {
+			warnings = append(warnings, newWarning(messages.RouteReceiverNotDefined, path, route.Receiver))
+		}
+
+		if produced != nil {
+			warnings = append(warnings, matcherWarnings(path, route.matchers(), *produced)...)
+		}
+
+		for i, child := range route.Routes {
+			childPath := fmt.Sprintf("%s.routes[%d]", path, i)
+			for j, previous := range route.Routes[:i] {
+				if !previous.Continue && shadows(previous.matchers(), child.matchers()) {
+					warnings = append(warnings, newWarning(messages.RouteUnreachable, childPath, fmt.Sprintf("%s.routes[%d]", path, j)))
+					break
+				}
+			}
+			walk(childPath, child)
+		}
+	}
+	walk("route", config.Route)
+
+	return warnings
+}
+
+// hasIntegration reports whether the receiver configures an integration,
+// such as email_configs or webhook_configs.
+func hasIntegration(receiver map[string]any) bool {
+	for key, value := range receiver {
+		if !strings.HasSuffix(key, "_configs") {
+			continue
+		}
+		if configs, ok := value.([]any); ok && len(configs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// shadows reports whether all the alerts matched by the matchers of a route
+// are matched by the matchers of a previous sibling, that is when the
+// matchers of the sibling are a subset of the ones of the route.
+func shadows(previous, matchers []routeMatcher) bool {
+	for _, m := range previous {
+		if !slices.Contains(matchers, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// matcherWarnings returns the warnings about the positive matchers on labels
+// or values which no rule produces, which never match.
+func matcherWarnings(path string, matchers []routeMatcher, produced producedLabels) []analyzerWarning {
+	var warnings []analyzerWarning
+	for _, m := range matchers {
+		if m.op != "=" && m.op != "=~" {
+			continue
+		}
+
+		values, static := produced.values[m.name]
+		if !produced.names[m.name] && !static {
+			warnings = append(warnings, newWarning(messages.MatcherLabelNotProduced, path, m.name))
+			continue
+		}
+
+		// The value can only be checked for the labels only set by the rules.
+		if m.op == "=" && m.value != "" && static && (!produced.names[m.name] || m.name == "alertname") && !values[m.value] && !hasTemplatedValue(values) {
+			warnings = append(warnings, newWarning(messages.MatcherValueNotProduced, path, m.String(), m.name))
+		}
+	}
+	return warnings
+}
+
+// hasTemplatedValue reports whether one of the values is a template,
+// expanded when the alert fires.
+func hasTemplatedValue(values map[string]bool) bool {
+	for value := range values {
+		if strings.Contains(value, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadEffectiveConfig returns the configuration generated by the operator for
+// the Alertmanager, merging its configuration Secret and the
+// AlertmanagerConfigs. It returns nil when the operator hasn't generated it
+// yet or when its Secret can't be read.
+func loadEffectiveConfig(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) (*alertmanagerConfig, error) {
+	secretName := fmt.Sprintf("alertmanager-%s-generated", name)
+	secret, err := getSecret(ctx, clientSets, r, secretName, namespace)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while getting secret %s: %w", secretName, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	data, found := secret.Data["alertmanager.yaml"]
+	if compressed, ok := secret.Data["alertmanager.yaml.gz"]; ok {
+		r, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("error while decompressing secret %s: %w", secretName, err)
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("error while decompressing secret %s: %w", secretName, err)
+		}
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var config alertmanagerConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error while parsing the configuration of secret %s: %w", secretName, err)
+	}
+	return &config, nil
+}
+
+// loadProducedLabels returns the labels of the alerts produced by the rules
+// of the cluster along with the external labels of the Prometheuses, nil
+// when there is no alerting rule to compare the matchers with.
+func loadProducedLabels(ctx context.Context, clientSets *k8sutil.ClientSets) (*producedLabels, error) {
+	rules, err := clientSets.MClient.MonitoringV1().PrometheusRules(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing PrometheusRules: %w", err)
+	}
+
+	produced := newProducedLabels()
+	alerts := 0
+	for _, rule := range rules.Items {
+		for _, group := range rule.Spec.Groups {
+			for _, r := range group.Rules {
+				if r.Alert == "" {
+					continue
+				}
+				produced.addRule(r.Alert, r.Expr.String(), r.Labels)
+				alerts++
+			}
+		}
+	}
+	if alerts == 0 {
+		return nil, nil
+	}
+
+	prometheuses, err := clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Prometheuses: %w", err)
+	}
+	for _, p := range prometheuses.Items {
+		for name := range p.Spec.ExternalLabels {
+			produced.names[name] = true
+		}
+		if p.Spec.ReplicaExternalLabelName != nil && *p.Spec.ReplicaExternalLabelName != "" {
+			produced.names[*p.Spec.ReplicaExternalLabelName] = true
+		}
+		if p.Spec.PrometheusExternalLabelName != nil && *p.Spec.PrometheusExternalLabelName != "" {
+			produced.names[*p.Spec.PrometheusExternalLabelName] = true
+		}
+	}
+
+	return &produced, nil
+}
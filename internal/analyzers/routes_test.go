@@ -0,0 +1,212 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRouteWarnings(t *testing.T) {
+	type testCase struct {
+		name             string
+		config           string
+		expectedMessages []string
+	}
+
+	produced := newProducedLabels()
+	produced.addRule("KubePodCrashLooping", `max_over_time(kube_pod_container_status_waiting_reason{reason="CrashLoopBackOff", cluster!=""}[5m]) >= 1`, map[string]string{"severity": "warning"})
+	produced.addRule("TargetDown", `100 * (count by (job, team) (up == 0)) > 10`, map[string]string{"severity": "critical"})
+
+	tests := []testCase{
+		{
+			name: "ValidRoutes",
+			config: `
+route:
+  receiver: default
+  routes:
+  - receiver: pager
+    matchers: ['severity="critical"', 'team=~"a|b"']
+  - receiver: default
+    match:
+      alertname: KubePodCrashLooping
+  - receiver: default
+    matchers: ['cluster!="dev"']
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://example.com
+- name: pager
+  pagerduty_configs:
+  - routing_key: key
+`,
+		},
+		{
+			name: "UndefinedReceiver",
+			config: `
+route:
+  receiver: default
+  routes:
+  - receiver: pagr
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://example.com
+`,
+			expectedMessages: []string{
+				"route.routes[0] routes to receiver pagr which isn't defined",
+			},
+		},
+		{
+			name: "ReceiverWithoutIntegration",
+			config: `
+route:
+  receiver: "null"
+receivers:
+- name: "null"
+- name: empty
+  email_configs: []
+`,
+			expectedMessages: []string{
+				"receiver null has no integration configured",
+				"receiver empty has no integration configured",
+			},
+		},
+		{
+			name: "UnreachableRoutes",
+			config: `
+route:
+  receiver: default
+  routes:
+  - receiver: default
+    matchers: ['severity="critical"']
+  - receiver: default
+    matchers: ['severity="critical"', 'team="a"']
+  - receiver: default
+    continue: true
+  - receiver: default
+    matchers: ['severity="warning"']
+  - receiver: default
+  - receiver: default
+    matchers: ['team="a"']
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://example.com
+`,
+			expectedMessages: []string{
+				`route.routes[1] is unreachable, route.routes[0] before it matches all its alerts`,
+				`route.routes[5] is unreachable, route.routes[4] before it matches all its alerts`,
+			},
+		},
+		{
+			name: "MatchersNotProduced",
+			config: `
+route:
+  receiver: default
+  routes:
+  - receiver: default
+    matchers: ['severty="critical"']
+  - receiver: default
+    matchers: ['severity="critcal"']
+  - receiver: default
+    match:
+      alertname: TargetDwn
+  - receiver: default
+    matchers: ['environment!="prod"']
+receivers:
+- name: default
+  webhook_configs:
+  - url: http://example.com
+`,
+			expectedMessages: []string{
+				"route.routes[0] matches on label severty which no alerting rule produces",
+				`route.routes[1] matches severity="critcal" but no alerting rule sets this value of severity`,
+				`route.routes[2] matches alertname="TargetDwn" but no alerting rule sets this value of alertname`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var config alertmanagerConfig
+			require.NoError(t, yaml.Unmarshal([]byte(tc.config), &config))
+
+			var messages []string
+			for _, w := range routeWarnings(config, &produced) {
+				messages = append(messages, w.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+}
+
+func TestLoadEffectiveConfig(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	_, err := w.Write([]byte("route:\n  receiver: default\nreceivers:\n- name: default\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	clientSets := k8stesting.NewFakeClientSets(
+		k8stesting.WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "alertmanager-main-generated", Namespace: "monitoring"},
+				Data:       map[string][]byte{"alertmanager.yaml.gz": compressed.Bytes()},
+			},
+			&monitoringv1.PrometheusRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "rules", Namespace: "default"},
+				Spec: monitoringv1.PrometheusRuleSpec{
+					Groups: []monitoringv1.RuleGroup{
+						{
+							Name: "group",
+							Rules: []monitoringv1.Rule{
+								{Record: "job:up:sum", Expr: intstr.FromString("sum by (job) (up)")},
+								{Alert: "Down", Expr: intstr.FromString("up == 0"), Labels: map[string]string{"severity": "critical"}},
+							},
+						},
+					},
+				},
+			},
+		),
+	)
+
+	config, err := loadEffectiveConfig(context.Background(), clientSets, newReport("Alertmanager", "main", "monitoring"), "main", "monitoring")
+	require.NoError(t, err)
+	require.NotNil(t, config)
+	assert.Equal(t, "default", config.Route.Receiver)
+
+	config, err = loadEffectiveConfig(context.Background(), clientSets, newReport("Alertmanager", "other", "monitoring"), "other", "monitoring")
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	produced, err := loadProducedLabels(context.Background(), clientSets)
+	require.NoError(t, err)
+	require.NotNil(t, produced)
+	assert.True(t, produced.values["alertname"]["Down"])
+	assert.True(t, produced.values["severity"]["critical"])
+	assert.False(t, produced.values["alertname"]["job:up:sum"])
+}
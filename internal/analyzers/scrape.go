@@ -0,0 +1,102 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+// targetLabels are the labels Prometheus uses to identify a scraped target.
+var targetLabels = []string{"job", "instance"}
+
+// endpointWarnings returns the honorLabels, honorTimestamps and
+// metricRelabelings pitfalls found in a scrape endpoint. Federation and
+// Pushgateway endpoints are expected to honor the scraped labels.
+func endpointWarnings(monitorName string, endpoint monitoringv1.Endpoint) []analyzerWarning {
+	var warnings []analyzerWarning
+
+	aggregator := isAggregatorEndpoint(monitorName, endpoint)
+
+	if endpoint.HonorLabels && !aggregator {
+		warnings = append(warnings, newWarning(messages.EndpointHonorLabels, endpoint.Port))
+	}
+
+	if endpoint.HonorTimestamps != nil && !*endpoint.HonorTimestamps && aggregator {
+		warnings = append(warnings, newWarning(messages.EndpointHonorTimestamps, endpoint.Port))
+	}
+
+	for _, label := range droppedTargetLabels(endpoint.MetricRelabelConfigs) {
+		warnings = append(warnings, newWarning(messages.EndpointDropsTargetLabel, label, endpoint.Port))
+	}
+
+	return warnings
+}
+
+// isAggregatorEndpoint reports whether the endpoint scrapes a federation
+// endpoint or a Pushgateway, for which honoring labels is expected.
+func isAggregatorEndpoint(monitorName string, endpoint monitoringv1.Endpoint) bool {
+	if endpoint.Path == "/federate" {
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(monitorName), "pushgateway") || strings.Contains(strings.ToLower(endpoint.Port), "pushgateway")
+}
+
+// droppedTargetLabels returns the target labels removed by labeldrop and
+// labelkeep actions in the relabeling configuration.
+func droppedTargetLabels(configs []monitoringv1.RelabelConfig) []string {
+	var dropped []string
+
+	for _, label := range targetLabels {
+		for _, cfg := range configs {
+			regex := cfg.Regex
+			if regex == "" {
+				regex = "(.*)"
+			}
+
+			re, err := regexp.Compile("^(?:" + regex + ")$")
+			if err != nil {
+				continue
+			}
+
+			action := strings.ToLower(cfg.Action)
+			if (action == "labeldrop" && re.MatchString(label)) || (action == "labelkeep" && !re.MatchString(label)) {
+				dropped = append(dropped, label)
+				break
+			}
+		}
+	}
+
+	return dropped
+}
+
+// invalidRelabelRegex returns the index and the error of the first
+// relabeling configuration whose regex doesn't compile.
+func invalidRelabelRegex(configs []monitoringv1.RelabelConfig) (int, error) {
+	for i, cfg := range configs {
+		if cfg.Regex == "" {
+			continue
+		}
+
+		if _, err := regexp.Compile("^(?:" + cfg.Regex + ")$"); err != nil {
+			return i, err
+		}
+	}
+	return 0, nil
+}
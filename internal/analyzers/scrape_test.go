@@ -0,0 +1,117 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestEndpointWarnings(t *testing.T) {
+	type testCase struct {
+		name             string
+		monitorName      string
+		endpoint         monitoringv1.Endpoint
+		expectedMessages []string
+	}
+
+	tests := []testCase{
+		{
+			name:        "NoPitfalls",
+			monitorName: "app",
+			endpoint: monitoringv1.Endpoint{
+				Port: "web",
+			},
+		},
+		{
+			name:        "HonorLabelsOnApplication",
+			monitorName: "app",
+			endpoint: monitoringv1.Endpoint{
+				Port:        "web",
+				HonorLabels: true,
+			},
+			expectedMessages: []string{"honorLabels is enabled for port web"},
+		},
+		{
+			name:        "HonorLabelsOnFederation",
+			monitorName: "federation",
+			endpoint: monitoringv1.Endpoint{
+				Port:        "web",
+				Path:        "/federate",
+				HonorLabels: true,
+			},
+		},
+		{
+			name:        "HonorLabelsOnPushgateway",
+			monitorName: "pushgateway",
+			endpoint: monitoringv1.Endpoint{
+				Port:        "http",
+				HonorLabels: true,
+			},
+		},
+		{
+			name:        "HonorTimestampsDisabledOnFederation",
+			monitorName: "federation",
+			endpoint: monitoringv1.Endpoint{
+				Port:            "web",
+				Path:            "/federate",
+				HonorLabels:     true,
+				HonorTimestamps: ptr.To(false),
+			},
+			expectedMessages: []string{"honorTimestamps is disabled for port web"},
+		},
+		{
+			name:        "LabelDropInstance",
+			monitorName: "app",
+			endpoint: monitoringv1.Endpoint{
+				Port: "web",
+				MetricRelabelConfigs: []monitoringv1.RelabelConfig{
+					{
+						Action: "labeldrop",
+						Regex:  "instance|pod",
+					},
+				},
+			},
+			expectedMessages: []string{"metricRelabelings drop the instance label for port web"},
+		},
+		{
+			name:        "LabelKeepWithoutTargetLabels",
+			monitorName: "app",
+			endpoint: monitoringv1.Endpoint{
+				Port: "web",
+				MetricRelabelConfigs: []monitoringv1.RelabelConfig{
+					{
+						Action: "labelkeep",
+						Regex:  "__name__|job",
+					},
+				},
+			},
+			expectedMessages: []string{"metricRelabelings drop the instance label for port web"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var messages []string
+			for _, w := range endpointWarnings(tc.monitorName, tc.endpoint) {
+				messages = append(messages, w.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// discoveryVerbs are the verbs Prometheus needs on the objects it discovers.
+var discoveryVerbs = []string{"list", "watch"}
+
+// discoveredResource is a resource watched by a Kubernetes service discovery.
+type discoveredResource struct {
+	Group    string
+	Resource string
+	// Cluster is true for cluster-scoped resources, which can only be
+	// granted by a ClusterRoleBinding.
+	Cluster bool
+}
+
+// kubernetesSDRoles are the roles of the Kubernetes service discovery.
+var kubernetesSDRoles = []string{"node", "service", "pod", "endpoints", "endpointslice", "ingress"}
+
+// scrapeConfigScraper is a Prometheus or a PrometheusAgent selecting a
+// ScrapeConfig.
+type scrapeConfigScraper struct {
+	kind           string
+	name           string
+	namespace      string
+	serviceAccount string
+}
+
+// secretReference is a Secret or a ConfigMap key referenced by a field of a
+// ScrapeConfig.
+type secretReference struct {
+	field string
+	ref   monitoringv1.SecretOrConfigMap
+}
+
+func RunScrapeConfigAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("ScrapeConfig", name, namespace)
+	return r.result(analyzeScrapeConfig(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeScrapeConfig(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	scrapeConfig, err := clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "ScrapeConfig", name, namespace)
+		}
+		return fmt.Errorf("error while getting ScrapeConfig: %w", err)
+	}
+
+	for _, ref := range scrapeConfigReferences(scrapeConfig) {
+		if err := k8sutil.CheckSecretOrConfigMap(ctx, *clientSets, namespace, ref.ref); err != nil {
+			r.fail(messages.ScrapeConfigInvalidReference, ref.field, name, namespace, err)
+		}
+	}
+
+	if i, err := invalidRelabelRegex(scrapeConfig.Spec.RelabelConfigs); err != nil {
+		r.fail(messages.ScrapeConfigInvalidRegex, "relabelings", i, name, namespace, err)
+	}
+
+	if i, err := invalidRelabelRegex(scrapeConfig.Spec.MetricRelabelConfigs); err != nil {
+		r.fail(messages.ScrapeConfigInvalidRegex, "metricRelabelings", i, name, namespace, err)
+	}
+
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := kubernetesSDWarnings(scrapeConfig, namespaces)
+	if err != nil {
+		return err
+	}
+	r.warn(warnings...)
+
+	scrapers, err := selectingScrapers(ctx, clientSets, scrapeConfig, namespaces)
+	if err != nil {
+		return err
+	}
+
+	if len(scrapers) == 0 {
+		r.fail(messages.ScrapeConfigNotSelected, name)
+	}
+
+	for _, scraper := range scrapers {
+		for i, sd := range scrapeConfig.Spec.KubernetesSDConfigs {
+			// The cluster-wide resources are checked once per discovery.
+			denied := map[string]bool{}
+			if sd.APIServer != nil {
+				// The discovery authenticates against another API server,
+				// not with the ServiceAccount of the scraper.
+				continue
+			}
+
+			for _, ns := range kubernetesSDNamespaces(sd, scraper.namespace) {
+				for _, resource := range kubernetesSDResources(sd) {
+					scope := ns
+					if resource.Cluster {
+						scope = metav1.NamespaceAll
+					}
+
+					allowed, err := serviceAccountAllowed(ctx, clientSets, scraper.serviceAccount, scraper.namespace, scope, resource)
+					if err != nil {
+						return err
+					}
+
+					if !allowed && !denied[scope+"/"+resource.Resource] {
+						denied[scope+"/"+resource.Resource] = true
+						r.fail(messages.ScrapeConfigDiscoveryRBAC, i, sd.Role, scraper.serviceAccount, scraper.kind, scraper.namespace, scraper.name, resource.Resource, scopeSuffix(scope))
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// scrapeConfigReferences returns the Secrets and ConfigMaps referenced by the
+// authentication and the TLS configuration of the ScrapeConfig, of its
+// Kubernetes and of its HTTP service discoveries.
+func scrapeConfigReferences(scrapeConfig *monitoringv1alpha1.ScrapeConfig) []secretReference {
+	spec := scrapeConfig.Spec
+	refs := httpClientReferences("", spec.BasicAuth, spec.Authorization, nil, spec.TLSConfig)
+
+	for i, sd := range spec.KubernetesSDConfigs {
+		refs = append(refs, httpClientReferences(fmt.Sprintf("kubernetesSDConfigs[%d].", i), sd.BasicAuth, sd.Authorization, sd.OAuth2, sd.TLSConfig)...)
+	}
+
+	for i, sd := range spec.HTTPSDConfigs {
+		refs = append(refs, httpClientReferences(fmt.Sprintf("httpSDConfigs[%d].", i), sd.BasicAuth, sd.Authorization, nil, sd.TLSConfig)...)
+	}
+
+	return refs
+}
+
+// httpClientReferences returns the Secrets and ConfigMaps referenced by the
+// configuration of an HTTP client, the fields being prefixed with prefix.
+func httpClientReferences(prefix string, basicAuth *monitoringv1.BasicAuth, authorization *monitoringv1.SafeAuthorization, oauth2 *monitoringv1.OAuth2, tlsConfig *monitoringv1.SafeTLSConfig) []secretReference {
+	var refs []secretReference
+	add := func(field string, ref monitoringv1.SecretOrConfigMap) {
+		if (ref.Secret != nil && ref.Secret.Name != "") || (ref.ConfigMap != nil && ref.ConfigMap.Name != "") {
+			refs = append(refs, secretReference{field: prefix + field, ref: ref})
+		}
+	}
+
+	if basicAuth != nil {
+		add("basicAuth.username", monitoringv1.SecretOrConfigMap{Secret: &basicAuth.Username})
+		add("basicAuth.password", monitoringv1.SecretOrConfigMap{Secret: &basicAuth.Password})
+	}
+
+	if authorization != nil {
+		add("authorization.credentials", monitoringv1.SecretOrConfigMap{Secret: authorization.Credentials})
+	}
+
+	if oauth2 != nil {
+		add("oauth2.clientId", oauth2.ClientID)
+		add("oauth2.clientSecret", monitoringv1.SecretOrConfigMap{Secret: &oauth2.ClientSecret})
+	}
+
+	if tlsConfig != nil {
+		add("tlsConfig.ca", tlsConfig.CA)
+		add("tlsConfig.cert", tlsConfig.Cert)
+		add("tlsConfig.keySecret", monitoringv1.SecretOrConfigMap{Secret: tlsConfig.KeySecret})
+	}
+
+	return refs
+}
+
+// kubernetesSDWarnings validates the roles, the namespaces and the selectors
+// of the Kubernetes service discoveries, returning the warnings about the
+// namespaces which don't exist or are ignored.
+func kubernetesSDWarnings(scrapeConfig *monitoringv1alpha1.ScrapeConfig, namespaces map[string]labels.Set) ([]analyzerWarning, error) {
+	name, namespace := scrapeConfig.Name, scrapeConfig.Namespace
+
+	var warnings []analyzerWarning
+	for i, sd := range scrapeConfig.Spec.KubernetesSDConfigs {
+		role := strings.ToLower(string(sd.Role))
+		if !slices.Contains(kubernetesSDRoles, role) {
+			return nil, messages.New(messages.ScrapeConfigUnknownRole, i, name, namespace, sd.Role, strings.Join(kubernetesSDRoles, ", "))
+		}
+
+		if sd.Namespaces != nil {
+			if role == "node" {
+				warnings = append(warnings, newWarning(messages.ScrapeConfigNamespaceIgnored, i))
+			}
+
+			for _, ns := range sd.Namespaces.Names {
+				if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+					return nil, messages.New(messages.ScrapeConfigInvalidNamespace, i, name, namespace, ns, strings.Join(errs, ", "))
+				}
+
+				// The discoveries of other clusters can't be checked
+				// against the namespaces of this one.
+				if _, ok := namespaces[ns]; !ok && sd.APIServer == nil && role != "node" {
+					warnings = append(warnings, newWarning(messages.ScrapeConfigNamespaceMissing, i, ns))
+				}
+			}
+		}
+
+		for j, selector := range sd.Selectors {
+			if _, err := labels.Parse(selector.Label); err != nil {
+				return nil, messages.New(messages.ScrapeConfigInvalidSelector, i, j, name, namespace, "label", err)
+			}
+
+			if _, err := fields.ParseSelector(selector.Field); err != nil {
+				return nil, messages.New(messages.ScrapeConfigInvalidSelector, i, j, name, namespace, "field", err)
+			}
+		}
+	}
+
+	return warnings, nil
+}
+
+// selectingScrapers returns the Prometheuses and the PrometheusAgents whose
+// ScrapeConfig selectors match the ScrapeConfig.
+func selectingScrapers(ctx context.Context, clientSets *k8sutil.ClientSets, scrapeConfig *monitoringv1alpha1.ScrapeConfig, namespaces map[string]labels.Set) ([]scrapeConfigScraper, error) {
+	var scrapers []scrapeConfigScraper
+	selects := func(kind string, objectMeta metav1.ObjectMeta, spec monitoringv1.CommonPrometheusFields) {
+		if selectsObject(objectMeta.Namespace, spec.ScrapeConfigSelector, spec.ScrapeConfigNamespaceSelector, scrapeConfig.ObjectMeta, namespaces) {
+			scrapers = append(scrapers, scrapeConfigScraper{
+				kind:           kind,
+				name:           objectMeta.Name,
+				namespace:      objectMeta.Namespace,
+				serviceAccount: cmp.Or(spec.ServiceAccountName, "default"),
+			})
+		}
+	}
+
+	err := k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		selects("Prometheus", p.ObjectMeta, p.Spec.CommonPrometheusFields)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PrometheusAgents", clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1alpha1.PrometheusAgent) error {
+		selects("PrometheusAgent", p.ObjectMeta, p.Spec.CommonPrometheusFields)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scrapers, nil
+}
+
+// kubernetesSDNamespaces returns the namespaces watched by the discovery,
+// metav1.NamespaceAll standing for all namespaces.
+func kubernetesSDNamespaces(sd monitoringv1alpha1.KubernetesSDConfig, prometheusNamespace string) []string {
+	if sd.Namespaces == nil {
+		return []string{metav1.NamespaceAll}
+	}
+
+	namespaces := slices.Clone(sd.Namespaces.Names)
+	if sd.Namespaces.IncludeOwnNamespace != nil && *sd.Namespaces.IncludeOwnNamespace && !slices.Contains(namespaces, prometheusNamespace) {
+		namespaces = append(namespaces, prometheusNamespace)
+	}
+
+	if len(namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return namespaces
+}
+
+// kubernetesSDResources returns the resources Prometheus watches for the
+// role of the discovery, including the nodes when the node metadata is
+// attached to the targets.
+func kubernetesSDResources(sd monitoringv1alpha1.KubernetesSDConfig) []discoveredResource {
+	var resources []discoveredResource
+
+	switch strings.ToLower(string(sd.Role)) {
+	case "node":
+		resources = []discoveredResource{{Resource: "nodes", Cluster: true}}
+	case "service":
+		resources = []discoveredResource{{Resource: "services"}}
+	case "pod":
+		resources = []discoveredResource{{Resource: "pods"}}
+	case "endpoints":
+		resources = []discoveredResource{{Resource: "endpoints"}, {Resource: "services"}, {Resource: "pods"}}
+	case "endpointslice":
+		resources = []discoveredResource{{Group: "discovery.k8s.io", Resource: "endpointslices"}, {Resource: "services"}, {Resource: "pods"}}
+	case "ingress":
+		resources = []discoveredResource{{Group: "networking.k8s.io", Resource: "ingresses"}}
+	}
+
+	if sd.AttachMetadata != nil && sd.AttachMetadata.Node != nil && *sd.AttachMetadata.Node && !slices.Contains(resources, discoveredResource{Resource: "nodes", Cluster: true}) {
+		resources = append(resources, discoveredResource{Resource: "nodes", Cluster: true})
+	}
+
+	return resources
+}
+
+// serviceAccountAllowed reports whether the ServiceAccount can list and
+// watch the resource in the namespace, or in all namespaces for
+// metav1.NamespaceAll. Cluster-wide permissions come from the
+// ClusterRoleBindings, namespaced ones from the RoleBindings.
+func serviceAccountAllowed(ctx context.Context, clientSets *k8sutil.ClientSets, serviceAccount, serviceAccountNamespace, namespace string, resource discoveredResource) (bool, error) {
+	crbs, err := clientSets.KClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error while listing ClusterRoleBindings: %w", err)
+	}
+
+	for _, crb := range crbs.Items {
+		if !isBoundServiceAccount(crb.Subjects, serviceAccount, serviceAccountNamespace) {
+			continue
+		}
+
+		rules, err := roleRefRules(ctx, clientSets, crb.RoleRef, "")
+		if err != nil {
+			return false, err
+		}
+		if allowsResource(rules, resource) {
+			return true, nil
+		}
+	}
+
+	if namespace == metav1.NamespaceAll {
+		return false, nil
+	}
+
+	rbs, err := clientSets.KClient.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error while listing RoleBindings in namespace %s: %w", namespace, err)
+	}
+
+	for _, rb := range rbs.Items {
+		if !isBoundServiceAccount(rb.Subjects, serviceAccount, serviceAccountNamespace) {
+			continue
+		}
+
+		rules, err := roleRefRules(ctx, clientSets, rb.RoleRef, namespace)
+		if err != nil {
+			return false, err
+		}
+		if allowsResource(rules, resource) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func roleRefRules(ctx context.Context, clientSets *k8sutil.ClientSets, ref rbacv1.RoleRef, namespace string) ([]rbacv1.PolicyRule, error) {
+	if ref.Kind == "Role" {
+		role, err := clientSets.KClient.RbacV1().Roles(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("error while getting Role %s/%s: %w", namespace, ref.Name, err)
+		}
+		return role.Rules, nil
+	}
+
+	clusterRole, err := clientSets.KClient.RbacV1().ClusterRoles().Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while getting ClusterRole %s: %w", ref.Name, err)
+	}
+	return clusterRole.Rules, nil
+}
+
+func isBoundServiceAccount(subjects []rbacv1.Subject, name, namespace string) bool {
+	for _, s := range subjects {
+		if s.Kind == rbacv1.ServiceAccountKind && s.Name == name && s.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func allowsResource(rules []rbacv1.PolicyRule, resource discoveredResource) bool {
+	for _, verb := range discoveryVerbs {
+		allowed := false
+		for _, r := range rules {
+			if len(r.ResourceNames) > 0 {
+				continue
+			}
+			if matchesRuleValue(r.APIGroups, resource.Group) && matchesRuleValue(r.Resources, resource.Resource) && matchesRuleValue(r.Verbs, verb) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesRuleValue(values []string, value string) bool {
+	return slices.Contains(values, rbacv1.ResourceAll) || slices.Contains(values, value)
+}
+
+func scopeSuffix(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return " cluster-wide"
+	}
+	return fmt.Sprintf(" in namespace %s", namespace)
+}
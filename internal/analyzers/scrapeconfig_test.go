@@ -0,0 +1,365 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestScrapeConfigAnalyzer(t *testing.T) {
+	type testCase struct {
+		name       string
+		sd         monitoringv1alpha1.KubernetesSDConfig
+		selected   bool
+		rbac       []runtime.Object
+		shouldFail bool
+	}
+
+	tests := []testCase{
+		{
+			name:       "NotSelected",
+			sd:         monitoringv1alpha1.KubernetesSDConfig{Role: "Pod"},
+			rbac:       getScrapeConfigClusterRBAC("pods"),
+			shouldFail: true,
+		},
+		{
+			name:     "PodRoleAllowedClusterWide",
+			sd:       monitoringv1alpha1.KubernetesSDConfig{Role: "Pod"},
+			selected: true,
+			rbac:     getScrapeConfigClusterRBAC("pods"),
+		},
+		{
+			name:       "EndpointSliceRoleWithoutEndpointSlices",
+			sd:         monitoringv1alpha1.KubernetesSDConfig{Role: "EndpointSlice"},
+			selected:   true,
+			rbac:       getScrapeConfigClusterRBAC("endpoints", "services", "pods"),
+			shouldFail: true,
+		},
+		{
+			name: "NamespacedRoleBinding",
+			sd: monitoringv1alpha1.KubernetesSDConfig{
+				Role:       "service",
+				Namespaces: &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"app"}},
+			},
+			selected: true,
+			rbac:     getScrapeConfigNamespacedRBAC("app", "services"),
+		},
+		{
+			name:       "NamespacedRoleBindingWithoutNamespaces",
+			sd:         monitoringv1alpha1.KubernetesSDConfig{Role: "service"},
+			selected:   true,
+			rbac:       getScrapeConfigNamespacedRBAC("app", "services"),
+			shouldFail: true,
+		},
+		{
+			name: "AttachNodeMetadataWithoutNodes",
+			sd: monitoringv1alpha1.KubernetesSDConfig{
+				Role:           "pod",
+				Namespaces:     &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"app"}},
+				AttachMetadata: &monitoringv1alpha1.AttachMetadata{Node: ptr.To(true)},
+			},
+			selected:   true,
+			rbac:       getScrapeConfigNamespacedRBAC("app", "pods", "nodes"),
+			shouldFail: true,
+		},
+		{
+			name: "ExternalAPIServer",
+			sd: monitoringv1alpha1.KubernetesSDConfig{
+				Role:      "node",
+				APIServer: ptr.To("https://other-cluster:6443"),
+			},
+			selected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scrapeConfig := &monitoringv1alpha1.ScrapeConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "kubernetes",
+					Namespace: "monitoring",
+					Labels:    map[string]string{"team": "platform"},
+				},
+				Spec: monitoringv1alpha1.ScrapeConfigSpec{
+					KubernetesSDConfigs: []monitoringv1alpha1.KubernetesSDConfig{tc.sd},
+				},
+			}
+
+			selector := map[string]string{"team": "other"}
+			if tc.selected {
+				selector = scrapeConfig.Labels
+			}
+
+			prometheus := &monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "k8s",
+					Namespace: "monitoring",
+				},
+				Spec: monitoringv1.PrometheusSpec{
+					CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+						ServiceAccountName:   "prometheus",
+						ScrapeConfigSelector: &metav1.LabelSelector{MatchLabels: selector},
+					},
+				},
+			}
+
+			clientSets := &k8sutil.ClientSets{
+				KClient: fake.NewSimpleClientset(tc.rbac...),
+				MClient: monitoringclient.NewSimpleClientset(scrapeConfig, prometheus),
+			}
+
+			err := analysisErr(RunScrapeConfigAnalyzer(context.Background(), clientSets, scrapeConfig.Name, scrapeConfig.Namespace))
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestScrapeConfigAnalyzerSpec(t *testing.T) {
+	agent := &monitoringv1alpha1.PrometheusAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "monitoring"},
+		Spec: monitoringv1alpha1.PrometheusAgentSpec{
+			CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+				ScrapeConfigSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "monitoring"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	basicAuth := &monitoringv1.BasicAuth{
+		Password: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"},
+			Key:                  "password",
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		spec     monitoringv1alpha1.ScrapeConfigSpec
+		objects  []runtime.Object
+		expected messages.ID
+	}{
+		{
+			name:    "SelectedByPrometheusAgent",
+			spec:    monitoringv1alpha1.ScrapeConfigSpec{BasicAuth: basicAuth},
+			objects: []runtime.Object{agent, secret},
+		},
+		{
+			name:     "NotSelected",
+			spec:     monitoringv1alpha1.ScrapeConfigSpec{},
+			expected: messages.ScrapeConfigNotSelected,
+		},
+		{
+			name:     "MissingSecret",
+			spec:     monitoringv1alpha1.ScrapeConfigSpec{BasicAuth: basicAuth},
+			objects:  []runtime.Object{agent},
+			expected: messages.ScrapeConfigInvalidReference,
+		},
+		{
+			name: "MissingSecretKey",
+			spec: monitoringv1alpha1.ScrapeConfigSpec{
+				HTTPSDConfigs: []monitoringv1alpha1.HTTPSDConfig{
+					{
+						URL: "http://sd.example.com",
+						Authorization: &monitoringv1.SafeAuthorization{
+							Credentials: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"},
+								Key:                  "token",
+							},
+						},
+					},
+				},
+			},
+			objects:  []runtime.Object{agent, secret},
+			expected: messages.ScrapeConfigInvalidReference,
+		},
+		{
+			name: "InvalidRelabelingRegex",
+			spec: monitoringv1alpha1.ScrapeConfigSpec{
+				MetricRelabelConfigs: []monitoringv1.RelabelConfig{{Action: "drop", Regex: "go_(.*"}},
+			},
+			objects:  []runtime.Object{agent},
+			expected: messages.ScrapeConfigInvalidRegex,
+		},
+		{
+			name: "UnknownRole",
+			spec: monitoringv1alpha1.ScrapeConfigSpec{
+				KubernetesSDConfigs: []monitoringv1alpha1.KubernetesSDConfig{{Role: "Deployment"}},
+			},
+			objects:  []runtime.Object{agent},
+			expected: messages.ScrapeConfigUnknownRole,
+		},
+		{
+			name: "InvalidNamespace",
+			spec: monitoringv1alpha1.ScrapeConfigSpec{
+				KubernetesSDConfigs: []monitoringv1alpha1.KubernetesSDConfig{
+					{Role: "Pod", Namespaces: &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"App_1"}}},
+				},
+			},
+			objects:  []runtime.Object{agent},
+			expected: messages.ScrapeConfigInvalidNamespace,
+		},
+		{
+			name: "InvalidLabelSelector",
+			spec: monitoringv1alpha1.ScrapeConfigSpec{
+				KubernetesSDConfigs: []monitoringv1alpha1.KubernetesSDConfig{
+					{Role: "Pod", Selectors: []monitoringv1alpha1.K8SSelectorConfig{{Role: "Pod", Label: "app in (a"}}},
+				},
+			},
+			objects:  []runtime.Object{agent},
+			expected: messages.ScrapeConfigInvalidSelector,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scrapeConfig := &monitoringv1alpha1.ScrapeConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "static",
+					Namespace: "monitoring",
+					Labels:    map[string]string{"team": "platform"},
+				},
+				Spec: tc.spec,
+			}
+
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(append(tc.objects, scrapeConfig)...))
+
+			err := analysisErr(RunScrapeConfigAnalyzer(context.Background(), clientSets, scrapeConfig.Name, scrapeConfig.Namespace))
+			if tc.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			id, ok := messages.IDOf(err)
+			require.True(t, ok, "unexpected error %v", err)
+			assert.Equal(t, tc.expected, id)
+		})
+	}
+}
+
+func TestKubernetesSDWarnings(t *testing.T) {
+	namespaces := map[string]labels.Set{"app": {}}
+
+	for _, tc := range []struct {
+		name     string
+		sd       monitoringv1alpha1.KubernetesSDConfig
+		expected []messages.ID
+	}{
+		{
+			name: "ExistingNamespace",
+			sd:   monitoringv1alpha1.KubernetesSDConfig{Role: "pod", Namespaces: &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"app"}}},
+		},
+		{
+			name:     "MissingNamespace",
+			sd:       monitoringv1alpha1.KubernetesSDConfig{Role: "pod", Namespaces: &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"app", "other"}}},
+			expected: []messages.ID{messages.ScrapeConfigNamespaceMissing},
+		},
+		{
+			name: "MissingNamespaceOfAnotherCluster",
+			sd: monitoringv1alpha1.KubernetesSDConfig{
+				Role:       "pod",
+				APIServer:  ptr.To("https://other-cluster:6443"),
+				Namespaces: &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"other"}},
+			},
+		},
+		{
+			name:     "NodeRoleWithNamespaces",
+			sd:       monitoringv1alpha1.KubernetesSDConfig{Role: "node", Namespaces: &monitoringv1alpha1.NamespaceDiscovery{Names: []string{"other"}}},
+			expected: []messages.ID{messages.ScrapeConfigNamespaceIgnored},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scrapeConfig := &monitoringv1alpha1.ScrapeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "monitoring"},
+				Spec: monitoringv1alpha1.ScrapeConfigSpec{
+					KubernetesSDConfigs: []monitoringv1alpha1.KubernetesSDConfig{tc.sd},
+				},
+			}
+
+			warnings, err := kubernetesSDWarnings(scrapeConfig, namespaces)
+			require.NoError(t, err)
+
+			var ids []messages.ID
+			for _, w := range warnings {
+				ids = append(ids, w.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func getScrapeConfigClusterRBAC(resources ...string) []runtime.Object {
+	return []runtime.Object{
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus"},
+			Rules: []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: resources,
+					Verbs:     []string{"get", "list", "watch"},
+				},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "prometheus"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "prometheus", Namespace: "monitoring"},
+			},
+		},
+	}
+}
+
+func getScrapeConfigNamespacedRBAC(namespace string, resources ...string) []runtime.Object {
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: namespace},
+			Rules: []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: resources,
+					Verbs:     []string{"list", "watch"},
+				},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: namespace},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "prometheus"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "prometheus", Namespace: "monitoring"},
+			},
+		},
+	}
+}
@@ -17,86 +17,363 @@ package analyzers
 import (
 	"context"
 	"fmt"
-	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/prometheus-operator/poctl/internal/resolve"
+	"github.com/prometheus-operator/poctl/pkg/builder"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func RunServiceMonitorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) error {
+func RunServiceMonitorAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("ServiceMonitor", name, namespace)
+	return r.result(analyzeServiceMonitor(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeServiceMonitor(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
 	serviceMonitor, err := clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return fmt.Errorf("ServiceMonitor %s not found in namespace %s", name, namespace)
+			return messages.New(messages.ObjectNotFound, "ServiceMonitor", name, namespace)
 		}
-		return fmt.Errorf("error while getting ServiceMonitor: %v", err)
+		return fmt.Errorf("error while getting ServiceMonitor: %w", err)
 	}
 
 	if len(serviceMonitor.Spec.Selector.MatchLabels) == 0 && len(serviceMonitor.Spec.Selector.MatchExpressions) == 0 {
-		return fmt.Errorf("ServiceMonitor %s in namespace %s does not have a selector", name, namespace)
+		return messages.New(messages.ServiceMonitorNoSelector, name, namespace)
 	}
 
-	if len(serviceMonitor.Spec.Selector.MatchLabels) > 0 {
-		services, err := clientSets.KClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+	namespaces := resolve.SelectedNamespaces(namespace, serviceMonitor.Spec.NamespaceSelector)
+
+	services := &v1.ServiceList{}
+	for _, ns := range namespaces {
+		list, err := clientSets.KClient.CoreV1().Services(ns).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(&serviceMonitor.Spec.Selector),
 		})
 
 		if err != nil {
-			return fmt.Errorf("error while listing services: %v", err)
+			return fmt.Errorf("error while listing services: %w", err)
+		}
+
+		services.Items = append(services.Items, list.Items...)
+	}
+
+	if len(services.Items) == 0 {
+		return messages.New(messages.ServiceMonitorNoServices, name, namespace, namespacesString(namespaces))
+	}
+
+	// The warnings about the Services are logged first, since they often
+	// explain the failures of the checks of the ports.
+	serviceWarnings, err := servicesWarnings(ctx, clientSets, services)
+	if err != nil {
+		return err
+	}
+	r.warn(serviceWarnings...)
+
+	candidates, err := servicePortCandidates(ctx, clientSets, services)
+	if err != nil {
+		return err
+	}
+
+	if err := r.check(evaluatePortMatches(serviceMonitor, candidates, name, namespace)); err != nil {
+		return err
+	}
+
+	evaluateEndpointsTLS(ctx, clientSets, r, serviceMonitor, candidates, name, namespace)
+
+	if err := evaluateEndpointsTokens(ctx, clientSets, r, serviceMonitor, namespace); err != nil {
+		return err
+	}
+
+	for _, endpoint := range serviceMonitor.Spec.Endpoints {
+		r.warn(endpointWarnings(name, endpoint)...)
+	}
+
+	return nil
+}
+
+// servicesWarnings returns the warnings about the Services which have no
+// endpoints for the ServiceMonitor to scrape: the ExternalName Services, and
+// the headless Services without endpoints.
+func servicesWarnings(ctx context.Context, clientSets *k8sutil.ClientSets, services *v1.ServiceList) ([]analyzerWarning, error) {
+	var warnings []analyzerWarning
+	for _, service := range services.Items {
+		switch {
+		case service.Spec.Type == v1.ServiceTypeExternalName:
+			warnings = append(warnings, newWarning(messages.ServiceExternalName, service.Name, service.Namespace, service.Spec.ExternalName))
+		case service.Spec.ClusterIP == v1.ClusterIPNone:
+			groups, err := resolve.ServiceEndpoints(ctx, clientSets, service.Namespace, service.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !resolve.HasAddresses(groups) {
+				warnings = append(warnings, newWarning(messages.HeadlessServiceNoEndpoints, service.Name, service.Namespace))
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// namespacesString describes the namespaces searched for services.
+func namespacesString(namespaces []string) string {
+	if len(namespaces) == 1 && namespaces[0] == metav1.NamespaceAll {
+		return "all namespaces"
+	}
+	if len(namespaces) == 1 {
+		return "namespace " + namespaces[0]
+	}
+	return "namespaces " + strings.Join(namespaces, ", ")
+}
+
+// servicePortCandidates returns the ports of the services, their named target
+// ports being resolved to the container ports of the pods they select.
+func servicePortCandidates(ctx context.Context, clientSets *k8sutil.ClientSets, services *v1.ServiceList) ([]servicePortCandidate, error) {
+	var candidates []servicePortCandidate
+	for _, service := range services.Items {
+		for _, port := range service.Spec.Ports {
+			containerPorts, err := resolveTargetPort(ctx, clientSets, service, port)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, servicePortCandidate{service: service.Name, port: port, containerPorts: containerPorts})
+		}
+	}
+	return candidates, nil
+}
+
+// evaluatePortMatches checks that each endpoint selects one of the candidate
+// ports, by name with port or by number or name with targetPort. The
+// candidate ports are reported on failure.
+func evaluatePortMatches(serviceMonitor *monitoringv1.ServiceMonitor, candidates []servicePortCandidate, name string, namespace string) error {
+	for _, endpoint := range serviceMonitor.Spec.Endpoints {
+		found := false
+		for _, candidate := range candidates {
+			if candidate.matches(endpoint) {
+				found = true
+				break
+			}
 		}
 
-		if len(services.Items) == 0 {
-			return fmt.Errorf("ServiceMonitor %s in namespace %s has no services matching the selector", name, namespace)
+		if found {
+			continue
 		}
 
-		if err = evaluatePortMatches(serviceMonitor, services, name, namespace); err != nil {
-			return err
+		var considered []string
+		for _, candidate := range candidates {
+			considered = append(considered, candidate.String())
 		}
+
+		return messages.New(messages.ServiceMonitorNoPort, name, namespace, endpointPortString(endpoint), strings.Join(considered, ", "))
 	}
+	return nil
+}
 
-	if len(serviceMonitor.Spec.Selector.MatchExpressions) > 0 {
-		services, err := clientSets.KClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
-			LabelSelector: metav1.FormatLabelSelector(&serviceMonitor.Spec.Selector),
+// servicePortCandidate is a service port which an endpoint may select, along
+// with the container ports its target port resolves to.
+type servicePortCandidate struct {
+	service        string
+	port           v1.ServicePort
+	containerPorts []v1.ContainerPort
+}
+
+// matches reports whether the endpoint selects the service port. An endpoint
+// without port nor targetPort selects all the ports.
+func (c servicePortCandidate) matches(endpoint monitoringv1.Endpoint) bool {
+	if endpoint.Port != "" {
+		return c.port.Name == endpoint.Port
+	}
+
+	if endpoint.TargetPort == nil {
+		return true
+	}
+
+	if endpoint.TargetPort.Type == intstr.String && c.port.TargetPort.Type == intstr.String && c.port.TargetPort.StrVal == endpoint.TargetPort.StrVal {
+		return true
+	}
+
+	for _, containerPort := range c.containerPorts {
+		if endpoint.TargetPort.Type == intstr.String && containerPort.Name == endpoint.TargetPort.StrVal {
+			return true
+		}
+		if endpoint.TargetPort.Type == intstr.Int && containerPort.ContainerPort == endpoint.TargetPort.IntVal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// portString returns the name of the service port, or its number when it's
+// unnamed.
+func (c servicePortCandidate) portString() string {
+	if c.port.Name == "" {
+		return strconv.Itoa(int(c.port.Port))
+	}
+	return c.port.Name
+}
+
+func (c servicePortCandidate) String() string {
+	port := c.portString()
+
+	var containerPorts []string
+	for _, p := range c.containerPorts {
+		if p.Name == "" {
+			containerPorts = append(containerPorts, strconv.Itoa(int(p.ContainerPort)))
+			continue
+		}
+		containerPorts = append(containerPorts, fmt.Sprintf("%s:%d", p.Name, p.ContainerPort))
+	}
+
+	targetPort := c.port.TargetPort.String()
+	if c.port.TargetPort.Type == intstr.Int && c.port.TargetPort.IntVal == 0 {
+		targetPort = strconv.Itoa(int(c.port.Port))
+	}
+
+	return fmt.Sprintf("%s/%s (targetPort %s, container ports [%s])", c.service, port, targetPort, strings.Join(containerPorts, " "))
+}
+
+// resolveTargetPort returns the container ports the target port of a service
+// port resolves to, looked up in the containers of the pods selected by the
+// service. A numeric target port is kept when no pod exposes it.
+func resolveTargetPort(ctx context.Context, clientSets *k8sutil.ClientSets, service v1.Service, port v1.ServicePort) ([]v1.ContainerPort, error) {
+	number := port.TargetPort.IntVal
+	if port.TargetPort.Type == intstr.Int && number == 0 {
+		// The target port defaults to the service port.
+		number = port.Port
+	}
+
+	var containerPorts []v1.ContainerPort
+	if len(service.Spec.Selector) > 0 {
+		pods, err := clientSets.KClient.CoreV1().Pods(service.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(service.Spec.Selector).String(),
 		})
+		if err != nil {
+			return nil, fmt.Errorf("error while listing pods of service %s: %w", service.Name, err)
+		}
+
+		for _, pod := range pods.Items {
+			for _, container := range pod.Spec.Containers {
+				for _, containerPort := range container.Ports {
+					resolved := containerPort.ContainerPort == number
+					if port.TargetPort.Type == intstr.String {
+						resolved = containerPort.Name == port.TargetPort.StrVal
+					}
+
+					if resolved && !slices.ContainsFunc(containerPorts, func(p v1.ContainerPort) bool {
+						return p.Name == containerPort.Name && p.ContainerPort == containerPort.ContainerPort
+					}) {
+						containerPorts = append(containerPorts, containerPort)
+					}
+				}
+			}
+		}
+	}
 
+	if len(containerPorts) == 0 && number != 0 {
+		containerPorts = append(containerPorts, v1.ContainerPort{ContainerPort: number})
+	}
+
+	return containerPorts, nil
+}
+
+// endpointPortString describes the port selected by an endpoint.
+func endpointPortString(endpoint monitoringv1.Endpoint) string {
+	if endpoint.Port != "" || endpoint.TargetPort == nil {
+		return "port " + endpoint.Port
+	}
+	return "targetPort " + endpoint.TargetPort.String()
+}
+
+// tokenExpiryThreshold is the duration before the expiry of a token under
+// which it's reported as expiring.
+const tokenExpiryThreshold = 30 * 24 * time.Hour
+
+// evaluateEndpointsTokens checks the expiry of the tokens requested by poctl
+// create servicemonitor --secure, which aren't renewed: the scrapes of the
+// endpoints authorized by an expired token fail with 401. The Secrets
+// without the expiry annotation, or which don't exist, aren't checked.
+func evaluateEndpointsTokens(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, serviceMonitor *monitoringv1.ServiceMonitor, namespace string) error {
+	for _, endpoint := range serviceMonitor.Spec.Endpoints {
+		if endpoint.Authorization == nil || endpoint.Authorization.Credentials == nil {
+			continue
+		}
+
+		name := endpoint.Authorization.Credentials.Name
+		secret, err := getSecret(ctx, clientSets, r, name, namespace)
 		if err != nil {
-			return fmt.Errorf("error while listing services: %v", err)
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("error while getting Secret %s: %w", name, err)
+		}
+		if secret == nil {
+			continue
 		}
 
-		if len(services.Items) == 0 {
-			return fmt.Errorf("ServiceMonitor %s in namespace %s has no services matching the selector", name, namespace)
+		expiry, err := time.Parse(time.RFC3339, secret.Annotations[builder.TokenExpirationAnnotation])
+		if err != nil {
+			continue
 		}
 
-		if err = evaluatePortMatches(serviceMonitor, services, name, namespace); err != nil {
-			return err
+		port := endpointPortString(endpoint)
+		switch remaining := time.Until(expiry); {
+		case remaining <= 0:
+			r.fail(messages.ServiceMonitorTokenExpired, name, namespace, port, expiry.Format(time.RFC3339))
+		case remaining < tokenExpiryThreshold:
+			r.warn(newWarning(messages.EndpointTokenExpiring, name, namespace, port, expiry.Format(time.RFC3339)))
 		}
 	}
-
-	slog.Info("ServiceMonitor is compliant, no issues found", "name", name, "namespace", namespace)
 	return nil
 }
 
-func evaluatePortMatches(serviceMonitor *monitoringv1.ServiceMonitor, services *v1.ServiceList, name string, namespace string) error {
+// evaluateEndpointsTLS checks that the scheme of each endpoint is consistent
+// with the Service ports it scrapes and that the TLS assets referenced by
+// https endpoints are present, reporting each endpoint on its own.
+func evaluateEndpointsTLS(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, serviceMonitor *monitoringv1.ServiceMonitor, candidates []servicePortCandidate, name string, namespace string) {
 	for _, endpoint := range serviceMonitor.Spec.Endpoints {
-		found := false
-		for _, service := range services.Items {
-			for _, port := range service.Spec.Ports {
-				if port.Name == endpoint.Port {
-					found = true
-					break
-				}
+		port := endpointPortString(endpoint)
+		if strings.EqualFold(endpoint.Scheme, "https") {
+			if endpoint.TLSConfig == nil {
+				r.warn(newWarning(messages.EndpointHTTPSWithoutTLSConfig, port))
+				continue
 			}
-			if found {
-				break
+
+			if err := k8sutil.CheckSecretOrConfigMap(ctx, *clientSets, namespace, endpoint.TLSConfig.CA); err != nil {
+				r.fail(messages.ServiceMonitorInvalidTLS, name, namespace, "ca", port, err)
+			}
+
+			if err := k8sutil.CheckSecretOrConfigMap(ctx, *clientSets, namespace, endpoint.TLSConfig.Cert); err != nil {
+				r.fail(messages.ServiceMonitorInvalidTLS, name, namespace, "cert", port, err)
 			}
+
+			if err := k8sutil.CheckSecretKeySelector(ctx, *clientSets, namespace, endpoint.TLSConfig.KeySecret); err != nil {
+				r.fail(messages.ServiceMonitorInvalidTLS, name, namespace, "keySecret", port, err)
+			}
+			continue
 		}
 
-		if !found {
-			return fmt.Errorf("ServiceMonitor %s in namespace %s has no services with port %s", name, namespace, endpoint.Port)
+		for _, candidate := range candidates {
+			if candidate.matches(endpoint) && isHTTPSPort(candidate.port) {
+				r.fail(messages.ServiceMonitorHTTPOnHTTPS, name, namespace, candidate.portString(), candidate.service)
+			}
 		}
 	}
-	return nil
+}
+
+// isHTTPSPort reports whether the Service port advertises https through its
+// appProtocol or its name.
+func isHTTPSPort(port v1.ServicePort) bool {
+	if port.AppProtocol != nil {
+		return strings.EqualFold(*port.AppProtocol, "https")
+	}
+	return strings.Contains(strings.ToLower(port.Name), "https")
 }
@@ -17,16 +17,24 @@ package analyzers
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/prometheus-operator/poctl/pkg/builder"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 )
 
 func TestServiceMonitorAnalyzer(t *testing.T) {
@@ -170,12 +178,387 @@ func TestServiceMonitorAnalyzer(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:       "ServiceMonitorHTTPSchemeOnHTTPSPortName",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.ServiceMonitorList{})
+				mClient.PrependReactor("get", "servicemonitors", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1.ServiceMonitor{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1.ServiceMonitorSpec{
+							Endpoints: []monitoringv1.Endpoint{
+								{
+									Port: "https",
+								},
+							},
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset(&v1.ServiceList{})
+				kClient.PrependReactor("list", "services", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.ServiceList{
+						Items: []v1.Service{
+							{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:      "test",
+									Namespace: tc.namespace,
+									Labels: map[string]string{
+										"app": "test",
+									},
+								},
+								Spec: v1.ServiceSpec{
+									Ports: []v1.ServicePort{
+										{
+											Name: "https",
+										},
+									},
+								},
+							},
+						},
+					}, nil
+				})
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:       "ServiceMonitorHTTPSchemeOnHTTPSAppProtocol",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.ServiceMonitorList{})
+				mClient.PrependReactor("get", "servicemonitors", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1.ServiceMonitor{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1.ServiceMonitorSpec{
+							Endpoints: []monitoringv1.Endpoint{
+								{
+									Port:   "web",
+									Scheme: "http",
+								},
+							},
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset(&v1.ServiceList{})
+				kClient.PrependReactor("list", "services", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.ServiceList{
+						Items: []v1.Service{
+							{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:      "test",
+									Namespace: tc.namespace,
+									Labels: map[string]string{
+										"app": "test",
+									},
+								},
+								Spec: v1.ServiceSpec{
+									Ports: []v1.ServicePort{
+										{
+											Name:        "web",
+											AppProtocol: ptr.To("https"),
+										},
+									},
+								},
+							},
+						},
+					}, nil
+				})
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:       "ServiceMonitorHTTPSSchemeWithTLSConfig",
+			namespace:  "test",
+			shouldFail: false,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.ServiceMonitorList{})
+				mClient.PrependReactor("get", "servicemonitors", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1.ServiceMonitor{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1.ServiceMonitorSpec{
+							Endpoints: []monitoringv1.Endpoint{
+								{
+									Port:   "web",
+									Scheme: "https",
+									TLSConfig: &monitoringv1.TLSConfig{
+										SafeTLSConfig: monitoringv1.SafeTLSConfig{
+											CA: monitoringv1.SecretOrConfigMap{
+												Secret: &v1.SecretKeySelector{
+													LocalObjectReference: v1.LocalObjectReference{
+														Name: "tls",
+													},
+													Key: "ca.crt",
+												},
+											},
+										},
+									},
+								},
+							},
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset(&v1.ServiceList{})
+				kClient.PrependReactor("list", "services", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.ServiceList{
+						Items: []v1.Service{
+							{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:      "test",
+									Namespace: tc.namespace,
+									Labels: map[string]string{
+										"app": "test",
+									},
+								},
+								Spec: v1.ServiceSpec{
+									Ports: []v1.ServicePort{
+										{
+											Name:        "web",
+											AppProtocol: ptr.To("https"),
+										},
+									},
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient.PrependReactor("get", "secrets", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "tls",
+							Namespace: tc.namespace,
+						},
+						Data: map[string][]byte{
+							"ca.crt": []byte("data"),
+						},
+					}, nil
+				})
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:       "ServiceMonitorHTTPSSchemeMissingCAKey",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.ServiceMonitorList{})
+				mClient.PrependReactor("get", "servicemonitors", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1.ServiceMonitor{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1.ServiceMonitorSpec{
+							Endpoints: []monitoringv1.Endpoint{
+								{
+									Port:   "web",
+									Scheme: "https",
+									TLSConfig: &monitoringv1.TLSConfig{
+										SafeTLSConfig: monitoringv1.SafeTLSConfig{
+											CA: monitoringv1.SecretOrConfigMap{
+												Secret: &v1.SecretKeySelector{
+													LocalObjectReference: v1.LocalObjectReference{
+														Name: "tls",
+													},
+													Key: "ca.crt",
+												},
+											},
+										},
+									},
+								},
+							},
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset(&v1.ServiceList{})
+				kClient.PrependReactor("list", "services", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.ServiceList{
+						Items: []v1.Service{
+							{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:      "test",
+									Namespace: tc.namespace,
+									Labels: map[string]string{
+										"app": "test",
+									},
+								},
+								Spec: v1.ServiceSpec{
+									Ports: []v1.ServicePort{
+										{
+											Name:        "web",
+											AppProtocol: ptr.To("https"),
+										},
+									},
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient.PrependReactor("get", "secrets", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "tls",
+							Namespace: tc.namespace,
+						},
+						Data: map[string][]byte{
+							"tls.crt": []byte("data"),
+						},
+					}, nil
+				})
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:       "ServiceMonitorHTTPSSchemeMissingCASecret",
+			namespace:  "test",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				mClient := monitoringclient.NewSimpleClientset(&monitoringv1.ServiceMonitorList{})
+				mClient.PrependReactor("get", "servicemonitors", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &monitoringv1.ServiceMonitor{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      tc.name,
+							Namespace: tc.namespace,
+						},
+						Spec: monitoringv1.ServiceMonitorSpec{
+							Endpoints: []monitoringv1.Endpoint{
+								{
+									Port:   "web",
+									Scheme: "https",
+									TLSConfig: &monitoringv1.TLSConfig{
+										SafeTLSConfig: monitoringv1.SafeTLSConfig{
+											CA: monitoringv1.SecretOrConfigMap{
+												Secret: &v1.SecretKeySelector{
+													LocalObjectReference: v1.LocalObjectReference{
+														Name: "tls",
+													},
+													Key: "ca.crt",
+												},
+											},
+										},
+									},
+								},
+							},
+							Selector: metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+					}, nil
+				})
+
+				kClient := fake.NewSimpleClientset(&v1.ServiceList{})
+				kClient.PrependReactor("list", "services", func(_ clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &v1.ServiceList{
+						Items: []v1.Service{
+							{
+								ObjectMeta: metav1.ObjectMeta{
+									Name:      "test",
+									Namespace: tc.namespace,
+									Labels: map[string]string{
+										"app": "test",
+									},
+								},
+								Spec: v1.ServiceSpec{
+									Ports: []v1.ServicePort{
+										{
+											Name:        "web",
+											AppProtocol: ptr.To("https"),
+										},
+									},
+								},
+							},
+						},
+					}, nil
+				})
+
+				return k8sutil.ClientSets{
+					MClient: mClient,
+					KClient: kClient,
+				}
+			},
+		},
+		{
+			name:      "ServiceMonitorNamespaceSelectorMatchNames",
+			namespace: "monitoring",
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				return getCrossNamespaceClientSets(tc.name, tc.namespace, monitoringv1.NamespaceSelector{MatchNames: []string{"app"}})
+			},
+		},
+		{
+			name:      "ServiceMonitorNamespaceSelectorAny",
+			namespace: "monitoring",
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				return getCrossNamespaceClientSets(tc.name, tc.namespace, monitoringv1.NamespaceSelector{Any: true})
+			},
+		},
+		{
+			name:       "ServiceMonitorWithoutNamespaceSelector",
+			namespace:  "monitoring",
+			shouldFail: true,
+			getMockedClientSets: func(tc testCase) k8sutil.ClientSets {
+				return getCrossNamespaceClientSets(tc.name, tc.namespace, monitoringv1.NamespaceSelector{})
+			},
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			clientSets := tc.getMockedClientSets(tc)
-			err := RunServiceMonitorAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace)
+			err := analysisErr(RunServiceMonitorAnalyzer(context.Background(), &clientSets, tc.name, tc.namespace))
 			if tc.shouldFail {
 				assert.Error(t, err)
 			} else {
@@ -184,3 +567,380 @@ func TestServiceMonitorAnalyzer(t *testing.T) {
 		})
 	}
 }
+
+// getCrossNamespaceClientSets returns a ServiceMonitor in namespace selecting
+// a Service of the app namespace through the namespace selector.
+func getCrossNamespaceClientSets(name, namespace string, namespaceSelector monitoringv1.NamespaceSelector) k8sutil.ClientSets {
+	mClient := monitoringclient.NewSimpleClientset(&monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Endpoints: []monitoringv1.Endpoint{
+				{
+					Port: "http",
+				},
+			},
+			NamespaceSelector: namespaceSelector,
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": "test",
+				},
+			},
+		},
+	})
+
+	kClient := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "app",
+			Labels: map[string]string{
+				"app": "test",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{
+				{
+					Name: "http",
+					Port: 8080,
+				},
+			},
+		},
+	})
+
+	return k8sutil.ClientSets{
+		MClient: mClient,
+		KClient: kClient,
+	}
+}
+
+func TestEvaluatePortMatches(t *testing.T) {
+	service := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{"app": "app"},
+			Ports: []v1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http")},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test", Labels: map[string]string{"app": "app"}},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:  "app",
+				Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "admin", ContainerPort: 9090}},
+			}},
+		},
+	}
+
+	type testCase struct {
+		name          string
+		endpoint      monitoringv1.Endpoint
+		expectedError string
+	}
+
+	tests := []testCase{
+		{
+			name:     "PortName",
+			endpoint: monitoringv1.Endpoint{Port: "metrics"},
+		},
+		{
+			name:     "TargetPortNumberResolvedFromName",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromInt32(8080))},
+		},
+		{
+			name:     "TargetPortNumberDefaultingToPort",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromInt32(9090))},
+		},
+		{
+			name:     "TargetPortName",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromString("http"))},
+		},
+		{
+			name:     "TargetPortContainerName",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromString("admin"))},
+		},
+		{
+			name:          "UnknownTargetPort",
+			endpoint:      monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromInt32(7070))},
+			expectedError: "ServiceMonitor app in namespace test has no services with targetPort 7070, candidates: app/80 (targetPort http, container ports [http:8080]), app/metrics (targetPort 9090, container ports [admin:9090])",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := &k8sutil.ClientSets{KClient: fake.NewSimpleClientset(pod)}
+			serviceMonitor := &monitoringv1.ServiceMonitor{
+				Spec: monitoringv1.ServiceMonitorSpec{Endpoints: []monitoringv1.Endpoint{tc.endpoint}},
+			}
+
+			candidates, err := servicePortCandidates(context.Background(), clientSets, &v1.ServiceList{Items: []v1.Service{service}})
+			require.NoError(t, err)
+
+			err = evaluatePortMatches(serviceMonitor, candidates, "app", "test")
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestServicesWarnings(t *testing.T) {
+	headless := func(name string) v1.Service {
+		return v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+			Spec:       v1.ServiceSpec{ClusterIP: v1.ClusterIPNone},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		service  v1.Service
+		objects  []runtime.Object
+		expected []messages.ID
+		message  string
+	}{
+		{
+			name: "ClusterIPService",
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+				Spec:       v1.ServiceSpec{ClusterIP: "10.0.0.1"},
+			},
+		},
+		{
+			name: "ExternalNameService",
+			service: v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+				Spec:       v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "app.example.com"},
+			},
+			expected: []messages.ID{messages.ServiceExternalName},
+			message:  "Service app in namespace test is an ExternalName Service pointing to app.example.com",
+		},
+		{
+			name:     "HeadlessServiceWithoutEndpoints",
+			service:  headless("app"),
+			expected: []messages.ID{messages.HeadlessServiceNoEndpoints},
+		},
+		{
+			name:    "HeadlessServiceWithEndpointSlice",
+			service: headless("app"),
+			objects: []runtime.Object{
+				&discoveryv1.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "app-abcde",
+						Namespace: "test",
+						Labels:    map[string]string{discoveryv1.LabelServiceName: "app"},
+					},
+					Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.2"}}},
+				},
+			},
+		},
+		{
+			name:    "HeadlessServiceWithEmptyEndpointSlice",
+			service: headless("app"),
+			objects: []runtime.Object{
+				&discoveryv1.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "app-abcde",
+						Namespace: "test",
+						Labels:    map[string]string{discoveryv1.LabelServiceName: "app"},
+					},
+				},
+				&v1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+					Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "10.0.0.2"}}}},
+				},
+			},
+			expected: []messages.ID{messages.HeadlessServiceNoEndpoints},
+		},
+		{
+			name:    "HeadlessServiceWithEndpoints",
+			service: headless("app"),
+			objects: []runtime.Object{
+				&v1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+					Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "10.0.0.2"}}}},
+				},
+			},
+		},
+		{
+			name:    "HeadlessServiceWithEndpointsOfAnotherService",
+			service: headless("app"),
+			objects: []runtime.Object{
+				&v1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test"},
+					Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "10.0.0.2"}}}},
+				},
+			},
+			expected: []messages.ID{messages.HeadlessServiceNoEndpoints},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(tc.objects...))
+
+			warnings, err := servicesWarnings(context.Background(), clientSets, &v1.ServiceList{Items: []v1.Service{tc.service}})
+			assert.NoError(t, err)
+
+			var ids []messages.ID
+			for _, w := range warnings {
+				ids = append(ids, w.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+
+			if tc.message != "" {
+				assert.Equal(t, tc.message, warnings[0].Message)
+				assert.Equal(t, "ExternalName Services have no endpoints, so the ServiceMonitor scrapes nothing from app, scrape the external name app.example.com with a ScrapeConfig, or probe it with a Probe", warnings[0].Hint)
+			}
+		})
+	}
+}
+
+func TestEvaluateEndpointsTokens(t *testing.T) {
+	tokenSecret := func(expiry time.Time) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-metrics-reader-token",
+			Namespace:   "test",
+			Annotations: map[string]string{builder.TokenExpirationAnnotation: expiry.UTC().Format(time.RFC3339)},
+		}}
+	}
+
+	serviceMonitor := &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test"},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Endpoints: []monitoringv1.Endpoint{
+				{Port: "http"},
+				{
+					Port: "https",
+					Authorization: &monitoringv1.SafeAuthorization{
+						Credentials: &v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: "api-metrics-reader-token"},
+							Key:                  builder.TokenSecretKey,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		opts     []k8stesting.Option
+		expected []messages.ID
+	}{
+		{
+			name: "ValidToken",
+			opts: []k8stesting.Option{k8stesting.WithObjects(tokenSecret(time.Now().Add(90 * 24 * time.Hour)))},
+		},
+		{
+			name:     "ExpiringToken",
+			opts:     []k8stesting.Option{k8stesting.WithObjects(tokenSecret(time.Now().Add(10 * 24 * time.Hour)))},
+			expected: []messages.ID{messages.EndpointTokenExpiring},
+		},
+		{
+			name:     "ExpiredToken",
+			opts:     []k8stesting.Option{k8stesting.WithObjects(tokenSecret(time.Now().Add(-time.Hour)))},
+			expected: []messages.ID{messages.ServiceMonitorTokenExpired},
+		},
+		{
+			// The Secrets not created by poctl have no expiry.
+			name: "SecretWithoutExpiry",
+			opts: []k8stesting.Option{k8stesting.WithObjects(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "api-metrics-reader-token", Namespace: "test"}})},
+		},
+		{
+			name: "MissingSecret",
+		},
+		{
+			name:     "SecretNotReadable",
+			opts:     []k8stesting.Option{k8stesting.WithKubeReactor("get", "secrets", k8stesting.Forbidden())},
+			expected: []messages.ID{messages.SecretNotReadable},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(tc.opts...)
+
+			r := newReport("ServiceMonitor", "api", "test")
+			assert.NoError(t, evaluateEndpointsTokens(context.Background(), clientSets, r, serviceMonitor, "test"))
+
+			var ids []messages.ID
+			for _, f := range r.findings {
+				ids = append(ids, f.Check)
+				assert.Contains(t, f.Message, "api-metrics-reader-token")
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestEvaluateEndpointsTLS(t *testing.T) {
+	candidates := []servicePortCandidate{
+		{service: "api", port: v1.ServicePort{Name: "web", Port: 80, TargetPort: intstr.FromInt32(8080)}, containerPorts: []v1.ContainerPort{{ContainerPort: 8080}}},
+		{service: "api", port: v1.ServicePort{Port: 443, TargetPort: intstr.FromInt32(8443), AppProtocol: ptr.To("https")}, containerPorts: []v1.ContainerPort{{ContainerPort: 8443}}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		endpoint monitoringv1.Endpoint
+		expected []messages.ID
+	}{
+		{
+			name:     "HTTPOnHTTP",
+			endpoint: monitoringv1.Endpoint{Port: "web"},
+		},
+		{
+			name:     "HTTPOnHTTPSByTargetPort",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromInt32(8443))},
+			expected: []messages.ID{messages.ServiceMonitorHTTPOnHTTPS},
+		},
+		{
+			// The unnamed https port isn't selected by the targetPort.
+			name:     "HTTPOnHTTPByTargetPort",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromInt32(8080))},
+		},
+		{
+			// Without port nor targetPort, all the ports are scraped.
+			name:     "HTTPOnAllPorts",
+			endpoint: monitoringv1.Endpoint{},
+			expected: []messages.ID{messages.ServiceMonitorHTTPOnHTTPS},
+		},
+		{
+			name:     "HTTPSWithoutTLSConfig",
+			endpoint: monitoringv1.Endpoint{TargetPort: ptr.To(intstr.FromInt32(8443)), Scheme: "https"},
+			expected: []messages.ID{messages.EndpointHTTPSWithoutTLSConfig},
+		},
+		{
+			name: "HTTPSWithMissingCA",
+			endpoint: monitoringv1.Endpoint{
+				TargetPort: ptr.To(intstr.FromInt32(8443)),
+				Scheme:     "https",
+				TLSConfig: &monitoringv1.TLSConfig{SafeTLSConfig: monitoringv1.SafeTLSConfig{
+					CA: monitoringv1.SecretOrConfigMap{ConfigMap: &v1.ConfigMapKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: "ca"},
+						Key:                  "ca.crt",
+					}},
+				}},
+			},
+			expected: []messages.ID{messages.ServiceMonitorInvalidTLS},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			serviceMonitor := &monitoringv1.ServiceMonitor{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test"},
+				Spec:       monitoringv1.ServiceMonitorSpec{Endpoints: []monitoringv1.Endpoint{tc.endpoint}},
+			}
+
+			r := newReport("ServiceMonitor", "api", "test")
+			evaluateEndpointsTLS(context.Background(), k8stesting.NewFakeClientSets(), r, serviceMonitor, candidates, "api", "test")
+
+			var ids []messages.ID
+			for _, f := range r.findings {
+				ids = append(ids, f.Check)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// The container and the ports of the Thanos sidecar generated by the
+	// operator.
+	thanosSidecarContainer = "thanos-sidecar"
+	thanosGRPCPort         = 10901
+	thanosGRPCPortName     = "grpc"
+	thanosHTTPPort         = 10902
+	thanosHTTPPortName     = "http"
+
+	// The external labels set by the operator unless disabled.
+	defaultPrometheusExternalLabelName = "prometheus"
+	defaultReplicaExternalLabelName    = "prometheus_replica"
+)
+
+// thanosObjectStorageConfig is the object storage configuration of Thanos,
+// the configuration of the bucket depending on the provider.
+type thanosObjectStorageConfig struct {
+	Type   string         `json:"type"`
+	Config map[string]any `json:"config"`
+	Prefix string         `json:"prefix"`
+}
+
+// RunThanosAnalyzer checks the Thanos sidecar of a Prometheus: the sidecar
+// runs in the generated StatefulSets, its object storage Secret holds a
+// valid configuration, its gRPC port is reachable by Thanos Query through a
+// Service, its metrics are scraped by a ServiceMonitor, and the external
+// labels of the Prometheus identify it among the other Prometheus uploading
+// to Thanos.
+func RunThanosAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("Thanos sidecar", name, namespace)
+	return r.result(analyzeThanos(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeThanos(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	prometheus, err := clientSets.MClient.MonitoringV1().Prometheuses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "prometheus", name, namespace)
+		}
+		return fmt.Errorf("error while getting Prometheus: %w", err)
+	}
+
+	if prometheus.Spec.Thanos == nil {
+		return messages.New(messages.ThanosNotConfigured, name, namespace)
+	}
+
+	statefulSets, err := clientSets.KClient.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=prometheus,operator.prometheus.io/name=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("error while listing StatefulSets: %w", err)
+	}
+
+	if len(statefulSets.Items) == 0 {
+		r.fail(messages.ObjectNotFound, "StatefulSet", "prometheus-"+name, namespace)
+	} else if err := r.check(checkThanosSidecars(name, statefulSets.Items)); err != nil {
+		return err
+	}
+
+	warnings, err := checkThanosObjectStorage(ctx, clientSets, r, prometheus)
+	if err := r.check(err); err != nil {
+		return err
+	}
+
+	services, err := clientSets.KClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing Services: %w", err)
+	}
+
+	serviceMonitors, err := clientSets.MClient.MonitoringV1().ServiceMonitors(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing ServiceMonitors: %w", err)
+	}
+
+	warnings = append(warnings, thanosServiceWarnings(prometheus, services.Items, serviceMonitors.Items)...)
+
+	prometheuses, err := clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing Prometheus objects: %w", err)
+	}
+
+	warnings = append(warnings, thanosExternalLabelsWarnings(prometheus, prometheuses.Items)...)
+	r.warn(warnings...)
+
+	return nil
+}
+
+// checkThanosSidecars returns an error when a StatefulSet generated for the
+// Prometheus doesn't run the Thanos sidecar, such as when the operator
+// failed to reconcile the thanos field.
+func checkThanosSidecars(name string, statefulSets []appsv1.StatefulSet) error {
+	for _, sts := range statefulSets {
+		hasSidecar := slices.ContainsFunc(sts.Spec.Template.Spec.Containers, func(c corev1.Container) bool {
+			return c.Name == thanosSidecarContainer
+		})
+		if !hasSidecar {
+			return messages.New(messages.ThanosSidecarMissing, sts.Name, name)
+		}
+	}
+	return nil
+}
+
+// checkThanosObjectStorage checks that the object storage Secret of the
+// Prometheus holds a valid configuration. A configuration given as a file
+// can't be checked, and a missing configuration is only a warning since the
+// sidecar can serve the recent data to Thanos Query without uploading it.
+func checkThanosObjectStorage(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, prometheus *monitoringv1.Prometheus) ([]analyzerWarning, error) {
+	thanos := prometheus.Spec.Thanos
+	if thanos.ObjectStorageConfigFile != nil {
+		return nil, nil
+	}
+
+	if thanos.ObjectStorageConfig == nil {
+		return []analyzerWarning{newWarning(messages.ThanosObjectStorageMissing, prometheus.Name)}, nil
+	}
+
+	selector := thanos.ObjectStorageConfig
+	secret, err := getSecret(ctx, clientSets, r, selector.Name, prometheus.Namespace)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, messages.New(messages.ObjectNotFound, "Secret", selector.Name, prometheus.Namespace)
+		}
+		return nil, fmt.Errorf("error while getting Secret %s: %w", selector.Name, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	data, ok := secret.Data[selector.Key]
+	if !ok {
+		return nil, messages.New(messages.SecretKeyNotFound, selector.Key, selector.Name, prometheus.Namespace)
+	}
+
+	if err := validateThanosObjectStorageConfig(data); err != nil {
+		return nil, messages.New(messages.ThanosObjectStorageInvalid, selector.Key, selector.Name, err)
+	}
+
+	return nil, nil
+}
+
+// validateThanosObjectStorageConfig checks that the object storage
+// configuration is valid YAML naming its provider, without unknown fields
+// which Thanos rejects.
+func validateThanosObjectStorageConfig(data []byte) error {
+	var config thanosObjectStorageConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return err
+	}
+
+	if config.Type == "" {
+		return fmt.Errorf("the type of the object storage is missing")
+	}
+
+	return nil
+}
+
+// thanosServiceWarnings returns the warnings about the Services of the Thanos
+// sidecar: Thanos Query discovers the sidecars through a Service exposing
+// their gRPC port, and the sidecars are monitored through a ServiceMonitor
+// scraping their HTTP port.
+func thanosServiceWarnings(prometheus *monitoringv1.Prometheus, services []corev1.Service, serviceMonitors []*monitoringv1.ServiceMonitor) []analyzerWarning {
+	podLabels := prometheusPodLabels(prometheus)
+
+	var warnings []analyzerWarning
+	if !slices.ContainsFunc(services, func(svc corev1.Service) bool {
+		return exposesPort(svc, podLabels, thanosGRPCPort, thanosGRPCPortName)
+	}) {
+		warnings = append(warnings, newWarning(messages.ThanosSidecarNotExposed, prometheus.Name))
+	}
+
+	monitored := false
+	for _, svc := range services {
+		port, ok := thanosHTTPServicePort(svc, podLabels)
+		if !ok {
+			continue
+		}
+
+		if slices.ContainsFunc(serviceMonitors, func(sm *monitoringv1.ServiceMonitor) bool {
+			return scrapesServicePort(sm, svc, port)
+		}) {
+			monitored = true
+			break
+		}
+	}
+
+	if !monitored {
+		warnings = append(warnings, newWarning(messages.ThanosSidecarNotMonitored, prometheus.Name))
+	}
+
+	return warnings
+}
+
+// thanosHTTPServicePort returns the port of the Service routing the traffic
+// to the HTTP port of the Thanos sidecar.
+func thanosHTTPServicePort(svc corev1.Service, podLabels labels.Set) (corev1.ServicePort, bool) {
+	if len(svc.Spec.Selector) == 0 || !labels.SelectorFromSet(svc.Spec.Selector).Matches(podLabels) {
+		return corev1.ServicePort{}, false
+	}
+
+	for _, p := range svc.Spec.Ports {
+		if p.TargetPort.String() == thanosHTTPPortName || p.TargetPort.IntValue() == thanosHTTPPort {
+			return p, true
+		}
+
+		if p.TargetPort.String() == "0" && p.Port == thanosHTTPPort {
+			return p, true
+		}
+	}
+
+	return corev1.ServicePort{}, false
+}
+
+// scrapesServicePort reports whether an endpoint of the ServiceMonitor
+// scrapes the port of the Service.
+func scrapesServicePort(sm *monitoringv1.ServiceMonitor, svc corev1.Service, port corev1.ServicePort) bool {
+	namespaces := sm.Spec.NamespaceSelector
+	switch {
+	case namespaces.Any:
+	case len(namespaces.MatchNames) > 0:
+		if !slices.Contains(namespaces.MatchNames, svc.Namespace) {
+			return false
+		}
+	case sm.Namespace != svc.Namespace:
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&sm.Spec.Selector)
+	if err != nil || !selector.Matches(labels.Set(svc.Labels)) {
+		return false
+	}
+
+	candidate := servicePortCandidate{
+		service:        svc.Name,
+		port:           port,
+		containerPorts: []corev1.ContainerPort{{Name: thanosHTTPPortName, ContainerPort: thanosHTTPPort}},
+	}
+	return slices.ContainsFunc(sm.Spec.Endpoints, candidate.matches)
+}
+
+// thanosExternalLabelsWarnings returns the warnings about the external
+// labels of the Prometheus: Thanos tells the series and the blocks of each
+// Prometheus apart with them, and the replicas of a Prometheus with the
+// replica label.
+func thanosExternalLabelsWarnings(prometheus *monitoringv1.Prometheus, prometheuses []*monitoringv1.Prometheus) []analyzerWarning {
+	var warnings []analyzerWarning
+
+	externalLabels := thanosExternalLabels(prometheus)
+	for _, other := range prometheuses {
+		if other.Spec.Thanos == nil || (other.Namespace == prometheus.Namespace && other.Name == prometheus.Name) {
+			continue
+		}
+
+		if labels.Equals(externalLabels, thanosExternalLabels(other)) {
+			warnings = append(warnings, newWarning(messages.ThanosDuplicateLabels, prometheus.Name, "{"+externalLabels.String()+"}", other.Namespace+"/"+other.Name))
+		}
+	}
+
+	if replicas := ptr.Deref(prometheus.Spec.Replicas, 1); replicas > 1 && ptr.Deref(prometheus.Spec.ReplicaExternalLabelName, defaultReplicaExternalLabelName) == "" {
+		warnings = append(warnings, newWarning(messages.ThanosReplicaLabelDisabled, prometheus.Name, replicas))
+	}
+
+	return warnings
+}
+
+// thanosExternalLabels returns the external labels shared by the replicas of
+// the Prometheus, as set by the operator: the external labels of the spec and
+// the label holding the namespace and the name of the Prometheus.
+func thanosExternalLabels(prometheus *monitoringv1.Prometheus) labels.Set {
+	externalLabels := labels.Set{}
+	maps.Copy(externalLabels, prometheus.Spec.ExternalLabels)
+
+	if name := ptr.Deref(prometheus.Spec.PrometheusExternalLabelName, defaultPrometheusExternalLabelName); name != "" {
+		externalLabels[name] = prometheus.Namespace + "/" + prometheus.Name
+	}
+
+	return externalLabels
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func newThanosPrometheus(namespace, name string) *monitoringv1.Prometheus {
+	return &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: monitoringv1.PrometheusSpec{
+			Thanos: &monitoringv1.ThanosSpec{
+				ObjectStorageConfig: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "thanos-objstore"},
+					Key:                  "objstore.yaml",
+				},
+			},
+		},
+	}
+}
+
+func TestValidateThanosObjectStorageConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		config     string
+		shouldFail bool
+	}{
+		{
+			name:   "Valid",
+			config: "type: S3\nconfig:\n  bucket: metrics\n  endpoint: s3.example.com\n",
+		},
+		{
+			name:       "InvalidYAML",
+			config:     "type: S3\nconfig: [bucket",
+			shouldFail: true,
+		},
+		{
+			name:       "UnknownField",
+			config:     "type: S3\nbucket: metrics\n",
+			shouldFail: true,
+		},
+		{
+			name:       "NoType",
+			config:     "config:\n  bucket: metrics\n",
+			shouldFail: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateThanosObjectStorageConfig([]byte(tc.config))
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestThanosServiceWarnings(t *testing.T) {
+	prometheus := newThanosPrometheus("monitoring", "k8s")
+
+	sidecarService := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus-k8s-thanos-sidecar",
+			Namespace: "monitoring",
+			Labels:    map[string]string{"app.kubernetes.io/component": "thanos-sidecar"},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app.kubernetes.io/name": "prometheus", "prometheus": "k8s"},
+			Ports: []corev1.ServicePort{
+				{Name: "grpc", Port: 10901, TargetPort: intstr.FromString("grpc")},
+				{Name: "http", Port: 10902, TargetPort: intstr.FromString("http")},
+			},
+		},
+	}
+
+	serviceMonitor := &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "thanos-sidecar", Namespace: "monitoring"},
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/component": "thanos-sidecar"}},
+			Endpoints: []monitoringv1.Endpoint{{Port: "http"}},
+		},
+	}
+
+	otherNamespace := serviceMonitor.DeepCopy()
+	otherNamespace.Namespace = "default"
+
+	grpcOnly := sidecarService
+	grpcOnly.Spec.Ports = sidecarService.Spec.Ports[:1]
+
+	for _, tc := range []struct {
+		name            string
+		services        []corev1.Service
+		serviceMonitors []*monitoringv1.ServiceMonitor
+		expected        []messages.ID
+	}{
+		{
+			name:            "Wired",
+			services:        []corev1.Service{sidecarService},
+			serviceMonitors: []*monitoringv1.ServiceMonitor{serviceMonitor},
+		},
+		{
+			name:     "NoService",
+			expected: []messages.ID{messages.ThanosSidecarNotExposed, messages.ThanosSidecarNotMonitored},
+		},
+		{
+			name:     "NoServiceMonitor",
+			services: []corev1.Service{sidecarService},
+			expected: []messages.ID{messages.ThanosSidecarNotMonitored},
+		},
+		{
+			name:            "ServiceMonitorInOtherNamespace",
+			services:        []corev1.Service{sidecarService},
+			serviceMonitors: []*monitoringv1.ServiceMonitor{otherNamespace},
+			expected:        []messages.ID{messages.ThanosSidecarNotMonitored},
+		},
+		{
+			name:            "NoHTTPPort",
+			services:        []corev1.Service{grpcOnly},
+			serviceMonitors: []*monitoringv1.ServiceMonitor{serviceMonitor},
+			expected:        []messages.ID{messages.ThanosSidecarNotMonitored},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []messages.ID
+			for _, w := range thanosServiceWarnings(prometheus, tc.services, tc.serviceMonitors) {
+				ids = append(ids, w.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestThanosExternalLabelsWarnings(t *testing.T) {
+	withLabels := func(p *monitoringv1.Prometheus, externalLabels map[string]string, prometheusLabel *string) *monitoringv1.Prometheus {
+		p.Spec.ExternalLabels = externalLabels
+		p.Spec.PrometheusExternalLabelName = prometheusLabel
+		return p
+	}
+
+	for _, tc := range []struct {
+		name       string
+		prometheus *monitoringv1.Prometheus
+		others     []*monitoringv1.Prometheus
+		expected   []messages.ID
+	}{
+		{
+			name:       "DefaultLabels",
+			prometheus: newThanosPrometheus("monitoring", "k8s"),
+			others:     []*monitoringv1.Prometheus{newThanosPrometheus("team-a", "k8s")},
+		},
+		{
+			name:       "DistinctClusterLabels",
+			prometheus: withLabels(newThanosPrometheus("monitoring", "k8s"), map[string]string{"cluster": "eu"}, ptr.To("")),
+			others: []*monitoringv1.Prometheus{
+				withLabels(newThanosPrometheus("monitoring", "other"), map[string]string{"cluster": "us"}, ptr.To("")),
+			},
+		},
+		{
+			name:       "SameClusterLabels",
+			prometheus: withLabels(newThanosPrometheus("monitoring", "k8s"), map[string]string{"cluster": "eu"}, ptr.To("")),
+			others: []*monitoringv1.Prometheus{
+				withLabels(newThanosPrometheus("monitoring", "other"), map[string]string{"cluster": "eu"}, ptr.To("")),
+			},
+			expected: []messages.ID{messages.ThanosDuplicateLabels},
+		},
+		{
+			name:       "SameLabelsWithoutThanos",
+			prometheus: withLabels(newThanosPrometheus("monitoring", "k8s"), nil, ptr.To("")),
+			others: []*monitoringv1.Prometheus{
+				{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "monitoring"}},
+			},
+		},
+		{
+			name: "ReplicaLabelDisabled",
+			prometheus: func() *monitoringv1.Prometheus {
+				p := newThanosPrometheus("monitoring", "k8s")
+				p.Spec.Replicas = ptr.To(int32(2))
+				p.Spec.ReplicaExternalLabelName = ptr.To("")
+				return p
+			}(),
+			expected: []messages.ID{messages.ThanosReplicaLabelDisabled},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prometheuses := append([]*monitoringv1.Prometheus{tc.prometheus}, tc.others...)
+
+			var ids []messages.ID
+			for _, w := range thanosExternalLabelsWarnings(tc.prometheus, prometheuses) {
+				ids = append(ids, w.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestRunThanosAnalyzer(t *testing.T) {
+	statefulSet := func(containers ...string) *appsv1.StatefulSet {
+		sts := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "prometheus-k8s",
+				Namespace: "monitoring",
+				Labels:    map[string]string{"app.kubernetes.io/name": "prometheus", "operator.prometheus.io/name": "k8s"},
+			},
+		}
+		for _, c := range containers {
+			sts.Spec.Template.Spec.Containers = append(sts.Spec.Template.Spec.Containers, corev1.Container{Name: c})
+		}
+		return sts
+	}
+
+	secret := func(data string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "thanos-objstore", Namespace: "monitoring"},
+			Data:       map[string][]byte{"objstore.yaml": []byte(data)},
+		}
+	}
+
+	withoutThanos := newThanosPrometheus("monitoring", "k8s")
+	withoutThanos.Spec.Thanos = nil
+
+	for _, tc := range []struct {
+		name       string
+		objects    []runtime.Object
+		expectedID messages.ID
+	}{
+		{
+			name:    "Compliant",
+			objects: []runtime.Object{newThanosPrometheus("monitoring", "k8s"), statefulSet("prometheus", "thanos-sidecar"), secret("type: GCS\nconfig:\n  bucket: metrics\n")},
+		},
+		{
+			name:       "PrometheusNotFound",
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name:       "NotConfigured",
+			objects:    []runtime.Object{withoutThanos, statefulSet("prometheus")},
+			expectedID: messages.ThanosNotConfigured,
+		},
+		{
+			name:       "StatefulSetNotFound",
+			objects:    []runtime.Object{newThanosPrometheus("monitoring", "k8s")},
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name:       "SidecarMissing",
+			objects:    []runtime.Object{newThanosPrometheus("monitoring", "k8s"), statefulSet("prometheus")},
+			expectedID: messages.ThanosSidecarMissing,
+		},
+		{
+			name:       "SecretNotFound",
+			objects:    []runtime.Object{newThanosPrometheus("monitoring", "k8s"), statefulSet("prometheus", "thanos-sidecar")},
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name: "SecretKeyNotFound",
+			objects: []runtime.Object{newThanosPrometheus("monitoring", "k8s"), statefulSet("prometheus", "thanos-sidecar"), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "thanos-objstore", Namespace: "monitoring"},
+			}},
+			expectedID: messages.SecretKeyNotFound,
+		},
+		{
+			name:       "InvalidObjectStorage",
+			objects:    []runtime.Object{newThanosPrometheus("monitoring", "k8s"), statefulSet("prometheus", "thanos-sidecar"), secret("bucket: metrics\n")},
+			expectedID: messages.ThanosObjectStorageInvalid,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(tc.objects...))
+
+			err := analysisErr(RunThanosAnalyzer(context.Background(), clientSets, "k8s", "monitoring"))
+			if tc.expectedID == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			id, _ := messages.IDOf(err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// alertmanagerURLPrefixes are the prefixes of the alertmanagersUrl resolved
+// by the DNS service discovery of Thanos.
+var alertmanagerURLPrefixes = []string{"dns+", "dnssrv+", "dnssrvnoa+"}
+
+func RunThanosRulerAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("ThanosRuler", name, namespace)
+	return r.result(analyzeThanosRuler(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeThanosRuler(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	thanosRuler, err := clientSets.MClient.MonitoringV1().ThanosRulers(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return messages.New(messages.ObjectNotFound, "ThanosRuler", name, namespace)
+		}
+		return fmt.Errorf("error while getting ThanosRuler: %w", err)
+	}
+
+	serviceAccount := cmp.Or(thanosRuler.Spec.ServiceAccountName, "default")
+	if _, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).Get(ctx, serviceAccount, metav1.GetOptions{}); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error while getting ServiceAccount %s: %w", serviceAccount, err)
+		}
+		r.fail(messages.ThanosRulerServiceAccount, serviceAccount, name, namespace)
+	}
+
+	if err := k8sutil.CheckResourceNamespaceSelectors(ctx, *clientSets, thanosRuler.Spec.RuleNamespaceSelector); err != nil {
+		r.fail(messages.SelectorNotProperlyDefined, "ruleNamespaceSelector", err)
+	}
+
+	if thanosRuler.Spec.RuleSelector == nil {
+		r.fail(messages.SelectorNotDefined, k8sutil.PrometheusRule)
+	} else {
+		rules, err := selectedRulesCount(ctx, clientSets, thanosRuler)
+		if err != nil {
+			return err
+		}
+
+		if rules == 0 {
+			r.fail(messages.ThanosRulerNoRules, name, namespace)
+		}
+	}
+
+	if thanosRuler.Spec.QueryConfig == nil && len(thanosRuler.Spec.QueryEndpoints) == 0 {
+		r.fail(messages.ThanosRulerNoQuery, name, namespace)
+	}
+
+	for _, ref := range []struct {
+		field    string
+		selector *corev1.SecretKeySelector
+	}{
+		{"queryConfig", thanosRuler.Spec.QueryConfig},
+		{"alertmanagersConfig", thanosRuler.Spec.AlertManagersConfig},
+		{"alertRelabelConfigs", thanosRuler.Spec.AlertRelabelConfigs},
+		{"objectStorageConfig", thanosRuler.Spec.ObjectStorageConfig},
+		{"tracingConfig", thanosRuler.Spec.TracingConfig},
+	} {
+		if err := k8sutil.CheckSecretKeySelector(ctx, *clientSets, namespace, ref.selector); err != nil {
+			r.fail(messages.ThanosRulerInvalidReference, ref.field, name, namespace, err)
+		}
+	}
+
+	// The alertmanagersUrl are ignored when alertmanagersConfig is set.
+	if thanosRuler.Spec.AlertManagersConfig == nil {
+		if len(thanosRuler.Spec.AlertManagersURL) == 0 {
+			r.warn(newWarning(messages.ThanosRulerNoAlertmanager))
+		}
+
+		for _, u := range thanosRuler.Spec.AlertManagersURL {
+			if err := validateAlertmanagerURL(u); err != nil {
+				r.fail(messages.ThanosRulerInvalidAMURL, u, name, namespace, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// selectedRulesCount returns the number of PrometheusRules selected by the
+// ruleSelector and the ruleNamespaceSelector of the ThanosRuler.
+func selectedRulesCount(ctx context.Context, clientSets *k8sutil.ClientSets, thanosRuler *monitoringv1.ThanosRuler) (int, error) {
+	namespaces, err := namespaceLabels(ctx, clientSets)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = k8sutil.EachListItem(ctx, "PrometheusRules", clientSets.MClient.MonitoringV1().PrometheusRules(metav1.NamespaceAll).List, metav1.ListOptions{}, func(rule *monitoringv1.PrometheusRule) error {
+		if selectsObject(thanosRuler.Namespace, thanosRuler.Spec.RuleSelector, thanosRuler.Spec.RuleNamespaceSelector, rule.ObjectMeta, namespaces) {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// validateAlertmanagerURL checks that an alertmanagersUrl is an HTTP URL with
+// a host, optionally prefixed to be resolved by the DNS service discovery.
+func validateAlertmanagerURL(s string) error {
+	for _, prefix := range alertmanagerURLPrefixes {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			s = rest
+			break
+		}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("the scheme must be http or https")
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("the host is missing")
+	}
+
+	return nil
+}
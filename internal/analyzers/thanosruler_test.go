@@ -0,0 +1,210 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidateAlertmanagerURL(t *testing.T) {
+	for _, tc := range []struct {
+		url string
+		ok  bool
+	}{
+		{url: "http://alertmanager-operated.monitoring.svc:9093", ok: true},
+		{url: "https://alertmanager.example.org/api", ok: true},
+		{url: "dnssrv+http://_web._tcp.alertmanager-operated.monitoring.svc", ok: true},
+		{url: "dns+http://alertmanager:9093", ok: true},
+		{url: "alertmanager:9093"},
+		{url: "ftp://alertmanager:9093"},
+		{url: "http://"},
+		{url: "http://alertmanager:port"},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			err := validateAlertmanagerURL(tc.url)
+			if tc.ok {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestThanosRulerAnalyzer(t *testing.T) {
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "thanos-ruler", Namespace: "monitoring"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "thanos-ruler-query", Namespace: "monitoring"},
+			Data:       map[string][]byte{"query.yaml": []byte("- static_configs: [thanos-query:10902]")},
+		},
+		&monitoringv1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: "alerts", Namespace: "team-a", Labels: map[string]string{"role": "alerts"}}},
+	}
+
+	newThanosRuler := func(mutate func(*monitoringv1.ThanosRulerSpec)) *monitoringv1.ThanosRuler {
+		tr := &monitoringv1.ThanosRuler{
+			ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+			Spec: monitoringv1.ThanosRulerSpec{
+				ServiceAccountName:    "thanos-ruler",
+				RuleSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"role": "alerts"}},
+				RuleNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				QueryEndpoints:        []string{"dnssrv+_http._tcp.thanos-query.monitoring.svc"},
+				AlertManagersURL:      []string{"dnssrv+http://_web._tcp.alertmanager-operated.monitoring.svc"},
+			},
+		}
+		if mutate != nil {
+			mutate(&tr.Spec)
+		}
+		return tr
+	}
+	querySecret := func(name string) *corev1.SecretKeySelector {
+		return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "query.yaml"}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		thanosRuler *monitoringv1.ThanosRuler
+		expectedID  messages.ID
+	}{
+		{
+			name:       "NotFound",
+			expectedID: messages.ObjectNotFound,
+		},
+		{
+			name: "ServiceAccountNotFound",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.ServiceAccountName = ""
+			}),
+			expectedID: messages.ThanosRulerServiceAccount,
+		},
+		{
+			name: "NoNamespaceMatches",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.RuleNamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}
+			}),
+			expectedID: messages.SelectorNotProperlyDefined,
+		},
+		{
+			name: "NoRuleSelector",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.RuleSelector = nil
+			}),
+			expectedID: messages.SelectorNotDefined,
+		},
+		{
+			// The ruleNamespaceSelector isn't set, only the namespace of the
+			// ThanosRuler is selected.
+			name: "NoRules",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.RuleNamespaceSelector = nil
+			}),
+			expectedID: messages.ThanosRulerNoRules,
+		},
+		{
+			name: "NoQuery",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.QueryEndpoints = nil
+			}),
+			expectedID: messages.ThanosRulerNoQuery,
+		},
+		{
+			name: "QueryConfigNotFound",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.QueryEndpoints = nil
+				spec.QueryConfig = querySecret("missing")
+			}),
+			expectedID: messages.ThanosRulerInvalidReference,
+		},
+		{
+			name: "InvalidAlertmanagerURL",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.AlertManagersURL = []string{"alertmanager-operated:9093"}
+			}),
+			expectedID: messages.ThanosRulerInvalidAMURL,
+		},
+		{
+			name:        "Valid",
+			thanosRuler: newThanosRuler(nil),
+		},
+		{
+			name: "QueryConfig",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.QueryEndpoints = nil
+				spec.QueryConfig = querySecret("thanos-ruler-query")
+			}),
+		},
+		{
+			// Without Alertmanager, the alerts are dropped but the
+			// ThanosRuler still records the rules.
+			name: "NoAlertmanager",
+			thanosRuler: newThanosRuler(func(spec *monitoringv1.ThanosRulerSpec) {
+				spec.AlertManagersURL = nil
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := objects
+			if tc.thanosRuler != nil {
+				objs = append(append([]runtime.Object{}, objects...), tc.thanosRuler)
+			}
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(objs...))
+
+			err := analysisErr(RunThanosRulerAnalyzer(context.Background(), clientSets, "main", "monitoring"))
+			if tc.expectedID == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			id, ok := messages.IDOf(err)
+			require.True(t, ok, "unexpected error %v", err)
+			assert.Equal(t, tc.expectedID, id)
+		})
+	}
+}
+
+func TestThanosRulerAnalyzerReportsEveryError(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "thanos-ruler", Namespace: "monitoring"}},
+		&monitoringv1.ThanosRuler{
+			ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+			Spec: monitoringv1.ThanosRulerSpec{
+				ServiceAccountName: "thanos-ruler",
+				AlertManagersURL:   []string{"alertmanager-operated:9093"},
+			},
+		},
+	))
+
+	findings, err := RunThanosRulerAnalyzer(context.Background(), clientSets, "main", "monitoring")
+	require.NoError(t, err)
+
+	var checks []messages.ID
+	for _, f := range findings {
+		require.Equal(t, SeverityError, f.Severity)
+		checks = append(checks, f.Check)
+	}
+	assert.Equal(t, []messages.ID{messages.SelectorNotDefined, messages.ThanosRulerNoQuery, messages.ThanosRulerInvalidAMURL}, checks)
+}
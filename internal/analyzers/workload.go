@@ -0,0 +1,168 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RunWorkloadAnalyzer checks that the containers of the Deployment, the
+// StatefulSet or the DaemonSet with the name define liveness and readiness
+// probes, and that their listen addresses serve the IP families of the
+// Services selecting them. The kinds are looked up in this order.
+func RunWorkloadAnalyzer(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Finding, error) {
+	r := newReport("workload", name, namespace)
+	return r.result(analyzeWorkload(ctx, clientSets, r, name, namespace))
+}
+
+func analyzeWorkload(ctx context.Context, clientSets *k8sutil.ClientSets, r *report, name, namespace string) error {
+	kind, template, err := getWorkload(ctx, clientSets, name, namespace)
+	if err != nil {
+		return err
+	}
+
+	if template == nil {
+		return messages.New(messages.ObjectNotFound, "workload", name, namespace)
+	}
+	r.kind = kind
+
+	services, err := clientSets.KClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing Services: %w", err)
+	}
+
+	warnings := probeWarnings(kind, name, template.Spec)
+	warnings = append(warnings, listenAddressWarnings(kind, name, template, services.Items)...)
+	r.warn(warnings...)
+	return nil
+}
+
+// getWorkload returns the kind and the Pod template of the Deployment, the
+// StatefulSet or the DaemonSet with the name, a nil template when none
+// exists.
+func getWorkload(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) (string, *corev1.PodTemplateSpec, error) {
+	deployment, err := clientSets.KClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return "Deployment", &deployment.Spec.Template, nil
+	case !errors.IsNotFound(err):
+		return "", nil, fmt.Errorf("error while getting Deployment %s: %w", name, err)
+	}
+
+	statefulSet, err := clientSets.KClient.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return "StatefulSet", &statefulSet.Spec.Template, nil
+	case !errors.IsNotFound(err):
+		return "", nil, fmt.Errorf("error while getting StatefulSet %s: %w", name, err)
+	}
+
+	daemonSet, err := clientSets.KClient.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return "DaemonSet", &daemonSet.Spec.Template, nil
+	case !errors.IsNotFound(err):
+		return "", nil, fmt.Errorf("error while getting DaemonSet %s: %w", name, err)
+	}
+
+	return "", nil, nil
+}
+
+// probeWarnings returns the containers of the Pod spec without liveness or
+// readiness probe. Init containers run to completion and aren't probed.
+func probeWarnings(kind, name string, spec corev1.PodSpec) []analyzerWarning {
+	var warnings []analyzerWarning
+	for _, container := range spec.Containers {
+		if container.LivenessProbe == nil {
+			warnings = append(warnings, newWarning(messages.LivenessProbeMissing, kind, name, container.Name))
+		}
+
+		if container.ReadinessProbe == nil {
+			warnings = append(warnings, newWarning(messages.ReadinessProbeMissing, kind, name, container.Name))
+		}
+	}
+	return warnings
+}
+
+// listenAddressWarnings returns the listen addresses of the containers which
+// aren't valid host:port addresses, and the IPv4 addresses which refuse the
+// IPv6 connections of the dual-stack Services selecting the Pods.
+func listenAddressWarnings(kind, name string, template *corev1.PodTemplateSpec, services []corev1.Service) []analyzerWarning {
+	var ipv6Service string
+	for _, svc := range services {
+		if len(svc.Spec.Selector) == 0 || !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(template.Labels)) {
+			continue
+		}
+
+		if slices.Contains(svc.Spec.IPFamilies, corev1.IPv6Protocol) {
+			ipv6Service = svc.Name
+			break
+		}
+	}
+
+	var warnings []analyzerWarning
+	for _, container := range template.Spec.Containers {
+		for _, address := range listenAddresses(container) {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				warnings = append(warnings, newWarning(messages.ListenAddressInvalid, kind, name, container.Name, address))
+				continue
+			}
+
+			if ip := net.ParseIP(host); ipv6Service != "" && ip != nil && ip.To4() != nil {
+				warnings = append(warnings, newWarning(messages.ListenAddressIPv4Only, kind, name, container.Name, address, ipv6Service))
+			}
+		}
+	}
+	return warnings
+}
+
+// listenAddresses returns the values of the --*listen-address flags of the
+// container, such as --web.listen-address, given either as --flag=value or as
+// --flag value.
+func listenAddresses(container corev1.Container) []string {
+	var addresses []string
+	args := append(slices.Clone(container.Command), container.Args...)
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		flag, value, found := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasSuffix(flag, "listen-address") {
+			continue
+		}
+
+		if !found {
+			if i+1 >= len(args) {
+				continue
+			}
+			value = args[i+1]
+		}
+		addresses = append(addresses, value)
+	}
+	return addresses
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/messages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProbeWarnings(t *testing.T) {
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name       string
+		containers []corev1.Container
+		expected   []messages.ID
+	}{
+		{
+			name: "Probed",
+			containers: []corev1.Container{
+				{Name: "app", LivenessProbe: probe, ReadinessProbe: probe},
+			},
+		},
+		{
+			name: "NoLivenessProbe",
+			containers: []corev1.Container{
+				{Name: "app", ReadinessProbe: probe},
+			},
+			expected: []messages.ID{messages.LivenessProbeMissing},
+		},
+		{
+			name: "NoProbes",
+			containers: []corev1.Container{
+				{Name: "app", LivenessProbe: probe, ReadinessProbe: probe},
+				{Name: "sidecar"},
+			},
+			expected: []messages.ID{messages.LivenessProbeMissing, messages.ReadinessProbeMissing},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []messages.ID
+			for _, w := range probeWarnings("Deployment", "app", corev1.PodSpec{Containers: tc.containers}) {
+				ids = append(ids, w.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestRunWorkloadAnalyzer(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-state-metrics", Namespace: "monitoring"},
+		Spec: appsv1.StatefulSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "kube-state-metrics"}},
+				},
+			},
+		},
+	}
+
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(statefulSet))
+	findings, err := RunWorkloadAnalyzer(context.Background(), clientSets, "kube-state-metrics", "monitoring")
+	require.NoError(t, err)
+	require.NoError(t, Err(findings))
+	assert.Equal(t, "StatefulSet", findings[len(findings)-1].Kind)
+
+	err = analysisErr(RunWorkloadAnalyzer(context.Background(), clientSets, "missing", "monitoring"))
+	id, _ := messages.IDOf(err)
+	assert.Equal(t, messages.ObjectNotFound, id)
+
+	clientSets = k8stesting.NewFakeClientSets(k8stesting.WithKubeReactor("get", "deployments", k8stesting.InternalError()))
+	assert.Error(t, analysisErr(RunWorkloadAnalyzer(context.Background(), clientSets, "kube-state-metrics", "monitoring")))
+}
+
+func TestListenAddressWarnings(t *testing.T) {
+	dualStack := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-exporter"},
+		Spec: corev1.ServiceSpec{
+			Selector:   map[string]string{"app": "node-exporter"},
+			IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+		},
+	}
+	singleStack := dualStack
+	singleStack.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv4Protocol}
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		service  corev1.Service
+		expected []messages.ID
+	}{
+		{
+			name:    "AllFamilies",
+			args:    []string{"--web.listen-address=:9100"},
+			service: dualStack,
+		},
+		{
+			name:    "IPv6Brackets",
+			args:    []string{"--web.listen-address", "[::]:9100"},
+			service: dualStack,
+		},
+		{
+			name:     "IPv4OnDualStack",
+			args:     []string{"--web.listen-address=0.0.0.0:9100"},
+			service:  dualStack,
+			expected: []messages.ID{messages.ListenAddressIPv4Only},
+		},
+		{
+			name:    "IPv4OnSingleStack",
+			args:    []string{"--web.listen-address=0.0.0.0:9100"},
+			service: singleStack,
+		},
+		{
+			name:     "IPv6WithoutBrackets",
+			args:     []string{"--listen-address=::1:9100"},
+			service:  singleStack,
+			expected: []messages.ID{messages.ListenAddressInvalid},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			template := &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "node-exporter"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "node-exporter", Args: tc.args}},
+				},
+			}
+
+			var ids []messages.ID
+			for _, w := range listenAddressWarnings("DaemonSet", "node-exporter", template, []corev1.Service{tc.service}) {
+				ids = append(ids, w.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package annotations writes the findings of poctl as GitHub Actions
+// workflow commands, which the runs display inline on the files of the pull
+// requests.
+package annotations
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Level is the severity of an annotation.
+type Level string
+
+// The levels of the annotations, displayed as errors, warnings and notices.
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNotice  Level = "notice"
+)
+
+// Annotation is a finding, attached to a line of a file when File is set.
+type Annotation struct {
+	Level Level
+	// File is the path of the file relative to the root of the repository.
+	File string
+	// Line is the line of the finding in the file, starting at 1, 0 when
+	// unknown.
+	Line int
+	// Col is the column of the finding in the line, starting at 1, 0 when
+	// unknown.
+	Col     int
+	Title   string
+	Message string
+}
+
+// String returns the workflow command of the annotation.
+func (a Annotation) String() string {
+	var props []string
+	if a.File != "" {
+		props = append(props, "file="+escapeProperty(a.File))
+		if a.Line > 0 {
+			props = append(props, "line="+strconv.Itoa(a.Line))
+			if a.Col > 0 {
+				props = append(props, "col="+strconv.Itoa(a.Col))
+			}
+		}
+	}
+	if a.Title != "" {
+		props = append(props, "title="+escapeProperty(a.Title))
+	}
+
+	var b strings.Builder
+	b.WriteString("::")
+	b.WriteString(string(a.Level))
+	if len(props) > 0 {
+		b.WriteString(" ")
+		b.WriteString(strings.Join(props, ","))
+	}
+	b.WriteString("::")
+	b.WriteString(escapeData(a.Message))
+	return b.String()
+}
+
+// Write writes the annotations, one workflow command per line.
+func Write(w io.Writer, annotations []Annotation) error {
+	for _, a := range annotations {
+		if _, err := fmt.Fprintln(w, a.String()); err != nil {
+			return fmt.Errorf("error while writing annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+var (
+	dataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// escapeData escapes the message of a workflow command, which would
+// otherwise end at the first line break.
+func escapeData(s string) string {
+	return dataEscaper.Replace(s)
+}
+
+// escapeProperty escapes a property of a workflow command, in which colons
+// and commas are separators.
+func escapeProperty(s string) string {
+	return propertyEscaper.Replace(s)
+}
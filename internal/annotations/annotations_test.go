@@ -0,0 +1,52 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, []Annotation{
+		{
+			Level:   LevelError,
+			File:    "monitoring/targets.yaml",
+			Line:    4,
+			Col:     3,
+			Title:   "SM003 ServiceMonitor payments/ledger",
+			Message: "no services with port web",
+		},
+		{
+			Level:   LevelWarning,
+			File:    "monitoring/targets.yaml",
+			Title:   "prod-eu: Prometheus monitoring/k8s",
+			Message: "PR101: the admin API is exposed\n100% of the replicas",
+		},
+		{
+			Level:   LevelNotice,
+			Message: "analysis interrupted",
+		},
+	}))
+
+	assert.Equal(t, `::error file=monitoring/targets.yaml,line=4,col=3,title=SM003 ServiceMonitor payments/ledger::no services with port web
+::warning file=monitoring/targets.yaml,title=prod-eu%3A Prometheus monitoring/k8s::PR101: the admin API is exposed%0A100%25 of the replicas
+::notice::analysis interrupted
+`, buf.String())
+}
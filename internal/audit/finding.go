@@ -0,0 +1,59 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Severity ranks the findings of an audit, the most severe first.
+type Severity int
+
+const (
+	High Severity = iota
+	Medium
+	Low
+)
+
+func (s Severity) String() string {
+	switch s {
+	case High:
+		return "HIGH"
+	case Medium:
+		return "MEDIUM"
+	default:
+		return "LOW"
+	}
+}
+
+// Finding is an issue found on a resource during an audit.
+type Finding struct {
+	Severity Severity
+	Kind     string
+	Name     string
+	Message  string
+}
+
+// sortFindings orders the findings by severity, then by resource.
+func sortFindings(findings []Finding) {
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(a.Severity, b.Severity),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+}
@@ -0,0 +1,424 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Component is the kind of workload running with a ServiceAccount.
+type Component string
+
+const (
+	ComponentOperator   Component = "operator"
+	ComponentPrometheus Component = "prometheus"
+)
+
+// operatorRules are the permissions the Prometheus Operator needs, the
+// workload resources are added depending on its feature gates.
+var operatorRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{"monitoring.coreos.com"},
+		Resources: []string{
+			"alertmanagers",
+			"alertmanagerconfigs",
+			"prometheuses",
+			"prometheusagents",
+			"thanosrulers",
+			"scrapeconfigs",
+			"servicemonitors",
+			"podmonitors",
+			"probes",
+			"prometheusrules",
+		},
+		Verbs: []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{"monitoring.coreos.com"},
+		Resources: []string{
+			"alertmanagers/finalizers",
+			"prometheuses/finalizers",
+			"prometheusagents/finalizers",
+			"thanosrulers/finalizers",
+		},
+		Verbs: []string{"update"},
+	},
+	{
+		APIGroups: []string{"monitoring.coreos.com"},
+		Resources: []string{
+			"alertmanagers/status",
+			"prometheuses/status",
+			"prometheusagents/status",
+			"thanosrulers/status",
+		},
+		Verbs: []string{"get", "update", "patch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"configmaps", "secrets"},
+		Verbs:     []string{"get", "list", "watch", "create", "update", "delete"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"list", "delete"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"services", "services/finalizers", "endpoints"},
+		Verbs:     []string{"get", "create", "update", "delete"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"nodes"},
+		Verbs:     []string{"list", "watch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"namespaces"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"events"},
+		Verbs:     []string{"patch", "create"},
+	},
+	{
+		APIGroups: []string{"networking.k8s.io"},
+		Resources: []string{"ingresses"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{"storage.k8s.io"},
+		Resources: []string{"storageclasses"},
+		Verbs:     []string{"get"},
+	},
+}
+
+// prometheusRules are the permissions Prometheus needs to discover targets,
+// scrape the kubelet and read its rule files.
+var prometheusRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"nodes", "nodes/metrics", "services", "endpoints", "pods"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"configmaps"},
+		Verbs:     []string{"get"},
+	},
+	{
+		APIGroups: []string{"networking.k8s.io"},
+		Resources: []string{"ingresses"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		NonResourceURLs: []string{"/metrics"},
+		Verbs:           []string{"get"},
+	},
+}
+
+// GrantedRule is a rule of a ClusterRole bound to the audited ServiceAccount.
+type GrantedRule struct {
+	ClusterRole string
+	Rule        rbacv1.PolicyRule
+}
+
+// RBACReport compares the permissions granted to a ServiceAccount with the
+// permissions its component needs.
+type RBACReport struct {
+	ServiceAccount string
+	Namespace      string
+	Component      Component
+	ClusterRoles   []string
+	// Minimized are the needed permissions which are granted.
+	Minimized []rbacv1.PolicyRule
+	// Missing are the needed permissions which aren't granted.
+	Missing []rbacv1.PolicyRule
+	// Excess are the granted rules with permissions which aren't needed.
+	Excess []GrantedRule
+}
+
+// RBAC audits the ClusterRoles bound to a ServiceAccount used by the
+// Prometheus Operator or by Prometheus.
+func RBAC(ctx context.Context, clientSets *k8sutil.ClientSets, serviceAccount, namespace string) (*RBACReport, error) {
+	component, required, err := serviceAccountComponent(ctx, clientSets, serviceAccount, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	crbs, err := clientSets.KClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing ClusterRoleBindings: %w", err)
+	}
+
+	report := &RBACReport{
+		ServiceAccount: serviceAccount,
+		Namespace:      namespace,
+		Component:      component,
+	}
+
+	var granted []rbacv1.PolicyRule
+	for _, crb := range crbs.Items {
+		if crb.RoleRef.Kind != "ClusterRole" || !bindsServiceAccount(crb.Subjects, serviceAccount, namespace) {
+			continue
+		}
+		if slices.Contains(report.ClusterRoles, crb.RoleRef.Name) {
+			continue
+		}
+
+		cr, err := clientSets.KClient.RbacV1().ClusterRoles().Get(ctx, crb.RoleRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error while getting ClusterRole %s: %w", crb.RoleRef.Name, err)
+		}
+
+		report.ClusterRoles = append(report.ClusterRoles, cr.Name)
+		granted = append(granted, cr.Rules...)
+		for _, rule := range cr.Rules {
+			if !isCovered(required, rule) {
+				report.Excess = append(report.Excess, GrantedRule{ClusterRole: cr.Name, Rule: rule})
+			}
+		}
+	}
+
+	if len(report.ClusterRoles) == 0 {
+		return nil, fmt.Errorf("ServiceAccount %s in namespace %s is not bound to any ClusterRole", serviceAccount, namespace)
+	}
+
+	report.Minimized, report.Missing = minimizeRules(required, granted)
+	return report, nil
+}
+
+// ApplyRBAC replaces the rules of the ClusterRole bound to the ServiceAccount
+// with the minimized rules of the report.
+func ApplyRBAC(ctx context.Context, clientSets *k8sutil.ClientSets, report *RBACReport) error {
+	if len(report.ClusterRoles) != 1 {
+		return fmt.Errorf("ServiceAccount %s is bound to %d ClusterRoles, the minimized rules can only be applied to a single ClusterRole", report.ServiceAccount, len(report.ClusterRoles))
+	}
+
+	cr, err := clientSets.KClient.RbacV1().ClusterRoles().Get(ctx, report.ClusterRoles[0], metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error while getting ClusterRole %s: %w", report.ClusterRoles[0], err)
+	}
+
+	cr.Rules = report.Minimized
+	if _, err := clientSets.KClient.RbacV1().ClusterRoles().Update(ctx, cr, metav1.UpdateOptions{FieldManager: k8sutil.ApplyOption.FieldManager}); err != nil {
+		return fmt.Errorf("error while updating ClusterRole %s: %w", cr.Name, err)
+	}
+
+	return nil
+}
+
+// serviceAccountComponent returns the component running with the
+// ServiceAccount along with the rules it needs.
+func serviceAccountComponent(ctx context.Context, clientSets *k8sutil.ClientSets, serviceAccount, namespace string) (Component, []rbacv1.PolicyRule, error) {
+	deployments, err := clientSets.KClient.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, fmt.Errorf("error while listing deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		if d.Spec.Template.Spec.ServiceAccountName == serviceAccount && isOperatorDeployment(d) {
+			return ComponentOperator, requiredOperatorRules(d), nil
+		}
+	}
+
+	prometheuses, err := clientSets.MClient.MonitoringV1().Prometheuses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, fmt.Errorf("error while listing Prometheuses: %w", err)
+	}
+	for _, p := range prometheuses.Items {
+		if p.Spec.ServiceAccountName == serviceAccount {
+			return ComponentPrometheus, prometheusRules, nil
+		}
+	}
+
+	agents, err := clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, fmt.Errorf("error while listing PrometheusAgents: %w", err)
+	}
+	for _, a := range agents.Items {
+		if a.Spec.ServiceAccountName == serviceAccount {
+			return ComponentPrometheus, prometheusRules, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("ServiceAccount %s in namespace %s isn't used by the Prometheus Operator nor by Prometheus", serviceAccount, namespace)
+}
+
+func isOperatorDeployment(d appsv1.Deployment) bool {
+	for _, c := range d.Spec.Template.Spec.Containers {
+		if strings.Contains(c.Image, "prometheus-operator/prometheus-operator") {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredOperatorRules returns the operator rules, including DaemonSets when
+// PrometheusAgents can run in DaemonSet mode.
+func requiredOperatorRules(d appsv1.Deployment) []rbacv1.PolicyRule {
+	workloads := []string{"statefulsets"}
+	for _, c := range d.Spec.Template.Spec.Containers {
+		for _, arg := range c.Args {
+			if strings.HasPrefix(arg, "--feature-gates=") && strings.Contains(arg, builder.PrometheusAgentDaemonSetFeatureGate+"=true") {
+				workloads = append(workloads, "daemonsets")
+			}
+		}
+	}
+
+	return append(slices.Clone(operatorRules), rbacv1.PolicyRule{
+		APIGroups: []string{"apps"},
+		Resources: workloads,
+		Verbs:     []string{"get", "list", "watch", "create", "update", "delete"},
+	})
+}
+
+func bindsServiceAccount(subjects []rbacv1.Subject, name, namespace string) bool {
+	for _, s := range subjects {
+		if s.Kind == rbacv1.ServiceAccountKind && s.Name == name && s.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// minimizeRules returns the required rules restricted to the granted
+// permissions, and the required permissions which aren't granted.
+func minimizeRules(required, granted []rbacv1.PolicyRule) ([]rbacv1.PolicyRule, []rbacv1.PolicyRule) {
+	var minimized, missing []rbacv1.PolicyRule
+
+	for _, r := range required {
+		if len(r.NonResourceURLs) > 0 {
+			for _, url := range r.NonResourceURLs {
+				covered, uncovered := splitVerbs(r.Verbs, func(verb string) bool {
+					return grantsURL(granted, url, verb)
+				})
+				if len(covered) > 0 {
+					minimized = append(minimized, rbacv1.PolicyRule{NonResourceURLs: []string{url}, Verbs: covered})
+				}
+				if len(uncovered) > 0 {
+					missing = append(missing, rbacv1.PolicyRule{NonResourceURLs: []string{url}, Verbs: uncovered})
+				}
+			}
+			continue
+		}
+
+		for _, group := range r.APIGroups {
+			var coveredRules, missingRules []rbacv1.PolicyRule
+			for _, resource := range r.Resources {
+				covered, uncovered := splitVerbs(r.Verbs, func(verb string) bool {
+					return grantsResource(granted, group, resource, verb)
+				})
+				coveredRules = addResource(coveredRules, group, resource, covered)
+				missingRules = addResource(missingRules, group, resource, uncovered)
+			}
+			minimized = append(minimized, coveredRules...)
+			missing = append(missing, missingRules...)
+		}
+	}
+
+	return minimized, missing
+}
+
+// splitVerbs partitions the verbs between the granted and the missing ones.
+func splitVerbs(verbs []string, isGranted func(string) bool) ([]string, []string) {
+	var covered, uncovered []string
+	for _, verb := range verbs {
+		if isGranted(verb) {
+			covered = append(covered, verb)
+		} else {
+			uncovered = append(uncovered, verb)
+		}
+	}
+	return covered, uncovered
+}
+
+// addResource adds the resource to the rule with the same verbs, or to a new
+// rule.
+func addResource(rules []rbacv1.PolicyRule, group, resource string, verbs []string) []rbacv1.PolicyRule {
+	if len(verbs) == 0 {
+		return rules
+	}
+
+	for i := range rules {
+		if slices.Equal(rules[i].Verbs, verbs) {
+			rules[i].Resources = append(rules[i].Resources, resource)
+			return rules
+		}
+	}
+
+	return append(rules, rbacv1.PolicyRule{
+		APIGroups: []string{group},
+		Resources: []string{resource},
+		Verbs:     verbs,
+	})
+}
+
+// isCovered reports whether every permission of the rule is part of the
+// required rules. Wildcards are never covered.
+func isCovered(required []rbacv1.PolicyRule, rule rbacv1.PolicyRule) bool {
+	for _, verb := range rule.Verbs {
+		for _, url := range rule.NonResourceURLs {
+			if !grantsURL(required, url, verb) {
+				return false
+			}
+		}
+
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				if !grantsResource(required, group, resource, verb) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func grantsResource(rules []rbacv1.PolicyRule, group, resource, verb string) bool {
+	for _, r := range rules {
+		// Rules restricted to some objects don't grant the whole resource.
+		if len(r.ResourceNames) > 0 {
+			continue
+		}
+		if matches(r.APIGroups, group) && matches(r.Resources, resource) && matches(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func grantsURL(rules []rbacv1.PolicyRule, url, verb string) bool {
+	for _, r := range rules {
+		if matches(r.NonResourceURLs, url) && matches(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	return slices.Contains(values, "*") || slices.Contains(values, value)
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMinimizeRules(t *testing.T) {
+	required := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"pods", "services", "configmaps"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		{
+			NonResourceURLs: []string{"/metrics"},
+			Verbs:           []string{"get"},
+		},
+	}
+
+	granted := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{"*"},
+			Resources: []string{"pods", "services"},
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"get"},
+		},
+	}
+
+	minimized, missing := minimizeRules(required, granted)
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"get", "list", "watch"}},
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+	}, minimized)
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"list", "watch"}},
+		{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+	}, missing)
+}
+
+func TestIsCovered(t *testing.T) {
+	type testCase struct {
+		name     string
+		rule     rbacv1.PolicyRule
+		expected bool
+	}
+
+	tests := []testCase{
+		{
+			name:     "RequiredPermissions",
+			rule:     rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+			expected: true,
+		},
+		{
+			name:     "WildcardVerbs",
+			rule:     rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"*"}},
+			expected: false,
+		},
+		{
+			name:     "UnneededResource",
+			rule:     rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+			expected: false,
+		},
+		{
+			name:     "RequiredURL",
+			rule:     rbacv1.PolicyRule{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isCovered(prometheusRules, tc.rule))
+		})
+	}
+}
+
+func TestRBAC(t *testing.T) {
+	type testCase struct {
+		name              string
+		serviceAccount    string
+		objects           []runtime.Object
+		prometheuses      []runtime.Object
+		expectedComponent Component
+		expectedExcess    int
+		shouldFail        bool
+	}
+
+	wildcardRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "wildcard"},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+		},
+	}
+
+	binding := func(serviceAccount string) *rbacv1.ClusterRoleBinding {
+		return &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: serviceAccount},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "wildcard"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: "monitoring"},
+			},
+		}
+	}
+
+	tests := []testCase{
+		{
+			name:           "Operator",
+			serviceAccount: "prometheus-operator",
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "prometheus-operator", Namespace: "monitoring"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: "prometheus-operator",
+								Containers: []corev1.Container{
+									{Name: "prometheus-operator", Image: "quay.io/prometheus-operator/prometheus-operator:v0.75.1"},
+								},
+							},
+						},
+					},
+				},
+				wildcardRole,
+				binding("prometheus-operator"),
+			},
+			expectedComponent: ComponentOperator,
+			expectedExcess:    2,
+		},
+		{
+			name:           "Prometheus",
+			serviceAccount: "prometheus",
+			objects:        []runtime.Object{wildcardRole, binding("prometheus")},
+			prometheuses: []runtime.Object{
+				&monitoringv1.Prometheus{
+					ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: "monitoring"},
+					Spec: monitoringv1.PrometheusSpec{
+						CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+							ServiceAccountName: "prometheus",
+						},
+					},
+				},
+			},
+			expectedComponent: ComponentPrometheus,
+			expectedExcess:    1,
+		},
+		{
+			name:           "UnknownServiceAccount",
+			serviceAccount: "default",
+			objects:        []runtime.Object{wildcardRole, binding("default")},
+			shouldFail:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := &k8sutil.ClientSets{
+				KClient: fake.NewSimpleClientset(tc.objects...),
+				MClient: monitoringclient.NewSimpleClientset(tc.prometheuses...),
+			}
+
+			report, err := RBAC(context.Background(), clientSets, tc.serviceAccount, "monitoring")
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedComponent, report.Component)
+			assert.Equal(t, []string{"wildcard"}, report.ClusterRoles)
+			assert.Len(t, report.Excess, tc.expectedExcess)
+			assert.Empty(t, report.Missing)
+		})
+	}
+}
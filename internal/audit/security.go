@@ -0,0 +1,242 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultSecretMode is the mode of the files of a Secret volume when
+// defaultMode isn't set.
+const defaultSecretMode = 0o644
+
+// Security audits the workloads of a namespace and the roles bound to its
+// ServiceAccounts, and returns the findings sorted by severity.
+func Security(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) ([]Finding, error) {
+	var findings []Finding
+
+	deployments, err := clientSets.KClient.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		findings = append(findings, podSpecFindings("Deployment", d.Name, d.Spec.Template.Spec)...)
+	}
+
+	statefulSets, err := clientSets.KClient.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		findings = append(findings, podSpecFindings("StatefulSet", s.Name, s.Spec.Template.Spec)...)
+	}
+
+	daemonSets, err := clientSets.KClient.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing daemonsets: %w", err)
+	}
+	for _, d := range daemonSets.Items {
+		findings = append(findings, podSpecFindings("DaemonSet", d.Name, d.Spec.Template.Spec)...)
+	}
+
+	roleFindings, err := boundRoleFindings(ctx, clientSets, namespace)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, roleFindings...)
+
+	sortFindings(findings)
+	return findings, nil
+}
+
+// podSpecFindings returns the privileged containers, the containers without
+// seccomp profile or read-only root filesystem, and the Secret volumes
+// readable by any user of the pod template.
+func podSpecFindings(kind, name string, spec corev1.PodSpec) []Finding {
+	var findings []Finding
+
+	podSeccomp := spec.SecurityContext != nil && spec.SecurityContext.SeccompProfile != nil
+
+	for _, c := range slices.Concat(spec.InitContainers, spec.Containers) {
+		sc := c.SecurityContext
+
+		if sc != nil && sc.Privileged != nil && *sc.Privileged {
+			findings = append(findings, Finding{
+				Severity: High,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("container %s is privileged", c.Name),
+			})
+		}
+
+		if !podSeccomp && (sc == nil || sc.SeccompProfile == nil) {
+			findings = append(findings, Finding{
+				Severity: Medium,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("container %s has no seccomp profile", c.Name),
+			})
+		}
+
+		if sc == nil || sc.ReadOnlyRootFilesystem == nil || !*sc.ReadOnlyRootFilesystem {
+			findings = append(findings, Finding{
+				Severity: Medium,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("container %s doesn't have a read-only root filesystem", c.Name),
+			})
+		}
+	}
+
+	for _, v := range spec.Volumes {
+		if v.Secret == nil {
+			continue
+		}
+
+		mode := int32(defaultSecretMode)
+		if v.Secret.DefaultMode != nil {
+			mode = *v.Secret.DefaultMode
+		}
+		for _, item := range v.Secret.Items {
+			if item.Mode != nil {
+				mode |= *item.Mode
+			}
+		}
+
+		if mode&0o004 != 0 {
+			findings = append(findings, Finding{
+				Severity: Low,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("Secret %s is mounted world-readable (mode %#o) by volume %s", v.Secret.SecretName, mode, v.Name),
+			})
+		}
+	}
+
+	return findings
+}
+
+// boundRoleFindings returns the wildcard rules of the Roles and ClusterRoles
+// bound to the ServiceAccounts of the namespace.
+func boundRoleFindings(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) ([]Finding, error) {
+	var findings []Finding
+
+	crbs, err := clientSets.KClient.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing ClusterRoleBindings: %w", err)
+	}
+
+	rbs, err := clientSets.KClient.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing RoleBindings: %w", err)
+	}
+
+	var refs []rbacv1.RoleRef
+	for _, crb := range crbs.Items {
+		if bindsNamespace(crb.Subjects, namespace) {
+			refs = append(refs, crb.RoleRef)
+		}
+	}
+	for _, rb := range rbs.Items {
+		if bindsNamespace(rb.Subjects, namespace) {
+			refs = append(refs, rb.RoleRef)
+		}
+	}
+
+	seen := map[rbacv1.RoleRef]bool{}
+	for _, ref := range refs {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		var rules []rbacv1.PolicyRule
+		switch ref.Kind {
+		case "ClusterRole":
+			cr, err := clientSets.KClient.RbacV1().ClusterRoles().Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("error while getting ClusterRole %s: %w", ref.Name, err)
+			}
+			rules = cr.Rules
+		case "Role":
+			role, err := clientSets.KClient.RbacV1().Roles(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("error while getting Role %s: %w", ref.Name, err)
+			}
+			rules = role.Rules
+		default:
+			continue
+		}
+
+		findings = append(findings, wildcardRuleFindings(ref.Kind, ref.Name, rules)...)
+	}
+
+	return findings, nil
+}
+
+// bindsNamespace reports whether the subjects include a ServiceAccount of the
+// namespace.
+func bindsNamespace(subjects []rbacv1.Subject, namespace string) bool {
+	for _, s := range subjects {
+		if s.Kind == rbacv1.ServiceAccountKind && s.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardRuleFindings returns the rules granting every verb or every
+// resource. Wildcards granting both are the most severe.
+func wildcardRuleFindings(kind, name string, rules []rbacv1.PolicyRule) []Finding {
+	var findings []Finding
+
+	for _, rule := range rules {
+		verbs := slices.Contains(rule.Verbs, rbacv1.VerbAll)
+		resources := slices.Contains(rule.Resources, rbacv1.ResourceAll)
+
+		switch {
+		case verbs && resources:
+			findings = append(findings, Finding{
+				Severity: High,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("rule grants every verb on every resource of API groups %v", rule.APIGroups),
+			})
+		case verbs:
+			findings = append(findings, Finding{
+				Severity: Medium,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("rule grants every verb on %v", rule.Resources),
+			})
+		case resources:
+			findings = append(findings, Finding{
+				Severity: Medium,
+				Kind:     kind,
+				Name:     name,
+				Message:  fmt.Sprintf("rule grants %v on every resource of API groups %v", rule.Verbs, rule.APIGroups),
+			})
+		}
+	}
+
+	return findings
+}
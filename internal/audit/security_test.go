@@ -0,0 +1,186 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestPodSpecFindings(t *testing.T) {
+	type testCase struct {
+		name     string
+		spec     corev1.PodSpec
+		expected []Finding
+	}
+
+	hardened := &corev1.SecurityContext{
+		ReadOnlyRootFilesystem: ptr.To(true),
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+
+	tests := []testCase{
+		{
+			name: "HardenedContainer",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", SecurityContext: hardened}},
+			},
+		},
+		{
+			name: "PodSeccompProfile",
+			spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{
+					SeccompProfile: &corev1.SeccompProfile{
+						Type: corev1.SeccompProfileTypeRuntimeDefault,
+					},
+				},
+				Containers: []corev1.Container{
+					{
+						Name: "app",
+						SecurityContext: &corev1.SecurityContext{
+							ReadOnlyRootFilesystem: ptr.To(true),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "PrivilegedContainer",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "app",
+						SecurityContext: &corev1.SecurityContext{
+							Privileged:             ptr.To(true),
+							ReadOnlyRootFilesystem: ptr.To(true),
+							SeccompProfile:         hardened.SeccompProfile,
+						},
+					},
+				},
+			},
+			expected: []Finding{
+				{Severity: High, Kind: "Deployment", Name: "test", Message: "container app is privileged"},
+			},
+		},
+		{
+			name: "DefaultSecurityContext",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+			expected: []Finding{
+				{Severity: Medium, Kind: "Deployment", Name: "test", Message: "container app has no seccomp profile"},
+				{Severity: Medium, Kind: "Deployment", Name: "test", Message: "container app doesn't have a read-only root filesystem"},
+			},
+		},
+		{
+			name: "WorldReadableSecret",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", SecurityContext: hardened}},
+				Volumes: []corev1.Volume{
+					{
+						Name: "tls",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{SecretName: "tls"},
+						},
+					},
+					{
+						Name: "config",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{SecretName: "config", DefaultMode: ptr.To(int32(0o440))},
+						},
+					},
+				},
+			},
+			expected: []Finding{
+				{Severity: Low, Kind: "Deployment", Name: "test", Message: "Secret tls is mounted world-readable (mode 0644) by volume tls"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, podSpecFindings("Deployment", "test", tc.spec))
+		})
+	}
+}
+
+func TestWildcardRuleFindings(t *testing.T) {
+	findings := wildcardRuleFindings("ClusterRole", "test", []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"statefulsets"}, Verbs: []string{"*"}},
+		{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get"}},
+	})
+
+	assert.Equal(t, []Finding{
+		{Severity: High, Kind: "ClusterRole", Name: "test", Message: "rule grants every verb on every resource of API groups [*]"},
+		{Severity: Medium, Kind: "ClusterRole", Name: "test", Message: "rule grants every verb on [statefulsets]"},
+		{Severity: Medium, Kind: "ClusterRole", Name: "test", Message: "rule grants [get] on every resource of API groups []"},
+	}, findings)
+}
+
+func TestSecurity(t *testing.T) {
+	kClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "monitoring"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "operator"}},
+					},
+				},
+			},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "operator"},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "operator"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "operator"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "operator", Namespace: "monitoring"},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "missing"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: "other", Namespace: "other"},
+			},
+		},
+	)
+
+	findings, err := Security(context.Background(), &k8sutil.ClientSets{KClient: kClient}, "monitoring")
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{Severity: High, Kind: "ClusterRole", Name: "operator", Message: "rule grants every verb on every resource of API groups [*]"},
+		{Severity: Medium, Kind: "Deployment", Name: "operator", Message: "container operator has no seccomp profile"},
+		{Severity: Medium, Kind: "Deployment", Name: "operator", Message: "container operator doesn't have a read-only root filesystem"},
+	}, findings)
+}
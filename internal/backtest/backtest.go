@@ -0,0 +1,270 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// maxPoints is the maximum number of points per series of a range query
+// accepted by Prometheus.
+const maxPoints = 11000
+
+// durationRegexp matches the durations of Prometheus, such as 7d or 1h30m.
+var durationRegexp = regexp.MustCompile(`^(?:(\d+)y)?(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?(?:(\d+)ms)?$`)
+
+// durationUnits are the units of the groups of durationRegexp.
+var durationUnits = []time.Duration{
+	365 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+	24 * time.Hour,
+	time.Hour,
+	time.Minute,
+	time.Second,
+	time.Millisecond,
+}
+
+// ParseDuration parses a duration in the format of Prometheus, which accepts
+// days, weeks and years unlike the time package.
+func ParseDuration(s string) (time.Duration, error) {
+	matches := durationRegexp.FindStringSubmatch(s)
+	if s == "" || matches == nil {
+		return 0, fmt.Errorf("invalid duration %q, expected a Prometheus duration such as 7d or 1h30m", s)
+	}
+
+	var d time.Duration
+	for i, unit := range durationUnits {
+		if matches[i+1] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// Rule is an alerting rule to backtest.
+type Rule struct {
+	// Source is the PrometheusRule defining the rule, as namespace/name,
+	// empty for plain rule files.
+	Source string
+	Group  string
+	Alert  string
+	Expr   string
+	For    time.Duration
+}
+
+type ruleDocument struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        metav1.ObjectMeta `json:"metadata"`
+	Spec            struct {
+		Groups []monitoringv1.RuleGroup `json:"groups"`
+	} `json:"spec"`
+	Groups []monitoringv1.RuleGroup `json:"groups"`
+}
+
+// LoadRules returns the alerting rules of the PrometheusRule manifests and of
+// the plain rule files of a YAML or JSON stream. Recording rules and
+// documents of any other kind are ignored.
+func LoadRules(data []byte) ([]Rule, error) {
+	var rules []Rule
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var doc ruleDocument
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("error while decoding rules: %w", err)
+		}
+
+		var (
+			source string
+			groups []monitoringv1.RuleGroup
+		)
+		switch {
+		case doc.Kind == monitoringv1.PrometheusRuleKind && doc.APIVersion == monitoringv1.SchemeGroupVersion.String():
+			source = doc.Metadata.Namespace + "/" + doc.Metadata.Name
+			groups = doc.Spec.Groups
+		case doc.Kind == "" && doc.APIVersion == "":
+			groups = doc.Groups
+		default:
+			continue
+		}
+
+		for _, group := range groups {
+			for _, r := range group.Rules {
+				if r.Alert == "" {
+					continue
+				}
+
+				rule := Rule{
+					Source: source,
+					Group:  group.Name,
+					Alert:  r.Alert,
+					Expr:   r.Expr.String(),
+				}
+
+				if r.For != nil {
+					d, err := ParseDuration(string(*r.For))
+					if err != nil {
+						return nil, fmt.Errorf("alert %s: %w", r.Alert, err)
+					}
+					rule.For = d
+				}
+
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// Period is an interval during which an alert would have been firing for a
+// series.
+type Period struct {
+	Labels map[string]string
+	Start  time.Time
+	End    time.Time
+}
+
+// FormatLabels formats the labels of a period.
+func (p Period) FormatLabels() string {
+	return labelsString(p.Labels)
+}
+
+// Result is the outcome of the backtesting of an alerting rule.
+type Result struct {
+	Rule    Rule
+	Periods []Period
+}
+
+// Series returns the number of series for which the alert would have fired.
+func (r Result) Series() int {
+	series := map[string]struct{}{}
+	for _, p := range r.Periods {
+		series[labelsString(p.Labels)] = struct{}{}
+	}
+	return len(series)
+}
+
+// FiringTime returns the total time the alert would have been firing, summed
+// over its series.
+func (r Result) FiringTime() time.Duration {
+	var d time.Duration
+	for _, p := range r.Periods {
+		d += p.End.Sub(p.Start)
+	}
+	return d
+}
+
+// Step returns the resolution of the range queries, the requested step being
+// raised when the range would exceed the number of points accepted by
+// Prometheus.
+func Step(queryRange, step time.Duration) time.Duration {
+	if minStep := (queryRange + maxPoints - 1) / maxPoints; step < minStep {
+		return (minStep + time.Second - 1).Truncate(time.Second)
+	}
+	return step
+}
+
+// Run evaluates the expression of each rule between start and end with the
+// query_range API, and returns the periods during which each alert would have
+// fired: the expression must return a series for at least the for duration
+// of the rule, consecutive points being step apart.
+func Run(ctx context.Context, client promapi.Client, rules []Rule, start, end time.Time, step time.Duration) ([]Result, error) {
+	results := make([]Result, 0, len(rules))
+
+	for _, rule := range rules {
+		series, err := promapi.QueryRange(ctx, client, rule.Expr, start, end, step)
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %w", rule.Alert, err)
+		}
+
+		result := Result{Rule: rule}
+		for _, s := range series {
+			for _, p := range FiringPeriods(s.Timestamps, step, rule.For) {
+				p.Labels = s.Labels
+				result.Periods = append(result.Periods, p)
+			}
+		}
+
+		slices.SortStableFunc(result.Periods, func(a, b Period) int {
+			return a.Start.Compare(b.Start)
+		})
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// FiringPeriods returns the periods during which an alert would have fired
+// for a series returned at the given timestamps. The series is active over
+// runs of timestamps at most step apart, and the alert fires once a run lasts
+// for the for duration.
+func FiringPeriods(timestamps []time.Time, step, forDuration time.Duration) []Period {
+	var periods []Period
+
+	closeRun := func(start, end time.Time) {
+		if end.Sub(start) >= forDuration {
+			periods = append(periods, Period{Start: start.Add(forDuration), End: end})
+		}
+	}
+
+	for i := 0; i < len(timestamps); {
+		j := i
+		for j+1 < len(timestamps) && timestamps[j+1].Sub(timestamps[j]) <= step {
+			j++
+		}
+		closeRun(timestamps[i], timestamps[j])
+		i = j + 1
+	}
+
+	return periods
+}
+
+// labelsString formats a label set as {name="value", ...}, the metric name
+// being omitted.
+func labelsString(labels map[string]string) string {
+	var pairs []string
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		if name == "__name__" {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"7d":    7 * 24 * time.Hour,
+		"1w":    7 * 24 * time.Hour,
+		"1h30m": 90 * time.Minute,
+		"30s":   30 * time.Second,
+		"500ms": 500 * time.Millisecond,
+	} {
+		d, err := ParseDuration(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, d, s)
+	}
+
+	for _, s := range []string{"", "7", "1.5h", "30m1h"} {
+		_, err := ParseDuration(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules([]byte(`apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: app
+  namespace: team-a
+spec:
+  groups:
+  - name: app
+    rules:
+    - record: job:errors:rate5m
+      expr: sum by (job) (rate(errors_total[5m]))
+    - alert: HighErrorRate
+      expr: job:errors:rate5m > 1
+      for: 10m
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+---
+groups:
+- name: plain
+  rules:
+  - alert: Down
+    expr: up == 0
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Rule{
+		{Source: "team-a/app", Group: "app", Alert: "HighErrorRate", Expr: "job:errors:rate5m > 1", For: 10 * time.Minute},
+		{Group: "plain", Alert: "Down", Expr: "up == 0"},
+	}, rules)
+}
+
+func TestFiringPeriods(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes ...int) []time.Time {
+		var timestamps []time.Time
+		for _, m := range minutes {
+			timestamps = append(timestamps, start.Add(time.Duration(m)*time.Minute))
+		}
+		return timestamps
+	}
+
+	// Without for duration, each run fires.
+	assert.Equal(t, []Period{
+		{Start: at(0)[0], End: at(2)[0]},
+		{Start: at(5)[0], End: at(5)[0]},
+	}, FiringPeriods(at(0, 1, 2, 5), time.Minute, 0))
+
+	// The run starting at 5 doesn't last for 2 minutes.
+	assert.Equal(t, []Period{
+		{Start: at(2)[0], End: at(3)[0]},
+	}, FiringPeriods(at(0, 1, 2, 3, 5, 6), time.Minute, 2*time.Minute))
+}
+
+func TestStep(t *testing.T) {
+	assert.Equal(t, time.Minute, Step(7*24*time.Hour, time.Minute))
+	assert.Equal(t, 55*time.Second, Step(7*24*time.Hour, 15*time.Second))
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "up == 0", r.URL.Query().Get("query"))
+		assert.Equal(t, "60", r.URL.Query().Get("step"))
+
+		var values [][2]any
+		for _, m := range []int{10, 11, 12, 13, 30} {
+			values = append(values, [2]any{float64(start.Add(time.Duration(m) * time.Minute).Unix()), "0"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "matrix",
+				"result": []map[string]any{
+					{
+						"metric": map[string]string{"job": "app", "instance": "10.0.0.1:8080"},
+						"values": values,
+					},
+				},
+			},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rule := Rule{Alert: "Down", Expr: "up == 0", For: 2 * time.Minute}
+	results, err := Run(context.Background(), promapi.NewURLClient(server.URL), []Rule{rule}, start, start.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	assert.Len(t, results[0].Periods, 1)
+	assert.Equal(t, start.Add(12*time.Minute), results[0].Periods[0].Start)
+	assert.Equal(t, start.Add(13*time.Minute), results[0].Periods[0].End)
+	assert.Equal(t, 1, results[0].Series())
+	assert.Equal(t, time.Minute, results[0].FiringTime())
+	assert.Equal(t, `{instance="10.0.0.1:8080", job="app"}`, results[0].Periods[0].FormatLabels())
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package catalog lists the alerts and the scrape targets defined by the
+// Prometheus Operator objects of a namespace, and renders them as a
+// human-readable document.
+package catalog
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Catalog is the list of the alerts and scrape targets of a namespace.
+type Catalog struct {
+	Namespace string
+	Alerts    []Alert
+	Targets   []Target
+}
+
+// Alert is an alerting rule of a PrometheusRule.
+type Alert struct {
+	Name string
+	// Rule is the name of the PrometheusRule defining the alert and Group
+	// the name of its rule group.
+	Rule     string
+	Group    string
+	Severity string
+	Expr     string
+	For      string
+	// Summary is the summary annotation of the alert, or its description
+	// when it has no summary.
+	Summary string
+	Runbook string
+}
+
+// Target is a scrape endpoint of a ServiceMonitor, PodMonitor, Probe or
+// ScrapeConfig.
+type Target struct {
+	Kind string
+	Name string
+	// Endpoint is the port and path of the ServiceMonitors and
+	// PodMonitors, the URLs or the prober of the Probes and the service
+	// discovery of the ScrapeConfigs.
+	Endpoint string
+	// Selector tells which objects and namespaces are discovered, empty for
+	// static targets.
+	Selector string
+	Interval string
+}
+
+// Build lists the alerts of the PrometheusRules and the targets of the
+// ServiceMonitors, PodMonitors, Probes and ScrapeConfigs of the namespace.
+// The alerts are sorted by rule and the targets by kind and name.
+func Build(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) (*Catalog, error) {
+	c := &Catalog{Namespace: namespace}
+
+	rules, err := clientSets.MClient.MonitoringV1().PrometheusRules(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing PrometheusRules: %w", err)
+	}
+
+	for _, pr := range rules.Items {
+		c.Alerts = append(c.Alerts, alerts(pr)...)
+	}
+
+	serviceMonitors, err := clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing ServiceMonitors: %w", err)
+	}
+
+	for _, sm := range serviceMonitors.Items {
+		c.Targets = append(c.Targets, serviceMonitorTargets(sm)...)
+	}
+
+	podMonitors, err := clientSets.MClient.MonitoringV1().PodMonitors(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing PodMonitors: %w", err)
+	}
+
+	for _, pm := range podMonitors.Items {
+		c.Targets = append(c.Targets, podMonitorTargets(pm)...)
+	}
+
+	probes, err := clientSets.MClient.MonitoringV1().Probes(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Probes: %w", err)
+	}
+
+	for _, probe := range probes.Items {
+		c.Targets = append(c.Targets, probeTarget(probe))
+	}
+
+	scrapeConfigs, err := clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing ScrapeConfigs: %w", err)
+	}
+
+	for _, sc := range scrapeConfigs.Items {
+		c.Targets = append(c.Targets, scrapeConfigTarget(sc))
+	}
+
+	slices.SortStableFunc(c.Alerts, func(a, b Alert) int {
+		return cmp.Compare(a.Rule, b.Rule)
+	})
+	slices.SortStableFunc(c.Targets, func(a, b Target) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name))
+	})
+
+	return c, nil
+}
+
+// alerts returns the alerting rules of a PrometheusRule, in the order of
+// its groups. The recording rules are skipped.
+func alerts(pr *monitoringv1.PrometheusRule) []Alert {
+	var alerts []Alert
+	for _, group := range pr.Spec.Groups {
+		for _, rule := range group.Rules {
+			if rule.Alert == "" {
+				continue
+			}
+
+			alert := Alert{
+				Name:     rule.Alert,
+				Rule:     pr.Name,
+				Group:    group.Name,
+				Severity: rule.Labels["severity"],
+				Expr:     strings.TrimSpace(rule.Expr.String()),
+				Summary:  cmp.Or(rule.Annotations["summary"], rule.Annotations["description"]),
+				Runbook:  cmp.Or(rule.Annotations["runbook_url"], rule.Annotations["runbook"]),
+			}
+			if rule.For != nil {
+				alert.For = string(*rule.For)
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+func serviceMonitorTargets(sm *monitoringv1.ServiceMonitor) []Target {
+	selector := fmt.Sprintf("services %s%s", metav1.FormatLabelSelector(&sm.Spec.Selector), namespaces(sm.Spec.NamespaceSelector))
+
+	var targets []Target
+	for _, endpoint := range sm.Spec.Endpoints {
+		port := endpoint.Port
+		if port == "" && endpoint.TargetPort != nil {
+			port = endpoint.TargetPort.String()
+		}
+
+		targets = append(targets, Target{
+			Kind:     monitoringv1.ServiceMonitorsKind,
+			Name:     sm.Name,
+			Endpoint: endpointURL(endpoint.Scheme, port, endpoint.Path),
+			Selector: selector,
+			Interval: string(endpoint.Interval),
+		})
+	}
+	return targets
+}
+
+func podMonitorTargets(pm *monitoringv1.PodMonitor) []Target {
+	selector := fmt.Sprintf("pods %s%s", metav1.FormatLabelSelector(&pm.Spec.Selector), namespaces(pm.Spec.NamespaceSelector))
+
+	var targets []Target
+	for _, endpoint := range pm.Spec.PodMetricsEndpoints {
+		port := endpoint.Port
+		if port == "" && endpoint.TargetPort != nil {
+			port = endpoint.TargetPort.String()
+		}
+
+		targets = append(targets, Target{
+			Kind:     monitoringv1.PodMonitorsKind,
+			Name:     pm.Name,
+			Endpoint: endpointURL(endpoint.Scheme, port, endpoint.Path),
+			Selector: selector,
+			Interval: string(endpoint.Interval),
+		})
+	}
+	return targets
+}
+
+func probeTarget(probe *monitoringv1.Probe) Target {
+	target := Target{
+		Kind:     monitoringv1.ProbesKind,
+		Name:     probe.Name,
+		Interval: string(probe.Spec.Interval),
+	}
+
+	var endpoint string
+	switch {
+	case probe.Spec.Targets.StaticConfig != nil:
+		endpoint = strings.Join(probe.Spec.Targets.StaticConfig.Targets, ", ")
+	case probe.Spec.Targets.Ingress != nil:
+		target.Selector = fmt.Sprintf("ingresses %s%s", metav1.FormatLabelSelector(&probe.Spec.Targets.Ingress.Selector), namespaces(probe.Spec.Targets.Ingress.NamespaceSelector))
+	}
+
+	prober := probe.Spec.ProberSpec.URL
+	if probe.Spec.Module != "" {
+		prober += " module " + probe.Spec.Module
+	}
+	target.Endpoint = strings.TrimSpace(endpoint + " via " + prober)
+
+	return target
+}
+
+func scrapeConfigTarget(sc *monitoringv1alpha1.ScrapeConfig) Target {
+	target := Target{
+		Kind: monitoringv1alpha1.ScrapeConfigsKind,
+		Name: sc.Name,
+	}
+	if sc.Spec.ScrapeInterval != nil {
+		target.Interval = string(*sc.Spec.ScrapeInterval)
+	}
+
+	var endpoints []string
+	for _, static := range sc.Spec.StaticConfigs {
+		for _, t := range static.Targets {
+			endpoints = append(endpoints, string(t))
+		}
+	}
+
+	// The service discoveries are told by the name of their field, the
+	// ScrapeConfig supporting dozens of them.
+	var discoveries []string
+	if data, err := json.Marshal(sc.Spec); err == nil {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err == nil {
+			for field, value := range fields {
+				if strings.HasSuffix(field, "SDConfigs") && string(value) != "[]" {
+					discoveries = append(discoveries, field)
+				}
+			}
+		}
+	}
+	slices.Sort(discoveries)
+
+	target.Endpoint = strings.Join(endpoints, ", ")
+	target.Selector = strings.Join(discoveries, ", ")
+	return target
+}
+
+// endpointURL describes the port, path and scheme of an endpoint, with the
+// defaults of Prometheus.
+func endpointURL(scheme, port, path string) string {
+	return fmt.Sprintf("port %s path %s over %s", port, cmp.Or(path, "/metrics"), cmp.Or(scheme, "http"))
+}
+
+// namespaces describes the namespaces selected by a namespace selector, the
+// namespace of the monitor when empty.
+func namespaces(selector monitoringv1.NamespaceSelector) string {
+	switch {
+	case selector.Any:
+		return " in all namespaces"
+	case len(selector.MatchNames) > 0:
+		return " in " + strings.Join(selector.MatchNames, ", ")
+	}
+	return ""
+}
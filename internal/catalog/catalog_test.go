@@ -0,0 +1,206 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func newCatalog(t *testing.T) *Catalog {
+	t.Helper()
+
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-rules", Namespace: "team-a"},
+			Spec: monitoringv1.PrometheusRuleSpec{
+				Groups: []monitoringv1.RuleGroup{
+					{
+						Name: "app",
+						Rules: []monitoringv1.Rule{
+							{
+								Record: "job:http_requests:rate5m",
+								Expr:   intstr.FromString("sum by (job) (rate(http_requests_total[5m]))"),
+							},
+							{
+								Alert: "AppErrors",
+								Expr:  intstr.FromString("job:http_errors:rate5m > 0.1\n  or absent(up{job=\"app\"})"),
+								For:   ptr.To(monitoringv1.Duration("10m")),
+								Labels: map[string]string{
+									"severity": "critical",
+								},
+								Annotations: map[string]string{
+									"summary":     "App <api> | errors",
+									"runbook_url": "https://runbooks.example.com/app-errors",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&monitoringv1.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-team", Namespace: "team-b"},
+			Spec: monitoringv1.PrometheusRuleSpec{
+				Groups: []monitoringv1.RuleGroup{
+					{
+						Name:  "other",
+						Rules: []monitoringv1.Rule{{Alert: "Other", Expr: intstr.FromString("vector(1)")}},
+					},
+				},
+			},
+		},
+		&monitoringv1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: monitoringv1.ServiceMonitorSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				Endpoints: []monitoringv1.Endpoint{
+					{Port: "web", Interval: "15s"},
+					{Port: "admin", Path: "/admin/metrics", Scheme: "https"},
+				},
+			},
+		},
+		&monitoringv1.PodMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "workers", Namespace: "team-a"},
+			Spec: monitoringv1.PodMonitorSpec{
+				Selector:          metav1.LabelSelector{MatchLabels: map[string]string{"app": "worker"}},
+				NamespaceSelector: monitoringv1.NamespaceSelector{Any: true},
+				PodMetricsEndpoints: []monitoringv1.PodMetricsEndpoint{
+					{Port: "metrics"},
+				},
+			},
+		},
+		&monitoringv1.Probe{
+			ObjectMeta: metav1.ObjectMeta{Name: "website", Namespace: "team-a"},
+			Spec: monitoringv1.ProbeSpec{
+				ProberSpec: monitoringv1.ProberSpec{URL: "blackbox-exporter:9115"},
+				Module:     "http_2xx",
+				Interval:   "1m",
+				Targets: monitoringv1.ProbeTargets{
+					StaticConfig: &monitoringv1.ProbeTargetStaticConfig{Targets: []string{"https://example.com"}},
+				},
+			},
+		},
+		&monitoringv1alpha1.ScrapeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "nodes", Namespace: "team-a"},
+			Spec: monitoringv1alpha1.ScrapeConfigSpec{
+				StaticConfigs: []monitoringv1alpha1.StaticConfig{
+					{Targets: []monitoringv1alpha1.Target{"10.0.0.1:9100"}},
+				},
+				FileSDConfigs: []monitoringv1alpha1.FileSDConfig{
+					{Files: []monitoringv1alpha1.SDFile{"/etc/targets.json"}},
+				},
+			},
+		},
+	))
+
+	c, err := Build(context.Background(), clientSets, "team-a")
+	require.NoError(t, err)
+	return c
+}
+
+func TestBuild(t *testing.T) {
+	c := newCatalog(t)
+
+	assert.Equal(t, []Alert{
+		{
+			Name:     "AppErrors",
+			Rule:     "app-rules",
+			Group:    "app",
+			Severity: "critical",
+			Expr:     "job:http_errors:rate5m > 0.1\n  or absent(up{job=\"app\"})",
+			For:      "10m",
+			Summary:  "App <api> | errors",
+			Runbook:  "https://runbooks.example.com/app-errors",
+		},
+	}, c.Alerts)
+
+	assert.Equal(t, []Target{
+		{
+			Kind:     "PodMonitor",
+			Name:     "workers",
+			Endpoint: "port metrics path /metrics over http",
+			Selector: "pods app=worker in all namespaces",
+		},
+		{
+			Kind:     "Probe",
+			Name:     "website",
+			Endpoint: "https://example.com via blackbox-exporter:9115 module http_2xx",
+			Interval: "1m",
+		},
+		{
+			Kind:     "ScrapeConfig",
+			Name:     "nodes",
+			Endpoint: "10.0.0.1:9100",
+			Selector: "fileSDConfigs",
+		},
+		{
+			Kind:     "ServiceMonitor",
+			Name:     "app",
+			Endpoint: "port web path /metrics over http",
+			Selector: "services app=api",
+			Interval: "15s",
+		},
+		{
+			Kind:     "ServiceMonitor",
+			Name:     "app",
+			Endpoint: "port admin path /admin/metrics over https",
+			Selector: "services app=api",
+		},
+	}, c.Targets)
+}
+
+func TestRender(t *testing.T) {
+	c := newCatalog(t)
+
+	t.Run("Markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Render(&buf, c, FormatMarkdown))
+
+		assert.Contains(t, buf.String(), "# Monitoring Catalog of Namespace team-a")
+		assert.Contains(t, buf.String(), "| AppErrors | critical | `job:http_errors:rate5m > 0.1 or absent(up{job=\"app\"})` | 10m | App &lt;api&gt; \\| errors | [runbook](https://runbooks.example.com/app-errors) | app-rules/app |")
+		assert.Contains(t, buf.String(), "| Probe | website | https://example.com via blackbox-exporter:9115 module http_2xx | - | 1m |")
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Render(&buf, c, FormatHTML))
+
+		assert.Contains(t, buf.String(), "<td>App &lt;api&gt; | errors</td>")
+		assert.Contains(t, buf.String(), `<a href="https://runbooks.example.com/app-errors">runbook</a>`)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Render(&buf, &Catalog{Namespace: "empty"}, FormatMarkdown))
+
+		assert.Contains(t, buf.String(), "No alerts are defined in this namespace.")
+		assert.Contains(t, buf.String(), "No scrape targets are defined in this namespace.")
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		assert.Error(t, Render(&bytes.Buffer{}, c, "pdf"))
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// The formats the catalog is rendered in.
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+var markdownTemplate = template.Must(template.New("md").Funcs(template.FuncMap{
+	"cell": markdownCell,
+	"code": markdownCode,
+}).Parse(`# Monitoring Catalog of Namespace {{ .Namespace }}
+
+## Alerts
+{{ if .Alerts }}
+| Alert | Severity | Expression | For | Summary | Runbook | Rule |
+|-------|----------|------------|-----|---------|---------|------|
+{{- range .Alerts }}
+| {{ cell .Name }} | {{ cell .Severity }} | {{ code .Expr }} | {{ cell .For }} | {{ cell .Summary }} | {{ if .Runbook }}[runbook]({{ .Runbook }}){{ else }}-{{ end }} | {{ cell .Rule }}/{{ cell .Group }} |
+{{- end }}
+{{ else }}
+No alerts are defined in this namespace.
+{{ end }}
+## Scrape Targets
+{{ if .Targets }}
+| Kind | Name | Endpoint | Selector | Interval |
+|------|------|----------|----------|----------|
+{{- range .Targets }}
+| {{ .Kind }} | {{ cell .Name }} | {{ cell .Endpoint }} | {{ cell .Selector }} | {{ cell .Interval }} |
+{{- end }}
+{{ else }}
+No scrape targets are defined in this namespace.
+{{ end -}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monitoring Catalog of Namespace {{ .Namespace }}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+code { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Monitoring Catalog of Namespace {{ .Namespace }}</h1>
+<h2>Alerts</h2>
+{{- if .Alerts }}
+<table>
+<tr><th>Alert</th><th>Severity</th><th>Expression</th><th>For</th><th>Summary</th><th>Runbook</th><th>Rule</th></tr>
+{{- range .Alerts }}
+<tr><td>{{ .Name }}</td><td>{{ .Severity }}</td><td><code>{{ .Expr }}</code></td><td>{{ .For }}</td><td>{{ .Summary }}</td><td>{{ if .Runbook }}<a href="{{ .Runbook }}">runbook</a>{{ end }}</td><td>{{ .Rule }}/{{ .Group }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No alerts are defined in this namespace.</p>
+{{- end }}
+<h2>Scrape Targets</h2>
+{{- if .Targets }}
+<table>
+<tr><th>Kind</th><th>Name</th><th>Endpoint</th><th>Selector</th><th>Interval</th></tr>
+{{- range .Targets }}
+<tr><td>{{ .Kind }}</td><td>{{ .Name }}</td><td>{{ .Endpoint }}</td><td>{{ .Selector }}</td><td>{{ .Interval }}</td></tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No scrape targets are defined in this namespace.</p>
+{{- end }}
+</body>
+</html>
+`))
+
+// Render writes the catalog to w in the format, either FormatMarkdown or
+// FormatHTML.
+func Render(w io.Writer, c *Catalog, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return markdownTemplate.Execute(w, c)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, c)
+	}
+	return fmt.Errorf("unsupported output format %q, one of: %s, %s", format, FormatMarkdown, FormatHTML)
+}
+
+// markdownEscaper escapes the table delimiters and the HTML tags.
+var markdownEscaper = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;")
+
+// markdownCell escapes a value for a cell of a Markdown table, which holds a
+// single line, "-" standing for the empty values.
+func markdownCell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return markdownEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+// markdownCode formats an expression as inline code in a Markdown table
+// cell, with a longer delimiter when it contains backticks. The code spans
+// aren't parsed for HTML, only the table delimiters are escaped.
+func markdownCode(s string) string {
+	if s == "" {
+		return "-"
+	}
+	s = strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package certificates reports the expiry of the certificates used by the
+// monitoring resources: the certificates served by Prometheus, the
+// PrometheusAgents and the Alertmanagers, the certificates used to scrape
+// the monitors and to reach the remote write endpoints and the
+// Alertmanagers, and the certificates of the admission and conversion
+// webhooks of the monitoring.coreos.com resources.
+package certificates
+
+import (
+	"cmp"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Status tells whether a certificate expires within the threshold, the most
+// urgent first.
+type Status int
+
+const (
+	Expired Status = iota
+	Expiring
+	Unreadable
+	Valid
+)
+
+func (s Status) String() string {
+	switch s {
+	case Expired:
+		return "EXPIRED"
+	case Expiring:
+		return "EXPIRING"
+	case Unreadable:
+		return "UNREADABLE"
+	default:
+		return "VALID"
+	}
+}
+
+// Options selects the certificates to check.
+type Options struct {
+	// Namespace of the resources, metav1.NamespaceAll for all namespaces.
+	Namespace string
+	// Threshold under which a certificate is reported as expiring.
+	Threshold time.Duration
+	// Now is the time the expiry is computed at, the current time when
+	// zero.
+	Now time.Time
+}
+
+// Certificate is the certificate found at a TLS reference of a resource,
+// the first to expire when the reference holds a bundle of certificates.
+type Certificate struct {
+	// Source is the resource and the field referencing the certificate.
+	Source string
+	// Object is the Secret or the ConfigMap holding the certificate, empty
+	// when the certificate is inlined in the resource.
+	Object   string
+	Key      string
+	Subject  string
+	NotAfter time.Time
+	DaysLeft int
+	Status   Status
+	// Err is the reason why an Unreadable certificate can't be read.
+	Err error
+}
+
+// reference is a certificate referenced by a resource, either in a Secret or
+// a ConfigMap of its namespace, or inlined in its data.
+type reference struct {
+	source    string
+	namespace string
+	ref       monitoringv1.SecretOrConfigMap
+	data      []byte
+}
+
+// Check returns the certificates referenced by the monitoring resources,
+// the expired and expiring ones first.
+func Check(ctx context.Context, clientSets *k8sutil.ClientSets, opts Options) ([]Certificate, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	refs, err := resourceReferences(ctx, clientSets, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookRefs, err := webhookReferences(ctx, clientSets, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	refs = append(refs, webhookRefs...)
+
+	certificates := make([]Certificate, 0, len(refs))
+	for _, r := range refs {
+		c, err := read(ctx, clientSets, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Err == nil {
+			left := c.NotAfter.Sub(now)
+			c.DaysLeft = int(math.Floor(left.Hours() / 24))
+			switch {
+			case left <= 0:
+				c.Status = Expired
+			case left <= opts.Threshold:
+				c.Status = Expiring
+			default:
+				c.Status = Valid
+			}
+		}
+
+		certificates = append(certificates, c)
+	}
+
+	slices.SortStableFunc(certificates, func(a, b Certificate) int {
+		return cmp.Or(
+			cmp.Compare(a.Status, b.Status),
+			a.NotAfter.Compare(b.NotAfter),
+			cmp.Compare(a.Source, b.Source),
+		)
+	})
+	return certificates, nil
+}
+
+// read returns the certificate of the reference, Unreadable when the Secret,
+// the ConfigMap or the key is missing or doesn't hold a PEM certificate.
+func read(ctx context.Context, clientSets *k8sutil.ClientSets, r reference) (Certificate, error) {
+	c := Certificate{Source: r.source}
+
+	data := r.data
+	switch {
+	case r.ref.Secret != nil:
+		c.Object = fmt.Sprintf("Secret %s/%s", r.namespace, r.ref.Secret.Name)
+		c.Key = r.ref.Secret.Key
+
+		secret, err := clientSets.KClient.CoreV1().Secrets(r.namespace).Get(ctx, r.ref.Secret.Name, metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return c, fmt.Errorf("error while getting Secret %s/%s: %w", r.namespace, r.ref.Secret.Name, err)
+			}
+			return unreadable(c, fmt.Errorf("secret not found")), nil
+		}
+
+		var ok bool
+		if data, ok = secret.Data[c.Key]; !ok {
+			return unreadable(c, fmt.Errorf("key not found")), nil
+		}
+	case r.ref.ConfigMap != nil:
+		c.Object = fmt.Sprintf("ConfigMap %s/%s", r.namespace, r.ref.ConfigMap.Name)
+		c.Key = r.ref.ConfigMap.Key
+
+		cm, err := clientSets.KClient.CoreV1().ConfigMaps(r.namespace).Get(ctx, r.ref.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return c, fmt.Errorf("error while getting ConfigMap %s/%s: %w", r.namespace, r.ref.ConfigMap.Name, err)
+			}
+			return unreadable(c, fmt.Errorf("configmap not found")), nil
+		}
+
+		value, ok := cm.Data[c.Key]
+		if !ok {
+			return unreadable(c, fmt.Errorf("key not found")), nil
+		}
+		data = []byte(value)
+	}
+
+	cert, err := firstToExpire(data)
+	if err != nil {
+		return unreadable(c, err), nil
+	}
+
+	c.Subject = cert.Subject.String()
+	c.NotAfter = cert.NotAfter
+	return c, nil
+}
+
+func unreadable(c Certificate, err error) Certificate {
+	c.Status = Unreadable
+	c.Err = err
+	return c
+}
+
+// firstToExpire returns the certificate of the PEM data which expires first.
+func firstToExpire(data []byte) (*x509.Certificate, error) {
+	var first *x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+
+		if first == nil || cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+
+	if first == nil {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return first, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+var now = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// newCertificate returns a PEM self-signed certificate expiring after the
+// duration.
+func newCertificate(t *testing.T, cn string, expiresIn time.Duration) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    now.Add(-24 * time.Hour),
+		NotAfter:     now.Add(expiresIn),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func secretRef(name, key string) *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func TestCheck(t *testing.T) {
+	day := 24 * time.Hour
+
+	objects := []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-tls", Namespace: "team"},
+			Data:       map[string][]byte{"ca.crt": newCertificate(t, "app-ca", 10*day)},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-ca", Namespace: "team"},
+			Data:       map[string]string{"ca.crt": string(newCertificate(t, "node-ca", -day))},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "monitoring"},
+			Data:       map[string][]byte{corev1.TLSCertKey: newCertificate(t, "prometheus", 100*day)},
+		},
+		&monitoringv1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team"},
+			Spec: monitoringv1.ServiceMonitorSpec{
+				Endpoints: []monitoringv1.Endpoint{
+					{Port: "web", TLSConfig: &monitoringv1.TLSConfig{SafeTLSConfig: monitoringv1.SafeTLSConfig{
+						CA: monitoringv1.SecretOrConfigMap{Secret: secretRef("app-tls", "ca.crt")},
+					}}},
+				},
+			},
+		},
+		&monitoringv1.PodMonitor{
+			ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "team"},
+			Spec: monitoringv1.PodMonitorSpec{
+				PodMetricsEndpoints: []monitoringv1.PodMetricsEndpoint{
+					{Port: "web", TLSConfig: &monitoringv1.SafeTLSConfig{
+						CA: monitoringv1.SecretOrConfigMap{ConfigMap: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "node-ca"},
+							Key:                  "ca.crt",
+						}},
+						Cert: monitoringv1.SecretOrConfigMap{Secret: secretRef("node-client", "tls.crt")},
+					}},
+				},
+			},
+		},
+		&monitoringv1.Prometheus{
+			ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+			Spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					Web: &monitoringv1.PrometheusWebSpec{
+						WebConfigFileFields: monitoringv1.WebConfigFileFields{
+							TLSConfig: &monitoringv1.WebTLSConfig{
+								Cert:      monitoringv1.SecretOrConfigMap{Secret: secretRef("web-tls", corev1.TLSCertKey)},
+								KeySecret: *secretRef("web-tls", corev1.TLSPrivateKeyKey),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		namespace string
+		expected  []Certificate
+	}{
+		{
+			name:      "AllNamespaces",
+			namespace: metav1.NamespaceAll,
+			expected: []Certificate{
+				{Source: "PodMonitor team/node podMetricsEndpoints[0].tlsConfig.ca", Object: "ConfigMap team/node-ca", Key: "ca.crt", Subject: "CN=node-ca", NotAfter: now.Add(-day), DaysLeft: -1, Status: Expired},
+				{Source: "ServiceMonitor team/app endpoints[0].tlsConfig.ca", Object: "Secret team/app-tls", Key: "ca.crt", Subject: "CN=app-ca", NotAfter: now.Add(10 * day), DaysLeft: 10, Status: Expiring},
+				{Source: "PodMonitor team/node podMetricsEndpoints[0].tlsConfig.cert", Object: "Secret team/node-client", Key: "tls.crt", Status: Unreadable},
+				{Source: "Prometheus monitoring/k8s web.tlsConfig.cert", Object: "Secret monitoring/web-tls", Key: corev1.TLSCertKey, Subject: "CN=prometheus", NotAfter: now.Add(100 * day), DaysLeft: 100, Status: Valid},
+			},
+		},
+		{
+			name:      "Namespace",
+			namespace: "monitoring",
+			expected: []Certificate{
+				{Source: "Prometheus monitoring/k8s web.tlsConfig.cert", Object: "Secret monitoring/web-tls", Key: corev1.TLSCertKey, Subject: "CN=prometheus", NotAfter: now.Add(100 * day), DaysLeft: 100, Status: Valid},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(objects...))
+
+			certificates, err := Check(context.Background(), clientSets, Options{Namespace: tc.namespace, Threshold: 30 * day, Now: now})
+			require.NoError(t, err)
+
+			for i := range certificates {
+				if certificates[i].Status == Unreadable {
+					assert.Error(t, certificates[i].Err)
+					certificates[i].Err = nil
+				}
+				certificates[i].NotAfter = certificates[i].NotAfter.UTC()
+			}
+			assert.Equal(t, tc.expected, certificates)
+		})
+	}
+}
+
+func TestCheckWebhooks(t *testing.T) {
+	day := 24 * time.Hour
+	labels := map[string]string{"app.kubernetes.io/name": "prometheus-operator-admission-webhook"}
+
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheusrule-validation"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{
+					Name: "prometheusrulevalidate.monitoring.coreos.com",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service:  &admissionregistrationv1.ServiceReference{Namespace: "monitoring", Name: "admission-webhook"},
+						CABundle: newCertificate(t, "webhook-ca", 20*day),
+					},
+					Rules: []admissionregistrationv1.RuleWithOperations{
+						{Rule: admissionregistrationv1.Rule{APIGroups: []string{"monitoring.coreos.com"}, Resources: []string{"prometheusrules"}}},
+					},
+				},
+				{
+					Name:         "other.example.com",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: newCertificate(t, "other-ca", -day)},
+					Rules: []admissionregistrationv1.RuleWithOperations{
+						{Rule: admissionregistrationv1.Rule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}}},
+					},
+				},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "admission-webhook", Namespace: "monitoring"},
+			Spec:       corev1.ServiceSpec{Selector: labels},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "admission-webhook", Namespace: "monitoring"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Volumes: []corev1.Volume{
+							{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "admission-webhook-tls"}}},
+							{Name: "config", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "admission-webhook-config"}}},
+							{Name: "extra", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "missing", Optional: ptr.To(true)}}},
+						},
+					},
+				},
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "admission-webhook-tls", Namespace: "monitoring"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: newCertificate(t, "admission-webhook", 5*day)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "admission-webhook-config", Namespace: "monitoring"},
+			Data:       map[string][]byte{"config.yaml": []byte("{}")},
+		},
+	))
+
+	for _, tc := range []struct {
+		namespace string
+		expected  []string
+	}{
+		{
+			namespace: metav1.NamespaceAll,
+			expected: []string{
+				"ValidatingWebhookConfiguration prometheusrule-validation webhooks[prometheusrulevalidate.monitoring.coreos.com] serving certificate",
+				"ValidatingWebhookConfiguration prometheusrule-validation webhooks[prometheusrulevalidate.monitoring.coreos.com] caBundle",
+			},
+		},
+		{
+			namespace: "monitoring",
+			expected: []string{
+				"ValidatingWebhookConfiguration prometheusrule-validation webhooks[prometheusrulevalidate.monitoring.coreos.com] serving certificate",
+				"ValidatingWebhookConfiguration prometheusrule-validation webhooks[prometheusrulevalidate.monitoring.coreos.com] caBundle",
+			},
+		},
+		{
+			namespace: "team",
+		},
+	} {
+		t.Run(tc.namespace, func(t *testing.T) {
+			certificates, err := Check(context.Background(), clientSets, Options{Namespace: tc.namespace, Threshold: 30 * day, Now: now})
+			require.NoError(t, err)
+
+			var sources []string
+			for _, c := range certificates {
+				assert.Equal(t, Expiring, c.Status, c.Source)
+				sources = append(sources, c.Source)
+			}
+			assert.Equal(t, tc.expected, sources)
+		})
+	}
+}
+
+func TestFirstToExpire(t *testing.T) {
+	bundle := append(newCertificate(t, "later", 48*time.Hour), newCertificate(t, "sooner", 24*time.Hour)...)
+
+	cert, err := firstToExpire(bundle)
+	require.NoError(t, err)
+	assert.Equal(t, "sooner", cert.Subject.CommonName)
+
+	_, err = firstToExpire([]byte("not a certificate"))
+	require.Error(t, err)
+}
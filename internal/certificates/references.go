@@ -0,0 +1,160 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certificates
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resourceReferences returns the certificates referenced by the TLS
+// configurations of the monitoring.coreos.com resources of the namespace.
+func resourceReferences(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) ([]reference, error) {
+	var refs []reference
+
+	err := k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(namespace).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		source := fmt.Sprintf("Prometheus %s/%s", p.Namespace, p.Name)
+		refs = append(refs, commonReferences(source, p.Namespace, p.Spec.CommonPrometheusFields)...)
+
+		if p.Spec.Alerting != nil {
+			for i, am := range p.Spec.Alerting.Alertmanagers {
+				if am.TLSConfig != nil {
+					refs = append(refs, tlsReferences(fmt.Sprintf("%s alerting.alertmanagers[%d].tlsConfig", source, i), p.Namespace, &am.TLSConfig.SafeTLSConfig)...)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PrometheusAgents", clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(namespace).List, metav1.ListOptions{}, func(p *monitoringv1alpha1.PrometheusAgent) error {
+		refs = append(refs, commonReferences(fmt.Sprintf("PrometheusAgent %s/%s", p.Namespace, p.Name), p.Namespace, p.Spec.CommonPrometheusFields)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Alertmanagers", clientSets.MClient.MonitoringV1().Alertmanagers(namespace).List, metav1.ListOptions{}, func(am *monitoringv1.Alertmanager) error {
+		if am.Spec.Web != nil {
+			refs = append(refs, webReferences(fmt.Sprintf("Alertmanager %s/%s", am.Namespace, am.Name), am.Namespace, am.Spec.Web.WebConfigFileFields)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ServiceMonitors", clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).List, metav1.ListOptions{}, func(sm *monitoringv1.ServiceMonitor) error {
+		for i, endpoint := range sm.Spec.Endpoints {
+			if endpoint.TLSConfig != nil {
+				refs = append(refs, tlsReferences(fmt.Sprintf("ServiceMonitor %s/%s endpoints[%d].tlsConfig", sm.Namespace, sm.Name, i), sm.Namespace, &endpoint.TLSConfig.SafeTLSConfig)...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PodMonitors", clientSets.MClient.MonitoringV1().PodMonitors(namespace).List, metav1.ListOptions{}, func(pm *monitoringv1.PodMonitor) error {
+		for i, endpoint := range pm.Spec.PodMetricsEndpoints {
+			refs = append(refs, tlsReferences(fmt.Sprintf("PodMonitor %s/%s podMetricsEndpoints[%d].tlsConfig", pm.Namespace, pm.Name, i), pm.Namespace, endpoint.TLSConfig)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Probes", clientSets.MClient.MonitoringV1().Probes(namespace).List, metav1.ListOptions{}, func(probe *monitoringv1.Probe) error {
+		refs = append(refs, tlsReferences(fmt.Sprintf("Probe %s/%s tlsConfig", probe.Namespace, probe.Name), probe.Namespace, probe.Spec.TLSConfig)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ScrapeConfigs", clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(namespace).List, metav1.ListOptions{}, func(sc *monitoringv1alpha1.ScrapeConfig) error {
+		refs = append(refs, tlsReferences(fmt.Sprintf("ScrapeConfig %s/%s tlsConfig", sc.Namespace, sc.Name), sc.Namespace, sc.Spec.TLSConfig)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// commonReferences returns the certificates served by a Prometheus or a
+// PrometheusAgent and those used to reach its remote write endpoints.
+func commonReferences(source, namespace string, spec monitoringv1.CommonPrometheusFields) []reference {
+	var refs []reference
+	if spec.Web != nil {
+		refs = append(refs, webReferences(source, namespace, spec.Web.WebConfigFileFields)...)
+	}
+
+	for i, rw := range spec.RemoteWrite {
+		if rw.TLSConfig != nil {
+			refs = append(refs, tlsReferences(fmt.Sprintf("%s remoteWrite[%d].tlsConfig", source, i), namespace, &rw.TLSConfig.SafeTLSConfig)...)
+		}
+	}
+	return refs
+}
+
+// webReferences returns the certificate served by the web server and the CA
+// verifying its clients.
+func webReferences(source, namespace string, web monitoringv1.WebConfigFileFields) []reference {
+	if web.TLSConfig == nil {
+		return nil
+	}
+
+	return referencesOf(namespace, map[string]monitoringv1.SecretOrConfigMap{
+		source + " web.tlsConfig.cert":      web.TLSConfig.Cert,
+		source + " web.tlsConfig.client_ca": web.TLSConfig.ClientCA,
+	})
+}
+
+// tlsReferences returns the CA and the client certificate of a TLS client
+// configuration.
+func tlsReferences(source, namespace string, tlsConfig *monitoringv1.SafeTLSConfig) []reference {
+	if tlsConfig == nil {
+		return nil
+	}
+
+	return referencesOf(namespace, map[string]monitoringv1.SecretOrConfigMap{
+		source + ".ca":   tlsConfig.CA,
+		source + ".cert": tlsConfig.Cert,
+	})
+}
+
+// referencesOf returns the references of the Secrets and the ConfigMaps
+// which are set, indexed by their source.
+func referencesOf(namespace string, sources map[string]monitoringv1.SecretOrConfigMap) []reference {
+	var refs []reference
+	for source, ref := range sources {
+		if (ref.Secret != nil && ref.Secret.Name != "") || (ref.ConfigMap != nil && ref.ConfigMap.Name != "") {
+			refs = append(refs, reference{source: source, namespace: namespace, ref: ref})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certificates
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const monitoringGroup = "monitoring.coreos.com"
+
+// webhookReferences returns the CA bundles and the serving certificates of
+// the admission webhooks validating or mutating the monitoring.coreos.com
+// resources, and of the conversion webhooks of their CRDs. The webhooks
+// served outside of the namespace are skipped, and those reached through an
+// URL are only checked for all namespaces.
+func webhookReferences(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) ([]reference, error) {
+	var refs []reference
+
+	err := k8sutil.EachListItem(ctx, "ValidatingWebhookConfigurations", clientSets.KClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List, metav1.ListOptions{}, func(c *admissionregistrationv1.ValidatingWebhookConfiguration) error {
+		for _, w := range c.Webhooks {
+			if !matchesMonitoringGroup(w.Rules) {
+				continue
+			}
+
+			r, err := clientConfigReferences(ctx, clientSets, fmt.Sprintf("ValidatingWebhookConfiguration %s webhooks[%s]", c.Name, w.Name), namespace, w.ClientConfig)
+			if err != nil {
+				return err
+			}
+			refs = append(refs, r...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "MutatingWebhookConfigurations", clientSets.KClient.AdmissionregistrationV1().MutatingWebhookConfigurations().List, metav1.ListOptions{}, func(c *admissionregistrationv1.MutatingWebhookConfiguration) error {
+		for _, w := range c.Webhooks {
+			if !matchesMonitoringGroup(w.Rules) {
+				continue
+			}
+
+			r, err := clientConfigReferences(ctx, clientSets, fmt.Sprintf("MutatingWebhookConfiguration %s webhooks[%s]", c.Name, w.Name), namespace, w.ClientConfig)
+			if err != nil {
+				return err
+			}
+			refs = append(refs, r...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "CustomResourceDefinitions", clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().List, metav1.ListOptions{}, func(crd *apiextensionsv1.CustomResourceDefinition) error {
+		conversion := crd.Spec.Conversion
+		if crd.Spec.Group != monitoringGroup || conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+			return nil
+		}
+
+		clientConfig := conversion.Webhook.ClientConfig
+		config := admissionregistrationv1.WebhookClientConfig{CABundle: clientConfig.CABundle, URL: clientConfig.URL}
+		if clientConfig.Service != nil {
+			config.Service = &admissionregistrationv1.ServiceReference{Namespace: clientConfig.Service.Namespace, Name: clientConfig.Service.Name}
+		}
+
+		r, err := clientConfigReferences(ctx, clientSets, fmt.Sprintf("CustomResourceDefinition %s conversion webhook", crd.Name), namespace, config)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, r...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// matchesMonitoringGroup reports whether the webhook rules match the
+// monitoring.coreos.com resources.
+func matchesMonitoringGroup(rules []admissionregistrationv1.RuleWithOperations) bool {
+	for _, r := range rules {
+		if slices.Contains(r.APIGroups, monitoringGroup) || slices.Contains(r.APIGroups, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// clientConfigReferences returns the CA bundle of the webhook, and the
+// serving certificates of its Service.
+func clientConfigReferences(ctx context.Context, clientSets *k8sutil.ClientSets, source, namespace string, config admissionregistrationv1.WebhookClientConfig) ([]reference, error) {
+	if config.Service == nil && namespace != metav1.NamespaceAll {
+		return nil, nil
+	}
+	if config.Service != nil && namespace != metav1.NamespaceAll && config.Service.Namespace != namespace {
+		return nil, nil
+	}
+
+	var refs []reference
+	if len(config.CABundle) > 0 {
+		refs = append(refs, reference{source: source + " caBundle", data: config.CABundle})
+	}
+
+	if config.Service == nil {
+		return refs, nil
+	}
+
+	serving, err := servingReferences(ctx, clientSets, source+" serving certificate", config.Service.Namespace, config.Service.Name)
+	if err != nil {
+		return nil, err
+	}
+	return append(refs, serving...), nil
+}
+
+// servingReferences returns the kubernetes.io/tls Secrets mounted by the
+// Deployments backing a Service, which hold the certificate it serves.
+func servingReferences(ctx context.Context, clientSets *k8sutil.ClientSets, source, namespace, name string) ([]reference, error) {
+	service, err := clientSets.KClient.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while getting service %s/%s: %w", namespace, name, err)
+	}
+
+	if len(service.Spec.Selector) == 0 {
+		return nil, nil
+	}
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+
+	var refs []reference
+	err = k8sutil.EachListItem(ctx, "Deployments", clientSets.KClient.AppsV1().Deployments(namespace).List, metav1.ListOptions{}, func(d *appsv1.Deployment) error {
+		if !selector.Matches(labels.Set(d.Spec.Template.Labels)) {
+			return nil
+		}
+
+		for _, volume := range d.Spec.Template.Spec.Volumes {
+			if volume.Secret == nil {
+				continue
+			}
+
+			secret, err := clientSets.KClient.CoreV1().Secrets(namespace).Get(ctx, volume.Secret.SecretName, metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("error while getting Secret %s/%s: %w", namespace, volume.Secret.SecretName, err)
+			}
+
+			// A missing Secret is reported as unreadable unless it's
+			// optional, the Secrets which don't hold a certificate are
+			// skipped.
+			if err != nil && volume.Secret.Optional != nil && *volume.Secret.Optional {
+				continue
+			}
+			if err == nil {
+				if _, ok := secret.Data[corev1.TLSCertKey]; !ok {
+					continue
+				}
+			}
+
+			refs = append(refs, reference{
+				source:    source,
+				namespace: namespace,
+				ref: monitoringv1.SecretOrConfigMap{
+					Secret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: volume.Secret.SecretName},
+						Key:                  corev1.TLSCertKey,
+					},
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clidocs generates the man pages and the Markdown reference of the
+// commands of poctl from the cobra command tree, so that the distributions
+// can package the manuals along with the binary.
+package clidocs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The formats of the generated documentation.
+const (
+	FormatMan      = "man"
+	FormatMarkdown = "markdown"
+)
+
+// GenTree writes the documentation of the command and of its available
+// subcommands in the directory, a file per command named after its path,
+// such as poctl-analyze.1 or poctl_analyze.md.
+func GenTree(cmd *cobra.Command, dir, format string) error {
+	var (
+		gen       func(io.Writer, *cobra.Command) error
+		separator string
+		extension string
+	)
+	switch format {
+	case FormatMan:
+		gen, separator, extension = GenMan, "-", ".1"
+	case FormatMarkdown:
+		gen, separator, extension = GenMarkdown, "_", ".md"
+	default:
+		return fmt.Errorf("unknown format %s, must be one of: %s, %s", format, FormatMan, FormatMarkdown)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error while creating directory %s: %w", dir, err)
+	}
+
+	return walk(cmd, func(c *cobra.Command) error {
+		filename := filepath.Join(dir, fileName(c, separator)+extension)
+		f, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("error while creating %s: %w", filename, err)
+		}
+
+		if err := gen(f, c); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("error while writing %s: %w", filename, err)
+		}
+		return nil
+	})
+}
+
+// walk calls fn on the command and on its documented subcommands, depth
+// first.
+func walk(cmd *cobra.Command, fn func(*cobra.Command) error) error {
+	if err := fn(cmd); err != nil {
+		return err
+	}
+
+	for _, c := range subcommands(cmd) {
+		if err := walk(c, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subcommands returns the subcommands worth documenting, leaving aside the
+// hidden and deprecated ones and the help command.
+func subcommands(cmd *cobra.Command) []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds
+}
+
+// fileName returns the path of the command with its words joined by the
+// separator.
+func fileName(cmd *cobra.Command, separator string) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", separator)
+}
+
+// description returns the long description of the command, its short one
+// when it has none.
+func description(cmd *cobra.Command) string {
+	if cmd.Long != "" {
+		return cmd.Long
+	}
+	return cmd.Short
+}
+
+// flagLine returns the names and the value placeholder of a flag, such as
+// -k, --kind string.
+func flagLine(f *pflag.Flag) (string, string) {
+	varname, usage := pflag.UnquoteUsage(f)
+
+	var b strings.Builder
+	if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+		fmt.Fprintf(&b, "-%s, ", f.Shorthand)
+	}
+	fmt.Fprintf(&b, "--%s", f.Name)
+	if varname != "" {
+		fmt.Fprintf(&b, " %s", varname)
+	}
+
+	if hasDefault(f) {
+		if f.Value.Type() == "string" {
+			usage += fmt.Sprintf(" (default %q)", f.DefValue)
+		} else {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+	}
+	return b.String(), usage
+}
+
+// hasDefault reports whether the default value of the flag is worth
+// printing, as the usage of the flags does.
+func hasDefault(f *pflag.Flag) bool {
+	switch f.DefValue {
+	case "", "false", "0", "0s", "[]":
+		return false
+	}
+	return true
+}
+
+// visibleFlags returns the flags which aren't hidden.
+func visibleFlags(flags *pflag.FlagSet) []*pflag.Flag {
+	var visible []*pflag.Flag
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		visible = append(visible, f)
+	})
+	return visible
+}
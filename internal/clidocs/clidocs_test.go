@@ -0,0 +1,129 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clidocs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCommandTree returns a poctl command with an analyze subcommand, a
+// hidden one and the help command.
+func newCommandTree() (*cobra.Command, *cobra.Command) {
+	root := &cobra.Command{Use: "poctl", Short: "Manage Prometheus Operator resources."}
+	root.PersistentFlags().String("kubeconfig", "", "path to the kubeconfig file")
+
+	analyze := &cobra.Command{
+		Use:   "analyze",
+		Short: "Analyze Prometheus Operator resources.",
+		Long:  "Analyze the resources.\n\n.dotted paragraph with a \\ backslash.",
+		Example: `  # Analyze a Prometheus
+  poctl analyze -k prometheus -n k8s -s monitoring`,
+		RunE: func(*cobra.Command, []string) error { return nil },
+	}
+	analyze.Flags().StringP("kind", "k", "", "The `kind` of object to analyze")
+	analyze.Flags().String("output", "text", "Output format")
+	analyze.Flags().Bool("secret", false, "Hidden flag")
+	_ = analyze.Flags().MarkHidden("secret")
+
+	hidden := &cobra.Command{Use: "internal", Hidden: true, Run: func(*cobra.Command, []string) {}}
+
+	root.AddCommand(analyze, hidden)
+	root.InitDefaultHelpCmd()
+	return root, analyze
+}
+
+func TestGenMan(t *testing.T) {
+	_, analyze := newCommandTree()
+
+	var buf bytes.Buffer
+	require.NoError(t, GenMan(&buf, analyze))
+
+	assert.Equal(t, `.TH "POCTL-ANALYZE" "1" "" "poctl" "poctl Manual"
+.SH NAME
+poctl-analyze \- Analyze Prometheus Operator resources.
+.SH SYNOPSIS
+.B poctl analyze [flags]
+.SH DESCRIPTION
+Analyze the resources.
+.PP
+\&.dotted paragraph with a \e backslash.
+.SH OPTIONS
+.TP
+.B \-k, \-\-kind kind
+The kind of object to analyze
+.TP
+.B \-\-output string
+Output format (default "text")
+.SH OPTIONS INHERITED FROM PARENT COMMANDS
+.TP
+.B \-\-kubeconfig string
+path to the kubeconfig file
+.SH EXAMPLE
+.nf
+  # Analyze a Prometheus
+  poctl analyze \-k prometheus \-n k8s \-s monitoring
+.fi
+.SH SEE ALSO
+.BR poctl (1)
+`, buf.String())
+}
+
+func TestGenMarkdown(t *testing.T) {
+	root, _ := newCommandTree()
+
+	var buf bytes.Buffer
+	require.NoError(t, GenMarkdown(&buf, root))
+
+	assert.Equal(t, "## poctl\n\nManage Prometheus Operator resources.\n\n"+
+		"### Synopsis\n\nManage Prometheus Operator resources.\n\n"+
+		"### Options\n\n```\n      --kubeconfig string   path to the kubeconfig file\n```\n\n"+
+		"### SEE ALSO\n\n* [poctl analyze](poctl_analyze.md) - Analyze Prometheus Operator resources.\n", buf.String())
+}
+
+func TestGenTree(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		files  []string
+	}{
+		{format: FormatMan, files: []string{"poctl-analyze.1", "poctl.1"}},
+		{format: FormatMarkdown, files: []string{"poctl.md", "poctl_analyze.md"}},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			root, _ := newCommandTree()
+			dir := t.TempDir()
+
+			require.NoError(t, GenTree(root, dir, tc.format))
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			assert.Equal(t, tc.files, files)
+		})
+	}
+
+	root, _ := newCommandTree()
+	err := GenTree(root, filepath.Join(t.TempDir(), "docs"), "html")
+	require.EqualError(t, err, "unknown format html, must be one of: man, markdown")
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clidocs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// GenMan writes the man page of the command in section 1. The page has no
+// date, so that the generated pages are reproducible.
+func GenMan(w io.Writer, cmd *cobra.Command) error {
+	var b bytes.Buffer
+	name := fileName(cmd, "-")
+	root := cmd.Root().Name()
+
+	fmt.Fprintf(&b, ".TH %q \"1\" \"\" %q %q\n", strings.ToUpper(name), root, root+" Manual")
+
+	b.WriteString(".SH NAME\n")
+	fmt.Fprintf(&b, "%s \\- %s\n", name, roffEscape(cmd.Short))
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", roffEscape(cmd.UseLine()))
+
+	b.WriteString(".SH DESCRIPTION\n")
+	writeParagraphs(&b, description(cmd))
+
+	manFlags(&b, "OPTIONS", cmd.NonInheritedFlags())
+	manFlags(&b, "OPTIONS INHERITED FROM PARENT COMMANDS", cmd.InheritedFlags())
+
+	if cmd.HasExample() {
+		b.WriteString(".SH EXAMPLE\n.nf\n")
+		for _, line := range strings.Split(strings.TrimRight(cmd.Example, "\n"), "\n") {
+			b.WriteString(roffLine(line) + "\n")
+		}
+		b.WriteString(".fi\n")
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		seeAlso = append(seeAlso, fileName(cmd.Parent(), "-"))
+	}
+	for _, c := range subcommands(cmd) {
+		seeAlso = append(seeAlso, fileName(c, "-"))
+	}
+	if len(seeAlso) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, page := range seeAlso {
+			sep := ","
+			if i == len(seeAlso)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&b, ".BR %s (1)%s\n", page, sep)
+		}
+	}
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("error while writing man page of %s: %v", cmd.CommandPath(), err)
+	}
+	return nil
+}
+
+// manFlags writes a section listing the flags, skipped when there are none.
+func manFlags(b *bytes.Buffer, section string, flags *pflag.FlagSet) {
+	visible := visibleFlags(flags)
+	if len(visible) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, ".SH %s\n", section)
+	for _, f := range visible {
+		names, usage := flagLine(f)
+		fmt.Fprintf(b, ".TP\n.B %s\n%s\n", roffEscape(names), roffLine(usage))
+	}
+}
+
+// writeParagraphs writes the text, its blank lines separating paragraphs.
+func writeParagraphs(b *bytes.Buffer, text string) {
+	for i, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if i > 0 {
+			b.WriteString(".PP\n")
+		}
+		for _, line := range strings.Split(paragraph, "\n") {
+			b.WriteString(roffLine(line) + "\n")
+		}
+	}
+}
+
+var roffEscaper = strings.NewReplacer(`\`, `\e`, "-", `\-`)
+
+// roffEscape escapes the backslashes and the hyphens, which roff would
+// otherwise render as escapes and as typographic hyphens.
+func roffEscape(s string) string {
+	return roffEscaper.Replace(s)
+}
+
+// roffLine escapes a line of text, a leading dot or quote making it a roff
+// request otherwise.
+func roffLine(s string) string {
+	s = roffEscape(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
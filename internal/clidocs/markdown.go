@@ -0,0 +1,67 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clidocs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// GenMarkdown writes the Markdown reference of the command, linking to the
+// pages of its parent and of its subcommands as written by GenTree.
+func GenMarkdown(w io.Writer, cmd *cobra.Command) error {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "## %s\n\n%s\n\n", cmd.CommandPath(), cmd.Short)
+
+	b.WriteString("### Synopsis\n\n")
+	fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(description(cmd)))
+	if cmd.Runnable() {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", cmd.UseLine())
+	}
+
+	if cmd.HasExample() {
+		fmt.Fprintf(&b, "### Examples\n\n```\n%s\n```\n\n", strings.TrimRight(cmd.Example, "\n"))
+	}
+
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(&b, "### Options inherited from parent commands\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		parent := cmd.Parent()
+		seeAlso = append(seeAlso, fmt.Sprintf("* [%s](%s.md) - %s", parent.CommandPath(), fileName(parent, "_"), parent.Short))
+	}
+	for _, c := range subcommands(cmd) {
+		seeAlso = append(seeAlso, fmt.Sprintf("* [%s](%s.md) - %s", c.CommandPath(), fileName(c, "_"), c.Short))
+	}
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&b, "### SEE ALSO\n\n%s\n", strings.Join(seeAlso, "\n"))
+	}
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("error while writing Markdown reference of %s: %v", cmd.CommandPath(), err)
+	}
+	return nil
+}
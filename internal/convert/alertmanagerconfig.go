@@ -0,0 +1,64 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringv1beta1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// AlertmanagerConfig rewrites every v1alpha1 AlertmanagerConfig found in the
+// given manifest stream (YAML or JSON) to v1beta1. Documents of any other
+// kind are kept as is. The result is encoded in the requested format,
+// FormatAuto keeping the format of the input. It returns the resulting stream
+// and whether at least one document has been converted.
+func AlertmanagerConfig(data []byte, format Format) ([]byte, bool, error) {
+	return convertDocuments(data, format, func(typeMeta metav1.TypeMeta, doc []byte) ([]byte, bool, error) {
+		if typeMeta.Kind != monitoringv1alpha1.AlertmanagerConfigKind || typeMeta.APIVersion != monitoringv1alpha1.SchemeGroupVersion.String() {
+			return nil, false, nil
+		}
+
+		b, err := convertAlertmanagerConfig(doc)
+		return b, err == nil, err
+	})
+}
+
+func convertAlertmanagerConfig(doc []byte) ([]byte, error) {
+	var src monitoringv1alpha1.AlertmanagerConfig
+	if err := yaml.UnmarshalStrict(doc, &src); err != nil {
+		return nil, err
+	}
+
+	var dst monitoringv1beta1.AlertmanagerConfig
+	if err := dst.ConvertFrom(&src); err != nil {
+		return nil, err
+	}
+	dst.TypeMeta = metav1.TypeMeta{
+		Kind:       monitoringv1beta1.AlertmanagerConfigKind,
+		APIVersion: monitoringv1beta1.SchemeGroupVersion.String(),
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&dst)
+	if err != nil {
+		return nil, err
+	}
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+
+	return yaml.Marshal(obj)
+}
@@ -0,0 +1,332 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertmanagerConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		format    Format
+		expected  string
+		converted bool
+		shouldErr bool
+	}{
+		{
+			name: "NoAlertmanagerConfig",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+		},
+		{
+			name: "AlreadyV1beta1",
+			input: `apiVersion: monitoring.coreos.com/v1beta1
+kind: AlertmanagerConfig
+metadata:
+  name: test
+`,
+			expected: `apiVersion: monitoring.coreos.com/v1beta1
+kind: AlertmanagerConfig
+metadata:
+  name: test
+`,
+		},
+		{
+			name: "ConvertMatchers",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+apiVersion: monitoring.coreos.com/v1alpha1
+kind: AlertmanagerConfig
+metadata:
+  name: test
+  namespace: default
+spec:
+  route:
+    receiver: webhook
+    matchers:
+    - name: severity
+      value: critical|warning
+      regex: true
+  receivers:
+  - name: webhook
+    webhookConfigs:
+    - url: http://example.com
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+apiVersion: monitoring.coreos.com/v1beta1
+kind: AlertmanagerConfig
+metadata:
+  name: test
+  namespace: default
+spec:
+  route:
+    receiver: webhook
+    matchers:
+    - name: severity
+      value: critical|warning
+      matchType: =~
+  receivers:
+  - name: webhook
+    webhookConfigs:
+    - url: http://example.com
+`,
+			converted: true,
+		},
+		{
+			name: "PreserveCommentsAndIndentation",
+			input: `# Routing for the payments team.
+apiVersion: monitoring.coreos.com/v1alpha1
+kind: AlertmanagerConfig
+metadata:
+  name: payments # owned by team payments
+  labels:
+    team: payments
+spec:
+  route:
+    # Critical alerts only.
+    matchers:
+      - name: severity
+        value: critical
+    receiver: "pager"
+  receivers:
+    - name: "pager"
+`,
+			expected: `# Routing for the payments team.
+apiVersion: monitoring.coreos.com/v1beta1
+kind: AlertmanagerConfig
+metadata:
+  name: payments # owned by team payments
+  labels:
+    team: payments
+spec:
+  route:
+    # Critical alerts only.
+    matchers:
+      - name: severity
+        value: critical
+        matchType: =
+    receiver: "pager"
+  receivers:
+    - name: "pager"
+`,
+			converted: true,
+		},
+		{
+			name: "JSONList",
+			input: `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "apiVersion": "monitoring.coreos.com/v1alpha1",
+      "kind": "AlertmanagerConfig",
+      "metadata": {"name": "test"},
+      "spec": {"route": {"receiver": "null"}, "receivers": [{"name": "null"}]}
+    },
+    {
+      "apiVersion": "v1",
+      "kind": "ConfigMap",
+      "metadata": {"name": "test"}
+    }
+  ]
+}`,
+			expected: `{
+  "apiVersion": "v1",
+  "items": [
+    {
+      "apiVersion": "monitoring.coreos.com/v1beta1",
+      "kind": "AlertmanagerConfig",
+      "metadata": {
+        "name": "test"
+      },
+      "spec": {
+        "receivers": [
+          {
+            "name": "null"
+          }
+        ],
+        "route": {
+          "receiver": "null"
+        }
+      }
+    },
+    {
+      "apiVersion": "v1",
+      "kind": "ConfigMap",
+      "metadata": {
+        "name": "test"
+      }
+    }
+  ],
+  "kind": "List"
+}
+`,
+			converted: true,
+		},
+		{
+			name: "JSONSingleItemList",
+			input: `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "apiVersion": "monitoring.coreos.com/v1alpha1",
+      "kind": "AlertmanagerConfig",
+      "metadata": {"name": "test"},
+      "spec": {"route": {"receiver": "null"}, "receivers": [{"name": "null"}]}
+    }
+  ]
+}`,
+			expected: `{
+  "apiVersion": "v1",
+  "items": [
+    {
+      "apiVersion": "monitoring.coreos.com/v1beta1",
+      "kind": "AlertmanagerConfig",
+      "metadata": {
+        "name": "test"
+      },
+      "spec": {
+        "receivers": [
+          {
+            "name": "null"
+          }
+        ],
+        "route": {
+          "receiver": "null"
+        }
+      }
+    }
+  ],
+  "kind": "List"
+}
+`,
+			converted: true,
+		},
+		{
+			name: "JSONArray",
+			input: `[
+  {
+    "apiVersion": "monitoring.coreos.com/v1alpha1",
+    "kind": "AlertmanagerConfig",
+    "metadata": {"name": "test"},
+    "spec": {"route": {"receiver": "null"}, "receivers": [{"name": "null"}]}
+  },
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "test"}}
+]`,
+			expected: `[
+  {
+    "apiVersion": "monitoring.coreos.com/v1beta1",
+    "kind": "AlertmanagerConfig",
+    "metadata": {
+      "name": "test"
+    },
+    "spec": {
+      "receivers": [
+        {
+          "name": "null"
+        }
+      ],
+      "route": {
+        "receiver": "null"
+      }
+    }
+  },
+  {
+    "apiVersion": "v1",
+    "kind": "ConfigMap",
+    "metadata": {
+      "name": "test"
+    }
+  }
+]
+`,
+			converted: true,
+		},
+		{
+			name:   "JSONToYAML",
+			input:  `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "test"}}`,
+			format: FormatYAML,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+		},
+		{
+			name: "YAMLToJSON",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+			format: FormatJSON,
+			expected: `{
+  "apiVersion": "v1",
+  "kind": "ConfigMap",
+  "metadata": {
+    "name": "test"
+  }
+}
+`,
+		},
+		{
+			name: "UnknownField",
+			input: `apiVersion: monitoring.coreos.com/v1alpha1
+kind: AlertmanagerConfig
+metadata:
+  name: test
+spec:
+  unknown: true
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, converted, err := AlertmanagerConfig([]byte(tc.input), tc.format)
+			if tc.shouldErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.converted, converted)
+			assert.Equal(t, tc.expected, string(out))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestAlertmanagerConfigGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden.yaml") {
+			continue
+		}
+
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			out, _, err := AlertmanagerConfig(data, FormatAuto)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			golden := strings.TrimSuffix(input, ".yaml") + ".golden.yaml"
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, out, 0o644))
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, string(expected), string(out))
+		})
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+type Format string
+
+const (
+	// FormatAuto keeps the format of the input.
+	FormatAuto Format = ""
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// DetectFormat returns the format of a manifest stream, JSON streams
+// starting with an object or an array.
+func DetectFormat(data []byte) Format {
+	if isJSON(data) {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatAuto, FormatYAML, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q", s)
+	}
+}
+
+const documentSeparator = "---\n"
+
+// jsonWrapper is the container of the objects of a JSON stream, restored
+// when the stream is written back in JSON.
+type jsonWrapper int
+
+const (
+	// wrapNone writes a single object as is, and several objects in a List.
+	wrapNone jsonWrapper = iota
+	// wrapList writes the objects in a List, even a single one.
+	wrapList
+	// wrapArray writes the objects in an array.
+	wrapArray
+)
+
+// document is a single Kubernetes object from a manifest stream, stored as
+// it was read so that untouched objects can be written back verbatim.
+type document struct {
+	// header holds the directives and the "---" marker line which precede
+	// the document in a YAML stream.
+	header []byte
+	data   []byte
+	// footer holds the "..." marker line which ends the document, and
+	// anything following it up to the next document.
+	footer []byte
+	isJSON bool
+}
+
+// isEmpty returns true if the document contains only comments or blank
+// lines.
+func (d document) isEmpty() bool {
+	for _, line := range bytes.Split(d.data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		return false
+	}
+
+	return true
+}
+
+// convertDocuments applies convertFn to each document of a manifest stream
+// (YAML or JSON) and encodes the result in the requested format, FormatAuto
+// keeping the format of the input. convertFn returns false for the documents
+// it leaves untouched, which are kept as is. The formatting of the converted
+// YAML documents is preserved where their content didn't change. It returns
+// the resulting stream and whether at least one document has been converted.
+func convertDocuments(data []byte, format Format, convertFn func(typeMeta metav1.TypeMeta, doc []byte) ([]byte, bool, error)) ([]byte, bool, error) {
+	docs, inputFormat, wrapper, err := decodeDocuments(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if format == FormatAuto {
+		format = inputFormat
+	}
+
+	var converted bool
+	for i, doc := range docs {
+		if doc.isEmpty() {
+			continue
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc.data, &typeMeta); err != nil {
+			return nil, false, fmt.Errorf("error while decoding document %d: %w", i, err)
+		}
+
+		b, ok, err := convertFn(typeMeta, doc.data)
+		if err != nil {
+			return nil, false, fmt.Errorf("error while converting document %d: %w", i, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if !doc.isJSON {
+			b, err = preserveFormatting(doc.data, b)
+			if err != nil {
+				return nil, false, fmt.Errorf("error while encoding document %d: %w", i, err)
+			}
+		}
+
+		docs[i].data = b
+		docs[i].isJSON = false
+		converted = true
+	}
+
+	out, err := encodeDocuments(docs, format, wrapper)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return out, converted, nil
+}
+
+// decodeDocuments splits a manifest stream into documents. The stream can
+// either be a multi-document YAML stream or a JSON stream, in which case
+// objects of kind List and arrays are flattened into their items, the
+// returned wrapper recording which of them contained the items.
+func decodeDocuments(data []byte) ([]document, Format, jsonWrapper, error) {
+	if isJSON(data) {
+		docs, wrapper, err := decodeJSONDocuments(data)
+		return docs, FormatJSON, wrapper, err
+	}
+
+	return decodeYAMLDocuments(data), FormatYAML, wrapNone, nil
+}
+
+func isJSON(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// decodeYAMLDocuments splits a YAML stream on document markers. The
+// concatenation of the headers and data of the returned documents is equal to
+// the input, including empty documents, so that the stream can be written
+// back faithfully.
+func decodeYAMLDocuments(data []byte) []document {
+	var (
+		docs    []document
+		current document
+		inDoc   bool
+	)
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for _, line := range lines {
+		switch {
+		case isDocumentStart(line):
+			if inDoc || len(current.footer) > 0 {
+				docs = append(docs, current)
+				current = document{}
+			}
+			current.header = append(current.header, line...)
+			inDoc = true
+
+		case isDocumentEnd(line) || len(current.footer) > 0:
+			current.footer = append(current.footer, line...)
+			inDoc = false
+
+		case !inDoc && isHeaderLine(line):
+			// Directives are only allowed before the document start marker.
+			current.header = append(current.header, line...)
+
+		default:
+			current.data = append(current.data, line...)
+			inDoc = true
+		}
+	}
+
+	if len(current.header) > 0 || len(current.data) > 0 || len(current.footer) > 0 {
+		docs = append(docs, current)
+	}
+
+	return docs
+}
+
+func isHeaderLine(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) == 0 || trimmed[0] == '#' || trimmed[0] == '%'
+}
+
+func isDocumentEnd(line []byte) bool {
+	return bytes.Equal(bytes.TrimRight(line, " \t\r\n"), []byte("..."))
+}
+
+func isDocumentStart(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+
+	rest := line[3:]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r'
+}
+
+func decodeJSONDocuments(data []byte) ([]document, jsonWrapper, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	var (
+		docs    []document
+		wrapper = wrapNone
+	)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, wrapNone, fmt.Errorf("error while reading JSON document: %w", err)
+		}
+
+		if isJSONArray(raw) {
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, wrapNone, fmt.Errorf("error while reading JSON document: %w", err)
+			}
+
+			wrapper = wrapArray
+			for _, item := range items {
+				docs = append(docs, document{data: item, isJSON: true})
+			}
+			continue
+		}
+
+		var list struct {
+			Kind  string            `json:"kind"`
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, wrapNone, fmt.Errorf("error while reading JSON document: %w", err)
+		}
+
+		if list.Kind != "List" {
+			docs = append(docs, document{data: raw, isJSON: true})
+			continue
+		}
+
+		wrapper = wrapList
+		for _, item := range list.Items {
+			docs = append(docs, document{data: item, isJSON: true})
+		}
+	}
+
+	return docs, wrapper, nil
+}
+
+func isJSONArray(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// encodeDocuments writes the documents in the requested format. Several
+// documents are written as a multi-document YAML stream, or in JSON in the
+// wrapper of the input, a List object by default.
+func encodeDocuments(docs []document, format Format, wrapper jsonWrapper) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return encodeJSONDocuments(docs, wrapper)
+	default:
+		return encodeYAMLDocuments(docs)
+	}
+}
+
+func encodeYAMLDocuments(docs []document) ([]byte, error) {
+	var out bytes.Buffer
+	for i, doc := range docs {
+		if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteByte('\n')
+		}
+
+		switch {
+		case len(doc.header) > 0:
+			out.Write(doc.header)
+		case i > 0:
+			out.WriteString(documentSeparator)
+		}
+
+		b := doc.data
+		if doc.isJSON {
+			var err error
+			b, err = yaml.JSONToYAML(doc.data)
+			if err != nil {
+				return nil, fmt.Errorf("error while encoding document %d: %w", i, err)
+			}
+		}
+
+		out.Write(b)
+
+		if len(doc.footer) > 0 {
+			if len(b) > 0 && !bytes.HasSuffix(b, []byte("\n")) {
+				out.WriteByte('\n')
+			}
+			out.Write(doc.footer)
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+func encodeJSONDocuments(docs []document, wrapper jsonWrapper) ([]byte, error) {
+	items := make([]json.RawMessage, 0, len(docs))
+	for i, doc := range docs {
+		if doc.isEmpty() {
+			continue
+		}
+
+		b := doc.data
+		if !doc.isJSON {
+			var err error
+			b, err = yaml.YAMLToJSON(doc.data)
+			if err != nil {
+				return nil, fmt.Errorf("error while encoding document %d: %w", i, err)
+			}
+		}
+
+		items = append(items, b)
+	}
+
+	var v any
+	switch {
+	case wrapper == wrapArray:
+		v = items
+	case wrapper == wrapNone && len(items) == 1:
+		v = items[0]
+	default:
+		v = map[string]any{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
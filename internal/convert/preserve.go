@@ -0,0 +1,143 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"bytes"
+	"regexp"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// compactSeqRegexp matches a sequence which isn't indented relatively to its
+// parent key, which is the style used by kubectl and most Kubernetes tooling.
+var compactSeqRegexp = regexp.MustCompile(`(?m)^( *)[^\s#-][^\n]*:\n( *#[^\n]*\n)*( *)- `)
+
+// preserveFormatting re-encodes updated, the result of a map-based
+// conversion of original, so that the portions of the document which haven't
+// changed keep the key order, comments and scalar styles of original.
+func preserveFormatting(original, updated []byte) ([]byte, error) {
+	var orig, upd yaml.Node
+	if err := yaml.Unmarshal(original, &orig); err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(updated, &upd); err != nil {
+		return nil, err
+	}
+
+	if len(orig.Content) != 1 || len(upd.Content) != 1 {
+		return updated, nil
+	}
+	orig.Content[0] = mergeNode(orig.Content[0], upd.Content[0])
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if isCompactSeq(original) {
+		encoder.CompactSeqIndent()
+	}
+
+	if err := encoder.Encode(&orig); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func isCompactSeq(data []byte) bool {
+	for _, m := range compactSeqRegexp.FindAllSubmatch(data, -1) {
+		if len(m[1]) == len(m[3]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeNode returns a node with the content of upd, reusing the nodes of orig
+// wherever the content is identical.
+func mergeNode(orig, upd *yaml.Node) *yaml.Node {
+	if orig.Kind != upd.Kind {
+		copyComments(orig, upd)
+		return upd
+	}
+
+	switch orig.Kind {
+	case yaml.ScalarNode:
+		if orig.Value == upd.Value && orig.ShortTag() == upd.ShortTag() {
+			return orig
+		}
+
+		copyComments(orig, upd)
+		return upd
+
+	case yaml.MappingNode:
+		updIndex := make(map[string]int, len(upd.Content)/2)
+		for i := 0; i+1 < len(upd.Content); i += 2 {
+			updIndex[upd.Content[i].Value] = i
+		}
+
+		content := make([]*yaml.Node, 0, len(upd.Content))
+		seen := make(map[string]struct{}, len(updIndex))
+		for i := 0; i+1 < len(orig.Content); i += 2 {
+			key := orig.Content[i].Value
+			j, found := updIndex[key]
+			if !found {
+				continue
+			}
+
+			seen[key] = struct{}{}
+			content = append(content, orig.Content[i], mergeNode(orig.Content[i+1], upd.Content[j+1]))
+		}
+
+		for i := 0; i+1 < len(upd.Content); i += 2 {
+			if _, found := seen[upd.Content[i].Value]; found {
+				continue
+			}
+
+			content = append(content, upd.Content[i], upd.Content[i+1])
+		}
+
+		orig.Content = content
+		return orig
+
+	case yaml.SequenceNode:
+		content := make([]*yaml.Node, 0, len(upd.Content))
+		for i, n := range upd.Content {
+			if i < len(orig.Content) {
+				n = mergeNode(orig.Content[i], n)
+			}
+
+			content = append(content, n)
+		}
+
+		orig.Content = content
+		return orig
+
+	default:
+		return upd
+	}
+}
+
+func copyComments(from, to *yaml.Node) {
+	to.HeadComment = from.HeadComment
+	to.LineComment = from.LineComment
+	to.FootComment = from.FootComment
+}
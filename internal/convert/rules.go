@@ -0,0 +1,195 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// RuleTarget is the rule evaluator which the rules are converted for.
+type RuleTarget string
+
+const (
+	RuleTargetPrometheus RuleTarget = "prometheus"
+	RuleTargetThanos     RuleTarget = "thanos"
+)
+
+const partialResponseStrategyField = "partial_response_strategy"
+
+func ParseRuleTarget(s string) (RuleTarget, error) {
+	switch t := RuleTarget(strings.ToLower(s)); t {
+	case RuleTargetPrometheus, RuleTargetThanos:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown rule target %q, must be one of: prometheus, thanos", s)
+	}
+}
+
+// RuleOptions configures the conversion of rules between Prometheus and
+// Thanos Ruler.
+type RuleOptions struct {
+	Target RuleTarget
+	// PartialResponseStrategy is set on the groups which don't define one
+	// when converting to Thanos, one of: warn, abort. The groups are left
+	// untouched when empty.
+	PartialResponseStrategy string
+	// TenantLabel is the name of the label identifying the tenant of the
+	// rules. It is removed from the rules when converting to Prometheus.
+	TenantLabel string
+	// Tenant is the value of the TenantLabel added to the rules when
+	// converting to Thanos.
+	Tenant string
+}
+
+// Validate checks the consistency of the options.
+func (o RuleOptions) Validate() error {
+	switch strings.ToLower(o.PartialResponseStrategy) {
+	case "", "warn", "abort":
+	default:
+		return fmt.Errorf("unknown partial response strategy %q, must be one of: warn, abort", o.PartialResponseStrategy)
+	}
+
+	if o.Target == RuleTargetThanos && (o.TenantLabel == "") != (o.Tenant == "") {
+		return fmt.Errorf("the tenant label and the tenant must be set together")
+	}
+
+	return nil
+}
+
+// Rules converts the rule groups found in the given manifest stream (YAML or
+// JSON) for evaluation by the target of the options. Both PrometheusRule
+// objects and plain rule files are converted:
+//
+//   - For Thanos, the partial response strategy and the tenant label are set
+//     according to the options.
+//   - For Prometheus, the partial response strategy, which Prometheus
+//     rejects in rule files, and the tenant label are removed.
+//
+// Documents of any other kind are kept as is. The result is encoded in the
+// requested format, FormatAuto keeping the format of the input. It returns
+// the resulting stream and whether at least one document has been converted.
+func Rules(data []byte, format Format, opts RuleOptions) ([]byte, bool, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	return convertDocuments(data, format, func(typeMeta metav1.TypeMeta, doc []byte) ([]byte, bool, error) {
+		var path []string
+		switch {
+		case typeMeta.Kind == monitoringv1.PrometheusRuleKind && typeMeta.APIVersion == monitoringv1.SchemeGroupVersion.String():
+			path = []string{"spec", "groups"}
+		case typeMeta.Kind == "" && typeMeta.APIVersion == "":
+			path = []string{"groups"}
+		default:
+			return nil, false, nil
+		}
+
+		var obj map[string]any
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, false, err
+		}
+
+		groups, found, err := unstructured.NestedFieldNoCopy(obj, path...)
+		if err != nil || !found {
+			return nil, false, err
+		}
+
+		groupList, ok := groups.([]any)
+		if !ok {
+			return nil, false, fmt.Errorf("%s must be a list", strings.Join(path, "."))
+		}
+
+		var changed bool
+		for _, g := range groupList {
+			group, ok := g.(map[string]any)
+			if !ok {
+				return nil, false, fmt.Errorf("rule groups must be objects")
+			}
+
+			if convertRuleGroup(group, opts) {
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil, false, nil
+		}
+
+		b, err := yaml.Marshal(obj)
+		return b, err == nil, err
+	})
+}
+
+// convertRuleGroup converts a rule group in place and reports whether it
+// changed.
+func convertRuleGroup(group map[string]any, opts RuleOptions) bool {
+	var changed bool
+
+	switch opts.Target {
+	case RuleTargetThanos:
+		if _, found := group[partialResponseStrategyField]; !found && opts.PartialResponseStrategy != "" {
+			group[partialResponseStrategyField] = strings.ToLower(opts.PartialResponseStrategy)
+			changed = true
+		}
+	case RuleTargetPrometheus:
+		if _, found := group[partialResponseStrategyField]; found {
+			delete(group, partialResponseStrategyField)
+			changed = true
+		}
+	}
+
+	if opts.TenantLabel == "" {
+		return changed
+	}
+
+	rules, _ := group["rules"].([]any)
+	for _, r := range rules {
+		rule, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		labels, _ := rule["labels"].(map[string]any)
+
+		switch opts.Target {
+		case RuleTargetThanos:
+			if labels == nil {
+				labels = map[string]any{}
+				rule["labels"] = labels
+			}
+			if labels[opts.TenantLabel] != opts.Tenant {
+				labels[opts.TenantLabel] = opts.Tenant
+				changed = true
+			}
+		case RuleTargetPrometheus:
+			if _, found := labels[opts.TenantLabel]; !found {
+				continue
+			}
+			delete(labels, opts.TenantLabel)
+			if len(labels) == 0 {
+				delete(rule, "labels")
+			}
+			changed = true
+		}
+	}
+
+	return changed
+}
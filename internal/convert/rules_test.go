@@ -0,0 +1,167 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		opts      RuleOptions
+		expected  string
+		converted bool
+		shouldErr bool
+	}{
+		{
+			name: "NoRules",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+			opts: RuleOptions{Target: RuleTargetThanos, PartialResponseStrategy: "warn"},
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+`,
+		},
+		{
+			name: "PrometheusRuleToThanos",
+			input: `apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: test
+spec:
+  groups:
+  - name: recording
+    rules:
+    - record: job:up:sum
+      expr: sum by (job) (up)
+  - name: alerting
+    partial_response_strategy: abort
+    rules:
+    - alert: Down
+      expr: up == 0
+      labels:
+        severity: critical
+`,
+			opts: RuleOptions{Target: RuleTargetThanos, PartialResponseStrategy: "Warn", TenantLabel: "tenant_id", Tenant: "team-a"},
+			expected: `apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: test
+spec:
+  groups:
+  - name: recording
+    rules:
+    - record: job:up:sum
+      expr: sum by (job) (up)
+      labels:
+        tenant_id: team-a
+    partial_response_strategy: warn
+  - name: alerting
+    partial_response_strategy: abort
+    rules:
+    - alert: Down
+      expr: up == 0
+      labels:
+        severity: critical
+        tenant_id: team-a
+`,
+			converted: true,
+		},
+		{
+			name: "RuleFileToPrometheus",
+			input: `groups:
+  # Evaluated by the ruler.
+  - name: alerting
+    partial_response_strategy: warn
+    rules:
+      - alert: Down
+        expr: up == 0
+        labels:
+          tenant_id: team-a
+      - alert: Slow
+        expr: rate(errors_total[5m]) > 1
+        labels:
+          severity: warning
+          tenant_id: team-a
+`,
+			opts: RuleOptions{Target: RuleTargetPrometheus, TenantLabel: "tenant_id"},
+			expected: `groups:
+  # Evaluated by the ruler.
+  - name: alerting
+    rules:
+      - alert: Down
+        expr: up == 0
+      - alert: Slow
+        expr: rate(errors_total[5m]) > 1
+        labels:
+          severity: warning
+`,
+			converted: true,
+		},
+		{
+			name: "AlreadyConverted",
+			input: `groups:
+- name: alerting
+  rules:
+  - alert: Down
+    expr: up == 0
+`,
+			opts: RuleOptions{Target: RuleTargetPrometheus, TenantLabel: "tenant_id"},
+			expected: `groups:
+- name: alerting
+  rules:
+  - alert: Down
+    expr: up == 0
+`,
+		},
+		{
+			name:      "InvalidPartialResponseStrategy",
+			input:     "groups: []\n",
+			opts:      RuleOptions{Target: RuleTargetThanos, PartialResponseStrategy: "ignore"},
+			shouldErr: true,
+		},
+		{
+			name:      "TenantWithoutLabel",
+			input:     "groups: []\n",
+			opts:      RuleOptions{Target: RuleTargetThanos, Tenant: "team-a"},
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, converted, err := Rules([]byte(tc.input), FormatAuto, tc.opts)
+			if tc.shouldErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.converted, converted)
+			assert.Equal(t, tc.expected, string(out))
+		})
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Group is the API group of the Prometheus Operator resources.
+const Group = "monitoring.coreos.com"
+
+// ValidationError is returned for an object which is invalid against the
+// schema of its CRD.
+type ValidationError struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Errors    field.ErrorList
+}
+
+func (e *ValidationError) Error() string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + name
+	}
+
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, "  "+err.Error())
+	}
+	return fmt.Sprintf("%s %s is invalid:\n%s", e.Kind, name, strings.Join(msgs, "\n"))
+}
+
+// Validator validates the Prometheus Operator objects against the CRDs
+// installed in the cluster, before they're applied. The CRDs are fetched once.
+type Validator struct {
+	client apiextensions.Interface
+	crds   map[string]*apiextensionsv1.CustomResourceDefinition
+}
+
+// NewValidator returns a validator reading the CRDs with the client.
+func NewValidator(client apiextensions.Interface) *Validator {
+	return &Validator{
+		client: client,
+		crds:   map[string]*apiextensionsv1.CustomResourceDefinition{},
+	}
+}
+
+// ValidateObjects validates the objects, given as typed objects, apply
+// configurations or unstructured objects. The objects outside of the
+// monitoring.coreos.com group are left to the API server, as are the objects
+// whose CRD isn't installed.
+func (v *Validator) ValidateObjects(ctx context.Context, objs ...any) error {
+	for _, obj := range objs {
+		if err := v.validateObject(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateObject(ctx context.Context, obj any) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("error while encoding object: %w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("error while decoding object: %w", err)
+	}
+
+	// Unset optional objects, such as the PrometheusAgent of a stack
+	// running a Prometheus.
+	if fields == nil {
+		return nil
+	}
+
+	apiVersion, _ := fields["apiVersion"].(string)
+	kind, _ := fields["kind"].(string)
+	if !strings.HasPrefix(apiVersion, Group+"/") {
+		return nil
+	}
+
+	crd, err := v.crd(ctx, kind)
+	if err != nil || crd == nil {
+		return err
+	}
+
+	errs, err := Validate(crd, fields)
+	if err != nil {
+		return err
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	metadata, _ := fields["metadata"].(map[string]any)
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	return &ValidationError{Kind: kind, Namespace: namespace, Name: name, Errors: errs}
+}
+
+// crd returns the installed CRD of the kind, nil when it isn't installed.
+func (v *Validator) crd(ctx context.Context, kind string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	name, err := NameForKind(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if crd, found := v.crds[name]; found {
+		return crd, nil
+	}
+
+	crd, err := v.client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("error while getting CRD %s: %w", name, err)
+		}
+		crd = nil
+	}
+
+	v.crds[name] = crd
+	return crd, nil
+}
+
+// NameForKind returns the name of the monitoring CRD of a kind.
+func NameForKind(kind string) (string, error) {
+	singular := strings.ToLower(kind)
+	for _, name := range List {
+		plural := strings.TrimSuffix(name, "."+Group)
+		if plural == singular+"s" || plural == singular+"es" {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown kind %s of the %s group", kind, Group)
+}
+
+// Validate validates an object against the schema of its API version in the
+// CRD, client-side. It covers the structural part of the schema: the types,
+// the unknown fields, the required fields, the enums, the patterns and the
+// bounds. The CEL validation rules and the formats are left to the API server.
+func Validate(crd *apiextensionsv1.CustomResourceDefinition, obj map[string]any) (field.ErrorList, error) {
+	apiVersion, _ := obj["apiVersion"].(string)
+	group, version, found := strings.Cut(apiVersion, "/")
+	if !found || group != crd.Spec.Group {
+		return nil, fmt.Errorf("API version %q doesn't belong to CRD %s", apiVersion, crd.Name)
+	}
+
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version || !v.Served {
+			continue
+		}
+
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			return nil, fmt.Errorf("CRD %s has no schema for version %s", crd.Name, version)
+		}
+
+		// The object metadata is validated by the API server, the CRD
+		// schemas don't describe it.
+		schema := v.Schema.OpenAPIV3Schema.DeepCopy()
+		delete(schema.Properties, "metadata")
+		obj = maps.Clone(obj)
+		delete(obj, "metadata")
+
+		return validateValue(nil, schema, obj), nil
+	}
+
+	return nil, fmt.Errorf("version %s isn't served by CRD %s", version, crd.Name)
+}
+
+func validateValue(path *field.Path, schema *apiextensionsv1.JSONSchemaProps, value any) field.ErrorList {
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return field.ErrorList{field.Invalid(path, nil, "must not be null")}
+	}
+
+	if schema.XIntOrString {
+		switch value.(type) {
+		case string, int64, float64, int:
+			return nil
+		}
+		return field.ErrorList{field.TypeInvalid(path, value, "must be an integer or a string")}
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		return field.ErrorList{field.TypeInvalid(path, value, fmt.Sprintf("must be of type %s", schema.Type))}
+	}
+
+	var errs field.ErrorList
+
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		var allowed []string
+		for _, e := range schema.Enum {
+			allowed = append(allowed, strings.Trim(string(e.Raw), `"`))
+		}
+		errs = append(errs, field.NotSupported(path, value, allowed))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		errs = append(errs, validateObject(path, schema, v)...)
+
+	case []any:
+		if schema.MinItems != nil && int64(len(v)) < *schema.MinItems {
+			errs = append(errs, field.Invalid(path, len(v), fmt.Sprintf("must have at least %d items", *schema.MinItems)))
+		}
+		if schema.MaxItems != nil && int64(len(v)) > *schema.MaxItems {
+			errs = append(errs, field.TooMany(path, len(v), int(*schema.MaxItems)))
+		}
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range v {
+				errs = append(errs, validateValue(path.Index(i), schema.Items.Schema, item)...)
+			}
+		}
+
+	case string:
+		if schema.MinLength != nil && int64(len(v)) < *schema.MinLength {
+			errs = append(errs, field.Invalid(path, v, fmt.Sprintf("must be at least %d characters long", *schema.MinLength)))
+		}
+		if schema.MaxLength != nil && int64(len(v)) > *schema.MaxLength {
+			errs = append(errs, field.TooLong(path, v, int(*schema.MaxLength)))
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(v) {
+				errs = append(errs, field.Invalid(path, v, fmt.Sprintf("must match the pattern %s", schema.Pattern)))
+			}
+		}
+
+	default:
+		n, ok := toFloat(v)
+		if !ok {
+			break
+		}
+		if schema.Minimum != nil && (n < *schema.Minimum || (schema.ExclusiveMinimum && n == *schema.Minimum)) {
+			errs = append(errs, field.Invalid(path, v, bound("greater", schema.ExclusiveMinimum, *schema.Minimum)))
+		}
+		if schema.Maximum != nil && (n > *schema.Maximum || (schema.ExclusiveMaximum && n == *schema.Maximum)) {
+			errs = append(errs, field.Invalid(path, v, bound("less", schema.ExclusiveMaximum, *schema.Maximum)))
+		}
+	}
+
+	return errs
+}
+
+func validateObject(path *field.Path, schema *apiextensionsv1.JSONSchemaProps, obj map[string]any) field.ErrorList {
+	var errs field.ErrorList
+
+	for _, name := range schema.Required {
+		if _, found := obj[name]; !found {
+			errs = append(errs, field.Required(child(path, name), ""))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(obj)) {
+		if prop, found := schema.Properties[name]; found {
+			errs = append(errs, validateValue(child(path, name), &prop, obj[name])...)
+			continue
+		}
+
+		switch {
+		case schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+			errs = append(errs, validateValue(child(path, name), schema.AdditionalProperties.Schema, obj[name])...)
+		case schema.AdditionalProperties != nil && schema.AdditionalProperties.Allows:
+		case schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields:
+		default:
+			errs = append(errs, field.Forbidden(child(path, name), "unknown field"))
+		}
+	}
+
+	return errs
+}
+
+// child returns the path of a field, starting a new path at the root.
+func child(path *field.Path, name string) *field.Path {
+	if path == nil {
+		return field.NewPath(name)
+	}
+	return path.Child(name)
+}
+
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	default:
+		return true
+	}
+}
+
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func inEnum(value any, enum []apiextensionsv1.JSON) bool {
+	for _, e := range enum {
+		var v any
+		if err := json.Unmarshal(e.Raw, &v); err != nil {
+			continue
+		}
+
+		if n, ok := toFloat(value); ok {
+			value = n
+		}
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func bound(comparison string, exclusive bool, limit float64) string {
+	if exclusive {
+		return fmt.Sprintf("must be %s than %v", comparison, limit)
+	}
+	return fmt.Sprintf("must be %s than or equal to %v", comparison, limit)
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func getPrometheusCRD() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheuses.monitoring.coreos.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "monitoring.coreos.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Prometheus", Plural: "prometheuses"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    "v1",
+					Served:  true,
+					Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"logLevel": {
+											Type: "string",
+											Enum: []apiextensionsv1.JSON{{Raw: []byte(`"debug"`)}, {Raw: []byte(`"info"`)}},
+										},
+										"replicas": {Type: "integer", Format: "int32"},
+										"shards":   {Type: "integer", Minimum: ptr.To(1.0)},
+										"retention": {
+											Type:    "string",
+											Pattern: "^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$",
+										},
+										"externalLabels": {
+											Type:                 "object",
+											AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+										},
+										"remoteWrite": {
+											Type: "array",
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+												Type:     "object",
+												Required: []string{"url"},
+												Properties: map[string]apiextensionsv1.JSONSchemaProps{
+													"url": {Type: "string", MinLength: ptr.To[int64](1)},
+												},
+											}},
+										},
+										"containers": {
+											Type: "array",
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+												Type:                   "object",
+												XPreserveUnknownFields: ptr.To(true),
+											}},
+										},
+										"maximumStartupDurationSeconds": {Type: "integer", XIntOrString: true},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		name           string
+		spec           map[string]any
+		expectedErrors []string
+	}
+
+	tests := []testCase{
+		{
+			name: "Valid",
+			spec: map[string]any{
+				"logLevel":       "debug",
+				"replicas":       int64(2),
+				"shards":         float64(1),
+				"retention":      "30d",
+				"externalLabels": map[string]any{"cluster": "eu-1"},
+				"remoteWrite":    []any{map[string]any{"url": "https://example.com"}},
+				"containers":     []any{map[string]any{"name": "prometheus", "args": []any{"--log.level=debug"}}},
+			},
+		},
+		{
+			name:           "UnknownField",
+			spec:           map[string]any{"logLevle": "debug"},
+			expectedErrors: []string{"spec.logLevle: Forbidden: unknown field"},
+		},
+		{
+			name:           "Enum",
+			spec:           map[string]any{"logLevel": "trace"},
+			expectedErrors: []string{`spec.logLevel: Unsupported value: "trace": supported values: "debug", "info"`},
+		},
+		{
+			name:           "Type",
+			spec:           map[string]any{"replicas": "3", "externalLabels": map[string]any{"cluster": int64(1)}},
+			expectedErrors: []string{"spec.externalLabels.cluster: Invalid value: 1: must be of type string", `spec.replicas: Invalid value: "3": must be of type integer`},
+		},
+		{
+			name:           "Bounds",
+			spec:           map[string]any{"shards": int64(0), "retention": "30 days"},
+			expectedErrors: []string{"spec.retention: Invalid value: \"30 days\": must match the pattern ^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$", "spec.shards: Invalid value: 0: must be greater than or equal to 1"},
+		},
+		{
+			name:           "ArrayItems",
+			spec:           map[string]any{"remoteWrite": []any{map[string]any{"url": "https://example.com"}, map[string]any{}}},
+			expectedErrors: []string{"spec.remoteWrite[1].url: Required value"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs, err := Validate(getPrometheusCRD(), map[string]any{
+				"apiVersion": "monitoring.coreos.com/v1",
+				"kind":       "Prometheus",
+				"metadata":   map[string]any{"name": "prometheus", "labels": map[string]any{"app": "prometheus"}},
+				"spec":       tc.spec,
+			})
+			require.NoError(t, err)
+
+			var messages []string
+			for _, e := range errs {
+				messages = append(messages, e.Error())
+			}
+			assert.Equal(t, tc.expectedErrors, messages)
+		})
+	}
+
+	_, err := Validate(getPrometheusCRD(), map[string]any{"apiVersion": "monitoring.coreos.com/v1alpha1", "kind": "Prometheus"})
+	require.Error(t, err)
+}
+
+func TestValidatorValidateObjects(t *testing.T) {
+	validator := NewValidator(apiextensionsfake.NewSimpleClientset(getPrometheusCRD()))
+
+	prometheus := monitoringv1.Prometheus("prometheus", "default").
+		WithSpec(monitoringv1.PrometheusSpec().WithReplicas(2))
+	require.NoError(t, validator.ValidateObjects(context.Background(), prometheus, corev1.Service("prometheus", "default")))
+
+	invalid := map[string]any{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "Prometheus",
+		"metadata":   map[string]any{"name": "prometheus", "namespace": "default"},
+		"spec":       map[string]any{"replicas": "two"},
+	}
+	err := validator.ValidateObjects(context.Background(), prometheus, invalid)
+	require.Error(t, err)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "Prometheus default/prometheus is invalid:\n  spec.replicas: Invalid value: \"two\": must be of type integer", err.Error())
+
+	// The objects whose CRD isn't installed are left to the API server.
+	require.NoError(t, validator.ValidateObjects(context.Background(), monitoringv1.ServiceMonitor("app", "default")))
+}
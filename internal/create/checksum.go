@@ -0,0 +1,272 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// ChecksumAnnotation holds the checksum of the manifest of an object, as
+// rendered and applied by poctl.
+const ChecksumAnnotation = "poctl.prometheus-operator.dev/spec-hash"
+
+// ChecksumStatus is the outcome of the verification of the checksum of an
+// object.
+type ChecksumStatus string
+
+const (
+	// ChecksumValid means that the object matches the manifest rendered from
+	// the recorded parameters of the stack.
+	ChecksumValid ChecksumStatus = "valid"
+	// ChecksumMissing means that the object doesn't exist, the stack has
+	// been partially applied or the object has been deleted.
+	ChecksumMissing ChecksumStatus = "missing"
+	// ChecksumOutdated means that the object has been applied from another
+	// manifest than the one rendered from the recorded parameters, an apply
+	// having been interrupted or the object being applied by another
+	// version of poctl.
+	ChecksumOutdated ChecksumStatus = "outdated"
+	// ChecksumTampered means that the fields applied by poctl have been
+	// changed since.
+	ChecksumTampered ChecksumStatus = "tampered"
+)
+
+// ObjectChecksum is the verification of the checksum of an object of the
+// stack.
+type ObjectChecksum struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Expected is the checksum of the manifest rendered from the recorded
+	// parameters of the stack.
+	Expected string
+	// Recorded is the checksum annotation of the live object.
+	Recorded string
+	// Computed is the checksum of the fields of the live object applied by
+	// poctl.
+	Computed string
+	Status   ChecksumStatus
+}
+
+// RunVerifyChecksums renders the stack with its recorded parameters and
+// verifies the checksums of the live objects: the checksum recorded on each
+// object must be the one of the rendered manifest, and must still match the
+// fields of the object applied by poctl.
+func RunVerifyChecksums(ctx context.Context, clientSets *k8sutil.ClientSets, stack string) ([]ObjectChecksum, error) {
+	namespace := metav1.NamespaceDefault
+
+	params, anchor, err := loadStackParameters(ctx, clientSets, namespace, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderStack(newStackOwner(anchor), namespace, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums []ObjectChecksum
+	for _, manifests := range rendered {
+		for _, obj := range manifests.Manifests() {
+			desired, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object type %T", obj)
+			}
+
+			live, err := k8sutil.ResourceClient(clientSets, desired.GroupVersionKind(), desired.GetNamespace()).
+				Get(ctx, desired.GetName(), metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("error while getting %s %s: %v", desired.GetKind(), desired.GetName(), err)
+			}
+
+			checksum, err := verifyChecksum(desired, live)
+			if err != nil {
+				return nil, err
+			}
+			checksums = append(checksums, checksum)
+		}
+	}
+
+	return checksums, nil
+}
+
+// verifyChecksum compares the live object, nil when it doesn't exist, with
+// the desired one.
+func verifyChecksum(desired, live *unstructured.Unstructured) (ObjectChecksum, error) {
+	c := ObjectChecksum{
+		Kind:      desired.GetKind(),
+		Namespace: desired.GetNamespace(),
+		Name:      desired.GetName(),
+		Expected:  desired.GetAnnotations()[ChecksumAnnotation],
+	}
+
+	if live == nil {
+		c.Status = ChecksumMissing
+		return c, nil
+	}
+
+	c.Recorded = live.GetAnnotations()[ChecksumAnnotation]
+
+	applied, ok := projectFields(desired.Object, live.Object).(map[string]any)
+	if !ok {
+		return c, fmt.Errorf("unexpected content of %s %s", c.Kind, c.Name)
+	}
+
+	var err error
+	if c.Computed, err = checksum(applied); err != nil {
+		return c, err
+	}
+
+	switch {
+	case c.Recorded != c.Expected:
+		c.Status = ChecksumOutdated
+	case c.Computed != c.Recorded:
+		c.Status = ChecksumTampered
+	default:
+		c.Status = ChecksumValid
+	}
+
+	return c, nil
+}
+
+// projectFields returns the fields of live which are set in desired, that is
+// the fields of a live object applied by poctl. Lists are projected item by
+// item when they have the same length, and kept whole otherwise.
+func projectFields(desired, live any) any {
+	switch d := desired.(type) {
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			return live
+		}
+
+		projected := map[string]any{}
+		for key := range d {
+			if v, found := l[key]; found {
+				projected[key] = projectFields(d[key], v)
+			}
+		}
+		return projected
+
+	case []any:
+		l, ok := live.([]any)
+		if !ok || len(l) != len(d) {
+			return live
+		}
+
+		projected := make([]any, len(l))
+		for i := range l {
+			projected[i] = projectFields(d[i], l[i])
+		}
+		return projected
+
+	default:
+		return live
+	}
+}
+
+// annotateChecksums sets the checksum annotation on the apply configurations
+// of the manifests, given as a pointer to a manifests struct. It is the last
+// step of the rendering of the manifests.
+func annotateChecksums(manifests any) error {
+	v := reflect.ValueOf(manifests).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+
+		var configs []reflect.Value
+		switch field.Kind() {
+		case reflect.Pointer:
+			configs = append(configs, field)
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				configs = append(configs, field.Index(j))
+			}
+		}
+
+		for _, config := range configs {
+			if config.IsNil() {
+				continue
+			}
+
+			if err := annotateChecksum(config); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func annotateChecksum(config reflect.Value) error {
+	meta, ok := config.Elem().FieldByName("ObjectMetaApplyConfiguration").Interface().(*applyConfigMetav1.ObjectMetaApplyConfiguration)
+	if !ok || meta == nil {
+		return fmt.Errorf("%s has no metadata", config.Type())
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(config.Interface())
+	if err != nil {
+		return fmt.Errorf("error while converting %s: %v", config.Type(), err)
+	}
+
+	sum, err := checksum(content)
+	if err != nil {
+		return err
+	}
+
+	// The builders may share the annotations between objects.
+	annotations := maps.Clone(meta.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ChecksumAnnotation] = sum
+	meta.Annotations = annotations
+
+	return nil
+}
+
+// checksum returns the SHA-256 checksum of the object, leaving out its
+// checksum annotation.
+func checksum(obj map[string]any) (string, error) {
+	u := (&unstructured.Unstructured{Object: obj}).DeepCopy()
+
+	annotations := u.GetAnnotations()
+	delete(annotations, ChecksumAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	u.SetAnnotations(annotations)
+
+	// The keys of the maps are sorted by the encoder.
+	data, err := json.Marshal(u.Object)
+	if err != nil {
+		return "", fmt.Errorf("error while encoding %s %s: %v", u.GetKind(), u.GetName(), err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
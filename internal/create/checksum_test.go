@@ -0,0 +1,100 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	profile, err := GetProfile("ha")
+	require.NoError(t, err)
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor(""), UID: "1234"}})
+	manifests, err := buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	require.NotEmpty(t, manifests.Prometheus.Annotations[ChecksumAnnotation])
+	require.NotEmpty(t, manifests.ServiceAccount.Annotations[ChecksumAnnotation])
+
+	var desired *unstructured.Unstructured
+	for _, obj := range manifests.Manifests() {
+		if u := obj.(*unstructured.Unstructured); u.GetKind() == "Prometheus" {
+			desired = u
+		}
+	}
+	require.NotNil(t, desired)
+
+	// The live object carries the fields set by the API server and the
+	// operator.
+	applied := func() *unstructured.Unstructured {
+		live := desired.DeepCopy()
+		live.SetResourceVersion("42")
+		live.SetUID("5678")
+		annotations := live.GetAnnotations()
+		annotations["kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+		live.SetAnnotations(annotations)
+		require.NoError(t, unstructured.SetNestedField(live.Object, "30s", "spec", "evaluationInterval"))
+		require.NoError(t, unstructured.SetNestedField(live.Object, map[string]any{"availableReplicas": int64(2)}, "status"))
+		return live
+	}
+
+	for _, tc := range []struct {
+		name     string
+		live     func() *unstructured.Unstructured
+		expected ChecksumStatus
+	}{
+		{
+			name:     "Valid",
+			live:     applied,
+			expected: ChecksumValid,
+		},
+		{
+			name:     "Missing",
+			live:     func() *unstructured.Unstructured { return nil },
+			expected: ChecksumMissing,
+		},
+		{
+			name: "Outdated",
+			live: func() *unstructured.Unstructured {
+				live := applied()
+				live.SetAnnotations(nil)
+				return live
+			},
+			expected: ChecksumOutdated,
+		},
+		{
+			name: "Tampered",
+			live: func() *unstructured.Unstructured {
+				live := applied()
+				require.NoError(t, unstructured.SetNestedField(live.Object, int64(1), "spec", "replicas"))
+				return live
+			},
+			expected: ChecksumTampered,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := verifyChecksum(desired, tc.live())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, c.Status)
+			assert.Equal(t, manifests.Prometheus.Annotations[ChecksumAnnotation], c.Expected)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+// RunDeleteStack deletes the stack created by RunCreateStack, the default
+// stack when the stack name is empty. The namespaced resources are
+// garbage-collected by Kubernetes once the anchor ConfigMap is deleted, the
+// cluster-scoped ones are deleted by label.
+func RunDeleteStack(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, stack string) error {
+	namespace := metav1.NamespaceDefault
+	anchorName := StackAnchor(stack)
+
+	anchor, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Get(ctx, anchorName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("stack anchor ConfigMap %s not found in namespace %s", anchorName, namespace)
+		}
+		return fmt.Errorf("error while getting stack anchor ConfigMap: %w", err)
+	}
+
+	if err := warnStrayObjects(ctx, logger, clientSets, namespace, anchorName, anchor.UID); err != nil {
+		return err
+	}
+
+	listOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", StackLabel, anchorName),
+	}
+
+	if err := clientSets.KClient.RbacV1().ClusterRoleBindings().DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions); err != nil {
+		return fmt.Errorf("error while deleting ClusterRoleBindings: %w", err)
+	}
+
+	if err := clientSets.KClient.RbacV1().ClusterRoles().DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions); err != nil {
+		return fmt.Errorf("error while deleting ClusterRoles: %w", err)
+	}
+
+	if err := deleteRoles(ctx, clientSets, listOptions); err != nil {
+		return err
+	}
+
+	err = clientSets.KClient.CoreV1().ConfigMaps(namespace).Delete(ctx, anchorName, metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationForeground),
+	})
+	if err != nil {
+		return fmt.Errorf("error while deleting stack anchor ConfigMap: %w", err)
+	}
+
+	return nil
+}
+
+// deleteRoles deletes the Roles and RoleBindings of a namespaced operator
+// install, which may live in any of the watched namespaces.
+func deleteRoles(ctx context.Context, clientSets *k8sutil.ClientSets, listOptions metav1.ListOptions) error {
+	roleBindings, err := clientSets.KClient.RbacV1().RoleBindings(metav1.NamespaceAll).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("error while listing RoleBindings: %w", err)
+	}
+	for _, rb := range roleBindings.Items {
+		if err := clientSets.KClient.RbacV1().RoleBindings(rb.Namespace).Delete(ctx, rb.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error while deleting RoleBinding %s/%s: %w", rb.Namespace, rb.Name, err)
+		}
+	}
+
+	roles, err := clientSets.KClient.RbacV1().Roles(metav1.NamespaceAll).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("error while listing Roles: %w", err)
+	}
+	for _, role := range roles.Items {
+		if err := clientSets.KClient.RbacV1().Roles(role.Namespace).Delete(ctx, role.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error while deleting Role %s/%s: %w", role.Namespace, role.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// warnStrayObjects logs the namespaced resources labeled as part of the stack
+// which aren't owned by the anchor, and so won't be garbage-collected with it.
+func warnStrayObjects(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, namespace, anchorName string, uid types.UID) error {
+	listOptions := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", StackLabel, anchorName),
+	}
+
+	var objects []metav1.Object
+
+	serviceAccounts, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("error while listing ServiceAccounts: %w", err)
+	}
+	for i := range serviceAccounts.Items {
+		objects = append(objects, &serviceAccounts.Items[i])
+	}
+
+	services, err := clientSets.KClient.CoreV1().Services(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("error while listing Services: %w", err)
+	}
+	for i := range services.Items {
+		objects = append(objects, &services.Items[i])
+	}
+
+	deployments, err := clientSets.KClient.AppsV1().Deployments(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("error while listing Deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		objects = append(objects, &deployments.Items[i])
+	}
+
+	daemonSets, err := clientSets.KClient.AppsV1().DaemonSets(namespace).List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("error while listing DaemonSets: %w", err)
+	}
+	for i := range daemonSets.Items {
+		objects = append(objects, &daemonSets.Items[i])
+	}
+
+	for _, obj := range objects {
+		if !isOwnedBy(obj.GetOwnerReferences(), uid) {
+			logger.Warn("resource is labeled as part of the stack but isn't owned by it, it must be deleted manually", "name", obj.GetName(), "namespace", obj.GetNamespace())
+		}
+	}
+
+	return nil
+}
+
+func isOwnedBy(references []metav1.OwnerReference, uid types.UID) bool {
+	for _, ref := range references {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// stackMeta returns the metadata of an object labeled as part of the
+// stack, owned by the anchor with the UID when it isn't empty.
+func stackMeta(name, namespace, stack string, uid types.UID) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{StackLabel: StackAnchor(stack)},
+	}
+	if uid != "" {
+		meta.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: StackAnchor(stack), UID: uid}}
+	}
+	return meta
+}
+
+func TestRunDeleteStack(t *testing.T) {
+	const uid = types.UID("anchor-uid")
+
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&corev1.ConfigMap{ObjectMeta: stackMeta(StackAnchorName, metav1.NamespaceDefault, "", "")},
+		&corev1.ServiceAccount{ObjectMeta: stackMeta("prometheus", metav1.NamespaceDefault, "", uid)},
+		&appsv1.Deployment{ObjectMeta: stackMeta("prometheus-operator", metav1.NamespaceDefault, "", uid)},
+		// Labeled as part of the stack but not owned by the anchor.
+		&corev1.Service{ObjectMeta: stackMeta("stray", metav1.NamespaceDefault, "", "")},
+		&appsv1.DaemonSet{ObjectMeta: stackMeta("stray-node-exporter", metav1.NamespaceDefault, "", "other-uid")},
+		// Owned by another stack.
+		&corev1.Service{ObjectMeta: stackMeta("team-a-prometheus", metav1.NamespaceDefault, "team-a", "team-a-uid")},
+		&rbacv1.ClusterRole{ObjectMeta: stackMeta("prometheus", "", "", "")},
+		&rbacv1.ClusterRole{ObjectMeta: stackMeta("team-a-prometheus", "", "team-a", "")},
+		&rbacv1.Role{ObjectMeta: stackMeta("prometheus-operator", "apps", "", "")},
+		&rbacv1.RoleBinding{ObjectMeta: stackMeta("prometheus-operator", "apps", "", "")},
+	))
+	kClient := clientSets.KClient.(*kubefake.Clientset)
+
+	// The UID of the anchor is set once created, the fake clientset doesn't
+	// generate it.
+	anchor, err := kClient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get(context.Background(), StackAnchorName, metav1.GetOptions{})
+	require.NoError(t, err)
+	anchor.UID = uid
+	_, err = kClient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Update(context.Background(), anchor, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// The fake clientset doesn't implement the deletion of collections.
+	var deletedCollections []string
+	kClient.PrependReactor("delete-collection", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		restrictions := action.(clienttesting.DeleteCollectionAction).GetListRestrictions()
+		deletedCollections = append(deletedCollections, action.GetResource().Resource+" "+restrictions.Labels.String())
+		return true, nil, nil
+	})
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	require.NoError(t, RunDeleteStack(context.Background(), logger, clientSets, ""))
+
+	// The anchor is deleted in the foreground, so that it's only gone once
+	// the objects it owns are garbage-collected.
+	var propagation *metav1.DeletionPropagation
+	for _, action := range kClient.Actions() {
+		if deleteAction, ok := action.(clienttesting.DeleteActionImpl); ok && action.GetResource().Resource == "configmaps" {
+			assert.Equal(t, StackAnchorName, deleteAction.GetName())
+			propagation = deleteAction.DeleteOptions.PropagationPolicy
+		}
+	}
+	require.NotNil(t, propagation, "the anchor isn't deleted")
+	assert.Equal(t, metav1.DeletePropagationForeground, *propagation)
+
+	_, err = kClient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get(context.Background(), StackAnchorName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// The cluster-scoped objects are deleted by label.
+	selector := StackLabel + "=" + StackAnchorName
+	assert.Equal(t, []string{"clusterrolebindings " + selector, "clusterroles " + selector}, deletedCollections)
+
+	_, err = kClient.RbacV1().Roles("apps").Get(context.Background(), "prometheus-operator", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	_, err = kClient.RbacV1().RoleBindings("apps").Get(context.Background(), "prometheus-operator", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Only the objects labeled as part of the stack but not owned by its
+	// anchor are reported.
+	assert.Contains(t, logs.String(), "name=stray ")
+	assert.Contains(t, logs.String(), "name=stray-node-exporter ")
+	assert.NotContains(t, logs.String(), "name=prometheus ")
+	assert.NotContains(t, logs.String(), "name=prometheus-operator ")
+	assert.NotContains(t, logs.String(), "name=team-a-prometheus ")
+}
+
+func TestRunDeleteStackNotFound(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets()
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	err := RunDeleteStack(context.Background(), logger, clientSets, "team-a")
+	require.EqualError(t, err, "stack anchor ConfigMap poctl-stack-team-a not found in namespace default")
+}
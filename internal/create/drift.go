@@ -0,0 +1,229 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Missing is the live value of a field which isn't set.
+const Missing = "<missing>"
+
+// Drift is an object of the stack whose live state differs from the desired
+// state rendered from the recorded parameters of the stack.
+type Drift struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Missing is set when the object doesn't exist.
+	Missing bool
+	Fields  []FieldDrift
+
+	desired *unstructured.Unstructured
+}
+
+// FieldDrift is a field whose live value differs from the desired one.
+type FieldDrift struct {
+	Path    string
+	Desired string
+	Live    string
+}
+
+// RunDrift renders the desired state of the stack with the parameters
+// recorded at creation and compares it with the live objects. Only the fields
+// set by poctl are compared, the fields defaulted by the API server or set by
+// other clients aren't drifts.
+func RunDrift(ctx context.Context, clientSets *k8sutil.ClientSets, stack string) ([]Drift, error) {
+	namespace := metav1.NamespaceDefault
+
+	params, anchor, err := loadStackParameters(ctx, clientSets, namespace, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderStack(newStackOwner(anchor), namespace, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, manifests := range rendered {
+		for _, obj := range manifests.Manifests() {
+			desired, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object type %T", obj)
+			}
+
+			drift, err := compareObject(ctx, clientSets, desired)
+			if err != nil {
+				return nil, err
+			}
+
+			if drift != nil {
+				drifts = append(drifts, *drift)
+			}
+		}
+	}
+
+	return drifts, nil
+}
+
+// RepairDrift applies the desired state of the drifted objects, taking over
+// the ownership of the fields changed by other clients.
+func RepairDrift(ctx context.Context, clientSets *k8sutil.ClientSets, drifts []Drift) error {
+	opts := k8sutil.ApplyOption
+	opts.Force = true
+
+	validator := crds.NewValidator(clientSets.APIExtensionsClient)
+	objects := make([]any, 0, len(drifts))
+	for _, drift := range drifts {
+		if err := validator.ValidateObjects(ctx, drift.desired); err != nil {
+			return err
+		}
+		objects = append(objects, drift.desired)
+	}
+
+	return k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).WithOptions(opts).Apply(ctx, objects...))
+}
+
+// compareObject returns the drift of the live object from the desired one,
+// nil when there is none.
+func compareObject(ctx context.Context, clientSets *k8sutil.ClientSets, desired *unstructured.Unstructured) (*Drift, error) {
+	// The API server clears the namespace of the cluster-scoped objects.
+	if k8sutil.IsClusterScoped(desired.GetKind()) {
+		desired = desired.DeepCopy()
+		desired.SetNamespace("")
+	}
+
+	drift := &Drift{
+		Kind:      desired.GetKind(),
+		Namespace: desired.GetNamespace(),
+		Name:      desired.GetName(),
+		desired:   desired,
+	}
+
+	live, err := k8sutil.ResourceClient(clientSets, desired.GroupVersionKind(), desired.GetNamespace()).
+		Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			drift.Missing = true
+			return drift, nil
+		}
+		return nil, fmt.Errorf("error while getting %s %s: %w", drift.Kind, drift.Name, err)
+	}
+
+	drift.Fields = compareFields("", desired.Object, live.Object)
+	if len(drift.Fields) == 0 {
+		return nil, nil
+	}
+
+	return drift, nil
+}
+
+// compareFields returns the fields set in desired whose value differs in
+// live. Lists are compared item by item and must have the same length.
+func compareFields(path string, desired, live any) []FieldDrift {
+	switch d := desired.(type) {
+	case map[string]any:
+		l, _ := live.(map[string]any)
+
+		var drifts []FieldDrift
+		for _, key := range slices.Sorted(maps.Keys(d)) {
+			drifts = append(drifts, compareFields(joinPath(path, key), d[key], l[key])...)
+		}
+		return drifts
+
+	case []any:
+		l, ok := live.([]any)
+		if !ok || len(l) != len(d) {
+			return []FieldDrift{newFieldDrift(path, desired, live)}
+		}
+
+		var drifts []FieldDrift
+		for i := range d {
+			drifts = append(drifts, compareFields(fmt.Sprintf("%s[%d]", path, i), d[i], l[i])...)
+		}
+		return drifts
+
+	default:
+		if live == nil || !reflect.DeepEqual(normalizeNumber(desired), normalizeNumber(live)) {
+			return []FieldDrift{newFieldDrift(path, desired, live)}
+		}
+		return nil
+	}
+}
+
+func newFieldDrift(path string, desired, live any) FieldDrift {
+	return FieldDrift{
+		Path:    path,
+		Desired: formatValue(desired),
+		Live:    formatValue(live),
+	}
+}
+
+// normalizeNumber converts the numbers to float64, the desired objects
+// holding int64 values where the live ones may hold float64 values.
+func normalizeNumber(v any) any {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int:
+		return float64(n)
+	default:
+		return v
+	}
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return Missing
+	case string:
+		return v
+	case map[string]any, []any:
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return strings.TrimSpace(strings.ReplaceAll(string(b), "\n", " "))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+	}
+	return path + "." + key
+}
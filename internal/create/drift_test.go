@@ -0,0 +1,127 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCompareFields(t *testing.T) {
+	desired := map[string]any{
+		"metadata": map[string]any{
+			"name":   "prometheus",
+			"labels": map[string]any{"app.kubernetes.io/name": "prometheus"},
+		},
+		"spec": map[string]any{
+			"replicas": int64(2),
+			"args":     []any{"--a", "--b"},
+		},
+	}
+
+	live := map[string]any{
+		"metadata": map[string]any{
+			"name":            "prometheus",
+			"labels":          map[string]any{"app.kubernetes.io/name": "prometheus", "extra": "label"},
+			"resourceVersion": "42",
+		},
+		"spec": map[string]any{
+			"replicas":       float64(3),
+			"args":           []any{"--a"},
+			"serviceAccount": "defaulted",
+		},
+	}
+
+	assert.Equal(t, []FieldDrift{
+		{Path: "spec.args", Desired: "- --a - --b", Live: "- --a"},
+		{Path: "spec.replicas", Desired: "2", Live: "3"},
+	}, compareFields("", desired, live))
+
+	assert.Empty(t, compareFields("", desired, desired))
+
+	assert.Equal(t, []FieldDrift{
+		{Path: `metadata.labels["app.kubernetes.io/name"]`, Desired: "prometheus", Live: Missing},
+	}, compareFields("", map[string]any{"metadata": desired["metadata"]}, map[string]any{"metadata": map[string]any{"name": "prometheus"}}))
+}
+
+func TestRunDrift(t *testing.T) {
+	params := StackParameters{
+		Version: "0.78.2",
+		Profile: Profile{
+			Name:               "minimal",
+			PrometheusReplicas: 1,
+		},
+	}
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	anchor := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      StackAnchorName,
+			Namespace: metav1.NamespaceDefault,
+			UID:       "anchor-uid",
+		},
+		Data: map[string]string{parametersKey: string(data)},
+	}
+
+	// The live objects are the desired ones, except for a Prometheus scaled
+	// manually and a Service which has been deleted.
+	rendered, err := renderStack(newStackOwner(anchor), metav1.NamespaceDefault, params)
+	require.NoError(t, err)
+
+	var objects []runtime.Object
+	for _, manifests := range rendered {
+		for _, obj := range manifests.Manifests() {
+			u := liveObject(obj.(*unstructured.Unstructured))
+			switch {
+			case u.GetKind() == "Prometheus":
+				require.NoError(t, unstructured.SetNestedField(u.Object, int64(5), "spec", "replicas"))
+			case u.GetKind() == "Service" && u.GetName() == "prometheus":
+				continue
+			}
+			objects = append(objects, u)
+		}
+	}
+
+	clientSets := &k8sutil.ClientSets{
+		KClient: fake.NewSimpleClientset(anchor),
+		DClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...),
+	}
+
+	drifts, err := RunDrift(context.Background(), clientSets, "")
+	require.NoError(t, err)
+	require.Len(t, drifts, 2)
+
+	assert.Equal(t, "Prometheus", drifts[0].Kind)
+	assert.Equal(t, []FieldDrift{{Path: "spec.replicas", Desired: "1", Live: "5"}}, drifts[0].Fields)
+
+	assert.Equal(t, "Service", drifts[1].Kind)
+	assert.Equal(t, "prometheus", drifts[1].Name)
+	assert.True(t, drifts[1].Missing)
+
+	_, err = RunDrift(context.Background(), clientSets, "team-a")
+	require.Error(t, err)
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/pkg/builder"
+)
+
+// Components of the stack which the overrides apply to, each one designating
+// its main object.
+const (
+	// OverrideOperator designates the Deployment of the operator.
+	OverrideOperator = "operator"
+	// OverridePrometheus designates the Prometheus or the PrometheusAgent.
+	OverridePrometheus = "prometheus"
+	// OverrideAlertmanager designates the Alertmanager.
+	OverrideAlertmanager = "alertmanager"
+	// OverrideNodeExporter designates the DaemonSet of the node exporter.
+	OverrideNodeExporter = "node-exporter"
+	// OverrideKubeStateMetrics designates the Deployment or the StatefulSet
+	// of kube-state-metrics.
+	OverrideKubeStateMetrics = "kube-state-metrics"
+	// OverridePushgateway designates the Deployment of the Pushgateway.
+	OverridePushgateway = "pushgateway"
+	// OverrideBlackboxExporter designates the Deployment of the blackbox
+	// exporter.
+	OverrideBlackboxExporter = "blackbox-exporter"
+	// OverrideOpenTelemetryCollector designates the Deployment of the
+	// OpenTelemetry Collector.
+	OverrideOpenTelemetryCollector = "otel-collector"
+)
+
+// OverrideComponents are the components which the overrides apply to.
+var OverrideComponents = []string{OverrideOperator, OverridePrometheus, OverrideAlertmanager, OverrideNodeExporter, OverrideKubeStateMetrics, OverridePushgateway, OverrideBlackboxExporter, OverrideOpenTelemetryCollector}
+
+// ParseOverrides parses the overrides of the stack components.
+func ParseOverrides(values []string) ([]builder.Override, error) {
+	overrides := make([]builder.Override, 0, len(values))
+	for _, value := range values {
+		o, err := builder.ParseOverride(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(OverrideComponents, o.Component) {
+			return nil, fmt.Errorf("unknown component %q in override %q, must be one of: %s", o.Component, value, strings.Join(OverrideComponents, ", "))
+		}
+
+		overrides = append(overrides, o)
+	}
+	return overrides, nil
+}
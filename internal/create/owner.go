@@ -0,0 +1,123 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+const (
+	// StackAnchorName is the name of the ConfigMap owning the namespaced
+	// resources of the default stack.
+	StackAnchorName = "poctl-stack"
+	// StackLabel is set on every resource of the stack, including the
+	// cluster-scoped ones which can't be owned by the anchor ConfigMap.
+	StackLabel = "poctl.prometheus-operator.dev/stack"
+)
+
+// StackAnchor returns the name of the anchor ConfigMap of a stack, which is
+// also the value of the StackLabel of its resources.
+func StackAnchor(stack string) string {
+	if stack == "" {
+		return StackAnchorName
+	}
+	return fmt.Sprintf("%s-%s", StackAnchorName, stack)
+}
+
+// stackOwner attaches the resources of the stack to the anchor ConfigMap, so
+// that deleting the anchor garbage-collects them.
+type stackOwner struct {
+	anchor    string
+	reference *applyConfigMetav1.OwnerReferenceApplyConfiguration
+}
+
+// createStackAnchor applies the anchor ConfigMap of the stack and returns
+// the owner built from its UID. The parameters and the inventory recorded
+// in the anchor are applied again along with it: they're owned by the same
+// field manager, an apply without them would remove them.
+func createStackAnchor(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, stack string) (*stackOwner, error) {
+	gvk := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	if err := k8sutil.MigrateFieldManager(ctx, clientSets, gvk, namespace, StackAnchor(stack)); err != nil {
+		return nil, err
+	}
+
+	anchor := stackAnchor(namespace, stack)
+	current, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Get(ctx, StackAnchor(stack), metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if data := recordedData(current); len(data) > 0 {
+			anchor = anchor.WithData(data)
+		}
+	case !errors.IsNotFound(err):
+		return nil, fmt.Errorf("error while getting stack anchor ConfigMap: %w", err)
+	}
+
+	cm, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Apply(ctx, anchor, k8sutil.ApplyOption)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating stack anchor ConfigMap: %w", err)
+	}
+
+	return newStackOwner(cm), nil
+}
+
+// stackAnchor returns the apply configuration of the anchor ConfigMap of the
+// stack.
+func stackAnchor(namespace, stack string) *applyConfigCorev1.ConfigMapApplyConfiguration {
+	return applyConfigCorev1.ConfigMap(StackAnchor(stack), namespace).
+		WithLabels(map[string]string{StackLabel: StackAnchor(stack)})
+}
+
+// newStackOwner returns the owner built from the anchor ConfigMap.
+func newStackOwner(cm *corev1.ConfigMap) *stackOwner {
+	return &stackOwner{
+		anchor: cm.Name,
+		reference: applyConfigMetav1.OwnerReference().
+			WithAPIVersion("v1").
+			WithKind("ConfigMap").
+			WithName(cm.Name).
+			WithUID(cm.UID).
+			WithBlockOwnerDeletion(false).
+			WithController(false),
+	}
+}
+
+// own labels a namespaced resource and sets the anchor as its owner.
+func (o *stackOwner) own(meta *applyConfigMetav1.ObjectMetaApplyConfiguration) {
+	o.label(meta)
+	meta.OwnerReferences = append(meta.OwnerReferences, *o.reference)
+}
+
+// label labels a resource as part of the stack. Owner references from
+// cluster-scoped resources to namespaced ones aren't allowed, so the label is
+// the only link between them and the stack.
+func (o *stackOwner) label(meta *applyConfigMetav1.ObjectMetaApplyConfiguration) {
+	// The builders share the labels map between objects and selectors, copy
+	// it to avoid leaking the stack label into selectors.
+	labels := maps.Clone(meta.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[StackLabel] = o.anchor
+	meta.Labels = labels
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestStackOwnership(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithServerSideApply())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	profile := Profile{Name: "minimal", PrometheusReplicas: 1, NodeExporter: true}
+	require.NoError(t, createStack(ctx, logger, clientSets, newSummary(profile), "0.78.2", nil, profile))
+
+	anchor := getStackAnchor(t, clientSets)
+	assert.Equal(t, StackAnchorName, anchor.Labels[StackLabel])
+	require.NotEmpty(t, anchor.UID)
+
+	inventory, err := loadStackInventory(ctx, clientSets, metav1.NamespaceDefault, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, inventory)
+
+	for _, obj := range inventory {
+		u, err := clientSets.DClient.Resource(k8sutil.ResourceFor(obj.groupVersionKind())).Namespace(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+		require.NoError(t, err, "%s %s", obj.Kind, obj.Name)
+
+		assert.Equal(t, StackAnchorName, u.GetLabels()[StackLabel], "%s %s", obj.Kind, obj.Name)
+
+		// The cluster-scoped objects can't be owned by the namespaced
+		// anchor.
+		if obj.Namespace == "" {
+			assert.Empty(t, u.GetOwnerReferences(), "%s %s", obj.Kind, obj.Name)
+			continue
+		}
+		require.Len(t, u.GetOwnerReferences(), 1, "%s %s", obj.Kind, obj.Name)
+		ref := u.GetOwnerReferences()[0]
+		assert.Equal(t, "ConfigMap", ref.Kind)
+		assert.Equal(t, StackAnchorName, ref.Name)
+		assert.Equal(t, anchor.UID, ref.UID)
+		assert.True(t, isOwnedBy(u.GetOwnerReferences(), anchor.UID))
+
+		// The stack label doesn't leak into the selectors.
+		if selector, found, _ := unstructured.NestedStringMap(u.Object, "spec", "selector", "matchLabels"); found {
+			assert.NotContains(t, selector, StackLabel, "%s %s", obj.Kind, obj.Name)
+		}
+	}
+
+	// Creating the stack again keeps the anchor, and so the owner
+	// references.
+	require.NoError(t, createStack(ctx, logger, clientSets, newSummary(profile), "0.78.2", nil, profile))
+	assert.Equal(t, anchor.UID, getStackAnchor(t, clientSets).UID)
+}
+
+func TestIsOwnedBy(t *testing.T) {
+	references := []metav1.OwnerReference{{Kind: "ConfigMap", Name: StackAnchorName, UID: "anchor-uid"}}
+
+	assert.True(t, isOwnedBy(references, "anchor-uid"))
+	assert.False(t, isOwnedBy(references, types.UID("other-uid")))
+	assert.False(t, isOwnedBy(nil, "anchor-uid"))
+}
@@ -0,0 +1,208 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// parametersKey is the key of the anchor ConfigMap holding the parameters of
+// the stack.
+const parametersKey = "parameters.json"
+
+// StackParameters are the parameters a stack has been created with. They are
+// recorded in the anchor ConfigMap of the stack, so that its desired state can
+// be rendered again.
+type StackParameters struct {
+	Version string
+	Profile Profile
+	// Rules are the RBAC rules of the operator downloaded from the release,
+	// empty when the built-in rules are used.
+	Rules []rbacv1.PolicyRule `json:",omitempty"`
+}
+
+// recordStackParameters stores the parameters and the inventory of the
+// objects they render in the anchor ConfigMap of the stack.
+func recordStackParameters(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string, params StackParameters, inventory []StackObject) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("error while encoding stack parameters: %w", err)
+	}
+
+	inventoryData, err := json.Marshal(inventory)
+	if err != nil {
+		return fmt.Errorf("error while encoding stack inventory: %w", err)
+	}
+
+	anchor := stackAnchor(namespace, params.Profile.Stack).
+		WithData(map[string]string{
+			parametersKey: string(data),
+			inventoryKey:  string(inventoryData),
+		})
+
+	if err := k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, anchor)); err != nil {
+		return fmt.Errorf("error while recording stack parameters: %w", err)
+	}
+
+	return nil
+}
+
+// recordStackInventory stores the inventory in the anchor ConfigMap of the
+// stack, keeping the parameters already recorded.
+func recordStackInventory(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, stack string, inventory []StackObject) error {
+	cm, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Get(ctx, StackAnchor(stack), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error while getting stack anchor ConfigMap: %w", err)
+	}
+
+	inventoryData, err := json.Marshal(inventory)
+	if err != nil {
+		return fmt.Errorf("error while encoding stack inventory: %w", err)
+	}
+
+	data := recordedData(cm)
+	data[inventoryKey] = string(inventoryData)
+
+	if err := k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, stackAnchor(namespace, stack).WithData(data))); err != nil {
+		return fmt.Errorf("error while recording stack inventory: %w", err)
+	}
+
+	return nil
+}
+
+// recordedData returns the parameters and the inventory recorded in the
+// anchor ConfigMap of a stack.
+func recordedData(cm *corev1.ConfigMap) map[string]string {
+	data := map[string]string{}
+	for _, key := range []string{parametersKey, inventoryKey} {
+		if value, ok := cm.Data[key]; ok {
+			data[key] = value
+		}
+	}
+	return data
+}
+
+// loadStackParameters returns the parameters recorded in the anchor ConfigMap
+// of the stack, along with the ConfigMap.
+func loadStackParameters(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, stack string) (StackParameters, *corev1.ConfigMap, error) {
+	anchorName := StackAnchor(stack)
+
+	cm, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Get(ctx, anchorName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return StackParameters{}, nil, fmt.Errorf("stack anchor ConfigMap %s not found in namespace %s", anchorName, namespace)
+		}
+		return StackParameters{}, nil, fmt.Errorf("error while getting stack anchor ConfigMap: %w", err)
+	}
+
+	params, err := decodeStackParameters(cm)
+	if err != nil {
+		return StackParameters{}, nil, err
+	}
+
+	return params, cm, nil
+}
+
+// decodeStackParameters returns the parameters recorded in the anchor
+// ConfigMap.
+func decodeStackParameters(cm *corev1.ConfigMap) (StackParameters, error) {
+	data, ok := cm.Data[parametersKey]
+	if !ok {
+		return StackParameters{}, fmt.Errorf("stack anchor ConfigMap %s has no recorded parameters, create the stack again to record them", cm.Name)
+	}
+
+	var params StackParameters
+	if err := json.Unmarshal([]byte(data), &params); err != nil {
+		return StackParameters{}, fmt.Errorf("error while decoding stack parameters: %w", err)
+	}
+
+	return params, nil
+}
+
+// renderStack returns the manifests of the components of the stack, as
+// applied by RunCreateStack with the same parameters.
+func renderStack(owner *stackOwner, namespace string, params StackParameters) ([]builder.Manifests, error) {
+	profile := params.Profile
+
+	operator, err := buildPrometheusOperator(owner, namespace, params.Version, params.Rules, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	prometheus, err := buildPrometheus(owner, namespace, profile)
+	if err != nil {
+		return nil, err
+	}
+	manifests := []builder.Manifests{&operator, &prometheus}
+
+	if profile.Alertmanager {
+		alertmanager, err := buildAlertManager(owner, namespace, profile)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, &alertmanager)
+	}
+
+	if profile.NodeExporter {
+		nodeExporter, err := buildNodeExporter(owner, namespace, profile)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, &nodeExporter)
+	}
+
+	if profile.KubeStateMetrics {
+		kubeStateMetrics, err := buildKubeStateMetrics(owner, namespace, profile)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, &kubeStateMetrics)
+	}
+
+	if profile.Pushgateway {
+		pushgateway, err := buildPushgateway(owner, namespace, profile)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, &pushgateway)
+	}
+
+	if profile.BlackboxExporter {
+		blackboxExporter, err := buildBlackboxExporter(owner, namespace, profile)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, &blackboxExporter)
+	}
+
+	if profile.OTLPEndpoint != "" {
+		collector, err := buildOpenTelemetryCollector(owner, namespace, profile)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, &collector)
+	}
+
+	return manifests, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Profile is a coherent set of settings for the components of the stack.
+type Profile struct {
+	Name                 string
+	PrometheusReplicas   int32
+	PrometheusRetention  string
+	PrometheusResources  corev1.ResourceList
+	AlertmanagerReplicas int32
+	// Agent deploys a PrometheusAgent instead of a Prometheus, it excludes
+	// the Alertmanager since agents don't evaluate rules.
+	Agent bool
+	// AgentDaemonSet runs the PrometheusAgent as a DaemonSet rather than a
+	// StatefulSet.
+	AgentDaemonSet bool
+	Alertmanager   bool
+	NodeExporter   bool
+	// NodeExporterCollectors enables and disables collectors of the node
+	// exporter, and NodeExporterArgs are appended to its arguments.
+	NodeExporterCollectors builder.NodeExporterCollectors
+	NodeExporterArgs       []string `json:",omitempty"`
+	KubeStateMetrics       bool
+	// KubeStateMetricsShards runs kube-state-metrics as a StatefulSet with
+	// this number of shards when greater than 1.
+	KubeStateMetricsShards int32
+	// KubeStateMetricsCRDMetrics makes kube-state-metrics expose the state
+	// of the Prometheus, PrometheusAgent and Alertmanager resources, along
+	// with the alerts on these metrics.
+	KubeStateMetricsCRDMetrics bool
+	// SelfMonitoringAlerts alerts on the failures of the operator and of the
+	// config-reloaders, scraping the config-reloader sidecars of Prometheus
+	// and Alertmanager.
+	SelfMonitoringAlerts bool
+	// WatchedNamespaces restricts the operator to these namespaces, granting
+	// it access through Roles instead of a ClusterRole. The operator watches
+	// the whole cluster when empty.
+	WatchedNamespaces []string
+	// RemoteWriteURL is the remote write endpoint receiving the samples of
+	// Prometheus or of the PrometheusAgent.
+	RemoteWriteURL string
+	// Pushgateway deploys a Pushgateway receiving the metrics of the batch
+	// jobs, along with an alert on the stale push groups unless the stack
+	// runs a PrometheusAgent.
+	Pushgateway bool
+	// BlackboxExporter deploys a blackbox exporter probing the targets of
+	// the Probes over HTTP, TCP and ICMP.
+	BlackboxExporter bool
+	// OTLPEndpoint deploys an OpenTelemetry Collector exporting the samples
+	// of the stack to this OTLP endpoint when set. The collector receives
+	// the samples through remote write, or federates Prometheus when
+	// OTelCollectorFederation is set.
+	OTLPEndpoint            string
+	OTelCollectorFederation bool
+	// PrometheusVersion and AlertmanagerVersion pin the versions of
+	// Prometheus and Alertmanager, the operator picks its default versions
+	// when empty.
+	PrometheusVersion   string
+	AlertmanagerVersion string
+	// ImagePullSecrets are attached to the ServiceAccounts and the Pods of
+	// all the components, to pull their images from private registries.
+	ImagePullSecrets []string
+	// Scheduling sets the priority class, the topology spread and the
+	// anti-affinity of the Pods of all the components.
+	Scheduling builder.Scheduling
+	// Overrides set fields of the main object of the components, for the
+	// settings without a dedicated option.
+	Overrides []builder.Override `json:",omitempty"`
+	// Stack names the stack, prefixing the names of its objects so that
+	// several stacks can run in the same cluster. The default stack is
+	// unnamed.
+	Stack string
+	// IPFamilyPolicy is the IP family policy of the Services of the
+	// components, such as PreferDualStack on dual-stack clusters. The
+	// Services get the policy of the cluster when empty.
+	IPFamilyPolicy corev1.IPFamilyPolicy
+	// ClusterDomain is the DNS domain of the Services of the cluster, which
+	// qualifies the addresses at which the components reach each other. The
+	// addresses are relative to the search domains of the Pods when empty.
+	ClusterDomain string
+	// KubeVersion is the Kubernetes version targeted by the manifests, the
+	// latest releases when empty.
+	KubeVersion builder.KubeVersion `json:",omitempty"`
+}
+
+const DefaultProfile = "default"
+
+var profiles = map[string]Profile{
+	"minimal": {
+		Name:                "minimal",
+		PrometheusReplicas:  1,
+		PrometheusRetention: "24h",
+		PrometheusResources: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("400Mi"),
+		},
+	},
+	DefaultProfile: {
+		Name:                 DefaultProfile,
+		PrometheusReplicas:   2,
+		AlertmanagerReplicas: 1,
+		Alertmanager:         true,
+		NodeExporter:         true,
+		KubeStateMetrics:     true,
+	},
+	"ha": {
+		Name:                "ha",
+		PrometheusReplicas:  2,
+		PrometheusRetention: "15d",
+		PrometheusResources: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+		AlertmanagerReplicas: 3,
+		Alertmanager:         true,
+		NodeExporter:         true,
+		KubeStateMetrics:     true,
+	},
+	"edge": {
+		Name:               "edge",
+		PrometheusReplicas: 1,
+		PrometheusResources: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+		Agent:        true,
+		NodeExporter: true,
+	},
+}
+
+// ProfileNames returns the sorted names of the available profiles.
+func ProfileNames() []string {
+	return slices.Sorted(maps.Keys(profiles))
+}
+
+// AsAgent returns the lightweight variant of the profile, where the
+// Prometheus is replaced by a PrometheusAgent forwarding its samples through
+// remote write. Agents don't evaluate rules, so the variant has no
+// Alertmanager, and they don't store samples so it has no retention.
+func (p Profile) AsAgent() Profile {
+	p.Agent = true
+	p.Alertmanager = false
+	p.AlertmanagerReplicas = 0
+	p.PrometheusRetention = ""
+	return p
+}
+
+// GetProfile returns the profile with the given name.
+func GetProfile(name string) (Profile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %s, must be one of: %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// versionRegexp matches the semantic versions of the releases, such as 2.54.1
+// or 3.0.0-rc.0.
+var versionRegexp = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+// ParseVersion returns the version of a component of the stack given with or
+// without the "v" prefix of its image tag.
+func ParseVersion(s string) (string, error) {
+	version := strings.TrimPrefix(s, "v")
+	if !versionRegexp.MatchString(version) {
+		return "", fmt.Errorf("invalid version %s, must be a semantic version such as 1.2.3", s)
+	}
+	return version, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"testing"
+
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAsAgent(t *testing.T) {
+	profile, err := GetProfile("ha")
+	require.NoError(t, err)
+
+	agent := profile.AsAgent()
+	assert.True(t, agent.Agent)
+	assert.False(t, agent.Alertmanager)
+	assert.Empty(t, agent.PrometheusRetention)
+	assert.Equal(t, profile.PrometheusReplicas, agent.PrometheusReplicas)
+	assert.Equal(t, profile.NodeExporter, agent.NodeExporter)
+	assert.Equal(t, profile.KubeStateMetrics, agent.KubeStateMetrics)
+
+	// The profiles are left untouched.
+	assert.True(t, profile.Alertmanager)
+
+	agent.RemoteWriteURL = "https://metrics.example.com/api/v1/write"
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	manifests, err := buildPrometheus(owner, metav1.NamespaceDefault, agent)
+	require.NoError(t, err)
+	assert.Nil(t, manifests.Prometheus)
+	require.NotNil(t, manifests.PrometheusAgent)
+	require.Len(t, manifests.PrometheusAgent.Spec.RemoteWrite, 1)
+	assert.Equal(t, agent.RemoteWriteURL, *manifests.PrometheusAgent.Spec.RemoteWrite[0].URL)
+}
+
+func TestParseVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		expected string
+		err      bool
+	}{
+		{version: "2.54.1", expected: "2.54.1"},
+		{version: "v0.27.0", expected: "0.27.0"},
+		{version: "3.0.0-rc.0", expected: "3.0.0-rc.0"},
+		{version: "2.54", err: true},
+		{version: "latest", err: true},
+		{version: "", err: true},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			version, err := ParseVersion(tc.version)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, version)
+		})
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	profile, err := GetProfile("ha")
+	require.NoError(t, err)
+
+	profile.Overrides, err = ParseOverrides([]string{
+		"prometheus.spec.retention=30d",
+		"alertmanager.spec.replicas=5",
+	})
+	require.NoError(t, err)
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	prometheus, err := buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Equal(t, "30d", string(*prometheus.Prometheus.Spec.Retention))
+
+	alertmanager, err := buildAlertManager(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), *alertmanager.AlertManager.Spec.Replicas)
+
+	_, err = ParseOverrides([]string{"grafana.spec.replicas=1"})
+	require.Error(t, err)
+}
+
+func TestSelfMonitoringAlerts(t *testing.T) {
+	profile, err := GetProfile(DefaultProfile)
+	require.NoError(t, err)
+	profile.SelfMonitoringAlerts = true
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	operator, err := buildPrometheusOperator(owner, metav1.NamespaceDefault, "0.75.1", nil, profile)
+	require.NoError(t, err)
+	require.NotNil(t, operator.PrometheusRule)
+	assert.Len(t, operator.PrometheusRule.OwnerReferences, 1)
+
+	prometheus, err := buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Len(t, prometheus.ServiceMonitor.Spec.Endpoints, 2)
+
+	alertmanager, err := buildAlertManager(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Len(t, alertmanager.ServiceMonitor.Spec.Endpoints, 2)
+
+	profile.SelfMonitoringAlerts = false
+	operator, err = buildPrometheusOperator(owner, metav1.NamespaceDefault, "0.75.1", nil, profile)
+	require.NoError(t, err)
+	assert.Nil(t, operator.PrometheusRule)
+}
+
+func TestOpenTelemetryCollector(t *testing.T) {
+	profile, err := GetProfile(DefaultProfile)
+	require.NoError(t, err)
+	profile.RemoteWriteURL = "https://metrics.example.com/api/v1/write"
+	profile.OTLPEndpoint = "otlp.example.com:4317"
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	prometheus, err := buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	require.Len(t, prometheus.Prometheus.Spec.RemoteWrite, 2)
+	assert.Equal(t, "http://otel-collector.default.svc:9090/api/v1/write", *prometheus.Prometheus.Spec.RemoteWrite[1].URL)
+
+	collector, err := buildOpenTelemetryCollector(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Len(t, collector.Deployment.OwnerReferences, 1)
+	assert.Len(t, collector.Config.OwnerReferences, 1)
+
+	manifests, err := renderStack(owner, metav1.NamespaceDefault, StackParameters{Version: "0.75.1", Profile: profile})
+	require.NoError(t, err)
+	assert.Contains(t, manifests, builder.Manifests(&collector))
+
+	profile.ClusterDomain = "example.org"
+	prometheus, err = buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Equal(t, "http://otel-collector.default.svc.example.org:9090/api/v1/write", *prometheus.Prometheus.Spec.RemoteWrite[1].URL)
+
+	// Federating Prometheus doesn't need remote write.
+	profile.OTelCollectorFederation = true
+	prometheus, err = buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Len(t, prometheus.Prometheus.Spec.RemoteWrite, 1)
+
+	collector, err = buildOpenTelemetryCollector(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Contains(t, collector.Config.Data["config.yaml"], "prometheus.default.svc.example.org:9090")
+}
+
+func TestPushgateway(t *testing.T) {
+	profile, err := GetProfile(DefaultProfile)
+	require.NoError(t, err)
+	profile.Pushgateway = true
+	profile.Overrides, err = ParseOverrides([]string{"pushgateway.spec.replicas=2"})
+	require.NoError(t, err)
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	pushgateway, err := buildPushgateway(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	require.NotNil(t, pushgateway.PrometheusRule)
+	assert.Len(t, pushgateway.PrometheusRule.OwnerReferences, 1)
+	assert.Equal(t, int32(2), *pushgateway.Deployment.Spec.Replicas)
+
+	// Agents don't evaluate the alert.
+	pushgateway, err = buildPushgateway(owner, metav1.NamespaceDefault, profile.AsAgent())
+	require.NoError(t, err)
+	assert.Nil(t, pushgateway.PrometheusRule)
+}
+
+func TestBlackboxExporter(t *testing.T) {
+	profile, err := GetProfile(DefaultProfile)
+	require.NoError(t, err)
+	profile.BlackboxExporter = true
+	profile.Overrides, err = ParseOverrides([]string{"blackbox-exporter.spec.replicas=2"})
+	require.NoError(t, err)
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	blackboxExporter, err := buildBlackboxExporter(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Len(t, blackboxExporter.Config.OwnerReferences, 1)
+	assert.Equal(t, int32(2), *blackboxExporter.Deployment.Spec.Replicas)
+	assert.Contains(t, newSummary(profile).Components, ComponentResult{Name: componentBlackboxExporter, Status: ComponentNotRun})
+}
+
+func TestIPFamilyPolicy(t *testing.T) {
+	profile, err := GetProfile(DefaultProfile)
+	require.NoError(t, err)
+	profile.IPFamilyPolicy = corev1.IPFamilyPolicyRequireDualStack
+
+	owner := newStackOwner(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: StackAnchor("")}})
+	prometheus, err := buildPrometheus(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Equal(t, corev1.IPFamilyPolicyRequireDualStack, *prometheus.Service.Spec.IPFamilyPolicy)
+
+	alertmanager, err := buildAlertManager(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Equal(t, corev1.IPFamilyPolicyRequireDualStack, *alertmanager.Service.Spec.IPFamilyPolicy)
+
+	kubeStateMetrics, err := buildKubeStateMetrics(owner, metav1.NamespaceDefault, profile)
+	require.NoError(t, err)
+	assert.Equal(t, corev1.IPFamilyPolicyRequireDualStack, *kubeStateMetrics.Service.Spec.IPFamilyPolicy)
+}
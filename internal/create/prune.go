@@ -0,0 +1,163 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// inventoryKey is the key of the anchor ConfigMap holding the inventory of
+// the objects of the stack.
+const inventoryKey = "inventory.json"
+
+// StackObject is an object of the inventory of a stack.
+type StackObject struct {
+	APIVersion string
+	Kind       string
+	Namespace  string `json:",omitempty"`
+	Name       string
+}
+
+// groupVersionKind returns the GroupVersionKind of the object.
+func (o StackObject) groupVersionKind() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(o.APIVersion, o.Kind)
+}
+
+// same reports whether both objects are the same object, regardless of the
+// version of their API. The namespace of the cluster-scoped objects, recorded
+// by the previous versions of poctl, is ignored.
+func (o StackObject) same(other StackObject) bool {
+	return o.groupVersionKind().GroupKind() == other.groupVersionKind().GroupKind() &&
+		(o.Namespace == other.Namespace || k8sutil.IsClusterScoped(o.Kind)) &&
+		o.Name == other.Name
+}
+
+// stackInventory returns the objects of the rendered manifests.
+func stackInventory(rendered []builder.Manifests) ([]StackObject, error) {
+	var inventory []StackObject
+	for _, manifests := range rendered {
+		for _, obj := range manifests.Manifests() {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object type %T", obj)
+			}
+
+			obj := StackObject{
+				APIVersion: u.GetAPIVersion(),
+				Kind:       u.GetKind(),
+				Namespace:  u.GetNamespace(),
+				Name:       u.GetName(),
+			}
+			if k8sutil.IsClusterScoped(obj.Kind) {
+				obj.Namespace = ""
+			}
+			inventory = append(inventory, obj)
+		}
+	}
+
+	return inventory, nil
+}
+
+// loadStackInventory returns the inventory recorded in the anchor ConfigMap
+// of the stack, empty when the stack doesn't exist. The inventory of the
+// stacks created before it was recorded is rendered from their recorded
+// parameters.
+func loadStackInventory(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, stack string) ([]StackObject, error) {
+	cm, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Get(ctx, StackAnchor(stack), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while getting stack anchor ConfigMap: %w", err)
+	}
+
+	if data, ok := cm.Data[inventoryKey]; ok {
+		var inventory []StackObject
+		if err := json.Unmarshal([]byte(data), &inventory); err != nil {
+			return nil, fmt.Errorf("error while decoding stack inventory: %w", err)
+		}
+		return inventory, nil
+	}
+
+	if _, ok := cm.Data[parametersKey]; !ok {
+		return nil, nil
+	}
+
+	params, err := decodeStackParameters(cm)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderStackInventory(newStackOwner(cm), namespace, params)
+}
+
+// renderStackInventory returns the inventory of the objects rendered with
+// the parameters.
+func renderStackInventory(owner *stackOwner, namespace string, params StackParameters) ([]StackObject, error) {
+	rendered, err := renderStack(owner, namespace, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return stackInventory(rendered)
+}
+
+// mergeInventories returns the objects of the current inventory followed by
+// the objects of the previous one which are missing from it.
+func mergeInventories(previous, current []StackObject) []StackObject {
+	merged := slices.Clone(current)
+	for _, obj := range previous {
+		if !slices.ContainsFunc(merged, obj.same) {
+			merged = append(merged, obj)
+		}
+	}
+	return merged
+}
+
+// pruneStack deletes the objects of the previous inventory of the stack which
+// are missing from the current one, such as the objects of a component
+// removed from the profile. The objects are deleted in the reverse order of
+// their creation, the workloads before their RBAC resources.
+func pruneStack(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, previous, current []StackObject) error {
+	for _, obj := range slices.Backward(previous) {
+		if slices.ContainsFunc(current, obj.same) {
+			continue
+		}
+
+		err := k8sutil.ResourceClient(clientSets, obj.groupVersionKind(), obj.Namespace).
+			Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("error while pruning %s %s: %w", obj.Kind, obj.Name, err)
+		}
+
+		logger.Info("pruned obsolete object", "kind", obj.Kind, "name", obj.Name, "namespace", obj.Namespace)
+	}
+
+	return nil
+}
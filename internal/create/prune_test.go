@@ -0,0 +1,180 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// liveObject returns the object as stored by the API server, which clears
+// the namespace of the cluster-scoped objects.
+func liveObject(desired *unstructured.Unstructured) *unstructured.Unstructured {
+	u := desired.DeepCopy()
+	if k8sutil.IsClusterScoped(u.GetKind()) {
+		u.SetNamespace("")
+	}
+	return u
+}
+
+func TestPruneStack(t *testing.T) {
+	anchor := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      StackAnchorName,
+			Namespace: metav1.NamespaceDefault,
+			UID:       "anchor-uid",
+		},
+	}
+	owner := newStackOwner(anchor)
+
+	previousParams := StackParameters{
+		Version: "0.78.2",
+		Profile: Profile{
+			Name:               "minimal",
+			PrometheusReplicas: 1,
+			NodeExporter:       true,
+			Pushgateway:        true,
+		},
+	}
+	currentParams := previousParams
+	currentParams.Profile.NodeExporter = false
+
+	previousRendered, err := renderStack(owner, metav1.NamespaceDefault, previousParams)
+	require.NoError(t, err)
+	previous, err := stackInventory(previousRendered)
+	require.NoError(t, err)
+
+	currentRendered, err := renderStack(owner, metav1.NamespaceDefault, currentParams)
+	require.NoError(t, err)
+	current, err := stackInventory(currentRendered)
+	require.NoError(t, err)
+
+	var objects []runtime.Object
+	for _, manifests := range previousRendered {
+		for _, obj := range manifests.Manifests() {
+			objects = append(objects, liveObject(obj.(*unstructured.Unstructured)))
+		}
+	}
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithDynamicObjects(objects...))
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	require.NoError(t, pruneStack(context.Background(), logger, clientSets, previous, current))
+
+	for _, obj := range previous {
+		_, err := k8sutil.ResourceClient(clientSets, obj.groupVersionKind(), obj.Namespace).
+			Get(context.Background(), obj.Name, metav1.GetOptions{})
+
+		if slices.ContainsFunc(current, obj.same) {
+			assert.NoError(t, err, "%s %s", obj.Kind, obj.Name)
+			continue
+		}
+		assert.True(t, errors.IsNotFound(err), "%s %s should be pruned", obj.Kind, obj.Name)
+		assert.Contains(t, obj.Name, "node-exporter")
+	}
+
+	// Pruning again ignores the objects which are already deleted.
+	require.NoError(t, pruneStack(context.Background(), logger, clientSets, previous, current))
+}
+
+func TestLoadStackInventory(t *testing.T) {
+	params := StackParameters{
+		Version: "0.78.2",
+		Profile: Profile{
+			Name:               "minimal",
+			PrometheusReplicas: 1,
+		},
+	}
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+
+	inventory := []StackObject{{APIVersion: "v1", Kind: "Service", Namespace: metav1.NamespaceDefault, Name: "prometheus"}}
+	inventoryData, err := json.Marshal(inventory)
+	require.NoError(t, err)
+
+	newAnchor := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      StackAnchorName,
+				Namespace: metav1.NamespaceDefault,
+			},
+			Data: data,
+		}
+	}
+
+	type testCase struct {
+		name     string
+		anchor   *corev1.ConfigMap
+		expected func(t *testing.T, got []StackObject)
+	}
+
+	tests := []testCase{
+		{
+			name: "NoStack",
+			expected: func(t *testing.T, got []StackObject) {
+				assert.Empty(t, got)
+			},
+		},
+		{
+			name:   "NoParameters",
+			anchor: newAnchor(nil),
+			expected: func(t *testing.T, got []StackObject) {
+				assert.Empty(t, got)
+			},
+		},
+		{
+			name:   "RecordedInventory",
+			anchor: newAnchor(map[string]string{parametersKey: string(data), inventoryKey: string(inventoryData)}),
+			expected: func(t *testing.T, got []StackObject) {
+				assert.Equal(t, inventory, got)
+			},
+		},
+		{
+			name:   "RenderedFromParameters",
+			anchor: newAnchor(map[string]string{parametersKey: string(data)}),
+			expected: func(t *testing.T, got []StackObject) {
+				assert.Contains(t, got, StackObject{APIVersion: "monitoring.coreos.com/v1", Kind: "Prometheus", Namespace: metav1.NamespaceDefault, Name: "prometheus"})
+				assert.NotContains(t, got, StackObject{APIVersion: "apps/v1", Kind: "DaemonSet", Namespace: metav1.NamespaceDefault, Name: "node-exporter"})
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if tc.anchor != nil {
+				objects = append(objects, tc.anchor)
+			}
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(objects...))
+
+			got, err := loadStackInventory(context.Background(), clientSets, metav1.NamespaceDefault, "")
+			require.NoError(t, err)
+			tc.expected(t, got)
+		})
+	}
+}
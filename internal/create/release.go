@@ -0,0 +1,412 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// VerifyMode defines how the verification failures of the release files are
+// handled.
+type VerifyMode string
+
+const (
+	// VerifyWarn logs the verification failures.
+	VerifyWarn VerifyMode = "warn"
+	// VerifyEnforce aborts on verification failures.
+	VerifyEnforce VerifyMode = "enforce"
+	// VerifySkip disables the verification.
+	VerifySkip VerifyMode = "skip"
+)
+
+// ParseVerifyMode returns the verification mode with the given name.
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	switch mode := VerifyMode(strings.ToLower(s)); mode {
+	case VerifyWarn, VerifyEnforce, VerifySkip:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown verification mode %s, must be one of: warn, enforce, skip", s)
+	}
+}
+
+// BundleAsset is the release asset holding the CustomResourceDefinitions and
+// the RBAC objects of the Prometheus Operator, against which the downloaded
+// files are verified.
+const BundleAsset = "bundle.yaml"
+
+// Verification configures the verification of the files of a release.
+type Verification struct {
+	Mode VerifyMode
+	// Key is the public key of the release signing BundleAsset. It is the
+	// trust root of the verification, the signature being checked offline.
+	Key crypto.PublicKey
+	// Signature is the signature of BundleAsset made with the key.
+	Signature *SignatureBundle
+}
+
+// SignatureBundle is the signature of a file as found in a cosign bundle.
+type SignatureBundle struct {
+	Signature []byte
+	// Digest is the SHA-256 digest of the signed file, nil when the bundle
+	// doesn't record it.
+	Digest []byte
+}
+
+// ParseSignatureBundle returns the signature held by a cosign bundle, either
+// a Sigstore bundle as written by cosign sign-blob --new-bundle-format or a
+// bundle in the legacy format of cosign sign-blob --bundle. Only the signature
+// is used, the transparency log entry isn't verified.
+func ParseSignatureBundle(data []byte) (*SignatureBundle, error) {
+	var bundle struct {
+		// Legacy cosign format.
+		Base64Signature string `json:"base64Signature"`
+		// Sigstore bundle format.
+		MessageSignature *struct {
+			MessageDigest *struct {
+				Algorithm string `json:"algorithm"`
+				Digest    []byte `json:"digest"`
+			} `json:"messageDigest"`
+			Signature []byte `json:"signature"`
+		} `json:"messageSignature"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("error while decoding signature bundle: %w", err)
+	}
+
+	switch {
+	case bundle.MessageSignature != nil && len(bundle.MessageSignature.Signature) > 0:
+		signature := &SignatureBundle{Signature: bundle.MessageSignature.Signature}
+		if digest := bundle.MessageSignature.MessageDigest; digest != nil {
+			if digest.Algorithm != "SHA2_256" {
+				return nil, fmt.Errorf("unsupported digest algorithm %s, must be SHA2_256", digest.Algorithm)
+			}
+			signature.Digest = digest.Digest
+		}
+		return signature, nil
+	case bundle.Base64Signature != "":
+		sig, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+		if err != nil {
+			return nil, fmt.Errorf("error while decoding signature: %w", err)
+		}
+		return &SignatureBundle{Signature: sig}, nil
+	default:
+		return nil, errors.New("no signature found in bundle")
+	}
+}
+
+// ParseSignatureKey returns the public key of a PEM-encoded ECDSA or Ed25519
+// key, such as a cosign.pub file.
+func ParseSignatureKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing public key: %w", err)
+	}
+
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T, must be ECDSA or Ed25519", key)
+	}
+}
+
+// release downloads the files of a Prometheus Operator release. Unless the
+// checks are skipped, the files are pinned to the commit of the release tag,
+// which GitHub must report as verified, and their content must match the Git
+// blob listed by GitHub for this commit. When a signature key is given, the
+// BundleAsset of the release must also be signed by it and every object of
+// the files must be found as is in this bundle.
+type release struct {
+	logger  *slog.Logger
+	client  *github.Client
+	version string
+	ref     string
+	mode    VerifyMode
+	// bundle holds the objects of the signed BundleAsset by kind, namespace
+	// and name, nil when the signature isn't verified.
+	bundle map[string]map[string]any
+}
+
+func newRelease(ctx context.Context, logger *slog.Logger, client *github.Client, version string, verification Verification) (*release, error) {
+	r := &release{
+		logger:  logger,
+		client:  client,
+		version: version,
+		ref:     fmt.Sprintf("v%s", version),
+		mode:    verification.Mode,
+	}
+
+	if r.mode == VerifySkip {
+		return r, nil
+	}
+
+	switch {
+	case verification.Key == nil && r.mode == VerifyWarn:
+		// The signature is an opt-in check, there's nothing to warn about.
+		logger.Debug("no signature key given, the signature of the release isn't verified", "version", version)
+	case verification.Key == nil:
+		if err := r.verificationFailed("no signature key given, the signature of release v%s can't be verified", version); err != nil {
+			return nil, err
+		}
+	case verification.Signature == nil:
+		if err := r.verificationFailed("no signature given for %s of release v%s", BundleAsset, version); err != nil {
+			return nil, err
+		}
+	default:
+		bundle, err := r.signedBundle(ctx, verification)
+		if err != nil {
+			if err := r.verificationFailed("%s", err); err != nil {
+				return nil, err
+			}
+		}
+		r.bundle = bundle
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	commit, _, err := client.Repositories.GetCommit(ctx, "prometheus-operator", "prometheus-operator", r.ref, nil)
+	if err != nil {
+		// The files are downloaded from the tag instead.
+		if err := r.verificationFailed("error while getting commit of release %s: %s", r.ref, err); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	// Pin the downloads to the commit, the tag could be moved in between.
+	r.ref = commit.GetSHA()
+
+	commitVerification := commit.GetCommit().GetVerification()
+	if !commitVerification.GetVerified() {
+		if err := r.verificationFailed("GitHub doesn't report commit %s of release v%s as verified (reason: %s)", commit.GetSHA(), version, commitVerification.GetReason()); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// download returns the content of a file of the release.
+func (r *release) download(ctx context.Context, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	reader, content, _, err := r.client.Repositories.DownloadContentsWithMeta(
+		ctx,
+		"prometheus-operator",
+		"prometheus-operator",
+		path,
+		&github.RepositoryContentGetOptions{
+			Ref: r.ref,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error while downloading %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading %s: %w", path, err)
+	}
+
+	if r.mode == VerifySkip {
+		return data, nil
+	}
+
+	if sha := gitBlobSHA(data); sha != content.GetSHA() {
+		if err := r.verificationFailed("content of %s doesn't match release v%s (blob %s, expected %s)", path, r.version, sha, content.GetSHA()); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.bundle == nil {
+		return data, nil
+	}
+
+	objects, err := decodeObjects(data)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding %s: %w", path, err)
+	}
+
+	for key, obj := range objects {
+		expected, ok := r.bundle[key]
+		if !ok {
+			if err := r.verificationFailed("%s of %s isn't found in the signed %s of release v%s", key, path, BundleAsset, r.version); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(obj, expected) {
+			if err := r.verificationFailed("%s of %s doesn't match the signed %s of release v%s", key, path, BundleAsset, r.version); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// signedBundle downloads the BundleAsset of the release and returns its
+// objects once its signature is verified.
+func (r *release) signedBundle(ctx context.Context, verification Verification) (map[string]map[string]any, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	rel, _, err := r.client.Repositories.GetReleaseByTag(ctx, "prometheus-operator", "prometheus-operator", fmt.Sprintf("v%s", r.version))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting release v%s: %w", r.version, err)
+	}
+
+	data, err := r.downloadAsset(ctx, rel, BundleAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(verification.Key, data, verification.Signature); err != nil {
+		return nil, fmt.Errorf("signature of %s of release v%s: %w", BundleAsset, r.version, err)
+	}
+
+	objects, err := decodeObjects(data)
+	if err != nil {
+		return nil, fmt.Errorf("error while decoding %s of release v%s: %w", BundleAsset, r.version, err)
+	}
+
+	return objects, nil
+}
+
+// downloadAsset returns the content of the asset of the release with the
+// given name.
+func (r *release) downloadAsset(ctx context.Context, rel *github.RepositoryRelease, name string) ([]byte, error) {
+	for _, asset := range rel.Assets {
+		if asset.GetName() != name {
+			continue
+		}
+
+		reader, _, err := r.client.Repositories.DownloadReleaseAsset(ctx, "prometheus-operator", "prometheus-operator", asset.GetID(), http.DefaultClient)
+		if err != nil {
+			return nil, fmt.Errorf("error while downloading %s: %w", name, err)
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %s: %w", name, err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("release v%s has no %s asset", r.version, name)
+}
+
+// verifySignature checks the signature of the data, an ASN.1 ECDSA signature
+// of its SHA-256 digest or an Ed25519 signature of the data.
+func verifySignature(key crypto.PublicKey, data []byte, signature *SignatureBundle) error {
+	digest := sha256.Sum256(data)
+	if signature.Digest != nil && !bytes.Equal(signature.Digest, digest[:]) {
+		return errors.New("digest doesn't match the signature bundle")
+	}
+
+	var valid bool
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature.Signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, signature.Signature)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	if !valid {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// decodeObjects returns the objects of a YAML or JSON stream by kind,
+// namespace and name.
+func decodeObjects(data []byte) (map[string]map[string]any, error) {
+	objects := map[string]map[string]any{}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var obj map[string]any
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if obj == nil {
+			continue
+		}
+
+		u := unstructured.Unstructured{Object: obj}
+		objects[fmt.Sprintf("%s %s", u.GetKind(), types.NamespacedName{Namespace: u.GetNamespace(), Name: u.GetName()})] = obj
+	}
+
+	return objects, nil
+}
+
+// verificationFailed returns an error in enforce mode, and only logs a
+// warning otherwise.
+func (r *release) verificationFailed(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if r.mode == VerifyEnforce {
+		return fmt.Errorf("verification failed: %s", msg)
+	}
+
+	r.logger.Warn("verification failed", "error", msg)
+	return nil
+}
+
+// gitBlobSHA returns the identifier of a Git blob with the given content.
+func gitBlobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,383 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitBlobSHA(t *testing.T) {
+	assert.Equal(t, "b6fc4c620b67d95f953a5c1c1230aaab5db5a1b0", gitBlobSHA([]byte("hello")))
+}
+
+func TestParseSignatureKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	parsed, err := ParseSignatureKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(parsed))
+
+	_, err = ParseSignatureKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestParseSignatureBundle(t *testing.T) {
+	signature := []byte("signature")
+	digest := sha256.Sum256([]byte("hello"))
+
+	legacy, err := ParseSignatureBundle([]byte(fmt.Sprintf(`{"base64Signature": %q, "rekorBundle": {}}`, base64.StdEncoding.EncodeToString(signature))))
+	require.NoError(t, err)
+	assert.Equal(t, &SignatureBundle{Signature: signature}, legacy)
+
+	sigstore, err := ParseSignatureBundle([]byte(fmt.Sprintf(
+		`{"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json", "messageSignature": {"messageDigest": {"algorithm": "SHA2_256", "digest": %q}, "signature": %q}}`,
+		base64.StdEncoding.EncodeToString(digest[:]),
+		base64.StdEncoding.EncodeToString(signature),
+	)))
+	require.NoError(t, err)
+	assert.Equal(t, &SignatureBundle{Signature: signature, Digest: digest[:]}, sigstore)
+
+	_, err = ParseSignatureBundle([]byte(`{"messageSignature": {"messageDigest": {"algorithm": "SHA2_384", "digest": ""}, "signature": "c2lnbmF0dXJl"}}`))
+	assert.Error(t, err)
+
+	_, err = ParseSignatureBundle([]byte(`{"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json"}`))
+	assert.Error(t, err)
+
+	_, err = ParseSignatureBundle([]byte("not a bundle"))
+	assert.Error(t, err)
+}
+
+const (
+	releaseFile = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: prometheus-operator
+rules:
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  verbs:
+  - list
+`
+	// releaseBundle holds the object of releaseFile in another format.
+	releaseBundle = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: monitoring
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata: {name: prometheus-operator}
+rules:
+- {apiGroups: [""], resources: [pods], verbs: [list]}
+`
+	tamperedBundle = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata: {name: prometheus-operator}
+rules:
+- {apiGroups: [""], resources: [pods], verbs: [list, delete]}
+`
+	otherBundle = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: monitoring
+`
+)
+
+func TestReleaseDownload(t *testing.T) {
+	releaseKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	type testCase struct {
+		name     string
+		mode     VerifyMode
+		verified bool
+		// commitError makes GitHub fail to return the commit of the tag.
+		commitError bool
+		blobSHA     string
+		// key verifying the signature, none when nil.
+		key *ecdsa.PrivateKey
+		// noSignature leaves the signature of the bundle out.
+		noSignature bool
+		// bundle is the content of the bundle asset, none when empty.
+		bundle      string
+		shouldFail  bool
+		shouldWarn  bool
+		expectedRef string
+	}
+
+	tests := []testCase{
+		{
+			name:        "VerifiedCommit",
+			mode:        VerifyEnforce,
+			verified:    true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			key:         releaseKey,
+			bundle:      releaseBundle,
+			expectedRef: "0123456789abcdef",
+		},
+		{
+			name:       "UnverifiedCommitEnforced",
+			mode:       VerifyEnforce,
+			blobSHA:    gitBlobSHA([]byte(releaseFile)),
+			key:        releaseKey,
+			bundle:     releaseBundle,
+			shouldFail: true,
+		},
+		{
+			name:        "UnverifiedCommitWarned",
+			mode:        VerifyWarn,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			key:         releaseKey,
+			bundle:      releaseBundle,
+			shouldWarn:  true,
+			expectedRef: "0123456789abcdef",
+		},
+		{
+			name:        "CommitErrorEnforced",
+			mode:        VerifyEnforce,
+			commitError: true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			key:         releaseKey,
+			bundle:      releaseBundle,
+			shouldFail:  true,
+		},
+		{
+			name:        "CommitErrorWarned",
+			mode:        VerifyWarn,
+			commitError: true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			shouldWarn:  true,
+			expectedRef: "v0.75.1",
+		},
+		{
+			name:       "ContentMismatchEnforced",
+			mode:       VerifyEnforce,
+			verified:   true,
+			blobSHA:    gitBlobSHA([]byte("tampered")),
+			key:        releaseKey,
+			bundle:     releaseBundle,
+			shouldFail: true,
+		},
+		{
+			name:        "ContentMismatchSkipped",
+			mode:        VerifySkip,
+			blobSHA:     gitBlobSHA([]byte("tampered")),
+			expectedRef: "v0.75.1",
+		},
+		{
+			name:       "NoKeyEnforced",
+			mode:       VerifyEnforce,
+			verified:   true,
+			blobSHA:    gitBlobSHA([]byte(releaseFile)),
+			bundle:     releaseBundle,
+			shouldFail: true,
+		},
+		{
+			// The signature isn't verified without a key, quietly.
+			name:        "NoKeyWarned",
+			mode:        VerifyWarn,
+			verified:    true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			expectedRef: "0123456789abcdef",
+		},
+		{
+			name:        "NoSignatureEnforced",
+			mode:        VerifyEnforce,
+			verified:    true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			key:         releaseKey,
+			noSignature: true,
+			bundle:      releaseBundle,
+			shouldFail:  true,
+		},
+		{
+			name:       "NoBundleEnforced",
+			mode:       VerifyEnforce,
+			verified:   true,
+			blobSHA:    gitBlobSHA([]byte(releaseFile)),
+			key:        releaseKey,
+			shouldFail: true,
+		},
+		{
+			name:       "InvalidSignatureEnforced",
+			mode:       VerifyEnforce,
+			verified:   true,
+			blobSHA:    gitBlobSHA([]byte(releaseFile)),
+			key:        otherKey,
+			bundle:     releaseBundle,
+			shouldFail: true,
+		},
+		{
+			name:        "InvalidSignatureWarned",
+			mode:        VerifyWarn,
+			verified:    true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			key:         otherKey,
+			bundle:      releaseBundle,
+			shouldWarn:  true,
+			expectedRef: "0123456789abcdef",
+		},
+		{
+			name:       "BundleMismatchEnforced",
+			mode:       VerifyEnforce,
+			verified:   true,
+			blobSHA:    gitBlobSHA([]byte(releaseFile)),
+			key:        releaseKey,
+			bundle:     tamperedBundle,
+			shouldFail: true,
+		},
+		{
+			name:        "BundleMismatchWarned",
+			mode:        VerifyWarn,
+			verified:    true,
+			blobSHA:     gitBlobSHA([]byte(releaseFile)),
+			key:         releaseKey,
+			bundle:      tamperedBundle,
+			shouldWarn:  true,
+			expectedRef: "0123456789abcdef",
+		},
+		{
+			name:       "MissingFromBundleEnforced",
+			mode:       VerifyEnforce,
+			verified:   true,
+			blobSHA:    gitBlobSHA([]byte(releaseFile)),
+			key:        releaseKey,
+			bundle:     otherBundle,
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			var ref string
+			mux.HandleFunc("/repos/prometheus-operator/prometheus-operator/commits/v0.75.1", func(w http.ResponseWriter, _ *http.Request) {
+				if tc.commitError {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"sha": "0123456789abcdef",
+					"commit": map[string]any{
+						"verification": map[string]any{
+							"verified": tc.verified,
+							"reason":   "unsigned",
+						},
+					},
+				})
+			})
+			mux.HandleFunc("/repos/prometheus-operator/prometheus-operator/contents/example", func(w http.ResponseWriter, r *http.Request) {
+				ref = r.URL.Query().Get("ref")
+				_ = json.NewEncoder(w).Encode([]map[string]any{
+					{
+						"name":         "file.yaml",
+						"sha":          tc.blobSHA,
+						"download_url": server.URL + "/raw/file.yaml",
+					},
+				})
+			})
+			mux.HandleFunc("/raw/file.yaml", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, releaseFile)
+			})
+
+			assets := []map[string]any{}
+			if tc.bundle != "" {
+				assets = append(assets, map[string]any{"id": 1, "name": BundleAsset})
+			}
+			mux.HandleFunc("/repos/prometheus-operator/prometheus-operator/releases/tags/v0.75.1", func(w http.ResponseWriter, _ *http.Request) {
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"tag_name": "v0.75.1",
+					"assets":   assets,
+				})
+			})
+			mux.HandleFunc("/repos/prometheus-operator/prometheus-operator/releases/assets/1", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, tc.bundle)
+			})
+
+			client := github.NewClient(nil)
+			baseURL, err := url.Parse(server.URL + "/")
+			require.NoError(t, err)
+			client.BaseURL = baseURL
+
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+			verification := Verification{Mode: tc.mode}
+			if tc.key != nil {
+				verification.Key = &tc.key.PublicKey
+			}
+			if !tc.noSignature {
+				// The bundle is always signed by the release key.
+				digest := sha256.Sum256([]byte(tc.bundle))
+				signature, err := ecdsa.SignASN1(rand.Reader, releaseKey, digest[:])
+				require.NoError(t, err)
+				verification.Signature = &SignatureBundle{Signature: signature, Digest: digest[:]}
+			}
+
+			rel, err := newRelease(context.Background(), logger, client, "0.75.1", verification)
+			if err == nil {
+				var data []byte
+				data, err = rel.download(context.Background(), "example/file.yaml")
+				if err == nil {
+					assert.Equal(t, releaseFile, string(data))
+				}
+			}
+
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRef, ref)
+			if tc.shouldWarn {
+				assert.Contains(t, logs.String(), "verification failed")
+			} else {
+				assert.Empty(t, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// seriesPerNode estimates the series of the node exporter, the kubelet
+	// and cAdvisor for a node.
+	seriesPerNode = 1500
+	// seriesPerPod estimates the cAdvisor and kube-state-metrics series of a
+	// Pod.
+	seriesPerPod = 250
+	// bytesPerSeries is the memory Prometheus needs per active series,
+	// including headroom for compactions and queries.
+	bytesPerSeries = 8 * 1024
+	// seriesPerCPU is the number of active series a CPU core ingests.
+	seriesPerCPU = 1_000_000
+	// podsPerShard is the number of Pods above which kube-state-metrics
+	// gets another shard.
+	podsPerShard = 10_000
+)
+
+var (
+	minPrometheusMemory = resource.MustParse("400Mi")
+	minPrometheusCPU    = resource.MustParse("100m")
+)
+
+// ClusterSize is the number of nodes and Pods of a cluster.
+type ClusterSize struct {
+	Nodes int
+	Pods  int
+}
+
+// Sizing is the resource sizing recommended for the components of the stack.
+type Sizing struct {
+	// Series is the estimated number of active series scraped by Prometheus.
+	Series                 int64
+	PrometheusResources    corev1.ResourceList
+	PrometheusRetention    string
+	KubeStateMetricsShards int32
+}
+
+// MeasureCluster counts the nodes and Pods of the cluster.
+func MeasureCluster(ctx context.Context, clientSets *k8sutil.ClientSets) (ClusterSize, error) {
+	nodes, err := clientSets.KClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ClusterSize{}, fmt.Errorf("error while listing nodes: %w", err)
+	}
+
+	pods, err := clientSets.KClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ClusterSize{}, fmt.Errorf("error while listing pods: %w", err)
+	}
+
+	return ClusterSize{
+		Nodes: len(nodes.Items),
+		Pods:  len(pods.Items),
+	}, nil
+}
+
+// RecommendSizing estimates the number of series of a cluster from its size
+// and derives the Prometheus requests, the retention and the number of
+// kube-state-metrics shards from it. The retention is shortened on large
+// clusters to bound the disk usage.
+func RecommendSizing(size ClusterSize) Sizing {
+	series := int64(size.Nodes)*seriesPerNode + int64(size.Pods)*seriesPerPod
+
+	memory := resource.NewQuantity(roundUp(series*bytesPerSeries, 256*1024*1024), resource.BinarySI)
+	if memory.Cmp(minPrometheusMemory) < 0 {
+		memory = ptr.To(minPrometheusMemory.DeepCopy())
+	}
+
+	cpu := resource.NewMilliQuantity(roundUp(series*1000/seriesPerCPU, 100), resource.DecimalSI)
+	if cpu.Cmp(minPrometheusCPU) < 0 {
+		cpu = ptr.To(minPrometheusCPU.DeepCopy())
+	}
+
+	var retention string
+	switch {
+	case series < 1_000_000:
+		retention = "15d"
+	case series < 5_000_000:
+		retention = "7d"
+	default:
+		retention = "3d"
+	}
+
+	return Sizing{
+		Series: series,
+		PrometheusResources: corev1.ResourceList{
+			corev1.ResourceCPU:    *cpu,
+			corev1.ResourceMemory: *memory,
+		},
+		PrometheusRetention:    retention,
+		KubeStateMetricsShards: int32(max(1, (size.Pods+podsPerShard-1)/podsPerShard)),
+	}
+}
+
+// ApplySizing overrides the resource settings of the profile with the
+// recommended sizing. The retention is left untouched for agents which don't
+// store samples.
+func (p *Profile) ApplySizing(sizing Sizing) {
+	p.PrometheusResources = sizing.PrometheusResources
+	if !p.Agent {
+		p.PrometheusRetention = sizing.PrometheusRetention
+	}
+	p.KubeStateMetricsShards = sizing.KubeStateMetricsShards
+}
+
+// roundUp rounds v up to a multiple of step.
+func roundUp(v, step int64) int64 {
+	return (v + step - 1) / step * step
+}
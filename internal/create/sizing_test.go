@@ -0,0 +1,115 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRecommendSizing(t *testing.T) {
+	type testCase struct {
+		name              string
+		size              ClusterSize
+		expectedCPU       string
+		expectedMemory    string
+		expectedRetention string
+		expectedShards    int32
+	}
+
+	tests := []testCase{
+		{
+			name:              "EmptyCluster",
+			expectedCPU:       "100m",
+			expectedMemory:    "400Mi",
+			expectedRetention: "15d",
+			expectedShards:    1,
+		},
+		{
+			name:              "SmallCluster",
+			size:              ClusterSize{Nodes: 10, Pods: 300},
+			expectedCPU:       "100m",
+			expectedMemory:    "768Mi",
+			expectedRetention: "15d",
+			expectedShards:    1,
+		},
+		{
+			name:              "MediumCluster",
+			size:              ClusterSize{Nodes: 200, Pods: 6000},
+			expectedCPU:       "1800m",
+			expectedMemory:    "14080Mi",
+			expectedRetention: "7d",
+			expectedShards:    1,
+		},
+		{
+			name:              "LargeCluster",
+			size:              ClusterSize{Nodes: 1000, Pods: 30000},
+			expectedCPU:       "9",
+			expectedMemory:    "70400Mi",
+			expectedRetention: "3d",
+			expectedShards:    3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sizing := RecommendSizing(tc.size)
+			assert.Equal(t, 0, sizing.PrometheusResources.Cpu().Cmp(resource.MustParse(tc.expectedCPU)), "cpu %s", sizing.PrometheusResources.Cpu())
+			assert.Equal(t, 0, sizing.PrometheusResources.Memory().Cmp(resource.MustParse(tc.expectedMemory)), "memory %s", sizing.PrometheusResources.Memory())
+			assert.Equal(t, tc.expectedRetention, sizing.PrometheusRetention)
+			assert.Equal(t, tc.expectedShards, sizing.KubeStateMetricsShards)
+		})
+	}
+}
+
+func TestApplySizing(t *testing.T) {
+	sizing := RecommendSizing(ClusterSize{Nodes: 1000, Pods: 30000})
+
+	profile, err := GetProfile("ha")
+	require.NoError(t, err)
+	profile.ApplySizing(sizing)
+	assert.Equal(t, "3d", profile.PrometheusRetention)
+	assert.Equal(t, int32(3), profile.KubeStateMetricsShards)
+
+	agent, err := GetProfile("edge")
+	require.NoError(t, err)
+	agent.ApplySizing(sizing)
+	assert.Empty(t, agent.PrometheusRetention)
+	assert.Equal(t, sizing.PrometheusResources, agent.PrometheusResources)
+}
+
+func TestMeasureCluster(t *testing.T) {
+	objects := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	}
+	for i := range 5 {
+		objects = append(objects, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: fmt.Sprintf("ns-%d", i%2)}})
+	}
+
+	size, err := MeasureCluster(context.Background(), &k8sutil.ClientSets{KClient: fake.NewSimpleClientset(objects...)})
+	require.NoError(t, err)
+	assert.Equal(t, ClusterSize{Nodes: 2, Pods: 5}, size)
+}
@@ -15,56 +15,287 @@
 package create
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v62/github"
-	"github.com/prometheus-operator/poctl/internal/builder"
+	"github.com/prometheus-operator/poctl/internal/admission"
+	"github.com/prometheus-operator/poctl/internal/crds"
 	"github.com/prometheus-operator/poctl/internal/k8sutil"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"github.com/prometheus-operator/poctl/internal/resolve"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apihelpers"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
 )
 
-func RunCreateStack(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, gitHubClient *github.Client, version string) error {
-	if err := installCRDs(ctx, logger, version, clientSets, gitHubClient); err != nil {
+const (
+	// downloadTimeout bounds each request to GitHub.
+	downloadTimeout = 30 * time.Second
+	// stepTimeout bounds each step of the stack creation, such as the
+	// installation of the CRDs or the creation of a component.
+	stepTimeout = 2 * time.Minute
+	// crdPollInterval is the interval at which the conditions of the
+	// applied CRDs are checked.
+	crdPollInterval = time.Second
+	// crdEstablishedTimeout bounds the wait for the applied CRDs to be
+	// established.
+	crdEstablishedTimeout = time.Minute
+)
+
+// crdStepTimeout bounds the installation of the CRDs, which downloads each
+// of them before applying them within stepTimeout and waiting for them to be
+// established.
+var crdStepTimeout = time.Duration(len(crdResources))*downloadTimeout + stepTimeout + crdEstablishedTimeout
+
+// RunCreateStack creates the stack described by the profile. The failure of
+// the CRDs or of the Prometheus Operator stops the creation, while the other
+// components are created independently of each other: the returned summary
+// reports the outcome of each component and the error joins the errors of
+// the failed ones.
+func RunCreateStack(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, gitHubClient *github.Client, version string, verification Verification, profile Profile) (*Summary, error) {
+	summary := newSummary(profile)
+
+	rel, err := newRelease(ctx, logger, gitHubClient, version, verification)
+	if err != nil {
+		logger.Error("error while verifying release", "error", err)
+		return summary, err
+	}
+
+	if err := runStepWithin(ctx, crdStepTimeout, func(ctx context.Context) error { return installCRDs(ctx, logger, clientSets, rel) }); err != nil {
 		logger.Error("error while installing CRDs", "error", err)
+		summary.record(componentCRDs, ComponentFailed, err)
+		return summary, summary.Err()
+	}
+	summary.record(componentCRDs, ComponentCreated, nil)
+
+	rules, err := downloadOperatorRules(ctx, rel)
+	if err != nil {
+		// Falling back to unverified rules defeats the enforce mode.
+		if verification.Mode == VerifyEnforce {
+			logger.Error("error while downloading Prometheus Operator RBAC rules", "error", err)
+			summary.record(componentOperator, ComponentFailed, err)
+			return summary, summary.Err()
+		}
+		logger.Warn("falling back to the built-in Prometheus Operator RBAC rules", "version", version, "error", err)
+	}
+
+	return summary, createStack(ctx, logger, clientSets, summary, version, rules, profile)
+}
+
+// createStack creates the components of the stack once the CRDs are
+// installed, recording their outcome in the summary, and records the
+// parameters of the stack in its anchor ConfigMap once every component is
+// created.
+func createStack(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, summary *Summary, version string, rules []rbacv1.PolicyRule, profile Profile) error {
+	validator := crds.NewValidator(clientSets.APIExtensionsClient)
+
+	previous, err := loadStackInventory(ctx, clientSets, metav1.NamespaceDefault, profile.Stack)
+	if err != nil {
+		logger.Warn("the obsolete objects of the stack won't be pruned", "error", err)
+	}
+
+	owner, err := createStackAnchor(ctx, clientSets, metav1.NamespaceDefault, profile.Stack)
+	if err != nil {
+		logger.Error("error while creating stack anchor", "error", err)
 		return err
 	}
 
-	if err := createPrometheusOperator(ctx, clientSets, metav1.NamespaceDefault, version); err != nil {
+	checkImagePullSecrets(ctx, logger, clientSets, metav1.NamespaceDefault, profile.ImagePullSecrets)
+	checkPriorityClass(ctx, logger, clientSets, profile.Scheduling.PriorityClassName)
+
+	if err := runStep(ctx, func(ctx context.Context) error {
+		return createPrometheusOperator(ctx, clientSets, validator, owner, metav1.NamespaceDefault, version, rules, profile)
+	}); err != nil {
 		logger.Error("error while creating Prometheus Operator", "error", err)
+		summary.record(componentOperator, ComponentFailed, err)
+		return summary.Err()
+	}
+	summary.record(componentOperator, ComponentCreated, nil)
+
+	if err := interrupted(ctx); err != nil {
 		return err
 	}
 
-	if err := createPrometheus(ctx, clientSets, metav1.NamespaceDefault); err != nil {
+	err = runStep(ctx, func(ctx context.Context) error {
+		return createPrometheus(ctx, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+	})
+	if err != nil {
 		logger.Error("error while creating Prometheus", "error", err)
+	}
+	summary.record(componentPrometheus, ComponentCreated, err)
+
+	if profile.Agent && profile.RemoteWriteURL == "" && profile.OTLPEndpoint == "" {
+		logger.Warn("the PrometheusAgent doesn't store samples locally, configure remote write to forward them", "profile", profile.Name)
+	}
+
+	if err := interrupted(ctx); err != nil {
 		return err
 	}
 
-	if err := createAlertManager(ctx, clientSets, metav1.NamespaceDefault); err != nil {
-		logger.Error("error while creating AlertManager", "error", err)
+	if profile.Alertmanager {
+		err := runStep(ctx, func(ctx context.Context) error {
+			return createAlertManager(ctx, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+		})
+		if err != nil {
+			logger.Error("error while creating AlertManager", "error", err)
+		}
+		summary.record(componentAlertmanager, ComponentCreated, err)
+	}
+
+	if err := interrupted(ctx); err != nil {
 		return err
 	}
 
-	if err := createNodeExporter(ctx, clientSets, metav1.NamespaceDefault); err != nil {
-		logger.Error("error while creating NodeExporter", "error", err)
+	if profile.NodeExporter {
+		var deployed bool
+		err := runStep(ctx, func(ctx context.Context) error {
+			var err error
+			deployed, err = createNodeExporter(ctx, logger, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+			return err
+		})
+		status := ComponentCreated
+		if err != nil {
+			logger.Error("error while creating NodeExporter", "error", err)
+		} else if !deployed {
+			status = ComponentSkipped
+		}
+		summary.record(componentNodeExporter, status, err)
+		// The node exporter of another stack isn't part of the desired
+		// state of this one.
+		profile.NodeExporter = deployed
+	}
+
+	if err := interrupted(ctx); err != nil {
 		return err
 	}
 
-	if err := createKubeStateMetrics(ctx, clientSets, metav1.NamespaceDefault); err != nil {
-		logger.Error("error while creating KubeStateMetrics", "error", err)
+	if profile.KubeStateMetrics {
+		err := runStep(ctx, func(ctx context.Context) error {
+			return createKubeStateMetrics(ctx, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+		})
+		if err != nil {
+			logger.Error("error while creating KubeStateMetrics", "error", err)
+		}
+		summary.record(componentKubeStateMetrics, ComponentCreated, err)
+	}
+
+	if err := interrupted(ctx); err != nil {
 		return err
 	}
 
+	if profile.Pushgateway {
+		err := runStep(ctx, func(ctx context.Context) error {
+			return createPushgateway(ctx, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+		})
+		if err != nil {
+			logger.Error("error while creating Pushgateway", "error", err)
+		}
+		summary.record(componentPushgateway, ComponentCreated, err)
+	}
+
+	if err := interrupted(ctx); err != nil {
+		return err
+	}
+
+	if profile.BlackboxExporter {
+		err := runStep(ctx, func(ctx context.Context) error {
+			return createBlackboxExporter(ctx, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+		})
+		if err != nil {
+			logger.Error("error while creating Blackbox Exporter", "error", err)
+		}
+		summary.record(componentBlackboxExporter, ComponentCreated, err)
+	}
+
+	if err := interrupted(ctx); err != nil {
+		return err
+	}
+
+	if profile.OTLPEndpoint != "" {
+		err := runStep(ctx, func(ctx context.Context) error {
+			return createOpenTelemetryCollector(ctx, clientSets, validator, owner, metav1.NamespaceDefault, profile)
+		})
+		if err != nil {
+			logger.Error("error while creating OpenTelemetry Collector", "error", err)
+		}
+		summary.record(componentOpenTelemetryCollector, ComponentCreated, err)
+	}
+
+	params := StackParameters{
+		Version: version,
+		Profile: profile,
+		Rules:   rules,
+	}
+	inventory, renderErr := renderStackInventory(owner, metav1.NamespaceDefault, params)
+
+	// The parameters of a partial install don't describe the desired state
+	// of the stack, the previous ones stay recorded. The objects of both
+	// are recorded in the inventory, so that the next creation prunes the
+	// objects applied by this one which aren't part of the stack anymore.
+	if err := summary.Err(); err != nil {
+		if renderErr == nil {
+			if err := recordStackInventory(ctx, clientSets, metav1.NamespaceDefault, profile.Stack, mergeInventories(previous, inventory)); err != nil {
+				logger.Error("error while recording stack inventory", "error", err)
+			}
+		}
+		return err
+	}
+
+	if renderErr != nil {
+		logger.Error("error while rendering stack", "error", renderErr)
+		return renderErr
+	}
+
+	// The objects which aren't rendered anymore belong to the components
+	// removed since the previous creation of the stack.
+	if err := pruneStack(ctx, logger, clientSets, previous, inventory); err != nil {
+		logger.Error("error while pruning obsolete objects", "error", err)
+		return err
+	}
+
+	if err := recordStackParameters(ctx, clientSets, metav1.NamespaceDefault, params, inventory); err != nil {
+		logger.Error("error while recording stack parameters", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// runStep runs a step of the stack creation, bounded by stepTimeout.
+func runStep(ctx context.Context, step func(ctx context.Context) error) error {
+	return runStepWithin(ctx, stepTimeout, step)
+}
+
+// runStepWithin runs a step of the stack creation, bounded by the timeout.
+func runStepWithin(ctx context.Context, timeout time.Duration, step func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return step(ctx)
+}
+
+// interrupted returns an error when the stack creation has been cancelled,
+// leaving the remaining components not run.
+func interrupted(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("stack creation interrupted: %w", err)
+	}
 	return nil
 }
 
 var (
-	crds = []string{
+	crdResources = []string{
 		"alertmanagers",
 		"alertmanagerconfigs",
 		"podmonitors",
@@ -81,237 +312,759 @@ var (
 func installCRDs(
 	ctx context.Context,
 	logger *slog.Logger,
-	version string,
 	clientSets *k8sutil.ClientSets,
-	gitHubClient *github.Client) error {
+	rel *release) error {
 
-	nodeResource := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	crdClient := k8sutil.NewCRDClient(clientSets)
 
-	for _, crd := range crds {
-		reader, _, err := gitHubClient.Repositories.DownloadContents(
-			ctx,
-			"prometheus-operator",
-			"prometheus-operator",
-			fmt.Sprintf("example/prometheus-operator-crd/monitoring.coreos.com_%s.yaml", crd),
-			&github.RepositoryContentGetOptions{
-				Ref: fmt.Sprintf("v%s", version),
-			})
+	var names []string
 
+	for _, crd := range crdResources {
+		data, err := rel.download(ctx, fmt.Sprintf("example/prometheus-operator-crd/monitoring.coreos.com_%s.yaml", crd))
 		if err != nil {
-			return fmt.Errorf("error while downloading crds: %v", err)
+			return fmt.Errorf("error while downloading crds: %w", err)
 		}
 
-		crds, err := k8sutil.CrdDeserilezer(logger, reader)
+		crdObjs, err := k8sutil.DecodeCRDs(bytes.NewReader(data))
 		if err != nil {
-			return fmt.Errorf("error while deserializing crds: %v", err)
+			return fmt.Errorf("error while deserializing crds: %w", err)
 		}
 
-		unstructuredObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crds)
-		if err != nil {
-			return fmt.Errorf("error while converting CRDs to Unstructured: %v", err)
-		}
+		for _, crdObj := range crdObjs {
+			if err := checkConversionWebhook(ctx, clientSets, crdObj); err != nil {
+				return fmt.Errorf("error while checking conversion webhook of CRD %s: %w", crdObj.Name, err)
+			}
 
-		_, err = clientSets.DClient.Resource(nodeResource).Apply(ctx, fmt.Sprintf("%s.monitoring.coreos.com", crd), &unstructured.Unstructured{Object: unstructuredObj}, k8sutil.ApplyOption)
-
-		if err != nil {
-			return fmt.Errorf("error while applying CRD: %v", err)
+			if err := crdClient.ApplyCRD(ctx, crdObj); err != nil {
+				return err
+			}
+			names = append(names, crdObj.Name)
 		}
 
 		logger.Info("applied successfully", "CRD", crd)
 	}
 
-	return nil
+	return waitForCRDs(ctx, logger, clientSets, names)
 }
 
-func createPrometheusOperator(
-	ctx context.Context,
-	clientSets *k8sutil.ClientSets,
-	namespace, version string) error {
-	manifests := builder.NewOperator(namespace, version).
-		WithServiceAccount().
-		WithClusterRole().
-		WithClusterRoleBinding().
-		WithService().
-		WithServiceMonitor().
-		WithDeployment().
-		Build()
+// waitForCRDs waits for the CRDs to be established, the API server rejecting
+// the custom resources of a CRD with "no matches for kind" until then. A CRD
+// whose names are rejected, for example because they conflict with another
+// CRD, fails the wait immediately.
+func waitForCRDs(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, names []string) error {
+	pending := names
+	err := wait.PollUntilContextTimeout(ctx, crdPollInterval, crdEstablishedTimeout, true, func(ctx context.Context) (bool, error) {
+		var notEstablished []string
+		for _, name := range pending {
+			crd, err := clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("error while getting CRD %s: %w", name, err)
+			}
+
+			if cond := apihelpers.FindCRDCondition(crd, apiextensionsv1.NamesAccepted); cond != nil && cond.Status == apiextensionsv1.ConditionFalse {
+				return false, fmt.Errorf("names of CRD %s not accepted: %s", name, cond.Message)
+			}
+
+			if !apihelpers.IsCRDConditionTrue(crd, apiextensionsv1.Established) {
+				notEstablished = append(notEstablished, name)
+			}
+		}
 
-	_, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).Apply(ctx, manifests.ServiceAccount, k8sutil.ApplyOption)
+		pending = notEstablished
+		return len(pending) == 0, nil
+	})
 	if err != nil {
-		return fmt.Errorf("error while creating ServiceAccount: %v", err)
+		if wait.Interrupted(err) && ctx.Err() == nil {
+			return fmt.Errorf("CRDs not established after %s: %s", crdEstablishedTimeout, strings.Join(pending, ", "))
+		}
+		return err
 	}
 
-	_, err = clientSets.KClient.RbacV1().ClusterRoles().Apply(ctx, manifests.ClusterRole, k8sutil.ApplyOption)
+	logger.Info("CRDs established", "count", len(names))
+	return nil
+}
+
+// downloadOperatorRules returns the rules of the operator ClusterRole released
+// with the given version, which follow the resources and verbs needed by this
+// version.
+func downloadOperatorRules(ctx context.Context, rel *release) ([]rbacv1.PolicyRule, error) {
+	data, err := rel.download(ctx, "example/rbac/prometheus-operator/prometheus-operator-cluster-role.yaml")
 	if err != nil {
-		return fmt.Errorf("error while creating ClusterRole: %v", err)
+		return nil, err
 	}
 
-	_, err = clientSets.KClient.RbacV1().ClusterRoleBindings().Apply(ctx, manifests.ClusterRoleBinding, k8sutil.ApplyOption)
+	var cr rbacv1.ClusterRole
+	if err := yaml.Unmarshal(data, &cr); err != nil {
+		return nil, fmt.Errorf("error while decoding ClusterRole: %w", err)
+	}
+
+	if len(cr.Rules) == 0 {
+		return nil, fmt.Errorf("ClusterRole of version %s has no rules", rel.version)
+	}
+
+	return cr.Rules, nil
+}
+
+// checkConversionWebhook verifies that the service backing the conversion
+// webhook of a CRD exists and has ready endpoints when applying the CRD
+// changes the version in which its custom resources are stored. The objects
+// stored in the previous versions are then converted by the webhook on every
+// read, and applying a CRD whose webhook can't be reached would make them
+// inaccessible, so we refuse to apply it in that case.
+func checkConversionWebhook(ctx context.Context, clientSets *k8sutil.ClientSets, crd *apiextensionsv1.CustomResourceDefinition) error {
+	installed, err := clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crd.Name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("error while creating ClusterRoleBinding: %v", err)
+		if errors.IsNotFound(err) {
+			// No custom resource is stored yet.
+			return nil
+		}
+		return fmt.Errorf("error while getting installed CRD: %w", err)
 	}
 
-	_, err = clientSets.KClient.CoreV1().Services(namespace).Apply(ctx, manifests.Service, k8sutil.ApplyOption)
+	storageVersion, err := k8sutil.StorageVersion(crd)
 	if err != nil {
-		return fmt.Errorf("error while creating Service: %v", err)
+		return err
+	}
+
+	if !storesOtherVersions(installed, storageVersion) {
+		return nil
 	}
 
-	_, err = clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Apply(ctx, manifests.ServiceMonitor, k8sutil.ApplyOption)
+	// The conversion set on the installed CRD by another field manager is
+	// kept by the server-side apply when the new CRD doesn't define one.
+	conversion := crd.Spec.Conversion
+	if conversion == nil {
+		conversion = installed.Spec.Conversion
+	}
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter {
+		return nil
+	}
+
+	if conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+		return fmt.Errorf("conversion strategy is Webhook but no webhook client config is defined")
+	}
+
+	svcRef := conversion.Webhook.ClientConfig.Service
+	if svcRef == nil {
+		// The webhook is reached through an URL outside of the cluster, we can't verify it.
+		return nil
+	}
+
+	_, err = clientSets.KClient.CoreV1().Services(svcRef.Namespace).Get(ctx, svcRef.Name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("error while creating ServiceMonitor: %v", err)
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("conversion webhook service %s/%s not found", svcRef.Namespace, svcRef.Name)
+		}
+		return fmt.Errorf("error while getting conversion webhook service %s/%s: %w", svcRef.Namespace, svcRef.Name, err)
 	}
 
-	_, err = clientSets.KClient.AppsV1().Deployments(namespace).Apply(ctx, manifests.Deployment, k8sutil.ApplyOption)
+	groups, err := resolve.ServiceEndpoints(ctx, clientSets, svcRef.Namespace, svcRef.Name)
 	if err != nil {
-		return fmt.Errorf("error while creating Deployment: %v", err)
+		return fmt.Errorf("error while getting conversion webhook endpoints %s/%s: %w", svcRef.Namespace, svcRef.Name, err)
+	}
+
+	if !resolve.HasAddresses(groups) {
+		return fmt.Errorf("conversion webhook service %s/%s has no endpoints", svcRef.Namespace, svcRef.Name)
 	}
 
+	for _, group := range groups {
+		for _, address := range group.Addresses {
+			if address.Ready {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("conversion webhook service %s/%s has no ready endpoints", svcRef.Namespace, svcRef.Name)
+}
+
+// storesOtherVersions reports whether the installed CRD stores its custom
+// resources in another version than the storage version.
+func storesOtherVersions(installed *apiextensionsv1.CustomResourceDefinition, storageVersion string) bool {
+	if v, err := k8sutil.StorageVersion(installed); err == nil && v != storageVersion {
+		return true
+	}
+
+	for _, v := range installed.Status.StoredVersions {
+		if v != storageVersion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkImagePullSecrets warns about the image pull secrets missing from the
+// namespace of the stack, the Pods of the components couldn't pull their
+// images without them.
+func checkImagePullSecrets(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, namespace string, names []string) {
+	for _, name := range names {
+		_, err := clientSets.KClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			logger.Warn("image pull secret not found, the images of the stack may not be pulled", "name", name, "namespace", namespace)
+		} else if err != nil {
+			logger.Warn("error while getting image pull secret", "name", name, "namespace", namespace, "error", err)
+		}
+	}
+}
+
+// checkPriorityClass warns when the PriorityClass of the Pods of the stack
+// doesn't exist, the Pods would be rejected.
+func checkPriorityClass(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, name string) {
+	if name == "" {
+		return
+	}
+
+	_, err := clientSets.KClient.SchedulingV1().PriorityClasses().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		logger.Warn("priority class not found, the Pods of the stack would be rejected", "name", name)
+	} else if err != nil {
+		logger.Warn("error while getting priority class", "name", name, "error", err)
+	}
+}
+
+// finishManifests adapts the manifests of a component, given as a pointer to
+// a manifests struct, to the Kubernetes version targeted by the profile and
+// annotates their checksums, the last steps of their rendering.
+func finishManifests(manifests any, profile Profile) error {
+	if err := builder.TargetKubeVersion(manifests, profile.KubeVersion); err != nil {
+		return err
+	}
+	return annotateChecksums(manifests)
+}
+
+// applyManifests applies the objects of the manifests, the RBAC objects
+// before the workloads using them.
+func applyManifests(ctx context.Context, clientSets *k8sutil.ClientSets, manifests builder.Manifests) error {
+	var objects []any
+	for _, obj := range manifests.Manifests() {
+		objects = append(objects, obj)
+	}
+
+	return k8sutil.ApplyErr(k8sutil.NewApplier(clientSets).Apply(ctx, objects...))
+}
+
+// validateManifests validates the Prometheus Operator objects of the
+// manifests against the installed CRDs, so that the settings rejected by the
+// schemas, such as invalid overrides, are reported with their paths before
+// anything is applied.
+func validateManifests(ctx context.Context, validator *crds.Validator, manifests builder.Manifests) error {
+	for _, obj := range manifests.Manifests() {
+		if err := validator.ValidateObjects(ctx, obj); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func createPrometheus(
+// dryRunPrometheusRule submits the PrometheusRule of the manifests, if any,
+// with a server-side dry-run, so that the admission webhook of the Prometheus
+// Operator, when installed, rejects its invalid expressions before the
+// component is created.
+func dryRunPrometheusRule(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string, rule *monitoringv1.PrometheusRuleApplyConfiguration) error {
+	if rule == nil {
+		return nil
+	}
+
+	_, err := clientSets.MClient.MonitoringV1().PrometheusRules(namespace).Apply(ctx, rule, admission.DryRunOptions())
+	if err == nil {
+		return nil
+	}
+
+	if rejected := admission.Rejection("PrometheusRule", namespace, *rule.Name, err); rejected != nil {
+		return rejected
+	}
+	return fmt.Errorf("error while submitting PrometheusRule: %w", err)
+}
+
+// buildPrometheusOperator returns the manifests of the Prometheus Operator,
+// attached to the stack.
+func buildPrometheusOperator(owner *stackOwner, namespace, version string, rules []rbacv1.PolicyRule, profile Profile) (builder.OperatorManifests, error) {
+	b := builder.NewOperator(namespace, version).
+		WithStack(profile.Stack).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy)
+	if len(rules) > 0 {
+		b = b.WithRules(rules)
+	}
+	if profile.AgentDaemonSet {
+		b = b.WithFeatureGates(builder.PrometheusAgentDaemonSetFeatureGate)
+	}
+
+	b = b.WithServiceAccount()
+	if len(profile.WatchedNamespaces) > 0 {
+		b = b.WithNamespaces(profile.WatchedNamespaces...).WithRoles()
+	} else {
+		b = b.WithClusterRole().WithClusterRoleBinding()
+	}
+
+	b = b.WithService().
+		WithServiceMonitor().
+		WithDeployment()
+
+	if profile.SelfMonitoringAlerts {
+		b = b.WithSelfMonitoringRules()
+	}
+
+	manifests := b.Build()
+
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Deployment.ObjectMetaApplyConfiguration)
+	if manifests.PrometheusRule != nil {
+		owner.own(manifests.PrometheusRule.ObjectMetaApplyConfiguration)
+	}
+
+	if manifests.ClusterRole != nil {
+		owner.label(manifests.ClusterRole.ObjectMetaApplyConfiguration)
+	}
+	if manifests.ClusterRoleBinding != nil {
+		owner.label(manifests.ClusterRoleBinding.ObjectMetaApplyConfiguration)
+	}
+
+	// Owner references can't cross namespaces, the Roles and RoleBindings
+	// are only labeled.
+	for _, role := range manifests.Roles {
+		owner.label(role.ObjectMetaApplyConfiguration)
+	}
+	for _, roleBinding := range manifests.RoleBindings {
+		owner.label(roleBinding.ObjectMetaApplyConfiguration)
+	}
+
+	if err := builder.ApplyOverrides(manifests.Deployment, OverrideOperator, profile.Overrides); err != nil {
+		return manifests, err
+	}
+	return manifests, finishManifests(&manifests, profile)
+}
+
+func createPrometheusOperator(
 	ctx context.Context,
 	clientSets *k8sutil.ClientSets,
-	namespace string) error {
-	manifests := builder.NewPrometheus(namespace).
-		WithServiceAccount().
+	validator *crds.Validator,
+	owner *stackOwner,
+	namespace, version string,
+	rules []rbacv1.PolicyRule,
+	profile Profile) error {
+	manifests, err := buildPrometheusOperator(owner, namespace, version, rules, profile)
+	if err != nil {
+		return err
+	}
+
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
+	}
+
+	if err := dryRunPrometheusRule(ctx, clientSets, namespace, manifests.PrometheusRule); err != nil {
+		return err
+	}
+
+	return applyManifests(ctx, clientSets, &manifests)
+}
+
+// buildPrometheus returns the manifests of the Prometheus or the
+// PrometheusAgent of the stack.
+func buildPrometheus(owner *stackOwner, namespace string, profile Profile) (builder.PrometheusManifests, error) {
+	b := builder.NewPrometheus(namespace).
+		WithStack(profile.Stack).
+		WithReplicas(profile.PrometheusReplicas).
+		WithRetention(profile.PrometheusRetention).
+		WithResources(profile.PrometheusResources).
+		WithVersion(profile.PrometheusVersion).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy)
+
+	if profile.AgentDaemonSet {
+		b = b.WithDaemonSetMode()
+	}
+
+	if profile.RemoteWriteURL != "" {
+		b = b.WithRemoteWrite(profile.RemoteWriteURL)
+	}
+
+	if profile.OTLPEndpoint != "" && !profile.OTelCollectorFederation {
+		b = b.WithRemoteWrite(builder.OpenTelemetryCollectorRemoteWriteURL(namespace, profile.Stack, profile.ClusterDomain))
+	}
+
+	if profile.SelfMonitoringAlerts {
+		b = b.WithReloaderMonitoring()
+	}
+
+	b = b.WithServiceAccount().
 		WithClusterRole().
 		WithClusterRoleBinding().
 		WithService().
-		WithServiceMonitor().
-		WithPrometheus().
-		Build()
+		WithServiceMonitor()
 
-	_, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).Apply(ctx, manifests.ServiceAccount, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ServiceAccount: %v", err)
+	if !profile.Alertmanager {
+		b = b.WithoutAlerting()
 	}
 
-	_, err = clientSets.KClient.RbacV1().ClusterRoles().Apply(ctx, manifests.ClusterRole, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ClusterRole: %v", err)
+	if profile.Agent {
+		b = b.WithPrometheusAgent()
+	} else {
+		b = b.WithPrometheus()
 	}
 
-	_, err = clientSets.KClient.RbacV1().ClusterRoleBindings().Apply(ctx, manifests.ClusterRoleBinding, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ClusterRoleBinding: %v", err)
+	manifests := b.Build()
+
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.label(manifests.ClusterRole.ObjectMetaApplyConfiguration)
+	owner.label(manifests.ClusterRoleBinding.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
+	if manifests.PrometheusAgent != nil {
+		owner.own(manifests.PrometheusAgent.ObjectMetaApplyConfiguration)
+		if err := builder.ApplyOverrides(manifests.PrometheusAgent, OverridePrometheus, profile.Overrides); err != nil {
+			return manifests, err
+		}
+		return manifests, finishManifests(&manifests, profile)
 	}
 
-	_, err = clientSets.MClient.MonitoringV1().Prometheuses(namespace).Apply(ctx, manifests.Prometheus, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating Prometheus: %v", err)
+	owner.own(manifests.Prometheus.ObjectMetaApplyConfiguration)
+	if err := builder.ApplyOverrides(manifests.Prometheus, OverridePrometheus, profile.Overrides); err != nil {
+		return manifests, err
 	}
+	return manifests, finishManifests(&manifests, profile)
+}
 
-	_, err = clientSets.KClient.CoreV1().Services(namespace).Apply(ctx, manifests.Service, k8sutil.ApplyOption)
+func createPrometheus(
+	ctx context.Context,
+	clientSets *k8sutil.ClientSets,
+	validator *crds.Validator,
+	owner *stackOwner,
+	namespace string,
+	profile Profile) error {
+	manifests, err := buildPrometheus(owner, namespace, profile)
 	if err != nil {
-		return fmt.Errorf("error while creating Service: %v", err)
+		return err
 	}
 
-	_, err = clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Apply(ctx, manifests.ServiceMonitor, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ServiceMonitor: %v", err)
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
 	}
 
-	return nil
+	return applyManifests(ctx, clientSets, &manifests)
 }
 
-func createAlertManager(
-	ctx context.Context,
-	clientSets *k8sutil.ClientSets,
-	namespace string) error {
-	manifests := builder.NewAlertManager(namespace).
-		WithServiceAccount().
+// buildAlertManager returns the manifests of the Alertmanager of the stack.
+func buildAlertManager(owner *stackOwner, namespace string, profile Profile) (builder.AlertManagerManifests, error) {
+	b := builder.NewAlertManager(namespace).
+		WithStack(profile.Stack).
+		WithReplicas(profile.AlertmanagerReplicas).
+		WithVersion(profile.AlertmanagerVersion).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy)
+
+	if profile.SelfMonitoringAlerts {
+		b = b.WithReloaderMonitoring()
+	}
+
+	manifests := b.WithServiceAccount().
 		WithAlertManager().
 		WithService().
 		WithServiceMonitor().
 		Build()
 
-	_, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).Apply(ctx, manifests.ServiceAccount, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ServiceAccount: %v", err)
-	}
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.own(manifests.AlertManager.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
 
-	_, err = clientSets.MClient.MonitoringV1().Alertmanagers(namespace).Apply(ctx, manifests.AlertManager, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating AlertManager: %v", err)
+	if err := builder.ApplyOverrides(manifests.AlertManager, OverrideAlertmanager, profile.Overrides); err != nil {
+		return manifests, err
 	}
+	return manifests, finishManifests(&manifests, profile)
+}
 
-	_, err = clientSets.KClient.CoreV1().Services(namespace).Apply(ctx, manifests.Service, k8sutil.ApplyOption)
+func createAlertManager(
+	ctx context.Context,
+	clientSets *k8sutil.ClientSets,
+	validator *crds.Validator,
+	owner *stackOwner,
+	namespace string,
+	profile Profile) error {
+	manifests, err := buildAlertManager(owner, namespace, profile)
 	if err != nil {
-		return fmt.Errorf("error while creating Service: %v", err)
+		return err
 	}
 
-	_, err = clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Apply(ctx, manifests.ServiceMonitor, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ServiceMonitor: %v", err)
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
 	}
 
-	return nil
+	return applyManifests(ctx, clientSets, &manifests)
 }
 
-func createNodeExporter(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) error {
+// buildNodeExporter returns the manifests of the node exporter of the stack.
+func buildNodeExporter(owner *stackOwner, namespace string, profile Profile) (builder.NodexExporterManifests, error) {
 	manifests := builder.NewNodeExporterBuilder(namespace, builder.LatestNodeExporterVersion).
+		WithStack(profile.Stack).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithCollectors(profile.NodeExporterCollectors).
+		WithArgs(profile.NodeExporterArgs...).
 		WithServiceAccount().
 		WithDaemonSet().
 		WithPodMonitor().
 		Build()
 
-	_, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).Apply(ctx, manifests.ServiceAccount, k8sutil.ApplyOption)
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.own(manifests.DaemonSet.ObjectMetaApplyConfiguration)
+	owner.own(manifests.PodMonitor.ObjectMetaApplyConfiguration)
+
+	if err := builder.ApplyOverrides(manifests.DaemonSet, OverrideNodeExporter, profile.Overrides); err != nil {
+		return manifests, err
+	}
+	return manifests, finishManifests(&manifests, profile)
+}
+
+// createNodeExporter deploys the node exporter of the stack and reports
+// whether it did, it is skipped when another stack already deployed one.
+func createNodeExporter(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, validator *crds.Validator, owner *stackOwner, namespace string, profile Profile) (bool, error) {
+	// The node exporter listens on the host network, a second one would
+	// never get scheduled.
+	daemonSets, err := clientSets.KClient.AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=node-exporter",
+	})
 	if err != nil {
-		return fmt.Errorf("error while creating ServiceAccount: %v", err)
+		return false, fmt.Errorf("error while listing DaemonSets: %w", err)
+	}
+	for _, ds := range daemonSets.Items {
+		if ds.Labels[StackLabel] != owner.anchor {
+			logger.Warn("node exporter already deployed, skipping it", "name", ds.Name, "namespace", ds.Namespace)
+			return false, nil
+		}
 	}
 
-	_, err = clientSets.KClient.AppsV1().DaemonSets(namespace).Apply(ctx, manifests.DaemonSet, k8sutil.ApplyOption)
+	manifests, err := buildNodeExporter(owner, namespace, profile)
 	if err != nil {
-		return fmt.Errorf("error while creating DaemonSet: %v", err)
+		return false, err
 	}
 
-	_, err = clientSets.MClient.MonitoringV1().PodMonitors(namespace).Apply(ctx, manifests.PodMonitor, k8sutil.ApplyOption)
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return false, err
+	}
+
+	if err := applyManifests(ctx, clientSets, &manifests); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// buildKubeStateMetrics returns the manifests of kube-state-metrics, run by a
+// StatefulSet when it is sharded. The alerts on the state of the monitoring
+// resources are left out of agent stacks, which don't evaluate rules.
+func buildKubeStateMetrics(owner *stackOwner, namespace string, profile Profile) (builder.KubeStateMetricsManifests, error) {
+	b := builder.NewKubeStateMetricsBuilder(namespace, builder.LatestKubeStateMetricsVersion).
+		WithStack(profile.Stack).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy)
+
+	if profile.KubeStateMetricsCRDMetrics {
+		b = b.WithCustomResourceState()
+		if !profile.Agent {
+			b = b.WithCustomResourceStateRules()
+		}
+	}
+
+	b = b.WithServiceAccount().
+		WithClusterRole().
+		WithClusterRoleBinding()
+
+	if profile.KubeStateMetricsShards > 1 {
+		b = b.WithShards(profile.KubeStateMetricsShards).WithStatefulSet().WithShardingRole()
+	} else {
+		b = b.WithDeployment()
+	}
+
+	manifests := b.WithService().
+		WithServiceMonitor().
+		Build()
+
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.label(manifests.ClusterRole.ObjectMetaApplyConfiguration)
+	owner.label(manifests.ClusterRoleBinding.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
+	if manifests.CustomResourceStateConfig != nil {
+		owner.own(manifests.CustomResourceStateConfig.ObjectMetaApplyConfiguration)
+	}
+	if manifests.PrometheusRule != nil {
+		owner.own(manifests.PrometheusRule.ObjectMetaApplyConfiguration)
+	}
+	if manifests.StatefulSet != nil {
+		owner.own(manifests.Role.ObjectMetaApplyConfiguration)
+		owner.own(manifests.RoleBinding.ObjectMetaApplyConfiguration)
+		owner.own(manifests.StatefulSet.ObjectMetaApplyConfiguration)
+		if err := builder.ApplyOverrides(manifests.StatefulSet, OverrideKubeStateMetrics, profile.Overrides); err != nil {
+			return manifests, err
+		}
+		return manifests, finishManifests(&manifests, profile)
+	}
+
+	owner.own(manifests.Deployment.ObjectMetaApplyConfiguration)
+	if err := builder.ApplyOverrides(manifests.Deployment, OverrideKubeStateMetrics, profile.Overrides); err != nil {
+		return manifests, err
+	}
+	return manifests, finishManifests(&manifests, profile)
+}
+
+func createKubeStateMetrics(ctx context.Context, clientSets *k8sutil.ClientSets, validator *crds.Validator, owner *stackOwner, namespace string, profile Profile) error {
+	manifests, err := buildKubeStateMetrics(owner, namespace, profile)
 	if err != nil {
-		return fmt.Errorf("error while creating PodMonitor: %v", err)
+		return err
 	}
 
-	return nil
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
+	}
+
+	if err := dryRunPrometheusRule(ctx, clientSets, namespace, manifests.PrometheusRule); err != nil {
+		return err
+	}
+
+	return applyManifests(ctx, clientSets, &manifests)
 }
 
-func createKubeStateMetrics(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) error {
-	manifests := builder.NewKubeStateMetricsBuilder(namespace, builder.LatestKubeStateMetricsVersion).
+// buildPushgateway returns the manifests of the Pushgateway. The alert on
+// the stale push groups is left out of agent stacks, which don't evaluate
+// rules.
+func buildPushgateway(owner *stackOwner, namespace string, profile Profile) (builder.PushgatewayManifests, error) {
+	b := builder.NewPushgatewayBuilder(namespace, builder.LatestPushgatewayVersion).
+		WithStack(profile.Stack).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy).
 		WithServiceAccount().
-		WithClusterRole().
-		WithClusterRoleBinding().
+		WithDeployment().
+		WithService().
+		WithServiceMonitor()
+
+	if !profile.Agent {
+		b = b.WithPrometheusRule()
+	}
+
+	manifests := b.Build()
+
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Deployment.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
+	if manifests.PrometheusRule != nil {
+		owner.own(manifests.PrometheusRule.ObjectMetaApplyConfiguration)
+	}
+
+	if err := builder.ApplyOverrides(manifests.Deployment, OverridePushgateway, profile.Overrides); err != nil {
+		return manifests, err
+	}
+	return manifests, finishManifests(&manifests, profile)
+}
+
+func createPushgateway(ctx context.Context, clientSets *k8sutil.ClientSets, validator *crds.Validator, owner *stackOwner, namespace string, profile Profile) error {
+	manifests, err := buildPushgateway(owner, namespace, profile)
+	if err != nil {
+		return err
+	}
+
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
+	}
+
+	if err := dryRunPrometheusRule(ctx, clientSets, namespace, manifests.PrometheusRule); err != nil {
+		return err
+	}
+
+	return applyManifests(ctx, clientSets, &manifests)
+}
+
+// buildBlackboxExporter returns the manifests of the blackbox exporter
+// probing the targets of the Probes.
+func buildBlackboxExporter(owner *stackOwner, namespace string, profile Profile) (builder.BlackboxExporterManifests, error) {
+	manifests := builder.NewBlackboxExporterBuilder(namespace, builder.LatestBlackboxExporterVersion).
+		WithStack(profile.Stack).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy).
+		WithServiceAccount().
+		WithConfig().
 		WithDeployment().
 		WithService().
 		WithServiceMonitor().
 		Build()
 
-	_, err := clientSets.KClient.CoreV1().ServiceAccounts(namespace).Apply(ctx, manifests.ServiceAccount, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ServiceAccount: %v", err)
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Config.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Deployment.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
+
+	if err := builder.ApplyOverrides(manifests.Deployment, OverrideBlackboxExporter, profile.Overrides); err != nil {
+		return manifests, err
 	}
+	return manifests, finishManifests(&manifests, profile)
+}
 
-	_, err = clientSets.KClient.RbacV1().ClusterRoles().Apply(ctx, manifests.ClusterRole, k8sutil.ApplyOption)
+func createBlackboxExporter(ctx context.Context, clientSets *k8sutil.ClientSets, validator *crds.Validator, owner *stackOwner, namespace string, profile Profile) error {
+	manifests, err := buildBlackboxExporter(owner, namespace, profile)
 	if err != nil {
-		return fmt.Errorf("error while creating ClusterRole: %v", err)
+		return err
 	}
 
-	_, err = clientSets.KClient.RbacV1().ClusterRoleBindings().Apply(ctx, manifests.ClusterRoleBinding, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating ClusterRoleBinding: %v", err)
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
 	}
 
-	_, err = clientSets.KClient.AppsV1().Deployments(namespace).Apply(ctx, manifests.Deployment, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating Deployment: %v", err)
+	return applyManifests(ctx, clientSets, &manifests)
+}
+
+// buildOpenTelemetryCollector returns the manifests of the OpenTelemetry
+// Collector exporting the samples of the stack to the OTLP endpoint.
+func buildOpenTelemetryCollector(owner *stackOwner, namespace string, profile Profile) (builder.OpenTelemetryCollectorManifests, error) {
+	b := builder.NewOpenTelemetryCollectorBuilder(namespace, builder.LatestOpenTelemetryCollectorVersion, profile.OTLPEndpoint).
+		WithStack(profile.Stack).
+		WithClusterDomain(profile.ClusterDomain).
+		WithImagePullSecrets(profile.ImagePullSecrets...).
+		WithScheduling(profile.Scheduling).
+		WithIPFamilyPolicy(profile.IPFamilyPolicy)
+
+	if profile.OTelCollectorFederation {
+		b = b.WithFederation()
 	}
 
-	_, err = clientSets.KClient.CoreV1().Services(namespace).Apply(ctx, manifests.Service, k8sutil.ApplyOption)
-	if err != nil {
-		return fmt.Errorf("error while creating Service: %v", err)
+	manifests := b.WithServiceAccount().
+		WithConfig().
+		WithDeployment().
+		WithService().
+		WithServiceMonitor().
+		Build()
+
+	owner.own(manifests.ServiceAccount.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Config.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Deployment.ObjectMetaApplyConfiguration)
+	owner.own(manifests.Service.ObjectMetaApplyConfiguration)
+	owner.own(manifests.ServiceMonitor.ObjectMetaApplyConfiguration)
+
+	if err := builder.ApplyOverrides(manifests.Deployment, OverrideOpenTelemetryCollector, profile.Overrides); err != nil {
+		return manifests, err
 	}
+	return manifests, finishManifests(&manifests, profile)
+}
 
-	_, err = clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Apply(ctx, manifests.ServiceMonitor, k8sutil.ApplyOption)
+func createOpenTelemetryCollector(ctx context.Context, clientSets *k8sutil.ClientSets, validator *crds.Validator, owner *stackOwner, namespace string, profile Profile) error {
+	manifests, err := buildOpenTelemetryCollector(owner, namespace, profile)
 	if err != nil {
-		return fmt.Errorf("error while creating ServiceMonitor: %v", err)
+		return err
 	}
-	return nil
+
+	if err := validateManifests(ctx, validator, &manifests); err != nil {
+		return err
+	}
+
+	return applyManifests(ctx, clientSets, &manifests)
 }
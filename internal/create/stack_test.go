@@ -0,0 +1,376 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func getCRD(name string, conditions ...apiextensionsv1.CustomResourceDefinitionCondition) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     apiextensionsv1.CustomResourceDefinitionStatus{Conditions: conditions},
+	}
+}
+
+func TestWaitForCRDs(t *testing.T) {
+	established := apiextensionsv1.CustomResourceDefinitionCondition{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}
+	namesAccepted := apiextensionsv1.CustomResourceDefinitionCondition{Type: apiextensionsv1.NamesAccepted, Status: apiextensionsv1.ConditionTrue}
+
+	type testCase struct {
+		name       string
+		crd        *apiextensionsv1.CustomResourceDefinition
+		shouldFail bool
+	}
+
+	tests := []testCase{
+		{
+			name: "Established",
+			crd:  getCRD("prometheuses.monitoring.coreos.com", namesAccepted, established),
+		},
+		{
+			name:       "NotEstablished",
+			crd:        getCRD("prometheuses.monitoring.coreos.com", namesAccepted),
+			shouldFail: true,
+		},
+		{
+			name: "NamesNotAccepted",
+			crd: getCRD("prometheuses.monitoring.coreos.com", apiextensionsv1.CustomResourceDefinitionCondition{
+				Type:    apiextensionsv1.NamesAccepted,
+				Status:  apiextensionsv1.ConditionFalse,
+				Message: `"prometheuses" is already in use`,
+			}),
+			shouldFail: true,
+		},
+		{
+			name:       "Missing",
+			crd:        getCRD("alertmanagers.monitoring.coreos.com", namesAccepted, established),
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := &k8sutil.ClientSets{APIExtensionsClient: apiextensionsfake.NewSimpleClientset(tc.crd)}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err := waitForCRDs(ctx, slog.Default(), clientSets, []string{"prometheuses.monitoring.coreos.com"})
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// conversionCRD returns a CRD of alertmanagerconfigs stored in the version,
+// converted with the webhook when it isn't nil.
+func conversionCRD(storageVersion string, webhook *apiextensionsv1.WebhookClientConfig, storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "alertmanagerconfigs.monitoring.coreos.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true, Storage: storageVersion == "v1alpha1"},
+				{Name: "v1beta1", Served: true, Storage: storageVersion == "v1beta1"},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+	if webhook != nil {
+		crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+			Strategy: apiextensionsv1.WebhookConverter,
+			Webhook:  &apiextensionsv1.WebhookConversion{ClientConfig: webhook, ConversionReviewVersions: []string{"v1"}},
+		}
+	}
+	return crd
+}
+
+func TestCheckConversionWebhook(t *testing.T) {
+	serviceWebhook := &apiextensionsv1.WebhookClientConfig{
+		Service: &apiextensionsv1.ServiceReference{Namespace: "default", Name: "prometheus-operator-admission-webhook"},
+	}
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "prometheus-operator-admission-webhook"}}
+	endpoints := func(ready bool) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "prometheus-operator-admission-webhook-abcde",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "prometheus-operator-admission-webhook"},
+			},
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(ready)}},
+			},
+		}
+	}
+
+	type testCase struct {
+		name       string
+		objects    []runtime.Object
+		crd        *apiextensionsv1.CustomResourceDefinition
+		shouldFail string
+	}
+
+	tests := []testCase{
+		{
+			name: "NotInstalled",
+			crd:  conversionCRD("v1beta1", serviceWebhook),
+		},
+		{
+			// The webhook isn't called without a change of the storage version.
+			name:    "SameStorageVersion",
+			objects: []runtime.Object{conversionCRD("v1beta1", nil, "v1beta1")},
+			crd:     conversionCRD("v1beta1", serviceWebhook),
+		},
+		{
+			name:    "ReadyEndpoints",
+			objects: []runtime.Object{conversionCRD("v1alpha1", nil, "v1alpha1"), service, endpoints(true)},
+			crd:     conversionCRD("v1beta1", serviceWebhook),
+		},
+		{
+			name:       "MissingService",
+			objects:    []runtime.Object{conversionCRD("v1alpha1", nil, "v1alpha1")},
+			crd:        conversionCRD("v1beta1", serviceWebhook),
+			shouldFail: "conversion webhook service default/prometheus-operator-admission-webhook not found",
+		},
+		{
+			name:       "NoReadyEndpoints",
+			objects:    []runtime.Object{conversionCRD("v1alpha1", nil, "v1alpha1"), service, endpoints(false)},
+			crd:        conversionCRD("v1beta1", serviceWebhook),
+			shouldFail: "conversion webhook service default/prometheus-operator-admission-webhook has no ready endpoints",
+		},
+		{
+			name:       "NoEndpoints",
+			objects:    []runtime.Object{conversionCRD("v1alpha1", nil, "v1alpha1"), service},
+			crd:        conversionCRD("v1beta1", serviceWebhook),
+			shouldFail: "conversion webhook service default/prometheus-operator-admission-webhook has no endpoints",
+		},
+		{
+			// The webhook outside of the cluster can't be verified.
+			name:    "URLClientConfig",
+			objects: []runtime.Object{conversionCRD("v1alpha1", nil, "v1alpha1")},
+			crd:     conversionCRD("v1beta1", &apiextensionsv1.WebhookClientConfig{URL: ptr.To("https://webhook.example.com/convert")}),
+		},
+		{
+			// Objects are still stored in the previous version.
+			name:       "PreviousStoredVersion",
+			objects:    []runtime.Object{conversionCRD("v1beta1", nil, "v1alpha1", "v1beta1")},
+			crd:        conversionCRD("v1beta1", serviceWebhook),
+			shouldFail: "conversion webhook service default/prometheus-operator-admission-webhook not found",
+		},
+		{
+			// The webhook of the installed CRD is kept by the apply.
+			name:       "InstalledWebhook",
+			objects:    []runtime.Object{conversionCRD("v1alpha1", serviceWebhook, "v1alpha1")},
+			crd:        conversionCRD("v1beta1", nil),
+			shouldFail: "conversion webhook service default/prometheus-operator-admission-webhook not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(tc.objects...))
+
+			err := checkConversionWebhook(context.Background(), clientSets, tc.crd)
+			if tc.shouldFail != "" {
+				require.EqualError(t, err, tc.shouldFail)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// getStackAnchor returns the anchor ConfigMap of the default stack.
+func getStackAnchor(t *testing.T, clientSets *k8sutil.ClientSets) *corev1.ConfigMap {
+	t.Helper()
+
+	cm, err := clientSets.KClient.CoreV1().ConfigMaps(metav1.NamespaceDefault).Get(context.Background(), StackAnchorName, metav1.GetOptions{})
+	require.NoError(t, err)
+	return cm
+}
+
+func TestCreateStackKeepsAnchorData(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithServerSideApply())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	profile := Profile{Name: "minimal", PrometheusReplicas: 1}
+	require.NoError(t, createStack(ctx, logger, clientSets, newSummary(profile), "0.78.2", nil, profile))
+
+	created := getStackAnchor(t, clientSets)
+	require.Contains(t, created.Data, parametersKey)
+	require.Contains(t, created.Data, inventoryKey)
+	previous, err := loadStackInventory(ctx, clientSets, metav1.NamespaceDefault, "")
+	require.NoError(t, err)
+
+	// The Pushgateway added by the second creation fails to be applied.
+	clientSets.DClient.(*dynamicfake.FakeDynamicClient).PrependReactor("patch", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.PatchAction).GetName() != "pushgateway" {
+			return false, nil, nil
+		}
+		return true, nil, errors.New("quota exceeded")
+	})
+
+	withPushgateway := profile
+	withPushgateway.Pushgateway = true
+	summary := newSummary(withPushgateway)
+	require.Error(t, createStack(ctx, logger, clientSets, summary, "0.79.0", nil, withPushgateway))
+	require.Error(t, summary.Err())
+
+	// The parameters of the first creation stay recorded, the inventory
+	// records the objects of both.
+	partial := getStackAnchor(t, clientSets)
+	assert.Equal(t, created.UID, partial.UID)
+	assert.Equal(t, created.Data[parametersKey], partial.Data[parametersKey])
+
+	params, err := decodeStackParameters(partial)
+	require.NoError(t, err)
+	assert.Equal(t, "0.78.2", params.Version)
+
+	inventory, err := loadStackInventory(ctx, clientSets, metav1.NamespaceDefault, "")
+	require.NoError(t, err)
+	for _, obj := range previous {
+		assert.True(t, slices.ContainsFunc(inventory, obj.same), "%s %s missing from the inventory", obj.Kind, obj.Name)
+	}
+	assert.True(t, slices.ContainsFunc(inventory, func(obj StackObject) bool {
+		return obj.Kind == "Service" && obj.Name == "pushgateway"
+	}), "the objects of the Pushgateway are missing from the inventory")
+
+	// Creating the stack again without the Pushgateway prunes the objects
+	// applied by the partial creation.
+	require.NoError(t, createStack(ctx, logger, clientSets, newSummary(profile), "0.79.0", nil, profile))
+
+	_, err = clientSets.DClient.Resource(k8sutil.ResourceFor(corev1.SchemeGroupVersion.WithKind("Service"))).
+		Namespace(metav1.NamespaceDefault).
+		Get(ctx, "pushgateway", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "the Service of the Pushgateway isn't pruned: %v", err)
+
+	params, err = decodeStackParameters(getStackAnchor(t, clientSets))
+	require.NoError(t, err)
+	assert.Equal(t, "0.79.0", params.Version)
+}
+
+func TestRunStep(t *testing.T) {
+	for _, timeout := range []time.Duration{stepTimeout, crdStepTimeout} {
+		start := time.Now()
+		require.NoError(t, runStepWithin(context.Background(), timeout, func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			assert.WithinDuration(t, start.Add(timeout), deadline, time.Second)
+			return nil
+		}))
+	}
+
+	// The CRDs are downloaded one by one before waiting for them.
+	assert.Greater(t, crdStepTimeout, time.Duration(len(crdResources))*downloadTimeout+crdEstablishedTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := runStep(ctx, func(ctx context.Context) error { return ctx.Err() })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, interrupted(ctx))
+
+	cancel()
+	err := interrupted(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.EqualError(t, err, "stack creation interrupted: context canceled")
+}
+
+func TestRunCreateStackInterrupted(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	gitHubClient := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	gitHubClient.BaseURL = baseURL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	profile := Profile{Name: "minimal", PrometheusReplicas: 1, Alertmanager: true}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	summary, err := RunCreateStack(ctx, logger, k8stesting.NewFakeClientSets(), gitHubClient, "0.78.2", Verification{Mode: VerifyWarn}, profile)
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, ComponentFailed, summary.Components[0].Status)
+	for _, c := range summary.Components[1:] {
+		assert.Equal(t, ComponentNotRun, c.Status, c.Name)
+	}
+}
+
+func TestCreateStackInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The command is interrupted while Prometheus is created.
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithServerSideApply())
+	clientSets.DClient.(*dynamicfake.FakeDynamicClient).PrependReactor("patch", "prometheuses", func(clienttesting.Action) (bool, runtime.Object, error) {
+		cancel()
+		return false, nil, nil
+	})
+
+	profile := Profile{Name: "minimal", PrometheusReplicas: 1, Alertmanager: true, KubeStateMetrics: true}
+	summary := newSummary(profile)
+	summary.record(componentCRDs, ComponentCreated, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	err := createStack(ctx, logger, clientSets, summary, "0.78.2", nil, profile)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, summary.Err())
+
+	assert.Equal(t, []ComponentResult{
+		{Name: componentCRDs, Status: ComponentCreated},
+		{Name: componentOperator, Status: ComponentCreated},
+		{Name: componentPrometheus, Status: ComponentCreated},
+		{Name: componentAlertmanager, Status: ComponentNotRun},
+		{Name: componentKubeStateMetrics, Status: ComponentNotRun},
+	}, summary.Components)
+
+	// The parameters of an interrupted creation aren't recorded.
+	assert.NotContains(t, getStackAnchor(t, clientSets).Data, parametersKey)
+}
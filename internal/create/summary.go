@@ -0,0 +1,114 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ComponentStatus is the outcome of the creation of a stack component.
+type ComponentStatus string
+
+const (
+	ComponentCreated ComponentStatus = "created"
+	ComponentFailed  ComponentStatus = "failed"
+	ComponentSkipped ComponentStatus = "skipped"
+	// ComponentNotRun is the status of the components left aside after the
+	// failure of a step they depend on.
+	ComponentNotRun ComponentStatus = "not run"
+)
+
+const (
+	componentCRDs                   = "CRDs"
+	componentOperator               = "Prometheus Operator"
+	componentPrometheus             = "Prometheus"
+	componentAlertmanager           = "Alertmanager"
+	componentNodeExporter           = "Node Exporter"
+	componentKubeStateMetrics       = "kube-state-metrics"
+	componentPushgateway            = "Pushgateway"
+	componentBlackboxExporter       = "Blackbox Exporter"
+	componentOpenTelemetryCollector = "OpenTelemetry Collector"
+)
+
+// ComponentResult is the outcome of the creation of a stack component.
+type ComponentResult struct {
+	Name   string
+	Status ComponentStatus
+	Err    error
+}
+
+// Summary reports the outcome of each component of a stack creation, a
+// partial install leaving some components failed or not run.
+type Summary struct {
+	Components []ComponentResult
+}
+
+// newSummary returns the summary of the components deployed by the profile,
+// none of which has run yet.
+func newSummary(profile Profile) *Summary {
+	names := []string{componentCRDs, componentOperator, componentPrometheus}
+	if profile.Alertmanager {
+		names = append(names, componentAlertmanager)
+	}
+	if profile.NodeExporter {
+		names = append(names, componentNodeExporter)
+	}
+	if profile.KubeStateMetrics {
+		names = append(names, componentKubeStateMetrics)
+	}
+	if profile.Pushgateway {
+		names = append(names, componentPushgateway)
+	}
+	if profile.BlackboxExporter {
+		names = append(names, componentBlackboxExporter)
+	}
+	if profile.OTLPEndpoint != "" {
+		names = append(names, componentOpenTelemetryCollector)
+	}
+
+	s := &Summary{}
+	for _, name := range names {
+		s.Components = append(s.Components, ComponentResult{Name: name, Status: ComponentNotRun})
+	}
+	return s
+}
+
+// record sets the outcome of a component, failed when err isn't nil.
+func (s *Summary) record(name string, status ComponentStatus, err error) {
+	if err != nil {
+		status = ComponentFailed
+	}
+
+	for i := range s.Components {
+		if s.Components[i].Name == name {
+			s.Components[i].Status = status
+			s.Components[i].Err = err
+			return
+		}
+	}
+}
+
+// Err returns the errors of the failed components joined together, nil when
+// none failed.
+func (s *Summary) Err() error {
+	var errs []error
+	for _, c := range s.Components {
+		if c.Status == ComponentFailed {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, c.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary(t *testing.T) {
+	summary := newSummary(Profile{NodeExporter: true, KubeStateMetrics: true})
+	require.NoError(t, summary.Err())
+
+	summary.record(componentCRDs, ComponentCreated, nil)
+	summary.record(componentOperator, ComponentCreated, nil)
+	summary.record(componentPrometheus, ComponentCreated, errors.New("forbidden"))
+	summary.record(componentNodeExporter, ComponentSkipped, nil)
+
+	assert.Equal(t, []ComponentResult{
+		{Name: componentCRDs, Status: ComponentCreated},
+		{Name: componentOperator, Status: ComponentCreated},
+		{Name: componentPrometheus, Status: ComponentFailed, Err: errors.New("forbidden")},
+		{Name: componentNodeExporter, Status: ComponentSkipped},
+		{Name: componentKubeStateMetrics, Status: ComponentNotRun},
+	}, summary.Components)
+	assert.EqualError(t, summary.Err(), "Prometheus: forbidden")
+}
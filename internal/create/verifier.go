@@ -0,0 +1,49 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package create
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+)
+
+// RunInstallVerifier installs a CronJob running the analyzers on the given
+// schedule with the given poctl image. With readSecrets, the analyzers can
+// read the Secrets, and with a pushgatewayURL the metrics of the runs are
+// pushed to the Pushgateway.
+func RunInstallVerifier(ctx context.Context, logger *slog.Logger, clientSets *k8sutil.ClientSets, namespace, image, schedule string, readSecrets bool, pushgatewayURL string) error {
+	b := builder.NewVerifier(namespace, image, schedule).
+		WithServiceAccount().
+		WithClusterRole()
+	if readSecrets {
+		b = b.WithSecretsAccess()
+	}
+	b = b.WithClusterRoleBinding().
+		WithCronJob()
+	if pushgatewayURL != "" {
+		b = b.WithPushgateway(pushgatewayURL)
+	}
+	manifests := b.Build()
+
+	if err := applyManifests(ctx, clientSets, &manifests); err != nil {
+		return err
+	}
+
+	logger.Info("verifier installed", "namespace", namespace, "schedule", schedule)
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diffenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/prometheus-operator/poctl/internal/explain"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Unset is the value of a field which isn't set in an environment.
+const Unset = "<unset>"
+
+// Environment is a namespace of a kubeconfig context, the current context
+// when empty.
+type Environment struct {
+	Context   string
+	Namespace string
+}
+
+func (e Environment) String() string {
+	if e.Context == "" {
+		return e.Namespace
+	}
+	return fmt.Sprintf("%s/%s", e.Context, e.Namespace)
+}
+
+// Difference is a field whose value isn't the same in all the environments.
+type Difference struct {
+	Path string
+	// Values holds the value of the field in each environment, in the order
+	// of the compared specs.
+	Values []string
+}
+
+// GetSpec returns the spec of the object of the CRD, without the fields set
+// to the default value of the CRD schema. Such fields are usually defaulted
+// by the API server and don't make a difference between environments.
+func GetSpec(ctx context.Context, clientSets *k8sutil.ClientSets, crdName, name, namespace string) (map[string]any, error) {
+	crd, err := explain.InstalledCRD(ctx, clientSets, crdName)
+	if err != nil {
+		return nil, err
+	}
+
+	var version *apiextensionsv1.CustomResourceDefinitionVersion
+	for i := range crd.Spec.Versions {
+		if crd.Spec.Versions[i].Storage {
+			version = &crd.Spec.Versions[i]
+		}
+	}
+	if version == nil {
+		return nil, fmt.Errorf("CRD %s has no storage version", crdName)
+	}
+
+	gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: version.Name, Resource: crd.Spec.Names.Plural}
+	obj, err := clientSets.DClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("%s %s not found in namespace %s", crd.Spec.Names.Kind, name, namespace)
+		}
+		return nil, fmt.Errorf("error while getting %s: %w", crd.Spec.Names.Kind, err)
+	}
+
+	spec, _ := obj.Object["spec"].(map[string]any)
+	if spec == nil {
+		spec = map[string]any{}
+	}
+
+	if version.Schema != nil && version.Schema.OpenAPIV3Schema != nil {
+		if specSchema, ok := version.Schema.OpenAPIV3Schema.Properties["spec"]; ok {
+			StripDefaults(spec, &specSchema)
+		}
+	}
+
+	return spec, nil
+}
+
+// StripDefaults removes from the object the fields set to the default value
+// of their schema.
+func StripDefaults(obj map[string]any, schema *apiextensionsv1.JSONSchemaProps) {
+	for key, value := range obj {
+		prop, ok := schema.Properties[key]
+		if !ok {
+			continue
+		}
+
+		if prop.Default != nil && isDefault(value, prop.Default) {
+			delete(obj, key)
+			continue
+		}
+
+		stripValue(value, &prop)
+	}
+}
+
+func stripValue(value any, schema *apiextensionsv1.JSONSchemaProps) {
+	switch v := value.(type) {
+	case map[string]any:
+		StripDefaults(v, schema)
+	case []any:
+		if schema.Items == nil || schema.Items.Schema == nil {
+			return
+		}
+		for _, item := range v {
+			stripValue(item, schema.Items.Schema)
+		}
+	}
+}
+
+func isDefault(value any, def *apiextensionsv1.JSON) bool {
+	var defaultValue any
+	if err := json.Unmarshal(def.Raw, &defaultValue); err != nil {
+		return false
+	}
+
+	// Numbers are float64 once unmarshaled, and int64 in unstructured
+	// objects.
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(normalized, defaultValue)
+}
+
+// Compare returns the fields whose values differ between the specs, sorted
+// by path. Lists are compared item by item.
+func Compare(specs []map[string]any) []Difference {
+	flattened := make([]map[string]string, len(specs))
+	paths := map[string]struct{}{}
+	for i, spec := range specs {
+		flattened[i] = map[string]string{}
+		flatten("spec", spec, flattened[i])
+		for path := range flattened[i] {
+			paths[path] = struct{}{}
+		}
+	}
+
+	var differences []Difference
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		values := make([]string, len(flattened))
+		for i, fields := range flattened {
+			value, ok := fields[path]
+			if !ok {
+				value = Unset
+			}
+			values[i] = value
+		}
+
+		if slices.ContainsFunc(values, func(v string) bool { return v != values[0] }) {
+			differences = append(differences, Difference{Path: path, Values: values})
+		}
+	}
+
+	return differences
+}
+
+func flatten(path string, value any, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flatten(path+"."+key, child, fields)
+		}
+	case []any:
+		for i, child := range v {
+			flatten(path+"["+strconv.Itoa(i)+"]", child, fields)
+		}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			data = []byte(fmt.Sprint(v))
+		}
+		fields[path] = string(data)
+	}
+}
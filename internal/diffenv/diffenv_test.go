@@ -0,0 +1,81 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diffenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestStripDefaults(t *testing.T) {
+	schema := &apiextensionsv1.JSONSchemaProps{
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"evaluationInterval": {Type: "string", Default: &apiextensionsv1.JSON{Raw: []byte(`"30s"`)}},
+			"replicas":           {Type: "integer", Default: &apiextensionsv1.JSON{Raw: []byte(`1`)}},
+			"remoteWrite": {
+				Type: "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1.JSONSchemaProps{
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"sendExemplars": {Type: "boolean", Default: &apiextensionsv1.JSON{Raw: []byte(`false`)}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	spec := map[string]any{
+		"evaluationInterval": "30s",
+		"replicas":           int64(2),
+		"remoteWrite": []any{
+			map[string]any{"url": "http://remote", "sendExemplars": false},
+		},
+	}
+
+	StripDefaults(spec, schema)
+
+	assert.Equal(t, map[string]any{
+		"replicas": int64(2),
+		"remoteWrite": []any{
+			map[string]any{"url": "http://remote"},
+		},
+	}, spec)
+}
+
+func TestCompare(t *testing.T) {
+	staging := map[string]any{
+		"replicas":  int64(1),
+		"retention": "1d",
+		"remoteWrite": []any{
+			map[string]any{"url": "http://remote"},
+		},
+	}
+	production := map[string]any{
+		"replicas":  int64(3),
+		"retention": "1d",
+		"remoteWrite": []any{
+			map[string]any{"url": "http://remote"},
+			map[string]any{"url": "http://backup"},
+		},
+	}
+
+	assert.Equal(t, []Difference{
+		{Path: "spec.remoteWrite[1].url", Values: []string{Unset, `"http://backup"`}},
+		{Path: "spec.replicas", Values: []string{"1", "3"}},
+	}, Compare([]map[string]any{staging, production}))
+}
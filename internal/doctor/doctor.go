@@ -0,0 +1,277 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Status is the traffic-light status of a check.
+type Status int
+
+const (
+	OK Status = iota
+	Warn
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Check is the result of a doctor check.
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+const (
+	operatorSelector  = "app.kubernetes.io/name=prometheus-operator"
+	versionAnnotation = "operator.prometheus.io/version"
+	operatorContainer = "prometheus-operator"
+	checkCluster      = "Cluster reachable"
+	checkCRDs         = "CRDs installed and served"
+	checkOperator     = "Operator running"
+	checkSkew         = "Operator and CRDs versions"
+	checkReconciled   = "Operator reconciling"
+	checkPrometheus   = "Prometheus healthy"
+)
+
+// Run runs the doctor checks in order. The checks are limited to a few
+// requests each so that they complete quickly, the cluster check failing
+// stops the other ones.
+func Run(ctx context.Context, clientSets *k8sutil.ClientSets) []Check {
+	version, err := clientSets.KClient.Discovery().ServerVersion()
+	if err != nil {
+		return []Check{{Name: checkCluster, Status: Fail, Detail: err.Error()}}
+	}
+
+	checks := []Check{{Name: checkCluster, Status: OK, Detail: fmt.Sprintf("Kubernetes %s", version.GitVersion)}}
+
+	installed, check := checkInstalledCRDs(ctx, clientSets)
+	checks = append(checks, check)
+
+	operators, check := checkOperatorDeployments(ctx, clientSets)
+	checks = append(checks, check)
+	checks = append(checks, checkVersionSkew(installed, operators))
+
+	prometheuses, err := clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		detail := fmt.Sprintf("error while listing Prometheuses: %v", err)
+		return append(checks,
+			Check{Name: checkReconciled, Status: Fail, Detail: detail},
+			Check{Name: checkPrometheus, Status: Fail, Detail: detail},
+		)
+	}
+
+	return append(checks, checkReconciliation(prometheuses.Items), checkPrometheusHealth(prometheuses.Items))
+}
+
+func checkInstalledCRDs(ctx context.Context, clientSets *k8sutil.ClientSets) ([]apiextensionsv1.CustomResourceDefinition, Check) {
+	var (
+		installed []apiextensionsv1.CustomResourceDefinition
+		problems  []string
+	)
+
+	for _, name := range crds.List {
+		crd, err := clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				problems = append(problems, fmt.Sprintf("%s missing", name))
+				continue
+			}
+			return nil, Check{Name: checkCRDs, Status: Fail, Detail: fmt.Sprintf("error while getting CRD %s: %v", name, err)}
+		}
+
+		installed = append(installed, *crd)
+		switch {
+		case !isEstablished(crd):
+			problems = append(problems, fmt.Sprintf("%s not established", name))
+		case !isServed(crd):
+			problems = append(problems, fmt.Sprintf("%s not served", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return installed, Check{Name: checkCRDs, Status: Fail, Detail: strings.Join(problems, ", ")}
+	}
+	return installed, Check{Name: checkCRDs, Status: OK, Detail: fmt.Sprintf("%d CRDs established", len(installed))}
+}
+
+func isEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1.Established {
+			return c.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isServed(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			return true
+		}
+	}
+	return false
+}
+
+func checkOperatorDeployments(ctx context.Context, clientSets *k8sutil.ClientSets) ([]appsv1.Deployment, Check) {
+	deployments, err := clientSets.KClient.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: operatorSelector,
+	})
+	if err != nil {
+		return nil, Check{Name: checkOperator, Status: Fail, Detail: fmt.Sprintf("error while listing Prometheus Operator deployments: %v", err)}
+	}
+
+	if len(deployments.Items) == 0 {
+		return nil, Check{Name: checkOperator, Status: Fail, Detail: fmt.Sprintf("no deployment labeled %s found", operatorSelector)}
+	}
+
+	var ready, notReady []string
+	for _, d := range deployments.Items {
+		if d.Status.ReadyReplicas > 0 {
+			ready = append(ready, fmt.Sprintf("%s/%s", d.Namespace, d.Name))
+		} else {
+			notReady = append(notReady, fmt.Sprintf("%s/%s", d.Namespace, d.Name))
+		}
+	}
+
+	if len(ready) == 0 {
+		return deployments.Items, Check{Name: checkOperator, Status: Fail, Detail: fmt.Sprintf("no ready replica in %s", strings.Join(notReady, ", "))}
+	}
+	if len(notReady) > 0 {
+		return deployments.Items, Check{Name: checkOperator, Status: Warn, Detail: fmt.Sprintf("no ready replica in %s", strings.Join(notReady, ", "))}
+	}
+	return deployments.Items, Check{Name: checkOperator, Status: OK, Detail: fmt.Sprintf("%s ready", strings.Join(ready, ", "))}
+}
+
+// checkVersionSkew compares the version of the operator image with the
+// version annotation of the installed CRDs.
+func checkVersionSkew(installed []apiextensionsv1.CustomResourceDefinition, operators []appsv1.Deployment) Check {
+	crdVersions := map[string]struct{}{}
+	for _, crd := range installed {
+		if v, ok := crd.Annotations[versionAnnotation]; ok {
+			crdVersions[strings.TrimPrefix(v, "v")] = struct{}{}
+		}
+	}
+
+	var operatorVersions []string
+	for _, d := range operators {
+		if v := operatorVersion(d); v != "" {
+			operatorVersions = append(operatorVersions, v)
+		}
+	}
+
+	if len(crdVersions) == 0 || len(operatorVersions) == 0 {
+		return Check{Name: checkSkew, Status: Warn, Detail: "unable to determine the operator or CRDs version"}
+	}
+
+	var skewed []string
+	for _, v := range operatorVersions {
+		if _, ok := crdVersions[v]; !ok || len(crdVersions) > 1 {
+			skewed = append(skewed, v)
+		}
+	}
+
+	if len(skewed) > 0 {
+		versions := make([]string, 0, len(crdVersions))
+		for v := range crdVersions {
+			versions = append(versions, v)
+		}
+		return Check{Name: checkSkew, Status: Warn, Detail: fmt.Sprintf("operator %s, CRDs %s", strings.Join(operatorVersions, ", "), strings.Join(versions, ", "))}
+	}
+	return Check{Name: checkSkew, Status: OK, Detail: fmt.Sprintf("v%s", operatorVersions[0])}
+}
+
+// operatorVersion returns the version of the operator from the tag of its
+// image.
+func operatorVersion(d appsv1.Deployment) string {
+	for _, c := range d.Spec.Template.Spec.Containers {
+		if c.Name != operatorContainer {
+			continue
+		}
+		image, _, _ := strings.Cut(c.Image, "@")
+		i := strings.LastIndex(image, ":")
+		if i < 0 || strings.Contains(image[i:], "/") {
+			return ""
+		}
+		return strings.TrimPrefix(image[i+1:], "v")
+	}
+	return ""
+}
+
+func checkReconciliation(prometheuses []*monitoringv1.Prometheus) Check {
+	var failing []string
+	for _, p := range prometheuses {
+		if c := condition(p.Status.Conditions, monitoringv1.Reconciled); c != nil && c.Status != monitoringv1.ConditionTrue {
+			failing = append(failing, fmt.Sprintf("%s/%s (%s)", p.Namespace, p.Name, c.Reason))
+		}
+	}
+
+	if len(failing) > 0 {
+		return Check{Name: checkReconciled, Status: Fail, Detail: fmt.Sprintf("not reconciled: %s", strings.Join(failing, ", "))}
+	}
+	return Check{Name: checkReconciled, Status: OK, Detail: fmt.Sprintf("%d Prometheuses reconciled", len(prometheuses))}
+}
+
+func checkPrometheusHealth(prometheuses []*monitoringv1.Prometheus) Check {
+	if len(prometheuses) == 0 {
+		return Check{Name: checkPrometheus, Status: Warn, Detail: "no Prometheus found"}
+	}
+
+	available := 0
+	for _, p := range prometheuses {
+		if c := condition(p.Status.Conditions, monitoringv1.Available); c != nil && c.Status == monitoringv1.ConditionTrue {
+			available++
+		}
+	}
+
+	switch {
+	case available == 0:
+		return Check{Name: checkPrometheus, Status: Fail, Detail: "no Prometheus available"}
+	case available < len(prometheuses):
+		return Check{Name: checkPrometheus, Status: Warn, Detail: fmt.Sprintf("%d/%d Prometheuses available", available, len(prometheuses))}
+	default:
+		return Check{Name: checkPrometheus, Status: OK, Detail: fmt.Sprintf("%d/%d Prometheuses available", available, len(prometheuses))}
+	}
+}
+
+func condition(conditions []monitoringv1.Condition, conditionType monitoringv1.ConditionType) *monitoringv1.Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package doctor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	fakeApiExtensions "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRun(t *testing.T) {
+	type testCase struct {
+		name             string
+		crdVersion       string
+		operatorImage    string
+		operatorReady    int32
+		prometheusStatus monitoringv1.ConditionStatus
+		expected         map[string]Status
+	}
+
+	tests := []testCase{
+		{
+			name:             "Healthy",
+			crdVersion:       "0.75.1",
+			operatorImage:    "quay.io/prometheus-operator/prometheus-operator:v0.75.1",
+			operatorReady:    1,
+			prometheusStatus: monitoringv1.ConditionTrue,
+			expected: map[string]Status{
+				checkCluster:    OK,
+				checkCRDs:       OK,
+				checkOperator:   OK,
+				checkSkew:       OK,
+				checkReconciled: OK,
+				checkPrometheus: OK,
+			},
+		},
+		{
+			name:             "VersionSkewAndUnavailablePrometheus",
+			crdVersion:       "0.74.0",
+			operatorImage:    "quay.io/prometheus-operator/prometheus-operator:v0.75.1",
+			operatorReady:    1,
+			prometheusStatus: monitoringv1.ConditionFalse,
+			expected: map[string]Status{
+				checkCluster:    OK,
+				checkCRDs:       OK,
+				checkOperator:   OK,
+				checkSkew:       Warn,
+				checkReconciled: Fail,
+				checkPrometheus: Fail,
+			},
+		},
+		{
+			name:             "OperatorNotReady",
+			crdVersion:       "0.75.1",
+			operatorImage:    "registry.local:5000/prometheus-operator@sha256:0123",
+			prometheusStatus: monitoringv1.ConditionTrue,
+			expected: map[string]Status{
+				checkCluster:    OK,
+				checkCRDs:       OK,
+				checkOperator:   Fail,
+				checkSkew:       Warn,
+				checkReconciled: OK,
+				checkPrometheus: OK,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var crdObjects []runtime.Object
+			for _, name := range crds.List {
+				crdObjects = append(crdObjects, getCRD(name, tc.crdVersion))
+			}
+
+			clientSets := &k8sutil.ClientSets{
+				KClient:             fake.NewSimpleClientset(getOperatorDeployment(tc.operatorImage, tc.operatorReady)),
+				MClient:             monitoringclient.NewSimpleClientset(getPrometheus(tc.prometheusStatus)),
+				APIExtensionsClient: fakeApiExtensions.NewSimpleClientset(crdObjects...),
+			}
+
+			statuses := map[string]Status{}
+			for _, check := range Run(context.Background(), clientSets) {
+				statuses[check.Name] = check.Status
+			}
+			assert.Equal(t, tc.expected, statuses)
+		})
+	}
+}
+
+func getCRD(name, version string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{versionAnnotation: version},
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", Served: true, Storage: true},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+				{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func getOperatorDeployment(image string, ready int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus-operator",
+			Namespace: "monitoring",
+			Labels:    map[string]string{"app.kubernetes.io/name": "prometheus-operator"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "prometheus-operator", Image: image},
+					},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ReadyReplicas: ready,
+		},
+	}
+}
+
+func getPrometheus(status monitoringv1.ConditionStatus) *monitoringv1.Prometheus {
+	return &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "k8s",
+			Namespace: "monitoring",
+		},
+		Status: monitoringv1.PrometheusStatus{
+			Conditions: []monitoringv1.Condition{
+				{Type: monitoringv1.Available, Status: status},
+				{Type: monitoringv1.Reconciled, Status: status},
+			},
+		},
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const group = "monitoring.coreos.com"
+
+// Field is the documentation of a field of a CRD schema.
+type Field struct {
+	Kind        string
+	APIVersion  string
+	Path        string
+	Type        string
+	Description string
+	Fields      []Child
+}
+
+// Child is a direct sub-field of a documented field.
+type Child struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// ResolveCRDName returns the name of the monitoring CRD matching a resource
+// name, given in its singular or plural form.
+func ResolveCRDName(resource string) (string, error) {
+	resource = strings.ToLower(resource)
+	for _, name := range crds.List {
+		plural := strings.TrimSuffix(name, "."+group)
+		if plural == resource || plural == resource+"s" || plural == resource+"es" {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown resource %s, must be one of the %s CRDs", resource, group)
+}
+
+// InstalledCRD returns the CRD installed in the cluster.
+func InstalledCRD(ctx context.Context, clientSets *k8sutil.ClientSets, name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, err := clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while getting CRD %s: %w", name, err)
+	}
+	return crd, nil
+}
+
+// ReleasedCRD returns the CRD released with the given version of the
+// Prometheus Operator.
+func ReleasedCRD(ctx context.Context, gitHubClient *github.Client, name, version string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	reader, _, err := gitHubClient.Repositories.DownloadContents(
+		ctx,
+		"prometheus-operator",
+		"prometheus-operator",
+		fmt.Sprintf("example/prometheus-operator-crd/%s_%s.yaml", group, strings.TrimSuffix(name, "."+group)),
+		&github.RepositoryContentGetOptions{
+			Ref: fmt.Sprintf("v%s", version),
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error while downloading CRD %s: %w", name, err)
+	}
+	defer reader.Close()
+
+	crds, err := k8sutil.DecodeCRDs(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error while deserializing CRD %s: %w", name, err)
+	}
+
+	for _, crd := range crds {
+		if crd.Name == name {
+			return crd, nil
+		}
+	}
+	return nil, fmt.Errorf("CRD %s not found in the released file", name)
+}
+
+// Explain returns the documentation of the field at the given path of the
+// CRD schema, for the given version or the storage version when empty. The
+// path doesn't include the resource name, an empty path documents the
+// resource itself. Arrays are traversed transparently.
+func Explain(crd *apiextensionsv1.CustomResourceDefinition, version string, path []string) (*Field, error) {
+	v, err := crdVersion(crd, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+		return nil, fmt.Errorf("CRD %s has no schema for version %s", crd.Name, v.Name)
+	}
+
+	schema := v.Schema.OpenAPIV3Schema
+	for i, name := range path {
+		schema = elementSchema(schema)
+		prop, ok := schema.Properties[name]
+		if !ok {
+			return nil, fmt.Errorf("field %s doesn't exist in %s", strings.Join(path[:i+1], "."), crd.Spec.Names.Kind)
+		}
+		schema = &prop
+	}
+
+	field := &Field{
+		Kind:        crd.Spec.Names.Kind,
+		APIVersion:  fmt.Sprintf("%s/%s", crd.Spec.Group, v.Name),
+		Path:        strings.Join(path, "."),
+		Type:        typeName(schema),
+		Description: schema.Description,
+	}
+
+	element := elementSchema(schema)
+	for _, name := range slices.Sorted(maps.Keys(element.Properties)) {
+		prop := element.Properties[name]
+		field.Fields = append(field.Fields, Child{
+			Name:        name,
+			Type:        typeName(&prop),
+			Description: prop.Description,
+			Required:    slices.Contains(element.Required, name),
+		})
+	}
+
+	return field, nil
+}
+
+// Render formats the documentation of a field like kubectl explain.
+func Render(w io.Writer, field *Field) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "KIND:     %s\n", field.Kind)
+	fmt.Fprintf(&b, "VERSION:  %s\n\n", field.APIVersion)
+
+	if field.Path != "" {
+		fmt.Fprintf(&b, "FIELD: %s <%s>\n\n", field.Path, field.Type)
+	}
+
+	b.WriteString("DESCRIPTION:\n")
+	writeIndented(&b, field.Description, "    ")
+
+	if len(field.Fields) > 0 {
+		b.WriteString("\nFIELDS:\n")
+		for _, child := range field.Fields {
+			required := ""
+			if child.Required {
+				required = " -required-"
+			}
+			fmt.Fprintf(&b, "  %s\t<%s>%s\n", child.Name, child.Type, required)
+			writeIndented(&b, child.Description, "    ")
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeIndented(b *strings.Builder, text, indent string) {
+	if text == "" {
+		text = "<empty>"
+	}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+}
+
+func crdVersion(crd *apiextensionsv1.CustomResourceDefinition, version string) (*apiextensionsv1.CustomResourceDefinitionVersion, error) {
+	for i, v := range crd.Spec.Versions {
+		if (version == "" && v.Storage) || v.Name == version {
+			return &crd.Spec.Versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("version %s not found in CRD %s", version, crd.Name)
+}
+
+// elementSchema returns the schema of the elements of an array, and the
+// schema itself otherwise.
+func elementSchema(schema *apiextensionsv1.JSONSchemaProps) *apiextensionsv1.JSONSchemaProps {
+	for schema.Type == "array" && schema.Items != nil && schema.Items.Schema != nil {
+		schema = schema.Items.Schema
+	}
+	return schema
+}
+
+// typeName returns the type of a schema the way kubectl explain prints it.
+func typeName(schema *apiextensionsv1.JSONSchemaProps) string {
+	switch {
+	case schema.Type == "array" && schema.Items != nil && schema.Items.Schema != nil:
+		return "[]" + typeName(schema.Items.Schema)
+	case schema.Type == "object" && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		return "map[string]" + typeName(schema.AdditionalProperties.Schema)
+	case schema.Type == "object":
+		return "Object"
+	case schema.XIntOrString:
+		return "IntOrString"
+	case schema.Type == "":
+		return "Object"
+	default:
+		return schema.Type
+	}
+}
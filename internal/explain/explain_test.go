@@ -0,0 +1,177 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package explain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveCRDName(t *testing.T) {
+	for resource, expected := range map[string]string{
+		"prometheus":         "prometheuses.monitoring.coreos.com",
+		"Prometheuses":       "prometheuses.monitoring.coreos.com",
+		"servicemonitor":     "servicemonitors.monitoring.coreos.com",
+		"alertmanagerconfig": "alertmanagerconfigs.monitoring.coreos.com",
+	} {
+		name, err := ResolveCRDName(resource)
+		require.NoError(t, err)
+		assert.Equal(t, expected, name)
+	}
+
+	_, err := ResolveCRDName("deployment")
+	require.Error(t, err)
+}
+
+func TestExplain(t *testing.T) {
+	crd := getPrometheusCRD()
+
+	type testCase struct {
+		name           string
+		path           []string
+		shouldFail     bool
+		expectedType   string
+		expectedFields []string
+	}
+
+	tests := []testCase{
+		{
+			name:           "Resource",
+			expectedType:   "Object",
+			expectedFields: []string{"spec"},
+		},
+		{
+			name:           "ArrayOfObjects",
+			path:           []string{"spec", "remoteWrite"},
+			expectedType:   "[]Object",
+			expectedFields: []string{"queueConfig", "url"},
+		},
+		{
+			name:           "FieldOfArrayElement",
+			path:           []string{"spec", "remoteWrite", "queueConfig"},
+			expectedType:   "Object",
+			expectedFields: []string{"batchSendDeadline", "capacity"},
+		},
+		{
+			name:         "Map",
+			path:         []string{"spec", "externalLabels"},
+			expectedType: "map[string]string",
+		},
+		{
+			name:       "UnknownField",
+			path:       []string{"spec", "remoteWrite", "unknown"},
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			field, err := Explain(crd, "", tc.path)
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "monitoring.coreos.com/v1", field.APIVersion)
+			assert.Equal(t, tc.expectedType, field.Type)
+
+			var fields []string
+			for _, child := range field.Fields {
+				fields = append(fields, child.Name)
+			}
+			assert.Equal(t, tc.expectedFields, fields)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	field, err := Explain(getPrometheusCRD(), "", []string{"spec", "remoteWrite"})
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, Render(&b, field))
+
+	assert.Contains(t, b.String(), "FIELD: spec.remoteWrite <[]Object>")
+	assert.Contains(t, b.String(), "  url\t<string> -required-")
+	assert.Contains(t, b.String(), "    The URL of the endpoint to send samples to.")
+}
+
+func getPrometheusCRD() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "prometheuses.monitoring.coreos.com",
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "monitoring.coreos.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind: "Prometheus",
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    "v1",
+					Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type:        "object",
+							Description: "Prometheus defines a Prometheus deployment.",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"externalLabels": {
+											Type: "object",
+											AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+												Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"},
+											},
+										},
+										"remoteWrite": {
+											Type:        "array",
+											Description: "Defines the list of remote write configurations.",
+											Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+												Schema: &apiextensionsv1.JSONSchemaProps{
+													Type:     "object",
+													Required: []string{"url"},
+													Properties: map[string]apiextensionsv1.JSONSchemaProps{
+														"url": {
+															Type:        "string",
+															Description: "The URL of the endpoint to send samples to.",
+														},
+														"queueConfig": {
+															Type: "object",
+															Properties: map[string]apiextensionsv1.JSONSchemaProps{
+																"capacity":          {Type: "integer"},
+																"batchSendDeadline": {Type: "string"},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
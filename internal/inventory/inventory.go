@@ -0,0 +1,177 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inventory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const versionLabel = "app.kubernetes.io/version"
+
+// resources are the kinds of objects which poctl creates.
+var resources = []schema.GroupVersionResource{
+	{Version: "v1", Resource: "configmaps"},
+	{Version: "v1", Resource: "serviceaccounts"},
+	{Version: "v1", Resource: "services"},
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheuses"},
+	{Group: "monitoring.coreos.com", Version: "v1alpha1", Resource: "prometheusagents"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "alertmanagers"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "podmonitors"},
+}
+
+// Resource is an object of a stack created by poctl.
+type Resource struct {
+	// Stack is the name of the anchor ConfigMap of the stack.
+	Stack     string
+	Kind      string
+	Namespace string
+	Name      string
+	Version   string
+	// Applied is the last time poctl applied the object in UTC, zero when
+	// unknown.
+	Applied time.Time
+	// DriftedBy lists the field managers which changed the object since poctl
+	// applied it.
+	DriftedBy []string
+}
+
+// List returns the resources of the stacks created by poctl, found with the
+// stack label. Only the resources of the given stack are returned unless
+// allStacks is set.
+func List(ctx context.Context, clientSets *k8sutil.ClientSets, stack string, allStacks bool) ([]Resource, error) {
+	selector := create.StackLabel
+	if !allStacks {
+		selector = fmt.Sprintf("%s=%s", create.StackLabel, create.StackAnchor(stack))
+	}
+
+	var inventory []Resource
+	for _, gvr := range resources {
+		err := k8sutil.EachListItem(ctx, gvr.Resource, clientSets.DClient.Resource(gvr).Namespace(metav1.NamespaceAll).List, metav1.ListOptions{
+			LabelSelector: selector,
+		}, func(obj *unstructured.Unstructured) error {
+			inventory = append(inventory, newResource(*obj))
+			return nil
+		})
+		// The CRDs may not be installed.
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	slices.SortFunc(inventory, func(a, b Resource) int {
+		return cmp.Or(
+			cmp.Compare(a.Stack, b.Stack),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	return inventory, nil
+}
+
+func newResource(obj unstructured.Unstructured) Resource {
+	r := Resource{
+		Stack:     obj.GetLabels()[create.StackLabel],
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Version:   objectVersion(obj),
+	}
+
+	for _, entry := range obj.GetManagedFields() {
+		if k8sutil.IsFieldManager(entry.Manager) && entry.Operation == metav1.ManagedFieldsOperationApply {
+			if entry.Time != nil && entry.Time.UTC().After(r.Applied) {
+				r.Applied = entry.Time.UTC()
+			}
+			continue
+		}
+
+		if ownsDesiredState(entry) && !slices.Contains(r.DriftedBy, entry.Manager) {
+			r.DriftedBy = append(r.DriftedBy, entry.Manager)
+		}
+	}
+
+	return r
+}
+
+// objectVersion returns the version of the component run by the object: the
+// version label, the version of the Prometheus Operator resources or the tag
+// of the image of the workloads.
+func objectVersion(obj unstructured.Unstructured) string {
+	if v, ok := obj.GetLabels()[versionLabel]; ok {
+		return v
+	}
+
+	if v, found, _ := unstructured.NestedString(obj.Object, "spec", "version"); found {
+		return v
+	}
+
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return ""
+	}
+
+	container, _ := containers[0].(map[string]any)
+	image, _ := container["image"].(string)
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[i+1:]
+	}
+
+	return ""
+}
+
+// ownsDesiredState reports whether a field manager owns fields of the object
+// besides its metadata, such as its spec. Controllers updating the status
+// through its subresource don't count.
+func ownsDesiredState(entry metav1.ManagedFieldsEntry) bool {
+	if entry.Subresource != "" || entry.FieldsV1 == nil {
+		return false
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+		return false
+	}
+
+	for field := range fields {
+		if field != "f:metadata" && field != "f:status" {
+			return true
+		}
+	}
+
+	return false
+}
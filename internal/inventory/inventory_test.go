@@ -0,0 +1,156 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inventory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/create"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newObject(apiVersion, kind, namespace, name, stack string, spec map[string]any, managedFields ...metav1.ManagedFieldsEntry) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+	}}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{create.StackLabel: stack})
+	obj.SetManagedFields(managedFields)
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	return obj
+}
+
+// listKinds returns the list kinds of the resources for the fake dynamic
+// client.
+func listKinds() map[schema.GroupVersionResource]string {
+	kinds := map[string]string{
+		"configmaps":          "ConfigMapList",
+		"serviceaccounts":     "ServiceAccountList",
+		"services":            "ServiceList",
+		"deployments":         "DeploymentList",
+		"statefulsets":        "StatefulSetList",
+		"daemonsets":          "DaemonSetList",
+		"clusterroles":        "ClusterRoleList",
+		"clusterrolebindings": "ClusterRoleBindingList",
+		"roles":               "RoleList",
+		"rolebindings":        "RoleBindingList",
+		"prometheuses":        "PrometheusList",
+		"prometheusagents":    "PrometheusAgentList",
+		"alertmanagers":       "AlertmanagerList",
+		"servicemonitors":     "ServiceMonitorList",
+		"podmonitors":         "PodMonitorList",
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, gvr := range resources {
+		listKinds[gvr] = kinds[gvr.Resource]
+	}
+	return listKinds
+}
+
+func TestList(t *testing.T) {
+	applied := metav1.NewTime(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+
+	poctlEntry := metav1.ManagedFieldsEntry{
+		Manager:    k8sutil.ApplyOption.FieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		Time:       &applied,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+	}
+
+	objects := []runtime.Object{
+		newObject("apps/v1", "Deployment", "default", "prometheus-operator", "poctl-stack", map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{"name": "prometheus-operator", "image": "quay.io/prometheus-operator/prometheus-operator:v0.78.2"},
+					},
+				},
+			},
+		},
+			poctlEntry,
+			metav1.ManagedFieldsEntry{
+				Manager:    "kubectl-edit",
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				FieldsType: "FieldsV1",
+				FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+			},
+			metav1.ManagedFieldsEntry{
+				Manager:    "kube-controller-manager",
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				FieldsType: "FieldsV1",
+				FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{}}}`)},
+			},
+		),
+		newObject("monitoring.coreos.com/v1", "Prometheus", "default", "prometheus", "poctl-stack", map[string]any{"version": "v2.54.1"},
+			poctlEntry,
+			metav1.ManagedFieldsEntry{
+				Manager:     "PrometheusOperator",
+				Operation:   metav1.ManagedFieldsOperationApply,
+				Subresource: "status",
+				FieldsType:  "FieldsV1",
+				FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)},
+			},
+		),
+		newObject("v1", "ConfigMap", "default", "poctl-stack-team-a", "poctl-stack-team-a", nil),
+		newObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "team-a-prometheus", "poctl-stack-team-a", nil),
+	}
+
+	dClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds(), objects...)
+	clientSets := &k8sutil.ClientSets{DClient: dClient}
+
+	resources, err := List(context.Background(), clientSets, "", true)
+	require.NoError(t, err)
+	require.Len(t, resources, 4)
+
+	assert.Equal(t, Resource{
+		Stack:     "poctl-stack",
+		Kind:      "Deployment",
+		Namespace: "default",
+		Name:      "prometheus-operator",
+		Version:   "v0.78.2",
+		Applied:   applied.Time,
+		DriftedBy: []string{"kubectl-edit"},
+	}, resources[0])
+
+	assert.Equal(t, Resource{
+		Stack:     "poctl-stack",
+		Kind:      "Prometheus",
+		Namespace: "default",
+		Name:      "prometheus",
+		Version:   "v2.54.1",
+		Applied:   applied.Time,
+	}, resources[1])
+
+	resources, err = List(context.Background(), clientSets, "team-a", false)
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "ClusterRole", resources[0].Kind)
+	assert.Equal(t, "ConfigMap", resources[1].Kind)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package junit writes the results of the checks of poctl as JUnit XML
+// reports, which the CI systems display as test results.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// TestSuites is the root element of a report.
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr,omitempty"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite groups the test cases of a run, such as the checks run against
+// a cluster.
+type TestSuite struct {
+	Name     string     `xml:"name,attr"`
+	Tests    int        `xml:"tests,attr"`
+	Failures int        `xml:"failures,attr"`
+	Skipped  int        `xml:"skipped,attr"`
+	Cases    []TestCase `xml:"testcase"`
+}
+
+// TestCase is a check, failed when Failure is set and not run when Skipped
+// is set.
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Failure   *Failure `xml:"failure,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
+	// SystemOut holds the output of the check which doesn't fail it, such as
+	// warnings.
+	SystemOut string `xml:"system-out,omitempty"`
+}
+
+// Failure is the reason of a failed test case.
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// Skipped is the reason of a test case which didn't run.
+type Skipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// Add appends the test case to the suite and counts it.
+func (s *TestSuite) Add(c TestCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	switch {
+	case c.Failure != nil:
+		s.Failures++
+	case c.Skipped != nil:
+		s.Skipped++
+	}
+}
+
+// Write writes the report with the totals of its suites.
+func Write(w io.Writer, report TestSuites) error {
+	report.Tests, report.Failures, report.Skipped = 0, 0, 0
+	for _, s := range report.Suites {
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.Skipped += s.Skipped
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error while encoding JUnit report: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "%s%s\n", xml.Header, data); err != nil {
+		return fmt.Errorf("error while writing JUnit report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package junit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	suite := TestSuite{Name: "prod-eu"}
+	suite.Add(TestCase{Name: "monitoring/k8s", ClassName: "prometheus", SystemOut: "PR101: the admin API is exposed"})
+	suite.Add(TestCase{
+		Name:      "payments/ledger",
+		ClassName: "servicemonitor",
+		Failure:   &Failure{Message: "no services with port web", Type: "SM003", Text: "no services with port web"},
+	})
+	suite.Add(TestCase{Name: "payments/checkout", ClassName: "servicemonitor", Skipped: &Skipped{Message: "analysis interrupted"}})
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, TestSuites{Name: "poctl analyze", Suites: []TestSuite{suite}}))
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="poctl analyze" tests="3" failures="1" skipped="1">
+  <testsuite name="prod-eu" tests="3" failures="1" skipped="1">
+    <testcase name="monitoring/k8s" classname="prometheus">
+      <system-out>PR101: the admin API is exposed</system-out>
+    </testcase>
+    <testcase name="payments/ledger" classname="servicemonitor">
+      <failure message="no services with port web" type="SM003">no services with port web</failure>
+    </testcase>
+    <testcase name="payments/checkout" classname="servicemonitor">
+      <skipped message="analysis interrupted"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
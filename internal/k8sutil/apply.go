@@ -0,0 +1,352 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	appsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	batchv1 "k8s.io/client-go/applyconfigurations/batch/v1"
+	corev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	rbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultApplyConcurrency is the number of objects applied at once by an
+// Applier.
+const DefaultApplyConcurrency = 4
+
+// ErrNotApplied is the error of the objects left out by an Applier after the
+// failure of an object of an earlier stage.
+var ErrNotApplied = errors.New("not applied after the failure of an earlier object")
+
+// applyStages are the stages of the kinds applied before the others, so that
+// the objects exist before the objects referencing them. The other kinds are
+// applied in the last stage.
+var applyStages = map[string]int{
+	"Namespace":                0,
+	"CustomResourceDefinition": 0,
+	"ServiceAccount":           1,
+	"ClusterRole":              1,
+	"Role":                     1,
+	"ConfigMap":                1,
+	"Secret":                   1,
+	"ClusterRoleBinding":       2,
+	"RoleBinding":              2,
+}
+
+// lastApplyStage is the stage of the kinds missing from applyStages.
+const lastApplyStage = 3
+
+// ApplyResult is the outcome of the apply of an object.
+type ApplyResult struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	// Err is the error of the apply, ErrNotApplied when the object was left
+	// out after the failure of an earlier stage.
+	Err error
+}
+
+// ObjectName returns the name of the object, prefixed with its namespace
+// when it has one.
+func (r ApplyResult) ObjectName() string {
+	if r.Namespace == "" {
+		return r.Name
+	}
+	return r.Namespace + "/" + r.Name
+}
+
+// Applier applies objects of different kinds with server-side apply: the
+// apply configurations of the Kubernetes and monitoring.coreos.com types
+// with their typed client, the other objects, such as unstructured ones,
+// with the dynamic client. The objects are applied in stages, the Namespaces
+// and CRDs first, then the ServiceAccounts, the roles and the configuration,
+// then the bindings and finally the other objects. The objects of a stage
+// are applied concurrently, a failure leaving the later stages out.
+type Applier struct {
+	clientSets  *ClientSets
+	opts        metav1.ApplyOptions
+	concurrency int
+	stages      map[string]int
+}
+
+// NewApplier returns an Applier applying the objects with ApplyOption and
+// DefaultApplyConcurrency.
+func NewApplier(clientSets *ClientSets) *Applier {
+	return &Applier{
+		clientSets:  clientSets,
+		opts:        ApplyOption,
+		concurrency: DefaultApplyConcurrency,
+		stages:      map[string]int{},
+	}
+}
+
+// WithOptions sets the options of the applies, such as Force or DryRun.
+func (a *Applier) WithOptions(opts metav1.ApplyOptions) *Applier {
+	a.opts = opts
+	return a
+}
+
+// WithConcurrency sets the number of objects applied at once, 1 applying the
+// objects of a stage one by one in their order.
+func (a *Applier) WithConcurrency(n int) *Applier {
+	a.concurrency = max(n, 1)
+	return a
+}
+
+// WithStage applies the objects of the kind in the given stage instead of
+// the default one, for the objects depending on objects of the same stage.
+func (a *Applier) WithStage(kind string, stage int) *Applier {
+	a.stages[kind] = stage
+	return a
+}
+
+// Apply applies the objects and returns the result of each object in their
+// order, the nil ones being skipped. The objects are either apply
+// configurations or unstructured objects, carrying their kind and API
+// version.
+func (a *Applier) Apply(ctx context.Context, objects ...any) []ApplyResult {
+	var (
+		results []ApplyResult
+		configs []any
+		content []*unstructured.Unstructured
+	)
+	for _, obj := range objects {
+		if v := reflect.ValueOf(obj); obj == nil || (v.Kind() == reflect.Pointer && v.IsNil()) {
+			continue
+		}
+
+		u, err := applyContent(obj)
+		r := ApplyResult{Err: err}
+		if u != nil {
+			r.APIVersion, r.Kind, r.Namespace, r.Name = u.GetAPIVersion(), u.GetKind(), u.GetNamespace(), u.GetName()
+		}
+		results = append(results, r)
+		configs = append(configs, obj)
+		content = append(content, u)
+	}
+
+	stages := make(map[int][]int)
+	for i, r := range results {
+		stage := a.stage(r.Kind)
+		stages[stage] = append(stages[stage], i)
+	}
+
+	var failed bool
+	for _, stage := range slices.Sorted(maps.Keys(stages)) {
+		indexes := stages[stage]
+		if failed {
+			for _, i := range indexes {
+				if results[i].Err == nil {
+					results[i].Err = ErrNotApplied
+				}
+			}
+			continue
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, a.concurrency)
+		for _, i := range indexes {
+			// The objects which couldn't be converted fail their stage.
+			if results[i].Err != nil {
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[i].Err = a.apply(ctx, configs[i], content[i])
+			}()
+		}
+		wg.Wait()
+
+		failed = slices.ContainsFunc(indexes, func(i int) bool { return results[i].Err != nil })
+	}
+
+	return results
+}
+
+// stage returns the stage in which the objects of the kind are applied.
+func (a *Applier) stage(kind string) int {
+	if stage, ok := a.stages[kind]; ok {
+		return stage
+	}
+	if stage, ok := applyStages[kind]; ok {
+		return stage
+	}
+	return lastApplyStage
+}
+
+// apply applies an object with the client of its type, the dynamic client
+// when it has no typed client, after migrating the fields applied by the
+// previous versions of poctl.
+func (a *Applier) apply(ctx context.Context, obj any, u *unstructured.Unstructured) error {
+	k, m := a.clientSets.KClient, a.clientSets.MClient
+	ns := u.GetNamespace()
+
+	if len(a.opts.DryRun) == 0 {
+		client := ResourceClient(a.clientSets, u.GroupVersionKind(), ns)
+		if err := migrateFieldManager(ctx, client, u.GetKind(), u.GetName(), a.opts.FieldManager); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch c := obj.(type) {
+	case *corev1.NamespaceApplyConfiguration:
+		_, err = k.CoreV1().Namespaces().Apply(ctx, c, a.opts)
+	case *corev1.ServiceAccountApplyConfiguration:
+		_, err = k.CoreV1().ServiceAccounts(ns).Apply(ctx, c, a.opts)
+	case *corev1.ConfigMapApplyConfiguration:
+		_, err = k.CoreV1().ConfigMaps(ns).Apply(ctx, c, a.opts)
+	case *corev1.SecretApplyConfiguration:
+		_, err = k.CoreV1().Secrets(ns).Apply(ctx, c, a.opts)
+	case *corev1.ServiceApplyConfiguration:
+		_, err = k.CoreV1().Services(ns).Apply(ctx, c, a.opts)
+	case *rbacv1.ClusterRoleApplyConfiguration:
+		_, err = k.RbacV1().ClusterRoles().Apply(ctx, c, a.opts)
+	case *rbacv1.ClusterRoleBindingApplyConfiguration:
+		_, err = k.RbacV1().ClusterRoleBindings().Apply(ctx, c, a.opts)
+	case *rbacv1.RoleApplyConfiguration:
+		_, err = k.RbacV1().Roles(ns).Apply(ctx, c, a.opts)
+	case *rbacv1.RoleBindingApplyConfiguration:
+		_, err = k.RbacV1().RoleBindings(ns).Apply(ctx, c, a.opts)
+	case *appsv1.DeploymentApplyConfiguration:
+		_, err = k.AppsV1().Deployments(ns).Apply(ctx, c, a.opts)
+	case *appsv1.DaemonSetApplyConfiguration:
+		_, err = k.AppsV1().DaemonSets(ns).Apply(ctx, c, a.opts)
+	case *appsv1.StatefulSetApplyConfiguration:
+		_, err = k.AppsV1().StatefulSets(ns).Apply(ctx, c, a.opts)
+	case *batchv1.CronJobApplyConfiguration:
+		_, err = k.BatchV1().CronJobs(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.PrometheusApplyConfiguration:
+		_, err = m.MonitoringV1().Prometheuses(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.AlertmanagerApplyConfiguration:
+		_, err = m.MonitoringV1().Alertmanagers(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.ThanosRulerApplyConfiguration:
+		_, err = m.MonitoringV1().ThanosRulers(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.ServiceMonitorApplyConfiguration:
+		_, err = m.MonitoringV1().ServiceMonitors(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.PodMonitorApplyConfiguration:
+		_, err = m.MonitoringV1().PodMonitors(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.ProbeApplyConfiguration:
+		_, err = m.MonitoringV1().Probes(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1.PrometheusRuleApplyConfiguration:
+		_, err = m.MonitoringV1().PrometheusRules(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1alpha1.PrometheusAgentApplyConfiguration:
+		_, err = m.MonitoringV1alpha1().PrometheusAgents(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1alpha1.ScrapeConfigApplyConfiguration:
+		_, err = m.MonitoringV1alpha1().ScrapeConfigs(ns).Apply(ctx, c, a.opts)
+	case *monitoringv1alpha1.AlertmanagerConfigApplyConfiguration:
+		_, err = m.MonitoringV1alpha1().AlertmanagerConfigs(ns).Apply(ctx, c, a.opts)
+	default:
+		if IsClusterScoped(u.GetKind()) && ns != "" {
+			u = u.DeepCopy()
+			u.SetNamespace("")
+		}
+		_, err = ResourceClient(a.clientSets, u.GroupVersionKind(), ns).Apply(ctx, u.GetName(), u, a.opts)
+	}
+	return err
+}
+
+// ApplyErr returns the errors of the objects which failed to be applied, nil
+// when all of them were applied. The objects left out after a failure aren't
+// reported.
+func ApplyErr(results []ApplyResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err == nil || errors.Is(r.Err, ErrNotApplied) {
+			continue
+		}
+		name := strings.TrimSpace(r.Kind + " " + r.ObjectName())
+		errs = append(errs, fmt.Errorf("error while applying %s: %v", cmp.Or(name, "object"), r.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// clusterScopedKinds are the cluster-scoped kinds of the objects created by
+// poctl.
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                true,
+	"CustomResourceDefinition": true,
+	"ClusterRole":              true,
+	"ClusterRoleBinding":       true,
+	"PriorityClass":            true,
+}
+
+// IsClusterScoped reports whether the objects of the kind, one of the kinds
+// created by poctl, are cluster-scoped.
+func IsClusterScoped(kind string) bool {
+	return clusterScopedKinds[kind]
+}
+
+// ResourceClient returns the dynamic client of the objects of the kind in
+// the namespace. The namespace is ignored for the cluster-scoped kinds,
+// which the builders give the namespace of their stack but which aren't
+// served under a namespace.
+func ResourceClient(clientSets *ClientSets, gvk schema.GroupVersionKind, namespace string) dynamic.ResourceInterface {
+	resource := clientSets.DClient.Resource(ResourceFor(gvk))
+	if IsClusterScoped(gvk.Kind) {
+		return resource
+	}
+	return resource.Namespace(namespace)
+}
+
+// ResourceFor returns the resource of the kinds created by poctl.
+func ResourceFor(gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	resource := strings.ToLower(gvk.Kind)
+	if strings.HasSuffix(resource, "s") {
+		resource += "es"
+	} else {
+		resource += "s"
+	}
+	return gvk.GroupVersion().WithResource(resource)
+}
+
+// applyContent returns the content of an object to apply, which must carry
+// its kind and name.
+func applyContent(obj any) (*unstructured.Unstructured, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("error while converting %T: %w", obj, err)
+		}
+		u = &unstructured.Unstructured{Object: content}
+	}
+
+	if u.GetKind() == "" || u.GetName() == "" {
+		return u, fmt.Errorf("%T has no kind or name", obj)
+	}
+	return u, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	monitoringfake "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	appsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	corev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	rbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// applyRecorder records the applies reaching the fake clients, failing the
+// ones of the resources in failures.
+type applyRecorder struct {
+	mu       sync.Mutex
+	applies  []string
+	failures map[string]error
+}
+
+func (r *applyRecorder) react(client string) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		resource := action.GetResource().Resource
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.applies = append(r.applies, client+" "+resource+" "+patch.GetName())
+		return true, nil, r.failures[resource]
+	}
+}
+
+func newApplyClientSets(r *applyRecorder) *ClientSets {
+	kClient := kubefake.NewSimpleClientset()
+	kClient.PrependReactor("patch", "*", r.react("typed"))
+	mClient := monitoringfake.NewSimpleClientset()
+	mClient.PrependReactor("patch", "*", r.react("typed"))
+	dClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dClient.PrependReactor("patch", "*", r.react("dynamic"))
+
+	return &ClientSets{KClient: kClient, MClient: mClient, DClient: dClient, APIExtensionsClient: apiextensionsfake.NewSimpleClientset()}
+}
+
+func TestApplier(t *testing.T) {
+	configMap := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "prometheus-config", "namespace": "default"},
+	}}
+
+	for _, tc := range []struct {
+		name     string
+		failures map[string]error
+		stages   map[string]int
+		applies  []string
+		errs     []error
+		err      string
+	}{
+		{
+			name: "Stages",
+			applies: []string{
+				"typed serviceaccounts prometheus",
+				"dynamic configmaps prometheus-config",
+				"typed clusterrolebindings prometheus",
+				"typed deployments prometheus",
+				"typed servicemonitors prometheus",
+			},
+			errs: []error{nil, nil, nil, nil, nil},
+		},
+		{
+			name:   "StageHint",
+			stages: map[string]int{"ServiceMonitor": 0},
+			applies: []string{
+				"typed servicemonitors prometheus",
+				"typed serviceaccounts prometheus",
+				"dynamic configmaps prometheus-config",
+				"typed clusterrolebindings prometheus",
+				"typed deployments prometheus",
+			},
+			errs: []error{nil, nil, nil, nil, nil},
+		},
+		{
+			name:     "FailureLeavesLaterStagesOut",
+			failures: map[string]error{"serviceaccounts": errors.New("forbidden")},
+			applies: []string{
+				"typed serviceaccounts prometheus",
+				"dynamic configmaps prometheus-config",
+			},
+			errs: []error{nil, nil, ErrNotApplied, ErrNotApplied, ErrNotApplied},
+			err:  "error while applying ServiceAccount default/prometheus: forbidden",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &applyRecorder{failures: tc.failures}
+			applier := NewApplier(newApplyClientSets(recorder)).WithConcurrency(1)
+			for kind, stage := range tc.stages {
+				applier = applier.WithStage(kind, stage)
+			}
+
+			var skipped *rbacv1.ClusterRoleApplyConfiguration
+			results := applier.Apply(context.Background(),
+				appsv1.Deployment("prometheus", "default"),
+				monitoringv1.ServiceMonitor("prometheus", "default"),
+				rbacv1.ClusterRoleBinding("prometheus"),
+				skipped,
+				corev1.ServiceAccount("prometheus", "default"),
+				configMap,
+			)
+
+			assert.Equal(t, tc.applies, recorder.applies)
+
+			// The results follow the order of the objects.
+			require.Len(t, results, 5)
+			assert.Equal(t, "Deployment", results[0].Kind)
+			assert.Equal(t, "default/prometheus", results[0].ObjectName())
+			assert.Equal(t, "prometheus", results[2].ObjectName())
+			assert.Equal(t, "ConfigMap", results[4].Kind)
+
+			// The errors are listed in the order of the stages.
+			errs := []error{results[3].Err, results[4].Err, results[2].Err, results[0].Err, results[1].Err}
+			if tc.err == "" {
+				assert.Equal(t, tc.errs, errs)
+				require.NoError(t, ApplyErr(results))
+				return
+			}
+
+			assert.Error(t, errs[0])
+			assert.Equal(t, tc.errs[1:], errs[1:])
+			require.EqualError(t, ApplyErr(results), tc.err)
+		})
+	}
+}
+
+func TestApplierInvalidObject(t *testing.T) {
+	recorder := &applyRecorder{}
+	results := NewApplier(newApplyClientSets(recorder)).Apply(context.Background(),
+		&corev1.ServiceAccountApplyConfiguration{},
+		corev1.Service("prometheus", "default"),
+	)
+
+	// The object without kind fails the last stage, along with the others.
+	require.Len(t, results, 2)
+	require.Error(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	assert.Equal(t, []string{"typed services prometheus"}, recorder.applies)
+	require.EqualError(t, ApplyErr(results), "error while applying object: *v1.ServiceAccountApplyConfiguration has no kind or name")
+}
+
+func TestApplierClusterScopedObject(t *testing.T) {
+	var namespaces []string
+	dClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dClient.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		u := &unstructured.Unstructured{}
+		require.NoError(t, u.UnmarshalJSON(action.(clienttesting.PatchAction).GetPatch()))
+		namespaces = append(namespaces, action.GetNamespace()+"|"+u.GetNamespace())
+		return true, u, nil
+	})
+	clientSets := &ClientSets{KClient: kubefake.NewSimpleClientset(), DClient: dClient}
+
+	// The builders give their namespace to the cluster-scoped objects.
+	clusterRole := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "ClusterRole",
+		"metadata":   map[string]any{"name": "prometheus", "namespace": "default"},
+	}}
+	role := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "Role",
+		"metadata":   map[string]any{"name": "prometheus", "namespace": "default"},
+	}}
+
+	require.NoError(t, ApplyErr(NewApplier(clientSets).WithConcurrency(1).Apply(context.Background(), clusterRole, role)))
+	assert.Equal(t, []string{"|", "default|default"}, namespaces)
+	assert.Equal(t, "default", clusterRole.GetNamespace(), "the applied object is left unchanged")
+}
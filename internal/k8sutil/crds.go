@@ -0,0 +1,141 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	apiv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// UnknownKindError is returned when a document of a CRD stream is well
+// formed but isn't a v1 CustomResourceDefinition or a list of them.
+type UnknownKindError struct {
+	// Document is the position of the document in the stream, starting at 1.
+	Document int
+	GVK      schema.GroupVersionKind
+}
+
+func (e *UnknownKindError) Error() string {
+	return fmt.Sprintf("document %d: unsupported kind %q, expected a v1 CustomResourceDefinition or a list of them", e.Document, e.GVK.String())
+}
+
+// ParseError is returned when a document of a CRD stream isn't valid YAML or
+// JSON, or doesn't match the schema of its kind.
+type ParseError struct {
+	// Document is the position of the document in the stream, starting at 1.
+	Document int
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("document %d: %v", e.Document, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var crdCodecs = func() serializer.CodecFactory {
+	sch := runtime.NewScheme()
+	_ = corev1.AddToScheme(sch)
+	_ = apiv1.AddToScheme(sch)
+	return serializer.NewCodecFactory(sch)
+}()
+
+// DecodeCRDs decodes the v1 CustomResourceDefinitions of a YAML or JSON
+// stream. The stream can hold several documents, each being a
+// CustomResourceDefinition, a CustomResourceDefinitionList or a v1 List of
+// CustomResourceDefinitions, as found in the upstream bundles. The errors
+// are either an *UnknownKindError or a *ParseError.
+func DecodeCRDs(reader io.Reader) ([]*apiv1.CustomResourceDefinition, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(reader, 4096)
+
+	var crds []*apiv1.CustomResourceDefinition
+	for document := 1; ; document++ {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, &ParseError{Document: document, Err: err}
+		}
+
+		// Empty documents, such as a leading separator, are skipped.
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw.Raw), []byte("null")) {
+			continue
+		}
+
+		decoded, err := decodeCRDDocument(document, raw.Raw)
+		if err != nil {
+			return nil, err
+		}
+		crds = append(crds, decoded...)
+	}
+
+	if len(crds) == 0 {
+		return nil, &ParseError{Document: 1, Err: errors.New("no CustomResourceDefinition found")}
+	}
+
+	return crds, nil
+}
+
+func decodeCRDDocument(document int, data []byte) ([]*apiv1.CustomResourceDefinition, error) {
+	obj, gvk, err := crdCodecs.UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) && gvk != nil {
+			return nil, &UnknownKindError{Document: document, GVK: *gvk}
+		}
+		return nil, &ParseError{Document: document, Err: err}
+	}
+
+	crdKind := apiv1.SchemeGroupVersion.WithKind("CustomResourceDefinition")
+
+	switch o := obj.(type) {
+	case *apiv1.CustomResourceDefinition:
+		o.SetGroupVersionKind(crdKind)
+		return []*apiv1.CustomResourceDefinition{o}, nil
+
+	case *apiv1.CustomResourceDefinitionList:
+		crds := make([]*apiv1.CustomResourceDefinition, 0, len(o.Items))
+		for i := range o.Items {
+			o.Items[i].SetGroupVersionKind(crdKind)
+			crds = append(crds, &o.Items[i])
+		}
+		return crds, nil
+
+	case *corev1.List:
+		var crds []*apiv1.CustomResourceDefinition
+		for _, item := range o.Items {
+			decoded, err := decodeCRDDocument(document, item.Raw)
+			if err != nil {
+				return nil, err
+			}
+			crds = append(crds, decoded...)
+		}
+		return crds, nil
+
+	default:
+		return nil, &UnknownKindError{Document: document, GVK: *gvk}
+	}
+}
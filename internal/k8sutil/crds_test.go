@@ -0,0 +1,153 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const crdYAML = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: %s.monitoring.coreos.com
+spec:
+  group: monitoring.coreos.com
+  names:
+    kind: Kind
+    plural: %s
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+`
+
+func crd(plural string) string {
+	return strings.ReplaceAll(crdYAML, "%s", plural)
+}
+
+func TestDecodeCRDs(t *testing.T) {
+	type testCase struct {
+		name          string
+		data          string
+		expectedNames []string
+		unknownKind   bool
+		parseError    bool
+	}
+
+	tests := []testCase{
+		{
+			name:          "SingleDocument",
+			data:          crd("prometheuses"),
+			expectedNames: []string{"prometheuses.monitoring.coreos.com"},
+		},
+		{
+			name:          "MultiDocument",
+			data:          "---\n" + crd("prometheuses") + "---\n" + crd("alertmanagers") + "---\n",
+			expectedNames: []string{"prometheuses.monitoring.coreos.com", "alertmanagers.monitoring.coreos.com"},
+		},
+		{
+			name: "CRDList",
+			data: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinitionList
+items:
+- metadata:
+    name: probes.monitoring.coreos.com
+  spec:
+    group: monitoring.coreos.com
+    names:
+      kind: Probe
+      plural: probes
+    scope: Namespaced
+`,
+			expectedNames: []string{"probes.monitoring.coreos.com"},
+		},
+		{
+			name: "List",
+			data: `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": {"name": "probes.monitoring.coreos.com"}},
+    {"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": {"name": "scrapeconfigs.monitoring.coreos.com"}}
+  ]
+}`,
+			expectedNames: []string{"probes.monitoring.coreos.com", "scrapeconfigs.monitoring.coreos.com"},
+		},
+		{
+			name:          "JSONStream",
+			data:          `{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": {"name": "probes.monitoring.coreos.com"}}`,
+			expectedNames: []string{"probes.monitoring.coreos.com"},
+		},
+		{
+			name:        "RegisteredKind",
+			data:        crd("prometheuses") + "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+			unknownKind: true,
+		},
+		{
+			name:        "UnregisteredKind",
+			data:        "apiVersion: apiextensions.k8s.io/v1beta1\nkind: CustomResourceDefinition\nmetadata:\n  name: probes.monitoring.coreos.com\n",
+			unknownKind: true,
+		},
+		{
+			name:       "InvalidYAML",
+			data:       "apiVersion: apiextensions.k8s.io/v1\nkind: [CustomResourceDefinition\n",
+			parseError: true,
+		},
+		{
+			name:       "MissingKind",
+			data:       "metadata:\n  name: probes.monitoring.coreos.com\n",
+			parseError: true,
+		},
+		{
+			name:       "Empty",
+			data:       "---\n",
+			parseError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			crds, err := DecodeCRDs(strings.NewReader(tc.data))
+
+			var (
+				unknownKind *UnknownKindError
+				parseError  *ParseError
+			)
+			switch {
+			case tc.unknownKind:
+				require.True(t, errors.As(err, &unknownKind), "unexpected error %v", err)
+				return
+			case tc.parseError:
+				require.True(t, errors.As(err, &parseError), "unexpected error %v", err)
+				return
+			}
+
+			require.NoError(t, err)
+			var names []string
+			for _, crd := range crds {
+				assert.Equal(t, "CustomResourceDefinition", crd.Kind)
+				assert.Equal(t, "apiextensions.k8s.io/v1", crd.APIVersion)
+				names = append(names, crd.Name)
+			}
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
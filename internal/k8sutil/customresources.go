@@ -0,0 +1,217 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
+)
+
+// CRDResource is the resource of the CustomResourceDefinitions.
+var CRDResource = apiv1.SchemeGroupVersion.WithResource("customresourcedefinitions")
+
+// DefaultCRDBackoff is the backoff of the retries of a CRDClient, about 3s in
+// total.
+var DefaultCRDBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 200 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+}
+
+const (
+	// The default rate limit of the requests of a CRDClient, below the
+	// default limits of client-go so that the requests of the other clients
+	// aren't delayed.
+	defaultCRDQPS   = 5
+	defaultCRDBurst = 10
+)
+
+// CRDClient applies CustomResourceDefinitions and reads and rewrites their
+// custom resources with the dynamic client. The requests are rate limited
+// and the transient errors, such as conflicts, timeouts and throttling, are
+// retried with a backoff.
+type CRDClient struct {
+	client  dynamic.Interface
+	opts    metav1.ApplyOptions
+	backoff wait.Backoff
+	limiter flowcontrol.RateLimiter
+}
+
+// NewCRDClient returns a CRDClient applying with ApplyOption, retrying with
+// DefaultCRDBackoff.
+func NewCRDClient(clientSets *ClientSets) *CRDClient {
+	return &CRDClient{
+		client:  clientSets.DClient,
+		opts:    ApplyOption,
+		backoff: DefaultCRDBackoff,
+		limiter: flowcontrol.NewTokenBucketRateLimiter(defaultCRDQPS, defaultCRDBurst),
+	}
+}
+
+// WithBackoff sets the backoff of the retries, a single step disabling them.
+func (c *CRDClient) WithBackoff(backoff wait.Backoff) *CRDClient {
+	c.backoff = backoff
+	return c
+}
+
+// WithRateLimiter sets the rate limiter of the requests.
+func (c *CRDClient) WithRateLimiter(limiter flowcontrol.RateLimiter) *CRDClient {
+	c.limiter = limiter
+	return c
+}
+
+// ApplyCRD applies the CustomResourceDefinition.
+func (c *CRDClient) ApplyCRD(ctx context.Context, crd *apiv1.CustomResourceDefinition) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+	if err != nil {
+		return fmt.Errorf("error while converting CRD %s: %w", crd.Name, err)
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(apiv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+
+	err = c.retry(ctx, isTransient, func() error {
+		client := c.client.Resource(CRDResource)
+		if len(c.opts.DryRun) == 0 {
+			if err := migrateFieldManager(ctx, client, "CustomResourceDefinition", crd.Name, c.opts.FieldManager); err != nil {
+				return err
+			}
+		}
+
+		_, err := client.Apply(ctx, crd.Name, u, c.opts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error while applying CRD %s: %w", crd.Name, err)
+	}
+	return nil
+}
+
+// ListCRInstances calls fn on each custom resource of the CRD in the
+// namespace, all of them when it's empty, read in the storage version of
+// the CRD. The resources are listed page by page as EachListItem does.
+func (c *CRDClient) ListCRInstances(ctx context.Context, crd *apiv1.CustomResourceDefinition, namespace string, fn func(*unstructured.Unstructured) error) error {
+	version, err := StorageVersion(crd)
+	if err != nil {
+		return err
+	}
+
+	ri := c.client.Resource(CRResource(crd, version)).Namespace(namespace)
+	list := func(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+		var l *unstructured.UnstructuredList
+		err := c.retry(ctx, isTransient, func() error {
+			var err error
+			l, err = ri.List(ctx, opts)
+			return err
+		})
+		return l, err
+	}
+
+	return EachListItem(ctx, crd.Spec.Names.Plural, list, metav1.ListOptions{}, fn)
+}
+
+// UpdateCRAPIVersion rewrites the custom resource of the CRD in the given
+// version, so that the API server stores it again in the storage version of
+// the CRD. The custom resource is read again before each attempt, the
+// conflicts with the other writers being retried.
+func (c *CRDClient) UpdateCRAPIVersion(ctx context.Context, crd *apiv1.CustomResourceDefinition, namespace, name, version string) error {
+	if !servesVersion(crd, version) {
+		return fmt.Errorf("version %s not served by CRD %s", version, crd.Name)
+	}
+
+	ri := c.client.Resource(CRResource(crd, version)).Namespace(namespace)
+	err := c.retry(ctx, isConflictOrTransient, func() error {
+		obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		_, err = ri.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error while updating %s %s to %s: %w", crd.Spec.Names.Kind, name, version, err)
+	}
+	return nil
+}
+
+// retry calls fn once the rate limiter allows it, again as long as it fails
+// with a retriable error and the backoff isn't exhausted.
+func (c *CRDClient) retry(ctx context.Context, retriable func(error) bool, fn func() error) error {
+	return retry.OnError(c.backoff, retriable, func() error {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+// isTransient reports whether the request failing with err can succeed when
+// sent again. The conflicts of the applies are conflicts between field
+// managers, which persist.
+func isTransient(err error) bool {
+	return apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
+
+// isConflictOrTransient reports whether the update failing with err can
+// succeed with the object read again.
+func isConflictOrTransient(err error) bool {
+	return apierrors.IsConflict(err) || isTransient(err)
+}
+
+// CRResource returns the resource of the custom resources of the CRD in the
+// version.
+func CRResource(crd *apiv1.CustomResourceDefinition, version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: crd.Spec.Group, Version: version, Resource: crd.Spec.Names.Plural}
+}
+
+// StorageVersion returns the version in which the API server stores the
+// custom resources of the CRD.
+func StorageVersion(crd *apiv1.CustomResourceDefinition) (string, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name, nil
+		}
+	}
+	return "", fmt.Errorf("CRD %s has no storage version", crd.Name)
+}
+
+// servesVersion reports whether the API server serves the custom resources
+// of the CRD in the version.
+func servesVersion(crd *apiv1.CustomResourceDefinition, version string) bool {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version {
+			return v.Served
+		}
+	}
+	return false
+}
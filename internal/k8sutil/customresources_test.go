@@ -0,0 +1,179 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// noBackoff retries without waiting.
+var noBackoff = wait.Backoff{Steps: 3}
+
+var serviceMonitorsCRD = &apiv1.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{Name: "servicemonitors.monitoring.coreos.com"},
+	Spec: apiv1.CustomResourceDefinitionSpec{
+		Group: "monitoring.coreos.com",
+		Names: apiv1.CustomResourceDefinitionNames{Kind: "ServiceMonitor", ListKind: "ServiceMonitorList", Plural: "servicemonitors"},
+		Versions: []apiv1.CustomResourceDefinitionVersion{
+			{Name: "v1alpha1", Served: false},
+			{Name: "v1", Served: true, Storage: true},
+		},
+	},
+}
+
+func serviceMonitor(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "ServiceMonitor",
+		"metadata":   map[string]any{"name": name, "namespace": "monitoring"},
+	}}
+}
+
+func newCRDFakeClient(objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		CRResource(serviceMonitorsCRD, "v1"): "ServiceMonitorList",
+	}, objs...)
+}
+
+// failTimes returns a reaction failing the first n actions with err and
+// passing the next ones to the other reactors.
+func failTimes(n int, err error, calls *int) clienttesting.ReactionFunc {
+	return func(clienttesting.Action) (bool, runtime.Object, error) {
+		*calls++
+		if *calls <= n {
+			return true, nil, err
+		}
+		return false, nil, nil
+	}
+}
+
+func TestApplyCRD(t *testing.T) {
+	transient := apierrors.NewServerTimeout(CRDResource.GroupResource(), "patch", 1)
+	conflict := apierrors.NewConflict(CRDResource.GroupResource(), serviceMonitorsCRD.Name, errors.New("field managers conflict"))
+
+	for _, tc := range []struct {
+		name     string
+		failures int
+		err      error
+		calls    int
+		fails    bool
+	}{
+		{name: "Applied", calls: 1},
+		{name: "TransientErrorRetried", failures: 2, err: transient, calls: 3},
+		{name: "BackoffExhausted", failures: 3, err: transient, calls: 3, fails: true},
+		{name: "ConflictNotRetried", failures: 1, err: conflict, calls: 1, fails: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newCRDFakeClient()
+			var calls int
+			var applied *unstructured.Unstructured
+			client.PrependReactor("patch", "customresourcedefinitions", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				applied = &unstructured.Unstructured{}
+				require.NoError(t, applied.UnmarshalJSON(action.(clienttesting.PatchAction).GetPatch()))
+				return true, nil, nil
+			})
+			client.PrependReactor("patch", "customresourcedefinitions", failTimes(tc.failures, tc.err, &calls))
+
+			c := NewCRDClient(&ClientSets{DClient: client}).WithBackoff(noBackoff)
+			err := c.ApplyCRD(context.Background(), serviceMonitorsCRD)
+			assert.Equal(t, tc.calls, calls)
+			if tc.fails {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "CustomResourceDefinition", applied.GetKind())
+			assert.Equal(t, "apiextensions.k8s.io/v1", applied.GetAPIVersion())
+			assert.Equal(t, serviceMonitorsCRD.Name, applied.GetName())
+		})
+	}
+}
+
+// countingLimiter counts the requests it allows.
+type countingLimiter struct {
+	flowcontrol.RateLimiter
+	waits int
+}
+
+func (l *countingLimiter) Wait(context.Context) error {
+	l.waits++
+	return nil
+}
+
+func TestListCRInstances(t *testing.T) {
+	client := newCRDFakeClient(serviceMonitor("apiserver"), serviceMonitor("kubelet"))
+	var calls int
+	client.PrependReactor("list", "servicemonitors", failTimes(1, apierrors.NewTooManyRequests("throttled", 1), &calls))
+
+	limiter := &countingLimiter{}
+	c := NewCRDClient(&ClientSets{DClient: client}).WithBackoff(noBackoff).WithRateLimiter(limiter)
+
+	var names []string
+	err := c.ListCRInstances(context.Background(), serviceMonitorsCRD, "monitoring", func(obj *unstructured.Unstructured) error {
+		names = append(names, obj.GetName())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apiserver", "kubelet"}, names)
+	assert.Equal(t, 2, limiter.waits)
+
+	// The listing fails without storage version.
+	crd := serviceMonitorsCRD.DeepCopy()
+	crd.Spec.Versions[1].Storage = false
+	err = c.ListCRInstances(context.Background(), crd, "monitoring", func(*unstructured.Unstructured) error { return nil })
+	require.EqualError(t, err, "CRD servicemonitors.monitoring.coreos.com has no storage version")
+}
+
+func TestUpdateCRAPIVersion(t *testing.T) {
+	client := newCRDFakeClient(serviceMonitor("apiserver"))
+	var updates, gets int
+	client.PrependReactor("update", "servicemonitors", failTimes(1, apierrors.NewConflict(schema.GroupResource{Group: "monitoring.coreos.com", Resource: "servicemonitors"}, "apiserver", errors.New("object modified")), &updates))
+	client.PrependReactor("get", "servicemonitors", func(clienttesting.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+
+	c := NewCRDClient(&ClientSets{DClient: client}).WithBackoff(noBackoff)
+
+	// The conflict is retried with the object read again.
+	require.NoError(t, c.UpdateCRAPIVersion(context.Background(), serviceMonitorsCRD, "monitoring", "apiserver", "v1"))
+	assert.Equal(t, 2, updates)
+	assert.Equal(t, 2, gets)
+
+	err := c.UpdateCRAPIVersion(context.Background(), serviceMonitorsCRD, "monitoring", "apiserver", "v1alpha1")
+	require.EqualError(t, err, "version v1alpha1 not served by CRD servicemonitors.monitoring.coreos.com")
+
+	// A missing object isn't retried.
+	gets = 0
+	err = c.UpdateCRAPIVersion(context.Background(), serviceMonitorsCRD, "monitoring", "kubelet", "v1")
+	require.ErrorContains(t, err, "error while updating ServiceMonitor kubelet to v1")
+	assert.Equal(t, 1, gets)
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// MigrateFieldManager hands the fields of the object applied by the previous
+// versions of poctl over to the current field manager, so that the fields
+// dropped from the next apply are removed instead of staying owned by the
+// legacy field manager. The objects which don't exist or have no fields of
+// the legacy field manager are left as they are.
+func MigrateFieldManager(ctx context.Context, clientSets *ClientSets, gvk schema.GroupVersionKind, namespace, name string) error {
+	client := ResourceClient(clientSets, gvk, namespace)
+	return migrateFieldManager(ctx, client, gvk.Kind, name, ApplyOption.FieldManager)
+}
+
+// migrateFieldManager hands the fields of the object over to the manager.
+func migrateFieldManager(ctx context.Context, client dynamic.ResourceInterface, kind, name, manager string) error {
+	if manager == legacyFieldManager {
+		return nil
+	}
+
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error while getting %s %s: %w", kind, name, err)
+	}
+
+	managedFields, ok, err := migrateManagedFields(obj.GetManagedFields(), manager)
+	if err != nil || !ok {
+		return err
+	}
+
+	// The resource version guards against the changes made since the get,
+	// the next apply migrating the fields again.
+	patch, err := json.Marshal([]map[string]any{
+		{"op": "test", "path": "/metadata/resourceVersion", "value": obj.GetResourceVersion()},
+		{"op": "replace", "path": "/metadata/managedFields", "value": managedFields},
+	})
+	if err != nil {
+		return fmt.Errorf("error while marshaling the managed fields of %s %s: %w", kind, name, err)
+	}
+
+	if _, err := client.Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error while migrating the managed fields of %s %s: %w", kind, name, err)
+	}
+	return nil
+}
+
+// migrateManagedFields returns the managed fields with the fields applied by
+// the legacy field manager owned by the manager, merged with the fields it
+// already applied, and whether they changed.
+func migrateManagedFields(entries []metav1.ManagedFieldsEntry, manager string) ([]metav1.ManagedFieldsEntry, bool, error) {
+	legacy, current := -1, -1
+	for i, e := range entries {
+		if e.Operation != metav1.ManagedFieldsOperationApply {
+			continue
+		}
+		switch e.Manager {
+		case legacyFieldManager:
+			legacy = i
+		case manager:
+			current = i
+		}
+	}
+	if legacy == -1 {
+		return entries, false, nil
+	}
+
+	if current == -1 {
+		entries = append([]metav1.ManagedFieldsEntry(nil), entries...)
+		entries[legacy].Manager = manager
+		return entries, true, nil
+	}
+
+	fields, err := unionFields(entries[current].FieldsV1, entries[legacy].FieldsV1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var migrated []metav1.ManagedFieldsEntry
+	for i, e := range entries {
+		switch i {
+		case legacy:
+			continue
+		case current:
+			e.FieldsV1 = fields
+		}
+		migrated = append(migrated, e)
+	}
+	return migrated, true, nil
+}
+
+// unionFields returns the fields owned by either of the field sets.
+func unionFields(a, b *metav1.FieldsV1) (*metav1.FieldsV1, error) {
+	union := &fieldpath.Set{}
+	for _, fields := range []*metav1.FieldsV1{a, b} {
+		if fields == nil {
+			continue
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(fields.Raw)); err != nil {
+			return nil, fmt.Errorf("error while reading the managed fields: %w", err)
+		}
+		union = union.Union(set)
+	}
+
+	raw, err := union.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("error while writing the managed fields: %w", err)
+	}
+	return &metav1.FieldsV1{Raw: raw}, nil
+}
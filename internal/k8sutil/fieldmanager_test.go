@@ -0,0 +1,136 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func managedFieldsEntry(manager string, operation metav1.ManagedFieldsOperationType, fields string) metav1.ManagedFieldsEntry {
+	return metav1.ManagedFieldsEntry{
+		Manager:    manager,
+		Operation:  operation,
+		APIVersion: "v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+	}
+}
+
+func TestMigrateManagedFields(t *testing.T) {
+	const (
+		labelA = `{"f:metadata":{"f:labels":{"f:a":{}}}}`
+		labelB = `{"f:metadata":{"f:labels":{"f:b":{}}}}`
+		labels = `{"f:metadata":{"f:labels":{"f:a":{},"f:b":{}}}}`
+	)
+
+	tests := []struct {
+		name     string
+		entries  []metav1.ManagedFieldsEntry
+		expected []metav1.ManagedFieldsEntry
+		migrated bool
+	}{
+		{
+			name: "no legacy field manager",
+			entries: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry("poctl-create", metav1.ManagedFieldsOperationApply, labelA),
+			},
+			expected: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry("poctl-create", metav1.ManagedFieldsOperationApply, labelA),
+			},
+		},
+		{
+			name: "legacy field manager renamed",
+			entries: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry("kubectl", metav1.ManagedFieldsOperationUpdate, labelB),
+				managedFieldsEntry(legacyFieldManager, metav1.ManagedFieldsOperationApply, labelA),
+			},
+			expected: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry("kubectl", metav1.ManagedFieldsOperationUpdate, labelB),
+				managedFieldsEntry("poctl-create", metav1.ManagedFieldsOperationApply, labelA),
+			},
+			migrated: true,
+		},
+		{
+			name: "legacy field manager merged",
+			entries: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry(legacyFieldManager, metav1.ManagedFieldsOperationApply, labelA),
+				managedFieldsEntry("poctl-create", metav1.ManagedFieldsOperationApply, labelB),
+			},
+			expected: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry("poctl-create", metav1.ManagedFieldsOperationApply, labels),
+			},
+			migrated: true,
+		},
+		{
+			name: "legacy field manager updating",
+			entries: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry(legacyFieldManager, metav1.ManagedFieldsOperationUpdate, labelA),
+			},
+			expected: []metav1.ManagedFieldsEntry{
+				managedFieldsEntry(legacyFieldManager, metav1.ManagedFieldsOperationUpdate, labelA),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, migrated, err := migrateManagedFields(tc.entries, "poctl-create")
+			require.NoError(t, err)
+			assert.Equal(t, tc.migrated, migrated)
+			require.Len(t, entries, len(tc.expected))
+			for i, e := range tc.expected {
+				assert.Equal(t, e.Manager, entries[i].Manager)
+				assert.Equal(t, e.Operation, entries[i].Operation)
+				assert.JSONEq(t, string(e.FieldsV1.Raw), string(entries[i].FieldsV1.Raw))
+			}
+		})
+	}
+}
+
+func TestMigrateFieldManager(t *testing.T) {
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace("default")
+	cm.SetName("poctl-stack")
+	cm.SetResourceVersion("1")
+	cm.SetManagedFields([]metav1.ManagedFieldsEntry{
+		managedFieldsEntry(legacyFieldManager, metav1.ManagedFieldsOperationApply, `{"f:data":{"f:parameters":{}}}`),
+	})
+
+	defer UseFieldManager("")
+	UseFieldManager("poctl-create")
+
+	dClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), cm)
+	clientSets := &ClientSets{DClient: dClient}
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	require.NoError(t, MigrateFieldManager(context.Background(), clientSets, gvk, "default", "poctl-stack"))
+	require.NoError(t, MigrateFieldManager(context.Background(), clientSets, gvk, "default", "missing"))
+
+	obj, err := dClient.Resource(ResourceFor(gvk)).Namespace("default").Get(context.Background(), "poctl-stack", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, obj.GetManagedFields(), 1)
+	assert.Equal(t, "poctl-create", obj.GetManagedFields()[0].Manager)
+}
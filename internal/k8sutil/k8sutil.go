@@ -15,30 +15,25 @@
 package k8sutil
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"fmt"
-	"io"
-	"log/slog"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
 
+	"github.com/prometheus-operator/poctl/internal/messages"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
-	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
+	flag "github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/rbac/v1"
-	apiv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apiExtensions "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -51,8 +46,43 @@ const (
 	PrometheusRule = "PrometheusRule"
 )
 
+const (
+	// FieldManager is the field manager of the objects written by poctl,
+	// the commands writing objects using it as the prefix of their own.
+	FieldManager = "poctl"
+
+	// legacyFieldManager is the field manager of the objects applied by the
+	// previous versions of poctl.
+	legacyFieldManager = "application/apply-patch"
+)
+
 var ApplyOption = metav1.ApplyOptions{
-	FieldManager: "application/apply-patch",
+	FieldManager: FieldManager,
+}
+
+// fieldManager is the field manager given with --field-manager.
+var fieldManager string
+
+// RegisterFlags registers the flags tuning the requests to the cluster.
+func RegisterFlags(flagSet *flag.FlagSet) {
+	flagSet.Int64Var(&ChunkSize, "chunk-size", DefaultChunkSize, "Number of objects requested per page when listing the whole cluster, 0 to list all the objects at once")
+	flagSet.StringVar(&fieldManager, "field-manager", "", "Field manager of the objects written by the command, defaults to poctl-<command>")
+}
+
+// UseFieldManager sets the field manager of the objects written by the
+// command, the one given with --field-manager taking precedence.
+func UseFieldManager(manager string) {
+	ApplyOption.FieldManager = cmp.Or(fieldManager, manager, FieldManager)
+}
+
+// IsFieldManager reports whether the objects written by the field manager
+// were written by poctl: the current field manager, the ones of the commands
+// or the one of the previous versions.
+func IsFieldManager(manager string) bool {
+	return manager == ApplyOption.FieldManager ||
+		manager == FieldManager ||
+		strings.HasPrefix(manager, FieldManager+"-") ||
+		manager == legacyFieldManager
 }
 
 func getKubeConfig() (string, error) {
@@ -76,43 +106,47 @@ func GetRestConfig(kubeConfig string) (*rest.Config, error) {
 	if kubeConfig == "" {
 		kubeConfig, err = getKubeConfig()
 		if err != nil {
-			return nil, fmt.Errorf("error while getting kubeconfig: %v", err)
+			// Commands running in a pod, such as the verifier, rely on
+			// the ServiceAccount of the pod.
+			if config, inClusterErr := rest.InClusterConfig(); inClusterErr == nil {
+				return config, nil
+			}
+			return nil, fmt.Errorf("error while getting kubeconfig: %w", err)
 		}
 	}
 
 	config, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
 	if err != nil {
-		return nil, fmt.Errorf("error while creating k8s client config: %v", err)
+		return nil, fmt.Errorf("error while creating k8s client config: %w", err)
 	}
 
 	return config, nil
 }
 
-func CrdDeserilezer(logger *slog.Logger, reader io.ReadCloser) (runtime.Object, error) {
-	sch := runtime.NewScheme()
-	_ = clientgoscheme.AddToScheme(sch)
-	_ = apiextv1beta1.AddToScheme(sch)
-	_ = apiv1.AddToScheme(sch)
-
-	_ = monitoringv1.AddToScheme(sch)
-	_ = monitoringv1alpha1.AddToScheme(sch)
-
-	buf := new(bytes.Buffer)
-	_, err := buf.ReadFrom(reader)
-	if err != nil {
-		logger.Error("error while reading CRD", "error", err)
-		return &runtime.Unknown{}, err
+// GetRestConfigForContext returns the configuration of the given context of
+// the kubeconfig file, the current context being used when empty.
+func GetRestConfigForContext(kubeConfig, kubeContext string) (*rest.Config, error) {
+	if kubeContext == "" {
+		return GetRestConfig(kubeConfig)
 	}
 
-	decode := serializer.NewCodecFactory(sch).UniversalDeserializer().Decode
+	if kubeConfig == "" {
+		var err error
+		kubeConfig, err = getKubeConfig()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting kubeconfig: %w", err)
+		}
+	}
 
-	obj, _, err := decode(buf.Bytes(), nil, nil)
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
 	if err != nil {
-		logger.Error("error while decoding CRD", "error", err)
-		return &runtime.Unknown{}, err
+		return nil, fmt.Errorf("error while creating k8s client config for context %s: %w", kubeContext, err)
 	}
 
-	return obj, nil
+	return config, nil
 }
 
 type ClientSets struct {
@@ -123,30 +157,36 @@ type ClientSets struct {
 }
 
 func GetClientSets(kubeconfig string) (*ClientSets, error) {
-	restConfig, err := GetRestConfig(kubeconfig)
+	return GetClientSetsForContext(kubeconfig, "")
+}
+
+// GetClientSetsForContext returns the clients of the given context of the
+// kubeconfig file, the current context being used when empty.
+func GetClientSetsForContext(kubeconfig, kubeContext string) (*ClientSets, error) {
+	restConfig, err := GetRestConfigForContext(kubeconfig, kubeContext)
 	if err != nil {
-		return nil, fmt.Errorf("error while getting k8s client config: %v", err)
+		return nil, fmt.Errorf("error while getting k8s client config: %w", err)
 
 	}
 
 	kclient, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
-		return nil, fmt.Errorf("error while creating k8s client: %v", err)
+		return nil, fmt.Errorf("error while creating k8s client: %w", err)
 	}
 
 	mclient, err := monitoringclient.NewForConfig(restConfig)
 	if err != nil {
-		return nil, fmt.Errorf("error while creating Prometheus Operator client: %v", err)
+		return nil, fmt.Errorf("error while creating Prometheus Operator client: %w", err)
 	}
 
 	kdynamicClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
-		return nil, fmt.Errorf("error while creating dynamic client: %v", err)
+		return nil, fmt.Errorf("error while creating dynamic client: %w", err)
 	}
 
 	apiExtensions, err := apiExtensions.NewForConfig(restConfig)
 	if err != nil {
-		return nil, fmt.Errorf("error while creating apiextensions client: %v", err)
+		return nil, fmt.Errorf("error while creating apiextensions client: %w", err)
 	}
 
 	return &ClientSets{
@@ -179,26 +219,30 @@ func CheckResourceNamespaceSelectors(ctx context.Context, clientSets ClientSets,
 		return nil
 	}
 
-	labelMap, err := metav1.LabelSelectorAsMap(labelSelector)
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
 	if err != nil {
-		return fmt.Errorf("invalid label selector format in %s: %v", labelSelector, err)
+		return fmt.Errorf("invalid label selector format in %s: %w", labelSelector, err)
 	}
 
-	namespaces, err := clientSets.KClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
-
+	// The namespaces are filtered locally to explain the near misses when
+	// none matches.
+	namespaces, err := clientSets.KClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to list Namespaces in %s: %v", labelSelector, err)
+		return fmt.Errorf("failed to list Namespaces in %s: %w", labelSelector, err)
 	}
 
-	if len(namespaces.Items) == 0 {
-		return fmt.Errorf("no namespaces match the selector %s", labelSelector)
+	for _, ns := range namespaces.Items {
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return nil
+		}
 	}
-	return nil
+
+	return messages.New(messages.NoNamespacesMatchSelector, selector, explainNamespaceMisses(selector, namespaces.Items))
 }
 
 func CheckResourceLabelSelectors(ctx context.Context, clientSets ClientSets, labelSelector *metav1.LabelSelector, resourceName, namespace string) error {
 	if labelSelector == nil {
-		return fmt.Errorf("%s selector is not defined", resourceName)
+		return messages.New(messages.SelectorNotDefined, resourceName)
 	}
 
 	if len(labelSelector.MatchLabels) == 0 && len(labelSelector.MatchExpressions) == 0 {
@@ -207,49 +251,49 @@ func CheckResourceLabelSelectors(ctx context.Context, clientSets ClientSets, lab
 
 	labelMap, err := metav1.LabelSelectorAsMap(labelSelector)
 	if err != nil {
-		return fmt.Errorf("invalid label selector format in %s: %v", resourceName, err)
+		return fmt.Errorf("invalid label selector format in %s: %w", resourceName, err)
 	}
 
 	switch resourceName {
 	case ServiceMonitor:
 		serviceMonitors, err := clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
 		if err != nil {
-			return fmt.Errorf("failed to list ServiceMonitors in %s: %v", namespace, err)
+			return fmt.Errorf("failed to list ServiceMonitors in %s: %w", namespace, err)
 		}
 		if len(serviceMonitors.Items) == 0 {
-			return fmt.Errorf("no ServiceMonitors match the provided selector in Prometheus %s", namespace)
+			return messages.New(messages.NoResourcesMatchSelector, "ServiceMonitors", namespace)
 		}
 	case PodMonitor:
 		podMonitors, err := clientSets.MClient.MonitoringV1().PodMonitors(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
 		if err != nil {
-			return fmt.Errorf("failed to list PodMonitor in %s: %v", namespace, err)
+			return fmt.Errorf("failed to list PodMonitor in %s: %w", namespace, err)
 		}
 		if len(podMonitors.Items) == 0 {
-			return fmt.Errorf("no PodMonitors match the provided selector in Prometheus %s", namespace)
+			return messages.New(messages.NoResourcesMatchSelector, "PodMonitors", namespace)
 		}
 	case Probe:
 		probes, err := clientSets.MClient.MonitoringV1().Probes(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
 		if err != nil {
-			return fmt.Errorf("failed to list Probes in %s: %v", namespace, err)
+			return fmt.Errorf("failed to list Probes in %s: %w", namespace, err)
 		}
 		if len(probes.Items) == 0 {
-			return fmt.Errorf("no Probes match the provided selector in Prometheus %s", namespace)
+			return messages.New(messages.NoResourcesMatchSelector, "Probes", namespace)
 		}
 	case ScrapeConfig:
 		scrapeConfigs, err := clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
 		if err != nil {
-			return fmt.Errorf("failed to list ScrapeConfigs in %s: %v", namespace, err)
+			return fmt.Errorf("failed to list ScrapeConfigs in %s: %w", namespace, err)
 		}
 		if len(scrapeConfigs.Items) == 0 {
-			return fmt.Errorf("no ScrapeConfigs match the provided selector in Prometheus %s", namespace)
+			return messages.New(messages.NoResourcesMatchSelector, "ScrapeConfigs", namespace)
 		}
 	case PrometheusRule:
 		promRules, err := clientSets.MClient.MonitoringV1().PrometheusRules(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(labelMap).String()})
 		if err != nil {
-			return fmt.Errorf("failed to list Probes in %s: %v", namespace, err)
+			return fmt.Errorf("failed to list Probes in %s: %w", namespace, err)
 		}
 		if len(promRules.Items) == 0 {
-			return fmt.Errorf("no PrometheusRules match the provided selector in Prometheus %s", namespace)
+			return messages.New(messages.NoResourcesMatchSelector, "PrometheusRules", namespace)
 		}
 	default:
 		return fmt.Errorf("unknown selector type: %s", resourceName)
@@ -258,6 +302,52 @@ func CheckResourceLabelSelectors(ctx context.Context, clientSets ClientSets, lab
 	return nil
 }
 
+// CheckSecretKeySelector verifies that the Secret referenced by the selector
+// exists in the namespace and contains the expected key.
+func CheckSecretKeySelector(ctx context.Context, clientSets ClientSets, namespace string, selector *corev1.SecretKeySelector) error {
+	if selector == nil {
+		return nil
+	}
+
+	secret, err := clientSets.KClient.CoreV1().Secrets(namespace).Get(ctx, selector.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get Secret %s in namespace %s: %w", selector.Name, namespace, err)
+	}
+
+	if _, ok := secret.Data[selector.Key]; !ok {
+		if _, ok := secret.StringData[selector.Key]; !ok {
+			return messages.New(messages.SecretKeyNotFound, selector.Key, selector.Name, namespace)
+		}
+	}
+
+	return nil
+}
+
+// CheckSecretOrConfigMap verifies that the Secret or ConfigMap referenced by
+// ref exists in the namespace and contains the expected key.
+func CheckSecretOrConfigMap(ctx context.Context, clientSets ClientSets, namespace string, ref monitoringv1.SecretOrConfigMap) error {
+	if ref.Secret != nil {
+		return CheckSecretKeySelector(ctx, clientSets, namespace, ref.Secret)
+	}
+
+	if ref.ConfigMap == nil {
+		return nil
+	}
+
+	cm, err := clientSets.KClient.CoreV1().ConfigMaps(namespace).Get(ctx, ref.ConfigMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap %s in namespace %s: %w", ref.ConfigMap.Name, namespace, err)
+	}
+
+	if _, ok := cm.Data[ref.ConfigMap.Key]; !ok {
+		if _, ok := cm.BinaryData[ref.ConfigMap.Key]; !ok {
+			return messages.New(messages.ConfigMapKeyNotFound, ref.ConfigMap.Key, ref.ConfigMap.Name, namespace)
+		}
+	}
+
+	return nil
+}
+
 func CheckPrometheusClusterRoleRules(crb v1.ClusterRoleBinding, cr *v1.ClusterRole) error {
 	var errs []string
 	verbsToCheck := []string{"get", "list", "watch"}
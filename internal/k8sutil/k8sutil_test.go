@@ -0,0 +1,61 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseFieldManager(t *testing.T) {
+	t.Cleanup(func() {
+		fieldManager = ""
+		ApplyOption.FieldManager = FieldManager
+	})
+
+	UseFieldManager("")
+	assert.Equal(t, "poctl", ApplyOption.FieldManager)
+
+	UseFieldManager("poctl-create")
+	assert.Equal(t, "poctl-create", ApplyOption.FieldManager)
+
+	// The field manager given with --field-manager takes precedence.
+	fieldManager = "gitops-controller"
+	UseFieldManager("poctl-create")
+	assert.Equal(t, "gitops-controller", ApplyOption.FieldManager)
+}
+
+func TestIsFieldManager(t *testing.T) {
+	t.Cleanup(func() {
+		fieldManager = ""
+		ApplyOption.FieldManager = FieldManager
+	})
+
+	fieldManager = "gitops-controller"
+	UseFieldManager("poctl-create")
+
+	for manager, expected := range map[string]bool{
+		"gitops-controller":       true,
+		"poctl":                   true,
+		"poctl-create":            true,
+		"poctl-configure":         true,
+		"application/apply-patch": true,
+		"kubectl-edit":            false,
+		"poctlx":                  false,
+	} {
+		assert.Equal(t, expected, IsFieldManager(manager), manager)
+	}
+}
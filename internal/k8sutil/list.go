@@ -0,0 +1,72 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/pager"
+)
+
+// DefaultChunkSize is the default number of objects requested per page by
+// the listings of the whole cluster.
+const DefaultChunkSize = 500
+
+// ChunkSize is the number of objects requested per page by EachListItem, 0
+// requesting all the objects at once.
+var ChunkSize int64 = DefaultChunkSize
+
+// EachListItem lists the objects with the list function of a typed client,
+// such as ServiceMonitors(namespace).List, page by page of ChunkSize objects,
+// and calls fn on each object as the pages are received, so that the
+// objects of the whole cluster never need to be held in memory at once. The
+// errors of fn are returned unchanged, the errors of the listing are
+// wrapped with the name of the resource.
+func EachListItem[T any, L runtime.Object](ctx context.Context, resource string, list func(context.Context, metav1.ListOptions) (L, error), opts metav1.ListOptions, fn func(*T) error) error {
+	return eachListItem(ctx, resource, pageFunc(list), opts, func(obj runtime.Object) error {
+		item, ok := any(obj).(*T)
+		if !ok {
+			return fmt.Errorf("unexpected %T item while listing %s", obj, resource)
+		}
+		return fn(item)
+	})
+}
+
+// pageFunc adapts the list function of a typed client to the pager.
+func pageFunc[L runtime.Object](list func(context.Context, metav1.ListOptions) (L, error)) pager.ListPageFunc {
+	return func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		obj, err := list(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+}
+
+func eachListItem(ctx context.Context, resource string, list pager.ListPageFunc, opts metav1.ListOptions, fn func(runtime.Object) error) error {
+	p := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		obj, err := list(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error while listing %s: %w", resource, err)
+		}
+		return obj, nil
+	})
+	p.PageSize = max(ChunkSize, 0)
+
+	return p.EachListItem(ctx, opts, fn)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// pagedNamespaces returns a list function serving n Namespaces page by
+// page, as the API server does with the limit and continue options, and
+// recording the limits of the requests.
+func pagedNamespaces(n int, limits *[]int64) func(context.Context, metav1.ListOptions) (*corev1.NamespaceList, error) {
+	return func(_ context.Context, opts metav1.ListOptions) (*corev1.NamespaceList, error) {
+		*limits = append(*limits, opts.Limit)
+
+		start := 0
+		if opts.Continue != "" {
+			start, _ = strconv.Atoi(opts.Continue)
+		}
+		end := n
+		if opts.Limit > 0 {
+			end = min(start+int(opts.Limit), n)
+		}
+
+		list := &corev1.NamespaceList{}
+		for i := start; i < end; i++ {
+			list.Items = append(list.Items, corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("ns-%d", i)}})
+		}
+		if end < n {
+			list.Continue = strconv.Itoa(end)
+		}
+		return list, nil
+	}
+}
+
+func TestEachListItem(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		chunkSize int64
+		limits    []int64
+	}{
+		{
+			name:      "chunks",
+			chunkSize: 2,
+			limits:    []int64{2, 2, 2},
+		},
+		{
+			name:      "single request",
+			chunkSize: 0,
+			limits:    []int64{0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setChunkSize(t, tc.chunkSize)
+
+			var limits []int64
+			var names []string
+			err := EachListItem(context.Background(), "Namespaces", pagedNamespaces(5, &limits), metav1.ListOptions{}, func(ns *corev1.Namespace) error {
+				names = append(names, ns.Name)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"ns-0", "ns-1", "ns-2", "ns-3", "ns-4"}, names)
+			assert.Equal(t, tc.limits, limits)
+		})
+	}
+}
+
+func TestEachListItemErrors(t *testing.T) {
+	setChunkSize(t, 2)
+
+	// The errors of the callback stop the listing and are returned unchanged.
+	var limits []int64
+	errStop := errors.New("stop")
+	err := EachListItem(context.Background(), "Namespaces", pagedNamespaces(5, &limits), metav1.ListOptions{}, func(*corev1.Namespace) error {
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+
+	// The errors of the listing are wrapped with the resource.
+	client := kubefake.NewSimpleClientset()
+	client.PrependReactor("list", "namespaces", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "")
+	})
+	err = EachListItem(context.Background(), "Namespaces", client.CoreV1().Namespaces().List, metav1.ListOptions{}, func(*corev1.Namespace) error {
+		return nil
+	})
+	require.ErrorContains(t, err, "error while listing Namespaces")
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func setChunkSize(t *testing.T, chunkSize int64) {
+	previous := ChunkSize
+	ChunkSize = chunkSize
+	t.Cleanup(func() { ChunkSize = previous })
+}
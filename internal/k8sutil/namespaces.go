@@ -0,0 +1,128 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// maxNearMisses is the maximum number of near-miss namespaces explained.
+const maxNearMisses = 5
+
+// nearMiss is a namespace which doesn't match a selector but meets some of
+// its requirements, or carries the label of an unmet requirement.
+type nearMiss struct {
+	namespace corev1.Namespace
+	unmet     []labels.Requirement
+}
+
+// explainNamespaceMisses returns the explanation of why no namespace matches
+// the selector: the near-miss namespaces with their labels and the labels to
+// change on them. It lists the labels of the selector which no namespace
+// carries when there is no near miss.
+func explainNamespaceMisses(selector labels.Selector, namespaces []corev1.Namespace) string {
+	requirements, _ := selector.Requirements()
+	if len(requirements) == 0 {
+		return ""
+	}
+
+	var misses []nearMiss
+	for _, ns := range namespaces {
+		var (
+			unmet []labels.Requirement
+			near  bool
+		)
+		for _, r := range requirements {
+			if r.Matches(labels.Set(ns.Labels)) {
+				// Lacking a forbidden label doesn't bring a namespace closer
+				// to the selector.
+				near = near || requiresLabel(r)
+				continue
+			}
+
+			unmet = append(unmet, r)
+			if _, found := ns.Labels[r.Key()]; found {
+				near = true
+			}
+		}
+
+		if near {
+			misses = append(misses, nearMiss{namespace: ns, unmet: unmet})
+		}
+	}
+
+	if len(misses) == 0 {
+		var keys []string
+		for _, r := range requirements {
+			if requiresLabel(r) {
+				keys = append(keys, r.Key())
+			}
+		}
+		if len(keys) == 0 {
+			return ""
+		}
+		return fmt.Sprintf(", no namespace has the label %s", strings.Join(keys, ", "))
+	}
+
+	slices.SortStableFunc(misses, func(a, b nearMiss) int {
+		return cmp.Or(cmp.Compare(len(a.unmet), len(b.unmet)), cmp.Compare(a.namespace.Name, b.namespace.Name))
+	})
+
+	var explanations []string
+	for _, miss := range misses[:min(len(misses), maxNearMisses)] {
+		var fixes []string
+		for _, r := range miss.unmet {
+			fixes = append(fixes, requirementFix(r))
+		}
+
+		explanations = append(explanations, fmt.Sprintf("namespace %s has labels {%s}, %s", miss.namespace.Name, labels.Set(miss.namespace.Labels), strings.Join(fixes, " and ")))
+	}
+
+	return fmt.Sprintf(", near misses: %s", strings.Join(explanations, "; "))
+}
+
+// requiresLabel reports whether the requirement is only met by namespaces
+// carrying its label.
+func requiresLabel(r labels.Requirement) bool {
+	switch r.Operator() {
+	case selection.DoesNotExist, selection.NotEquals, selection.NotIn:
+		return false
+	default:
+		return true
+	}
+}
+
+// requirementFix returns the label change meeting an unmet requirement.
+func requirementFix(r labels.Requirement) string {
+	switch r.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		return fmt.Sprintf("add the label %s=%s", r.Key(), r.Values().List()[0])
+	case selection.Exists:
+		return fmt.Sprintf("add the label %s", r.Key())
+	case selection.DoesNotExist:
+		return fmt.Sprintf("remove the label %s", r.Key())
+	case selection.NotEquals, selection.NotIn:
+		return fmt.Sprintf("set the label %s to a value other than %s", r.Key(), strings.Join(r.Values().List(), ", "))
+	default:
+		return fmt.Sprintf("update the label %s to match %s", r.Key(), r.String())
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckResourceNamespaceSelectors(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a", "monitoring": "enable"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+	}
+
+	type testCase struct {
+		name          string
+		selector      *metav1.LabelSelector
+		expectedError string
+	}
+
+	tests := []testCase{
+		{
+			name:     "Match",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}},
+		},
+		{
+			name: "MatchExpression",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "c"}},
+			}},
+		},
+		{
+			name:          "WrongValue",
+			selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a", "monitoring": "enabled"}},
+			expectedError: "no namespaces match the selector monitoring=enabled,team=a, near misses: namespace team-a has labels {monitoring=enable,team=a}, add the label monitoring=enabled; namespace team-b has labels {team=b}, add the label monitoring=enabled and add the label team=a",
+		},
+		{
+			name: "ForbiddenLabel",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"b"}},
+				{Key: "team", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"b"}},
+			}},
+			expectedError: "no namespaces match the selector team in (b),team notin (b), near misses: namespace team-a has labels {monitoring=enable,team=a}, add the label team=b; namespace team-b has labels {team=b}, set the label team to a value other than b",
+		},
+		{
+			name:          "NoNearMiss",
+			selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "prod"}},
+			expectedError: "no namespaces match the selector environment=prod, no namespace has the label environment",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kClient := fake.NewSimpleClientset()
+			for i := range namespaces {
+				_, err := kClient.CoreV1().Namespaces().Create(context.Background(), &namespaces[i], metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			err := CheckResourceNamespaceSelectors(context.Background(), ClientSets{KClient: kClient}, tc.selector)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,776 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringinformers "github.com/prometheus-operator/prometheus-operator/pkg/client/informers/externalversions"
+	monitoringv1listers "github.com/prometheus-operator/prometheus-operator/pkg/client/listers/monitoring/v1"
+	monitoringv1alpha1listers "github.com/prometheus-operator/prometheus-operator/pkg/client/listers/monitoring/v1alpha1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
+	monitoringv1client "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/typed/monitoring/v1"
+	monitoringv1alpha1client "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/typed/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	rbacv1client "k8s.io/client-go/kubernetes/typed/rbac/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewSnapshot fills an informer cache with the monitoring.coreos.com objects,
+// the Namespaces, the Services, the ServiceAccounts and the RBAC objects of
+// the whole cluster, and returns ClientSets serving their gets and lists
+// from it, so that analyzing many objects costs a request per resource
+// instead of a request per check and object. The informers are stopped once
+// synced: the snapshot doesn't see the later changes, including the writes
+// of the ClientSets. The other requests, the lists with a field selector and
+// all the writes are sent to the cluster unchanged, as are the requests of
+// the monitoring.coreos.com resources which discovery doesn't report as
+// served, such as those of a CRD which isn't installed. The Secrets aren't
+// part of the snapshot: the analyzers read the data of a few of them, which
+// shouldn't be held in memory for the whole cluster.
+func NewSnapshot(ctx context.Context, clientSets *ClientSets) (*ClientSets, error) {
+	kFactory := informers.NewSharedInformerFactory(clientSets.KClient, 0)
+	mFactory := monitoringinformers.NewSharedInformerFactory(clientSets.MClient, 0)
+
+	core := kFactory.Core().V1()
+	rbac := kFactory.Rbac().V1()
+	v1 := mFactory.Monitoring().V1()
+	v1alpha1 := mFactory.Monitoring().V1alpha1()
+
+	kClient := &snapshotKubeClient{
+		Interface:           clientSets.KClient,
+		namespaces:          core.Namespaces().Lister(),
+		services:            core.Services().Lister(),
+		serviceAccounts:     core.ServiceAccounts().Lister(),
+		roles:               rbac.Roles().Lister(),
+		roleBindings:        rbac.RoleBindings().Lister(),
+		clusterRoles:        rbac.ClusterRoles().Lister(),
+		clusterRoleBindings: rbac.ClusterRoleBindings().Lister(),
+	}
+
+	informers := []cache.SharedIndexInformer{
+		core.Namespaces().Informer(), core.Services().Informer(), core.ServiceAccounts().Informer(),
+		rbac.Roles().Informer(), rbac.RoleBindings().Informer(), rbac.ClusterRoles().Informer(), rbac.ClusterRoleBindings().Informer(),
+	}
+
+	// The informers of the resources whose CRD isn't installed would fail
+	// to list them, their requests are sent to the cluster instead.
+	served, err := servedResources(clientSets.MClient.Discovery(), monitoringv1.SchemeGroupVersion, monitoringv1alpha1.SchemeGroupVersion)
+	if err != nil {
+		return nil, err
+	}
+	snapshotted := func(resource schema.GroupVersionResource, informer informerGetter) bool {
+		if !served[resource] {
+			return false
+		}
+		informers = append(informers, informer.Informer())
+		return true
+	}
+
+	mClient := &snapshotMonitoringClient{Interface: clientSets.MClient}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.PrometheusName), v1.Prometheuses()) {
+		mClient.prometheuses = v1.Prometheuses().Lister()
+	}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.AlertmanagerName), v1.Alertmanagers()) {
+		mClient.alertmanagers = v1.Alertmanagers().Lister()
+	}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.ThanosRulerName), v1.ThanosRulers()) {
+		mClient.thanosRulers = v1.ThanosRulers().Lister()
+	}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.ServiceMonitorName), v1.ServiceMonitors()) {
+		mClient.serviceMonitors = v1.ServiceMonitors().Lister()
+	}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.PodMonitorName), v1.PodMonitors()) {
+		mClient.podMonitors = v1.PodMonitors().Lister()
+	}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.ProbeName), v1.Probes()) {
+		mClient.probes = v1.Probes().Lister()
+	}
+	if snapshotted(monitoringv1.SchemeGroupVersion.WithResource(monitoringv1.PrometheusRuleName), v1.PrometheusRules()) {
+		mClient.prometheusRules = v1.PrometheusRules().Lister()
+	}
+	if snapshotted(monitoringv1alpha1.SchemeGroupVersion.WithResource(monitoringv1alpha1.PrometheusAgentName), v1alpha1.PrometheusAgents()) {
+		mClient.prometheusAgents = v1alpha1.PrometheusAgents().Lister()
+	}
+	if snapshotted(monitoringv1alpha1.SchemeGroupVersion.WithResource(monitoringv1alpha1.ScrapeConfigName), v1alpha1.ScrapeConfigs()) {
+		mClient.scrapeConfigs = v1alpha1.ScrapeConfigs().Lister()
+	}
+	if snapshotted(monitoringv1alpha1.SchemeGroupVersion.WithResource(monitoringv1alpha1.AlertmanagerConfigName), v1alpha1.AlertmanagerConfigs()) {
+		mClient.alertmanagerConfigs = v1alpha1.AlertmanagerConfigs().Lister()
+	}
+
+	if err := syncInformers(ctx, informers, kFactory, mFactory); err != nil {
+		return nil, err
+	}
+
+	return &ClientSets{
+		KClient:             kClient,
+		MClient:             mClient,
+		DClient:             clientSets.DClient,
+		APIExtensionsClient: clientSets.APIExtensionsClient,
+	}, nil
+}
+
+// servedResources returns the resources of the group versions served by the
+// cluster. A group version which isn't served has no resources.
+func servedResources(client discovery.DiscoveryInterface, groupVersions ...schema.GroupVersion) (map[schema.GroupVersionResource]bool, error) {
+	served := map[schema.GroupVersionResource]bool{}
+	for _, gv := range groupVersions {
+		list, err := client.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("error while discovering the resources of %s: %w", gv, err)
+		}
+
+		for _, r := range list.APIResources {
+			served[gv.WithResource(r.Name)] = true
+		}
+	}
+	return served, nil
+}
+
+// informerGetter is the part of the informers of the factories returning
+// their shared informer.
+type informerGetter interface {
+	Informer() cache.SharedIndexInformer
+}
+
+// informerFactory is the part of the informer factories of client-go and of
+// the monitoring client used by syncInformers.
+type informerFactory interface {
+	Start(stopCh <-chan struct{})
+	Shutdown()
+}
+
+// syncInformers runs the informers until they are synced, and stops them.
+// The informers retry the failed lists forever, the first failure is
+// returned instead.
+func syncInformers(ctx context.Context, informers []cache.SharedIndexInformer, factories ...informerFactory) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		for _, f := range factories {
+			f.Shutdown()
+		}
+	}()
+
+	var (
+		mtx     sync.Mutex
+		listErr error
+	)
+	synced := make([]cache.InformerSynced, 0, len(informers))
+	for _, informer := range informers {
+		err := informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if listErr == nil {
+				listErr = err
+			}
+			cancel()
+		})
+		if err != nil {
+			return fmt.Errorf("error while setting up snapshot: %w", err)
+		}
+		synced = append(synced, informer.HasSynced)
+	}
+
+	for _, f := range factories {
+		f.Start(ctx.Done())
+	}
+
+	if cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if listErr != nil {
+		return listErr
+	}
+	return context.Cause(ctx)
+}
+
+// fromSnapshot reports whether the list options can be served by the
+// snapshot, which only supports label selectors. The limit is ignored, all
+// the objects being returned in a single page.
+func fromSnapshot(opts metav1.ListOptions) bool {
+	return opts.FieldSelector == "" && opts.ResourceVersion == "" && opts.Continue == ""
+}
+
+// getCopy returns a copy of the object of the snapshot returned by a lister,
+// so that the callers can't alter the snapshot.
+func getCopy[E any, P interface {
+	*E
+	DeepCopy() *E
+}](obj P, err error) (*E, error) {
+	if err != nil {
+		return nil, err
+	}
+	return obj.DeepCopy(), nil
+}
+
+// listCopies returns copies of the objects of the snapshot matching the
+// label selector of the options.
+func listCopies[E any, P interface {
+	*E
+	DeepCopy() *E
+}](opts metav1.ListOptions, list func(labels.Selector) ([]P, error)) ([]*E, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing label selector: %w", err)
+	}
+
+	objs, err := list(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*E, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, obj.DeepCopy())
+	}
+	return items, nil
+}
+
+// values returns the objects of a list whose items aren't pointers.
+func values[E any](items []*E) []E {
+	objs := make([]E, 0, len(items))
+	for _, item := range items {
+		objs = append(objs, *item)
+	}
+	return objs
+}
+
+// snapshotKubeClient serves the gets and lists of the snapshotted Kubernetes
+// resources from the listers, and sends everything else to the cluster.
+type snapshotKubeClient struct {
+	kubernetes.Interface
+	namespaces          corev1listers.NamespaceLister
+	services            corev1listers.ServiceLister
+	serviceAccounts     corev1listers.ServiceAccountLister
+	roles               rbacv1listers.RoleLister
+	roleBindings        rbacv1listers.RoleBindingLister
+	clusterRoles        rbacv1listers.ClusterRoleLister
+	clusterRoleBindings rbacv1listers.ClusterRoleBindingLister
+}
+
+func (c *snapshotKubeClient) CoreV1() corev1client.CoreV1Interface {
+	return &snapshotCoreV1{CoreV1Interface: c.Interface.CoreV1(), c: c}
+}
+
+func (c *snapshotKubeClient) RbacV1() rbacv1client.RbacV1Interface {
+	return &snapshotRbacV1{RbacV1Interface: c.Interface.RbacV1(), c: c}
+}
+
+type snapshotCoreV1 struct {
+	corev1client.CoreV1Interface
+	c *snapshotKubeClient
+}
+
+func (s *snapshotCoreV1) Namespaces() corev1client.NamespaceInterface {
+	return &snapshotNamespaces{NamespaceInterface: s.CoreV1Interface.Namespaces(), lister: s.c.namespaces}
+}
+
+func (s *snapshotCoreV1) Services(namespace string) corev1client.ServiceInterface {
+	return &snapshotServices{ServiceInterface: s.CoreV1Interface.Services(namespace), lister: s.c.services.Services(namespace)}
+}
+
+func (s *snapshotCoreV1) ServiceAccounts(namespace string) corev1client.ServiceAccountInterface {
+	return &snapshotServiceAccounts{ServiceAccountInterface: s.CoreV1Interface.ServiceAccounts(namespace), lister: s.c.serviceAccounts.ServiceAccounts(namespace)}
+}
+
+type snapshotNamespaces struct {
+	corev1client.NamespaceInterface
+	lister corev1listers.NamespaceLister
+}
+
+func (s *snapshotNamespaces) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Namespace, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotNamespaces) List(ctx context.Context, opts metav1.ListOptions) (*corev1.NamespaceList, error) {
+	if !fromSnapshot(opts) {
+		return s.NamespaceInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.NamespaceList{Items: values(items)}, nil
+}
+
+type snapshotServices struct {
+	corev1client.ServiceInterface
+	lister corev1listers.ServiceNamespaceLister
+}
+
+func (s *snapshotServices) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Service, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotServices) List(ctx context.Context, opts metav1.ListOptions) (*corev1.ServiceList, error) {
+	if !fromSnapshot(opts) {
+		return s.ServiceInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ServiceList{Items: values(items)}, nil
+}
+
+type snapshotServiceAccounts struct {
+	corev1client.ServiceAccountInterface
+	lister corev1listers.ServiceAccountNamespaceLister
+}
+
+func (s *snapshotServiceAccounts) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.ServiceAccount, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotServiceAccounts) List(ctx context.Context, opts metav1.ListOptions) (*corev1.ServiceAccountList, error) {
+	if !fromSnapshot(opts) {
+		return s.ServiceAccountInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ServiceAccountList{Items: values(items)}, nil
+}
+
+type snapshotRbacV1 struct {
+	rbacv1client.RbacV1Interface
+	c *snapshotKubeClient
+}
+
+func (s *snapshotRbacV1) Roles(namespace string) rbacv1client.RoleInterface {
+	return &snapshotRoles{RoleInterface: s.RbacV1Interface.Roles(namespace), lister: s.c.roles.Roles(namespace)}
+}
+
+func (s *snapshotRbacV1) RoleBindings(namespace string) rbacv1client.RoleBindingInterface {
+	return &snapshotRoleBindings{RoleBindingInterface: s.RbacV1Interface.RoleBindings(namespace), lister: s.c.roleBindings.RoleBindings(namespace)}
+}
+
+func (s *snapshotRbacV1) ClusterRoles() rbacv1client.ClusterRoleInterface {
+	return &snapshotClusterRoles{ClusterRoleInterface: s.RbacV1Interface.ClusterRoles(), lister: s.c.clusterRoles}
+}
+
+func (s *snapshotRbacV1) ClusterRoleBindings() rbacv1client.ClusterRoleBindingInterface {
+	return &snapshotClusterRoleBindings{ClusterRoleBindingInterface: s.RbacV1Interface.ClusterRoleBindings(), lister: s.c.clusterRoleBindings}
+}
+
+type snapshotRoles struct {
+	rbacv1client.RoleInterface
+	lister rbacv1listers.RoleNamespaceLister
+}
+
+func (s *snapshotRoles) Get(_ context.Context, name string, _ metav1.GetOptions) (*rbacv1.Role, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotRoles) List(ctx context.Context, opts metav1.ListOptions) (*rbacv1.RoleList, error) {
+	if !fromSnapshot(opts) {
+		return s.RoleInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &rbacv1.RoleList{Items: values(items)}, nil
+}
+
+type snapshotRoleBindings struct {
+	rbacv1client.RoleBindingInterface
+	lister rbacv1listers.RoleBindingNamespaceLister
+}
+
+func (s *snapshotRoleBindings) Get(_ context.Context, name string, _ metav1.GetOptions) (*rbacv1.RoleBinding, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotRoleBindings) List(ctx context.Context, opts metav1.ListOptions) (*rbacv1.RoleBindingList, error) {
+	if !fromSnapshot(opts) {
+		return s.RoleBindingInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &rbacv1.RoleBindingList{Items: values(items)}, nil
+}
+
+type snapshotClusterRoles struct {
+	rbacv1client.ClusterRoleInterface
+	lister rbacv1listers.ClusterRoleLister
+}
+
+func (s *snapshotClusterRoles) Get(_ context.Context, name string, _ metav1.GetOptions) (*rbacv1.ClusterRole, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotClusterRoles) List(ctx context.Context, opts metav1.ListOptions) (*rbacv1.ClusterRoleList, error) {
+	if !fromSnapshot(opts) {
+		return s.ClusterRoleInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &rbacv1.ClusterRoleList{Items: values(items)}, nil
+}
+
+type snapshotClusterRoleBindings struct {
+	rbacv1client.ClusterRoleBindingInterface
+	lister rbacv1listers.ClusterRoleBindingLister
+}
+
+func (s *snapshotClusterRoleBindings) Get(_ context.Context, name string, _ metav1.GetOptions) (*rbacv1.ClusterRoleBinding, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotClusterRoleBindings) List(ctx context.Context, opts metav1.ListOptions) (*rbacv1.ClusterRoleBindingList, error) {
+	if !fromSnapshot(opts) {
+		return s.ClusterRoleBindingInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &rbacv1.ClusterRoleBindingList{Items: values(items)}, nil
+}
+
+// snapshotMonitoringClient serves the gets and lists of the
+// monitoring.coreos.com resources from the listers, and sends everything
+// else to the cluster, as well as the requests of the resources without a
+// lister, which aren't served by the cluster.
+type snapshotMonitoringClient struct {
+	monitoringclient.Interface
+	prometheuses        monitoringv1listers.PrometheusLister
+	alertmanagers       monitoringv1listers.AlertmanagerLister
+	thanosRulers        monitoringv1listers.ThanosRulerLister
+	serviceMonitors     monitoringv1listers.ServiceMonitorLister
+	podMonitors         monitoringv1listers.PodMonitorLister
+	probes              monitoringv1listers.ProbeLister
+	prometheusRules     monitoringv1listers.PrometheusRuleLister
+	prometheusAgents    monitoringv1alpha1listers.PrometheusAgentLister
+	scrapeConfigs       monitoringv1alpha1listers.ScrapeConfigLister
+	alertmanagerConfigs monitoringv1alpha1listers.AlertmanagerConfigLister
+}
+
+func (c *snapshotMonitoringClient) MonitoringV1() monitoringv1client.MonitoringV1Interface {
+	return &snapshotMonitoringV1{MonitoringV1Interface: c.Interface.MonitoringV1(), c: c}
+}
+
+func (c *snapshotMonitoringClient) MonitoringV1alpha1() monitoringv1alpha1client.MonitoringV1alpha1Interface {
+	return &snapshotMonitoringV1alpha1{MonitoringV1alpha1Interface: c.Interface.MonitoringV1alpha1(), c: c}
+}
+
+type snapshotMonitoringV1 struct {
+	monitoringv1client.MonitoringV1Interface
+	c *snapshotMonitoringClient
+}
+
+func (s *snapshotMonitoringV1) Prometheuses(namespace string) monitoringv1client.PrometheusInterface {
+	if s.c.prometheuses == nil {
+		return s.MonitoringV1Interface.Prometheuses(namespace)
+	}
+	return &snapshotPrometheuses{PrometheusInterface: s.MonitoringV1Interface.Prometheuses(namespace), lister: s.c.prometheuses.Prometheuses(namespace)}
+}
+
+func (s *snapshotMonitoringV1) Alertmanagers(namespace string) monitoringv1client.AlertmanagerInterface {
+	if s.c.alertmanagers == nil {
+		return s.MonitoringV1Interface.Alertmanagers(namespace)
+	}
+	return &snapshotAlertmanagers{AlertmanagerInterface: s.MonitoringV1Interface.Alertmanagers(namespace), lister: s.c.alertmanagers.Alertmanagers(namespace)}
+}
+
+func (s *snapshotMonitoringV1) ThanosRulers(namespace string) monitoringv1client.ThanosRulerInterface {
+	if s.c.thanosRulers == nil {
+		return s.MonitoringV1Interface.ThanosRulers(namespace)
+	}
+	return &snapshotThanosRulers{ThanosRulerInterface: s.MonitoringV1Interface.ThanosRulers(namespace), lister: s.c.thanosRulers.ThanosRulers(namespace)}
+}
+
+func (s *snapshotMonitoringV1) ServiceMonitors(namespace string) monitoringv1client.ServiceMonitorInterface {
+	if s.c.serviceMonitors == nil {
+		return s.MonitoringV1Interface.ServiceMonitors(namespace)
+	}
+	return &snapshotServiceMonitors{ServiceMonitorInterface: s.MonitoringV1Interface.ServiceMonitors(namespace), lister: s.c.serviceMonitors.ServiceMonitors(namespace)}
+}
+
+func (s *snapshotMonitoringV1) PodMonitors(namespace string) monitoringv1client.PodMonitorInterface {
+	if s.c.podMonitors == nil {
+		return s.MonitoringV1Interface.PodMonitors(namespace)
+	}
+	return &snapshotPodMonitors{PodMonitorInterface: s.MonitoringV1Interface.PodMonitors(namespace), lister: s.c.podMonitors.PodMonitors(namespace)}
+}
+
+func (s *snapshotMonitoringV1) Probes(namespace string) monitoringv1client.ProbeInterface {
+	if s.c.probes == nil {
+		return s.MonitoringV1Interface.Probes(namespace)
+	}
+	return &snapshotProbes{ProbeInterface: s.MonitoringV1Interface.Probes(namespace), lister: s.c.probes.Probes(namespace)}
+}
+
+func (s *snapshotMonitoringV1) PrometheusRules(namespace string) monitoringv1client.PrometheusRuleInterface {
+	if s.c.prometheusRules == nil {
+		return s.MonitoringV1Interface.PrometheusRules(namespace)
+	}
+	return &snapshotPrometheusRules{PrometheusRuleInterface: s.MonitoringV1Interface.PrometheusRules(namespace), lister: s.c.prometheusRules.PrometheusRules(namespace)}
+}
+
+type snapshotPrometheuses struct {
+	monitoringv1client.PrometheusInterface
+	lister monitoringv1listers.PrometheusNamespaceLister
+}
+
+func (s *snapshotPrometheuses) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.Prometheus, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotPrometheuses) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.PrometheusList, error) {
+	if !fromSnapshot(opts) {
+		return s.PrometheusInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.PrometheusList{Items: items}, nil
+}
+
+type snapshotAlertmanagers struct {
+	monitoringv1client.AlertmanagerInterface
+	lister monitoringv1listers.AlertmanagerNamespaceLister
+}
+
+func (s *snapshotAlertmanagers) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.Alertmanager, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotAlertmanagers) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.AlertmanagerList, error) {
+	if !fromSnapshot(opts) {
+		return s.AlertmanagerInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.AlertmanagerList{Items: values(items)}, nil
+}
+
+type snapshotThanosRulers struct {
+	monitoringv1client.ThanosRulerInterface
+	lister monitoringv1listers.ThanosRulerNamespaceLister
+}
+
+func (s *snapshotThanosRulers) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.ThanosRuler, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotThanosRulers) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.ThanosRulerList, error) {
+	if !fromSnapshot(opts) {
+		return s.ThanosRulerInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.ThanosRulerList{Items: items}, nil
+}
+
+type snapshotServiceMonitors struct {
+	monitoringv1client.ServiceMonitorInterface
+	lister monitoringv1listers.ServiceMonitorNamespaceLister
+}
+
+func (s *snapshotServiceMonitors) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.ServiceMonitor, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotServiceMonitors) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.ServiceMonitorList, error) {
+	if !fromSnapshot(opts) {
+		return s.ServiceMonitorInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.ServiceMonitorList{Items: items}, nil
+}
+
+type snapshotPodMonitors struct {
+	monitoringv1client.PodMonitorInterface
+	lister monitoringv1listers.PodMonitorNamespaceLister
+}
+
+func (s *snapshotPodMonitors) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.PodMonitor, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotPodMonitors) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.PodMonitorList, error) {
+	if !fromSnapshot(opts) {
+		return s.PodMonitorInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.PodMonitorList{Items: items}, nil
+}
+
+type snapshotProbes struct {
+	monitoringv1client.ProbeInterface
+	lister monitoringv1listers.ProbeNamespaceLister
+}
+
+func (s *snapshotProbes) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.Probe, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotProbes) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.ProbeList, error) {
+	if !fromSnapshot(opts) {
+		return s.ProbeInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.ProbeList{Items: items}, nil
+}
+
+type snapshotPrometheusRules struct {
+	monitoringv1client.PrometheusRuleInterface
+	lister monitoringv1listers.PrometheusRuleNamespaceLister
+}
+
+func (s *snapshotPrometheusRules) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1.PrometheusRule, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotPrometheusRules) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1.PrometheusRuleList, error) {
+	if !fromSnapshot(opts) {
+		return s.PrometheusRuleInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1.PrometheusRuleList{Items: items}, nil
+}
+
+type snapshotMonitoringV1alpha1 struct {
+	monitoringv1alpha1client.MonitoringV1alpha1Interface
+	c *snapshotMonitoringClient
+}
+
+func (s *snapshotMonitoringV1alpha1) PrometheusAgents(namespace string) monitoringv1alpha1client.PrometheusAgentInterface {
+	if s.c.prometheusAgents == nil {
+		return s.MonitoringV1alpha1Interface.PrometheusAgents(namespace)
+	}
+	return &snapshotPrometheusAgents{PrometheusAgentInterface: s.MonitoringV1alpha1Interface.PrometheusAgents(namespace), lister: s.c.prometheusAgents.PrometheusAgents(namespace)}
+}
+
+func (s *snapshotMonitoringV1alpha1) ScrapeConfigs(namespace string) monitoringv1alpha1client.ScrapeConfigInterface {
+	if s.c.scrapeConfigs == nil {
+		return s.MonitoringV1alpha1Interface.ScrapeConfigs(namespace)
+	}
+	return &snapshotScrapeConfigs{ScrapeConfigInterface: s.MonitoringV1alpha1Interface.ScrapeConfigs(namespace), lister: s.c.scrapeConfigs.ScrapeConfigs(namespace)}
+}
+
+func (s *snapshotMonitoringV1alpha1) AlertmanagerConfigs(namespace string) monitoringv1alpha1client.AlertmanagerConfigInterface {
+	if s.c.alertmanagerConfigs == nil {
+		return s.MonitoringV1alpha1Interface.AlertmanagerConfigs(namespace)
+	}
+	return &snapshotAlertmanagerConfigs{AlertmanagerConfigInterface: s.MonitoringV1alpha1Interface.AlertmanagerConfigs(namespace), lister: s.c.alertmanagerConfigs.AlertmanagerConfigs(namespace)}
+}
+
+type snapshotPrometheusAgents struct {
+	monitoringv1alpha1client.PrometheusAgentInterface
+	lister monitoringv1alpha1listers.PrometheusAgentNamespaceLister
+}
+
+func (s *snapshotPrometheusAgents) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1alpha1.PrometheusAgent, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotPrometheusAgents) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1alpha1.PrometheusAgentList, error) {
+	if !fromSnapshot(opts) {
+		return s.PrometheusAgentInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1alpha1.PrometheusAgentList{Items: items}, nil
+}
+
+type snapshotScrapeConfigs struct {
+	monitoringv1alpha1client.ScrapeConfigInterface
+	lister monitoringv1alpha1listers.ScrapeConfigNamespaceLister
+}
+
+func (s *snapshotScrapeConfigs) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1alpha1.ScrapeConfig, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotScrapeConfigs) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1alpha1.ScrapeConfigList, error) {
+	if !fromSnapshot(opts) {
+		return s.ScrapeConfigInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1alpha1.ScrapeConfigList{Items: items}, nil
+}
+
+type snapshotAlertmanagerConfigs struct {
+	monitoringv1alpha1client.AlertmanagerConfigInterface
+	lister monitoringv1alpha1listers.AlertmanagerConfigNamespaceLister
+}
+
+func (s *snapshotAlertmanagerConfigs) Get(_ context.Context, name string, _ metav1.GetOptions) (*monitoringv1alpha1.AlertmanagerConfig, error) {
+	return getCopy(s.lister.Get(name))
+}
+
+func (s *snapshotAlertmanagerConfigs) List(ctx context.Context, opts metav1.ListOptions) (*monitoringv1alpha1.AlertmanagerConfigList, error) {
+	if !fromSnapshot(opts) {
+		return s.AlertmanagerConfigInterface.List(ctx, opts)
+	}
+	items, err := listCopies(opts, s.lister.List)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoringv1alpha1.AlertmanagerConfigList{Items: items}, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"context"
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringfake "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	kClient := kubefake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "prometheus-operated", Namespace: "monitoring", Labels: map[string]string{"operated-prometheus": "true"}}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "thanos-objstore", Namespace: "monitoring"}, Data: map[string][]byte{"objstore.yaml": []byte("type: S3")}},
+	)
+	mClient := monitoringfake.NewSimpleClientset(
+		&monitoringv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"}},
+	)
+	mClient.Resources = []*metav1.APIResourceList{serve(monitoringv1.SchemeGroupVersion, monitoringv1.PrometheusName)}
+	recording := &createOptionsRecorder{Interface: kClient}
+	live := &ClientSets{KClient: recording, MClient: mClient}
+
+	snapshot, err := NewSnapshot(ctx, live)
+	require.NoError(t, err)
+
+	// The gets and the lists of the snapshotted resources don't reach the
+	// cluster.
+	var actions []clienttesting.Action
+	for _, fake := range []*clienttesting.Fake{&kClient.Fake, &mClient.Fake} {
+		fake.PrependReactor("*", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			actions = append(actions, action)
+			return false, nil, nil
+		})
+	}
+
+	prometheuses, err := snapshot.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, prometheuses.Items, 1)
+	assert.Equal(t, "k8s", prometheuses.Items[0].Name)
+
+	services, err := snapshot.KClient.CoreV1().Services("monitoring").List(ctx, metav1.ListOptions{LabelSelector: "operated-prometheus=true", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, services.Items, 1)
+	assert.Equal(t, "prometheus-operated", services.Items[0].Name)
+
+	service, err := snapshot.KClient.CoreV1().Services("monitoring").Get(ctx, "prometheus-operated", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	_, err = snapshot.KClient.CoreV1().Services("default").Get(ctx, "prometheus-operated", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Empty(t, actions)
+
+	// The objects returned are copies of the snapshot.
+	service.Labels["operated-prometheus"] = "false"
+	service, err = snapshot.KClient.CoreV1().Services("monitoring").Get(ctx, "prometheus-operated", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "true", service.Labels["operated-prometheus"])
+
+	// The Secrets and the lists with a field selector are read from the
+	// cluster.
+	secret, err := snapshot.KClient.CoreV1().Secrets("monitoring").Get(ctx, "thanos-objstore", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("type: S3"), secret.Data["objstore.yaml"])
+
+	_, err = snapshot.KClient.CoreV1().Services("monitoring").List(ctx, metav1.ListOptions{FieldSelector: "metadata.name=grafana"})
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "secrets", actions[0].GetResource().Resource)
+	assert.Equal(t, "services", actions[1].GetResource().Resource)
+
+	// The writes are sent to the cluster with their options, and aren't
+	// reflected in the snapshot.
+	_, err = snapshot.KClient.CoreV1().Services("monitoring").Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "alertmanager-operated", Namespace: "monitoring"},
+	}, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}, FieldManager: FieldManager})
+	require.NoError(t, err)
+	require.Len(t, actions, 3)
+	assert.Equal(t, "create", actions[2].GetVerb())
+	assert.Equal(t, []string{metav1.DryRunAll}, recording.options.DryRun)
+	assert.Equal(t, FieldManager, recording.options.FieldManager)
+
+	_, err = snapshot.KClient.CoreV1().Services("monitoring").Get(ctx, "alertmanager-operated", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+// createOptionsRecorder records the options of the creations of Services,
+// which the fake clientset doesn't keep.
+type createOptionsRecorder struct {
+	kubernetes.Interface
+	options metav1.CreateOptions
+}
+
+func (r *createOptionsRecorder) CoreV1() corev1client.CoreV1Interface {
+	return &createOptionsRecorderCoreV1{CoreV1Interface: r.Interface.CoreV1(), r: r}
+}
+
+type createOptionsRecorderCoreV1 struct {
+	corev1client.CoreV1Interface
+	r *createOptionsRecorder
+}
+
+func (c *createOptionsRecorderCoreV1) Services(namespace string) corev1client.ServiceInterface {
+	return &createOptionsRecorderServices{ServiceInterface: c.CoreV1Interface.Services(namespace), r: c.r}
+}
+
+type createOptionsRecorderServices struct {
+	corev1client.ServiceInterface
+	r *createOptionsRecorder
+}
+
+func (s *createOptionsRecorderServices) Create(ctx context.Context, service *corev1.Service, opts metav1.CreateOptions) (*corev1.Service, error) {
+	s.r.options = opts
+	return s.ServiceInterface.Create(ctx, service, opts)
+}
+
+// serve returns the discovery of the resources of a group version.
+func serve(gv schema.GroupVersion, resources ...string) *metav1.APIResourceList {
+	list := &metav1.APIResourceList{GroupVersion: gv.String()}
+	for _, r := range resources {
+		list.APIResources = append(list.APIResources, metav1.APIResource{Name: r, Namespaced: true})
+	}
+	return list
+}
+
+func TestNewSnapshotMissingCRD(t *testing.T) {
+	ctx := context.Background()
+
+	// The ScrapeConfig CRD isn't installed: the cluster doesn't serve
+	// monitoring.coreos.com/v1alpha1 and its lists fail.
+	mClient := monitoringfake.NewSimpleClientset(
+		&monitoringv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"}},
+	)
+	mClient.Resources = []*metav1.APIResourceList{serve(monitoringv1.SchemeGroupVersion, monitoringv1.PrometheusName, monitoringv1.ServiceMonitorName)}
+	mClient.PrependReactor("list", monitoringv1alpha1.ScrapeConfigName, func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(action.GetResource().GroupResource(), "")
+	})
+
+	snapshot, err := NewSnapshot(ctx, &ClientSets{KClient: kubefake.NewSimpleClientset(), MClient: mClient})
+	require.NoError(t, err)
+
+	prometheuses, err := snapshot.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, prometheuses.Items, 1)
+
+	// The requests of the missing resource reach the cluster, which answers
+	// as without a snapshot.
+	_, err = snapshot.MClient.MonitoringV1alpha1().ScrapeConfigs(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "unexpected error %v", err)
+}
+
+func TestNewSnapshotListError(t *testing.T) {
+	kClient := kubefake.NewSimpleClientset()
+	kClient.PrependReactor("list", "clusterroles", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}, "", nil)
+	})
+
+	_, err := NewSnapshot(context.Background(), &ClientSets{KClient: kClient, MClient: monitoringfake.NewSimpleClientset()})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsForbidden(err), "unexpected error %v", err)
+	// The callers wrap the error.
+	assert.NotContains(t, err.Error(), "snapshot")
+}
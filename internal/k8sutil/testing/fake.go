@@ -0,0 +1,296 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testing builds fake ClientSets for the tests, seeded with objects
+// and reactors.
+package testing
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringfake "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// schemes returns the schemes of the Kubernetes, monitoring and
+// apiextensions clientsets, used to route the seeded objects to the
+// clientset serving them and to decode the applied objects. They are built once and only read afterwards, so that the tests
+// running in parallel share them.
+var schemes = sync.OnceValue(func() fakeSchemes {
+	s := fakeSchemes{
+		kube:          runtime.NewScheme(),
+		monitoring:    runtime.NewScheme(),
+		apiextensions: runtime.NewScheme(),
+	}
+	for scheme, addToScheme := range map[*runtime.Scheme]func(*runtime.Scheme) error{
+		s.kube:          kubefake.AddToScheme,
+		s.monitoring:    monitoringfake.AddToScheme,
+		s.apiextensions: apiextensionsfake.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			panic(fmt.Sprintf("testing: error while building the schemes: %v", err))
+		}
+	}
+	return s
+})
+
+type fakeSchemes struct {
+	kube          *runtime.Scheme
+	monitoring    *runtime.Scheme
+	apiextensions *runtime.Scheme
+}
+
+type reactor struct {
+	verb     string
+	resource string
+	reaction clienttesting.ReactionFunc
+}
+
+type config struct {
+	kubeObjects           []runtime.Object
+	monitoringObjects     []runtime.Object
+	apiextensionsObjects  []runtime.Object
+	dynamicObjects        []runtime.Object
+	kubeReactors          []reactor
+	monitoringReactors    []reactor
+	apiextensionsReactors []reactor
+	serverSideApply       bool
+}
+
+// Option seeds the fake ClientSets built by NewFakeClientSets.
+type Option func(*config)
+
+// WithObjects seeds the clientsets serving the objects: the
+// monitoring.coreos.com objects go to the monitoring clientset, the
+// CustomResourceDefinitions to the apiextensions clientset and the other
+// objects to the Kubernetes clientset.
+func WithObjects(objs ...runtime.Object) Option {
+	return func(c *config) {
+		s := schemes()
+		for _, obj := range objs {
+			switch {
+			case recognizes(s.monitoring, obj):
+				c.monitoringObjects = append(c.monitoringObjects, obj)
+			case recognizes(s.apiextensions, obj):
+				c.apiextensionsObjects = append(c.apiextensionsObjects, obj)
+			default:
+				c.kubeObjects = append(c.kubeObjects, obj)
+			}
+		}
+	}
+}
+
+// WithDynamicObjects seeds the dynamic client, usually with unstructured
+// objects.
+func WithDynamicObjects(objs ...runtime.Object) Option {
+	return func(c *config) {
+		c.dynamicObjects = append(c.dynamicObjects, objs...)
+	}
+}
+
+// WithKubeReactor prepends a reactor to the Kubernetes clientset.
+func WithKubeReactor(verb, resource string, reaction clienttesting.ReactionFunc) Option {
+	return func(c *config) {
+		c.kubeReactors = append(c.kubeReactors, reactor{verb, resource, reaction})
+	}
+}
+
+// WithMonitoringReactor prepends a reactor to the monitoring clientset.
+func WithMonitoringReactor(verb, resource string, reaction clienttesting.ReactionFunc) Option {
+	return func(c *config) {
+		c.monitoringReactors = append(c.monitoringReactors, reactor{verb, resource, reaction})
+	}
+}
+
+// WithAPIExtensionsReactor prepends a reactor to the apiextensions
+// clientset.
+func WithAPIExtensionsReactor(verb, resource string, reaction clienttesting.ReactionFunc) Option {
+	return func(c *config) {
+		c.apiextensionsReactors = append(c.apiextensionsReactors, reactor{verb, resource, reaction})
+	}
+}
+
+// WithServerSideApply makes the applies of the Kubernetes, monitoring and
+// dynamic clientsets create the missing objects and replace the existing
+// ones, as the server-side applies of a single field manager do: the fields
+// left out of an apply are removed. The created objects get a UID, kept by
+// the next applies.
+func WithServerSideApply() Option {
+	return func(c *config) {
+		c.serverSideApply = true
+	}
+}
+
+// NewFakeClientSets returns ClientSets backed by fake clientsets, seeded
+// with the options. The reactors are prepended in the order of the options,
+// so that the last one added for a verb and resource takes precedence.
+func NewFakeClientSets(opts ...Option) *k8sutil.ClientSets {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	s := schemes()
+
+	kClient := kubefake.NewSimpleClientset(c.kubeObjects...)
+	if c.serverSideApply {
+		kClient.PrependReactor("patch", "*", serverSideApply(kClient.Tracker(), s.kube))
+	}
+	for _, r := range c.kubeReactors {
+		kClient.PrependReactor(r.verb, r.resource, r.reaction)
+	}
+
+	mClient := monitoringfake.NewSimpleClientset(c.monitoringObjects...)
+	if c.serverSideApply {
+		mClient.PrependReactor("patch", "*", serverSideApply(mClient.Tracker(), s.monitoring))
+	}
+	for _, r := range c.monitoringReactors {
+		mClient.PrependReactor(r.verb, r.resource, r.reaction)
+	}
+
+	apiExtensionsClient := apiextensionsfake.NewSimpleClientset(c.apiextensionsObjects...)
+	for _, r := range c.apiextensionsReactors {
+		apiExtensionsClient.PrependReactor(r.verb, r.resource, r.reaction)
+	}
+
+	dClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), c.dynamicObjects...)
+	if c.serverSideApply {
+		dClient.PrependReactor("patch", "*", serverSideApply(dClient.Tracker(), nil))
+	}
+
+	return &k8sutil.ClientSets{
+		KClient:             kClient,
+		MClient:             mClient,
+		DClient:             dClient,
+		APIExtensionsClient: apiExtensionsClient,
+	}
+}
+
+// ReturnObject returns a reaction answering every matching action with obj.
+func ReturnObject(obj runtime.Object) clienttesting.ReactionFunc {
+	return func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, obj, nil
+	}
+}
+
+// ReturnError returns a reaction failing every matching action with err.
+func ReturnError(err error) clienttesting.ReactionFunc {
+	return func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, err
+	}
+}
+
+// NotFound returns a reaction failing every matching action with a not found
+// error on the resource of the action, named after the requested object.
+func NotFound() clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(action.GetResource().GroupResource(), actionName(action))
+	}
+}
+
+// Forbidden returns a reaction failing every matching action with a
+// forbidden error on the resource of the action.
+func Forbidden() clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewForbidden(action.GetResource().GroupResource(), actionName(action), fmt.Errorf("access denied"))
+	}
+}
+
+// InternalError returns a reaction failing every matching action with an
+// internal server error.
+func InternalError() clienttesting.ReactionFunc {
+	return ReturnError(errors.NewInternalError(fmt.Errorf("internal error")))
+}
+
+// serverSideApply returns a reaction storing the objects of the applies in
+// the tracker, decoded with the scheme or kept unstructured when it's nil.
+// The other patches are left to the next reactors.
+func serverSideApply(tracker clienttesting.ObjectTracker, scheme *runtime.Scheme) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(clienttesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		u := &unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+
+		var obj runtime.Object = u
+		if scheme != nil {
+			typed, err := scheme.New(u.GroupVersionKind())
+			if err != nil {
+				return true, nil, err
+			}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+				return true, nil, err
+			}
+			obj = typed
+		}
+
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return true, nil, err
+		}
+
+		gvr, ns, name := action.GetResource(), action.GetNamespace(), patch.GetName()
+		existing, err := tracker.Get(gvr, ns, name)
+		switch {
+		case errors.IsNotFound(err):
+			accessor.SetUID(uuid.NewUUID())
+			err = tracker.Create(gvr, obj, ns)
+		case err == nil:
+			var existingAccessor metav1.Object
+			if existingAccessor, err = meta.Accessor(existing); err == nil {
+				accessor.SetUID(existingAccessor.GetUID())
+				err = tracker.Update(gvr, obj, ns)
+			}
+		}
+		if err != nil {
+			return true, nil, err
+		}
+
+		stored, err := tracker.Get(gvr, ns, name)
+		return true, stored, err
+	}
+}
+
+// recognizes reports whether the type of the object is registered in the
+// scheme.
+func recognizes(scheme *runtime.Scheme, obj runtime.Object) bool {
+	_, _, err := scheme.ObjectKinds(obj)
+	return err == nil
+}
+
+// actionName returns the name of the object of an action, empty for the
+// actions on collections.
+func actionName(action clienttesting.Action) string {
+	if a, ok := action.(interface{ GetName() string }); ok {
+		return a.GetName()
+	}
+	return ""
+}
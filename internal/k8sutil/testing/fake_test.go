@@ -0,0 +1,122 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+func TestNewFakeClientSets(t *testing.T) {
+	clientSets := NewFakeClientSets(
+		WithObjects(
+			&monitoringv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"}},
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "prometheus", Namespace: "monitoring"}},
+			&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "prometheuses.monitoring.coreos.com"}},
+		),
+	)
+	ctx := context.Background()
+
+	_, err := clientSets.MClient.MonitoringV1().Prometheuses("monitoring").Get(ctx, "k8s", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	_, err = clientSets.KClient.CoreV1().ServiceAccounts("monitoring").Get(ctx, "prometheus", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	_, err = clientSets.APIExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, "prometheuses.monitoring.coreos.com", metav1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestReactors(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "monitoring"}}
+	clientSets := NewFakeClientSets(
+		WithKubeReactor("get", "secrets", ReturnObject(secret)),
+		WithKubeReactor("get", "configmaps", InternalError()),
+		WithKubeReactor("list", "services", Forbidden()),
+		WithMonitoringReactor("get", "alertmanagers", NotFound()),
+	)
+	ctx := context.Background()
+
+	got, err := clientSets.KClient.CoreV1().Secrets("monitoring").Get(ctx, "other", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "config", got.Name)
+
+	_, err = clientSets.KClient.CoreV1().ConfigMaps("monitoring").Get(ctx, "config", metav1.GetOptions{})
+	assert.True(t, errors.IsInternalError(err))
+
+	_, err = clientSets.KClient.CoreV1().Services("monitoring").List(ctx, metav1.ListOptions{})
+	assert.True(t, errors.IsForbidden(err))
+
+	_, err = clientSets.MClient.MonitoringV1().Alertmanagers("monitoring").Get(ctx, "main", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+	assert.Contains(t, err.Error(), `alertmanagers.monitoring.coreos.com "main" not found`)
+}
+
+func TestNewFakeClientSetsConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clientSets := NewFakeClientSets(WithObjects(&monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}))
+			_, err := clientSets.MClient.MonitoringV1().ServiceMonitors("default").Get(context.Background(), "app", metav1.GetOptions{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithServerSideApply(t *testing.T) {
+	clientSets := NewFakeClientSets(WithServerSideApply())
+	ctx := context.Background()
+	configMaps := clientSets.KClient.CoreV1().ConfigMaps("default")
+
+	created, err := configMaps.Apply(ctx, applyConfigCorev1.ConfigMap("config", "default").
+		WithLabels(map[string]string{"app": "config"}).
+		WithData(map[string]string{"key": "value"}), metav1.ApplyOptions{FieldManager: "test"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.UID)
+
+	// The data left out of the apply is removed, the UID is kept.
+	updated, err := configMaps.Apply(ctx, applyConfigCorev1.ConfigMap("config", "default").
+		WithLabels(map[string]string{"app": "config"}), metav1.ApplyOptions{FieldManager: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, created.UID, updated.UID)
+	assert.Empty(t, updated.Data)
+
+	u := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ServiceAccount",
+		"metadata":   map[string]any{"name": "prometheus", "namespace": "default"},
+	}}
+	resource := schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}
+	_, err = clientSets.DClient.Resource(resource).Namespace("default").Apply(ctx, "prometheus", u, metav1.ApplyOptions{FieldManager: "test"})
+	require.NoError(t, err)
+
+	_, err = clientSets.DClient.Resource(resource).Namespace("default").Get(ctx, "prometheus", metav1.GetOptions{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package labeling sets the external labels and the alert labels which
+// identify the tenant or the cluster on the Prometheus resources, so that
+// the routing relying on them stays consistent across the resources.
+package labeling
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/internal/patch"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// The external labels set by the Prometheus Operator when the
+	// corresponding fields are empty.
+	defaultPrometheusExternalLabel = "prometheus"
+	defaultReplicaExternalLabel    = "prometheus_replica"
+
+	replaceAction = "replace"
+)
+
+// labelNameRegexp matches the valid Prometheus label names.
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Options are the labels set on the Prometheus resources.
+type Options struct {
+	// ExternalLabels are added to every series and alert leaving
+	// Prometheus, through federation, remote write or Alertmanager.
+	ExternalLabels map[string]string
+	// AlertLabels are added to the alerts sent to each Alertmanager, by
+	// alert relabelings.
+	AlertLabels map[string]string
+}
+
+// Validate checks that there are labels to set and that their names are
+// valid.
+func (o Options) Validate() error {
+	if len(o.ExternalLabels) == 0 && len(o.AlertLabels) == 0 {
+		return fmt.Errorf("no external labels nor alert labels to set")
+	}
+
+	for _, labels := range []map[string]string{o.ExternalLabels, o.AlertLabels} {
+		for _, name := range slices.Sorted(maps.Keys(labels)) {
+			if !labelNameRegexp.MatchString(name) {
+				return fmt.Errorf("invalid label name %q", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of the labeling of a Prometheus.
+type Result struct {
+	Namespace string
+	Name      string
+	// Diff is the unified diff of the Prometheus, empty when it already has
+	// the labels.
+	Diff string
+	// Warnings are the labels which couldn't be set.
+	Warnings []string
+}
+
+// Run sets the labels on the Prometheus resources of the namespace, all the
+// namespaces when empty, matching the label selector. A Prometheus is
+// updated with the resource version it has been read with, and read again
+// when it has changed in the meantime. With dryRun, only the diffs are
+// computed.
+func Run(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, selector string, opts Options, dryRun bool) ([]Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	err := k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(namespace).List, metav1.ListOptions{LabelSelector: selector}, func(p *monitoringv1.Prometheus) error {
+		result, err := label(ctx, clientSets, p, opts, dryRun)
+		if err != nil {
+			return err
+		}
+		results = append(results, *result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// label sets the labels on a Prometheus, retrying on conflicts.
+func label(ctx context.Context, clientSets *k8sutil.ClientSets, p *monitoringv1.Prometheus, opts Options, dryRun bool) (*Result, error) {
+	client := clientSets.MClient.MonitoringV1().Prometheuses(p.Namespace)
+
+	var result *Result
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if result != nil {
+			live, err := client.Get(ctx, p.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("error while getting Prometheus %s/%s: %w", p.Namespace, p.Name, err)
+			}
+			p = live
+		}
+
+		labeled := p.DeepCopy()
+		warnings, err := Apply(labeled, opts)
+		if err != nil {
+			return err
+		}
+
+		result = &Result{
+			Namespace: p.Namespace,
+			Name:      p.Name,
+			Warnings:  warnings,
+		}
+		if result.Diff, err = diff(p, labeled); err != nil {
+			return err
+		}
+
+		if dryRun || result.Diff == "" {
+			return nil
+		}
+
+		_, err = client.Update(ctx, labeled, metav1.UpdateOptions{
+			FieldManager: k8sutil.ApplyOption.FieldManager,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while updating Prometheus %s/%s: %w", p.Namespace, p.Name, err)
+	}
+
+	return result, nil
+}
+
+// Apply sets the labels on the Prometheus. The alert labels are set by
+// replace relabelings without source labels, so that the existing ones are
+// updated instead of being duplicated, and they are skipped with a warning
+// when Prometheus sends its alerts to no Alertmanager.
+func Apply(p *monitoringv1.Prometheus, opts Options) ([]string, error) {
+	reserved := map[string]struct{}{
+		cmp.Or(ptr.Deref(p.Spec.PrometheusExternalLabelName, ""), defaultPrometheusExternalLabel): {},
+		cmp.Or(ptr.Deref(p.Spec.ReplicaExternalLabelName, ""), defaultReplicaExternalLabel):       {},
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(opts.ExternalLabels)) {
+		if _, ok := reserved[name]; ok {
+			return nil, fmt.Errorf("external label %q of Prometheus %s/%s is set by the Prometheus Operator", name, p.Namespace, p.Name)
+		}
+
+		if p.Spec.ExternalLabels == nil {
+			p.Spec.ExternalLabels = map[string]string{}
+		}
+		p.Spec.ExternalLabels[name] = opts.ExternalLabels[name]
+	}
+
+	if len(opts.AlertLabels) == 0 {
+		return nil, nil
+	}
+
+	if p.Spec.Alerting == nil || len(p.Spec.Alerting.Alertmanagers) == 0 {
+		return []string{"the alert labels aren't set as Prometheus sends its alerts to no Alertmanager"}, nil
+	}
+
+	for i := range p.Spec.Alerting.Alertmanagers {
+		am := &p.Spec.Alerting.Alertmanagers[i]
+		for _, name := range slices.Sorted(maps.Keys(opts.AlertLabels)) {
+			am.AlertRelabelConfigs = setLabel(am.AlertRelabelConfigs, name, opts.AlertLabels[name])
+		}
+	}
+
+	return nil, nil
+}
+
+// setLabel sets the label by the replace relabeling without source labels
+// targeting it, appending one when there is none.
+func setLabel(configs []monitoringv1.RelabelConfig, name, value string) []monitoringv1.RelabelConfig {
+	for i, c := range configs {
+		if c.TargetLabel == name && len(c.SourceLabels) == 0 && (c.Action == "" || c.Action == replaceAction) {
+			configs[i].Replacement = ptr.To(value)
+			return configs
+		}
+	}
+
+	return append(configs, monitoringv1.RelabelConfig{
+		Action:      replaceAction,
+		TargetLabel: name,
+		Replacement: ptr.To(value),
+	})
+}
+
+// diff returns the unified diff of the live and labeled Prometheus.
+func diff(live, labeled *monitoringv1.Prometheus) (string, error) {
+	var objs [2]map[string]any
+	for i, p := range []*monitoringv1.Prometheus{live, labeled} {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(p)
+		if err != nil {
+			return "", fmt.Errorf("error while converting Prometheus %s/%s: %w", p.Namespace, p.Name, err)
+		}
+		objs[i] = obj
+	}
+
+	return patch.Diff(objs[0], objs[1])
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labeling
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newPrometheus(namespace, name string, labels map[string]string, alertmanagers ...monitoringv1.AlertmanagerEndpoints) *monitoringv1.Prometheus {
+	p := &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+	}
+	if len(alertmanagers) > 0 {
+		p.Spec.Alerting = &monitoringv1.AlertingSpec{Alertmanagers: alertmanagers}
+	}
+	return p
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, Options{}.Validate())
+	assert.Error(t, Options{ExternalLabels: map[string]string{"cluster-name": "prod"}}.Validate())
+	assert.Error(t, Options{AlertLabels: map[string]string{"0tenant": "a"}}.Validate())
+	assert.NoError(t, Options{ExternalLabels: map[string]string{"cluster": "prod"}, AlertLabels: map[string]string{"tenant": "a"}}.Validate())
+}
+
+func TestApply(t *testing.T) {
+	type testCase struct {
+		name                   string
+		prometheus             *monitoringv1.Prometheus
+		opts                   Options
+		shouldFail             bool
+		expectedExternalLabels map[string]string
+		expectedRelabelings    [][]monitoringv1.RelabelConfig
+		expectedWarnings       int
+	}
+
+	tests := []testCase{
+		{
+			name:                   "ExternalLabels",
+			prometheus:             newPrometheus("default", "prom", nil),
+			opts:                   Options{ExternalLabels: map[string]string{"cluster": "prod"}},
+			expectedExternalLabels: map[string]string{"cluster": "prod"},
+		},
+		{
+			name: "ExternalLabelsMerged",
+			prometheus: func() *monitoringv1.Prometheus {
+				p := newPrometheus("default", "prom", nil)
+				p.Spec.ExternalLabels = map[string]string{"cluster": "dev", "region": "eu"}
+				return p
+			}(),
+			opts:                   Options{ExternalLabels: map[string]string{"cluster": "prod"}},
+			expectedExternalLabels: map[string]string{"cluster": "prod", "region": "eu"},
+		},
+		{
+			name:       "ReservedExternalLabel",
+			prometheus: newPrometheus("default", "prom", nil),
+			opts:       Options{ExternalLabels: map[string]string{"prometheus_replica": "a"}},
+			shouldFail: true,
+		},
+		{
+			name: "CustomReservedExternalLabel",
+			prometheus: func() *monitoringv1.Prometheus {
+				p := newPrometheus("default", "prom", nil)
+				p.Spec.PrometheusExternalLabelName = ptr.To("cluster")
+				return p
+			}(),
+			opts:       Options{ExternalLabels: map[string]string{"cluster": "prod"}},
+			shouldFail: true,
+		},
+		{
+			name: "AlertLabels",
+			prometheus: newPrometheus("default", "prom", nil,
+				monitoringv1.AlertmanagerEndpoints{
+					Name: "main",
+					AlertRelabelConfigs: []monitoringv1.RelabelConfig{
+						{Action: "labeldrop", Regex: "pod"},
+						{TargetLabel: "tenant", Replacement: ptr.To("old")},
+					},
+				},
+				monitoringv1.AlertmanagerEndpoints{Name: "backup"},
+			),
+			opts: Options{AlertLabels: map[string]string{"tenant": "team-a"}},
+			expectedRelabelings: [][]monitoringv1.RelabelConfig{
+				{
+					{Action: "labeldrop", Regex: "pod"},
+					{TargetLabel: "tenant", Replacement: ptr.To("team-a")},
+				},
+				{
+					{Action: "replace", TargetLabel: "tenant", Replacement: ptr.To("team-a")},
+				},
+			},
+		},
+		{
+			name:             "AlertLabelsWithoutAlertmanager",
+			prometheus:       newPrometheus("default", "prom", nil),
+			opts:             Options{AlertLabels: map[string]string{"tenant": "team-a"}},
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := Apply(tc.prometheus, tc.opts)
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Len(t, warnings, tc.expectedWarnings)
+			assert.Equal(t, tc.expectedExternalLabels, tc.prometheus.Spec.ExternalLabels)
+			for i, expected := range tc.expectedRelabelings {
+				assert.Equal(t, expected, tc.prometheus.Spec.Alerting.Alertmanagers[i].AlertRelabelConfigs)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tenant := map[string]string{"tenant": "team-a"}
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		newPrometheus("team-a", "prom", tenant),
+		newPrometheus("team-a", "labeled", tenant),
+		newPrometheus("team-b", "prom", map[string]string{"tenant": "team-b"}),
+	))
+
+	ctx := context.Background()
+	labeled, err := clientSets.MClient.MonitoringV1().Prometheuses("team-a").Get(ctx, "labeled", metav1.GetOptions{})
+	require.NoError(t, err)
+	labeled.Spec.ExternalLabels = map[string]string{"cluster": "prod"}
+	_, err = clientSets.MClient.MonitoringV1().Prometheuses("team-a").Update(ctx, labeled, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	opts := Options{ExternalLabels: map[string]string{"cluster": "prod"}}
+
+	results, err := Run(ctx, clientSets, "", "tenant=team-a", opts, true)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "labeled", results[0].Name)
+	assert.Empty(t, results[0].Diff)
+	assert.Equal(t, "prom", results[1].Name)
+	assert.Contains(t, results[1].Diff, "+    cluster: prod")
+
+	p, err := clientSets.MClient.MonitoringV1().Prometheuses("team-a").Get(ctx, "prom", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, p.Spec.ExternalLabels, "dry-run updated the Prometheus")
+
+	_, err = Run(ctx, clientSets, "", "tenant=team-a", opts, false)
+	require.NoError(t, err)
+
+	p, err = clientSets.MClient.MonitoringV1().Prometheuses("team-a").Get(ctx, "prom", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"cluster": "prod"}, p.Spec.ExternalLabels)
+
+	p, err = clientSets.MClient.MonitoringV1().Prometheuses("team-b").Get(ctx, "prom", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, p.Spec.ExternalLabels, "the Prometheus not selected was updated")
+}
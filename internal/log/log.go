@@ -16,6 +16,7 @@ package log
 
 import (
 	"errors"
+	"io"
 	"log/slog"
 	"os"
 
@@ -42,6 +43,12 @@ func parseLogLevel() (*slog.Level, error) {
 }
 
 func NewLogger() (*slog.Logger, error) {
+	return NewLoggerWithOutput(os.Stdout)
+}
+
+// NewLoggerWithOutput returns a logger writing to w, such as the standard
+// error when the standard output holds a report.
+func NewLoggerWithOutput(w io.Writer) (*slog.Logger, error) {
 	level, err := parseLogLevel()
 	if err != nil {
 		return nil, err
@@ -53,9 +60,9 @@ func NewLogger() (*slog.Logger, error) {
 	}
 	switch {
 	case logFormat == "text":
-		handler = slog.NewTextHandler(os.Stdout, handlerOptions)
+		handler = slog.NewTextHandler(w, handlerOptions)
 	case logFormat == "json":
-		handler = slog.NewJSONHandler(os.Stdout, handlerOptions)
+		handler = slog.NewJSONHandler(w, handlerOptions)
 	default:
 		return nil, errors.New("unknown log format")
 	}
@@ -0,0 +1,410 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package messages holds the catalog of the user-facing messages of the
+// analyzers. Each message has a stable ID which scripts and tests can rely
+// on while the wording evolves, and which allows localizing the catalog.
+package messages
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ID identifies a message of the catalog. IDs are never reused nor
+// renumbered.
+type ID string
+
+// Message is the text of a message, and the hint on how to address it for
+// warnings. Both are format strings taking the arguments of the message, the
+// hint picking the ones it uses by explicit index such as %[2]s.
+type Message struct {
+	Text string
+	Hint string
+}
+
+const (
+	// Generic findings, shared by the analyzers.
+	ObjectNotFound                ID = "PO001"
+	ObjectCompliant               ID = "PO002"
+	ServiceAccountNotBound        ID = "PO003"
+	SelectorNotProperlyDefined    ID = "PO004"
+	SelectorNotDefined            ID = "PO005"
+	NoNamespacesMatchSelector     ID = "PO006"
+	NoResourcesMatchSelector      ID = "PO007"
+	SecretKeyNotFound             ID = "PO008"
+	ConfigMapKeyNotFound          ID = "PO009"
+	ServiceAccountNotFound        ID = "PO010"
+	ObjectRejected                ID = "PO011"
+	ReplicasOnSameNode            ID = "PO101"
+	ReplicasInSameZone            ID = "PO102"
+	SecretNotReadable             ID = "PO103"
+	ServiceMonitorNoSelector      ID = "SM001"
+	ServiceMonitorNoServices      ID = "SM002"
+	ServiceMonitorNoPort          ID = "SM003"
+	ServiceMonitorInvalidTLS      ID = "SM004"
+	ServiceMonitorHTTPOnHTTPS     ID = "SM005"
+	ServiceMonitorTokenExpired    ID = "SM006"
+	EndpointHonorLabels           ID = "SM101"
+	EndpointHonorTimestamps       ID = "SM102"
+	EndpointDropsTargetLabel      ID = "SM103"
+	ServiceExternalName           ID = "SM104"
+	HeadlessServiceNoEndpoints    ID = "SM105"
+	EndpointTokenExpiring         ID = "SM106"
+	EndpointHTTPSWithoutTLSConfig ID = "SM107"
+	OperatorNotBoundInNamespace   ID = "OP001"
+	OperatorMissingAPIGroup       ID = "OP002"
+	OperatorMissingResource       ID = "OP003"
+	OperatorMissingVerbs          ID = "OP004"
+	AdminAPIExposed               ID = "PR101"
+	RemoteWriteReceiverExposed    ID = "PR102"
+	ListenLocalExposed            ID = "PR103"
+	ScrapeIntervalAboveStaleness  ID = "PR104"
+	ScrapeIntervalAboveRetention  ID = "PR105"
+	ScrapeTimeoutAboveInterval    ID = "PR106"
+	ScrapeIntervalInconsistent    ID = "PR107"
+	AlertmanagerSecretNotFound    ID = "AM001"
+	AlertmanagerSecretEmpty       ID = "AM002"
+	AlertmanagerSecretKeyMissing  ID = "AM003"
+	AlertmanagerConfigNotFound    ID = "AM004"
+	AlertmanagerConfigsNoMatch    ID = "AM005"
+	AMConfigUnknownReceiver       ID = "AC001"
+	AMConfigInvalidRoute          ID = "AC002"
+	AMConfigUnknownTimeInterval   ID = "AC003"
+	AMConfigInvalidReference      ID = "AC004"
+	AMConfigNotSelected           ID = "AC005"
+	AMConfigDuplicateReceiver     ID = "AC006"
+	AMConfigUnusedReceiver        ID = "AC101"
+	AMConfigWithoutRoute          ID = "AC102"
+	ReceiverWithoutIntegration    ID = "AM101"
+	RouteReceiverNotDefined       ID = "AM102"
+	RouteUnreachable              ID = "AM103"
+	MatcherLabelNotProduced       ID = "AM104"
+	MatcherValueNotProduced       ID = "AM105"
+	AgentDaemonSetNotFound        ID = "PA001"
+	AgentSelectorsIgnored         ID = "PA101"
+	AgentPodsNotReady             ID = "PA102"
+	ScrapeConfigNotSelected       ID = "SC001"
+	ScrapeConfigDiscoveryRBAC     ID = "SC002"
+	ScrapeConfigInvalidReference  ID = "SC003"
+	ScrapeConfigInvalidRegex      ID = "SC004"
+	ScrapeConfigUnknownRole       ID = "SC005"
+	ScrapeConfigInvalidNamespace  ID = "SC006"
+	ScrapeConfigInvalidSelector   ID = "SC007"
+	ScrapeConfigNamespaceMissing  ID = "SC101"
+	ScrapeConfigNamespaceIgnored  ID = "SC102"
+	OverlappingConfigurations     ID = "OV001"
+	LivenessProbeMissing          ID = "WL101"
+	ReadinessProbeMissing         ID = "WL102"
+	ListenAddressIPv4Only         ID = "WL103"
+	ListenAddressInvalid          ID = "WL104"
+	ThanosNotConfigured           ID = "TH001"
+	ThanosSidecarMissing          ID = "TH002"
+	ThanosObjectStorageInvalid    ID = "TH003"
+	ThanosObjectStorageMissing    ID = "TH101"
+	ThanosSidecarNotExposed       ID = "TH102"
+	ThanosSidecarNotMonitored     ID = "TH103"
+	ThanosDuplicateLabels         ID = "TH104"
+	ThanosReplicaLabelDisabled    ID = "TH105"
+	RuleInvalidExpression         ID = "RU001"
+	RuleInvalidTemplate           ID = "RU002"
+	RuleNotSelected               ID = "RU003"
+	RuleDuplicated                ID = "RU101"
+	PodMonitorNoSelector          ID = "PM001"
+	PodMonitorNoPods              ID = "PM002"
+	PodMonitorNoPort              ID = "PM003"
+	PodMonitorNotSelected         ID = "PM004"
+	ProbeProberNotFound           ID = "PB001"
+	ProbeProberNoPort             ID = "PB002"
+	ProbeNoTargets                ID = "PB003"
+	ProbeNotSelected              ID = "PB004"
+	ProbeProberOutsideCluster     ID = "PB101"
+	ThanosRulerServiceAccount     ID = "TR001"
+	ThanosRulerNoRules            ID = "TR002"
+	ThanosRulerNoQuery            ID = "TR003"
+	ThanosRulerInvalidReference   ID = "TR004"
+	ThanosRulerInvalidAMURL       ID = "TR005"
+	ThanosRulerNoAlertmanager     ID = "TR101"
+)
+
+// catalog is the English catalog, the default one.
+var catalog = map[ID]Message{
+	ObjectNotFound:             {Text: "%s %s not found in namespace %s"},
+	ObjectCompliant:            {Text: "%s is compliant, no issues found"},
+	ServiceAccountNotBound:     {Text: "ServiceAccount %s is not bound to any RoleBindings"},
+	SelectorNotProperlyDefined: {Text: "%s is not properly defined: %s"},
+	SelectorNotDefined:         {Text: "%s selector is not defined"},
+	NoNamespacesMatchSelector:  {Text: "no namespaces match the selector %s%s"},
+	NoResourcesMatchSelector:   {Text: "no %s match the provided selector in Prometheus %s"},
+	SecretKeyNotFound:          {Text: "key %s not found in Secret %s in namespace %s"},
+	ConfigMapKeyNotFound:       {Text: "key %s not found in ConfigMap %s in namespace %s"},
+	ServiceAccountNotFound:     {Text: "alertmanager serviceaccount not found in namespace %s"},
+	ObjectRejected:             {Text: "%s %s in namespace %s is rejected by the API server: %s"},
+	ReplicasOnSameNode: {
+		Text: "%d replicas of %s %s run on node %s",
+		Hint: "a failure of node %[4]s takes down %[1]d replicas at once, spread them with a podAntiAffinity or a topologySpreadConstraint on the kubernetes.io/hostname topology key",
+	},
+	ReplicasInSameZone: {
+		Text: "%d replicas of %s %s run in zone %s",
+		Hint: "an outage of zone %[4]s takes down %[1]d replicas at once, spread them with a topologySpreadConstraint on the topology.kubernetes.io/zone topology key",
+	},
+	SecretNotReadable: {
+		Text: "Secret %s in namespace %s can't be read, the checks of its content are skipped",
+		Hint: "grant the get verb on the Secrets of namespace %[2]s to the identity running the analysis, the verifier is granted it with poctl install verifier --read-secrets",
+	},
+	ServiceMonitorNoSelector:   {Text: "ServiceMonitor %s in namespace %s does not have a selector"},
+	ServiceMonitorNoServices:   {Text: "ServiceMonitor %s in namespace %s has no services matching the selector in %s"},
+	ServiceMonitorNoPort:       {Text: "ServiceMonitor %s in namespace %s has no services with %s, candidates: %s"},
+	ServiceMonitorInvalidTLS:   {Text: "ServiceMonitor %s in namespace %s has an invalid tlsConfig.%s for %s: %v"},
+	ServiceMonitorHTTPOnHTTPS:  {Text: "ServiceMonitor %s in namespace %s scrapes port %s of service %s over http but the port serves https"},
+	ServiceMonitorTokenExpired: {Text: "the token of Secret %s in namespace %s authorizing %s expired on %s, request a new one with poctl create servicemonitor --secure"},
+	EndpointHonorLabels: {
+		Text: "honorLabels is enabled for port %s",
+		Hint: "labels exposed by the target override the target labels (job, instance, namespace...), only enable honorLabels for trusted sources such as federation or the Pushgateway",
+	},
+	EndpointHonorTimestamps: {
+		Text: "honorTimestamps is disabled for port %s",
+		Hint: "federated and pushed samples carry their own timestamps, disabling honorTimestamps assigns them the scrape time instead",
+	},
+	EndpointDropsTargetLabel: {
+		Text: "metricRelabelings drop the %s label for port %s",
+		Hint: "series without the %[1]s label collide with each other across targets, keep it or replace it with an equivalent label",
+	},
+	ServiceExternalName: {
+		Text: "Service %s in namespace %s is an ExternalName Service pointing to %s",
+		Hint: "ExternalName Services have no endpoints, so the ServiceMonitor scrapes nothing from %[1]s, scrape the external name %[3]s with a ScrapeConfig, or probe it with a Probe",
+	},
+	HeadlessServiceNoEndpoints: {
+		Text: "headless Service %s in namespace %s has no endpoints",
+		Hint: "the ServiceMonitor scrapes nothing from %[1]s until its endpoints are published, check that its selector matches ready Pods, or that its Endpoints are maintained when it has no selector, otherwise scrape the targets with a ScrapeConfig or a Probe",
+	},
+	EndpointTokenExpiring: {
+		Text: "the token of Secret %s in namespace %s authorizing %s expires on %s",
+		Hint: "the token isn't renewed and the scrapes of %[3]s fail with 401 once it expires, request a new token with poctl create servicemonitor --secure before",
+	},
+	EndpointHTTPSWithoutTLSConfig: {
+		Text: "the https scheme is used without tlsConfig for %s",
+		Hint: "the certificates of the targets are verified against the system CAs of Prometheus, which don't include the CAs of the cluster, set tlsConfig.ca to the CA signing them",
+	},
+	OperatorNotBoundInNamespace: {Text: "ServiceAccount %s is not bound to any RoleBindings in watched namespace %s"},
+	OperatorMissingAPIGroup:     {Text: "%s %s does not have monitoring.coreos.com APIGroup in its rules"},
+	OperatorMissingResource:     {Text: "%s %s does not have %s in its rules"},
+	OperatorMissingVerbs:        {Text: "ServiceAccount %s of operator version %s is not allowed to %s %s%s"},
+	AdminAPIExposed: {
+		Text: "enableAdminAPI is enabled without authentication nor NetworkPolicy",
+		Hint: "the admin API allows deleting series and shutting down the TSDB, require client certificates in web.tlsConfig or restrict the ingress traffic with a NetworkPolicy",
+	},
+	RemoteWriteReceiverExposed: {
+		Text: "enableRemoteWriteReceiver is enabled without authentication nor NetworkPolicy",
+		Hint: "any client reaching the web port can push arbitrary series, require client certificates in web.tlsConfig or restrict the ingress traffic with a NetworkPolicy",
+	},
+	ListenLocalExposed: {
+		Text: "listenLocal is enabled but Service %s exposes the web port",
+		Hint: "Prometheus only listens on localhost so the Service can't reach it, remove the port from the Service or disable listenLocal",
+	},
+	ScrapeIntervalAboveStaleness: {
+		Text: "%s scrapes every %s, beyond the 5m staleness window",
+		Hint: "series are marked stale 5m after their last sample, so they show gaps and the alerts on them resolve and fire again between scrapes, scrape at least every 2m",
+	},
+	ScrapeIntervalAboveRetention: {
+		Text: "%s scrapes every %s, beyond the retention %s of Prometheus",
+		Hint: "samples are deleted before the next scrape, shorten the interval or extend the retention",
+	},
+	ScrapeTimeoutAboveInterval: {
+		Text: "%s has a scrapeTimeout of %s, longer than its interval %s",
+		Hint: "Prometheus rejects a scrape timeout longer than the scrape interval and fails to reload its configuration, set the scrapeTimeout to at most the interval",
+	},
+	ScrapeIntervalInconsistent: {
+		Text: "%s scrapes every %s, more than twice the scrapeInterval %s of Prometheus",
+		Hint: "rate() windows sized for the scrapeInterval of Prometheus hold less than 2 samples of these series and return nothing, use windows of at least 4x %[2]s or align the intervals",
+	},
+	AlertmanagerSecretNotFound:   {Text: "failed to get alertmanager secret %s not found in namespace %s"},
+	AlertmanagerSecretEmpty:      {Text: "alertmanager Secret %s is empty"},
+	AlertmanagerSecretKeyMissing: {Text: "the %s key not found in Secret %s"},
+	AlertmanagerConfigNotFound:   {Text: "alertmanagerConfigs not found in namespace %s"},
+	AlertmanagerConfigsNoMatch:   {Text: "no AlertmanagerConfigs match the provided selector in %s"},
+	AMConfigUnknownReceiver:      {Text: "%s of AlertmanagerConfig %s in namespace %s references the receiver %s which isn't defined"},
+	AMConfigInvalidRoute:         {Text: "%s of AlertmanagerConfig %s in namespace %s is invalid: %v"},
+	AMConfigUnknownTimeInterval:  {Text: "%s of AlertmanagerConfig %s in namespace %s references the time interval %s which isn't defined"},
+	AMConfigInvalidReference:     {Text: "invalid %s of AlertmanagerConfig %s in namespace %s: %v"},
+	AMConfigNotSelected:          {Text: "AlertmanagerConfig %s in namespace %s isn't selected by any Alertmanager"},
+	AMConfigDuplicateReceiver:    {Text: "receiver %s is defined several times in AlertmanagerConfig %s in namespace %s"},
+	AMConfigUnusedReceiver: {
+		Text: "receiver %s isn't referenced by any route",
+		Hint: "no alert is ever sent to %[1]s, reference it from a route or remove it",
+	},
+	AMConfigWithoutRoute: {
+		Text: "AlertmanagerConfig has no route",
+		Hint: "the receivers of an AlertmanagerConfig without route never get any alert, add a route sending the alerts to one of them",
+	},
+	ReceiverWithoutIntegration: {
+		Text: "receiver %s has no integration configured",
+		Hint: "the alerts routed to %[1]s are silently dropped, add an integration such as webhook_configs unless it's meant to discard them",
+	},
+	RouteReceiverNotDefined: {
+		Text: "%s routes to receiver %s which isn't defined",
+		Hint: "Alertmanager rejects the configuration and keeps running the previous one, define the receiver or fix its name",
+	},
+	RouteUnreachable: {
+		Text: "%s is unreachable, %s before it matches all its alerts",
+		Hint: "the alerts stop at the first matching route, move %[1]s before %[2]s or set continue: true on %[2]s",
+	},
+	MatcherLabelNotProduced: {
+		Text: "%s matches on label %s which no alerting rule produces",
+		Hint: "the route never matches, check the name of the label against the labels of the alerting rules and the external labels of Prometheus",
+	},
+	MatcherValueNotProduced: {
+		Text: "%s matches %s but no alerting rule sets this value of %s",
+		Hint: "the route never matches, check the value against the labels of the alerting rules",
+	},
+	AgentDaemonSetNotFound:       {Text: "DaemonSet %s not found in namespace %s, check that the PrometheusAgentDaemonSet feature gate is enabled in the operator"},
+	AgentSelectorsIgnored:        {Text: "serviceMonitorSelector, probeSelector and scrapeConfigSelector are ignored in DaemonSet mode, only PodMonitors are discovered"},
+	AgentPodsNotReady:            {Text: "%d of the %d PrometheusAgent pods are ready"},
+	ScrapeConfigNotSelected:      {Text: "ScrapeConfig %s isn't selected by any Prometheus or PrometheusAgent"},
+	ScrapeConfigDiscoveryRBAC:    {Text: "kubernetesSDConfigs[%d] with role %s: ServiceAccount %s of %s %s/%s can't list and watch %s%s, the discovery would silently find no targets"},
+	ScrapeConfigInvalidReference: {Text: "invalid %s of ScrapeConfig %s in namespace %s: %v"},
+	ScrapeConfigInvalidRegex:     {Text: "%s[%d] of ScrapeConfig %s in namespace %s has an invalid regex: %v"},
+	ScrapeConfigUnknownRole:      {Text: "kubernetesSDConfigs[%d] of ScrapeConfig %s in namespace %s has the unknown role %s, expected one of %s"},
+	ScrapeConfigInvalidNamespace: {Text: "kubernetesSDConfigs[%d] of ScrapeConfig %s in namespace %s discovers the invalid namespace %q: %s"},
+	ScrapeConfigInvalidSelector:  {Text: "kubernetesSDConfigs[%d].selectors[%d] of ScrapeConfig %s in namespace %s has an invalid %s selector: %v"},
+	ScrapeConfigNamespaceMissing: {
+		Text: "kubernetesSDConfigs[%d] discovers the namespace %s which doesn't exist",
+		Hint: "the discovery finds no targets in %[2]s until the namespace is created, check the names of the namespaces",
+	},
+	ScrapeConfigNamespaceIgnored: {
+		Text: "kubernetesSDConfigs[%d] with role node sets namespaces, which are ignored",
+		Hint: "the nodes are cluster-scoped and always discovered cluster-wide, remove the namespaces",
+	},
+	OverlappingConfigurations: {Text: "prometheus %s in namespace %s has overlapping configurations: %s"},
+	LivenessProbeMissing: {
+		Text: "container %[3]s of %[1]s %[2]s has no livenessProbe",
+		Hint: "the container is never restarted when its process deadlocks and stays unresponsive until deleted by hand, add a livenessProbe on its health endpoint",
+	},
+	ReadinessProbeMissing: {
+		Text: "container %[3]s of %[1]s %[2]s has no readinessProbe",
+		Hint: "the Pod receives traffic as soon as the container starts and while it can't serve, add a readinessProbe on its readiness endpoint",
+	},
+	ListenAddressIPv4Only: {
+		Text: "container %[3]s of %[1]s %[2]s listens on the IPv4 address %[4]s but Service %[5]s is dual-stack",
+		Hint: "the connections to the IPv6 addresses of the Pods are refused, listen on all the families with an address without host such as :9100, or on [::]",
+	},
+	ListenAddressInvalid: {
+		Text: "container %[3]s of %[1]s %[2]s has the invalid listen address %[4]s",
+		Hint: "the listen addresses are host:port addresses, the IPv6 hosts being enclosed in brackets such as [::1]:9100",
+	},
+	ThanosNotConfigured:        {Text: "Prometheus %s in namespace %s has no thanos field, the Thanos sidecar isn't deployed"},
+	ThanosSidecarMissing:       {Text: "StatefulSet %s of Prometheus %s has no thanos-sidecar container"},
+	ThanosObjectStorageInvalid: {Text: "key %s of Secret %s holds an invalid object storage configuration: %v"},
+	ThanosObjectStorageMissing: {
+		Text: "Prometheus %s has no object storage configuration, the Thanos sidecar doesn't upload its blocks",
+		Hint: "the data is only queryable through Thanos as long as Prometheus retains it, set thanos.objectStorageConfig to a Secret key holding the configuration of the bucket",
+	},
+	ThanosSidecarNotExposed: {
+		Text: "no Service exposes the gRPC port of the Thanos sidecar of Prometheus %s",
+		Hint: "Thanos Query discovers the sidecars through the DNS records of a Service, create a headless Service selecting the Prometheus Pods with the grpc port 10901",
+	},
+	ThanosSidecarNotMonitored: {
+		Text: "no ServiceMonitor scrapes the metrics of the Thanos sidecar of Prometheus %s",
+		Hint: "the failed uploads of the sidecar go unnoticed, create a ServiceMonitor selecting a Service which exposes the http port 10902 of the sidecar",
+	},
+	ThanosDuplicateLabels: {
+		Text: "Prometheus %s has the same external labels %s as Prometheus %s",
+		Hint: "Thanos Query merges their series as if they were replicas and the compactor mixes their blocks, add an external label telling them apart",
+	},
+	ThanosReplicaLabelDisabled: {
+		Text: "replicaExternalLabelName is disabled on Prometheus %s with %d replicas",
+		Hint: "the replicas upload overlapping blocks with the same external labels, keep the replica label and set it as a replica label of Thanos Query and of the compactor to deduplicate them",
+	},
+	RuleInvalidExpression: {Text: "%s has an invalid expression: %v"},
+	RuleInvalidTemplate:   {Text: "%s has an invalid template in %s %s: %v"},
+	RuleNotSelected:       {Text: "PrometheusRule %s in namespace %s isn't selected by any Prometheus nor ThanosRuler"},
+	RuleDuplicated: {
+		Text: "%s has the same name and labels as %s",
+		Hint: "both rules produce the same series, which collide, or the same alerts, which can't be told apart, merge them or set a label telling them apart such as severity",
+	},
+	PodMonitorNoSelector:  {Text: "PodMonitor %s in namespace %s does not have a selector"},
+	PodMonitorNoPods:      {Text: "PodMonitor %s in namespace %s has no pods matching the selector in %s"},
+	PodMonitorNoPort:      {Text: "PodMonitor %s in namespace %s has no pods with %s, candidates: %s"},
+	PodMonitorNotSelected: {Text: "PodMonitor %s in namespace %s isn't selected by any Prometheus nor PrometheusAgent"},
+	ProbeProberNotFound:   {Text: "Probe %s in namespace %s uses the prober %s but Service %s isn't found in %s"},
+	ProbeProberNoPort:     {Text: "Probe %s in namespace %s uses the prober %s but Service %s in namespace %s has no port %d"},
+	ProbeNoTargets:        {Text: "Probe %s in namespace %s has no targets: %s"},
+	ProbeNotSelected:      {Text: "Probe %s in namespace %s isn't selected by any Prometheus nor PrometheusAgent"},
+	ProbeProberOutsideCluster: {
+		Text: "prober %s isn't a Service of the cluster",
+		Hint: "poctl can't check that Prometheus reaches the prober, use the <service>.<namespace>.svc name of the Service of the blackbox exporter when it runs in the cluster",
+	},
+	ThanosRulerServiceAccount:   {Text: "ServiceAccount %s of ThanosRuler %s not found in namespace %s"},
+	ThanosRulerNoRules:          {Text: "ThanosRuler %s in namespace %s selects no PrometheusRule"},
+	ThanosRulerNoQuery:          {Text: "ThanosRuler %s in namespace %s has neither queryEndpoints nor queryConfig"},
+	ThanosRulerInvalidReference: {Text: "invalid %s of ThanosRuler %s in namespace %s: %v"},
+	ThanosRulerInvalidAMURL:     {Text: "alertmanagersUrl %s of ThanosRuler %s in namespace %s is invalid: %v"},
+	ThanosRulerNoAlertmanager: {
+		Text: "ThanosRuler sends its alerts to no Alertmanager",
+		Hint: "the alerts evaluated by the ThanosRuler are dropped, set alertmanagersUrl or alertmanagersConfig",
+	},
+}
+
+// Text returns the text of the message with its arguments.
+func Text(id ID, args ...any) string {
+	return fmt.Sprintf(catalog[id].Text, args...)
+}
+
+// Hint returns the hint of the message with its arguments, empty when the
+// message has none. The hints without verbs are returned as is, since fmt
+// reports the arguments they don't use.
+func Hint(id ID, args ...any) string {
+	hint := catalog[id].Hint
+	if !strings.Contains(hint, "%") {
+		return hint
+	}
+	return fmt.Sprintf(hint, args...)
+}
+
+// Error is a finding reported as an error, carrying the ID of its message.
+type Error struct {
+	ID   ID
+	Args []any
+}
+
+// New returns the error of the message with its arguments.
+func New(id ID, args ...any) error {
+	return &Error{ID: id, Args: args}
+}
+
+func (e *Error) Error() string {
+	return Text(e.ID, e.Args...)
+}
+
+// Unwrap returns the errors given as arguments of the message.
+func (e *Error) Unwrap() []error {
+	var errs []error
+	for _, arg := range e.Args {
+		if err, ok := arg.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// IDOf returns the ID of the outermost message of the error chain, false
+// when the error doesn't come from the catalog.
+func IDOf(err error) (ID, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.ID, true
+	}
+	return "", false
+}
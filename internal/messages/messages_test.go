@@ -0,0 +1,203 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messages
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogIDs(t *testing.T) {
+	re := regexp.MustCompile(`^[A-Z]{2}[0-9]{3}$`)
+	for id, msg := range catalog {
+		assert.Regexp(t, re, string(id))
+		assert.NotEmpty(t, msg.Text, "message %s has no text", id)
+	}
+}
+
+// arities are the numbers of arguments the messages are given at their call
+// sites.
+var arities = map[ID]int{
+	ObjectNotFound:                3,
+	ObjectCompliant:               1,
+	ServiceAccountNotBound:        1,
+	SelectorNotProperlyDefined:    2,
+	SelectorNotDefined:            1,
+	NoNamespacesMatchSelector:     2,
+	NoResourcesMatchSelector:      2,
+	SecretKeyNotFound:             3,
+	ConfigMapKeyNotFound:          3,
+	ServiceAccountNotFound:        1,
+	ObjectRejected:                4,
+	ReplicasOnSameNode:            4,
+	ReplicasInSameZone:            4,
+	SecretNotReadable:             2,
+	ServiceMonitorNoSelector:      2,
+	ServiceMonitorNoServices:      3,
+	ServiceMonitorNoPort:          4,
+	ServiceMonitorInvalidTLS:      5,
+	ServiceMonitorHTTPOnHTTPS:     4,
+	ServiceMonitorTokenExpired:    4,
+	EndpointHonorLabels:           1,
+	EndpointHonorTimestamps:       1,
+	EndpointDropsTargetLabel:      2,
+	ServiceExternalName:           3,
+	HeadlessServiceNoEndpoints:    2,
+	EndpointTokenExpiring:         4,
+	EndpointHTTPSWithoutTLSConfig: 1,
+	OperatorNotBoundInNamespace:   2,
+	OperatorMissingAPIGroup:       2,
+	OperatorMissingResource:       3,
+	OperatorMissingVerbs:          5,
+	AdminAPIExposed:               0,
+	RemoteWriteReceiverExposed:    0,
+	ListenLocalExposed:            1,
+	ScrapeIntervalAboveStaleness:  2,
+	ScrapeIntervalAboveRetention:  3,
+	ScrapeTimeoutAboveInterval:    3,
+	ScrapeIntervalInconsistent:    3,
+	AlertmanagerSecretNotFound:    2,
+	AlertmanagerSecretEmpty:       1,
+	AlertmanagerSecretKeyMissing:  2,
+	AlertmanagerConfigNotFound:    1,
+	AlertmanagerConfigsNoMatch:    1,
+	AMConfigUnknownReceiver:       4,
+	AMConfigInvalidRoute:          4,
+	AMConfigUnknownTimeInterval:   4,
+	AMConfigInvalidReference:      4,
+	AMConfigNotSelected:           2,
+	AMConfigDuplicateReceiver:     3,
+	AMConfigUnusedReceiver:        1,
+	AMConfigWithoutRoute:          0,
+	ReceiverWithoutIntegration:    1,
+	RouteReceiverNotDefined:       2,
+	RouteUnreachable:              2,
+	MatcherLabelNotProduced:       2,
+	MatcherValueNotProduced:       3,
+	AgentDaemonSetNotFound:        2,
+	AgentSelectorsIgnored:         0,
+	AgentPodsNotReady:             2,
+	ScrapeConfigNotSelected:       1,
+	ScrapeConfigDiscoveryRBAC:     8,
+	ScrapeConfigInvalidReference:  4,
+	ScrapeConfigInvalidRegex:      5,
+	ScrapeConfigUnknownRole:       5,
+	ScrapeConfigInvalidNamespace:  5,
+	ScrapeConfigInvalidSelector:   6,
+	ScrapeConfigNamespaceMissing:  2,
+	ScrapeConfigNamespaceIgnored:  1,
+	OverlappingConfigurations:     3,
+	LivenessProbeMissing:          3,
+	ReadinessProbeMissing:         3,
+	ListenAddressIPv4Only:         5,
+	ListenAddressInvalid:          4,
+	ThanosNotConfigured:           2,
+	ThanosSidecarMissing:          2,
+	ThanosObjectStorageInvalid:    3,
+	ThanosObjectStorageMissing:    1,
+	ThanosSidecarNotExposed:       1,
+	ThanosSidecarNotMonitored:     1,
+	ThanosDuplicateLabels:         3,
+	ThanosReplicaLabelDisabled:    2,
+	RuleInvalidExpression:         2,
+	RuleInvalidTemplate:           4,
+	RuleNotSelected:               2,
+	RuleDuplicated:                2,
+	PodMonitorNoSelector:          2,
+	PodMonitorNoPods:              3,
+	PodMonitorNoPort:              4,
+	PodMonitorNotSelected:         2,
+	ProbeProberNotFound:           5,
+	ProbeProberNoPort:             6,
+	ProbeNoTargets:                3,
+	ProbeNotSelected:              2,
+	ProbeProberOutsideCluster:     1,
+	ThanosRulerServiceAccount:     3,
+	ThanosRulerNoRules:            2,
+	ThanosRulerNoQuery:            2,
+	ThanosRulerInvalidReference:   4,
+	ThanosRulerInvalidAMURL:       4,
+	ThanosRulerNoAlertmanager:     0,
+}
+
+// verbRegexp matches the verbs of the format strings, with their optional
+// explicit argument index.
+var verbRegexp = regexp.MustCompile(`%(?:\[(\d+)\])?([a-z])`)
+
+// TestCatalogArguments formats every message with the number of arguments
+// of its call sites, which must all be used by the text and be valid for the
+// hint.
+func TestCatalogArguments(t *testing.T) {
+	for id, msg := range catalog {
+		n, ok := arities[id]
+		if !assert.True(t, ok, "message %s has no arity", id) {
+			continue
+		}
+
+		// The arguments get the type of the verb of the text using them.
+		args := make([]any, n)
+		for i := range args {
+			args[i] = "arg"
+		}
+		next := 0
+		for _, m := range verbRegexp.FindAllStringSubmatch(msg.Text, -1) {
+			i := next
+			if m[1] != "" {
+				i, _ = strconv.Atoi(m[1])
+				i--
+			}
+			next = i + 1
+			if i < n && m[2] == "d" {
+				args[i] = 1
+			}
+		}
+
+		assert.NotContains(t, Text(id, args...), "%!", "text of message %s", id)
+		assert.NotContains(t, Hint(id, args...), "%!", "hint of message %s", id)
+
+		for _, m := range verbRegexp.FindAllStringSubmatch(msg.Hint, -1) {
+			assert.NotEmpty(t, m[1], "hint of message %s uses a verb without explicit index", id)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "ServiceMonitor app not found in namespace default", Text(ObjectNotFound, "ServiceMonitor", "app", "default"))
+	assert.Equal(t, "metricRelabelings drop the instance label for port web", Text(EndpointDropsTargetLabel, "instance", "web"))
+	assert.Equal(t, "series without the instance label collide with each other across targets, keep it or replace it with an equivalent label", Hint(EndpointDropsTargetLabel, "instance", "web"))
+	assert.Empty(t, Hint(ObjectNotFound, "ServiceMonitor", "app", "default"))
+	assert.Equal(t, "the admin API allows deleting series and shutting down the TSDB, require client certificates in web.tlsConfig or restrict the ingress traffic with a NetworkPolicy", Hint(AdminAPIExposed))
+	assert.Equal(t, "labels exposed by the target override the target labels (job, instance, namespace...), only enable honorLabels for trusted sources such as federation or the Pushgateway", Hint(EndpointHonorLabels, "web"))
+}
+
+func TestIDOf(t *testing.T) {
+	inner := New(NoNamespacesMatchSelector, "team=a", "")
+	outer := New(SelectorNotProperlyDefined, "serviceMonitorNamespaceSelector", inner)
+
+	id, ok := IDOf(fmt.Errorf("context prod: %w", outer))
+	require.True(t, ok)
+	assert.Equal(t, SelectorNotProperlyDefined, id)
+	assert.ErrorIs(t, outer, inner)
+	assert.EqualError(t, outer, "serviceMonitorNamespaceSelector is not properly defined: no namespaces match the selector team=a")
+
+	_, ok = IDOf(errors.New("error while listing services"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// onboardingSelector selects the RBAC objects created by the onboarding,
+// labeled by builder.OnboardingBuilder.
+const onboardingSelector = "app.kubernetes.io/name=onboarding"
+
+// monitoringResources are the monitoring.coreos.com resources deleted by the
+// offboarding, the monitors and the rules before the workloads using them.
+var monitoringResources = []schema.GroupVersionResource{
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "podmonitors"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "probes"},
+	{Group: "monitoring.coreos.com", Version: "v1alpha1", Resource: "scrapeconfigs"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"},
+	{Group: "monitoring.coreos.com", Version: "v1alpha1", Resource: "alertmanagerconfigs"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheuses"},
+	{Group: "monitoring.coreos.com", Version: "v1alpha1", Resource: "prometheusagents"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "alertmanagers"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "thanosrulers"},
+}
+
+var (
+	roleResource        = rbacv1.SchemeGroupVersion.WithResource("roles")
+	roleBindingResource = rbacv1.SchemeGroupVersion.WithResource("rolebindings")
+)
+
+// Object is an object of the namespace deleted by the offboarding.
+type Object struct {
+	Resource schema.GroupVersionResource
+	Kind     string
+	Name     string
+}
+
+func (o Object) String() string {
+	return o.Kind + " " + o.Name
+}
+
+// Offboarding is the cleanup of the monitoring of a namespace.
+type Offboarding struct {
+	Namespace string
+	// Objects are the monitoring.coreos.com objects of the namespace, and the
+	// RBAC created by the onboarding.
+	Objects []Object
+	// Labels are the labels of the namespace matching the namespace
+	// selectors of the Prometheus, PrometheusAgent, Alertmanager and
+	// ThanosRuler resources of the other namespaces.
+	Labels []string
+	// Leftovers are the objects out of the namespace referencing it, which
+	// the offboarding leaves untouched.
+	Leftovers []string
+	// Warnings are the namespace selectors which keep selecting the
+	// namespace once its labels are removed.
+	Warnings []string
+}
+
+// namespaceSelectorOf is a namespace selector of a resource.
+type namespaceSelectorOf struct {
+	owner    string
+	field    string
+	selector *metav1.LabelSelector
+}
+
+// PlanOffboarding lists what the offboarding of the namespace deletes, and
+// what it leaves behind.
+func PlanOffboarding(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) (*Offboarding, error) {
+	ns, err := clientSets.KClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while getting namespace %s: %w", namespace, err)
+	}
+
+	o := &Offboarding{Namespace: namespace}
+	for _, gvr := range append(slices.Clone(monitoringResources), roleBindingResource, roleResource) {
+		opts := metav1.ListOptions{}
+		if gvr.Group == rbacv1.GroupName {
+			opts.LabelSelector = onboardingSelector
+		}
+
+		err := k8sutil.EachListItem(ctx, gvr.Resource, clientSets.DClient.Resource(gvr).Namespace(namespace).List, opts, func(obj *unstructured.Unstructured) error {
+			o.Objects = append(o.Objects, Object{Resource: gvr, Kind: obj.GetKind(), Name: obj.GetName()})
+			return nil
+		})
+		// The CRDs may not be installed.
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	selectors, err := namespaceSelectors(ctx, clientSets, namespace)
+	if err != nil {
+		return nil, err
+	}
+	o.Labels, o.Warnings = selectedLabels(ns, selectors)
+
+	if o.Leftovers, err = leftovers(ctx, clientSets, namespace); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Offboard deletes the objects of the offboarding and removes the labels
+// from the namespace. The objects deleted in the meantime are skipped.
+func Offboard(ctx context.Context, clientSets *k8sutil.ClientSets, o *Offboarding) error {
+	for _, obj := range o.Objects {
+		err := clientSets.DClient.Resource(obj.Resource).Namespace(o.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error while deleting %s %s/%s: %w", obj.Kind, o.Namespace, obj.Name, err)
+		}
+	}
+
+	if len(o.Labels) == 0 {
+		return nil
+	}
+
+	// A null value removes the label with a merge patch.
+	removed := map[string]any{}
+	for _, label := range o.Labels {
+		removed[label] = nil
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"labels": removed}})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientSets.KClient.CoreV1().Namespaces().Patch(ctx, o.Namespace, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: k8sutil.ApplyOption.FieldManager})
+	if err != nil {
+		return fmt.Errorf("error while removing the labels of namespace %s: %w", o.Namespace, err)
+	}
+	return nil
+}
+
+// namespaceSelectors returns the namespace selectors of the Prometheus,
+// PrometheusAgent, Alertmanager and ThanosRuler resources out of the
+// namespace, which are deleted with it otherwise.
+func namespaceSelectors(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) ([]namespaceSelectorOf, error) {
+	var selectors []namespaceSelectorOf
+	common := func(owner string, spec monitoringv1.CommonPrometheusFields) {
+		selectors = append(selectors,
+			namespaceSelectorOf{owner, "serviceMonitorNamespaceSelector", spec.ServiceMonitorNamespaceSelector},
+			namespaceSelectorOf{owner, "podMonitorNamespaceSelector", spec.PodMonitorNamespaceSelector},
+			namespaceSelectorOf{owner, "probeNamespaceSelector", spec.ProbeNamespaceSelector},
+			namespaceSelectorOf{owner, "scrapeConfigNamespaceSelector", spec.ScrapeConfigNamespaceSelector},
+		)
+	}
+
+	err := k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		if p.Namespace == namespace {
+			return nil
+		}
+		owner := fmt.Sprintf("Prometheus %s/%s", p.Namespace, p.Name)
+		common(owner, p.Spec.CommonPrometheusFields)
+		selectors = append(selectors, namespaceSelectorOf{owner, "ruleNamespaceSelector", p.Spec.RuleNamespaceSelector})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PrometheusAgents", clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1alpha1.PrometheusAgent) error {
+		if p.Namespace != namespace {
+			common(fmt.Sprintf("PrometheusAgent %s/%s", p.Namespace, p.Name), p.Spec.CommonPrometheusFields)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Alertmanagers", clientSets.MClient.MonitoringV1().Alertmanagers(metav1.NamespaceAll).List, metav1.ListOptions{}, func(am *monitoringv1.Alertmanager) error {
+		if am.Namespace != namespace {
+			selectors = append(selectors, namespaceSelectorOf{fmt.Sprintf("Alertmanager %s/%s", am.Namespace, am.Name), "alertmanagerConfigNamespaceSelector", am.Spec.AlertmanagerConfigNamespaceSelector})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ThanosRulers", clientSets.MClient.MonitoringV1().ThanosRulers(metav1.NamespaceAll).List, metav1.ListOptions{}, func(tr *monitoringv1.ThanosRuler) error {
+		if tr.Namespace != namespace {
+			selectors = append(selectors, namespaceSelectorOf{fmt.Sprintf("ThanosRuler %s/%s", tr.Namespace, tr.Name), "ruleNamespaceSelector", tr.Spec.RuleNamespaceSelector})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return selectors, nil
+}
+
+// selectedLabels returns the labels of the namespace required by the
+// namespace selectors matching it, whose removal stops the selection. The
+// warnings tell the selectors which select the namespace whatever its
+// labels, or by its name.
+func selectedLabels(ns *corev1.Namespace, selectors []namespaceSelectorOf) ([]string, []string) {
+	var (
+		selected = map[string]struct{}{}
+		warnings []string
+	)
+	for _, s := range selectors {
+		if s.selector == nil {
+			continue
+		}
+
+		sel, err := metav1.LabelSelectorAsSelector(s.selector)
+		if err != nil || !sel.Matches(labels.Set(ns.Labels)) {
+			continue
+		}
+
+		if sel.Empty() {
+			warnings = append(warnings, fmt.Sprintf("%s selects all the namespaces with spec.%s", s.owner, s.field))
+			continue
+		}
+
+		var keys []string
+		for key := range s.selector.MatchLabels {
+			keys = append(keys, key)
+		}
+		for _, expr := range s.selector.MatchExpressions {
+			if expr.Operator == metav1.LabelSelectorOpIn || expr.Operator == metav1.LabelSelectorOpExists {
+				keys = append(keys, expr.Key)
+			}
+		}
+
+		// The API server sets the name label, which can't be removed.
+		if slices.Contains(keys, corev1.LabelMetadataName) {
+			warnings = append(warnings, fmt.Sprintf("%s selects the namespace by name with spec.%s", s.owner, s.field))
+			continue
+		}
+
+		if len(keys) == 0 {
+			warnings = append(warnings, fmt.Sprintf("%s selects the namespace with spec.%s whatever its labels", s.owner, s.field))
+			continue
+		}
+
+		for _, key := range keys {
+			selected[key] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(selected)), warnings
+}
+
+// leftovers returns the objects out of the namespace which reference it: the
+// ClusterRoleBindings binding its ServiceAccounts, and the ServiceMonitors
+// and PodMonitors of the other namespaces listing it in their namespace
+// selector.
+func leftovers(ctx context.Context, clientSets *k8sutil.ClientSets, namespace string) ([]string, error) {
+	var objects []string
+
+	err := k8sutil.EachListItem(ctx, "ClusterRoleBindings", clientSets.KClient.RbacV1().ClusterRoleBindings().List, metav1.ListOptions{}, func(crb *rbacv1.ClusterRoleBinding) error {
+		if slices.ContainsFunc(crb.Subjects, func(s rbacv1.Subject) bool { return s.Namespace == namespace }) {
+			objects = append(objects, "ClusterRoleBinding "+crb.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ServiceMonitors", clientSets.MClient.MonitoringV1().ServiceMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(sm *monitoringv1.ServiceMonitor) error {
+		if sm.Namespace != namespace && slices.Contains(sm.Spec.NamespaceSelector.MatchNames, namespace) {
+			objects = append(objects, fmt.Sprintf("ServiceMonitor %s/%s", sm.Namespace, sm.Name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PodMonitors", clientSets.MClient.MonitoringV1().PodMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(pm *monitoringv1.PodMonitor) error {
+		if pm.Namespace != namespace && slices.Contains(pm.Spec.NamespaceSelector.MatchNames, namespace) {
+			objects = append(objects, fmt.Sprintf("PodMonitor %s/%s", pm.Namespace, pm.Name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
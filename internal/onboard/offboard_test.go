@@ -0,0 +1,127 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onboard
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newUnstructured(gvr schema.GroupVersionResource, kind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": gvr.GroupVersion().String(),
+		"kind":       kind,
+	}}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+// offboardingListKinds returns the list kinds of the resources for the fake
+// dynamic client.
+func offboardingListKinds() map[schema.GroupVersionResource]string {
+	listKinds := map[schema.GroupVersionResource]string{
+		roleResource:        "RoleList",
+		roleBindingResource: "RoleBindingList",
+	}
+	for _, gvr := range monitoringResources {
+		listKinds[gvr] = "List"
+	}
+	return listKinds
+}
+
+func TestOffboarding(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "team-a",
+		Labels: map[string]string{"monitoring": "main", "team": "a", corev1.LabelMetadataName: "team-a"},
+	}}
+	prometheus := &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+		Spec: monitoringv1.PrometheusSpec{
+			CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+				ServiceMonitorNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "main"}},
+				PodMonitorNamespaceSelector:     &metav1.LabelSelector{},
+			},
+			RuleNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "other"}},
+		},
+	}
+	alertmanager := &monitoringv1.Alertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+		Spec: monitoringv1.AlertmanagerSpec{
+			AlertmanagerConfigNamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: corev1.LabelMetadataName, Operator: metav1.LabelSelectorOpIn, Values: []string{"team-a"}},
+			}},
+		},
+	}
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a-api-metrics-reader"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "api-metrics-reader", Namespace: "team-a"}},
+	}
+	federation := &monitoringv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "federation", Namespace: "monitoring"},
+		Spec:       monitoringv1.ServiceMonitorSpec{NamespaceSelector: monitoringv1.NamespaceSelector{MatchNames: []string{"team-a"}}},
+	}
+
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(namespace, prometheus, alertmanager, clusterRoleBinding, federation))
+	clientSets.DClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), offboardingListKinds(),
+		newUnstructured(monitoringResources[0], "ServiceMonitor", "team-a", "api", nil),
+		newUnstructured(monitoringResources[4], "PrometheusRule", "team-a", "api", nil),
+		newUnstructured(monitoringResources[4], "PrometheusRule", "team-b", "api", nil),
+		newUnstructured(roleResource, "Role", "team-a", "monitoring-editor", map[string]string{"app.kubernetes.io/name": "onboarding"}),
+		newUnstructured(roleResource, "Role", "team-a", "app", nil),
+	)
+
+	o, err := PlanOffboarding(context.Background(), clientSets, "team-a")
+	require.NoError(t, err)
+
+	var objects []string
+	for _, obj := range o.Objects {
+		objects = append(objects, obj.String())
+	}
+	assert.Equal(t, []string{"ServiceMonitor api", "PrometheusRule api", "Role monitoring-editor"}, objects)
+	assert.Equal(t, []string{"monitoring"}, o.Labels)
+	assert.Equal(t, []string{"ClusterRoleBinding team-a-api-metrics-reader", "ServiceMonitor monitoring/federation"}, o.Leftovers)
+	require.Len(t, o.Warnings, 2)
+	assert.True(t, strings.HasPrefix(o.Warnings[0], "Prometheus monitoring/main selects all the namespaces"), o.Warnings[0])
+	assert.True(t, strings.HasPrefix(o.Warnings[1], "Alertmanager monitoring/main selects the namespace by name"), o.Warnings[1])
+
+	require.NoError(t, Offboard(context.Background(), clientSets, o))
+
+	ns, err := clientSets.KClient.CoreV1().Namespaces().Get(context.Background(), "team-a", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "a", corev1.LabelMetadataName: "team-a"}, ns.Labels)
+
+	o, err = PlanOffboarding(context.Background(), clientSets, "team-a")
+	require.NoError(t, err)
+	assert.Empty(t, o.Objects)
+	assert.Empty(t, o.Labels)
+
+	_, err = PlanOffboarding(context.Background(), clientSets, "missing")
+	require.Error(t, err)
+}
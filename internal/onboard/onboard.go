@@ -0,0 +1,329 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package onboard sets up the monitoring of the namespace of a team by a
+// Prometheus: the namespace is labeled to match the namespace selectors of
+// the Prometheus, and gets starter monitors and rules along with the RBAC
+// letting the team manage them. The offboarding tears the monitoring of a
+// namespace down.
+package onboard
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Options describe the onboarding of a namespace.
+type Options struct {
+	Namespace string
+	// PrometheusName and PrometheusNamespace identify the Prometheus
+	// monitoring the namespace.
+	PrometheusName      string
+	PrometheusNamespace string
+	// Groups and Users are granted the management of the monitors and the
+	// rules of the namespace.
+	Groups []string
+	Users  []string
+}
+
+// Result is the outcome of the onboarding of a namespace.
+type Result struct {
+	// Manifests are the objects onboarding the namespace. The starter
+	// ServiceMonitor and PrometheusRule are only set when they don't exist
+	// yet.
+	Manifests builder.OnboardingManifests
+	// Applied are the objects applied, as "Kind name".
+	Applied []string
+	// Existing are the starter objects left unchanged, as "Kind name".
+	Existing []string
+	// Warnings are the parts of the onboarding which were skipped.
+	Warnings []string
+}
+
+// namespaceSelector is a pair of selectors of a Prometheus, selecting
+// objects of a kind and their namespaces.
+type namespaceSelector struct {
+	kind              string
+	selector          *metav1.LabelSelector
+	namespaceSelector *metav1.LabelSelector
+}
+
+// Run onboards the namespace on the Prometheus. The labels of the namespace
+// and the RBAC are applied, while the starter ServiceMonitor and
+// PrometheusRule are only created when missing, leaving the changes of the
+// team untouched. With dryRun, the manifests are only built.
+func Run(ctx context.Context, clientSets *k8sutil.ClientSets, opts Options, dryRun bool) (*Result, error) {
+	p, err := clientSets.MClient.MonitoringV1().Prometheuses(opts.PrometheusNamespace).Get(ctx, opts.PrometheusName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while getting Prometheus %s/%s: %w", opts.PrometheusNamespace, opts.PrometheusName, err)
+	}
+
+	var current map[string]string
+	namespaceExists := true
+	ns, err := clientSets.KClient.CoreV1().Namespaces().Get(ctx, opts.Namespace, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		namespaceExists = false
+	case err != nil:
+		return nil, fmt.Errorf("error while getting namespace %s: %w", opts.Namespace, err)
+	default:
+		current = ns.Labels
+	}
+
+	result := &Result{}
+	namespaceLabels, warnings, err := NamespaceLabels(p, opts.Namespace, current)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+
+	b := builder.NewOnboardingBuilder(opts.Namespace).
+		WithRBAC(opts.Groups, opts.Users)
+	if len(namespaceLabels) > 0 || !namespaceExists {
+		b.WithNamespaceLabels(namespaceLabels)
+	}
+	if len(opts.Groups) == 0 && len(opts.Users) == 0 {
+		result.Warnings = append(result.Warnings, "no group nor user to grant the management of the monitors, the RoleBinding isn't created")
+	}
+
+	serviceMonitorLabels, err := objectLabels(p, "ServiceMonitors", p.Spec.ServiceMonitorSelector)
+	if err != nil {
+		return nil, err
+	}
+	switch exists, err := starterExists(ctx, clientSets, "ServiceMonitor", opts.Namespace, namespaceExists); {
+	case err != nil:
+		return nil, err
+	case p.Spec.ServiceMonitorSelector == nil:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Prometheus %s/%s doesn't select ServiceMonitors, the starter ServiceMonitor isn't created", p.Namespace, p.Name))
+	case exists:
+		result.Existing = append(result.Existing, "ServiceMonitor "+opts.Namespace)
+	default:
+		b.WithServiceMonitor(serviceMonitorLabels)
+	}
+
+	ruleLabels, err := objectLabels(p, "PrometheusRules", p.Spec.RuleSelector)
+	if err != nil {
+		return nil, err
+	}
+	switch exists, err := starterExists(ctx, clientSets, "PrometheusRule", opts.Namespace, namespaceExists); {
+	case err != nil:
+		return nil, err
+	case p.Spec.RuleSelector == nil:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Prometheus %s/%s doesn't select PrometheusRules, the starter PrometheusRule isn't created", p.Namespace, p.Name))
+	case exists:
+		result.Existing = append(result.Existing, "PrometheusRule "+opts.Namespace)
+	default:
+		b.WithPrometheusRule(ruleLabels)
+	}
+
+	result.Manifests = b.Build()
+	if dryRun {
+		return result, nil
+	}
+
+	if err := apply(ctx, clientSets, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// apply applies the manifests of the result, in the order of their
+// dependencies, recording the applied objects.
+func apply(ctx context.Context, clientSets *k8sutil.ClientSets, result *Result) error {
+	m := result.Manifests
+
+	results := k8sutil.NewApplier(clientSets).
+		WithConcurrency(1).
+		Apply(ctx, m.Namespace, m.Role, m.RoleBinding, m.ServiceMonitor, m.PrometheusRule)
+	for _, r := range results {
+		if r.Err == nil {
+			result.Applied = append(result.Applied, r.Kind+" "+r.Name)
+		}
+	}
+
+	return k8sutil.ApplyErr(results)
+}
+
+// starterExists reports whether the starter object of the kind, named after
+// the namespace, exists.
+func starterExists(ctx context.Context, clientSets *k8sutil.ClientSets, kind, namespace string, namespaceExists bool) (bool, error) {
+	if !namespaceExists {
+		return false, nil
+	}
+
+	var err error
+	switch kind {
+	case "ServiceMonitor":
+		_, err = clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Get(ctx, namespace, metav1.GetOptions{})
+	case "PrometheusRule":
+		_, err = clientSets.MClient.MonitoringV1().PrometheusRules(namespace).Get(ctx, namespace, metav1.GetOptions{})
+	default:
+		return false, fmt.Errorf("unsupported kind %s", kind)
+	}
+
+	switch {
+	case errors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("error while getting %s %s/%s: %w", kind, namespace, namespace, err)
+	}
+	return true, nil
+}
+
+// NamespaceLabels returns the labels the namespace needs for the Prometheus
+// to select its ServiceMonitors, PodMonitors, Probes and PrometheusRules,
+// given its current labels. The warnings tell the kinds which can't be
+// selected from the namespace whatever its labels.
+func NamespaceLabels(p *monitoringv1.Prometheus, namespace string, current map[string]string) (map[string]string, []string, error) {
+	selectors := []namespaceSelector{
+		{"ServiceMonitors", p.Spec.ServiceMonitorSelector, p.Spec.ServiceMonitorNamespaceSelector},
+		{"PodMonitors", p.Spec.PodMonitorSelector, p.Spec.PodMonitorNamespaceSelector},
+		{"Probes", p.Spec.ProbeSelector, p.Spec.ProbeNamespaceSelector},
+		{"PrometheusRules", p.Spec.RuleSelector, p.Spec.RuleNamespaceSelector},
+	}
+
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+
+	var warnings []string
+	required := map[string]string{}
+	for _, s := range selectors {
+		// The Prometheus doesn't select any object of the kind.
+		if s.selector == nil {
+			continue
+		}
+
+		// The Prometheus only selects the objects of its namespace.
+		if s.namespaceSelector == nil {
+			if namespace != p.Namespace {
+				warnings = append(warnings, fmt.Sprintf("Prometheus %s/%s only selects the %s of its namespace, set spec.%s to select the %s of namespace %s", p.Namespace, p.Name, s.kind, namespaceSelectorField(s.kind), s.kind, namespace))
+			}
+			continue
+		}
+
+		needed, err := requiredLabels(s.namespaceSelector, merged)
+		if err != nil {
+			return nil, nil, fmt.Errorf("namespace %s can't match the %s namespace selector of Prometheus %s/%s: %w", namespace, s.kind, p.Namespace, p.Name, err)
+		}
+		maps.Copy(required, needed)
+		maps.Copy(merged, needed)
+	}
+
+	return required, warnings, nil
+}
+
+// objectLabels returns the labels an object of the kind needs for the
+// Prometheus to select it.
+func objectLabels(p *monitoringv1.Prometheus, kind string, selector *metav1.LabelSelector) (map[string]string, error) {
+	if selector == nil {
+		return nil, nil
+	}
+
+	needed, err := requiredLabels(selector, nil)
+	if err != nil {
+		return nil, fmt.Errorf("no starter object can match the %s selector of Prometheus %s/%s: %w", kind, p.Namespace, p.Name, err)
+	}
+	return needed, nil
+}
+
+// requiredLabels returns the labels an object needs to match the selector,
+// given its current labels. The In requirements take their current value
+// when it matches and their first value otherwise, the Exists requirements
+// take "true" when the label is missing. The current labels which don't
+// match are conflicts, never overwritten.
+func requiredLabels(selector *metav1.LabelSelector, current map[string]string) (map[string]string, error) {
+	required := map[string]string{}
+	set := func(key, value string) error {
+		if v, ok := current[key]; ok && v != value {
+			return fmt.Errorf("label %s=%s conflicts with the required %s=%s", key, v, key, value)
+		}
+		required[key] = value
+		return nil
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(selector.MatchLabels)) {
+		if err := set(key, selector.MatchLabels[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, expr := range selector.MatchExpressions {
+		value, ok := required[expr.Key]
+		if !ok {
+			value, ok = current[expr.Key]
+		}
+
+		switch expr.Operator {
+		case metav1.LabelSelectorOpIn:
+			if ok && slices.Contains(expr.Values, value) {
+				required[expr.Key] = value
+				continue
+			}
+			if len(expr.Values) == 0 {
+				return nil, fmt.Errorf("requirement on label %s has no value", expr.Key)
+			}
+			if err := set(expr.Key, expr.Values[0]); err != nil {
+				return nil, err
+			}
+		case metav1.LabelSelectorOpExists:
+			if !ok {
+				value = "true"
+			}
+			required[expr.Key] = value
+		}
+	}
+
+	// The NotIn and DoesNotExist requirements only exclude labels, they're
+	// checked on the labels with the requirements.
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, required)
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	if !s.Matches(labels.Set(merged)) {
+		return nil, fmt.Errorf("labels %s don't match the selector %q", labels.Set(merged), s)
+	}
+	return required, nil
+}
+
+// namespaceSelectorField returns the field of the Prometheus spec selecting
+// the namespaces of the objects of the kind.
+func namespaceSelectorField(kind string) string {
+	switch kind {
+	case "ServiceMonitors":
+		return "serviceMonitorNamespaceSelector"
+	case "PodMonitors":
+		return "podMonitorNamespaceSelector"
+	case "Probes":
+		return "probeNamespaceSelector"
+	default:
+		return "ruleNamespaceSelector"
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onboard
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPrometheus(spec monitoringv1.PrometheusSpec) *monitoringv1.Prometheus {
+	return &monitoringv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+		Spec:       spec,
+	}
+}
+
+func TestNamespaceLabels(t *testing.T) {
+	type testCase struct {
+		name             string
+		spec             monitoringv1.PrometheusSpec
+		current          map[string]string
+		shouldFail       bool
+		expectedLabels   map[string]string
+		expectedWarnings int
+	}
+
+	tests := []testCase{
+		{
+			name: "MatchLabels",
+			spec: monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+				ServiceMonitorSelector:          &metav1.LabelSelector{},
+				ServiceMonitorNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "main"}},
+			}},
+			expectedLabels: map[string]string{"monitoring": "main"},
+		},
+		{
+			name: "Expressions",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					PodMonitorSelector: &metav1.LabelSelector{},
+					PodMonitorNamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend", "backend"}},
+						{Key: "monitored", Operator: metav1.LabelSelectorOpExists},
+						{Key: "legacy", Operator: metav1.LabelSelectorOpDoesNotExist},
+					}},
+				},
+			},
+			current:        map[string]string{"tier": "backend"},
+			expectedLabels: map[string]string{"tier": "backend", "monitored": "true"},
+		},
+		{
+			name: "AllNamespaces",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					ServiceMonitorSelector:          &metav1.LabelSelector{},
+					ServiceMonitorNamespaceSelector: &metav1.LabelSelector{},
+				},
+				RuleSelector: &metav1.LabelSelector{},
+			},
+			expectedLabels:   map[string]string{},
+			expectedWarnings: 1,
+		},
+		{
+			name: "Conflict",
+			spec: monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+				ServiceMonitorSelector:          &metav1.LabelSelector{},
+				ServiceMonitorNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "main"}},
+			}},
+			current:    map[string]string{"monitoring": "other"},
+			shouldFail: true,
+		},
+		{
+			name: "ConflictingSelectors",
+			spec: monitoringv1.PrometheusSpec{
+				CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+					ServiceMonitorSelector:          &metav1.LabelSelector{},
+					ServiceMonitorNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "main"}},
+				},
+				RuleSelector:          &metav1.LabelSelector{},
+				RuleNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "rules"}},
+			},
+			shouldFail: true,
+		},
+		{
+			name: "Unsatisfiable",
+			spec: monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+				ServiceMonitorSelector: &metav1.LabelSelector{},
+				ServiceMonitorNamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"team-a"}},
+				}},
+			}},
+			current:    map[string]string{"team": "team-a"},
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			labels, warnings, err := NamespaceLabels(newPrometheus(tc.spec), "team-a", tc.current)
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedLabels, labels)
+			assert.Len(t, warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	p := newPrometheus(monitoringv1.PrometheusSpec{
+		CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+			ServiceMonitorSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"release": "main"}},
+			ServiceMonitorNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "main"}},
+		},
+		RuleSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"release": "main"}},
+		RuleNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "main"}},
+	})
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	existingRule := &monitoringv1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Namespace: "team-a"}}
+
+	clientSets := k8stesting.NewFakeClientSets(
+		k8stesting.WithObjects(p, namespace, existingRule),
+		// The fake clientsets only apply the objects which exist.
+		k8stesting.WithKubeReactor("patch", "roles", k8stesting.ReturnObject(&rbacv1.Role{})),
+		k8stesting.WithKubeReactor("patch", "rolebindings", k8stesting.ReturnObject(&rbacv1.RoleBinding{})),
+		k8stesting.WithMonitoringReactor("patch", "servicemonitors", k8stesting.ReturnObject(&monitoringv1.ServiceMonitor{})),
+	)
+
+	opts := Options{
+		Namespace:           "team-a",
+		PrometheusName:      "main",
+		PrometheusNamespace: "monitoring",
+		Groups:              []string{"team-a"},
+	}
+	result, err := Run(context.Background(), clientSets, opts, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Namespace team-a", "Role monitoring-editor", "RoleBinding monitoring-editor", "ServiceMonitor team-a"}, result.Applied)
+	assert.Equal(t, []string{"PrometheusRule team-a"}, result.Existing)
+	assert.Empty(t, result.Warnings)
+	assert.Equal(t, "main", result.Manifests.ServiceMonitor.Labels["release"])
+	assert.Nil(t, result.Manifests.PrometheusRule)
+
+	ns, err := clientSets.KClient.CoreV1().Namespaces().Get(context.Background(), "team-a", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "main", ns.Labels["monitoring"])
+
+	// Without subjects, the RoleBinding is skipped.
+	opts.Groups = nil
+	result, err = Run(context.Background(), clientSets, opts, true)
+	require.NoError(t, err)
+	assert.Nil(t, result.Manifests.RoleBinding)
+	assert.Empty(t, result.Applied)
+	assert.Len(t, result.Warnings, 1)
+
+	opts.PrometheusName = "missing"
+	_, err = Run(context.Background(), clientSets, opts, false)
+	require.Error(t, err)
+}
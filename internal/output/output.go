@@ -0,0 +1,128 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package output writes the results of the analyses of poctl as JSON or
+// YAML documents, which the CI pipelines consume programmatically.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/analyzers"
+	"sigs.k8s.io/yaml"
+)
+
+// Format is the format of a document.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// Status is the outcome of the analysis of an object.
+type Status string
+
+const (
+	StatusCompliant Status = "compliant"
+	StatusFailed    Status = "failed"
+	StatusNotRun    Status = "notRun"
+)
+
+// Report is the document of an analysis.
+type Report struct {
+	Summary Summary  `json:"summary"`
+	Results []Result `json:"results"`
+}
+
+// Summary counts the results of a report by status, and their warnings.
+type Summary struct {
+	Objects   int `json:"objects"`
+	Compliant int `json:"compliant"`
+	Failed    int `json:"failed"`
+	NotRun    int `json:"notRun"`
+	Warnings  int `json:"warnings"`
+}
+
+// Result is the outcome of the analysis of an object, or of a target whose
+// selector failed to be expanded.
+type Result struct {
+	// Context is the kubeconfig context of the cluster, empty for the
+	// current context.
+	Context   string `json:"context,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    Status `json:"status"`
+	// Error is the failure which isn't reported by a finding, such as a
+	// failed request to the API server.
+	Error    string              `json:"error,omitempty"`
+	Findings []analyzers.Finding `json:"findings"`
+}
+
+// Write writes the report in the format with the summary of its results.
+func Write(w io.Writer, format Format, report Report) error {
+	report.Results = slices.Clone(report.Results)
+	report.Summary = Summary{Objects: len(report.Results)}
+	for i, r := range report.Results {
+		switch r.Status {
+		case StatusCompliant:
+			report.Summary.Compliant++
+		case StatusFailed:
+			report.Summary.Failed++
+		case StatusNotRun:
+			report.Summary.NotRun++
+		}
+
+		for _, f := range r.Findings {
+			if f.Severity == analyzers.SeverityWarning {
+				report.Summary.Warnings++
+			}
+		}
+
+		// The objects without findings are listed with an empty list, not
+		// with null.
+		if r.Findings == nil {
+			report.Results[i].Findings = []analyzers.Finding{}
+		}
+	}
+	if report.Results == nil {
+		report.Results = []Result{}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case JSON:
+		data, err = json.MarshalIndent(report, "", "  ")
+		data = append(data, '\n')
+	case YAML:
+		data, err = yaml.Marshal(report)
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("error while encoding %s report: %w", format, err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error while writing %s report: %w", format, err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/analyzers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() Report {
+	return Report{Results: []Result{
+		{
+			Context:   "prod-eu",
+			Kind:      "prometheus",
+			Namespace: "monitoring",
+			Name:      "k8s",
+			Status:    StatusCompliant,
+			Findings: []analyzers.Finding{
+				{Kind: "Prometheus", Namespace: "monitoring", Name: "k8s", Check: "PR101", Severity: analyzers.SeverityWarning, Message: "the admin API is exposed", Fix: "disable the admin API"},
+				{Kind: "Prometheus", Namespace: "monitoring", Name: "k8s", Check: "PO002", Severity: analyzers.SeverityInfo, Message: "Prometheus is compliant, no issues found"},
+			},
+		},
+		{
+			Context:   "prod-eu",
+			Kind:      "servicemonitor",
+			Namespace: "payments",
+			Name:      "selector team=payments",
+			Status:    StatusFailed,
+			Error:     "no servicemonitor matches the selector team=payments in namespace payments",
+		},
+	}}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, JSON, testReport()))
+
+	assert.Equal(t, `{
+  "summary": {
+    "objects": 2,
+    "compliant": 1,
+    "failed": 1,
+    "notRun": 0,
+    "warnings": 1
+  },
+  "results": [
+    {
+      "context": "prod-eu",
+      "kind": "prometheus",
+      "namespace": "monitoring",
+      "name": "k8s",
+      "status": "compliant",
+      "findings": [
+        {
+          "kind": "Prometheus",
+          "namespace": "monitoring",
+          "name": "k8s",
+          "check": "PR101",
+          "severity": "warning",
+          "message": "the admin API is exposed",
+          "fix": "disable the admin API"
+        },
+        {
+          "kind": "Prometheus",
+          "namespace": "monitoring",
+          "name": "k8s",
+          "check": "PO002",
+          "severity": "info",
+          "message": "Prometheus is compliant, no issues found"
+        }
+      ]
+    },
+    {
+      "context": "prod-eu",
+      "kind": "servicemonitor",
+      "namespace": "payments",
+      "name": "selector team=payments",
+      "status": "failed",
+      "error": "no servicemonitor matches the selector team=payments in namespace payments",
+      "findings": []
+    }
+  ]
+}
+`, buf.String())
+}
+
+func TestWriteYAML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, YAML, testReport()))
+
+	assert.Equal(t, `results:
+- context: prod-eu
+  findings:
+  - check: PR101
+    fix: disable the admin API
+    kind: Prometheus
+    message: the admin API is exposed
+    name: k8s
+    namespace: monitoring
+    severity: warning
+  - check: PO002
+    kind: Prometheus
+    message: Prometheus is compliant, no issues found
+    name: k8s
+    namespace: monitoring
+    severity: info
+  kind: prometheus
+  name: k8s
+  namespace: monitoring
+  status: compliant
+- context: prod-eu
+  error: no servicemonitor matches the selector team=payments in namespace payments
+  findings: []
+  kind: servicemonitor
+  name: selector team=payments
+  namespace: payments
+  status: failed
+summary:
+  compliant: 1
+  failed: 1
+  notRun: 0
+  objects: 2
+  warnings: 1
+`, buf.String())
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, YAML, Report{}))
+	assert.Contains(t, buf.String(), "results: []\n")
+
+	assert.Error(t, Write(&buf, Format("xml"), Report{}))
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/prometheus-operator/poctl/internal/crds"
+	"github.com/prometheus-operator/poctl/internal/explain"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Type is the format of a patch.
+type Type string
+
+const (
+	// Merge is a JSON merge patch, RFC 7386.
+	Merge Type = "merge"
+	// JSON is a JSON patch, RFC 6902.
+	JSON Type = "json"
+)
+
+// ParseType returns the patch type matching s. Strategic merge patches
+// aren't supported by custom resources.
+func ParseType(s string) (Type, error) {
+	switch t := Type(strings.ToLower(s)); t {
+	case Merge, JSON:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown patch type %s, must be one of: %s, %s", s, Merge, JSON)
+	}
+}
+
+// Result is the outcome of a patch.
+type Result struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Diff is the unified diff of the object, empty when the patch doesn't
+	// change it.
+	Diff string
+}
+
+// Run patches a monitoring resource, such as prometheus/my-prom. The patch is
+// applied locally and the patched object is validated against the schema of
+// the installed CRD before being sent to the API server, so that an invalid
+// patch is rejected with the JSON paths of the offending fields. The object
+// is updated with the resource version it has been read with, failing if it
+// has changed in the meantime. With dryRun, only the diff is computed.
+func Run(ctx context.Context, clientSets *k8sutil.ClientSets, resource, name, namespace string, patchType Type, data []byte, dryRun bool) (*Result, error) {
+	crdName, err := explain.ResolveCRDName(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	crd, err := explain.InstalledCRD(ctx, clientSets, crdName)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := storageVersion(crd)
+	if err != nil {
+		return nil, err
+	}
+
+	if crd.Spec.Scope == apiextensionsv1.ClusterScoped {
+		namespace = ""
+	}
+
+	client := clientSets.DClient.Resource(schema.GroupVersionResource{
+		Group:    crd.Spec.Group,
+		Version:  version,
+		Resource: crd.Spec.Names.Plural,
+	}).Namespace(namespace)
+
+	live, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("%s %s not found in namespace %s", crd.Spec.Names.Kind, name, namespace)
+		}
+		return nil, fmt.Errorf("error while getting %s %s: %w", crd.Spec.Names.Kind, name, err)
+	}
+
+	patched, err := Apply(live.Object, patchType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(crd, live.Object, patched); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Kind:      crd.Spec.Names.Kind,
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	if result.Diff, err = Diff(live.Object, patched); err != nil {
+		return nil, err
+	}
+
+	if dryRun || result.Diff == "" {
+		return result, nil
+	}
+
+	_, err = client.Update(ctx, &unstructured.Unstructured{Object: patched}, metav1.UpdateOptions{
+		FieldManager: k8sutil.ApplyOption.FieldManager,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while updating %s %s: %w", result.Kind, name, err)
+	}
+
+	return result, nil
+}
+
+// Apply returns the object patched with data.
+func Apply(obj map[string]any, patchType Type, data []byte) (map[string]any, error) {
+	doc, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("error while encoding object: %w", err)
+	}
+
+	// The patch can be given in YAML too.
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	switch patchType {
+	case Merge:
+		doc, err = jsonpatch.MergePatch(doc, data)
+	case JSON:
+		var p jsonpatch.Patch
+		p, err = jsonpatch.DecodePatch(data)
+		if err == nil {
+			doc, err = p.Apply(doc)
+		}
+	default:
+		err = fmt.Errorf("unknown patch type %s", patchType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while applying patch: %w", err)
+	}
+
+	var patched map[string]any
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return nil, fmt.Errorf("error while decoding patched object: %w", err)
+	}
+
+	return patched, nil
+}
+
+// Diff returns the unified diff of the YAML representations of the live and
+// patched objects, leaving out the managed fields.
+func Diff(live, patched map[string]any) (string, error) {
+	from, err := diffLines(live)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := diffLines(patched)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        from,
+		B:        to,
+		FromFile: "live",
+		ToFile:   "patched",
+		Context:  3,
+	})
+}
+
+func diffLines(obj map[string]any) ([]string, error) {
+	u := &unstructured.Unstructured{Object: obj}
+	u = u.DeepCopy()
+	u.SetManagedFields(nil)
+
+	b, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return nil, fmt.Errorf("error while encoding object: %w", err)
+	}
+
+	return difflib.SplitLines(string(b)), nil
+}
+
+// validate rejects the patches which are invalid against the CRD schema or
+// which change the immutable parts of the object.
+func validate(crd *apiextensionsv1.CustomResourceDefinition, live, patched map[string]any) error {
+	for _, key := range []string{"apiVersion", "kind"} {
+		if live[key] != patched[key] {
+			return fmt.Errorf("the patch can't change the %s of the object", key)
+		}
+	}
+
+	l, p := &unstructured.Unstructured{Object: live}, &unstructured.Unstructured{Object: patched}
+	if l.GetName() != p.GetName() || l.GetNamespace() != p.GetNamespace() {
+		return fmt.Errorf("the patch can't change the name or the namespace of the object")
+	}
+
+	errs, err := crds.Validate(crd, patched)
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return &crds.ValidationError{Kind: crd.Spec.Names.Kind, Namespace: p.GetNamespace(), Name: p.GetName(), Errors: errs}
+	}
+
+	return nil
+}
+
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) (string, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name, nil
+		}
+	}
+	return "", fmt.Errorf("CRD %s has no storage version", crd.Name)
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getPrometheus() map[string]any {
+	return map[string]any{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "Prometheus",
+		"metadata": map[string]any{
+			"name":          "my-prom",
+			"namespace":     "default",
+			"managedFields": []any{map[string]any{"manager": "poctl"}},
+		},
+		"spec": map[string]any{
+			"logLevel": "info",
+			"replicas": int64(2),
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	type testCase struct {
+		name         string
+		patchType    Type
+		patch        string
+		shouldFail   bool
+		expectedSpec map[string]any
+	}
+
+	tests := []testCase{
+		{
+			name:         "Merge",
+			patchType:    Merge,
+			patch:        `{"spec":{"logLevel":"debug","replicas":null}}`,
+			expectedSpec: map[string]any{"logLevel": "debug"},
+		},
+		{
+			name:         "MergeYAML",
+			patchType:    Merge,
+			patch:        "spec:\n  retention: 30d\n",
+			expectedSpec: map[string]any{"logLevel": "info", "replicas": float64(2), "retention": "30d"},
+		},
+		{
+			name:         "JSON",
+			patchType:    JSON,
+			patch:        `[{"op":"replace","path":"/spec/replicas","value":3}]`,
+			expectedSpec: map[string]any{"logLevel": "info", "replicas": float64(3)},
+		},
+		{
+			name:       "JSONFailedTest",
+			patchType:  JSON,
+			patch:      `[{"op":"test","path":"/spec/logLevel","value":"debug"}]`,
+			shouldFail: true,
+		},
+		{
+			name:       "InvalidPatch",
+			patchType:  Merge,
+			patch:      `{"spec":`,
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patched, err := Apply(getPrometheus(), tc.patchType, []byte(tc.patch))
+			if tc.shouldFail {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSpec, patched["spec"])
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	live := getPrometheus()
+	patched, err := Apply(live, Merge, []byte(`{"spec":{"logLevel":"debug"}}`))
+	require.NoError(t, err)
+
+	diff, err := Diff(live, patched)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-  logLevel: info\n+  logLevel: debug\n")
+	assert.NotContains(t, diff, "managedFields")
+
+	diff, err = Diff(live, live)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestParseType(t *testing.T) {
+	typ, err := ParseType("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, JSON, typ)
+
+	_, err = ParseType("strategic")
+	require.Error(t, err)
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// prometheusWebPort is the port of the Prometheus web server.
+	prometheusWebPort = "9090"
+	// alertmanagerWebPort is the port of the Alertmanager web server.
+	alertmanagerWebPort = "9093"
+	// shardLabel is the label set by the operator on the pods of each
+	// Prometheus shard.
+	shardLabel = "operator.prometheus.io/shard"
+)
+
+// Client sends GET requests to the HTTP API of a Prometheus or an
+// Alertmanager server.
+type Client interface {
+	Get(ctx context.Context, path string, params url.Values) ([]byte, error)
+}
+
+type urlClient struct {
+	baseURL string
+}
+
+// NewURLClient returns a client reaching the server directly at the URL, for
+// instance through a port-forward or an Ingress.
+func NewURLClient(baseURL string) Client {
+	return &urlClient{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (c *urlClient) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned status %d: %s", c.baseURL, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+type podProxyClient struct {
+	clientSets *k8sutil.ClientSets
+	pod        corev1.Pod
+	port       string
+}
+
+func (c *podProxyClient) Get(ctx context.Context, path string, params url.Values) ([]byte, error) {
+	proxyParams := make(map[string]string, len(params))
+	for key := range params {
+		proxyParams[key] = params.Get(key)
+	}
+
+	body, err := c.clientSets.KClient.CoreV1().Pods(c.pod.Namespace).ProxyGet("http", c.pod.Name, c.port, path, proxyParams).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying pod %s/%s: %w", c.pod.Namespace, c.pod.Name, err)
+	}
+	return body, nil
+}
+
+// PrometheusClients returns a client per shard of the Prometheus, reaching a
+// running pod of the shard through the proxy of the Kubernetes API server.
+// Querying each shard is needed since each one only scrapes its share of the
+// targets.
+func PrometheusClients(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Client, error) {
+	pods, err := clientSets.KClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=prometheus,operator.prometheus.io/name=" + name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Prometheus pods: %w", err)
+	}
+
+	var (
+		clients []Client
+		shards  = map[string]bool{}
+	)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || shards[pod.Labels[shardLabel]] {
+			continue
+		}
+
+		shards[pod.Labels[shardLabel]] = true
+		clients = append(clients, &podProxyClient{clientSets: clientSets, pod: pod, port: prometheusWebPort})
+	}
+
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("no running pods found for Prometheus %s in namespace %s", name, namespace)
+	}
+	return clients, nil
+}
+
+// AlertmanagerClient returns a client reaching a running pod of the
+// Alertmanager through the proxy of the Kubernetes API server. The replicas of
+// an Alertmanager share their alerts, any of them can be queried.
+func AlertmanagerClient(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) (Client, error) {
+	pods, err := clientSets.KClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=alertmanager,alertmanager=" + name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Alertmanager pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			return &podProxyClient{clientSets: clientSets, pod: pod, port: alertmanagerWebPort}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no running pods found for Alertmanager %s in namespace %s", name, namespace)
+}
+
+type response struct {
+	Status string          `json:"status"`
+	Error  string          `json:"error"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Get sends a GET request to the HTTP API of Prometheus and decodes the data
+// of its response.
+func Get(ctx context.Context, client Client, path string, params url.Values, data any) error {
+	body, err := client.Get(ctx, path, params)
+	if err != nil {
+		return err
+	}
+
+	var resp response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("error while decoding Prometheus response: %w", err)
+	}
+
+	if resp.Status != "success" {
+		return fmt.Errorf("prometheus request %s failed: %s", path, resp.Error)
+	}
+
+	if err := json.Unmarshal(resp.Data, data); err != nil {
+		return fmt.Errorf("error while decoding Prometheus response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Series is a series of a range query result along with the timestamps it
+// has points at.
+type Series struct {
+	Labels     map[string]string
+	Timestamps []time.Time
+}
+
+// QueryRange runs a range query between start and end, one point every step.
+func QueryRange(ctx context.Context, client Client, expr string, start, end time.Time, step time.Duration) ([]Series, error) {
+	var data struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]any          `json:"values"`
+		} `json:"result"`
+	}
+
+	params := url.Values{
+		"query": {expr},
+		"start": {strconv.FormatInt(start.Unix(), 10)},
+		"end":   {strconv.FormatInt(end.Unix(), 10)},
+		"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	}
+	if err := Get(ctx, client, "/api/v1/query_range", params, &data); err != nil {
+		return nil, err
+	}
+
+	if data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected result type %q for query %s", data.ResultType, expr)
+	}
+
+	series := make([]Series, 0, len(data.Result))
+	for _, result := range data.Result {
+		s := Series{
+			Labels:     result.Metric,
+			Timestamps: make([]time.Time, 0, len(result.Values)),
+		}
+
+		for _, value := range result.Values {
+			ts, ok := value[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("invalid timestamp %v for query %s", value[0], expr)
+			}
+			s.Timestamps = append(s.Timestamps, time.UnixMilli(int64(ts*1000)).UTC())
+		}
+
+		series = append(series, s)
+	}
+
+	return series, nil
+}
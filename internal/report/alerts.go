@@ -0,0 +1,186 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/backtest"
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+const (
+	// firingAlertsQuery selects the firing alerts among the alerts recorded
+	// by Prometheus.
+	firingAlertsQuery = `ALERTS{alertstate="firing"}`
+	// permanentRatio is the share of the period from which a series firing
+	// that long is considered permanently firing.
+	permanentRatio = 0.9
+)
+
+// AlertOptions configures the alert fatigue report.
+type AlertOptions struct {
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+	// FlappingThreshold is the number of firings of a series from which the
+	// alert is considered flapping.
+	FlappingThreshold int
+}
+
+// AlertReport is the firing history of an alert.
+type AlertReport struct {
+	Name string
+	// Receivers are the receivers of the alert currently in Alertmanager,
+	// empty when it isn't firing anymore.
+	Receivers []string
+	// Rules are the PrometheusRules defining the alert, as namespace/name.
+	Rules      []string
+	Series     int
+	Firings    int
+	FiringTime time.Duration
+	// Flapping reports whether a series of the alert fired at least
+	// FlappingThreshold times.
+	Flapping bool
+	// Permanent reports whether a series of the alert fired for at least 90%
+	// of the period.
+	Permanent bool
+}
+
+type alertmanagerAlert struct {
+	Labels    map[string]string `json:"labels"`
+	Receivers []struct {
+		Name string `json:"name"`
+	} `json:"receivers"`
+}
+
+// Alerts returns the history of the alerts which fired during the period,
+// from the ALERTS series of Prometheus, with the receivers of the alerts
+// currently in Alertmanager and the PrometheusRules defining them. The alerts
+// firing the most often come first.
+func Alerts(ctx context.Context, prometheus, alertmanager promapi.Client, prometheusRules []monitoringv1.PrometheusRule, opts AlertOptions) ([]AlertReport, error) {
+	series, err := promapi.QueryRange(ctx, prometheus, firingAlertsQuery, opts.Start, opts.End, opts.Step)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying the alerts history: %w", err)
+	}
+
+	receivers, err := alertReceivers(ctx, alertmanager)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := alertRules(prometheusRules)
+	period := opts.End.Sub(opts.Start)
+
+	reports := map[string]*AlertReport{}
+	for _, s := range series {
+		name := s.Labels["alertname"]
+		report, ok := reports[name]
+		if !ok {
+			report = &AlertReport{
+				Name:      name,
+				Receivers: slices.Sorted(maps.Keys(receivers[name])),
+				Rules:     slices.Sorted(maps.Keys(rules[name])),
+			}
+			reports[name] = report
+		}
+
+		periods := backtest.FiringPeriods(s.Timestamps, opts.Step, 0)
+		if len(periods) == 0 {
+			continue
+		}
+
+		var firingTime time.Duration
+		for _, p := range periods {
+			// A single point still stands for one step of firing.
+			firingTime += p.End.Sub(p.Start) + opts.Step
+		}
+
+		report.Series++
+		report.Firings += len(periods)
+		report.FiringTime += firingTime
+		report.Flapping = report.Flapping || len(periods) >= opts.FlappingThreshold
+		report.Permanent = report.Permanent || float64(firingTime) >= permanentRatio*float64(period)
+	}
+
+	sorted := make([]AlertReport, 0, len(reports))
+	for _, name := range slices.Sorted(maps.Keys(reports)) {
+		sorted = append(sorted, *reports[name])
+	}
+	slices.SortStableFunc(sorted, func(a, b AlertReport) int {
+		return cmp.Or(cmp.Compare(b.Firings, a.Firings), cmp.Compare(b.FiringTime, a.FiringTime))
+	})
+
+	return sorted, nil
+}
+
+// alertReceivers returns the receivers of the alerts currently in
+// Alertmanager, by alert name. There are none without Alertmanager client.
+func alertReceivers(ctx context.Context, alertmanager promapi.Client) (map[string]map[string]struct{}, error) {
+	receivers := map[string]map[string]struct{}{}
+	if alertmanager == nil {
+		return receivers, nil
+	}
+
+	body, err := alertmanager.Get(ctx, "/api/v2/alerts", url.Values{})
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the Alertmanager alerts: %w", err)
+	}
+
+	var alerts []alertmanagerAlert
+	if err := json.Unmarshal(body, &alerts); err != nil {
+		return nil, fmt.Errorf("error while decoding the Alertmanager alerts: %w", err)
+	}
+
+	for _, alert := range alerts {
+		name := alert.Labels["alertname"]
+		if receivers[name] == nil {
+			receivers[name] = map[string]struct{}{}
+		}
+		for _, receiver := range alert.Receivers {
+			receivers[name][receiver.Name] = struct{}{}
+		}
+	}
+
+	return receivers, nil
+}
+
+// alertRules returns the PrometheusRules defining each alert, by alert name.
+func alertRules(prometheusRules []monitoringv1.PrometheusRule) map[string]map[string]struct{} {
+	rules := map[string]map[string]struct{}{}
+	for _, pr := range prometheusRules {
+		for _, group := range pr.Spec.Groups {
+			for _, rule := range group.Rules {
+				if rule.Alert == "" {
+					continue
+				}
+
+				if rules[rule.Alert] == nil {
+					rules[rule.Alert] = map[string]struct{}{}
+				}
+				rules[rule.Alert][pr.Namespace+"/"+pr.Name] = struct{}{}
+			}
+		}
+	}
+	return rules
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAlerts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	values := func(minutes ...int) [][2]any {
+		var v [][2]any
+		for _, m := range minutes {
+			v = append(v, [2]any{float64(start.Add(time.Duration(m) * time.Minute).Unix()), "1"})
+		}
+		return v
+	}
+
+	permanent := make([]int, 0, 60)
+	for m := 0; m < 60; m++ {
+		permanent = append(permanent, m)
+	}
+
+	prometheus := http.NewServeMux()
+	prometheus.HandleFunc("/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, firingAlertsQuery, r.URL.Query().Get("query"))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "matrix",
+				"result": []map[string]any{
+					{
+						"metric": map[string]string{"alertname": "Flapping", "alertstate": "firing", "pod": "a"},
+						"values": values(0, 5, 10, 20),
+					},
+					{
+						"metric": map[string]string{"alertname": "Watchdog", "alertstate": "firing"},
+						"values": values(permanent...),
+					},
+					{
+						"metric": map[string]string{"alertname": "Rare", "alertstate": "firing", "pod": "a"},
+						"values": values(30, 31),
+					},
+				},
+			},
+		})
+	})
+	prometheusServer := httptest.NewServer(prometheus)
+	defer prometheusServer.Close()
+
+	alertmanager := http.NewServeMux()
+	alertmanager.HandleFunc("/api/v2/alerts", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"labels":    map[string]string{"alertname": "Watchdog"},
+				"receivers": []map[string]string{{"name": "null"}},
+			},
+		})
+	})
+	alertmanagerServer := httptest.NewServer(alertmanager)
+	defer alertmanagerServer.Close()
+
+	rules := []monitoringv1.PrometheusRule{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"},
+			Spec: monitoringv1.PrometheusRuleSpec{
+				Groups: []monitoringv1.RuleGroup{
+					{
+						Name: "app",
+						Rules: []monitoringv1.Rule{
+							{Alert: "Flapping"},
+							{Record: "job:up:sum"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	reports, err := Alerts(context.Background(), promapi.NewURLClient(prometheusServer.URL), promapi.NewURLClient(alertmanagerServer.URL), rules, AlertOptions{
+		Start:             start,
+		End:               end,
+		Step:              time.Minute,
+		FlappingThreshold: 4,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []AlertReport{
+		{Name: "Flapping", Rules: []string{"team-a/app"}, Series: 1, Firings: 4, FiringTime: 4 * time.Minute, Flapping: true},
+		{Name: "Watchdog", Receivers: []string{"null"}, Series: 1, Firings: 1, FiringTime: time.Hour, Permanent: true},
+		{Name: "Rare", Series: 1, Firings: 1, FiringTime: 2 * time.Minute},
+	}, reports)
+}
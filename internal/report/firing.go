@@ -0,0 +1,211 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+// The states of a firing alert.
+const (
+	AlertFiring    = "firing"
+	AlertSilenced  = "silenced"
+	AlertInhibited = "inhibited"
+)
+
+// FiringAlert is an alert currently firing.
+type FiringAlert struct {
+	Name string
+	// Namespace and Severity are the values of the namespace and severity
+	// labels of the alert, empty when it doesn't have them.
+	Namespace string
+	Severity  string
+	// Labels are the other labels of the alert.
+	Labels map[string]string
+	Since  time.Time
+	// Rules are the PrometheusRules defining the alert, as namespace/name.
+	Rules []string
+	// State is firing, silenced or inhibited. It is always firing when the
+	// alerts come from Prometheus, which doesn't know about the silences.
+	State string
+	// SilencedBy are the IDs of the silences matching the alert.
+	SilencedBy []string
+}
+
+// alertmanagerFiringAlert is an alert of the Alertmanager API, with its
+// silence status.
+type alertmanagerFiringAlert struct {
+	Labels   map[string]string `json:"labels"`
+	StartsAt time.Time         `json:"startsAt"`
+	Status   struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+}
+
+// prometheusAlert is an alert of the Prometheus API.
+type prometheusAlert struct {
+	Labels   map[string]string `json:"labels"`
+	State    string            `json:"state"`
+	ActiveAt time.Time         `json:"activeAt"`
+}
+
+// Firing returns the alerts currently firing along with the PrometheusRules
+// defining them. The alerts come from Alertmanager, with their silence
+// status, or from Prometheus when there is no Alertmanager client.
+func Firing(ctx context.Context, prometheus, alertmanager promapi.Client, prometheusRules []monitoringv1.PrometheusRule) ([]FiringAlert, error) {
+	var (
+		alerts []FiringAlert
+		err    error
+	)
+	if alertmanager != nil {
+		alerts, err = alertmanagerFiringAlerts(ctx, alertmanager)
+	} else {
+		alerts, err = prometheusFiringAlerts(ctx, prometheus)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rules := alertRules(prometheusRules)
+	for i := range alerts {
+		alerts[i].Rules = definingRules(rules[alerts[i].Name], alerts[i].Namespace)
+	}
+
+	slices.SortStableFunc(alerts, func(a, b FiringAlert) int {
+		return cmp.Or(
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(FormatLabels(a.Labels), FormatLabels(b.Labels)),
+		)
+	})
+
+	return alerts, nil
+}
+
+// alertmanagerFiringAlerts returns the alerts received by Alertmanager which
+// aren't resolved yet.
+func alertmanagerFiringAlerts(ctx context.Context, alertmanager promapi.Client) ([]FiringAlert, error) {
+	body, err := alertmanager.Get(ctx, "/api/v2/alerts", url.Values{"active": {"true"}, "silenced": {"true"}, "inhibited": {"true"}})
+	if err != nil {
+		return nil, fmt.Errorf("error while getting the Alertmanager alerts: %w", err)
+	}
+
+	var received []alertmanagerFiringAlert
+	if err := json.Unmarshal(body, &received); err != nil {
+		return nil, fmt.Errorf("error while decoding the Alertmanager alerts: %w", err)
+	}
+
+	alerts := make([]FiringAlert, 0, len(received))
+	for _, a := range received {
+		alert := newFiringAlert(a.Labels, a.StartsAt)
+		switch {
+		case len(a.Status.SilencedBy) > 0:
+			alert.State = AlertSilenced
+			alert.SilencedBy = slices.Sorted(slices.Values(a.Status.SilencedBy))
+		case len(a.Status.InhibitedBy) > 0:
+			alert.State = AlertInhibited
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// prometheusFiringAlerts returns the alerts Prometheus evaluates as firing,
+// leaving out the pending ones.
+func prometheusFiringAlerts(ctx context.Context, prometheus promapi.Client) ([]FiringAlert, error) {
+	var data struct {
+		Alerts []prometheusAlert `json:"alerts"`
+	}
+	if err := promapi.Get(ctx, prometheus, "/api/v1/alerts", url.Values{}, &data); err != nil {
+		return nil, fmt.Errorf("error while getting the Prometheus alerts: %w", err)
+	}
+
+	var alerts []FiringAlert
+	for _, a := range data.Alerts {
+		if a.State != AlertFiring {
+			continue
+		}
+		alerts = append(alerts, newFiringAlert(a.Labels, a.ActiveAt))
+	}
+
+	return alerts, nil
+}
+
+// newFiringAlert returns a firing alert from its labels, splitting out the
+// alertname, namespace and severity labels.
+func newFiringAlert(labels map[string]string, since time.Time) FiringAlert {
+	alert := FiringAlert{
+		Name:      labels["alertname"],
+		Namespace: labels["namespace"],
+		Severity:  labels["severity"],
+		Labels:    map[string]string{},
+		Since:     since,
+		State:     AlertFiring,
+	}
+	for name, value := range labels {
+		switch name {
+		case "alertname", "namespace", "severity":
+		default:
+			alert.Labels[name] = value
+		}
+	}
+	return alert
+}
+
+// definingRules returns the PrometheusRules defining an alert among the
+// PrometheusRules defining an alert with its name: when several define it,
+// the ones in the namespace of the alert are kept if any, as the namespace
+// label is usually enforced for the rules of the user namespaces.
+func definingRules(rules map[string]struct{}, namespace string) []string {
+	all := slices.Sorted(maps.Keys(rules))
+	if len(all) < 2 || namespace == "" {
+		return all
+	}
+
+	var inNamespace []string
+	for _, rule := range all {
+		if strings.HasPrefix(rule, namespace+"/") {
+			inNamespace = append(inNamespace, rule)
+		}
+	}
+	if len(inNamespace) == 0 {
+		return all
+	}
+	return inNamespace
+}
+
+// FormatLabels formats labels as comma-separated name=value pairs sorted by
+// name.
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, name+"="+labels[name])
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func firingRules() []monitoringv1.PrometheusRule {
+	prometheusRule := func(namespace, name string, alerts ...string) monitoringv1.PrometheusRule {
+		pr := monitoringv1.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: monitoringv1.PrometheusRuleSpec{
+				Groups: []monitoringv1.RuleGroup{{Name: name}},
+			},
+		}
+		for _, alert := range alerts {
+			pr.Spec.Groups[0].Rules = append(pr.Spec.Groups[0].Rules, monitoringv1.Rule{Alert: alert})
+		}
+		return pr
+	}
+
+	return []monitoringv1.PrometheusRule{
+		prometheusRule("monitoring", "general", "Watchdog"),
+		prometheusRule("team-a", "app", "AppDown"),
+		prometheusRule("team-b", "app", "AppDown"),
+	}
+}
+
+func TestFiringFromAlertmanager(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	alertmanager := http.NewServeMux()
+	alertmanager.HandleFunc("/api/v2/alerts", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.URL.Query().Get("silenced"))
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{
+				"labels":   map[string]string{"alertname": "Watchdog", "severity": "none"},
+				"startsAt": since,
+				"status":   map[string]any{"state": "suppressed", "silencedBy": []string{"b", "a"}, "inhibitedBy": []string{}},
+			},
+			{
+				"labels":   map[string]string{"alertname": "AppDown", "namespace": "team-b", "severity": "critical", "pod": "app-0"},
+				"startsAt": since,
+				"status":   map[string]any{"state": "suppressed", "silencedBy": []string{}, "inhibitedBy": []string{"c"}},
+			},
+			{
+				"labels":   map[string]string{"alertname": "AppDown", "namespace": "team-a", "severity": "critical", "pod": "app-0"},
+				"startsAt": since,
+				"status":   map[string]any{"state": "active", "silencedBy": []string{}, "inhibitedBy": []string{}},
+			},
+			{
+				"labels":   map[string]string{"alertname": "Unknown"},
+				"startsAt": since,
+				"status":   map[string]any{"state": "active"},
+			},
+		})
+	})
+	alertmanagerServer := httptest.NewServer(alertmanager)
+	defer alertmanagerServer.Close()
+
+	alerts, err := Firing(context.Background(), nil, promapi.NewURLClient(alertmanagerServer.URL), firingRules())
+	require.NoError(t, err)
+
+	assert.Equal(t, []FiringAlert{
+		{Name: "AppDown", Namespace: "team-a", Severity: "critical", Labels: map[string]string{"pod": "app-0"}, Since: since, Rules: []string{"team-a/app"}, State: AlertFiring},
+		{Name: "AppDown", Namespace: "team-b", Severity: "critical", Labels: map[string]string{"pod": "app-0"}, Since: since, Rules: []string{"team-b/app"}, State: AlertInhibited},
+		{Name: "Unknown", Labels: map[string]string{}, Since: since, State: AlertFiring},
+		{Name: "Watchdog", Severity: "none", Labels: map[string]string{}, Since: since, Rules: []string{"monitoring/general"}, State: AlertSilenced, SilencedBy: []string{"a", "b"}},
+	}, alerts)
+}
+
+func TestFiringFromPrometheus(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	prometheus := http.NewServeMux()
+	prometheus.HandleFunc("/api/v1/alerts", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"alerts": []map[string]any{
+					{"labels": map[string]string{"alertname": "Watchdog"}, "state": "firing", "activeAt": since},
+					{"labels": map[string]string{"alertname": "AppDown", "namespace": "team-c"}, "state": "pending", "activeAt": since},
+					{"labels": map[string]string{"alertname": "AppDown", "namespace": "team-c", "pod": "app-1"}, "state": "firing", "activeAt": since},
+				},
+			},
+		})
+	})
+	prometheusServer := httptest.NewServer(prometheus)
+	defer prometheusServer.Close()
+
+	alerts, err := Firing(context.Background(), promapi.NewURLClient(prometheusServer.URL), nil, firingRules())
+	require.NoError(t, err)
+
+	assert.Equal(t, []FiringAlert{
+		{Name: "AppDown", Namespace: "team-c", Labels: map[string]string{"pod": "app-1"}, Since: since, Rules: []string{"team-a/app", "team-b/app"}, State: AlertFiring},
+		{Name: "Watchdog", Labels: map[string]string{}, Since: since, Rules: []string{"monitoring/general"}, State: AlertFiring},
+	}, alerts)
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, "", FormatLabels(nil))
+	assert.Equal(t, "instance=a,job=b", FormatLabels(map[string]string{"job": "b", "instance": "a"}))
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// EndpointGroup is a group of addresses of a Service sharing the same ports:
+// an EndpointSlice, or a subset of the Endpoints of the Service.
+type EndpointGroup struct {
+	Ports     []EndpointPort
+	Addresses []EndpointAddress
+}
+
+// EndpointPort is a port of an EndpointGroup.
+type EndpointPort struct {
+	Name string
+	Port int32
+}
+
+// EndpointAddress is an address of an EndpointGroup, an IPv4 or an IPv6
+// address for the dual-stack Services, with the Pod it belongs to if any.
+type EndpointAddress struct {
+	IP    string
+	Pod   string
+	Ready bool
+}
+
+// HasAddresses reports whether any of the groups has an address, ready or
+// not.
+func HasAddresses(groups []EndpointGroup) bool {
+	for _, group := range groups {
+		if len(group.Addresses) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceEndpoints returns the endpoints of a Service as Prometheus discovers
+// them with the endpointslice role: from the discovery.k8s.io EndpointSlices
+// of the Service, which aren't truncated to 1000 addresses as the Endpoints
+// are and hold the addresses of both families of the dual-stack Services.
+// The Endpoints of the Service are read instead when the cluster doesn't
+// serve the EndpointSlices or when the Service has none.
+func ServiceEndpoints(ctx context.Context, clientSets *k8sutil.ClientSets, namespace, name string) ([]EndpointGroup, error) {
+	var groups []EndpointGroup
+	err := k8sutil.EachListItem(ctx, "EndpointSlices", clientSets.KClient.DiscoveryV1().EndpointSlices(namespace).List, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: name}).String(),
+	}, func(slice *discoveryv1.EndpointSlice) error {
+		groups = append(groups, sliceGroup(slice))
+		return nil
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if len(groups) > 0 {
+		return groups, nil
+	}
+
+	endpoints, err := clientSets.KClient.CoreV1().Endpoints(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while getting endpoints %s/%s: %w", namespace, name, err)
+	}
+
+	for _, subset := range endpoints.Subsets {
+		groups = append(groups, subsetGroup(subset))
+	}
+	return groups, nil
+}
+
+// sliceGroup converts an EndpointSlice, whose endpoints are ready unless
+// their ready condition is false.
+func sliceGroup(slice *discoveryv1.EndpointSlice) EndpointGroup {
+	var group EndpointGroup
+	for _, port := range slice.Ports {
+		p := EndpointPort{}
+		if port.Name != nil {
+			p.Name = *port.Name
+		}
+		if port.Port != nil {
+			p.Port = *port.Port
+		}
+		group.Ports = append(group.Ports, p)
+	}
+
+	for _, endpoint := range slice.Endpoints {
+		ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+		for _, ip := range endpoint.Addresses {
+			group.Addresses = append(group.Addresses, EndpointAddress{IP: ip, Pod: podName(endpoint.TargetRef), Ready: ready})
+		}
+	}
+
+	return group
+}
+
+// subsetGroup converts a subset of Endpoints, the ready addresses first.
+func subsetGroup(subset v1.EndpointSubset) EndpointGroup {
+	var group EndpointGroup
+	for _, port := range subset.Ports {
+		group.Ports = append(group.Ports, EndpointPort{Name: port.Name, Port: port.Port})
+	}
+
+	for _, address := range subset.Addresses {
+		group.Addresses = append(group.Addresses, EndpointAddress{IP: address.IP, Pod: podName(address.TargetRef), Ready: true})
+	}
+
+	for _, address := range subset.NotReadyAddresses {
+		group.Addresses = append(group.Addresses, EndpointAddress{IP: address.IP, Pod: podName(address.TargetRef), Ready: false})
+	}
+
+	return group
+}
+
+func podName(ref *v1.ObjectReference) string {
+	if ref != nil && ref.Kind == "Pod" {
+		return ref.Name
+	}
+	return ""
+}
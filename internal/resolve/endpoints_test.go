@@ -0,0 +1,137 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestServiceEndpoints(t *testing.T) {
+	slice := func(service, name string, addressType discoveryv1.AddressType, addresses ...string) *discoveryv1.EndpointSlice {
+		s := &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+			},
+			AddressType: addressType,
+			Ports:       []discoveryv1.EndpointPort{{Name: ptr.To("web"), Port: ptr.To[int32](8080)}},
+		}
+		for i, address := range addresses {
+			s.Endpoints = append(s.Endpoints, discoveryv1.Endpoint{
+				Addresses:  []string{address},
+				Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(i == 0)},
+				TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: fmt.Sprintf("app-%d", i+1)},
+			})
+		}
+		return s
+	}
+
+	endpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+		Subsets: []v1.EndpointSubset{
+			{
+				Addresses:         []v1.EndpointAddress{{IP: "10.0.0.1", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "app-1"}}},
+				NotReadyAddresses: []v1.EndpointAddress{{IP: "10.0.0.2", TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "app-2"}}},
+				Ports:             []v1.EndpointPort{{Name: "web", Port: 8080}},
+			},
+		},
+	}
+
+	fromEndpoints := []EndpointGroup{
+		{
+			Ports: []EndpointPort{{Name: "web", Port: 8080}},
+			Addresses: []EndpointAddress{
+				{IP: "10.0.0.1", Pod: "app-1", Ready: true},
+				{IP: "10.0.0.2", Pod: "app-2", Ready: false},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		opts     []k8stesting.Option
+		expected []EndpointGroup
+	}{
+		{
+			name: "DualStackEndpointSlices",
+			opts: []k8stesting.Option{
+				k8stesting.WithObjects(
+					slice("app", "app-ipv4", discoveryv1.AddressTypeIPv4, "10.0.0.1", "10.0.0.2"),
+					slice("app", "app-ipv6", discoveryv1.AddressTypeIPv6, "fd00::1"),
+					slice("other", "other-ipv4", discoveryv1.AddressTypeIPv4, "10.0.1.1"),
+					endpoints,
+				),
+			},
+			expected: []EndpointGroup{
+				{
+					Ports: []EndpointPort{{Name: "web", Port: 8080}},
+					Addresses: []EndpointAddress{
+						{IP: "10.0.0.1", Pod: "app-1", Ready: true},
+						{IP: "10.0.0.2", Pod: "app-2", Ready: false},
+					},
+				},
+				{
+					Ports:     []EndpointPort{{Name: "web", Port: 8080}},
+					Addresses: []EndpointAddress{{IP: "fd00::1", Pod: "app-1", Ready: true}},
+				},
+			},
+		},
+		{
+			name:     "FallbackToEndpoints",
+			opts:     []k8stesting.Option{k8stesting.WithObjects(slice("other", "other-ipv4", discoveryv1.AddressTypeIPv4, "10.0.1.1"), endpoints)},
+			expected: fromEndpoints,
+		},
+		{
+			name: "EndpointSlicesNotServed",
+			opts: []k8stesting.Option{
+				k8stesting.WithObjects(endpoints),
+				k8stesting.WithKubeReactor("list", "endpointslices", k8stesting.NotFound()),
+			},
+			expected: fromEndpoints,
+		},
+		{
+			name: "NoEndpoints",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSets := k8stesting.NewFakeClientSets(tc.opts...)
+
+			groups, err := ServiceEndpoints(context.Background(), clientSets, "test", "app")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, groups)
+			assert.Equal(t, len(tc.expected) > 0, HasAddresses(groups))
+		})
+	}
+}
+
+func TestServiceEndpointsError(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(
+		k8stesting.WithKubeReactor("list", "endpointslices", k8stesting.Forbidden()),
+	)
+
+	_, err := ServiceEndpoints(context.Background(), clientSets, "test", "app")
+	require.Error(t, err)
+}
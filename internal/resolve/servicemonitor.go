@@ -0,0 +1,154 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Target is a scrape target which Prometheus would discover for a monitor.
+type Target struct {
+	Namespace string
+	Service   string
+	Pod       string
+	URL       string
+	Ready     bool
+}
+
+// ServiceMonitorTargets computes the targets selected by a ServiceMonitor,
+// using only the Kubernetes API: the namespace selector and the label
+// selector pick the Services, and the endpoints ports are matched against the
+// EndpointSlices of each Service.
+func ServiceMonitorTargets(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]Target, error) {
+	serviceMonitor, err := clientSets.MClient.MonitoringV1().ServiceMonitors(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("ServiceMonitor %s not found in namespace %s", name, namespace)
+		}
+		return nil, fmt.Errorf("error while getting ServiceMonitor: %w", err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&serviceMonitor.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector in ServiceMonitor %s: %w", name, err)
+	}
+
+	var targets []Target
+	for _, ns := range SelectedNamespaces(serviceMonitor.Namespace, serviceMonitor.Spec.NamespaceSelector) {
+		services, err := clientSets.KClient.CoreV1().Services(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error while listing services: %w", err)
+		}
+
+		for _, service := range services.Items {
+			groups, err := ServiceEndpoints(ctx, clientSets, service.Namespace, service.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, endpoint := range serviceMonitor.Spec.Endpoints {
+				targets = append(targets, endpointTargets(service, groups, endpoint)...)
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// SelectedNamespaces returns the namespaces matched by the namespace selector
+// of a monitor living in namespace, metav1.NamespaceAll standing for all
+// namespaces.
+func SelectedNamespaces(namespace string, selector monitoringv1.NamespaceSelector) []string {
+	if selector.Any {
+		return []string{metav1.NamespaceAll}
+	}
+
+	if len(selector.MatchNames) > 0 {
+		return selector.MatchNames
+	}
+
+	return []string{namespace}
+}
+
+// endpointTargets returns the targets of a Service matching a ServiceMonitor
+// endpoint.
+func endpointTargets(service v1.Service, groups []EndpointGroup, endpoint monitoringv1.Endpoint) []Target {
+	scheme := endpoint.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	path := endpoint.Path
+	if path == "" {
+		path = "/metrics"
+	}
+
+	var targets []Target
+	for _, group := range groups {
+		for _, port := range group.Ports {
+			if !portMatches(service, port, endpoint) {
+				continue
+			}
+
+			for _, address := range group.Addresses {
+				targets = append(targets, Target{
+					Namespace: service.Namespace,
+					Service:   service.Name,
+					Pod:       address.Pod,
+					URL:       fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(address.IP, strconv.Itoa(int(port.Port))), path),
+					Ready:     address.Ready,
+				})
+			}
+		}
+	}
+
+	return targets
+}
+
+// portMatches reports whether an endpoints port is selected by the
+// ServiceMonitor endpoint, either by name or by target port. An endpoint
+// without port nor targetPort selects all the ports.
+func portMatches(service v1.Service, port EndpointPort, endpoint monitoringv1.Endpoint) bool {
+	if endpoint.Port != "" {
+		return port.Name == endpoint.Port
+	}
+
+	if endpoint.TargetPort == nil {
+		return true
+	}
+
+	if endpoint.TargetPort.IntValue() != 0 {
+		return int(port.Port) == endpoint.TargetPort.IntValue()
+	}
+
+	for _, servicePort := range service.Spec.Ports {
+		if servicePort.Name == port.Name && servicePort.TargetPort.String() == endpoint.TargetPort.String() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resolve
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestServiceMonitorTargets(t *testing.T) {
+	type testCase struct {
+		name            string
+		serviceMonitor  *monitoringv1.ServiceMonitor
+		expectedTargets []Target
+		shouldFail      bool
+	}
+
+	service := func(namespace string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app",
+				Namespace: namespace,
+				Labels:    map[string]string{"app": "test"},
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{
+					{
+						Name:       "web",
+						Port:       80,
+						TargetPort: intstr.FromString("http"),
+					},
+				},
+			},
+		}
+	}
+
+	endpoints := func(namespace string) *v1.Endpoints {
+		return &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app",
+				Namespace: namespace,
+			},
+			Subsets: []v1.EndpointSubset{
+				{
+					Addresses: []v1.EndpointAddress{
+						{
+							IP:        "10.0.0.1",
+							TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "app-1"},
+						},
+					},
+					NotReadyAddresses: []v1.EndpointAddress{
+						{
+							IP:        "10.0.0.2",
+							TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "app-2"},
+						},
+					},
+					Ports: []v1.EndpointPort{
+						{
+							Name: "web",
+							Port: 8080,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	serviceMonitor := func(spec monitoringv1.ServiceMonitorSpec) *monitoringv1.ServiceMonitor {
+		return &monitoringv1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app",
+				Namespace: "test",
+			},
+			Spec: spec,
+		}
+	}
+
+	tests := []testCase{
+		{
+			name:       "ServiceMonitorNotFound",
+			shouldFail: true,
+		},
+		{
+			name: "MatchingPortName",
+			serviceMonitor: serviceMonitor(monitoringv1.ServiceMonitorSpec{
+				Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				Endpoints: []monitoringv1.Endpoint{{Port: "web"}},
+			}),
+			expectedTargets: []Target{
+				{Namespace: "test", Service: "app", Pod: "app-1", URL: "http://10.0.0.1:8080/metrics", Ready: true},
+				{Namespace: "test", Service: "app", Pod: "app-2", URL: "http://10.0.0.2:8080/metrics", Ready: false},
+			},
+		},
+		{
+			name: "MatchingTargetPort",
+			serviceMonitor: serviceMonitor(monitoringv1.ServiceMonitorSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				Endpoints: []monitoringv1.Endpoint{
+					{
+						TargetPort: ptr.To(intstr.FromString("http")),
+						Scheme:     "https",
+						Path:       "/custom",
+					},
+				},
+			}),
+			expectedTargets: []Target{
+				{Namespace: "test", Service: "app", Pod: "app-1", URL: "https://10.0.0.1:8080/custom", Ready: true},
+				{Namespace: "test", Service: "app", Pod: "app-2", URL: "https://10.0.0.2:8080/custom", Ready: false},
+			},
+		},
+		{
+			name: "NoPortNorTargetPort",
+			serviceMonitor: serviceMonitor(monitoringv1.ServiceMonitorSpec{
+				Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				Endpoints: []monitoringv1.Endpoint{{}},
+			}),
+			expectedTargets: []Target{
+				{Namespace: "test", Service: "app", Pod: "app-1", URL: "http://10.0.0.1:8080/metrics", Ready: true},
+				{Namespace: "test", Service: "app", Pod: "app-2", URL: "http://10.0.0.2:8080/metrics", Ready: false},
+			},
+		},
+		{
+			name: "NoMatchingPort",
+			serviceMonitor: serviceMonitor(monitoringv1.ServiceMonitorSpec{
+				Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				Endpoints: []monitoringv1.Endpoint{{Port: "metrics"}},
+			}),
+		},
+		{
+			name: "NoMatchingLabels",
+			serviceMonitor: serviceMonitor(monitoringv1.ServiceMonitorSpec{
+				Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}},
+				Endpoints: []monitoringv1.Endpoint{{Port: "web"}},
+			}),
+		},
+		{
+			name: "NamespaceSelectorMatchNames",
+			serviceMonitor: serviceMonitor(monitoringv1.ServiceMonitorSpec{
+				NamespaceSelector: monitoringv1.NamespaceSelector{MatchNames: []string{"other"}},
+				Selector:          metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+				Endpoints:         []monitoringv1.Endpoint{{Port: "web"}},
+			}),
+			expectedTargets: []Target{
+				{Namespace: "other", Service: "app", Pod: "app-1", URL: "http://10.0.0.1:8080/metrics", Ready: true},
+				{Namespace: "other", Service: "app", Pod: "app-2", URL: "http://10.0.0.2:8080/metrics", Ready: false},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mClient := monitoringclient.NewSimpleClientset()
+			if tc.serviceMonitor != nil {
+				mClient = monitoringclient.NewSimpleClientset(tc.serviceMonitor)
+			}
+
+			clientSets := k8sutil.ClientSets{
+				MClient: mClient,
+				KClient: fake.NewSimpleClientset(service("test"), endpoints("test"), service("other"), endpoints("other")),
+			}
+
+			targets, err := ServiceMonitorTargets(context.Background(), &clientSets, "app", "test")
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedTargets, targets)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Error classes recorded instead of the error messages, which may contain
+// names of the cluster objects.
+const (
+	ErrorClassNotFound     = "not_found"
+	ErrorClassForbidden    = "forbidden"
+	ErrorClassUnauthorized = "unauthorized"
+	ErrorClassInvalid      = "invalid"
+	ErrorClassTimeout      = "timeout"
+	ErrorClassNetwork      = "network"
+	ErrorClassOther        = "other"
+)
+
+const sendTimeout = 2 * time.Second
+
+// CommandStats are the usage statistics of a command.
+type CommandStats struct {
+	Runs   int            `json:"runs"`
+	Errors map[string]int `json:"errors,omitempty"`
+}
+
+// State is the telemetry configuration along with the usage statistics
+// recorded locally.
+type State struct {
+	Enabled  bool                     `json:"enabled"`
+	Endpoint string                   `json:"endpoint,omitempty"`
+	Commands map[string]*CommandStats `json:"commands,omitempty"`
+}
+
+// Event is the anonymized usage event sent to the telemetry endpoint.
+type Event struct {
+	Command    string `json:"command"`
+	ErrorClass string `json:"errorClass,omitempty"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+}
+
+// DefaultPath returns the path of the telemetry state file in the user
+// configuration directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error while getting user config directory: %w", err)
+	}
+	return filepath.Join(dir, "poctl", "telemetry.json"), nil
+}
+
+// Load reads the telemetry state, a missing file being a disabled telemetry.
+func Load(path string) (*State, error) {
+	state := &State{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("error while reading telemetry state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error while parsing telemetry state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// Save writes the telemetry state.
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error while marshaling telemetry state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error while creating telemetry directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("error while writing telemetry state: %w", err)
+	}
+	return nil
+}
+
+// Record adds a run of the command to the statistics and returns the
+// corresponding event. Nothing is recorded when the telemetry is disabled.
+func (s *State) Record(command string, err error) (*Event, bool) {
+	if !s.Enabled {
+		return nil, false
+	}
+
+	if s.Commands == nil {
+		s.Commands = map[string]*CommandStats{}
+	}
+
+	stats, ok := s.Commands[command]
+	if !ok {
+		stats = &CommandStats{}
+		s.Commands[command] = stats
+	}
+	stats.Runs++
+
+	event := &Event{
+		Command: command,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+	}
+
+	if err != nil {
+		event.ErrorClass = ErrorClass(err)
+		if stats.Errors == nil {
+			stats.Errors = map[string]int{}
+		}
+		stats.Errors[event.ErrorClass]++
+	}
+
+	return event, true
+}
+
+// Send posts the event to the telemetry endpoint.
+func Send(ctx context.Context, endpoint string, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error while marshaling telemetry event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error while creating telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while sending telemetry event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ErrorClass returns the class of an error.
+func ErrorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case apierrors.IsNotFound(err):
+		return ErrorClassNotFound
+	case apierrors.IsForbidden(err):
+		return ErrorClassForbidden
+	case apierrors.IsUnauthorized(err):
+		return ErrorClassUnauthorized
+	case apierrors.IsInvalid(err) || apierrors.IsBadRequest(err):
+		return ErrorClassInvalid
+	case errors.Is(err, context.DeadlineExceeded) || apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err):
+		return ErrorClassTimeout
+	case errors.As(err, &netErr):
+		return ErrorClassNetwork
+	default:
+		return ErrorClassOther
+	}
+}
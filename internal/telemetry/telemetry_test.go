@@ -0,0 +1,69 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+
+	state, err := Load(path)
+	require.NoError(t, err)
+
+	_, recorded := state.Record("analyze prometheus", nil)
+	assert.False(t, recorded)
+
+	state.Enabled = true
+	_, recorded = state.Record("analyze prometheus", nil)
+	assert.True(t, recorded)
+
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "prometheuses"}, "k8s")
+	event, recorded := state.Record("analyze prometheus", fmt.Errorf("error while getting Prometheus: %w", notFound))
+	assert.True(t, recorded)
+	assert.Equal(t, ErrorClassNotFound, event.ErrorClass)
+	require.NoError(t, state.Save(path))
+
+	state, err = Load(path)
+	require.NoError(t, err)
+	assert.True(t, state.Enabled)
+	assert.Equal(t, &CommandStats{Runs: 2, Errors: map[string]int{ErrorClassNotFound: 1}}, state.Commands["analyze prometheus"])
+}
+
+func TestErrorClass(t *testing.T) {
+	gr := schema.GroupResource{Resource: "servicemonitors"}
+
+	for _, tc := range []struct {
+		err      error
+		expected string
+	}{
+		{err: apierrors.NewForbidden(gr, "app", errors.New("denied")), expected: ErrorClassForbidden},
+		{err: apierrors.NewUnauthorized("unauthorized"), expected: ErrorClassUnauthorized},
+		{err: context.DeadlineExceeded, expected: ErrorClassTimeout},
+		{err: errors.New("ServiceMonitor app not found in namespace default"), expected: ErrorClassOther},
+	} {
+		assert.Equal(t, tc.expected, ErrorClass(tc.err))
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+)
+
+// Orders of the monitors.
+const (
+	SortBySamples  = "samples"
+	SortByDuration = "duration"
+)
+
+// SortOrders are the supported orders of the monitors.
+var SortOrders = []string{SortBySamples, SortByDuration}
+
+// scrapePoolKinds maps the prefix of the scrape pools generated by the
+// operator to the kind of the monitor they come from.
+var scrapePoolKinds = map[string]string{
+	"serviceMonitor": "ServiceMonitor",
+	"podMonitor":     "PodMonitor",
+	"probe":          "Probe",
+	"scrapeConfig":   "ScrapeConfig",
+}
+
+// MonitorLoad is the scrape load of the targets of a monitor. The scrape pools
+// which don't come from a monitor, such as additional scrape configs, have
+// an empty kind and are named after the pool.
+type MonitorLoad struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Jobs are the values of the job label of the targets.
+	Jobs    []string
+	Targets int
+	// Samples is the number of samples scraped from the targets at their
+	// last scrape.
+	Samples float64
+	// Duration is the sum of the last scrape durations of the targets.
+	Duration time.Duration
+}
+
+type targetsData struct {
+	ActiveTargets []struct {
+		Labels     map[string]string `json:"labels"`
+		ScrapePool string            `json:"scrapePool"`
+	} `json:"activeTargets"`
+}
+
+type queryData struct {
+	ResultType string `json:"resultType"`
+	Result     []struct {
+		Metric map[string]string `json:"metric"`
+		Value  [2]any            `json:"value"`
+	} `json:"result"`
+}
+
+// Monitors queries the scrape samples and durations of the targets of each
+// Prometheus and sums them per monitor, mapping the targets to their monitor
+// with the scrape pool reported by the targets API. The endpoints of a monitor
+// each have their own scrape pool.
+func Monitors(ctx context.Context, clients []promapi.Client) ([]MonitorLoad, error) {
+	loads := map[string]*MonitorLoad{}
+
+	for _, client := range clients {
+		pools, err := scrapePools(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		samples, err := query(ctx, client, "scrape_samples_scraped")
+		if err != nil {
+			return nil, err
+		}
+
+		durations, err := query(ctx, client, "scrape_duration_seconds")
+		if err != nil {
+			return nil, err
+		}
+
+		for key, pool := range pools {
+			monitor := newMonitorLoad(pool.name)
+			monitorKey := monitor.Kind + "/" + monitor.Namespace + "/" + monitor.Name
+			load, ok := loads[monitorKey]
+			if !ok {
+				load = monitor
+				loads[monitorKey] = load
+			}
+
+			load.Targets++
+			load.Samples += samples[key]
+			load.Duration += time.Duration(durations[key] * float64(time.Second))
+			if !slices.Contains(load.Jobs, pool.job) {
+				load.Jobs = append(load.Jobs, pool.job)
+			}
+		}
+	}
+
+	monitors := make([]MonitorLoad, 0, len(loads))
+	for _, key := range slices.Sorted(maps.Keys(loads)) {
+		slices.Sort(loads[key].Jobs)
+		monitors = append(monitors, *loads[key])
+	}
+	return monitors, nil
+}
+
+// Top returns the limit heaviest monitors, by number of samples or by scrape
+// duration. All the monitors are returned when limit isn't positive.
+func Top(monitors []MonitorLoad, sortBy string, limit int) []MonitorLoad {
+	sorted := slices.Clone(monitors)
+	slices.SortStableFunc(sorted, func(a, b MonitorLoad) int {
+		if sortBy == SortByDuration {
+			return cmp.Or(cmp.Compare(b.Duration, a.Duration), cmp.Compare(b.Samples, a.Samples))
+		}
+		return cmp.Or(cmp.Compare(b.Samples, a.Samples), cmp.Compare(b.Duration, a.Duration))
+	})
+
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+// newMonitorLoad returns the load of the monitor generating the scrape pool,
+// following the naming of the operator: <kind>/<namespace>/<name>[/<index>].
+func newMonitorLoad(pool string) *MonitorLoad {
+	parts := strings.Split(pool, "/")
+	if kind, ok := scrapePoolKinds[parts[0]]; ok && len(parts) >= 3 {
+		return &MonitorLoad{Kind: kind, Namespace: parts[1], Name: parts[2]}
+	}
+	return &MonitorLoad{Name: pool}
+}
+
+type targetPool struct {
+	name string
+	job  string
+}
+
+// scrapePools returns the scrape pool and the job of the active targets, by
+// target labels.
+func scrapePools(ctx context.Context, client promapi.Client) (map[string]targetPool, error) {
+	var data targetsData
+	if err := promapi.Get(ctx, client, "/api/v1/targets", url.Values{"state": {"active"}}, &data); err != nil {
+		return nil, err
+	}
+
+	pools := make(map[string]targetPool, len(data.ActiveTargets))
+	for _, target := range data.ActiveTargets {
+		pools[labelsKey(target.Labels)] = targetPool{name: target.ScrapePool, job: target.Labels["job"]}
+	}
+	return pools, nil
+}
+
+// query runs an instant query and returns the values of the result series, by
+// target labels.
+func query(ctx context.Context, client promapi.Client, expr string) (map[string]float64, error) {
+	var data queryData
+	if err := promapi.Get(ctx, client, "/api/v1/query", url.Values{"query": {expr}}, &data); err != nil {
+		return nil, err
+	}
+
+	if data.ResultType != "vector" {
+		return nil, fmt.Errorf("unexpected result type %q for query %s", data.ResultType, expr)
+	}
+
+	values := make(map[string]float64, len(data.Result))
+	for _, sample := range data.Result {
+		raw, _ := sample.Value[1].(string)
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for query %s: %w", raw, expr, err)
+		}
+		values[labelsKey(sample.Metric)] = value
+	}
+	return values, nil
+}
+
+// labelsKey returns a key identifying the target of a label set, the metric
+// name being ignored.
+func labelsKey(labels map[string]string) string {
+	var pairs []string
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		if name == "__name__" {
+			continue
+		}
+		pairs = append(pairs, name+"="+strconv.Quote(labels[name]))
+	}
+	return strings.Join(pairs, ",")
+}
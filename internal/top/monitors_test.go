@@ -0,0 +1,132 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/promapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrometheusServer(t *testing.T, targets []map[string]any, samples, durations map[string]string) *httptest.Server {
+	t.Helper()
+
+	vector := func(values map[string]string) map[string]any {
+		var result []map[string]any
+		for instance, value := range values {
+			result = append(result, map[string]any{
+				"metric": map[string]string{"job": "app", "instance": instance},
+				"value":  []any{1700000000, value},
+			})
+		}
+		return map[string]any{"resultType": "vector", "result": result}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/targets", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   map[string]any{"activeTargets": targets},
+		})
+	})
+	mux.HandleFunc("/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+		values := samples
+		if r.URL.Query().Get("query") == "scrape_duration_seconds" {
+			values = durations
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data":   vector(values),
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func activeTarget(instance, pool string) map[string]any {
+	return map[string]any{
+		"labels":     map[string]string{"job": "app", "instance": instance},
+		"scrapePool": pool,
+	}
+}
+
+func TestMonitors(t *testing.T) {
+	server := newPrometheusServer(t,
+		[]map[string]any{
+			activeTarget("10.0.0.1:8080", "serviceMonitor/team-a/app/0"),
+			activeTarget("10.0.0.2:8080", "serviceMonitor/team-a/app/1"),
+			activeTarget("10.0.0.3:8080", "podMonitor/team-b/workers/0"),
+			activeTarget("10.0.0.4:8080", "scrapeConfig/team-c/external"),
+			activeTarget("10.0.0.5:8080", "federate"),
+		},
+		map[string]string{
+			"10.0.0.1:8080": "100",
+			"10.0.0.2:8080": "150",
+			"10.0.0.3:8080": "1000",
+			"10.0.0.4:8080": "10",
+			"10.0.0.5:8080": "5",
+		},
+		map[string]string{
+			"10.0.0.1:8080": "0.1",
+			"10.0.0.2:8080": "0.2",
+			"10.0.0.3:8080": "0.05",
+			"10.0.0.4:8080": "2",
+			"10.0.0.5:8080": "0.01",
+		},
+	)
+
+	monitors, err := Monitors(context.Background(), []promapi.Client{promapi.NewURLClient(server.URL)})
+	require.NoError(t, err)
+
+	assert.Equal(t, []MonitorLoad{
+		{Name: "federate", Jobs: []string{"app"}, Targets: 1, Samples: 5, Duration: 10 * time.Millisecond},
+		{Kind: "PodMonitor", Namespace: "team-b", Name: "workers", Jobs: []string{"app"}, Targets: 1, Samples: 1000, Duration: 50 * time.Millisecond},
+		{Kind: "ScrapeConfig", Namespace: "team-c", Name: "external", Jobs: []string{"app"}, Targets: 1, Samples: 10, Duration: 2 * time.Second},
+		{Kind: "ServiceMonitor", Namespace: "team-a", Name: "app", Jobs: []string{"app"}, Targets: 2, Samples: 250, Duration: 300 * time.Millisecond},
+	}, monitors)
+
+	var names []string
+	for _, m := range Top(monitors, SortBySamples, 2) {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"workers", "app"}, names)
+
+	names = nil
+	for _, m := range Top(monitors, SortByDuration, 0) {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"external", "app", "workers", "federate"}, names)
+}
+
+func TestMonitorsQueryError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/targets", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err := Monitors(context.Background(), []promapi.Client{promapi.NewURLClient(server.URL)})
+	assert.ErrorContains(t, err, "status 503")
+}
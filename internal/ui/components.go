@@ -0,0 +1,193 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ui implements the interactive browser of the monitoring
+// components of a cluster, letting power users check their health, drill
+// down into the findings of the analyzers and act on them.
+package ui
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Health is the health of a component, as reported by the operator.
+type Health string
+
+const (
+	Healthy     Health = "healthy"
+	Degraded    Health = "degraded"
+	Unavailable Health = "unavailable"
+	Paused      Health = "paused"
+	Unknown     Health = "unknown"
+)
+
+// The kinds of the components.
+const (
+	KindPrometheus      = "Prometheus"
+	KindPrometheusAgent = "PrometheusAgent"
+	KindAlertmanager    = "Alertmanager"
+	KindThanosRuler     = "ThanosRuler"
+)
+
+// Component is a workload managed by the operator.
+type Component struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Replicas is the number of desired replicas, of each shard for the
+	// Prometheus and PrometheusAgent components.
+	Replicas          int32
+	AvailableReplicas int32
+	Paused            bool
+	Health            Health
+	// Message is the message of the Available condition, if any.
+	Message string
+}
+
+// ListComponents returns the Prometheus, PrometheusAgent, Alertmanager and
+// ThanosRuler components of all the namespaces, sorted by namespace, kind
+// and name.
+func ListComponents(ctx context.Context, clientSets *k8sutil.ClientSets) ([]Component, error) {
+	var components []Component
+
+	prometheuses, err := clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Prometheus: %w", err)
+	}
+	for _, p := range prometheuses.Items {
+		components = append(components, newComponent(KindPrometheus, p.ObjectMeta, p.Spec.Replicas, p.Spec.Paused, p.Status.AvailableReplicas, p.Status.Conditions))
+	}
+
+	agents, err := clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing PrometheusAgents: %w", err)
+	}
+	for _, a := range agents.Items {
+		components = append(components, newComponent(KindPrometheusAgent, a.ObjectMeta, a.Spec.Replicas, a.Spec.Paused, a.Status.AvailableReplicas, a.Status.Conditions))
+	}
+
+	alertmanagers, err := clientSets.MClient.MonitoringV1().Alertmanagers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Alertmanagers: %w", err)
+	}
+	for _, a := range alertmanagers.Items {
+		components = append(components, newComponent(KindAlertmanager, a.ObjectMeta, a.Spec.Replicas, a.Spec.Paused, a.Status.AvailableReplicas, a.Status.Conditions))
+	}
+
+	rulers, err := clientSets.MClient.MonitoringV1().ThanosRulers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error while listing ThanosRulers: %w", err)
+	}
+	for _, r := range rulers.Items {
+		components = append(components, newComponent(KindThanosRuler, r.ObjectMeta, r.Spec.Replicas, r.Spec.Paused, r.Status.AvailableReplicas, r.Status.Conditions))
+	}
+
+	slices.SortFunc(components, func(a, b Component) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name))
+	})
+	return components, nil
+}
+
+// newComponent returns the component of an object, one replica being
+// desired when the spec doesn't set them.
+func newComponent(kind string, meta metav1.ObjectMeta, replicas *int32, paused bool, available int32, conditions []monitoringv1.Condition) Component {
+	c := Component{
+		Kind:              kind,
+		Namespace:         meta.Namespace,
+		Name:              meta.Name,
+		Replicas:          1,
+		AvailableReplicas: available,
+		Paused:            paused,
+	}
+	if replicas != nil {
+		c.Replicas = *replicas
+	}
+
+	c.Health, c.Message = health(paused, conditions)
+	return c
+}
+
+// health returns the health of a component from its Available condition,
+// unknown when the operator didn't report it yet.
+func health(paused bool, conditions []monitoringv1.Condition) (Health, string) {
+	if paused {
+		return Paused, ""
+	}
+
+	for _, c := range conditions {
+		if c.Type != monitoringv1.Available {
+			continue
+		}
+
+		switch c.Status {
+		case monitoringv1.ConditionTrue:
+			return Healthy, c.Message
+		case monitoringv1.ConditionDegraded:
+			return Degraded, c.Message
+		default:
+			return Unavailable, c.Message
+		}
+	}
+	return Unknown, ""
+}
+
+// SetPaused pauses or resumes the reconciliation of the component by the
+// operator.
+func SetPaused(ctx context.Context, clientSets *k8sutil.ClientSets, c Component, paused bool) error {
+	return patchSpec(ctx, clientSets, c, map[string]any{"paused": paused})
+}
+
+// Scale sets the number of desired replicas of the component.
+func Scale(ctx context.Context, clientSets *k8sutil.ClientSets, c Component, replicas int32) error {
+	if replicas < 0 {
+		return fmt.Errorf("invalid number of replicas %d", replicas)
+	}
+	return patchSpec(ctx, clientSets, c, map[string]any{"replicas": replicas})
+}
+
+// patchSpec merges the fields into the spec of the component.
+func patchSpec(ctx context.Context, clientSets *k8sutil.ClientSets, c Component, spec map[string]any) error {
+	data, err := json.Marshal(map[string]any{"spec": spec})
+	if err != nil {
+		return fmt.Errorf("error while encoding patch: %w", err)
+	}
+
+	opts := metav1.PatchOptions{FieldManager: k8sutil.ApplyOption.FieldManager}
+	switch c.Kind {
+	case KindPrometheus:
+		_, err = clientSets.MClient.MonitoringV1().Prometheuses(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, data, opts)
+	case KindPrometheusAgent:
+		_, err = clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, data, opts)
+	case KindAlertmanager:
+		_, err = clientSets.MClient.MonitoringV1().Alertmanagers(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, data, opts)
+	case KindThanosRuler:
+		_, err = clientSets.MClient.MonitoringV1().ThanosRulers(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, data, opts)
+	default:
+		return fmt.Errorf("kind %s not supported", c.Kind)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while patching %s %s/%s: %w", c.Kind, c.Namespace, c.Name, err)
+	}
+	return nil
+}
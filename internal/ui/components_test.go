@@ -0,0 +1,83 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func available(status monitoringv1.ConditionStatus, message string) []monitoringv1.Condition {
+	return []monitoringv1.Condition{{Type: monitoringv1.Available, Status: status, Message: message}}
+}
+
+func TestListComponents(t *testing.T) {
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.Prometheus{
+			ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+			Spec:       monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{Replicas: ptr.To[int32](2)}},
+			Status:     monitoringv1.PrometheusStatus{AvailableReplicas: 1, Conditions: available(monitoringv1.ConditionDegraded, "1 of 2 replicas are available")},
+		},
+		&monitoringv1.Alertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+			Status:     monitoringv1.AlertmanagerStatus{AvailableReplicas: 1, Conditions: available(monitoringv1.ConditionTrue, "")},
+		},
+		&monitoringv1alpha1.PrometheusAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "edge"},
+			Spec:       monitoringv1alpha1.PrometheusAgentSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{Paused: true}},
+		},
+		&monitoringv1.ThanosRuler{
+			ObjectMeta: metav1.ObjectMeta{Name: "ruler", Namespace: "monitoring"},
+			Status:     monitoringv1.ThanosRulerStatus{Conditions: available(monitoringv1.ConditionFalse, "no replica is available")},
+		},
+	))
+
+	components, err := ListComponents(context.Background(), clientSets)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Component{
+		{Kind: KindPrometheusAgent, Namespace: "edge", Name: "agent", Replicas: 1, Paused: true, Health: Paused},
+		{Kind: KindAlertmanager, Namespace: "monitoring", Name: "main", Replicas: 1, AvailableReplicas: 1, Health: Healthy},
+		{Kind: KindPrometheus, Namespace: "monitoring", Name: "k8s", Replicas: 2, AvailableReplicas: 1, Health: Degraded, Message: "1 of 2 replicas are available"},
+		{Kind: KindThanosRuler, Namespace: "monitoring", Name: "ruler", Replicas: 1, Health: Unavailable, Message: "no replica is available"},
+	}, components)
+}
+
+func TestSetPausedAndScale(t *testing.T) {
+	ctx := context.Background()
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.Alertmanager{ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"}},
+	))
+	c := Component{Kind: KindAlertmanager, Namespace: "monitoring", Name: "main"}
+
+	require.NoError(t, SetPaused(ctx, clientSets, c, true))
+	require.NoError(t, Scale(ctx, clientSets, c, 3))
+
+	am, err := clientSets.MClient.MonitoringV1().Alertmanagers("monitoring").Get(ctx, "main", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, am.Spec.Paused)
+	assert.Equal(t, ptr.To[int32](3), am.Spec.Replicas)
+
+	require.EqualError(t, Scale(ctx, clientSets, c, -1), "invalid number of replicas -1")
+	require.ErrorContains(t, SetPaused(ctx, clientSets, Component{Kind: KindPrometheus, Namespace: "monitoring", Name: "missing"}, true), "error while patching Prometheus monitoring/missing")
+}
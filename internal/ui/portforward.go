@@ -0,0 +1,145 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// Forward is a local port forwarded to the web port of a pod of a component
+// through the API server, until it is closed.
+type Forward struct {
+	Pod        string
+	LocalPort  uint16
+	RemotePort int
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// Close stops forwarding the port, it does nothing on a nil Forward.
+func (f *Forward) Close() {
+	if f == nil {
+		return
+	}
+	f.once.Do(func() {
+		if f.stop != nil {
+			close(f.stop)
+		}
+	})
+}
+
+func (f *Forward) String() string {
+	return fmt.Sprintf("localhost:%d -> %s:%d", f.LocalPort, f.Pod, f.RemotePort)
+}
+
+// webEndpoint returns the label selector of the pods of the component and
+// their web port.
+func webEndpoint(c Component) (string, int, error) {
+	switch c.Kind {
+	case KindPrometheus:
+		return "app.kubernetes.io/name=prometheus,operator.prometheus.io/name=" + c.Name, 9090, nil
+	case KindPrometheusAgent:
+		return "app.kubernetes.io/name=prometheus-agent,operator.prometheus.io/name=" + c.Name, 9090, nil
+	case KindAlertmanager:
+		return "app.kubernetes.io/name=alertmanager,alertmanager=" + c.Name, 9093, nil
+	case KindThanosRuler:
+		return "app.kubernetes.io/name=thanos-ruler,thanos-ruler=" + c.Name, 10902, nil
+	}
+	return "", 0, fmt.Errorf("kind %s not supported", c.Kind)
+}
+
+// forwardTarget returns the first running pod of the component and its web
+// port.
+func forwardTarget(ctx context.Context, clientSets *k8sutil.ClientSets, c Component) (string, int, error) {
+	selector, port, err := webEndpoint(c)
+	if err != nil {
+		return "", 0, err
+	}
+
+	pods, err := clientSets.KClient.CoreV1().Pods(c.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", 0, fmt.Errorf("error while listing pods of %s %s/%s: %w", c.Kind, c.Namespace, c.Name, err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			return pod.Name, port, nil
+		}
+	}
+	return "", 0, fmt.Errorf("no running pods found for %s %s in namespace %s", c.Kind, c.Name, c.Namespace)
+}
+
+// PortForward forwards a free local port to the web port of a running pod of
+// the component, as kubectl port-forward does.
+func PortForward(ctx context.Context, config *rest.Config, clientSets *k8sutil.ClientSets, c Component) (*Forward, error) {
+	pod, port, err := forwardTarget(ctx, clientSets, c)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating port-forward transport: %w", err)
+	}
+
+	url := clientSets.KClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(c.Namespace).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	f := &Forward{Pod: pod, RemotePort: port, stop: make(chan struct{})}
+	ready := make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, []string{fmt.Sprintf("0:%d", port)}, f.stop, ready, io.Discard, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating port-forward: %w", err)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- forwarder.ForwardPorts()
+	}()
+
+	select {
+	case <-ready:
+	case err := <-errs:
+		return nil, fmt.Errorf("error while forwarding port to pod %s: %w", pod, err)
+	case <-ctx.Done():
+		f.Close()
+		return nil, ctx.Err()
+	}
+
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error while getting forwarded port: %w", err)
+	}
+	f.LocalPort = ports[0].Local
+	return f, nil
+}
@@ -0,0 +1,545 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+)
+
+// DefaultInterval is the default interval between the refreshes of the
+// health of the components.
+const DefaultInterval = 5 * time.Second
+
+const (
+	listHelp   = "↑/↓ select • enter show • a analyze • p pause/resume • s scale • f port-forward • r refresh • q quit"
+	detailHelp = "esc back • a analyze • p pause/resume • s scale • f port-forward • r refresh • q quit"
+	scaleHelp  = "enter apply • esc cancel"
+)
+
+var (
+	titleStyle  = lipgloss.NewStyle().Bold(true)
+	labelStyle  = lipgloss.NewStyle().Bold(true).Width(12)
+	helpStyle   = lipgloss.NewStyle().Faint(true)
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	healthStyle = map[Health]lipgloss.Style{
+		Healthy:     lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+		Degraded:    lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+		Unavailable: lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+	}
+)
+
+// Session is a terminal UI listing the components with their health,
+// refreshed periodically, and acting on the selected one.
+type Session struct {
+	ClientSets *k8sutil.ClientSets
+	// Analyze analyzes a component, returning the warnings and the failure
+	// of the analysis.
+	Analyze func(ctx context.Context, c Component) ([]string, error)
+	// PortForward forwards a local port to the web port of a component.
+	PortForward func(ctx context.Context, c Component) (*Forward, error)
+	// Interval is the interval between the refreshes of the health of the
+	// components, DefaultInterval if zero.
+	Interval time.Duration
+	In       io.Reader
+	Out      io.Writer
+}
+
+// Run runs the terminal UI until the user quits or the context is done. The
+// failures of the actions are shown in the status line and don't end the
+// session. The forwarded ports are closed on exit.
+func (s *Session) Run(ctx context.Context) error {
+	opts := []tea.ProgramOption{tea.WithContext(ctx), tea.WithAltScreen()}
+	if s.In != nil {
+		opts = append(opts, tea.WithInput(s.In))
+	}
+	if s.Out != nil {
+		opts = append(opts, tea.WithOutput(s.Out))
+	}
+
+	final, err := tea.NewProgram(newModel(ctx, s), opts...).Run()
+	if m, ok := final.(model); ok {
+		m.closeForwards()
+	}
+	if err != nil {
+		return fmt.Errorf("error while running the terminal UI: %w", err)
+	}
+	return nil
+}
+
+// analysis is the result of the analysis of a component.
+type analysis struct {
+	warnings []string
+	err      error
+}
+
+// componentsMsg is sent with the listed components. Only the periodic
+// refreshes schedule the next one, the refreshes after an action don't.
+type componentsMsg struct {
+	components []Component
+	err        error
+	periodic   bool
+}
+
+// tickMsg is sent when the components are due for a periodic refresh.
+type tickMsg struct{}
+
+type analysisMsg struct {
+	key string
+	analysis
+}
+
+// actionMsg is sent when an action changing a component is done.
+type actionMsg struct {
+	status string
+	err    error
+}
+
+type forwardMsg struct {
+	key     string
+	forward *Forward
+	err     error
+}
+
+type model struct {
+	ctx     context.Context
+	session *Session
+
+	table      table.Model
+	components []Component
+	// detail is true when the selected component is shown.
+	detail bool
+	// scaling is true when the number of replicas is prompted.
+	scaling bool
+	input   textinput.Model
+
+	analyses  map[string]analysis
+	analyzing bool
+	// forwards are the forwarded ports by component, nil while the port
+	// is being forwarded.
+	forwards map[string]*Forward
+
+	status   string
+	failed   bool
+	listErr  error
+	interval time.Duration
+}
+
+func newModel(ctx context.Context, s *Session) model {
+	// f and b are the port-forward and back keys.
+	keys := table.DefaultKeyMap()
+	keys.PageUp = key.NewBinding(key.WithKeys("pgup"))
+	keys.PageDown = key.NewBinding(key.WithKeys("pgdown"))
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "NAMESPACE", Width: 20},
+			{Title: "KIND", Width: 16},
+			{Title: "NAME", Width: 24},
+			{Title: "REPLICAS", Width: 9},
+			{Title: "HEALTH", Width: 12},
+			{Title: "PORT", Width: 16},
+		}),
+		table.WithFocused(true),
+		table.WithKeyMap(keys),
+	)
+
+	input := textinput.New()
+	input.Placeholder = "replicas"
+	input.CharLimit = 6
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return model{
+		ctx:      ctx,
+		session:  s,
+		table:    t,
+		input:    input,
+		analyses: map[string]analysis{},
+		forwards: map[string]*Forward{},
+		interval: interval,
+	}
+}
+
+func componentKey(c Component) string {
+	return c.Kind + "/" + c.Namespace + "/" + c.Name
+}
+
+func (m model) Init() tea.Cmd {
+	return m.list(true)
+}
+
+func (m model) list(periodic bool) tea.Cmd {
+	return func() tea.Msg {
+		components, err := ListComponents(m.ctx, m.session.ClientSets)
+		return componentsMsg{components: components, err: err, periodic: periodic}
+	}
+}
+
+// selected returns the component under the cursor.
+func (m model) selected() (Component, bool) {
+	i := m.table.Cursor()
+	if i < 0 || i >= len(m.components) {
+		return Component{}, false
+	}
+	return m.components[i], true
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Leave room for the title, the header and the status lines.
+		m.table.SetHeight(max(msg.Height-6, 3))
+		return m, nil
+
+	case tickMsg:
+		return m, m.list(true)
+
+	case componentsMsg:
+		var next tea.Cmd
+		if msg.periodic {
+			next = tea.Tick(m.interval, func(time.Time) tea.Msg { return tickMsg{} })
+		}
+		m.listErr = msg.err
+		if msg.err == nil {
+			m.setComponents(msg.components)
+		}
+		return m, next
+
+	case analysisMsg:
+		m.analyzing = false
+		m.analyses[msg.key] = msg.analysis
+		if msg.err != nil {
+			m.setStatus(fmt.Errorf("analysis of %s failed: %w", msg.key, msg.err))
+		} else {
+			m.setStatus(fmt.Sprintf("analysis of %s passed with %d warnings", msg.key, len(msg.warnings)))
+		}
+		return m, nil
+
+	case actionMsg:
+		if msg.err != nil {
+			m.setStatus(msg.err)
+			return m, nil
+		}
+		m.setStatus(msg.status)
+		return m, m.list(false)
+
+	case forwardMsg:
+		if msg.err != nil {
+			delete(m.forwards, msg.key)
+			m.setStatus(fmt.Errorf("port-forward to %s failed: %w", msg.key, msg.err))
+			return m, nil
+		}
+		m.forwards[msg.key] = msg.forward
+		m.setStatus(fmt.Sprintf("forwarding %s", msg.forward))
+		m.setComponents(m.components)
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.scaling {
+			return m.updateScaling(msg)
+		}
+		return m.updateKey(msg)
+	}
+
+	return m, nil
+}
+
+func (m model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		m.closeForwards()
+		return m, tea.Quit
+	case "r":
+		return m, m.list(false)
+	case "esc", "b", "backspace":
+		m.detail = false
+		return m, nil
+	}
+
+	c, ok := m.selected()
+	if !ok {
+		var cmd tea.Cmd
+		m.table, cmd = m.table.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "enter":
+		m.detail = true
+		return m, nil
+	case "a":
+		return m.analyze(c)
+	case "p":
+		paused := !c.Paused
+		return m, func() tea.Msg {
+			if err := SetPaused(m.ctx, m.session.ClientSets, c, paused); err != nil {
+				return actionMsg{err: err}
+			}
+			if paused {
+				return actionMsg{status: fmt.Sprintf("paused %s", componentKey(c))}
+			}
+			return actionMsg{status: fmt.Sprintf("resumed %s", componentKey(c))}
+		}
+	case "s":
+		m.scaling = true
+		m.input.SetValue(strconv.Itoa(int(c.Replicas)))
+		m.input.CursorEnd()
+		return m, m.input.Focus()
+	case "f":
+		return m.toggleForward(c)
+	}
+
+	if m.detail {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateScaling(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.scaling = false
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		m.scaling = false
+		m.input.Blur()
+
+		c, ok := m.selected()
+		if !ok {
+			return m, nil
+		}
+		value := strings.TrimSpace(m.input.Value())
+		replicas, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			m.setStatus(fmt.Errorf("invalid number of replicas %s", value))
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			if err := Scale(m.ctx, m.session.ClientSets, c, int32(replicas)); err != nil {
+				return actionMsg{err: err}
+			}
+			return actionMsg{status: fmt.Sprintf("scaled %s to %d replicas", componentKey(c), replicas)}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// analyze runs the analysis of the component. One analysis runs at a time
+// as the warnings of the analyzers are recorded globally.
+func (m model) analyze(c Component) (tea.Model, tea.Cmd) {
+	if m.analyzing {
+		m.setStatus(fmt.Errorf("an analysis is already running"))
+		return m, nil
+	}
+
+	m.analyzing = true
+	m.setStatus(fmt.Sprintf("analyzing %s...", componentKey(c)))
+	return m, func() tea.Msg {
+		warnings, err := m.session.Analyze(m.ctx, c)
+		return analysisMsg{key: componentKey(c), analysis: analysis{warnings: warnings, err: err}}
+	}
+}
+
+// toggleForward forwards a local port to the component, or closes the
+// forwarded one.
+func (m model) toggleForward(c Component) (tea.Model, tea.Cmd) {
+	k := componentKey(c)
+	if f, ok := m.forwards[k]; ok {
+		if f == nil {
+			m.setStatus(fmt.Sprintf("forwarding a port to %s...", k))
+			return m, nil
+		}
+		f.Close()
+		delete(m.forwards, k)
+		m.setStatus(fmt.Sprintf("stopped forwarding %s", f))
+		m.setComponents(m.components)
+		return m, nil
+	}
+
+	if m.session.PortForward == nil {
+		m.setStatus(fmt.Errorf("port-forward not supported"))
+		return m, nil
+	}
+
+	m.forwards[k] = nil
+	m.setStatus(fmt.Sprintf("forwarding a port to %s...", k))
+	return m, func() tea.Msg {
+		f, err := m.session.PortForward(m.ctx, c)
+		return forwardMsg{key: k, forward: f, err: err}
+	}
+}
+
+func (m model) closeForwards() {
+	for _, f := range m.forwards {
+		f.Close()
+	}
+}
+
+// setStatus sets the status line to a message or an error.
+func (m *model) setStatus(status any) {
+	switch s := status.(type) {
+	case error:
+		m.status, m.failed = s.Error(), true
+	default:
+		m.status, m.failed = fmt.Sprint(s), false
+	}
+}
+
+// setComponents sets the rows of the table, keeping the cursor on the
+// selected component when it still exists.
+func (m *model) setComponents(components []Component) {
+	selected, hadSelected := m.selected()
+
+	rows := make([]table.Row, 0, len(components))
+	cursor := 0
+	found := false
+	for i, c := range components {
+		port := ""
+		if f := m.forwards[componentKey(c)]; f != nil {
+			port = fmt.Sprintf("localhost:%d", f.LocalPort)
+		}
+		rows = append(rows, table.Row{
+			c.Namespace,
+			c.Kind,
+			c.Name,
+			fmt.Sprintf("%d/%d", c.AvailableReplicas, c.Replicas),
+			string(c.Health),
+			port,
+		})
+		if hadSelected && componentKey(c) == componentKey(selected) {
+			cursor, found = i, true
+		}
+	}
+
+	m.components = components
+	m.table.SetRows(rows)
+	m.table.SetCursor(cursor)
+	if hadSelected && !found {
+		m.detail = false
+	}
+}
+
+func (m model) View() string {
+	var b strings.Builder
+
+	if m.detail {
+		c, _ := m.selected()
+		b.WriteString(m.viewComponent(c))
+	} else {
+		b.WriteString(m.viewList())
+	}
+	b.WriteString("\n")
+
+	if m.listErr != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("error while refreshing: %v", m.listErr)) + "\n")
+	}
+	if m.status != "" {
+		if m.failed {
+			b.WriteString(errorStyle.Render(m.status) + "\n")
+		} else {
+			b.WriteString(m.status + "\n")
+		}
+	}
+
+	switch {
+	case m.scaling:
+		b.WriteString("Replicas: " + m.input.View() + "\n")
+		b.WriteString(helpStyle.Render(scaleHelp))
+	case m.detail:
+		b.WriteString(helpStyle.Render(detailHelp))
+	default:
+		b.WriteString(helpStyle.Render(listHelp))
+	}
+	return b.String()
+}
+
+func (m model) viewList() string {
+	if len(m.components) == 0 {
+		return "No Prometheus, PrometheusAgent, Alertmanager or ThanosRuler found.\n"
+	}
+
+	namespaces := map[string]struct{}{}
+	unhealthy := 0
+	for _, c := range m.components {
+		namespaces[c.Namespace] = struct{}{}
+		if c.Health != Healthy {
+			unhealthy++
+		}
+	}
+
+	title := fmt.Sprintf("%d components in %d namespaces, %d not healthy", len(m.components), len(namespaces), unhealthy)
+	return titleStyle.Render(title) + "\n" + m.table.View() + "\n"
+}
+
+func (m model) viewComponent(c Component) string {
+	var b strings.Builder
+	field := func(label, value string) {
+		b.WriteString(labelStyle.Render(label) + value + "\n")
+	}
+
+	field("Kind:", c.Kind)
+	field("Namespace:", c.Namespace)
+	field("Name:", c.Name)
+	field("Replicas:", fmt.Sprintf("%d available of %d", c.AvailableReplicas, c.Replicas))
+	field("Paused:", strconv.FormatBool(c.Paused))
+	style, ok := healthStyle[c.Health]
+	if !ok {
+		style = lipgloss.NewStyle()
+	}
+	field("Health:", style.Render(string(c.Health)))
+	if c.Message != "" {
+		field("Message:", c.Message)
+	}
+	if f := m.forwards[componentKey(c)]; f != nil {
+		field("Forward:", f.String())
+	}
+
+	a, ok := m.analyses[componentKey(c)]
+	if !ok {
+		return b.String()
+	}
+
+	b.WriteString("\n")
+	if a.err != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Analysis failed: %v", a.err)) + "\n")
+	} else {
+		b.WriteString("Analysis passed.\n")
+	}
+	for _, w := range a.warnings {
+		b.WriteString(fmt.Sprintf("  warning: %s\n", w))
+	}
+	return b.String()
+}
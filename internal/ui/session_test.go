@@ -0,0 +1,263 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// send updates the model with a message and then with the messages of the
+// returned commands, except the ticks.
+func send(t *testing.T, m model, msg tea.Msg) model {
+	t.Helper()
+
+	updated, cmd := m.Update(msg)
+	m = updated.(model)
+	if cmd == nil {
+		return m
+	}
+	if _, ok := msg.(componentsMsg); ok {
+		return m
+	}
+
+	next := cmd()
+	if _, ok := next.(tea.QuitMsg); ok {
+		return m
+	}
+	return send(t, m, next)
+}
+
+func newTestSession(clientSets *k8sutil.ClientSets) *Session {
+	return &Session{
+		ClientSets: clientSets,
+		Analyze: func(_ context.Context, c Component) ([]string, error) {
+			if c.Kind == KindAlertmanager {
+				return nil, errors.New("no analyzer")
+			}
+			return []string{"PR101: enableAdminAPI is enabled"}, nil
+		},
+		PortForward: func(_ context.Context, c Component) (*Forward, error) {
+			return &Forward{Pod: "prometheus-" + c.Name + "-0", LocalPort: 34567, RemotePort: 9090}, nil
+		},
+	}
+}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.Alertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+			Status:     monitoringv1.AlertmanagerStatus{AvailableReplicas: 1, Conditions: available(monitoringv1.ConditionTrue, "")},
+		},
+		&monitoringv1.Prometheus{
+			ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+			Spec:       monitoringv1.PrometheusSpec{CommonPrometheusFields: monitoringv1.CommonPrometheusFields{Replicas: ptr.To[int32](2)}},
+			Status:     monitoringv1.PrometheusStatus{AvailableReplicas: 1, Conditions: available(monitoringv1.ConditionDegraded, "1 of 2 replicas are available")},
+		},
+	))
+
+	m := newModel(ctx, newTestSession(clientSets))
+	m = send(t, m, m.Init()())
+	assert.Contains(t, m.View(), "2 components in 1 namespaces, 1 not healthy")
+	assert.Contains(t, m.View(), "degraded")
+
+	// Select the Prometheus and show it.
+	m = send(t, m, keyMsg("down"))
+	m = send(t, m, keyMsg("enter"))
+	require.True(t, m.detail)
+	assert.Contains(t, m.View(), "1 available of 2")
+	assert.Contains(t, m.View(), "1 of 2 replicas are available")
+
+	m = send(t, m, keyMsg("a"))
+	assert.False(t, m.analyzing)
+	assert.Contains(t, m.View(), "Analysis passed.")
+	assert.Contains(t, m.View(), "warning: PR101: enableAdminAPI is enabled")
+
+	m = send(t, m, keyMsg("f"))
+	assert.Contains(t, m.View(), "localhost:34567 -> prometheus-k8s-0:9090")
+	m = send(t, m, keyMsg("f"))
+	assert.Contains(t, m.View(), "stopped forwarding localhost:34567 -> prometheus-k8s-0:9090")
+	assert.Empty(t, m.forwards)
+
+	m = send(t, m, keyMsg("p"))
+	assert.Contains(t, m.View(), "paused Prometheus/monitoring/k8s")
+
+	m = send(t, m, keyMsg("s"))
+	require.True(t, m.scaling)
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = send(t, m, keyMsg("3"))
+	m = send(t, m, keyMsg("enter"))
+	assert.False(t, m.scaling)
+	assert.Contains(t, m.View(), "scaled Prometheus/monitoring/k8s to 3 replicas")
+
+	// The refresh after the actions keeps the component shown.
+	require.True(t, m.detail)
+	c, ok := m.selected()
+	require.True(t, ok)
+	assert.Equal(t, "k8s", c.Name)
+	assert.True(t, c.Paused)
+	assert.Equal(t, int32(3), c.Replicas)
+
+	p, err := clientSets.MClient.MonitoringV1().Prometheuses("monitoring").Get(ctx, "k8s", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, p.Spec.Paused)
+	assert.Equal(t, ptr.To[int32](3), p.Spec.Replicas)
+
+	// Back to the list, the failures are shown in the status line.
+	m = send(t, m, keyMsg("esc"))
+	require.False(t, m.detail)
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyUp})
+	m = send(t, m, keyMsg("a"))
+	assert.Contains(t, m.View(), "analysis of Alertmanager/monitoring/main failed: no analyzer")
+	m = send(t, m, keyMsg("s"))
+	m = send(t, m, keyMsg("x"))
+	m = send(t, m, keyMsg("enter"))
+	assert.Contains(t, m.View(), "invalid number of replicas 1x")
+
+	_, cmd := m.Update(keyMsg("q"))
+	require.NotNil(t, cmd)
+	assert.IsType(t, tea.QuitMsg{}, cmd())
+}
+
+func TestSessionThanosRuler(t *testing.T) {
+	ctx := context.Background()
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.ThanosRuler{
+			ObjectMeta: metav1.ObjectMeta{Name: "ruler", Namespace: "monitoring"},
+			Status:     monitoringv1.ThanosRulerStatus{AvailableReplicas: 1, Conditions: available(monitoringv1.ConditionTrue, "")},
+		},
+	))
+
+	var analyzed []Component
+	s := newTestSession(clientSets)
+	s.Analyze = func(_ context.Context, c Component) ([]string, error) {
+		analyzed = append(analyzed, c)
+		return nil, errors.New("TR001: ServiceAccount thanos-ruler of ThanosRuler ruler not found in namespace monitoring")
+	}
+
+	m := newModel(ctx, s)
+	m = send(t, m, m.Init()())
+	m = send(t, m, keyMsg("enter"))
+	require.True(t, m.detail)
+	assert.Contains(t, m.View(), "ThanosRuler")
+
+	m = send(t, m, keyMsg("a"))
+	require.Len(t, analyzed, 1)
+	assert.Equal(t, KindThanosRuler, analyzed[0].Kind)
+	assert.Equal(t, "ruler", analyzed[0].Name)
+	assert.Contains(t, m.View(), "TR001: ServiceAccount thanos-ruler of ThanosRuler ruler not found in namespace monitoring")
+}
+
+func TestSessionRefresh(t *testing.T) {
+	ctx := context.Background()
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		&monitoringv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"}},
+	))
+
+	m := newModel(ctx, newTestSession(clientSets))
+
+	// Only the periodic refreshes schedule the next one.
+	msg := m.Init()()
+	require.Equal(t, true, msg.(componentsMsg).periodic)
+	updated, cmd := m.Update(msg)
+	m = updated.(model)
+	require.NotNil(t, cmd)
+
+	updated, cmd = m.Update(m.list(false)())
+	m = updated.(model)
+	require.Nil(t, cmd)
+
+	updated, cmd = m.Update(tickMsg{})
+	m = updated.(model)
+	require.Equal(t, true, cmd().(componentsMsg).periodic)
+
+	// The health is refreshed.
+	p, err := clientSets.MClient.MonitoringV1().Prometheuses("monitoring").Get(ctx, "k8s", metav1.GetOptions{})
+	require.NoError(t, err)
+	p.Status = monitoringv1.PrometheusStatus{AvailableReplicas: 1, Conditions: available(monitoringv1.ConditionTrue, "")}
+	_, err = clientSets.MClient.MonitoringV1().Prometheuses("monitoring").Update(ctx, p, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	m = send(t, m, tickMsg{})
+	assert.Contains(t, m.View(), "0 not healthy")
+
+	// A failed refresh keeps the components.
+	m = send(t, m, componentsMsg{err: errors.New("connection refused")})
+	assert.Contains(t, m.View(), "error while refreshing: connection refused")
+	assert.Len(t, m.components, 1)
+}
+
+func TestForwardTarget(t *testing.T) {
+	pod := func(name string, labels map[string]string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "monitoring", Labels: labels},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	clientSets := k8stesting.NewFakeClientSets(k8stesting.WithObjects(
+		pod("prometheus-k8s-0", map[string]string{"app.kubernetes.io/name": "prometheus", "operator.prometheus.io/name": "k8s"}, corev1.PodPending),
+		pod("prometheus-k8s-1", map[string]string{"app.kubernetes.io/name": "prometheus", "operator.prometheus.io/name": "k8s"}, corev1.PodRunning),
+		pod("alertmanager-main-0", map[string]string{"app.kubernetes.io/name": "alertmanager", "alertmanager": "main"}, corev1.PodRunning),
+		pod("thanos-ruler-ruler-0", map[string]string{"app.kubernetes.io/name": "thanos-ruler", "thanos-ruler": "ruler"}, corev1.PodFailed),
+	))
+
+	for _, tc := range []struct {
+		component Component
+		pod       string
+		port      int
+		err       string
+	}{
+		{component: Component{Kind: KindPrometheus, Namespace: "monitoring", Name: "k8s"}, pod: "prometheus-k8s-1", port: 9090},
+		{component: Component{Kind: KindAlertmanager, Namespace: "monitoring", Name: "main"}, pod: "alertmanager-main-0", port: 9093},
+		{component: Component{Kind: KindThanosRuler, Namespace: "monitoring", Name: "ruler"}, err: "no running pods found for ThanosRuler ruler in namespace monitoring"},
+		{component: Component{Kind: KindPrometheusAgent, Namespace: "monitoring", Name: "k8s"}, err: "no running pods found for PrometheusAgent k8s in namespace monitoring"},
+	} {
+		t.Run(tc.component.Kind, func(t *testing.T) {
+			pod, port, err := forwardTarget(context.Background(), clientSets, tc.component)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.pod, pod)
+			assert.Equal(t, tc.port, port)
+		})
+	}
+}
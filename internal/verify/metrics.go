@@ -0,0 +1,71 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"github.com/prometheus-operator/poctl/internal/analyzers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// metrics are the metrics of a verification run. The verifier is a CronJob
+// which can't be scraped, the metrics are pushed to a Pushgateway.
+type metrics struct {
+	objects  *prometheus.GaugeVec
+	failed   *prometheus.GaugeVec
+	findings *prometheus.GaugeVec
+	lastRun  prometheus.Gauge
+}
+
+// newMetrics returns the metrics of a run, registered with the registerer
+// unless it is nil.
+func newMetrics(reg prometheus.Registerer) *metrics {
+	factory := promauto.With(reg)
+	return &metrics{
+		objects: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "poctl_verify_objects",
+			Help: "Number of objects analyzed by the last verification run.",
+		}, []string{"kind"}),
+		failed: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "poctl_verify_failed_objects",
+			Help: "Number of objects failing the analysis of the last verification run.",
+		}, []string{"kind"}),
+		findings: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "poctl_verify_findings",
+			Help: "Number of error and warning findings of the objects analyzed by the last verification run.",
+		}, []string{"kind", "namespace", "name", "check", "severity"}),
+		lastRun: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "poctl_verify_last_run_timestamp_seconds",
+			Help: "Time of the end of the last verification run.",
+		}),
+	}
+}
+
+// observe records the analysis of an object. The informational findings,
+// such as the compliance of the object, aren't counted.
+func (m *metrics) observe(ref corev1.ObjectReference, findings []analyzers.Finding, failed bool) {
+	m.objects.WithLabelValues(ref.Kind).Inc()
+	if failed {
+		m.failed.WithLabelValues(ref.Kind).Inc()
+	}
+
+	for _, f := range findings {
+		if f.Severity == analyzers.SeverityInfo {
+			continue
+		}
+		m.findings.WithLabelValues(ref.Kind, ref.Namespace, ref.Name, string(f.Check), string(f.Severity)).Inc()
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus-operator/poctl/internal/analyzers"
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EventReason is the reason of the Events recorded on the objects
+	// failing the analysis.
+	EventReason = "PoctlAnalysisFailed"
+	// WarningEventReason is the reason of the Events recorded on the objects
+	// for each warning of the analysis, which doesn't fail it.
+	WarningEventReason = "PoctlAnalysisWarning"
+)
+
+// target is an object of the cluster along with the analyzer checking it.
+type target struct {
+	reference corev1.ObjectReference
+	analyze   func(ctx context.Context, clientSets *k8sutil.ClientSets, name, namespace string) ([]analyzers.Finding, error)
+}
+
+// Run analyzes the Prometheus Operator deployments and the Prometheus,
+// PrometheusAgent, Alertmanager, ServiceMonitor and ScrapeConfig objects of
+// the whole cluster. A Warning Event is recorded on every object failing the analysis,
+// and an error listing them is returned. The warnings of the analysis are
+// logged and recorded as Warning Events too, without failing the run. The
+// metrics of the run are registered with the registerer unless it is nil.
+func Run(ctx context.Context, clientSets *k8sutil.ClientSets, reg prometheus.Registerer) error {
+	targets, err := discover(ctx, clientSets)
+	if err != nil {
+		return err
+	}
+
+	m := newMetrics(reg)
+	defer m.lastRun.SetToCurrentTime()
+
+	var errs []error
+	for _, t := range targets {
+		findings, err := t.analyze(ctx, clientSets, t.reference.Name, t.reference.Namespace)
+		recordWarnings(ctx, clientSets, t.reference, findings)
+		if err == nil {
+			err = analyzers.Err(findings)
+		}
+		m.observe(t.reference, findings, err != nil)
+		if err == nil {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("%s %s/%s: %w", t.reference.Kind, t.reference.Namespace, t.reference.Name, err))
+		if err := recordEvent(ctx, clientSets, t.reference, EventReason, err.Error()); err != nil {
+			slog.Error("error while recording event", "kind", t.reference.Kind, "name", t.reference.Name, "namespace", t.reference.Namespace, "error", err)
+		}
+	}
+
+	slog.Info("verification done", "objects", len(targets), "failed", len(errs))
+	return errors.Join(errs...)
+}
+
+func discover(ctx context.Context, clientSets *k8sutil.ClientSets) ([]target, error) {
+	var targets []target
+
+	err := k8sutil.EachListItem(ctx, "Prometheus Operator deployments", clientSets.KClient.AppsV1().Deployments(metav1.NamespaceAll).List, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=prometheus-operator",
+	}, func(d *appsv1.Deployment) error {
+		targets = append(targets, target{
+			reference: reference("apps/v1", "Deployment", d.ObjectMeta),
+			analyze:   analyzers.RunOperatorAnalyzer,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Prometheuses", clientSets.MClient.MonitoringV1().Prometheuses(metav1.NamespaceAll).List, metav1.ListOptions{}, func(p *monitoringv1.Prometheus) error {
+		targets = append(targets, target{
+			reference: reference(monitoringv1.SchemeGroupVersion.String(), monitoringv1.PrometheusesKind, p.ObjectMeta),
+			analyze:   analyzers.RunPrometheusAnalyzer,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "PrometheusAgents", clientSets.MClient.MonitoringV1alpha1().PrometheusAgents(metav1.NamespaceAll).List, metav1.ListOptions{}, func(a *monitoringv1alpha1.PrometheusAgent) error {
+		targets = append(targets, target{
+			reference: reference("monitoring.coreos.com/v1alpha1", "PrometheusAgent", a.ObjectMeta),
+			analyze:   analyzers.RunPrometheusAgentAnalyzer,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "Alertmanagers", clientSets.MClient.MonitoringV1().Alertmanagers(metav1.NamespaceAll).List, metav1.ListOptions{}, func(a *monitoringv1.Alertmanager) error {
+		targets = append(targets, target{
+			reference: reference(monitoringv1.SchemeGroupVersion.String(), monitoringv1.AlertmanagersKind, a.ObjectMeta),
+			analyze:   analyzers.RunAlertmanagerAnalyzer,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ServiceMonitors", clientSets.MClient.MonitoringV1().ServiceMonitors(metav1.NamespaceAll).List, metav1.ListOptions{}, func(sm *monitoringv1.ServiceMonitor) error {
+		targets = append(targets, target{
+			reference: reference(monitoringv1.SchemeGroupVersion.String(), monitoringv1.ServiceMonitorsKind, sm.ObjectMeta),
+			analyze:   analyzers.RunServiceMonitorAnalyzer,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = k8sutil.EachListItem(ctx, "ScrapeConfigs", clientSets.MClient.MonitoringV1alpha1().ScrapeConfigs(metav1.NamespaceAll).List, metav1.ListOptions{}, func(sc *monitoringv1alpha1.ScrapeConfig) error {
+		targets = append(targets, target{
+			reference: reference("monitoring.coreos.com/v1alpha1", "ScrapeConfig", sc.ObjectMeta),
+			analyze:   analyzers.RunScrapeConfigAnalyzer,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+func reference(apiVersion, kind string, meta metav1.ObjectMeta) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Name:       meta.Name,
+		Namespace:  meta.Namespace,
+		UID:        meta.UID,
+	}
+}
+
+// recordWarnings logs the warnings of the analysis of the object and records
+// them as Events.
+func recordWarnings(ctx context.Context, clientSets *k8sutil.ClientSets, ref corev1.ObjectReference, findings []analyzers.Finding) {
+	for _, f := range findings {
+		if f.Severity != analyzers.SeverityWarning {
+			continue
+		}
+
+		slog.Warn(f.Message, "id", f.Check, "kind", ref.Kind, "name", ref.Name, "namespace", ref.Namespace, "hint", f.Fix)
+		if err := recordEvent(ctx, clientSets, ref, WarningEventReason, fmt.Sprintf("%s: %s", f.Check, f.Message)); err != nil {
+			slog.Error("error while recording event", "kind", ref.Kind, "name", ref.Name, "namespace", ref.Namespace, "error", err)
+		}
+	}
+}
+
+// recordEvent records a Warning Event on the object, which shows up in
+// kubectl describe and in the event exporters.
+func recordEvent(ctx context.Context, clientSets *k8sutil.ClientSets, ref corev1.ObjectReference, reason, message string) error {
+	now := metav1.NewTime(time.Now())
+	_, err := clientSets.KClient.CoreV1().Events(ref.Namespace).Create(ctx, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s.", ref.Name),
+			Namespace:    ref.Namespace,
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeWarning,
+		Source: corev1.EventSource{
+			Component: "poctl-verifier",
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}, metav1.CreateOptions{})
+	return err
+}
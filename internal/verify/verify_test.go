@@ -0,0 +1,152 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus-operator/poctl/internal/k8sutil"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestRun(t *testing.T) {
+	type testCase struct {
+		name           string
+		prometheuses   []monitoringv1.Prometheus
+		expectedEvents map[string]int
+		// expectedWarnings is the number of warning findings counted by
+		// the metrics.
+		expectedWarnings float64
+		shouldFail       bool
+	}
+
+	tests := []testCase{
+		{
+			name: "NoObjects",
+		},
+		{
+			// The ServiceAccount of the Prometheus isn't bound to any
+			// ClusterRole, which fails the analysis.
+			name: "FailingPrometheus",
+			prometheuses: []monitoringv1.Prometheus{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+					Spec: monitoringv1.PrometheusSpec{
+						CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+							ServiceAccountName: "prometheus",
+						},
+					},
+				},
+			},
+			expectedEvents: map[string]int{EventReason: 1},
+			shouldFail:     true,
+		},
+		{
+			// The admin API is exposed without protection, which is
+			// reported as a warning along with the failure.
+			name: "FailingPrometheusWithWarning",
+			prometheuses: []monitoringv1.Prometheus{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+					Spec: monitoringv1.PrometheusSpec{
+						CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+							ServiceAccountName: "prometheus",
+						},
+						EnableAdminAPI: true,
+					},
+				},
+			},
+			expectedEvents:   map[string]int{EventReason: 1, WarningEventReason: 1},
+			expectedWarnings: 1,
+			shouldFail:       true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mClient := monitoringclient.NewSimpleClientset()
+			for i := range tc.prometheuses {
+				_, err := mClient.MonitoringV1().Prometheuses(tc.prometheuses[i].Namespace).Create(context.Background(), &tc.prometheuses[i], metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			kClient := fake.NewSimpleClientset()
+			// The fake clientset doesn't generate the names of the Events.
+			var generated int
+			kClient.PrependReactor("create", "events", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				event := action.(clienttesting.CreateAction).GetObject().(*corev1.Event)
+				generated++
+				event.Name = fmt.Sprintf("%s%d", event.GenerateName, generated)
+				return false, nil, nil
+			})
+			clientSets := &k8sutil.ClientSets{KClient: kClient, MClient: mClient}
+
+			reg := prometheus.NewRegistry()
+			err := Run(context.Background(), clientSets, reg)
+			if tc.shouldFail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			metrics, err := reg.Gather()
+			require.NoError(t, err)
+			values := map[string]float64{}
+			for _, family := range metrics {
+				for _, metric := range family.GetMetric() {
+					name := family.GetName()
+					for _, label := range metric.GetLabel() {
+						if label.GetName() == "severity" {
+							name += ":" + label.GetValue()
+						}
+					}
+					values[name] += metric.GetGauge().GetValue()
+				}
+			}
+			assert.Equal(t, float64(len(tc.prometheuses)), values["poctl_verify_objects"])
+			if tc.shouldFail {
+				assert.Equal(t, float64(1), values["poctl_verify_failed_objects"])
+				assert.NotZero(t, values["poctl_verify_findings:error"])
+			}
+			assert.Equal(t, tc.expectedWarnings, values["poctl_verify_findings:warning"])
+			assert.NotZero(t, values["poctl_verify_last_run_timestamp_seconds"])
+
+			events, err := kClient.CoreV1().Events(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			reasons := map[string]int{}
+			for _, e := range events.Items {
+				reasons[e.Reason]++
+				assert.Equal(t, corev1.EventTypeWarning, e.Type)
+				assert.Equal(t, "Prometheus", e.InvolvedObject.Kind)
+			}
+			if tc.expectedEvents == nil {
+				assert.Empty(t, reasons)
+			} else {
+				assert.Equal(t, tc.expectedEvents, reasons)
+			}
+		})
+	}
+}
@@ -15,6 +15,8 @@
 package builder
 
 import (
+	"fmt"
+
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -24,10 +26,19 @@ import (
 )
 
 type AlertManagerBuilder struct {
-	labels         map[string]string
-	labelSelectors map[string]string
-	namespace      string
-	manifets       AlertManagerManifests
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	stack            string
+	name             string
+	replicas         int32
+	version          string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifets         AlertManagerManifests
+	// reloaderMonitoring scrapes the config-reloader sidecar.
+	reloaderMonitoring bool
 }
 
 type AlertManagerManifests struct {
@@ -39,6 +50,10 @@ type AlertManagerManifests struct {
 
 const AlertManagerName = "alertmanager"
 
+// AlertManagerImage is the image of Alertmanager, tagged with the version
+// pinned by WithVersion.
+const AlertManagerImage = "quay.io/prometheus/alertmanager"
+
 func NewAlertManager(namespace string) *AlertManagerBuilder {
 	return &AlertManagerBuilder{
 		labels: map[string]string{
@@ -48,7 +63,61 @@ func NewAlertManager(namespace string) *AlertManagerBuilder {
 			"alertmanager": AlertManagerName,
 		},
 		namespace: namespace,
+		name:      AlertManagerName,
+		replicas:  1,
+	}
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster. The Alertmanager of the stack
+// only selects the AlertmanagerConfigs labeled as part of the stack, and is
+// reconciled by the operator of the stack.
+func (a *AlertManagerBuilder) WithStack(stack string) *AlertManagerBuilder {
+	a.stack = stack
+	a.name = stackObjectName(stack, AlertManagerName)
+	a.labels["alertmanager"] = a.name
+	a.labelSelectors["alertmanager"] = a.name
+	if stack != "" {
+		a.labels[PartOfLabel] = stack
 	}
+	return a
+}
+
+// WithReplicas sets the number of replicas of the Alertmanager built
+// afterwards.
+func (a *AlertManagerBuilder) WithReplicas(replicas int32) *AlertManagerBuilder {
+	a.replicas = replicas
+	return a
+}
+
+// WithVersion pins the version of the Alertmanager built afterwards, along
+// with the tag of its image. The operator picks its default version when
+// unset.
+func (a *AlertManagerBuilder) WithVersion(version string) *AlertManagerBuilder {
+	a.version = version
+	return a
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Alertmanager built afterwards.
+func (a *AlertManagerBuilder) WithImagePullSecrets(names ...string) *AlertManagerBuilder {
+	a.imagePullSecrets = append(a.imagePullSecrets, names...)
+	return a
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the Alertmanager replicas built afterwards.
+func (a *AlertManagerBuilder) WithScheduling(scheduling Scheduling) *AlertManagerBuilder {
+	a.scheduling = scheduling
+	return a
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (a *AlertManagerBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *AlertManagerBuilder {
+	a.ipFamilyPolicy = policy
+	return a
 }
 
 func (a *AlertManagerBuilder) WithServiceAccount() *AlertManagerBuilder {
@@ -58,10 +127,11 @@ func (a *AlertManagerBuilder) WithServiceAccount() *AlertManagerBuilder {
 			APIVersion: ptr.To("v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To(AlertManagerName),
+			Name:      ptr.To(a.name),
 			Labels:    a.labels,
 			Namespace: ptr.To(a.namespace),
 		},
+		ImagePullSecrets: podImagePullSecrets(a.imagePullSecrets),
 	}
 	return a
 }
@@ -73,17 +143,30 @@ func (a *AlertManagerBuilder) WithAlertManager() *AlertManagerBuilder {
 			APIVersion: ptr.To("monitoring.coreos.com/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To(AlertManagerName),
-			Labels:    a.labels,
-			Namespace: ptr.To(a.namespace),
+			Name:        ptr.To(a.name),
+			Labels:      a.labels,
+			Annotations: stackAnnotations(a.stack),
+			Namespace:   ptr.To(a.namespace),
 		},
 		Spec: &monitoringv1.AlertmanagerSpecApplyConfiguration{
 			ServiceAccountName:                  a.manifets.ServiceAccount.Name,
-			Replicas:                            ptr.To(int32(1)),
-			AlertmanagerConfigSelector:          &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+			ImagePullSecrets:                    specImagePullSecrets(a.imagePullSecrets),
+			Replicas:                            ptr.To(a.replicas),
+			AlertmanagerConfigSelector:          stackSelector(a.stack),
 			AlertmanagerConfigNamespaceSelector: &applyConfigMetav1.LabelSelectorApplyConfiguration{},
 		},
 	}
+
+	spec := a.manifets.AlertManager.Spec
+	spec.PriorityClassName = a.scheduling.priorityClassName()
+	spec.Affinity = a.scheduling.affinity(a.labelSelectors)
+	spec.TopologySpreadConstraints = a.scheduling.topologySpread(a.labelSelectors)
+
+	if a.version != "" {
+		a.manifets.AlertManager.Spec.Version = ptr.To(a.version)
+		a.manifets.AlertManager.Spec.Image = ptr.To(fmt.Sprintf("%s:v%s", AlertManagerImage, a.version))
+	}
+
 	return a
 }
 
@@ -94,7 +177,7 @@ func (a *AlertManagerBuilder) WithService() *AlertManagerBuilder {
 			APIVersion: ptr.To("v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To(AlertManagerName),
+			Name:      ptr.To(a.name),
 			Labels:    a.labels,
 			Namespace: ptr.To(a.namespace),
 		},
@@ -112,7 +195,8 @@ func (a *AlertManagerBuilder) WithService() *AlertManagerBuilder {
 					TargetPort: ptr.To(intstr.FromString("reloader-web")),
 				},
 			},
-			Selector: a.labelSelectors,
+			Selector:       a.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(a.ipFamilyPolicy),
 		},
 	}
 	return a
@@ -125,7 +209,7 @@ func (a *AlertManagerBuilder) WithServiceMonitor() *AlertManagerBuilder {
 			APIVersion: ptr.To("monitoring.coreos.com/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To(AlertManagerName),
+			Name:      ptr.To(a.name),
 			Labels:    a.labels,
 			Namespace: ptr.To(a.namespace),
 		},
@@ -141,6 +225,10 @@ func (a *AlertManagerBuilder) WithServiceMonitor() *AlertManagerBuilder {
 			},
 		},
 	}
+
+	if a.reloaderMonitoring {
+		a.manifets.ServiceMonitor.Spec.Endpoints = append(a.manifets.ServiceMonitor.Spec.Endpoints, reloaderEndpoint())
+	}
 	return a
 }
 
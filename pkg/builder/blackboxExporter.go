@@ -0,0 +1,347 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyCofongiAppsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	LatestBlackboxExporterVersion = "0.25.0"
+
+	// BlackboxExporterDefaultModule is the module probing the targets over
+	// HTTP, expecting a 2xx status code.
+	BlackboxExporterDefaultModule = "http_2xx"
+
+	blackboxExporterConfigKey       = "blackbox.yml"
+	blackboxExporterConfigMountPath = "/etc/blackbox-exporter"
+	blackboxExporterPort            = 9115
+)
+
+// blackboxExporterModules are the modules configured in the blackbox
+// exporter deployed by poctl.
+var blackboxExporterModules = map[string]any{
+	"http_2xx": map[string]any{
+		"prober":  "http",
+		"timeout": "5s",
+		"http": map[string]any{
+			"preferred_ip_protocol": "ip4",
+		},
+	},
+	"tcp_connect": map[string]any{
+		"prober":  "tcp",
+		"timeout": "5s",
+	},
+	"icmp": map[string]any{
+		"prober":  "icmp",
+		"timeout": "5s",
+		"icmp": map[string]any{
+			"preferred_ip_protocol": "ip4",
+		},
+	},
+}
+
+// BlackboxExporterModules returns the sorted names of the modules
+// configured in the blackbox exporter deployed by poctl.
+func BlackboxExporterModules() []string {
+	return slices.Sorted(maps.Keys(blackboxExporterModules))
+}
+
+// BlackboxExporterName returns the name of the objects of the blackbox
+// exporter of the stack.
+func BlackboxExporterName(stack string) string {
+	return stackObjectName(stack, "blackbox-exporter")
+}
+
+// BlackboxExporterProberURL returns the address of the blackbox exporter of
+// the stack in the cluster domain, which the Probes set as their prober.
+func BlackboxExporterProberURL(namespace, stack, clusterDomain string) string {
+	return fmt.Sprintf("%s:%d", ServiceHost(BlackboxExporterName(stack), namespace, clusterDomain), blackboxExporterPort)
+}
+
+type BlackboxExporterBuilder struct {
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	name             string
+	version          string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifests        BlackboxExporterManifests
+}
+
+type BlackboxExporterManifests struct {
+	ServiceAccount *applyConfigCorev1.ServiceAccountApplyConfiguration
+	Config         *applyConfigCorev1.ConfigMapApplyConfiguration
+	Deployment     *applyCofongiAppsv1.DeploymentApplyConfiguration
+	Service        *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceMonitor *monitoringv1.ServiceMonitorApplyConfiguration
+}
+
+// NewBlackboxExporterBuilder returns a builder for the blackbox exporter
+// probing the targets of the Probes over HTTP, TCP and ICMP.
+func NewBlackboxExporterBuilder(namespace, version string) *BlackboxExporterBuilder {
+	return &BlackboxExporterBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name": "blackbox-exporter",
+		},
+		labelSelectors: map[string]string{
+			"app.kubernetes.io/name": "blackbox-exporter",
+		},
+		namespace: namespace,
+		name:      "blackbox-exporter",
+		version:   version,
+	}
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster.
+func (b *BlackboxExporterBuilder) WithStack(stack string) *BlackboxExporterBuilder {
+	b.name = BlackboxExporterName(stack)
+	if stack != "" {
+		b.labels[PartOfLabel] = stack
+		b.labelSelectors[PartOfLabel] = stack
+	}
+	return b
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Deployment built afterwards.
+func (b *BlackboxExporterBuilder) WithImagePullSecrets(names ...string) *BlackboxExporterBuilder {
+	b.imagePullSecrets = append(b.imagePullSecrets, names...)
+	return b
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the Pods of the Deployment built afterwards.
+func (b *BlackboxExporterBuilder) WithScheduling(scheduling Scheduling) *BlackboxExporterBuilder {
+	b.scheduling = scheduling
+	return b
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (b *BlackboxExporterBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *BlackboxExporterBuilder {
+	b.ipFamilyPolicy = policy
+	return b
+}
+
+func (b *BlackboxExporterBuilder) WithServiceAccount() *BlackboxExporterBuilder {
+	b.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(b.name),
+			Labels:    b.labels,
+			Namespace: ptr.To(b.namespace),
+		},
+		ImagePullSecrets: podImagePullSecrets(b.imagePullSecrets),
+	}
+	return b
+}
+
+// WithConfig builds the ConfigMap holding the modules of the blackbox
+// exporter, see BlackboxExporterModules.
+func (b *BlackboxExporterBuilder) WithConfig() *BlackboxExporterBuilder {
+	// The configuration only holds strings and maps, which are always
+	// marshaled.
+	data, err := yaml.Marshal(map[string]any{"modules": blackboxExporterModules})
+	if err != nil {
+		panic(fmt.Sprintf("builder: error while encoding the blackbox exporter configuration: %v", err))
+	}
+
+	b.manifests.Config = &applyConfigCorev1.ConfigMapApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ConfigMap"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(b.name + "-config"),
+			Labels:    b.labels,
+			Namespace: ptr.To(b.namespace),
+		},
+		Data: map[string]string{
+			blackboxExporterConfigKey: string(data),
+		},
+	}
+	return b
+}
+
+// WithDeployment builds the Deployment of the blackbox exporter. It must be
+// called after WithServiceAccount and WithConfig. The icmp module relies on
+// the unprivileged ICMP sockets allowed by the net.ipv4.ping_group_range
+// sysctl, so that the exporter runs without the NET_RAW capability.
+func (b *BlackboxExporterBuilder) WithDeployment() *BlackboxExporterBuilder {
+	template := &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Labels: b.labelSelectors,
+		},
+		Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+			ServiceAccountName: b.manifests.ServiceAccount.Name,
+			ImagePullSecrets:   podImagePullSecrets(b.imagePullSecrets),
+			SecurityContext: &applyConfigCorev1.PodSecurityContextApplyConfiguration{
+				Sysctls: []applyConfigCorev1.SysctlApplyConfiguration{
+					{
+						Name:  ptr.To("net.ipv4.ping_group_range"),
+						Value: ptr.To("0 2147483647"),
+					},
+				},
+			},
+			Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+				{
+					Name:  ptr.To("blackbox-exporter"),
+					Image: ptr.To(fmt.Sprintf("quay.io/prometheus/blackbox-exporter:v%s", b.version)),
+					Args:  []string{"--config.file=" + path.Join(blackboxExporterConfigMountPath, blackboxExporterConfigKey)},
+					Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
+						{
+							Name:          ptr.To("http"),
+							ContainerPort: ptr.To(int32(blackboxExporterPort)),
+						},
+					},
+					LivenessProbe:  httpGetProbe("/-/healthy", "http"),
+					ReadinessProbe: httpGetProbe("/-/healthy", "http"),
+					VolumeMounts: []applyConfigCorev1.VolumeMountApplyConfiguration{
+						{
+							Name:      ptr.To("config"),
+							MountPath: ptr.To(blackboxExporterConfigMountPath),
+							ReadOnly:  ptr.To(true),
+						},
+					},
+					SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+						AllowPrivilegeEscalation: ptr.To(false),
+						Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+							Drop: []corev1.Capability{
+								"ALL",
+							},
+						},
+						ReadOnlyRootFilesystem: ptr.To(true),
+						RunAsUser:              ptr.To(int64(65534)),
+						RunAsNonRoot:           ptr.To(true),
+						RunAsGroup:             ptr.To(int64(65534)),
+						SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+							Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
+						},
+					},
+				},
+			},
+			Volumes: []applyConfigCorev1.VolumeApplyConfiguration{
+				{
+					Name: ptr.To("config"),
+					VolumeSourceApplyConfiguration: applyConfigCorev1.VolumeSourceApplyConfiguration{
+						ConfigMap: &applyConfigCorev1.ConfigMapVolumeSourceApplyConfiguration{
+							LocalObjectReferenceApplyConfiguration: applyConfigCorev1.LocalObjectReferenceApplyConfiguration{
+								Name: b.manifests.Config.Name,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	b.scheduling.schedulePod(template.Spec, b.labelSelectors)
+
+	b.manifests.Deployment = &applyCofongiAppsv1.DeploymentApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Deployment"),
+			APIVersion: ptr.To("apps/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(b.name),
+			Labels:    b.labels,
+			Namespace: ptr.To(b.namespace),
+		},
+		Spec: &applyCofongiAppsv1.DeploymentSpecApplyConfiguration{
+			Replicas: ptr.To(int32(1)),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: b.labelSelectors,
+			},
+			Template: template,
+		},
+	}
+	return b
+}
+
+func (b *BlackboxExporterBuilder) WithService() *BlackboxExporterBuilder {
+	b.manifests.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(b.name),
+			Labels:    b.labels,
+			Namespace: ptr.To(b.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			Ports: []applyConfigCorev1.ServicePortApplyConfiguration{
+				{
+					Name:       ptr.To("http"),
+					Port:       ptr.To(int32(blackboxExporterPort)),
+					TargetPort: ptr.To(intstr.FromString("http")),
+				},
+			},
+			Selector:       b.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(b.ipFamilyPolicy),
+		},
+	}
+	return b
+}
+
+// WithServiceMonitor scrapes the metrics of the blackbox exporter itself,
+// the results of the probes being scraped through the Probes.
+func (b *BlackboxExporterBuilder) WithServiceMonitor() *BlackboxExporterBuilder {
+	b.manifests.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(b.name),
+			Labels:    b.labels,
+			Namespace: ptr.To(b.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: b.labelSelectors,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					Port: ptr.To("http"),
+				},
+			},
+		},
+	}
+	return b
+}
+
+func (b *BlackboxExporterBuilder) Build() BlackboxExporterManifests {
+	return b.manifests
+}
@@ -0,0 +1,255 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"maps"
+	"slices"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// The keys of the client certificates in the Secret referenced by the
+// ServiceMonitor of the control plane components authenticating their
+// clients with certificates.
+const (
+	ClientCASecretKey   = "ca.crt"
+	ClientCertSecretKey = "tls.crt"
+	ClientKeySecretKey  = "tls.key"
+)
+
+// serviceAccountTokenFile is the token of the ServiceAccount of Prometheus,
+// which is allowed to get /metrics.
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// ControlPlaneComponent describes how a control plane component serves its
+// metrics.
+type ControlPlaneComponent struct {
+	// Port serving the metrics over https.
+	Port int32
+	// ClientCertificates is true when the component authenticates its clients
+	// with certificates signed by its CA, the other components authenticating
+	// Prometheus with the token of its ServiceAccount.
+	ClientCertificates bool
+	// PodLabels select the static Pods of the component in the clusters set
+	// up by kubeadm.
+	PodLabels map[string]string
+}
+
+// ControlPlaneComponents are the control plane components whose scraping
+// can be configured.
+var ControlPlaneComponents = map[string]ControlPlaneComponent{
+	"etcd": {
+		Port:               2379,
+		ClientCertificates: true,
+		PodLabels:          map[string]string{"component": "etcd", "tier": "control-plane"},
+	},
+	"kube-controller-manager": {
+		Port:      10257,
+		PodLabels: map[string]string{"component": "kube-controller-manager", "tier": "control-plane"},
+	},
+	"kube-scheduler": {
+		Port:      10259,
+		PodLabels: map[string]string{"component": "kube-scheduler", "tier": "control-plane"},
+	},
+}
+
+// ControlPlaneComponentNames returns the sorted names of the control plane
+// components.
+func ControlPlaneComponentNames() []string {
+	return slices.Sorted(maps.Keys(ControlPlaneComponents))
+}
+
+// ControlPlaneScrapeBuilder builds the objects letting Prometheus scrape a
+// TLS-protected control plane component: the headless Service selecting its
+// Pods, the ServiceMonitor and the Secret holding the client certificates.
+type ControlPlaneScrapeBuilder struct {
+	labels     map[string]string
+	namespace  string
+	name       string
+	component  ControlPlaneComponent
+	podLabels  map[string]string
+	secretName string
+	manifests  ControlPlaneScrapeManifests
+}
+
+type ControlPlaneScrapeManifests struct {
+	Secret         *applyConfigCorev1.SecretApplyConfiguration
+	Service        *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceMonitor *monitoringv1.ServiceMonitorApplyConfiguration
+}
+
+// NewControlPlaneScrapeBuilder returns a builder for the scraping of the
+// component, one of ControlPlaneComponentNames. The objects must be in the
+// namespace of the Pods of the component.
+func NewControlPlaneScrapeBuilder(namespace, name string) *ControlPlaneScrapeBuilder {
+	component := ControlPlaneComponents[name]
+	return &ControlPlaneScrapeBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name": name,
+		},
+		namespace: namespace,
+		name:      name,
+		component: component,
+		podLabels: component.PodLabels,
+	}
+}
+
+// WithStack names the objects built afterwards after the stack and labels
+// the ServiceMonitor, so that the Prometheus of the stack selects it.
+func (c *ControlPlaneScrapeBuilder) WithStack(stack string) *ControlPlaneScrapeBuilder {
+	c.name = stackObjectName(stack, c.name)
+	if stack != "" {
+		c.labels[PartOfLabel] = stack
+	}
+	return c
+}
+
+// WithPodLabels selects the Pods of the component by these labels instead of
+// the ones set by kubeadm.
+func (c *ControlPlaneScrapeBuilder) WithPodLabels(labels map[string]string) *ControlPlaneScrapeBuilder {
+	c.podLabels = labels
+	return c
+}
+
+// WithClientCertificatesSecret makes the ServiceMonitor authenticate with
+// the client certificates of an existing Secret, holding them under the
+// ClientCASecretKey, ClientCertSecretKey and ClientKeySecretKey keys.
+func (c *ControlPlaneScrapeBuilder) WithClientCertificatesSecret(name string) *ControlPlaneScrapeBuilder {
+	c.secretName = name
+	return c
+}
+
+// WithClientCertificates builds the Secret holding the PEM-encoded CA of the
+// component and the client certificate and key signed by it, named name.
+func (c *ControlPlaneScrapeBuilder) WithClientCertificates(name string, ca, cert, key []byte) *ControlPlaneScrapeBuilder {
+	c.secretName = name
+	c.manifests.Secret = &applyConfigCorev1.SecretApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Secret"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(name),
+			Labels:    c.labels,
+			Namespace: ptr.To(c.namespace),
+		},
+		Type: ptr.To(corev1.SecretTypeOpaque),
+		Data: map[string][]byte{
+			ClientCASecretKey:   ca,
+			ClientCertSecretKey: cert,
+			ClientKeySecretKey:  key,
+		},
+	}
+	return c
+}
+
+// WithService builds the headless Service selecting the Pods of the
+// component, which run in the host network: its endpoints are the addresses
+// of the control plane nodes.
+func (c *ControlPlaneScrapeBuilder) WithService() *ControlPlaneScrapeBuilder {
+	c.manifests.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(c.name),
+			Labels:    c.labels,
+			Namespace: ptr.To(c.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			ClusterIP: ptr.To(corev1.ClusterIPNone),
+			Ports: []applyConfigCorev1.ServicePortApplyConfiguration{
+				{
+					Name:       ptr.To("metrics"),
+					Port:       ptr.To(c.component.Port),
+					TargetPort: ptr.To(intstr.FromInt32(c.component.Port)),
+				},
+			},
+			Selector: c.podLabels,
+		},
+	}
+	return c
+}
+
+// WithServiceMonitor builds the ServiceMonitor scraping the component over
+// https. The components authenticating their clients with certificates
+// present the ones of the Secret and have their certificate verified against
+// its CA. The other ones authenticate Prometheus with the token of its
+// ServiceAccount, and their self-signed certificates aren't verified.
+func (c *ControlPlaneScrapeBuilder) WithServiceMonitor() *ControlPlaneScrapeBuilder {
+	endpoint := monitoringv1.EndpointApplyConfiguration{
+		Port:   ptr.To("metrics"),
+		Scheme: ptr.To("https"),
+	}
+
+	if c.component.ClientCertificates {
+		endpoint.TLSConfig = &monitoringv1.TLSConfigApplyConfiguration{
+			SafeTLSConfigApplyConfiguration: monitoringv1.SafeTLSConfigApplyConfiguration{
+				CA: &monitoringv1.SecretOrConfigMapApplyConfiguration{
+					Secret: c.secretKey(ClientCASecretKey),
+				},
+				Cert: &monitoringv1.SecretOrConfigMapApplyConfiguration{
+					Secret: c.secretKey(ClientCertSecretKey),
+				},
+				KeySecret: c.secretKey(ClientKeySecretKey),
+			},
+		}
+	} else {
+		endpoint.BearerTokenFile = ptr.To(serviceAccountTokenFile)
+		endpoint.TLSConfig = &monitoringv1.TLSConfigApplyConfiguration{
+			SafeTLSConfigApplyConfiguration: monitoringv1.SafeTLSConfigApplyConfiguration{
+				InsecureSkipVerify: ptr.To(true),
+			},
+		}
+	}
+
+	c.manifests.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(c.name),
+			Labels:    c.labels,
+			Namespace: ptr.To(c.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			JobLabel: ptr.To("app.kubernetes.io/name"),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: c.labels,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{endpoint},
+		},
+	}
+	return c
+}
+
+func (c *ControlPlaneScrapeBuilder) secretKey(key string) *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: c.secretName},
+		Key:                  key,
+	}
+}
+
+func (c *ControlPlaneScrapeBuilder) Build() ControlPlaneScrapeManifests {
+	return c.manifests
+}
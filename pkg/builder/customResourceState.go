@@ -0,0 +1,244 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"path"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// CustomResourceStateMetricPrefix prefixes the metrics exposed by
+	// kube-state-metrics about the monitoring.coreos.com resources, the
+	// customresource_kind label telling the kind of the resource.
+	CustomResourceStateMetricPrefix = "kube_monitoring_resource"
+
+	customResourceStateKey       = "config.yaml"
+	customResourceStateMountPath = "/etc/kube-state-metrics/custom-resource-state"
+)
+
+// customResourceStateConfig is the custom resource state configuration of
+// kube-state-metrics, exposing the conditions, the replicas and the shards
+// of the Prometheus, PrometheusAgent and Alertmanager resources.
+var customResourceStateConfig = fmt.Sprintf(`kind: CustomResourceStateMetrics
+spec:
+  resources:
+  - groupVersionKind:
+      group: monitoring.coreos.com
+      version: v1
+      kind: Prometheus
+    metricNamePrefix: %[1]s
+    labelsFromPath:
+      name: [metadata, name]
+      namespace: [metadata, namespace]
+    metrics:%[2]s%[3]s
+  - groupVersionKind:
+      group: monitoring.coreos.com
+      version: v1alpha1
+      kind: PrometheusAgent
+    metricNamePrefix: %[1]s
+    labelsFromPath:
+      name: [metadata, name]
+      namespace: [metadata, namespace]
+    metrics:%[2]s%[3]s
+  - groupVersionKind:
+      group: monitoring.coreos.com
+      version: v1
+      kind: Alertmanager
+    metricNamePrefix: %[1]s
+    labelsFromPath:
+      name: [metadata, name]
+      namespace: [metadata, namespace]
+    metrics:%[2]s
+`, CustomResourceStateMetricPrefix, `
+    - name: info
+      help: Information about the resource.
+      each:
+        type: Info
+        info:
+          labelsFromPath:
+            version: [spec, version]
+    - name: spec_paused
+      help: Whether the reconciliation of the resource is paused.
+      each:
+        type: Gauge
+        gauge:
+          path: [spec, paused]
+          nilIsZero: true
+    - name: spec_replicas
+      help: Number of desired replicas.
+      each:
+        type: Gauge
+        gauge:
+          path: [spec, replicas]
+    - name: status_available_replicas
+      help: Number of available replicas.
+      each:
+        type: Gauge
+        gauge:
+          path: [status, availableReplicas]
+          nilIsZero: true
+    - name: status_unavailable_replicas
+      help: Number of unavailable replicas.
+      each:
+        type: Gauge
+        gauge:
+          path: [status, unavailableReplicas]
+          nilIsZero: true
+    - name: status_condition
+      help: Status of the conditions of the resource, 1 when the condition is true.
+      each:
+        type: Gauge
+        gauge:
+          path: [status, conditions]
+          labelsFromPath:
+            type: [type]
+            reason: [reason]
+          valueFrom: [status]`, `
+    - name: spec_shards
+      help: Number of desired shards.
+      each:
+        type: Gauge
+        gauge:
+          path: [spec, shards]`)
+
+// WithCustomResourceState makes kube-state-metrics expose metrics about the
+// Prometheus, PrometheusAgent and Alertmanager resources, from a custom
+// resource state configuration stored in a ConfigMap. It must be called
+// before the ClusterRole and the Deployment or StatefulSet are built.
+func (k *KubeStateMetricsBuilder) WithCustomResourceState() *KubeStateMetricsBuilder {
+	k.customResourceState = true
+	k.manifests.CustomResourceStateConfig = &applyConfigCorev1.ConfigMapApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ConfigMap"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(k.name + "-custom-resource-state"),
+			Labels:    k.labels,
+			Namespace: ptr.To(k.namespace),
+		},
+		Data: map[string]string{
+			customResourceStateKey: customResourceStateConfig,
+		},
+	}
+	return k
+}
+
+// WithCustomResourceStateRules builds the PrometheusRule alerting on the
+// metrics exposed with WithCustomResourceState: resources which fail to be
+// reconciled, which aren't available or whose reconciliation is paused.
+func (k *KubeStateMetricsBuilder) WithCustomResourceStateRules() *KubeStateMetricsBuilder {
+	resource := "{{ $labels.customresource_kind }} {{ $labels.namespace }}/{{ $labels.name }}"
+	k.manifests.PrometheusRule = &monitoringv1.PrometheusRuleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("PrometheusRule"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(k.name + "-custom-resource-state"),
+			Labels:    k.labels,
+			Namespace: ptr.To(k.namespace),
+		},
+		Spec: &monitoringv1.PrometheusRuleSpecApplyConfiguration{
+			Groups: []monitoringv1.RuleGroupApplyConfiguration{
+				{
+					Name: ptr.To("monitoring-resources"),
+					Rules: []monitoringv1.RuleApplyConfiguration{
+						alertingRule(
+							"MonitoringResourceNotReconciled",
+							fmt.Sprintf(`%s_status_condition{type="Reconciled"} == 0`, CustomResourceStateMetricPrefix),
+							"15m",
+							"warning",
+							resource+" fails to be reconciled by the Prometheus Operator.",
+						),
+						alertingRule(
+							"MonitoringResourceNotAvailable",
+							fmt.Sprintf(`%s_status_condition{type="Available"} == 0`, CustomResourceStateMetricPrefix),
+							"15m",
+							"critical",
+							resource+" isn't available.",
+						),
+						alertingRule(
+							"MonitoringResourceReplicasUnavailable",
+							fmt.Sprintf(`%s_status_unavailable_replicas > 0`, CustomResourceStateMetricPrefix),
+							"15m",
+							"warning",
+							resource+" has {{ $value }} unavailable replicas.",
+						),
+						alertingRule(
+							"MonitoringResourcePaused",
+							fmt.Sprintf(`%s_spec_paused == 1`, CustomResourceStateMetricPrefix),
+							"1h",
+							"info",
+							"The reconciliation of "+resource+" is paused.",
+						),
+					},
+				},
+			},
+		},
+	}
+	return k
+}
+
+// customResourceStateRules returns the ClusterRole rules letting
+// kube-state-metrics watch the monitoring.coreos.com resources.
+func customResourceStateRules() []applyConfigRbacv1.PolicyRuleApplyConfiguration {
+	return []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+		{
+			APIGroups: []string{"monitoring.coreos.com"},
+			Resources: []string{"prometheuses", "prometheusagents", "alertmanagers"},
+			Verbs:     []string{"list", "watch"},
+		},
+		{
+			APIGroups: []string{"apiextensions.k8s.io"},
+			Resources: []string{"customresourcedefinitions"},
+			Verbs:     []string{"list", "watch"},
+		},
+	}
+}
+
+// withCustomResourceState mounts the custom resource state configuration in
+// the kube-state-metrics container of the Pod template.
+func (k *KubeStateMetricsBuilder) withCustomResourceState(template *applyConfigCorev1.PodTemplateSpecApplyConfiguration) {
+	if !k.customResourceState {
+		return
+	}
+
+	template.Spec.Volumes = append(template.Spec.Volumes, applyConfigCorev1.VolumeApplyConfiguration{
+		Name: ptr.To("custom-resource-state"),
+		VolumeSourceApplyConfiguration: applyConfigCorev1.VolumeSourceApplyConfiguration{
+			ConfigMap: &applyConfigCorev1.ConfigMapVolumeSourceApplyConfiguration{
+				LocalObjectReferenceApplyConfiguration: applyConfigCorev1.LocalObjectReferenceApplyConfiguration{
+					Name: k.manifests.CustomResourceStateConfig.Name,
+				},
+			},
+		},
+	})
+
+	container := &template.Spec.Containers[0]
+	container.Args = append(container.Args, "--custom-resource-state-config-file="+path.Join(customResourceStateMountPath, customResourceStateKey))
+	container.VolumeMounts = append(container.VolumeMounts, applyConfigCorev1.VolumeMountApplyConfiguration{
+		Name:      ptr.To("custom-resource-state"),
+		MountPath: ptr.To(customResourceStateMountPath),
+		ReadOnly:  ptr.To(true),
+	})
+}
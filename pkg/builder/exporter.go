@@ -0,0 +1,338 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	monitoringv1api "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyCofongiAppsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// ExporterPreset describes how to deploy and scrape the Prometheus exporter
+// of a third-party application.
+type ExporterPreset struct {
+	Name  string
+	Image string
+	Port  int32
+	Path  string
+	// Args are passed to the exporter, "%s" is replaced by the name of the
+	// Service of the monitored application.
+	Args []string
+	// Env are set on the exporter container, "%s" is replaced by the name of
+	// the Service of the monitored application.
+	Env map[string]string
+	// SecretEnv maps environment variables to the keys of an optional Secret
+	// named after the monitored application, e.g. for credentials.
+	SecretEnv         map[string]string
+	MetricRelabelings []monitoringv1.RelabelConfigApplyConfiguration
+}
+
+// exporterPresets returns new presets on each call, so that the callers
+// can't change them for each other.
+func exporterPresets() map[string]ExporterPreset {
+	return map[string]ExporterPreset{
+		"postgres": {
+			Name:  "postgres",
+			Image: "quay.io/prometheuscommunity/postgres-exporter:v0.15.0",
+			Port:  9187,
+			Path:  "/metrics",
+			Env: map[string]string{
+				"DATA_SOURCE_URI": "%s:5432/postgres?sslmode=disable",
+			},
+			SecretEnv: map[string]string{
+				"DATA_SOURCE_USER": "username",
+				"DATA_SOURCE_PASS": "password",
+			},
+			MetricRelabelings: []monitoringv1.RelabelConfigApplyConfiguration{
+				{
+					SourceLabels: []monitoringv1api.LabelName{"__name__"},
+					Regex:        ptr.To("pg_settings_.*"),
+					Action:       ptr.To("drop"),
+				},
+			},
+		},
+		"redis": {
+			Name:  "redis",
+			Image: "quay.io/oliver006/redis_exporter:v1.62.0",
+			Port:  9121,
+			Path:  "/metrics",
+			Args: []string{
+				"--redis.addr=redis://%s:6379",
+			},
+		},
+		"nginx": {
+			Name:  "nginx",
+			Image: "nginx/nginx-prometheus-exporter:1.3.0",
+			Port:  9113,
+			Path:  "/metrics",
+			Args: []string{
+				"--nginx.scrape-uri=http://%s:8080/stub_status",
+			},
+		},
+		"kafka": {
+			Name:  "kafka",
+			Image: "danielqsj/kafka-exporter:v1.8.0",
+			Port:  9308,
+			Path:  "/metrics",
+			Args: []string{
+				"--kafka.server=%s:9092",
+			},
+		},
+	}
+}
+
+// ExporterPresetNames returns the sorted names of the available presets.
+func ExporterPresetNames() []string {
+	return slices.Sorted(maps.Keys(exporterPresets()))
+}
+
+// GetExporterPreset returns the preset with the given name. The preset is a
+// copy which the caller can modify.
+func GetExporterPreset(name string) (ExporterPreset, error) {
+	p, ok := exporterPresets()[name]
+	if !ok {
+		return ExporterPreset{}, fmt.Errorf("unknown preset %s, must be one of: %s", name, strings.Join(ExporterPresetNames(), ", "))
+	}
+	return p, nil
+}
+
+type ExporterBuilder struct {
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	name             string
+	target           string
+	host             string
+	preset           ExporterPreset
+	imagePullSecrets []string
+	manifests        ExporterManifests
+}
+
+type ExporterManifests struct {
+	Deployment     *applyCofongiAppsv1.DeploymentApplyConfiguration
+	Service        *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceMonitor *monitoringv1.ServiceMonitorApplyConfiguration
+}
+
+// NewExporterBuilder returns a builder for the exporter of the application
+// exposed by the target Service.
+func NewExporterBuilder(namespace, target string, preset ExporterPreset) *ExporterBuilder {
+	return &ExporterBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name":     fmt.Sprintf("%s-exporter", preset.Name),
+			"app.kubernetes.io/instance": target,
+		},
+		labelSelectors: map[string]string{
+			"app.kubernetes.io/name":     fmt.Sprintf("%s-exporter", preset.Name),
+			"app.kubernetes.io/instance": target,
+		},
+		namespace: namespace,
+		name:      fmt.Sprintf("%s-%s-exporter", target, preset.Name),
+		target:    target,
+		host:      target,
+		preset:    preset,
+	}
+}
+
+// WithClusterDomain makes the Deployment built afterwards reach the target
+// Service by its name in the DNS domain of the cluster, rather than by its
+// name relative to the namespace.
+func (e *ExporterBuilder) WithClusterDomain(clusterDomain string) *ExporterBuilder {
+	e.host = ServiceHost(e.target, e.namespace, clusterDomain)
+	return e
+}
+
+// WithImagePullSecrets attaches image pull secrets to the Deployment built
+// afterwards, the exporter images being pulled from a private registry.
+func (e *ExporterBuilder) WithImagePullSecrets(names ...string) *ExporterBuilder {
+	e.imagePullSecrets = append(e.imagePullSecrets, names...)
+	return e
+}
+
+func (e *ExporterBuilder) WithDeployment() *ExporterBuilder {
+	args := make([]string, 0, len(e.preset.Args))
+	for _, arg := range e.preset.Args {
+		args = append(args, fmt.Sprintf(arg, e.host))
+	}
+
+	var env []applyConfigCorev1.EnvVarApplyConfiguration
+	for _, name := range slices.Sorted(maps.Keys(e.preset.Env)) {
+		env = append(env, applyConfigCorev1.EnvVarApplyConfiguration{
+			Name:  ptr.To(name),
+			Value: ptr.To(fmt.Sprintf(e.preset.Env[name], e.host)),
+		})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(e.preset.SecretEnv)) {
+		env = append(env, applyConfigCorev1.EnvVarApplyConfiguration{
+			Name: ptr.To(name),
+			ValueFrom: &applyConfigCorev1.EnvVarSourceApplyConfiguration{
+				SecretKeyRef: &applyConfigCorev1.SecretKeySelectorApplyConfiguration{
+					LocalObjectReferenceApplyConfiguration: applyConfigCorev1.LocalObjectReferenceApplyConfiguration{
+						Name: ptr.To(e.target),
+					},
+					Key:      ptr.To(e.preset.SecretEnv[name]),
+					Optional: ptr.To(true),
+				},
+			},
+		})
+	}
+
+	e.manifests.Deployment = &applyCofongiAppsv1.DeploymentApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Deployment"),
+			APIVersion: ptr.To("apps/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(e.name),
+			Labels:    e.labels,
+			Namespace: ptr.To(e.namespace),
+		},
+		Spec: &applyCofongiAppsv1.DeploymentSpecApplyConfiguration{
+			Replicas: ptr.To(int32(1)),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: e.labelSelectors,
+			},
+			Template: &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+				ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+					Labels: e.labelSelectors,
+				},
+				Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+					ImagePullSecrets: podImagePullSecrets(e.imagePullSecrets),
+					Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+						{
+							Name:  ptr.To("exporter"),
+							Image: ptr.To(e.preset.Image),
+							Args:  args,
+							Env:   env,
+							Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
+								{
+									Name:          ptr.To("metrics"),
+									ContainerPort: ptr.To(e.preset.Port),
+								},
+							},
+							// The metrics endpoint of the exporters queries the
+							// exported application, only the listener is probed.
+							LivenessProbe:  tcpSocketProbe("metrics"),
+							ReadinessProbe: tcpSocketProbe("metrics"),
+							SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+								AllowPrivilegeEscalation: ptr.To(false),
+								Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+									Drop: []corev1.Capability{
+										"ALL",
+									},
+								},
+								ReadOnlyRootFilesystem: ptr.To(true),
+								RunAsUser:              ptr.To(int64(65534)),
+								RunAsNonRoot:           ptr.To(true),
+								RunAsGroup:             ptr.To(int64(65534)),
+								SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+									Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return e
+}
+
+func (e *ExporterBuilder) WithService() *ExporterBuilder {
+	e.manifests.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(e.name),
+			Labels:    e.labels,
+			Namespace: ptr.To(e.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			Ports: []applyConfigCorev1.ServicePortApplyConfiguration{
+				{
+					Name:       ptr.To("metrics"),
+					Port:       ptr.To(e.preset.Port),
+					TargetPort: ptr.To(intstr.FromString("metrics")),
+				},
+			},
+			Selector: e.labelSelectors,
+		},
+	}
+	return e
+}
+
+// WithServiceMonitor scrapes the Service of the exporter. The instance label
+// is set to the monitored application rather than to the exporter Pod.
+func (e *ExporterBuilder) WithServiceMonitor() *ExporterBuilder {
+	e.manifests.ServiceMonitor = e.serviceMonitor(e.name, e.labelSelectors, "metrics")
+	e.manifests.ServiceMonitor.Spec.Endpoints[0].RelabelConfigs = []monitoringv1.RelabelConfigApplyConfiguration{
+		{
+			TargetLabel: ptr.To("instance"),
+			Replacement: ptr.To(e.target),
+		},
+	}
+	return e
+}
+
+// WithSidecarServiceMonitor scrapes the exporter through the given port of
+// the Service of the monitored application, when the exporter already runs as
+// a sidecar.
+func (e *ExporterBuilder) WithSidecarServiceMonitor(serviceLabels map[string]string, port string) *ExporterBuilder {
+	e.manifests.ServiceMonitor = e.serviceMonitor(e.target, serviceLabels, port)
+	return e
+}
+
+func (e *ExporterBuilder) serviceMonitor(name string, selector map[string]string, port string) *monitoringv1.ServiceMonitorApplyConfiguration {
+	return &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(name),
+			Labels:    e.labels,
+			Namespace: ptr.To(e.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: selector,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					Port:                 ptr.To(port),
+					Path:                 ptr.To(e.preset.Path),
+					MetricRelabelConfigs: e.preset.MetricRelabelings,
+				},
+			},
+		},
+	}
+}
+
+func (e *ExporterBuilder) Build() ExporterManifests {
+	return e.manifests
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func redisPreset(t *testing.T) ExporterPreset {
+	t.Helper()
+
+	p, err := GetExporterPreset("redis")
+	require.NoError(t, err)
+	return p
+}
+
+func TestGetExporterPreset(t *testing.T) {
+	assert.Equal(t, []string{"kafka", "nginx", "postgres", "redis"}, ExporterPresetNames())
+
+	_, err := GetExporterPreset("mysql")
+	assert.EqualError(t, err, "unknown preset mysql, must be one of: kafka, nginx, postgres, redis")
+
+	// Changing a preset doesn't change the presets returned afterwards.
+	p, err := GetExporterPreset("postgres")
+	require.NoError(t, err)
+	p.Image = "example.com/postgres-exporter"
+	p.Env["DATA_SOURCE_URI"] = "changed"
+	*p.MetricRelabelings[0].Regex = "changed"
+
+	p, err = GetExporterPreset("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/prometheuscommunity/postgres-exporter:v0.15.0", p.Image)
+	assert.Equal(t, "%s:5432/postgres?sslmode=disable", p.Env["DATA_SOURCE_URI"])
+	assert.Equal(t, "pg_settings_.*", *p.MetricRelabelings[0].Regex)
+}
+
+func TestExporterManifests(t *testing.T) {
+	p, err := GetExporterPreset("postgres")
+	require.NoError(t, err)
+
+	manifests := NewExporterBuilder("db", "pg", p).
+		WithImagePullSecrets("registry").
+		WithDeployment().
+		WithService().
+		WithServiceMonitor().
+		Build()
+
+	assert.Equal(t, "pg-postgres-exporter", *manifests.Deployment.Name)
+	assert.Equal(t, "registry", *manifests.Deployment.Spec.Template.Spec.ImagePullSecrets[0].Name)
+
+	container := manifests.Deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, p.Image, *container.Image)
+	require.Len(t, container.Env, 3)
+	assert.Equal(t, "DATA_SOURCE_URI", *container.Env[0].Name)
+	assert.Equal(t, "pg:5432/postgres?sslmode=disable", *container.Env[0].Value)
+	for _, env := range container.Env[1:] {
+		assert.Equal(t, "pg", *env.ValueFrom.SecretKeyRef.Name)
+		assert.True(t, *env.ValueFrom.SecretKeyRef.Optional)
+	}
+
+	assert.Equal(t, int32(9187), *manifests.Service.Spec.Ports[0].Port)
+
+	endpoint := manifests.ServiceMonitor.Spec.Endpoints[0]
+	assert.Equal(t, "metrics", *endpoint.Port)
+	assert.Equal(t, p.MetricRelabelings, endpoint.MetricRelabelConfigs)
+	assert.Equal(t, "instance", *endpoint.RelabelConfigs[0].TargetLabel)
+	assert.Equal(t, "pg", *endpoint.RelabelConfigs[0].Replacement)
+}
+
+func TestExporterSidecarManifests(t *testing.T) {
+	manifests := NewExporterBuilder("db", "cache", redisPreset(t)).
+		WithSidecarServiceMonitor(map[string]string{"app": "cache"}, "exporter").
+		Build()
+
+	assert.Nil(t, manifests.Deployment)
+	assert.Nil(t, manifests.Service)
+	assert.Equal(t, "cache", *manifests.ServiceMonitor.Name)
+	assert.Equal(t, map[string]string{"app": "cache"}, manifests.ServiceMonitor.Spec.Selector.MatchLabels)
+	assert.Equal(t, ptr.To("exporter"), manifests.ServiceMonitor.Spec.Endpoints[0].Port)
+	assert.Nil(t, manifests.ServiceMonitor.Spec.Endpoints[0].RelabelConfigs)
+}
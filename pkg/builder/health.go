@@ -0,0 +1,49 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// httpGetProbe returns a probe getting the path on the named port of the
+// container. The liveness probes restart the containers whose process
+// deadlocked, the readiness probes keep the Pods out of the Services until
+// they serve.
+func httpGetProbe(path, port string) *applyConfigCorev1.ProbeApplyConfiguration {
+	return &applyConfigCorev1.ProbeApplyConfiguration{
+		ProbeHandlerApplyConfiguration: applyConfigCorev1.ProbeHandlerApplyConfiguration{
+			HTTPGet: &applyConfigCorev1.HTTPGetActionApplyConfiguration{
+				Path: ptr.To(path),
+				Port: ptr.To(intstr.FromString(port)),
+			},
+		},
+	}
+}
+
+// tcpSocketProbe returns a probe connecting to the named port of the
+// container, for the containers whose HTTP endpoints depend on another
+// application.
+func tcpSocketProbe(port string) *applyConfigCorev1.ProbeApplyConfiguration {
+	return &applyConfigCorev1.ProbeApplyConfiguration{
+		ProbeHandlerApplyConfiguration: applyConfigCorev1.ProbeHandlerApplyConfiguration{
+			TCPSocket: &applyConfigCorev1.TCPSocketActionApplyConfiguration{
+				Port: ptr.To(intstr.FromString(port)),
+			},
+		},
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// IPFamilyPolicies are the IP family policies of the Services.
+var IPFamilyPolicies = []corev1.IPFamilyPolicy{
+	corev1.IPFamilyPolicySingleStack,
+	corev1.IPFamilyPolicyPreferDualStack,
+	corev1.IPFamilyPolicyRequireDualStack,
+}
+
+// ParseIPFamilyPolicy returns the IP family policy with the given name,
+// regardless of its case. The empty name leaves the policy to the cluster.
+func ParseIPFamilyPolicy(s string) (corev1.IPFamilyPolicy, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	var names []string
+	for _, policy := range IPFamilyPolicies {
+		if strings.EqualFold(s, string(policy)) {
+			return policy, nil
+		}
+		names = append(names, string(policy))
+	}
+	return "", fmt.Errorf("unknown IP family policy %s, must be one of: %s", s, strings.Join(names, ", "))
+}
+
+func serviceIPFamilyPolicy(policy corev1.IPFamilyPolicy) *corev1.IPFamilyPolicy {
+	if policy == "" {
+		return nil
+	}
+	return ptr.To(policy)
+}
+
+// listenAddress returns the address listening on the port of all the
+// interfaces, over IPv4 and IPv6 alike. Unlike 0.0.0.0, it accepts the
+// connections of the IPv6 addresses of the Pods on dual-stack clusters.
+func listenAddress(port int) string {
+	return net.JoinHostPort("", strconv.Itoa(port))
+}
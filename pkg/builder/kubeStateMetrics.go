@@ -30,20 +30,38 @@ import (
 const LatestKubeStateMetricsVersion = "2.14.0"
 
 type KubeStateMetricsBuilder struct {
-	labels         map[string]string
-	labelSelectors map[string]string
-	namespace      string
-	manifests      KubeStateMetricsManifests
-	version        string
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	name             string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifests        KubeStateMetricsManifests
+	version          string
+	shards           int32
+	// customResourceState mounts the custom resource state configuration
+	// and grants access to the monitoring.coreos.com resources.
+	customResourceState bool
 }
 
 type KubeStateMetricsManifests struct {
-	Deployment         *applyCofongiAppsv1.DeploymentApplyConfiguration
+	Deployment *applyCofongiAppsv1.DeploymentApplyConfiguration
+	// StatefulSet replaces the Deployment when kube-state-metrics is
+	// sharded, along with the Role and RoleBinding letting the Pods
+	// discover their shard.
+	StatefulSet        *applyCofongiAppsv1.StatefulSetApplyConfiguration
+	Role               *applyConfigRbacv1.RoleApplyConfiguration
+	RoleBinding        *applyConfigRbacv1.RoleBindingApplyConfiguration
 	Service            *applyConfigCorev1.ServiceApplyConfiguration
 	ServiceAccount     *applyConfigCorev1.ServiceAccountApplyConfiguration
 	ClusterRole        *applyConfigRbacv1.ClusterRoleApplyConfiguration
 	ClusterRoleBinding *applyConfigRbacv1.ClusterRoleBindingApplyConfiguration
 	ServiceMonitor     *monitoringv1.ServiceMonitorApplyConfiguration
+	// CustomResourceStateConfig and PrometheusRule expose and alert on the
+	// state of the monitoring.coreos.com resources.
+	CustomResourceStateConfig *applyConfigCorev1.ConfigMapApplyConfiguration
+	PrometheusRule            *monitoringv1.PrometheusRuleApplyConfiguration
 }
 
 func NewKubeStateMetricsBuilder(namespace, version string) *KubeStateMetricsBuilder {
@@ -55,10 +73,53 @@ func NewKubeStateMetricsBuilder(namespace, version string) *KubeStateMetricsBuil
 			"app.kubernetes.io/name": "kube-state-metrics",
 		},
 		namespace: namespace,
+		name:      "kube-state-metrics",
 		version:   LatestKubeStateMetricsVersion,
+		shards:    1,
 	}
 }
 
+// WithShards sets the number of shards of the StatefulSet built afterwards,
+// each shard exposing the metrics of a subset of the objects.
+func (k *KubeStateMetricsBuilder) WithShards(shards int32) *KubeStateMetricsBuilder {
+	k.shards = max(shards, 1)
+	return k
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster.
+func (k *KubeStateMetricsBuilder) WithStack(stack string) *KubeStateMetricsBuilder {
+	k.name = stackObjectName(stack, "kube-state-metrics")
+	if stack != "" {
+		k.labels[PartOfLabel] = stack
+		k.labelSelectors[PartOfLabel] = stack
+	}
+	return k
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Deployment or StatefulSet built afterwards.
+func (k *KubeStateMetricsBuilder) WithImagePullSecrets(names ...string) *KubeStateMetricsBuilder {
+	k.imagePullSecrets = append(k.imagePullSecrets, names...)
+	return k
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the Pods of the Deployment or StatefulSet built
+// afterwards, spreading the shards.
+func (k *KubeStateMetricsBuilder) WithScheduling(scheduling Scheduling) *KubeStateMetricsBuilder {
+	k.scheduling = scheduling
+	return k
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (k *KubeStateMetricsBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *KubeStateMetricsBuilder {
+	k.ipFamilyPolicy = policy
+	return k
+}
+
 func (k *KubeStateMetricsBuilder) WithServiceAccount() *KubeStateMetricsBuilder {
 	k.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
 		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
@@ -66,10 +127,11 @@ func (k *KubeStateMetricsBuilder) WithServiceAccount() *KubeStateMetricsBuilder
 			APIVersion: ptr.To("v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("kube-state-metrics"),
+			Name:      ptr.To(k.name),
 			Labels:    k.labels,
 			Namespace: ptr.To(k.namespace),
 		},
+		ImagePullSecrets: podImagePullSecrets(k.imagePullSecrets),
 	}
 	return k
 }
@@ -81,7 +143,7 @@ func (k *KubeStateMetricsBuilder) WithClusterRole() *KubeStateMetricsBuilder {
 			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("kube-state-metrics"),
+			Name:      ptr.To(k.name),
 			Labels:    k.labels,
 			Namespace: ptr.To(k.namespace),
 		},
@@ -177,6 +239,10 @@ func (k *KubeStateMetricsBuilder) WithClusterRole() *KubeStateMetricsBuilder {
 			},
 		},
 	}
+
+	if k.customResourceState {
+		k.manifests.ClusterRole.Rules = append(k.manifests.ClusterRole.Rules, customResourceStateRules()...)
+	}
 	return k
 }
 
@@ -187,14 +253,14 @@ func (k *KubeStateMetricsBuilder) WithClusterRoleBinding() *KubeStateMetricsBuil
 			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("kube-state-metrics"),
+			Name:      ptr.To(k.name),
 			Labels:    k.labels,
 			Namespace: ptr.To(k.namespace),
 		},
 		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
 			APIGroup: ptr.To("rbac.authorization.k8s.io"),
 			Kind:     ptr.To("ClusterRole"),
-			Name:     ptr.To("kube-state-metrics"),
+			Name:     ptr.To(k.name),
 		},
 		Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
 			{
@@ -214,7 +280,7 @@ func (k *KubeStateMetricsBuilder) WithService() *KubeStateMetricsBuilder {
 			APIVersion: ptr.To("v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("kube-state-metrics"),
+			Name:      ptr.To(k.name),
 			Labels:    k.labels,
 			Namespace: ptr.To(k.namespace),
 		},
@@ -231,7 +297,8 @@ func (k *KubeStateMetricsBuilder) WithService() *KubeStateMetricsBuilder {
 					TargetPort: ptr.To(intstr.FromString("metrics")),
 				},
 			},
-			Selector: k.labelSelectors,
+			Selector:       k.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(k.ipFamilyPolicy),
 		},
 	}
 	return k
@@ -244,7 +311,7 @@ func (k *KubeStateMetricsBuilder) WithDeployment() *KubeStateMetricsBuilder {
 			APIVersion: ptr.To("apps/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("kube-state-metrics"),
+			Name:      ptr.To(k.name),
 			Labels:    k.labels,
 			Namespace: ptr.To(k.namespace),
 		},
@@ -253,51 +320,168 @@ func (k *KubeStateMetricsBuilder) WithDeployment() *KubeStateMetricsBuilder {
 			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
 				MatchLabels: k.labelSelectors,
 			},
-			Template: &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
-				ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-					Labels: k.labelSelectors,
+			Template: k.podTemplate(false),
+		},
+	}
+	return k
+}
+
+// WithStatefulSet builds a StatefulSet running one Pod per shard, instead of
+// the Deployment, using the automated sharding of kube-state-metrics.
+func (k *KubeStateMetricsBuilder) WithStatefulSet() *KubeStateMetricsBuilder {
+	k.manifests.StatefulSet = &applyCofongiAppsv1.StatefulSetApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("StatefulSet"),
+			APIVersion: ptr.To("apps/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(k.name),
+			Labels:    k.labels,
+			Namespace: ptr.To(k.namespace),
+		},
+		Spec: &applyCofongiAppsv1.StatefulSetSpecApplyConfiguration{
+			Replicas:    ptr.To(k.shards),
+			ServiceName: ptr.To(k.name),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: k.labelSelectors,
+			},
+			Template: k.podTemplate(true),
+		},
+	}
+	return k
+}
+
+// WithShardingRole builds the Role and RoleBinding allowing the Pods of the
+// StatefulSet to get themselves and the StatefulSet to find their shard.
+func (k *KubeStateMetricsBuilder) WithShardingRole() *KubeStateMetricsBuilder {
+	k.manifests.Role = &applyConfigRbacv1.RoleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Role"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(k.name),
+			Labels:    k.labels,
+			Namespace: ptr.To(k.namespace),
+		},
+		Rules: []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"get"},
+			},
+			{
+				APIGroups:     []string{"apps"},
+				Resources:     []string{"statefulsets"},
+				ResourceNames: []string{k.name},
+				Verbs:         []string{"get"},
+			},
+		},
+	}
+
+	k.manifests.RoleBinding = &applyConfigRbacv1.RoleBindingApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("RoleBinding"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(k.name),
+			Labels:    k.labels,
+			Namespace: ptr.To(k.namespace),
+		},
+		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+			APIGroup: ptr.To("rbac.authorization.k8s.io"),
+			Kind:     ptr.To("Role"),
+			Name:     ptr.To(k.name),
+		},
+		Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
+			{
+				Kind:      ptr.To("ServiceAccount"),
+				Name:      k.manifests.ServiceAccount.Name,
+				Namespace: ptr.To(k.namespace),
+			},
+		},
+	}
+	return k
+}
+
+// podTemplate returns the Pod template of the kube-state-metrics workload.
+// The Pods of a sharded StatefulSet find their shard from the ordinal in
+// their name and the number of replicas of the StatefulSet.
+func (k *KubeStateMetricsBuilder) podTemplate(sharded bool) *applyConfigCorev1.PodTemplateSpecApplyConfiguration {
+	args := []string{"--port=8080"}
+	var env []applyConfigCorev1.EnvVarApplyConfiguration
+	if sharded {
+		args = append(args, "--pod=$(POD_NAME)", "--pod-namespace=$(POD_NAMESPACE)")
+		env = []applyConfigCorev1.EnvVarApplyConfiguration{
+			{
+				Name: ptr.To("POD_NAME"),
+				ValueFrom: &applyConfigCorev1.EnvVarSourceApplyConfiguration{
+					FieldRef: &applyConfigCorev1.ObjectFieldSelectorApplyConfiguration{
+						FieldPath: ptr.To("metadata.name"),
+					},
+				},
+			},
+			{
+				Name: ptr.To("POD_NAMESPACE"),
+				ValueFrom: &applyConfigCorev1.EnvVarSourceApplyConfiguration{
+					FieldRef: &applyConfigCorev1.ObjectFieldSelectorApplyConfiguration{
+						FieldPath: ptr.To("metadata.namespace"),
+					},
 				},
-				Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
-					ServiceAccountName: k.manifests.ServiceAccount.Name,
-					Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+			},
+		}
+	}
+
+	template := &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Labels: k.labelSelectors,
+		},
+		Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+			ServiceAccountName: k.manifests.ServiceAccount.Name,
+			ImagePullSecrets:   podImagePullSecrets(k.imagePullSecrets),
+			Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+				{
+					Name:  ptr.To("kube-state-metrics"),
+					Image: ptr.To(fmt.Sprintf("registry.k8s.io/kube-state-metrics/kube-state-metrics:v%v", k.version)),
+					Args:  args,
+					Env:   env,
+					Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
 						{
-							Name:  ptr.To("kube-state-metrics"),
-							Image: ptr.To(fmt.Sprintf("registry.k8s.io/kube-state-metrics/kube-state-metrics:v%v", k.version)),
-							Args: []string{
-								"--port=8080",
-							},
-							Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
-								{
-									Name:          ptr.To("http"),
-									ContainerPort: ptr.To(int32(8080)),
-								},
-								{
-									Name:          ptr.To("metrics"),
-									ContainerPort: ptr.To(int32(8081)),
-								},
-							},
-							SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
-								AllowPrivilegeEscalation: ptr.To(false),
-								Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
-									Drop: []corev1.Capability{
-										"ALL",
-									},
-								},
-								ReadOnlyRootFilesystem: ptr.To(true),
-								RunAsUser:              ptr.To(int64(65534)),
-								RunAsNonRoot:           ptr.To(true),
-								RunAsGroup:             ptr.To(int64(65534)),
-								SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
-									Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
-								},
+							Name:          ptr.To("http"),
+							ContainerPort: ptr.To(int32(8080)),
+						},
+						{
+							Name:          ptr.To("metrics"),
+							ContainerPort: ptr.To(int32(8081)),
+						},
+					},
+					LivenessProbe:  httpGetProbe("/livez", "http"),
+					ReadinessProbe: httpGetProbe("/readyz", "metrics"),
+					SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+						AllowPrivilegeEscalation: ptr.To(false),
+						Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+							Drop: []corev1.Capability{
+								"ALL",
 							},
 						},
+						ReadOnlyRootFilesystem: ptr.To(true),
+						RunAsUser:              ptr.To(int64(65534)),
+						RunAsNonRoot:           ptr.To(true),
+						RunAsGroup:             ptr.To(int64(65534)),
+						SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+							Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
+						},
 					},
 				},
 			},
 		},
 	}
-	return k
+
+	k.withCustomResourceState(template)
+	k.scheduling.schedulePod(template.Spec, k.labelSelectors)
+
+	return template
 }
 
 func (k *KubeStateMetricsBuilder) WithServiceMonitor() *KubeStateMetricsBuilder {
@@ -307,7 +491,7 @@ func (k *KubeStateMetricsBuilder) WithServiceMonitor() *KubeStateMetricsBuilder
 			APIVersion: ptr.To("monitoring.coreos.com/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("kube-state-metrics"),
+			Name:      ptr.To(k.name),
 			Labels:    k.labels,
 			Namespace: ptr.To(k.namespace),
 		},
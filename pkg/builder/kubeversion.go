@@ -0,0 +1,229 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	applyCofongiAppsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyConfigBatchv1 "k8s.io/client-go/applyconfigurations/batch/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// MinKubeVersion is the oldest Kubernetes version the manifests can target,
+// the first one serving the apiextensions.k8s.io/v1 CRDs of the Prometheus
+// Operator.
+const MinKubeVersion KubeVersion = "1.16"
+
+// The Kubernetes versions from which the fields of the manifests are
+// supported.
+var (
+	topologySpreadVersion = version.MajorMinor(1, 18)
+	seccompProfileVersion = version.MajorMinor(1, 19)
+	ipFamilyPolicyVersion = version.MajorMinor(1, 21)
+	cronJobV1Version      = version.MajorMinor(1, 21)
+)
+
+const (
+	seccompPodAnnotation       = "seccomp.security.alpha.kubernetes.io/pod"
+	seccompContainerAnnotation = "container.seccomp.security.alpha.kubernetes.io/"
+)
+
+// KubeVersion is the major and minor version of Kubernetes targeted by the
+// manifests, such as 1.22. The empty version targets the latest releases.
+type KubeVersion string
+
+// ParseKubeVersion returns the Kubernetes version of a version string such as
+// 1.22, v1.22.3 or v1.22.3-eks-1234, as reported by the API server. The
+// empty string targets the latest releases.
+func ParseKubeVersion(s string) (KubeVersion, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	v, err := version.ParseGeneric(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid Kubernetes version %s: %w", s, err)
+	}
+
+	kubeVersion := KubeVersion(fmt.Sprintf("%d.%d", v.Major(), v.Minor()))
+	if !kubeVersion.atLeast(version.MustParseGeneric(string(MinKubeVersion))) {
+		return "", fmt.Errorf("unsupported Kubernetes version %s, the oldest supported version is %s", kubeVersion, MinKubeVersion)
+	}
+	return kubeVersion, nil
+}
+
+// atLeast reports whether the version is the given one or a later one.
+func (v KubeVersion) atLeast(min *version.Version) bool {
+	if v == "" {
+		return true
+	}
+	return version.MustParseGeneric(string(v)).AtLeast(min)
+}
+
+// TargetKubeVersion adapts the apply configurations of the manifests, given
+// as a pointer to a manifests struct, to the Kubernetes version. The fields
+// the version doesn't serve are replaced by their former equivalent, such as
+// the seccomp annotations before 1.19 and the batch/v1beta1 CronJobs before
+// 1.21, and the fields without equivalent are rejected.
+func TargetKubeVersion(manifests any, v KubeVersion) error {
+	if v == "" {
+		return nil
+	}
+
+	fields := reflect.ValueOf(manifests).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+
+		var configs []reflect.Value
+		switch field.Kind() {
+		case reflect.Pointer:
+			configs = append(configs, field)
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				configs = append(configs, field.Index(j))
+			}
+		}
+
+		for _, config := range configs {
+			if config.Kind() != reflect.Pointer || config.IsNil() {
+				continue
+			}
+
+			if err := targetKubeVersion(config.Interface(), v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func targetKubeVersion(config any, v KubeVersion) error {
+	switch c := config.(type) {
+	case *applyCofongiAppsv1.DeploymentApplyConfiguration:
+		if c.Spec != nil {
+			return targetPodTemplate(*c.Name, c.Spec.Template, v)
+		}
+	case *applyCofongiAppsv1.DaemonSetApplyConfiguration:
+		if c.Spec != nil {
+			return targetPodTemplate(*c.Name, c.Spec.Template, v)
+		}
+	case *applyCofongiAppsv1.StatefulSetApplyConfiguration:
+		if c.Spec != nil {
+			return targetPodTemplate(*c.Name, c.Spec.Template, v)
+		}
+	case *applyConfigBatchv1.CronJobApplyConfiguration:
+		if !v.atLeast(cronJobV1Version) {
+			c.APIVersion = ptr.To("batch/v1beta1")
+		}
+		if c.Spec != nil && c.Spec.JobTemplate != nil && c.Spec.JobTemplate.Spec != nil {
+			return targetPodTemplate(*c.Name, c.Spec.JobTemplate.Spec.Template, v)
+		}
+	case *applyConfigCorev1.ServiceApplyConfiguration:
+		if c.Spec != nil && c.Spec.IPFamilyPolicy != nil && !v.atLeast(ipFamilyPolicyVersion) {
+			return fmt.Errorf("the IP family policy of Service %s requires Kubernetes %s or later", *c.Name, ipFamilyPolicyVersion)
+		}
+	case *monitoringv1.PrometheusApplyConfiguration:
+		if c.Spec != nil && len(c.Spec.TopologySpreadConstraints) > 0 && !v.atLeast(topologySpreadVersion) {
+			return topologySpreadError("Prometheus", *c.Name)
+		}
+	case *monitoringv1alpha1.PrometheusAgentApplyConfiguration:
+		if c.Spec != nil && len(c.Spec.TopologySpreadConstraints) > 0 && !v.atLeast(topologySpreadVersion) {
+			return topologySpreadError("PrometheusAgent", *c.Name)
+		}
+	case *monitoringv1.AlertmanagerApplyConfiguration:
+		if c.Spec != nil && len(c.Spec.TopologySpreadConstraints) > 0 && !v.atLeast(topologySpreadVersion) {
+			return topologySpreadError("Alertmanager", *c.Name)
+		}
+	}
+
+	return nil
+}
+
+func topologySpreadError(kind, name string) error {
+	return fmt.Errorf("the topology spread constraints of %s %s require Kubernetes %s or later", kind, name, topologySpreadVersion)
+}
+
+// targetPodTemplate adapts the Pod template of a workload. Before 1.19, the
+// seccomp profiles are set by annotations instead of the security contexts.
+func targetPodTemplate(name string, template *applyConfigCorev1.PodTemplateSpecApplyConfiguration, v KubeVersion) error {
+	if template == nil || template.Spec == nil {
+		return nil
+	}
+	spec := template.Spec
+
+	if len(spec.TopologySpreadConstraints) > 0 && !v.atLeast(topologySpreadVersion) {
+		return fmt.Errorf("the topology spread constraints of %s require Kubernetes %s or later", name, topologySpreadVersion)
+	}
+
+	if v.atLeast(seccompProfileVersion) {
+		return nil
+	}
+
+	annotations := map[string]string{}
+	if spec.SecurityContext != nil && spec.SecurityContext.SeccompProfile != nil {
+		annotations[seccompPodAnnotation] = seccompAnnotation(spec.SecurityContext.SeccompProfile)
+		spec.SecurityContext.SeccompProfile = nil
+	}
+
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		if container.SecurityContext == nil || container.SecurityContext.SeccompProfile == nil {
+			continue
+		}
+
+		annotations[seccompContainerAnnotation+*container.Name] = seccompAnnotation(container.SecurityContext.SeccompProfile)
+		container.SecurityContext.SeccompProfile = nil
+	}
+
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	if template.ObjectMetaApplyConfiguration == nil {
+		template.ObjectMetaApplyConfiguration = &applyConfigMetav1.ObjectMetaApplyConfiguration{}
+	}
+	// The builders may share the annotations between objects.
+	merged := maps.Clone(template.Annotations)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, annotations)
+	template.Annotations = merged
+
+	return nil
+}
+
+// seccompAnnotation returns the value of the seccomp annotation equivalent
+// to the profile.
+func seccompAnnotation(profile *applyConfigCorev1.SeccompProfileApplyConfiguration) string {
+	switch ptr.Deref(profile.Type, "") {
+	case corev1.SeccompProfileTypeLocalhost:
+		return "localhost/" + ptr.Deref(profile.LocalhostProfile, "")
+	case corev1.SeccompProfileTypeUnconfined:
+		return "unconfined"
+	default:
+		return "runtime/default"
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseKubeVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		expected KubeVersion
+		err      bool
+	}{
+		{version: "", expected: ""},
+		{version: "1.22", expected: "1.22"},
+		{version: "v1.28.3", expected: "1.28"},
+		{version: "v1.27.8-eks-8cb36c9", expected: "1.27"},
+		{version: "1.16", expected: "1.16"},
+		{version: "1.15", err: true},
+		{version: "latest", err: true},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			v, err := ParseKubeVersion(tc.version)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestTargetKubeVersion(t *testing.T) {
+	newPushgateway := func() PushgatewayManifests {
+		return NewPushgatewayBuilder("monitoring", LatestPushgatewayVersion).WithServiceAccount().WithDeployment().WithService().Build()
+	}
+	newVerifier := func() VerifierManifests {
+		return NewVerifier("monitoring", "poctl:latest", "0 * * * *").WithServiceAccount().WithCronJob().Build()
+	}
+
+	// The latest versions leave the manifests untouched.
+	pushgateway := newPushgateway()
+	require.NoError(t, TargetKubeVersion(&pushgateway, ""))
+	require.NoError(t, TargetKubeVersion(&pushgateway, "1.30"))
+	assert.Equal(t, newPushgateway(), pushgateway)
+
+	// Before 1.19, the seccomp profiles are set by annotations.
+	require.NoError(t, TargetKubeVersion(&pushgateway, "1.18"))
+	template := pushgateway.Deployment.Spec.Template
+	assert.Nil(t, template.Spec.Containers[0].SecurityContext.SeccompProfile)
+	assert.Equal(t, "runtime/default", template.Annotations["container.seccomp.security.alpha.kubernetes.io/pushgateway"])
+
+	verifier := newVerifier()
+	require.NoError(t, TargetKubeVersion(&verifier, "1.20"))
+	assert.Equal(t, "batch/v1beta1", *verifier.CronJob.APIVersion)
+	assert.NotNil(t, verifier.CronJob.Spec.JobTemplate.Spec.Template.Spec.SecurityContext.SeccompProfile)
+
+	verifier = newVerifier()
+	require.NoError(t, TargetKubeVersion(&verifier, "1.16"))
+	template = verifier.CronJob.Spec.JobTemplate.Spec.Template
+	assert.Nil(t, template.Spec.SecurityContext.SeccompProfile)
+	assert.Equal(t, "runtime/default", template.Annotations["seccomp.security.alpha.kubernetes.io/pod"])
+
+	// The fields without former equivalent are rejected.
+	operator := NewOperator("monitoring", "0.75.1").WithIPFamilyPolicy(corev1.IPFamilyPolicyPreferDualStack).WithService().Build()
+	require.NoError(t, TargetKubeVersion(&operator, "1.21"))
+	assert.EqualError(t, TargetKubeVersion(&operator, "1.20"), "the IP family policy of Service prometheus-operator requires Kubernetes 1.21 or later")
+
+	prometheus := NewPrometheus("monitoring").
+		WithScheduling(Scheduling{TopologySpreadKey: "topology.kubernetes.io/zone"}).
+		WithServiceAccount().
+		WithPrometheus().
+		Build()
+	require.NoError(t, TargetKubeVersion(&prometheus, "1.18"))
+	assert.Error(t, TargetKubeVersion(&prometheus, "1.17"))
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package builder provides the opinionated manifests of the Prometheus
+// Operator stack deployed by poctl, as server-side apply configurations. The
+// manifests can also be converted to objects or encoded as YAML, for tools
+// which reuse them without applying them with poctl.
+package builder
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifests is implemented by the manifests returned by the builders.
+type Manifests interface {
+	// Manifests returns the objects of the manifests, in an order in which
+	// they can be applied.
+	Manifests() []runtime.Object
+	// EncodeYAML writes the objects as a multi-document YAML stream.
+	EncodeYAML(w io.Writer) error
+}
+
+var (
+	_ Manifests = &AlertManagerManifests{}
+	_ Manifests = &BlackboxExporterManifests{}
+	_ Manifests = &ControlPlaneScrapeManifests{}
+	_ Manifests = &ExporterManifests{}
+	_ Manifests = &KubeStateMetricsManifests{}
+	_ Manifests = &NodexExporterManifests{}
+	_ Manifests = &OpenTelemetryCollectorManifests{}
+	_ Manifests = &OnboardingManifests{}
+	_ Manifests = &OperatorManifests{}
+	_ Manifests = &PrometheusManifests{}
+	_ Manifests = &PushgatewayManifests{}
+	_ Manifests = &VerifierManifests{}
+)
+
+// Manifests returns the objects of the manifests.
+func (m *AlertManagerManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.AlertManager, m.Service, m.ServiceMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *AlertManagerManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *ExporterManifests) Manifests() []runtime.Object {
+	return toObjects(m.Deployment, m.Service, m.ServiceMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *ExporterManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *KubeStateMetricsManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.ClusterRole, m.ClusterRoleBinding, m.Role, m.RoleBinding, m.CustomResourceStateConfig, m.Deployment, m.StatefulSet, m.Service, m.ServiceMonitor, m.PrometheusRule)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *KubeStateMetricsManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *NodexExporterManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.DaemonSet, m.PodMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *NodexExporterManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *OpenTelemetryCollectorManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.Config, m.Deployment, m.Service, m.ServiceMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *OpenTelemetryCollectorManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *OnboardingManifests) Manifests() []runtime.Object {
+	return toObjects(m.Namespace, m.Role, m.RoleBinding, m.ServiceMonitor, m.PrometheusRule)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *OnboardingManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *OperatorManifests) Manifests() []runtime.Object {
+	configs := []any{m.ServiceAccount, m.ClusterRole, m.ClusterRoleBinding}
+	for _, role := range m.Roles {
+		configs = append(configs, role)
+	}
+	for _, roleBinding := range m.RoleBindings {
+		configs = append(configs, roleBinding)
+	}
+	configs = append(configs, m.Deployment, m.Service, m.ServiceMonitor, m.PrometheusRule)
+
+	return toObjects(configs...)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *OperatorManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *BlackboxExporterManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.Config, m.Deployment, m.Service, m.ServiceMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *BlackboxExporterManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *ControlPlaneScrapeManifests) Manifests() []runtime.Object {
+	return toObjects(m.Secret, m.Service, m.ServiceMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *ControlPlaneScrapeManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *PrometheusManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.ClusterRole, m.ClusterRoleBinding, m.Prometheus, m.PrometheusAgent, m.Service, m.ServiceMonitor)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *PrometheusManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *PushgatewayManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.Deployment, m.Service, m.ServiceMonitor, m.PrometheusRule)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *PushgatewayManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// Manifests returns the objects of the manifests.
+func (m *VerifierManifests) Manifests() []runtime.Object {
+	return toObjects(m.ServiceAccount, m.ClusterRole, m.ClusterRoleBinding, m.CronJob)
+}
+
+// EncodeYAML writes the objects of the manifests as YAML.
+func (m *VerifierManifests) EncodeYAML(w io.Writer) error {
+	return EncodeYAML(w, m.Manifests())
+}
+
+// EncodeYAML writes the objects as a multi-document YAML stream.
+func EncodeYAML(w io.Writer, objects []runtime.Object) error {
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("error while encoding %s: %v", obj.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toObjects converts the apply configurations into unstructured objects,
+// skipping the ones which aren't set. The apply configurations carry their
+// kind and API version, which the objects keep.
+func toObjects(configs ...any) []runtime.Object {
+	var objects []runtime.Object
+	for _, config := range configs {
+		v := reflect.ValueOf(config)
+		if config == nil || (v.Kind() == reflect.Pointer && v.IsNil()) {
+			continue
+		}
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(config)
+		if err != nil {
+			// The apply configurations are always structs, which can be
+			// converted.
+			panic(fmt.Sprintf("builder: error while converting %T: %v", config, err))
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: content})
+	}
+	return objects
+}
@@ -0,0 +1,666 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+func TestOperatorManifests(t *testing.T) {
+	manifests := NewOperator("monitoring", "0.75.1").
+		WithServiceAccount().
+		WithNamespaces("default", "app").
+		WithRoles().
+		WithDeployment().
+		WithService().
+		Build()
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"ServiceAccount", "Role", "Role", "RoleBinding", "RoleBinding", "Deployment", "Service"}, kinds)
+
+	var b strings.Builder
+	require.NoError(t, manifests.EncodeYAML(&b))
+	assert.Equal(t, 6, strings.Count(b.String(), "---\n"))
+	assert.Contains(t, b.String(), "kind: Deployment\n")
+	assert.Contains(t, b.String(), "namespace: monitoring\n")
+}
+
+func TestHealthProbesManifests(t *testing.T) {
+	templates := map[string]*applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+		"operator":           NewOperator("monitoring", "0.75.1").WithServiceAccount().WithDeployment().Build().Deployment.Spec.Template,
+		"kube-state-metrics": NewKubeStateMetricsBuilder("monitoring", LatestKubeStateMetricsVersion).WithServiceAccount().WithDeployment().Build().Deployment.Spec.Template,
+		"node-exporter":      NewNodeExporterBuilder("monitoring", LatestNodeExporterVersion).WithServiceAccount().WithDaemonSet().Build().DaemonSet.Spec.Template,
+		"pushgateway":        NewPushgatewayBuilder("monitoring", LatestPushgatewayVersion).WithServiceAccount().WithDeployment().Build().Deployment.Spec.Template,
+		"blackbox-exporter":  NewBlackboxExporterBuilder("monitoring", LatestBlackboxExporterVersion).WithServiceAccount().WithConfig().WithDeployment().Build().Deployment.Spec.Template,
+		"exporter":           NewExporterBuilder("monitoring", "redis:6379", redisPreset(t)).WithDeployment().Build().Deployment.Spec.Template,
+	}
+
+	for name, template := range templates {
+		t.Run(name, func(t *testing.T) {
+			for _, container := range template.Spec.Containers {
+				assert.NotNil(t, container.LivenessProbe, "container %s", *container.Name)
+				assert.NotNil(t, container.ReadinessProbe, "container %s", *container.Name)
+			}
+		})
+	}
+
+	ksm := templates["kube-state-metrics"].Spec.Containers[0]
+	assert.Equal(t, "/livez", *ksm.LivenessProbe.HTTPGet.Path)
+	assert.Equal(t, "http", ksm.LivenessProbe.HTTPGet.Port.StrVal)
+	assert.Equal(t, "/readyz", *ksm.ReadinessProbe.HTTPGet.Path)
+	assert.Equal(t, "metrics", ksm.ReadinessProbe.HTTPGet.Port.StrVal)
+}
+
+func TestExporterClusterDomainManifests(t *testing.T) {
+	manifests := NewExporterBuilder("monitoring", "cache", redisPreset(t)).
+		WithClusterDomain("example.org").
+		WithDeployment().
+		Build()
+
+	assert.Equal(t, "cache-redis-exporter", *manifests.Deployment.Name)
+	assert.Equal(t, []string{"--redis.addr=redis://cache.monitoring.svc.example.org:6379"}, manifests.Deployment.Spec.Template.Spec.Containers[0].Args)
+}
+
+func TestIPFamilyPolicyManifests(t *testing.T) {
+	policy, err := ParseIPFamilyPolicy("preferdualstack")
+	require.NoError(t, err)
+	assert.Equal(t, corev1.IPFamilyPolicyPreferDualStack, policy)
+
+	_, err = ParseIPFamilyPolicy("DualStack")
+	assert.EqualError(t, err, "unknown IP family policy DualStack, must be one of: SingleStack, PreferDualStack, RequireDualStack")
+
+	operator := NewOperator("monitoring", "0.75.1").WithIPFamilyPolicy(policy).WithService().Build()
+	assert.Equal(t, policy, *operator.Service.Spec.IPFamilyPolicy)
+
+	pushgateway := NewPushgatewayBuilder("monitoring", LatestPushgatewayVersion).WithService().Build()
+	assert.Nil(t, pushgateway.Service.Spec.IPFamilyPolicy)
+
+	// The components listen on the addresses of both families.
+	nodeExporter := NewNodeExporterBuilder("monitoring", LatestNodeExporterVersion).WithServiceAccount().WithDaemonSet().Build()
+	assert.Contains(t, nodeExporter.DaemonSet.Spec.Template.Spec.Containers[0].Args, "--web.listen-address=:9100")
+
+	collector := NewOpenTelemetryCollectorBuilder("monitoring", LatestOpenTelemetryCollectorVersion, "otlp.example.com:4317").WithConfig().Build()
+	assert.NotContains(t, collector.Config.Data["config.yaml"], "0.0.0.0")
+}
+
+func TestShardedKubeStateMetricsManifests(t *testing.T) {
+	manifests := NewKubeStateMetricsBuilder("monitoring", LatestKubeStateMetricsVersion).
+		WithShards(3).
+		WithServiceAccount().
+		WithStatefulSet().
+		WithShardingRole().
+		Build()
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"ServiceAccount", "Role", "RoleBinding", "StatefulSet"}, kinds)
+
+	assert.Nil(t, manifests.Deployment)
+	assert.Equal(t, int32(3), *manifests.StatefulSet.Spec.Replicas)
+	container := manifests.StatefulSet.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, container.Args, "--pod=$(POD_NAME)")
+	assert.Contains(t, container.Args, "--pod-namespace=$(POD_NAMESPACE)")
+	assert.Len(t, container.Env, 2)
+}
+
+func TestCustomResourceStateManifests(t *testing.T) {
+	manifests := NewKubeStateMetricsBuilder("monitoring", LatestKubeStateMetricsVersion).
+		WithCustomResourceState().
+		WithCustomResourceStateRules().
+		WithServiceAccount().
+		WithClusterRole().
+		WithDeployment().
+		Build()
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"ServiceAccount", "ClusterRole", "ConfigMap", "Deployment", "PrometheusRule"}, kinds)
+
+	var config map[string]any
+	require.NoError(t, yaml.Unmarshal([]byte(manifests.CustomResourceStateConfig.Data["config.yaml"]), &config))
+	assert.Equal(t, "CustomResourceStateMetrics", config["kind"])
+
+	assert.Contains(t, manifests.ClusterRole.Rules, customResourceStateRules()[0])
+
+	pod := manifests.Deployment.Spec.Template.Spec
+	require.Len(t, pod.Volumes, 1)
+	assert.Equal(t, "kube-state-metrics-custom-resource-state", *pod.Volumes[0].ConfigMap.Name)
+	container := pod.Containers[0]
+	assert.Contains(t, container.Args, "--custom-resource-state-config-file=/etc/kube-state-metrics/custom-resource-state/config.yaml")
+	require.Len(t, container.VolumeMounts, 1)
+
+	require.Len(t, manifests.PrometheusRule.Spec.Groups, 1)
+	for _, rule := range manifests.PrometheusRule.Spec.Groups[0].Rules {
+		assert.Contains(t, rule.Expr.String(), CustomResourceStateMetricPrefix+"_")
+	}
+
+	plain := NewKubeStateMetricsBuilder("monitoring", LatestKubeStateMetricsVersion).
+		WithServiceAccount().
+		WithClusterRole().
+		WithDeployment().
+		Build()
+	assert.NotContains(t, plain.ClusterRole.Rules, customResourceStateRules()[0])
+	assert.Empty(t, plain.Deployment.Spec.Template.Spec.Volumes)
+}
+
+func TestSelfMonitoringManifests(t *testing.T) {
+	operator := NewOperator("monitoring", "0.75.1").
+		WithStack("team-a").
+		WithServiceAccount().
+		WithDeployment().
+		WithSelfMonitoringRules().
+		Build()
+
+	require.NotNil(t, operator.PrometheusRule)
+	assert.Equal(t, "team-a-prometheus-operator", *operator.PrometheusRule.Name)
+	rules := operator.PrometheusRule.Spec.Groups[0].Rules
+	require.NotEmpty(t, rules)
+	assert.Contains(t, rules[0].Expr.String(), `job="team-a-prometheus-operator", namespace="monitoring"`)
+
+	prometheus := NewPrometheus("monitoring").
+		WithReloaderMonitoring().
+		WithService().
+		WithServiceMonitor().
+		Build()
+
+	require.Len(t, prometheus.Service.Spec.Ports, 2)
+	assert.Equal(t, "reloader-web", *prometheus.Service.Spec.Ports[1].Name)
+	require.Len(t, prometheus.ServiceMonitor.Spec.Endpoints, 2)
+	assert.Equal(t, "reloader-web", *prometheus.ServiceMonitor.Spec.Endpoints[1].Port)
+
+	alertmanager := NewAlertManager("monitoring").
+		WithReloaderMonitoring().
+		WithServiceMonitor().
+		Build()
+
+	require.Len(t, alertmanager.ServiceMonitor.Spec.Endpoints, 2)
+	assert.Equal(t, "reloader-web", *alertmanager.ServiceMonitor.Spec.Endpoints[1].Port)
+}
+
+func TestOpenTelemetryCollectorManifests(t *testing.T) {
+	build := func(b *OpenTelemetryCollectorBuilder) OpenTelemetryCollectorManifests {
+		return b.WithStack("team-a").
+			WithServiceAccount().
+			WithConfig().
+			WithDeployment().
+			WithService().
+			WithServiceMonitor().
+			Build()
+	}
+
+	manifests := build(NewOpenTelemetryCollectorBuilder("monitoring", LatestOpenTelemetryCollectorVersion, "otlp.example.com:4317"))
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"ServiceAccount", "ConfigMap", "Deployment", "Service", "ServiceMonitor"}, kinds)
+
+	var config map[string]any
+	require.NoError(t, yaml.Unmarshal([]byte(manifests.Config.Data["config.yaml"]), &config))
+	assert.Contains(t, config["receivers"], "prometheusremotewrite")
+	assert.Equal(t, map[string]any{"endpoint": "otlp.example.com:4317"}, config["exporters"].(map[string]any)["otlp"])
+
+	assert.Equal(t, "team-a-otel-collector-config", *manifests.Deployment.Spec.Template.Spec.Volumes[0].ConfigMap.Name)
+	assert.Len(t, manifests.Service.Spec.Ports, 2)
+	assert.Equal(t, "team-a", manifests.ServiceMonitor.Labels[PartOfLabel])
+	assert.Equal(t, "http://team-a-otel-collector.monitoring.svc:9090/api/v1/write", OpenTelemetryCollectorRemoteWriteURL("monitoring", "team-a", ""))
+	assert.Equal(t, "http://team-a-otel-collector.monitoring.svc.example.org:9090/api/v1/write", OpenTelemetryCollectorRemoteWriteURL("monitoring", "team-a", "example.org"))
+
+	federation := build(NewOpenTelemetryCollectorBuilder("monitoring", LatestOpenTelemetryCollectorVersion, "https://otlp.example.com").WithFederation())
+
+	config = nil
+	require.NoError(t, yaml.Unmarshal([]byte(federation.Config.Data["config.yaml"]), &config))
+	assert.NotContains(t, config["receivers"], "prometheusremotewrite")
+	assert.Contains(t, federation.Config.Data["config.yaml"], "team-a-prometheus.monitoring.svc:9090")
+	assert.Contains(t, config["exporters"], "otlphttp")
+	assert.Len(t, federation.Service.Spec.Ports, 1)
+
+	federation = build(NewOpenTelemetryCollectorBuilder("monitoring", LatestOpenTelemetryCollectorVersion, "https://otlp.example.com").WithFederation().WithClusterDomain("example.org"))
+	assert.Contains(t, federation.Config.Data["config.yaml"], "team-a-prometheus.monitoring.svc.example.org:9090")
+}
+
+func TestPushgatewayManifests(t *testing.T) {
+	manifests := NewPushgatewayBuilder("monitoring", LatestPushgatewayVersion).
+		WithStack("team-a").
+		WithServiceAccount().
+		WithDeployment().
+		WithService().
+		WithServiceMonitor().
+		WithPrometheusRule().
+		Build()
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"ServiceAccount", "Deployment", "Service", "ServiceMonitor", "PrometheusRule"}, kinds)
+
+	assert.Equal(t, "team-a-pushgateway", *manifests.Deployment.Name)
+	assert.Equal(t, "quay.io/prometheus/pushgateway:v"+LatestPushgatewayVersion, *manifests.Deployment.Spec.Template.Spec.Containers[0].Image)
+	require.Len(t, manifests.ServiceMonitor.Spec.Endpoints, 1)
+	assert.True(t, *manifests.ServiceMonitor.Spec.Endpoints[0].HonorLabels)
+
+	require.Len(t, manifests.PrometheusRule.Spec.Groups, 1)
+	rule := manifests.PrometheusRule.Spec.Groups[0].Rules[0]
+	assert.Equal(t, "PushgatewayGroupStale", *rule.Alert)
+	assert.Equal(t, `time() - push_time_seconds{service="team-a-pushgateway"} > 90000`, rule.Expr.String())
+}
+
+func TestBlackboxExporterManifests(t *testing.T) {
+	manifests := NewBlackboxExporterBuilder("monitoring", LatestBlackboxExporterVersion).
+		WithStack("team-a").
+		WithServiceAccount().
+		WithConfig().
+		WithDeployment().
+		WithService().
+		WithServiceMonitor().
+		Build()
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"ServiceAccount", "ConfigMap", "Deployment", "Service", "ServiceMonitor"}, kinds)
+
+	assert.Equal(t, "team-a-blackbox-exporter", *manifests.Deployment.Name)
+	assert.Equal(t, "team-a-blackbox-exporter-config", *manifests.Deployment.Spec.Template.Spec.Volumes[0].ConfigMap.Name)
+	assert.Equal(t, []string{"http_2xx", "icmp", "tcp_connect"}, BlackboxExporterModules())
+	for _, module := range BlackboxExporterModules() {
+		assert.Contains(t, manifests.Config.Data["blackbox.yml"], "\n  "+module+":\n")
+	}
+	assert.Equal(t, "team-a-blackbox-exporter.monitoring.svc:9115", BlackboxExporterProberURL("monitoring", "team-a", ""))
+
+	probe := NewProbeBuilder("default", "website", BlackboxExporterProberURL("monitoring", "team-a", "example.org."), "https://example.com").
+		WithStack("team-a").
+		WithModule("icmp").
+		WithInterval("1m").
+		Build()
+
+	assert.Equal(t, "team-a", probe.Labels[PartOfLabel])
+	assert.Equal(t, "team-a-blackbox-exporter.monitoring.svc.example.org:9115", *probe.Spec.ProberSpec.URL)
+	assert.Equal(t, "icmp", *probe.Spec.Module)
+	assert.Equal(t, []string{"https://example.com"}, probe.Spec.Targets.StaticConfig.Targets)
+	assert.Equal(t, "1m", string(*probe.Spec.Interval))
+}
+
+func TestControlPlaneScrapeManifests(t *testing.T) {
+	etcd := NewControlPlaneScrapeBuilder("kube-system", "etcd").
+		WithStack("team-a").
+		WithClientCertificates("etcd-client", []byte("ca"), []byte("cert"), []byte("key")).
+		WithService().
+		WithServiceMonitor().
+		Build()
+
+	var kinds []string
+	for _, obj := range etcd.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"Secret", "Service", "ServiceMonitor"}, kinds)
+
+	assert.Equal(t, "team-a-etcd", *etcd.Service.Name)
+	assert.Equal(t, "None", *etcd.Service.Spec.ClusterIP)
+	assert.Equal(t, map[string]string{"component": "etcd", "tier": "control-plane"}, etcd.Service.Spec.Selector)
+	assert.Equal(t, int32(2379), *etcd.Service.Spec.Ports[0].Port)
+	assert.Equal(t, []byte("key"), etcd.Secret.Data[ClientKeySecretKey])
+
+	require.Len(t, etcd.ServiceMonitor.Spec.Endpoints, 1)
+	endpoint := etcd.ServiceMonitor.Spec.Endpoints[0]
+	assert.Equal(t, "https", *endpoint.Scheme)
+	assert.Equal(t, "etcd-client", endpoint.TLSConfig.CA.Secret.Name)
+	assert.Equal(t, ClientCertSecretKey, endpoint.TLSConfig.Cert.Secret.Key)
+	assert.Equal(t, ClientKeySecretKey, endpoint.TLSConfig.KeySecret.Key)
+	assert.Nil(t, endpoint.BearerTokenFile)
+
+	scheduler := NewControlPlaneScrapeBuilder("kube-system", "kube-scheduler").
+		WithPodLabels(map[string]string{"k8s-app": "kube-scheduler"}).
+		WithService().
+		WithServiceMonitor().
+		Build()
+
+	assert.Nil(t, scheduler.Secret)
+	assert.Equal(t, map[string]string{"k8s-app": "kube-scheduler"}, scheduler.Service.Spec.Selector)
+	endpoint = scheduler.ServiceMonitor.Spec.Endpoints[0]
+	assert.NotNil(t, endpoint.BearerTokenFile)
+	assert.True(t, *endpoint.TLSConfig.InsecureSkipVerify)
+	assert.Nil(t, endpoint.TLSConfig.CA)
+}
+
+func TestRemoteWriteManifests(t *testing.T) {
+	agent := NewPrometheus("monitoring").
+		WithRemoteWrite("https://metrics.example.com/api/v1/write").
+		WithServiceAccount().
+		WithPrometheusAgent().
+		Build()
+
+	require.Len(t, agent.PrometheusAgent.Spec.RemoteWrite, 1)
+	assert.Equal(t, "https://metrics.example.com/api/v1/write", *agent.PrometheusAgent.Spec.RemoteWrite[0].URL)
+
+	prometheus := NewPrometheus("monitoring").
+		WithServiceAccount().
+		WithPrometheus().
+		Build()
+
+	assert.Empty(t, prometheus.Prometheus.Spec.RemoteWrite)
+}
+
+func TestVersionManifests(t *testing.T) {
+	prometheus := NewPrometheus("monitoring").
+		WithVersion("2.54.1").
+		WithServiceAccount().
+		WithPrometheus().
+		Build()
+
+	assert.Equal(t, "2.54.1", *prometheus.Prometheus.Spec.Version)
+	assert.Equal(t, "quay.io/prometheus/prometheus:v2.54.1", *prometheus.Prometheus.Spec.Image)
+
+	agent := NewPrometheus("monitoring").
+		WithVersion("2.54.1").
+		WithServiceAccount().
+		WithPrometheusAgent().
+		Build()
+
+	assert.Equal(t, "2.54.1", *agent.PrometheusAgent.Spec.Version)
+	assert.Equal(t, "quay.io/prometheus/prometheus:v2.54.1", *agent.PrometheusAgent.Spec.Image)
+
+	alertmanager := NewAlertManager("monitoring").
+		WithVersion("0.27.0").
+		WithServiceAccount().
+		WithAlertManager().
+		Build()
+
+	assert.Equal(t, "0.27.0", *alertmanager.AlertManager.Spec.Version)
+	assert.Equal(t, "quay.io/prometheus/alertmanager:v0.27.0", *alertmanager.AlertManager.Spec.Image)
+
+	// The operator picks the versions when they aren't pinned.
+	unpinned := NewAlertManager("monitoring").
+		WithServiceAccount().
+		WithAlertManager().
+		Build()
+
+	assert.Nil(t, unpinned.AlertManager.Spec.Version)
+	assert.Nil(t, unpinned.AlertManager.Spec.Image)
+}
+
+func TestImagePullSecretsManifests(t *testing.T) {
+	operator := NewOperator("monitoring", "0.78.2").
+		WithImagePullSecrets("registry").
+		WithServiceAccount().
+		WithDeployment().
+		Build()
+
+	require.Len(t, operator.ServiceAccount.ImagePullSecrets, 1)
+	assert.Equal(t, "registry", *operator.ServiceAccount.ImagePullSecrets[0].Name)
+	require.Len(t, operator.Deployment.Spec.Template.Spec.ImagePullSecrets, 1)
+	assert.Equal(t, "registry", *operator.Deployment.Spec.Template.Spec.ImagePullSecrets[0].Name)
+
+	prometheus := NewPrometheus("monitoring").
+		WithImagePullSecrets("registry").
+		WithServiceAccount().
+		WithPrometheus().
+		Build()
+
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}}, prometheus.Prometheus.Spec.ImagePullSecrets)
+
+	alertmanager := NewAlertManager("monitoring").
+		WithImagePullSecrets("registry").
+		WithServiceAccount().
+		WithAlertManager().
+		Build()
+
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}}, alertmanager.AlertManager.Spec.ImagePullSecrets)
+
+	nodeExporter := NewNodeExporterBuilder("monitoring", LatestNodeExporterVersion).
+		WithImagePullSecrets("registry").
+		WithServiceAccount().
+		WithDaemonSet().
+		Build()
+
+	require.Len(t, nodeExporter.DaemonSet.Spec.Template.Spec.ImagePullSecrets, 1)
+	assert.Equal(t, "registry", *nodeExporter.DaemonSet.Spec.Template.Spec.ImagePullSecrets[0].Name)
+
+	// Without image pull secrets, none is attached.
+	kubeStateMetrics := NewKubeStateMetricsBuilder("monitoring", LatestKubeStateMetricsVersion).
+		WithServiceAccount().
+		WithDeployment().
+		Build()
+
+	assert.Empty(t, kubeStateMetrics.ServiceAccount.ImagePullSecrets)
+	assert.Empty(t, kubeStateMetrics.Deployment.Spec.Template.Spec.ImagePullSecrets)
+}
+
+func TestSchedulingManifests(t *testing.T) {
+	scheduling := Scheduling{
+		PriorityClassName: "monitoring",
+		TopologySpreadKey: "topology.kubernetes.io/zone",
+		AntiAffinity:      HardAntiAffinity,
+	}
+
+	prometheus := NewPrometheus("monitoring").
+		WithScheduling(scheduling).
+		WithServiceAccount().
+		WithPrometheus().
+		Build()
+
+	spec := prometheus.Prometheus.Spec
+	assert.Equal(t, "monitoring", *spec.PriorityClassName)
+	require.Len(t, spec.TopologySpreadConstraints, 1)
+	assert.Equal(t, "topology.kubernetes.io/zone", *spec.TopologySpreadConstraints[0].TopologyKey)
+	assert.Equal(t, map[string]string{"prometheus": "prometheus"}, spec.TopologySpreadConstraints[0].LabelSelector.MatchLabels)
+	require.NotNil(t, spec.Affinity)
+	require.Len(t, spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, 1)
+	assert.Equal(t, "kubernetes.io/hostname", spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].TopologyKey)
+
+	// The agent in DaemonSet mode only gets the priority class.
+	agent := NewPrometheus("monitoring").
+		WithDaemonSetMode().
+		WithScheduling(scheduling).
+		WithServiceAccount().
+		WithPrometheusAgent().
+		Build()
+
+	assert.Equal(t, "monitoring", *agent.PrometheusAgent.Spec.PriorityClassName)
+	assert.Nil(t, agent.PrometheusAgent.Spec.Affinity)
+	assert.Empty(t, agent.PrometheusAgent.Spec.TopologySpreadConstraints)
+
+	alertmanager := NewAlertManager("monitoring").
+		WithScheduling(Scheduling{AntiAffinity: SoftAntiAffinity}).
+		WithServiceAccount().
+		WithAlertManager().
+		Build()
+
+	assert.Nil(t, alertmanager.AlertManager.Spec.PriorityClassName)
+	assert.Empty(t, alertmanager.AlertManager.Spec.TopologySpreadConstraints)
+	require.Len(t, alertmanager.AlertManager.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, 1)
+
+	kubeStateMetrics := NewKubeStateMetricsBuilder("monitoring", LatestKubeStateMetricsVersion).
+		WithScheduling(scheduling).
+		WithServiceAccount().
+		WithShards(3).
+		WithStatefulSet().
+		Build()
+
+	podSpec := kubeStateMetrics.StatefulSet.Spec.Template.Spec
+	assert.Equal(t, "monitoring", *podSpec.PriorityClassName)
+	require.Len(t, podSpec.TopologySpreadConstraints, 1)
+	assert.Equal(t, "topology.kubernetes.io/zone", *podSpec.TopologySpreadConstraints[0].TopologyKey)
+	require.Len(t, podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, 1)
+}
+
+func TestNodeExporterCollectorsManifests(t *testing.T) {
+	defaults := NewNodeExporterBuilder("monitoring", LatestNodeExporterVersion).
+		WithServiceAccount().
+		WithDaemonSet().
+		Build()
+
+	container := defaults.DaemonSet.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, container.Args, "--no-collector.hwmon")
+	assert.NotContains(t, container.Args, "--collector.systemd")
+	assert.Len(t, defaults.DaemonSet.Spec.Template.Spec.Volumes, 2)
+
+	collectors := NodeExporterCollectors{
+		Enable:            []string{"systemd", "processes", "hwmon"},
+		Disable:           []string{"arp"},
+		TextfileDirectory: "/var/lib/node-exporter",
+	}
+	require.NoError(t, collectors.Validate())
+
+	manifests := NewNodeExporterBuilder("monitoring", LatestNodeExporterVersion).
+		WithCollectors(collectors).
+		WithArgs("--collector.systemd.unit-include=kubelet.service").
+		WithServiceAccount().
+		WithDaemonSet().
+		Build()
+
+	podSpec := manifests.DaemonSet.Spec.Template.Spec
+	args := podSpec.Containers[0].Args
+	assert.Contains(t, args, "--no-collector.wifi")
+	assert.NotContains(t, args, "--no-collector.hwmon")
+	assert.NotContains(t, args, "--collector.hwmon")
+	assert.Contains(t, args, "--collector.systemd")
+	assert.Contains(t, args, "--collector.processes")
+	assert.Contains(t, args, "--no-collector.arp")
+	assert.Contains(t, args, "--collector.textfile.directory=/host/textfile")
+	assert.Equal(t, "--collector.systemd.unit-include=kubelet.service", args[len(args)-1])
+
+	var hostPaths []string
+	for _, v := range podSpec.Volumes {
+		hostPaths = append(hostPaths, *v.HostPath.Path)
+	}
+	assert.Equal(t, []string{"/sys", "/", "/var/lib/node-exporter", "/var/run/dbus/system_bus_socket"}, hostPaths)
+
+	var mountPaths []string
+	for _, m := range podSpec.Containers[0].VolumeMounts {
+		mountPaths = append(mountPaths, *m.MountPath)
+	}
+	assert.Equal(t, []string{"/host/sys", "/host/root", "/host/textfile", "/var/run/dbus/system_bus_socket"}, mountPaths)
+
+	for _, invalid := range []NodeExporterCollectors{
+		{Enable: []string{"--collector.systemd"}},
+		{Enable: []string{"systemd"}, Disable: []string{"systemd"}},
+		{TextfileDirectory: "var/lib/node-exporter"},
+		{Disable: []string{"textfile"}, TextfileDirectory: "/var/lib/node-exporter"},
+	} {
+		assert.Error(t, invalid.Validate())
+	}
+}
+
+func TestVerifierManifests(t *testing.T) {
+	manifests := NewVerifier("monitoring", "poctl:latest", "0 * * * *").
+		WithServiceAccount().
+		WithClusterRole().
+		WithClusterRoleBinding().
+		WithCronJob().
+		Build()
+
+	for _, rule := range manifests.ClusterRole.Rules {
+		assert.NotContains(t, rule.Resources, "secrets")
+	}
+	assert.Equal(t, []string{"verify", "--log-format=json", "--snapshot"}, manifests.CronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args)
+
+	manifests = NewVerifier("monitoring", "poctl:latest", "0 * * * *").
+		WithServiceAccount().
+		WithClusterRole().
+		WithSecretsAccess().
+		WithClusterRoleBinding().
+		WithCronJob().
+		WithPushgateway("http://pushgateway.monitoring.svc:9091").
+		Build()
+
+	secrets := manifests.ClusterRole.Rules[len(manifests.ClusterRole.Rules)-1]
+	assert.Equal(t, []string{"secrets"}, secrets.Resources)
+	assert.Equal(t, []string{"get"}, secrets.Verbs)
+	assert.Equal(t, []string{"verify", "--log-format=json", "--snapshot", "--pushgateway-url=http://pushgateway.monitoring.svc:9091"}, manifests.CronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args)
+}
+
+func TestMetricsReaderManifests(t *testing.T) {
+	manifests := NewMetricsReader("app", "api").
+		WithServiceAccount().
+		WithClusterRole().
+		WithClusterRoleBinding().
+		WithToken("bound-token", time.Date(2027, time.October, 16, 12, 0, 0, 0, time.UTC)).
+		Build()
+
+	assert.Equal(t, "api-metrics-reader", *manifests.ServiceAccount.Name)
+	assert.Equal(t, "app-api-metrics-reader", *manifests.ClusterRole.Name)
+	assert.Equal(t, []string{"/metrics"}, manifests.ClusterRole.Rules[0].NonResourceURLs)
+	assert.Equal(t, "api-metrics-reader", *manifests.ClusterRoleBinding.Subjects[0].Name)
+	assert.Equal(t, corev1.SecretTypeOpaque, *manifests.Secret.Type)
+	assert.Equal(t, []byte("bound-token"), manifests.Secret.Data[TokenSecretKey])
+	assert.Equal(t, "2027-10-16T12:00:00Z", manifests.Secret.Annotations[TokenExpirationAnnotation])
+
+	endpoint := monitoringv1.EndpointApplyConfiguration{Port: ptr.To("https")}
+	manifests.SecureEndpoint(&endpoint)
+	assert.Equal(t, "https", *endpoint.Scheme)
+	assert.Equal(t, "Bearer", *endpoint.Authorization.Type)
+	assert.Equal(t, "api-metrics-reader-token", endpoint.Authorization.Credentials.Name)
+	assert.Equal(t, TokenSecretKey, endpoint.Authorization.Credentials.Key)
+	assert.Nil(t, endpoint.TLSConfig.InsecureSkipVerify)
+	assert.Equal(t, "api.app.svc", *endpoint.TLSConfig.ServerName)
+	assert.Nil(t, endpoint.TLSConfig.CA)
+
+	withCA := NewMetricsReader("app", "api").
+		WithServiceAccount().
+		WithToken("bound-token", time.Now()).
+		WithCA("kube-root-ca.crt", "ca.crt").
+		Build()
+
+	withCA.SecureEndpoint(&endpoint)
+	assert.Equal(t, "kube-root-ca.crt", endpoint.TLSConfig.CA.ConfigMap.Name)
+	assert.Equal(t, "ca.crt", endpoint.TLSConfig.CA.ConfigMap.Key)
+}
+
+func TestOnboardingManifests(t *testing.T) {
+	manifests := NewOnboardingBuilder("team-a").
+		WithNamespaceLabels(map[string]string{"monitoring": "main"}).
+		WithServiceMonitor(map[string]string{"release": "main"}).
+		WithPrometheusRule(map[string]string{"release": "main"}).
+		WithRBAC([]string{"team-a"}, []string{"alice"}).
+		Build()
+
+	var kinds []string
+	for _, obj := range manifests.Manifests() {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	assert.Equal(t, []string{"Namespace", "Role", "RoleBinding", "ServiceMonitor", "PrometheusRule"}, kinds)
+
+	assert.Equal(t, "main", manifests.Namespace.Labels["monitoring"])
+	assert.Equal(t, "main", manifests.ServiceMonitor.Labels["release"])
+	assert.Equal(t, "team-a", manifests.ServiceMonitor.Spec.Selector.MatchLabels[PartOfLabel])
+	assert.Equal(t, `up{namespace="team-a"} == 0`, manifests.PrometheusRule.Spec.Groups[0].Rules[0].Expr.StrVal)
+	assert.Equal(t, []string{"servicemonitors", "podmonitors", "probes", "prometheusrules"}, manifests.Role.Rules[0].Resources)
+	require.Len(t, manifests.RoleBinding.Subjects, 2)
+	assert.Equal(t, "Group", *manifests.RoleBinding.Subjects[0].Kind)
+	assert.Equal(t, "User", *manifests.RoleBinding.Subjects[1].Kind)
+
+	assert.Nil(t, NewOnboardingBuilder("team-a").WithRBAC(nil, nil).Build().RoleBinding)
+}
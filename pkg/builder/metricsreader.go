@@ -0,0 +1,201 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"time"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// TokenSecretKey is the key of the token in the Secret of the metrics
+	// reader.
+	TokenSecretKey = "token"
+	// TokenExpirationAnnotation is the annotation of the Secret of the
+	// metrics reader holding the expiry of its token in RFC 3339 format,
+	// checked by the ServiceMonitor analyzer since nothing renews the token.
+	TokenExpirationAnnotation = "poctl.prometheus-operator.dev/token-expiration"
+)
+
+// MetricsReaderBuilder builds the identity Prometheus presents when scraping
+// endpoints protected by an authorizing proxy such as kube-rbac-proxy: a
+// ServiceAccount allowed to get /metrics, and a Secret holding its token.
+type MetricsReaderBuilder struct {
+	labels    map[string]string
+	namespace string
+	name      string
+	manifests MetricsReaderManifests
+}
+
+type MetricsReaderManifests struct {
+	ServiceAccount     *applyConfigCorev1.ServiceAccountApplyConfiguration
+	Secret             *applyConfigCorev1.SecretApplyConfiguration
+	ClusterRole        *applyConfigRbacv1.ClusterRoleApplyConfiguration
+	ClusterRoleBinding *applyConfigRbacv1.ClusterRoleBindingApplyConfiguration
+
+	// ServerName is the name the certificate of the proxy is verified
+	// against, the endpoints being scraped by their IP address.
+	ServerName string
+	// CA is the ConfigMap key holding the CA of the certificate of the
+	// proxy, the CAs of the Prometheus container are used when nil.
+	CA *corev1.ConfigMapKeySelector
+}
+
+// NewMetricsReader returns a builder for the metrics reader of the
+// endpoints of a Service.
+func NewMetricsReader(namespace, service string) *MetricsReaderBuilder {
+	return &MetricsReaderBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name":      "metrics-reader",
+			"app.kubernetes.io/instance":  service,
+			"app.kubernetes.io/component": "monitoring",
+		},
+		namespace: namespace,
+		name:      service + "-metrics-reader",
+		manifests: MetricsReaderManifests{
+			ServerName: service + "." + namespace + ".svc",
+		},
+	}
+}
+
+func (m *MetricsReaderBuilder) WithServiceAccount() *MetricsReaderBuilder {
+	m.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(m.name),
+			Labels:    m.labels,
+			Namespace: ptr.To(m.namespace),
+		},
+	}
+	return m
+}
+
+// WithClusterRole grants the get verb on the /metrics non-resource URL, which
+// kube-rbac-proxy authorizes by default. Non-resource URLs can only be
+// granted by ClusterRoles, the name of the ClusterRole is prefixed by the
+// namespace to stay unique.
+func (m *MetricsReaderBuilder) WithClusterRole() *MetricsReaderBuilder {
+	m.manifests.ClusterRole = &applyConfigRbacv1.ClusterRoleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRole"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:   ptr.To(m.namespace + "-" + m.name),
+			Labels: m.labels,
+		},
+		Rules: []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			{
+				NonResourceURLs: []string{"/metrics"},
+				Verbs:           []string{"get"},
+			},
+		},
+	}
+	return m
+}
+
+func (m *MetricsReaderBuilder) WithClusterRoleBinding() *MetricsReaderBuilder {
+	m.manifests.ClusterRoleBinding = &applyConfigRbacv1.ClusterRoleBindingApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRoleBinding"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:   m.manifests.ClusterRole.Name,
+			Labels: m.labels,
+		},
+		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+			APIGroup: ptr.To("rbac.authorization.k8s.io"),
+			Kind:     ptr.To("ClusterRole"),
+			Name:     m.manifests.ClusterRole.Name,
+		},
+		Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
+			{
+				Kind:      ptr.To("ServiceAccount"),
+				Name:      m.manifests.ServiceAccount.Name,
+				Namespace: ptr.To(m.namespace),
+			},
+		},
+	}
+	return m
+}
+
+// WithToken builds a Secret holding a token requested for the ServiceAccount
+// with the TokenRequest API, bound to the audiences expected by the scraped
+// endpoints, annotated with the expiry of the token.
+func (m *MetricsReaderBuilder) WithToken(token string, expiration time.Time) *MetricsReaderBuilder {
+	m.manifests.Secret = &applyConfigCorev1.SecretApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Secret"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(m.name + "-token"),
+			Labels:    m.labels,
+			Namespace: ptr.To(m.namespace),
+			Annotations: map[string]string{
+				TokenExpirationAnnotation: expiration.UTC().Format(time.RFC3339),
+			},
+		},
+		Type: ptr.To(corev1.SecretTypeOpaque),
+		Data: map[string][]byte{TokenSecretKey: []byte(token)},
+	}
+	return m
+}
+
+// WithCA verifies the certificate of the proxy with the CA held by the key
+// of a ConfigMap of the namespace.
+func (m *MetricsReaderBuilder) WithCA(configMap, key string) *MetricsReaderBuilder {
+	m.manifests.CA = &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: configMap},
+		Key:                  key,
+	}
+	return m
+}
+
+func (m *MetricsReaderBuilder) Build() MetricsReaderManifests {
+	return m.manifests
+}
+
+// SecureEndpoint scrapes the endpoint over https, authenticating with the
+// token of the metrics reader. The certificate of the proxy is verified
+// against the DNS name of the Service.
+func (m MetricsReaderManifests) SecureEndpoint(endpoint *monitoringv1.EndpointApplyConfiguration) {
+	endpoint.Scheme = ptr.To("https")
+	endpoint.Authorization = &monitoringv1.SafeAuthorizationApplyConfiguration{
+		Type: ptr.To("Bearer"),
+		Credentials: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: *m.Secret.Name},
+			Key:                  TokenSecretKey,
+		},
+	}
+	endpoint.TLSConfig = &monitoringv1.TLSConfigApplyConfiguration{
+		SafeTLSConfigApplyConfiguration: monitoringv1.SafeTLSConfigApplyConfiguration{
+			ServerName: ptr.To(m.ServerName),
+		},
+	}
+	if m.CA != nil {
+		endpoint.TLSConfig.CA = &monitoringv1.SecretOrConfigMapApplyConfiguration{ConfigMap: m.CA}
+	}
+}
@@ -16,6 +16,9 @@ package builder
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
@@ -31,11 +34,16 @@ import (
 const LatestNodeExporterVersion = "1.8.2"
 
 type NodeExporterBuilder struct {
-	labels         map[string]string
-	labelSelectors map[string]string
-	namespace      string
-	manifests      NodexExporterManifests
-	version        string
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	name             string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	collectors       NodeExporterCollectors
+	args             []string
+	manifests        NodexExporterManifests
+	version          string
 }
 
 type NodexExporterManifests struct {
@@ -47,6 +55,7 @@ type NodexExporterManifests struct {
 func NewNodeExporterBuilder(namespace, version string) *NodeExporterBuilder {
 	return &NodeExporterBuilder{
 		namespace: namespace,
+		name:      "node-exporter",
 		version:   version,
 		labels: map[string]string{
 			"app.kubernetes.io/name": "node-exporter",
@@ -57,6 +66,33 @@ func NewNodeExporterBuilder(namespace, version string) *NodeExporterBuilder {
 	}
 }
 
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster. The node exporter uses the
+// host network, so only one of the stacks can run it.
+func (n *NodeExporterBuilder) WithStack(stack string) *NodeExporterBuilder {
+	n.name = stackObjectName(stack, "node-exporter")
+	if stack != "" {
+		n.labels[PartOfLabel] = stack
+		n.labelSelectors[PartOfLabel] = stack
+	}
+	return n
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the DaemonSet built afterwards.
+func (n *NodeExporterBuilder) WithImagePullSecrets(names ...string) *NodeExporterBuilder {
+	n.imagePullSecrets = append(n.imagePullSecrets, names...)
+	return n
+}
+
+// WithScheduling sets the priority class of the DaemonSet built afterwards.
+// The node exporter runs on every node, so the topology spread and the
+// anti-affinity don't apply.
+func (n *NodeExporterBuilder) WithScheduling(scheduling Scheduling) *NodeExporterBuilder {
+	n.scheduling = scheduling
+	return n
+}
+
 func (n *NodeExporterBuilder) WithServiceAccount() *NodeExporterBuilder {
 	n.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
 		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
@@ -64,10 +100,11 @@ func (n *NodeExporterBuilder) WithServiceAccount() *NodeExporterBuilder {
 			APIVersion: ptr.To("v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("node-exporter"),
+			Name:      ptr.To(n.name),
 			Labels:    n.labels,
 			Namespace: ptr.To(n.namespace),
 		},
+		ImagePullSecrets: podImagePullSecrets(n.imagePullSecrets),
 	}
 	return n
 }
@@ -79,7 +116,7 @@ func (n *NodeExporterBuilder) WithPodMonitor() *NodeExporterBuilder {
 			APIVersion: ptr.To("monitoring.coreos.com/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("node-exporter"),
+			Name:      ptr.To(n.name),
 			Labels:    n.labels,
 			Namespace: ptr.To(n.namespace),
 		},
@@ -103,17 +140,139 @@ func (n *NodeExporterBuilder) WithPodMonitor() *NodeExporterBuilder {
 	return n
 }
 
-var nodeExporterArgs = []string{
-	"--web.listen-address=0.0.0.0:9100",
-	"--path.sysfs=/host/sys",
-	"--path.rootfs=/host/root",
-	"--path.udev.data=/host/root/run/udev/data",
-	"--no-collector.wifi",
-	"--no-collector.hwmon",
-	"--no-collector.btrfs",
-	"--collector.filesystem.mount-points-exclude=^/(dev|proc|sys|run/k3s/containerd/.+|var/lib/docker/.+|var/lib/kubelet/pods/.+)($|/)",
-	"--collector.netclass.ignored-devices=^(veth.*|[a-f0-9]{15})$",
-	"--collector.netdev.device-exclude=^(veth.*|[a-f0-9]{15})$",
+// The paths at which the directories of the host are mounted into the
+// container of the node exporter.
+const (
+	nodeExporterTextfileDirectory = "/host/textfile"
+	dbusSocket                    = "/var/run/dbus/system_bus_socket"
+)
+
+// nodeExporterDisabledCollectors are the collectors enabled by default in the
+// node exporter which the stack disables unless they are enabled.
+var nodeExporterDisabledCollectors = []string{"wifi", "hwmon", "btrfs"}
+
+// collectorNameRegexp matches the names of the collectors of the node
+// exporter, such as systemd or netclass.
+var collectorNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// NodeExporterCollectors are the collectors of the node exporter enabled and
+// disabled on top of the defaults of the stack.
+type NodeExporterCollectors struct {
+	// Enable lists the collectors to enable, such as systemd or processes.
+	Enable []string
+	// Disable lists the collectors to disable.
+	Disable []string
+	// TextfileDirectory is the directory of the nodes from which the
+	// textfile collector reads the *.prom files, mounted into the Pods. The
+	// collector reads nothing when empty.
+	TextfileDirectory string
+}
+
+// Validate returns an error when a collector name is invalid or when a
+// collector is both enabled and disabled.
+func (c NodeExporterCollectors) Validate() error {
+	for _, name := range slices.Concat(c.Enable, c.Disable) {
+		if !collectorNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid node exporter collector %s", name)
+		}
+	}
+
+	for _, name := range c.Enable {
+		if slices.Contains(c.Disable, name) {
+			return fmt.Errorf("node exporter collector %s is both enabled and disabled", name)
+		}
+	}
+
+	if c.TextfileDirectory != "" && !strings.HasPrefix(c.TextfileDirectory, "/") {
+		return fmt.Errorf("invalid textfile directory %s, must be an absolute path", c.TextfileDirectory)
+	}
+	if c.TextfileDirectory != "" && slices.Contains(c.Disable, "textfile") {
+		return fmt.Errorf("textfile directory %s is set but the textfile collector is disabled", c.TextfileDirectory)
+	}
+	return nil
+}
+
+// WithCollectors enables and disables the collectors of the DaemonSet built
+// afterwards. The systemd collector gets the D-Bus socket of the nodes.
+func (n *NodeExporterBuilder) WithCollectors(collectors NodeExporterCollectors) *NodeExporterBuilder {
+	n.collectors = collectors
+	return n
+}
+
+// WithArgs appends arguments to the node exporter of the DaemonSet built
+// afterwards, after the ones set by the builder.
+func (n *NodeExporterBuilder) WithArgs(args ...string) *NodeExporterBuilder {
+	n.args = append(n.args, args...)
+	return n
+}
+
+// nodeExporterArgs returns the arguments of the node exporter.
+func (n *NodeExporterBuilder) nodeExporterArgs() []string {
+	args := []string{
+		"--web.listen-address=:9100",
+		"--path.sysfs=/host/sys",
+		"--path.rootfs=/host/root",
+		"--path.udev.data=/host/root/run/udev/data",
+	}
+
+	for _, name := range nodeExporterDisabledCollectors {
+		if !slices.Contains(n.collectors.Enable, name) {
+			args = append(args, "--no-collector."+name)
+		}
+	}
+	for _, name := range n.collectors.Enable {
+		if !slices.Contains(nodeExporterDisabledCollectors, name) {
+			args = append(args, "--collector."+name)
+		}
+	}
+	for _, name := range n.collectors.Disable {
+		args = append(args, "--no-collector."+name)
+	}
+
+	args = append(args,
+		"--collector.filesystem.mount-points-exclude=^/(dev|proc|sys|run/k3s/containerd/.+|var/lib/docker/.+|var/lib/kubelet/pods/.+)($|/)",
+		"--collector.netclass.ignored-devices=^(veth.*|[a-f0-9]{15})$",
+		"--collector.netdev.device-exclude=^(veth.*|[a-f0-9]{15})$",
+	)
+
+	if n.collectors.TextfileDirectory != "" {
+		args = append(args, "--collector.textfile.directory="+nodeExporterTextfileDirectory)
+	}
+
+	return append(args, n.args...)
+}
+
+// hostVolumes returns the volumes of the directories of the nodes read by
+// the enabled collectors, along with their mounts.
+func (n *NodeExporterBuilder) hostVolumes() ([]applyConfigCorev1.VolumeApplyConfiguration, []applyConfigCorev1.VolumeMountApplyConfiguration) {
+	var (
+		volumes []applyConfigCorev1.VolumeApplyConfiguration
+		mounts  []applyConfigCorev1.VolumeMountApplyConfiguration
+	)
+	hostPath := func(name, path, mountPath string, pathType corev1.HostPathType) {
+		volumes = append(volumes, applyConfigCorev1.VolumeApplyConfiguration{
+			Name: ptr.To(name),
+			VolumeSourceApplyConfiguration: applyConfigCorev1.VolumeSourceApplyConfiguration{
+				HostPath: &applyConfigCorev1.HostPathVolumeSourceApplyConfiguration{
+					Path: ptr.To(path),
+					Type: ptr.To(pathType),
+				},
+			},
+		})
+		mounts = append(mounts, applyConfigCorev1.VolumeMountApplyConfiguration{
+			MountPath: ptr.To(mountPath),
+			Name:      ptr.To(name),
+			ReadOnly:  ptr.To(true),
+		})
+	}
+
+	if n.collectors.TextfileDirectory != "" {
+		hostPath("textfile", n.collectors.TextfileDirectory, nodeExporterTextfileDirectory, corev1.HostPathDirectoryOrCreate)
+	}
+	if slices.Contains(n.collectors.Enable, "systemd") {
+		hostPath("dbus", dbusSocket, dbusSocket, corev1.HostPathSocket)
+	}
+	return volumes, mounts
 }
 
 func (n *NodeExporterBuilder) WithDaemonSet() *NodeExporterBuilder {
@@ -123,7 +282,7 @@ func (n *NodeExporterBuilder) WithDaemonSet() *NodeExporterBuilder {
 			APIVersion: ptr.To("apps/v1"),
 		},
 		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
-			Name:      ptr.To("node-exporter"),
+			Name:      ptr.To(n.name),
 			Labels:    n.labels,
 			Namespace: ptr.To(n.namespace),
 		},
@@ -137,12 +296,13 @@ func (n *NodeExporterBuilder) WithDaemonSet() *NodeExporterBuilder {
 				},
 				Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
 					ServiceAccountName:           n.manifests.ServiceAccount.Name,
+					ImagePullSecrets:             podImagePullSecrets(n.imagePullSecrets),
 					AutomountServiceAccountToken: ptr.To(true),
 					Containers: []applyConfigCorev1.ContainerApplyConfiguration{
 						{
 							Name:  ptr.To("node-exporter"),
 							Image: ptr.To(fmt.Sprintf("quay.io/prometheus/node-exporter:v%s", n.version)),
-							Args:  nodeExporterArgs,
+							Args:  n.nodeExporterArgs(),
 							Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
 								{
 									Name:          ptr.To("metrics"),
@@ -150,6 +310,8 @@ func (n *NodeExporterBuilder) WithDaemonSet() *NodeExporterBuilder {
 									Protocol:      ptr.To(corev1.ProtocolTCP),
 								},
 							},
+							LivenessProbe:  httpGetProbe("/", "metrics"),
+							ReadinessProbe: httpGetProbe("/", "metrics"),
 							Resources: &applyConfigCorev1.ResourceRequirementsApplyConfiguration{
 								Requests: &corev1.ResourceList{
 									"cpu":    resource.MustParse("200m"),
@@ -232,6 +394,14 @@ func (n *NodeExporterBuilder) WithDaemonSet() *NodeExporterBuilder {
 			},
 		},
 	}
+
+	n.manifests.DaemonSet.Spec.Template.Spec.PriorityClassName = n.scheduling.priorityClassName()
+
+	volumes, mounts := n.hostVolumes()
+	podSpec := n.manifests.DaemonSet.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, volumes...)
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, mounts...)
+
 	return n
 }
 
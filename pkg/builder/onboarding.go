@@ -0,0 +1,218 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+// MonitoringEditorRole is the name of the Role letting a team manage the
+// monitors and the rules of its namespace.
+const MonitoringEditorRole = "monitoring-editor"
+
+// OnboardingBuilder builds the objects onboarding the namespace of a team on
+// a Prometheus: the labels of the namespace matching the namespace selectors
+// of the Prometheus, a starter ServiceMonitor and PrometheusRule for the team
+// to extend, and the RBAC letting the team manage its monitors.
+type OnboardingBuilder struct {
+	labels    map[string]string
+	namespace string
+	manifests OnboardingManifests
+}
+
+type OnboardingManifests struct {
+	Namespace      *applyConfigCorev1.NamespaceApplyConfiguration
+	Role           *applyConfigRbacv1.RoleApplyConfiguration
+	RoleBinding    *applyConfigRbacv1.RoleBindingApplyConfiguration
+	ServiceMonitor *monitoringv1.ServiceMonitorApplyConfiguration
+	PrometheusRule *monitoringv1.PrometheusRuleApplyConfiguration
+}
+
+// NewOnboardingBuilder returns a builder for the onboarding of a namespace.
+func NewOnboardingBuilder(namespace string) *OnboardingBuilder {
+	return &OnboardingBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name":      "onboarding",
+			"app.kubernetes.io/component": "monitoring",
+		},
+		namespace: namespace,
+	}
+}
+
+// WithNamespaceLabels builds the namespace with the labels selecting it.
+func (o *OnboardingBuilder) WithNamespaceLabels(labels map[string]string) *OnboardingBuilder {
+	o.manifests.Namespace = &applyConfigCorev1.NamespaceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Namespace"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:   ptr.To(o.namespace),
+			Labels: labels,
+		},
+	}
+	return o
+}
+
+// WithServiceMonitor builds the starter ServiceMonitor, scraping the metrics
+// port of the Services which are part of the namespace. The labels make the
+// Prometheus select it.
+func (o *OnboardingBuilder) WithServiceMonitor(labels map[string]string) *OnboardingBuilder {
+	o.manifests.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.namespace),
+			Labels:    o.objectLabels(labels),
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: map[string]string{
+					PartOfLabel: o.namespace,
+				},
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					Port: ptr.To("metrics"),
+				},
+			},
+		},
+	}
+	return o
+}
+
+// WithPrometheusRule builds the starter PrometheusRule, alerting on the
+// targets of the namespace which are down. The labels make the Prometheus
+// select it.
+func (o *OnboardingBuilder) WithPrometheusRule(labels map[string]string) *OnboardingBuilder {
+	o.manifests.PrometheusRule = &monitoringv1.PrometheusRuleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("PrometheusRule"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.namespace),
+			Labels:    o.objectLabels(labels),
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &monitoringv1.PrometheusRuleSpecApplyConfiguration{
+			Groups: []monitoringv1.RuleGroupApplyConfiguration{
+				{
+					Name: ptr.To(o.namespace),
+					Rules: []monitoringv1.RuleApplyConfiguration{
+						alertingRule(
+							"TargetDown",
+							fmt.Sprintf(`up{namespace=%q} == 0`, o.namespace),
+							"5m",
+							"warning",
+							"Target {{ $labels.instance }} of job {{ $labels.job }} is down.",
+						),
+					},
+				},
+			},
+		},
+	}
+	return o
+}
+
+// WithRBAC builds the Role letting the subjects manage the ServiceMonitors,
+// PodMonitors, Probes and PrometheusRules of the namespace, and the
+// RoleBinding granting it to the groups and the users. The RoleBinding is
+// skipped without subjects.
+func (o *OnboardingBuilder) WithRBAC(groups, users []string) *OnboardingBuilder {
+	o.manifests.Role = &applyConfigRbacv1.RoleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Role"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(MonitoringEditorRole),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Rules: []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			{
+				APIGroups: []string{"monitoring.coreos.com"},
+				Resources: []string{"servicemonitors", "podmonitors", "probes", "prometheusrules"},
+				Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+			},
+		},
+	}
+
+	var subjects []applyConfigRbacv1.SubjectApplyConfiguration
+	for _, group := range groups {
+		subjects = append(subjects, applyConfigRbacv1.SubjectApplyConfiguration{
+			APIGroup: ptr.To(rbacv1.GroupName),
+			Kind:     ptr.To(rbacv1.GroupKind),
+			Name:     ptr.To(group),
+		})
+	}
+	for _, user := range users {
+		subjects = append(subjects, applyConfigRbacv1.SubjectApplyConfiguration{
+			APIGroup: ptr.To(rbacv1.GroupName),
+			Kind:     ptr.To(rbacv1.UserKind),
+			Name:     ptr.To(user),
+		})
+	}
+	if len(subjects) == 0 {
+		return o
+	}
+
+	o.manifests.RoleBinding = &applyConfigRbacv1.RoleBindingApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("RoleBinding"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(MonitoringEditorRole),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+			APIGroup: ptr.To(rbacv1.GroupName),
+			Kind:     ptr.To("Role"),
+			Name:     ptr.To(MonitoringEditorRole),
+		},
+		Subjects: subjects,
+	}
+	return o
+}
+
+// objectLabels returns the labels of the builder merged with the labels
+// selecting the object.
+func (o *OnboardingBuilder) objectLabels(labels map[string]string) map[string]string {
+	merged := make(map[string]string, len(o.labels)+len(labels))
+	for k, v := range o.labels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (o *OnboardingBuilder) Build() OnboardingManifests {
+	return o.manifests
+}
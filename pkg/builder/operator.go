@@ -0,0 +1,545 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyCofongiAppsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+// PrometheusAgentDaemonSetFeatureGate allows running PrometheusAgents as
+// DaemonSets.
+const PrometheusAgentDaemonSetFeatureGate = "PrometheusAgentDaemonSet"
+
+type OperatorBuilder struct {
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	stack            string
+	name             string
+	version          string
+	featureGates     []string
+	namespaces       []string
+	rules            []applyConfigRbacv1.PolicyRuleApplyConfiguration
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifets         OperatorManifests
+}
+
+type OperatorManifests struct {
+	Deployment         *applyCofongiAppsv1.DeploymentApplyConfiguration
+	Service            *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceAccount     *applyConfigCorev1.ServiceAccountApplyConfiguration
+	ClusterRole        *applyConfigRbacv1.ClusterRoleApplyConfiguration
+	ClusterRoleBinding *applyConfigRbacv1.ClusterRoleBindingApplyConfiguration
+	Roles              []*applyConfigRbacv1.RoleApplyConfiguration
+	RoleBindings       []*applyConfigRbacv1.RoleBindingApplyConfiguration
+	ServiceMonitor     *monitoringv1.ServiceMonitorApplyConfiguration
+	PrometheusRule     *monitoringv1.PrometheusRuleApplyConfiguration
+}
+
+func NewOperator(namespace, version string) *OperatorBuilder {
+	return &OperatorBuilder{
+		namespace: namespace,
+		name:      "prometheus-operator",
+		version:   version,
+		labels: map[string]string{
+			"app.kubernetes.io/component": "controller",
+			"app.kubernetes.io/name":      "prometheus-operator",
+			"app.kubernetes.io/version":   version,
+		},
+		labelSelectors: map[string]string{
+			"app.kubernetes.io/component": "controller",
+			"app.kubernetes.io/name":      "prometheus-operator",
+		},
+	}
+
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster. The operator of the stack only
+// reconciles the resources annotated with the stack name as controller ID.
+func (o *OperatorBuilder) WithStack(stack string) *OperatorBuilder {
+	o.stack = stack
+	o.name = stackObjectName(stack, "prometheus-operator")
+	if stack != "" {
+		o.labels[PartOfLabel] = stack
+		o.labelSelectors[PartOfLabel] = stack
+	}
+	return o
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Deployment of the operator built afterwards, for private registries.
+func (o *OperatorBuilder) WithImagePullSecrets(names ...string) *OperatorBuilder {
+	o.imagePullSecrets = append(o.imagePullSecrets, names...)
+	return o
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the operator Pod built afterwards.
+func (o *OperatorBuilder) WithScheduling(scheduling Scheduling) *OperatorBuilder {
+	o.scheduling = scheduling
+	return o
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (o *OperatorBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *OperatorBuilder {
+	o.ipFamilyPolicy = policy
+	return o
+}
+
+func (o *OperatorBuilder) WithServiceAccount() *OperatorBuilder {
+	o.manifets.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		ImagePullSecrets: podImagePullSecrets(o.imagePullSecrets),
+	}
+	return o
+}
+
+// WithFeatureGates enables feature gates of the operator built afterwards.
+func (o *OperatorBuilder) WithFeatureGates(gates ...string) *OperatorBuilder {
+	o.featureGates = append(o.featureGates, gates...)
+	return o
+}
+
+func (o *OperatorBuilder) WithClusterRole() *OperatorBuilder {
+	o.manifets.ClusterRole = &applyConfigRbacv1.ClusterRoleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRole"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:   ptr.To(o.name),
+			Labels: o.labels,
+		},
+		Rules: o.clusterRules(),
+	}
+	return o
+}
+
+// WithRules replaces the built-in rules of the ClusterRole and of the Roles
+// built afterwards, typically with the rules released along with the
+// operator version.
+func (o *OperatorBuilder) WithRules(rules []rbacv1.PolicyRule) *OperatorBuilder {
+	o.rules = make([]applyConfigRbacv1.PolicyRuleApplyConfiguration, 0, len(rules))
+	for _, r := range rules {
+		o.rules = append(o.rules, applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			Verbs:           r.Verbs,
+			APIGroups:       r.APIGroups,
+			Resources:       r.Resources,
+			ResourceNames:   r.ResourceNames,
+			NonResourceURLs: r.NonResourceURLs,
+		})
+	}
+	return o
+}
+
+// WithNamespaces restricts the operator built afterwards to the given
+// namespaces, instead of watching the whole cluster.
+func (o *OperatorBuilder) WithNamespaces(namespaces ...string) *OperatorBuilder {
+	o.namespaces = append(o.namespaces, namespaces...)
+	return o
+}
+
+// WithRoles grants the operator access to each watched namespace through a
+// Role and a RoleBinding, replacing the ClusterRole and ClusterRoleBinding of
+// cluster-wide installs.
+func (o *OperatorBuilder) WithRoles() *OperatorBuilder {
+	for _, ns := range o.namespaces {
+		o.manifets.Roles = append(o.manifets.Roles, &applyConfigRbacv1.RoleApplyConfiguration{
+			TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+				Kind:       ptr.To("Role"),
+				APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+			},
+			ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+				Name:      ptr.To(o.name),
+				Labels:    o.labels,
+				Namespace: ptr.To(ns),
+			},
+			Rules: o.roleRules(),
+		})
+
+		o.manifets.RoleBindings = append(o.manifets.RoleBindings, &applyConfigRbacv1.RoleBindingApplyConfiguration{
+			TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+				Kind:       ptr.To("RoleBinding"),
+				APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+			},
+			ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+				Name:      ptr.To(o.name),
+				Labels:    o.labels,
+				Namespace: ptr.To(ns),
+			},
+			RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+				APIGroup: ptr.To("rbac.authorization.k8s.io"),
+				Kind:     ptr.To("Role"),
+				Name:     ptr.To(o.name),
+			},
+			Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
+				{
+					Kind:      ptr.To("ServiceAccount"),
+					Name:      o.manifets.ServiceAccount.Name,
+					Namespace: ptr.To(o.namespace),
+				},
+			},
+		})
+	}
+	return o
+}
+
+func (o *OperatorBuilder) WithClusterRoleBinding() *OperatorBuilder {
+	o.manifets.ClusterRoleBinding = &applyConfigRbacv1.ClusterRoleBindingApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRoleBinding"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+			APIGroup: ptr.To("rbac.authorization.k8s.io"),
+			Kind:     ptr.To("ClusterRole"),
+			Name:     ptr.To(o.name),
+		},
+		Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
+			{
+				Kind:      ptr.To("ServiceAccount"),
+				Name:      o.manifets.ServiceAccount.Name,
+				Namespace: ptr.To(o.namespace),
+			},
+		},
+	}
+	return o
+}
+
+func (o *OperatorBuilder) WithDeployment() *OperatorBuilder {
+	o.manifets.Deployment = &applyCofongiAppsv1.DeploymentApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Deployment"),
+			APIVersion: ptr.To("apps/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &applyCofongiAppsv1.DeploymentSpecApplyConfiguration{
+			Replicas: ptr.To(int32(1)),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: o.labelSelectors,
+			},
+			Template: &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+				ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+					Labels: o.labels,
+					Annotations: map[string]string{
+						"kubectl.kubernetes.io/default-container": "prometheus-operator",
+					},
+				},
+				Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+					AutomountServiceAccountToken: ptr.To(true),
+					Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+						{
+							Name:  ptr.To("prometheus-operator"),
+							Image: ptr.To(fmt.Sprintf("quay.io/prometheus-operator/prometheus-operator:v%s", o.version)),
+							Args: []string{
+								"--kubelet-service=kube-system/kubelet",
+								fmt.Sprintf("--prometheus-config-reloader=quay.io/prometheus-operator/prometheus-config-reloader:v%s", o.version),
+							},
+							Env: []applyConfigCorev1.EnvVarApplyConfiguration{
+								{
+									Name:  ptr.To("GOGC"),
+									Value: ptr.To("30"),
+								},
+							},
+							Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
+								{
+									Name:          ptr.To("http"),
+									ContainerPort: ptr.To(int32(8080)),
+								},
+							},
+							LivenessProbe:  httpGetProbe("/healthz", "http"),
+							ReadinessProbe: httpGetProbe("/healthz", "http"),
+							Resources: &applyConfigCorev1.ResourceRequirementsApplyConfiguration{
+								Requests: &corev1.ResourceList{
+									"cpu":    resource.MustParse("200m"),
+									"memory": resource.MustParse("200Mi"),
+								},
+								Limits: &corev1.ResourceList{
+									"cpu":    resource.MustParse("200m"),
+									"memory": resource.MustParse("200Mi"),
+								},
+							},
+							SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+								ReadOnlyRootFilesystem:   ptr.To(true),
+								AllowPrivilegeEscalation: ptr.To(false),
+								Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+									Drop: []corev1.Capability{"ALL"},
+								},
+							},
+						},
+					},
+					NodeSelector: map[string]string{
+						"kubernetes.io/os": "linux",
+					},
+					SecurityContext: &applyConfigCorev1.PodSecurityContextApplyConfiguration{
+						RunAsNonRoot: ptr.To(true),
+						RunAsUser:    ptr.To(int64(65534)),
+						SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+							Type: applyConfigCorev1.SeccompProfile().WithType("RuntimeDefault").Type,
+						},
+					},
+					ServiceAccountName: o.manifets.ServiceAccount.Name,
+					ImagePullSecrets:   podImagePullSecrets(o.imagePullSecrets),
+				},
+			},
+		},
+	}
+
+	o.scheduling.schedulePod(o.manifets.Deployment.Spec.Template.Spec, o.labelSelectors)
+
+	container := &o.manifets.Deployment.Spec.Template.Spec.Containers[0]
+
+	if len(o.namespaces) > 0 {
+		// The kubelet Service lives in kube-system, which a namespaced
+		// operator has no access to.
+		container.Args = []string{
+			fmt.Sprintf("--prometheus-config-reloader=quay.io/prometheus-operator/prometheus-config-reloader:v%s", o.version),
+			fmt.Sprintf("--namespaces=%s", strings.Join(o.namespaces, ",")),
+		}
+	}
+
+	if o.stack != "" {
+		container.Args = append(container.Args, fmt.Sprintf("--controller-id=%s", o.stack))
+	}
+
+	if len(o.featureGates) > 0 {
+		gates := make([]string, 0, len(o.featureGates))
+		for _, gate := range o.featureGates {
+			gates = append(gates, fmt.Sprintf("%s=true", gate))
+		}
+		container.Args = append(container.Args, fmt.Sprintf("--feature-gates=%s", strings.Join(gates, ",")))
+	}
+
+	return o
+}
+
+func (o *OperatorBuilder) WithService() *OperatorBuilder {
+	o.manifets.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			Ports: []applyConfigCorev1.ServicePortApplyConfiguration{
+				{
+					Name:        ptr.To("http"),
+					Port:        ptr.To(int32(8080)),
+					TargetPort:  ptr.To(intstr.FromString("http")),
+					AppProtocol: ptr.To("http"),
+				},
+			},
+			Selector:       o.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(o.ipFamilyPolicy),
+		},
+	}
+	return o
+}
+
+func (o *OperatorBuilder) WithServiceMonitor() *OperatorBuilder {
+	o.manifets.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: o.labelSelectors,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					HonorLabels: ptr.To(true),
+					Port:        ptr.To("http"),
+				},
+			},
+		},
+	}
+	return o
+}
+
+// clusterRules returns the rules of the ClusterRole of a cluster-wide install.
+func (o *OperatorBuilder) clusterRules() []applyConfigRbacv1.PolicyRuleApplyConfiguration {
+	if len(o.rules) > 0 {
+		return o.withWorkloadRule(o.rules)
+	}
+
+	return append(o.namespacedRules(),
+		applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			APIGroups: []string{""},
+			Resources: []string{"nodes"},
+			Verbs:     []string{"list", "watch"},
+		},
+		applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			APIGroups: []string{""},
+			Resources: []string{"namespaces"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			APIGroups: []string{"storage.k8s.io"},
+			Resources: []string{"storageclasses"},
+			Verbs:     []string{"get"},
+		},
+	)
+}
+
+// roleRules returns the rules of the Roles of a namespaced install. The
+// cluster-scoped resources of released rules are harmless in a Role, they
+// don't grant anything.
+func (o *OperatorBuilder) roleRules() []applyConfigRbacv1.PolicyRuleApplyConfiguration {
+	if len(o.rules) > 0 {
+		return o.withWorkloadRule(o.rules)
+	}
+	return o.namespacedRules()
+}
+
+// withWorkloadRule adds the workload resources needed by the enabled feature
+// gates to rules which don't grant them, such as the rules released with an
+// operator version predating the feature.
+func (o *OperatorBuilder) withWorkloadRule(rules []applyConfigRbacv1.PolicyRuleApplyConfiguration) []applyConfigRbacv1.PolicyRuleApplyConfiguration {
+	rules = slices.Clone(rules)
+
+	for _, resource := range o.workloadResources() {
+		granted := slices.ContainsFunc(rules, func(r applyConfigRbacv1.PolicyRuleApplyConfiguration) bool {
+			return slices.Contains(r.APIGroups, "apps") && (slices.Contains(r.Resources, resource) || slices.Contains(r.Resources, "*"))
+		})
+		if !granted {
+			rules = append(rules, applyConfigRbacv1.PolicyRuleApplyConfiguration{
+				APIGroups: []string{"apps"},
+				Resources: []string{resource},
+				Verbs:     []string{"*"},
+			})
+		}
+	}
+
+	return rules
+}
+
+// namespacedRules returns the rules the operator needs in each namespace it
+// manages.
+func (o *OperatorBuilder) namespacedRules() []applyConfigRbacv1.PolicyRuleApplyConfiguration {
+	return []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+		{
+			APIGroups: []string{"monitoring.coreos.com"},
+			Resources: []string{
+				"alertmanagers",
+				"alertmanagers/finalizers",
+				"alertmanagers/status",
+				"alertmanagerconfigs",
+				"prometheuses",
+				"prometheuses/finalizers",
+				"prometheuses/status",
+				"prometheusagents",
+				"prometheusagents/finalizers",
+				"prometheusagents/status",
+				"thanosrulers",
+				"thanosrulers/finalizers",
+				"thanosrulers/status",
+				"scrapeconfigs",
+				"servicemonitors",
+				"podmonitors",
+				"probes",
+				"prometheusrules",
+			},
+			Verbs: []string{"*"},
+		},
+		{
+			APIGroups: []string{"apps"},
+			Resources: o.workloadResources(),
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps", "secrets"},
+			Verbs:     []string{"*"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"pods"},
+			Verbs:     []string{"list", "delete"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"services", "services/finalizers", "endpoints"},
+			Verbs:     []string{"get", "create", "update", "delete"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+			Verbs:     []string{"patch", "create"},
+		},
+		{
+			APIGroups: []string{"networking.k8s.io"},
+			Resources: []string{"ingresses"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+	}
+}
+
+// workloadResources returns the workload resources managed by the operator,
+// DaemonSets are only needed when PrometheusAgents can run in DaemonSet mode.
+func (o *OperatorBuilder) workloadResources() []string {
+	if slices.Contains(o.featureGates, PrometheusAgentDaemonSetFeatureGate) {
+		return []string{"statefulsets", "daemonsets"}
+	}
+	return []string{"statefulsets"}
+}
+
+func (o *OperatorBuilder) Build() OperatorManifests {
+	return o.manifets
+}
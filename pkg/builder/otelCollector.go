@@ -0,0 +1,427 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyCofongiAppsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	LatestOpenTelemetryCollectorVersion = "0.120.0"
+
+	// OpenTelemetryCollectorImage is the image of the contrib distribution
+	// of the collector, which ships the Prometheus receivers.
+	OpenTelemetryCollectorImage = "otel/opentelemetry-collector-contrib"
+
+	otelCollectorConfigKey       = "config.yaml"
+	otelCollectorConfigMountPath = "/etc/otelcol"
+	otelCollectorRemoteWritePort = 9090
+	otelCollectorMetricsPort     = 8888
+	otelCollectorHealthPort      = 13133
+)
+
+type OpenTelemetryCollectorBuilder struct {
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	name             string
+	prometheusName   string
+	version          string
+	otlpEndpoint     string
+	federation       bool
+	clusterDomain    string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifests        OpenTelemetryCollectorManifests
+}
+
+type OpenTelemetryCollectorManifests struct {
+	ServiceAccount *applyConfigCorev1.ServiceAccountApplyConfiguration
+	Config         *applyConfigCorev1.ConfigMapApplyConfiguration
+	Deployment     *applyCofongiAppsv1.DeploymentApplyConfiguration
+	Service        *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceMonitor *monitoringv1.ServiceMonitorApplyConfiguration
+}
+
+// NewOpenTelemetryCollectorBuilder returns a builder for an OpenTelemetry
+// Collector exporting the samples of the stack to the OTLP endpoint. The
+// endpoint is an http or https URL for OTLP over HTTP, a host:port address
+// for OTLP over gRPC.
+func NewOpenTelemetryCollectorBuilder(namespace, version, otlpEndpoint string) *OpenTelemetryCollectorBuilder {
+	return &OpenTelemetryCollectorBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name": "otel-collector",
+		},
+		labelSelectors: map[string]string{
+			"app.kubernetes.io/name": "otel-collector",
+		},
+		namespace:      namespace,
+		name:           "otel-collector",
+		prometheusName: "prometheus",
+		version:        version,
+		otlpEndpoint:   otlpEndpoint,
+	}
+}
+
+// OpenTelemetryCollectorRemoteWriteURL returns the URL at which the
+// collector of the stack receives the samples sent through remote write, in
+// the cluster domain.
+func OpenTelemetryCollectorRemoteWriteURL(namespace, stack, clusterDomain string) string {
+	return fmt.Sprintf("http://%s:%d/api/v1/write", ServiceHost(stackObjectName(stack, "otel-collector"), namespace, clusterDomain), otelCollectorRemoteWritePort)
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster. The collector federates the
+// Prometheus of the same stack.
+func (o *OpenTelemetryCollectorBuilder) WithStack(stack string) *OpenTelemetryCollectorBuilder {
+	o.name = stackObjectName(stack, "otel-collector")
+	o.prometheusName = stackObjectName(stack, "prometheus")
+	if stack != "" {
+		o.labels[PartOfLabel] = stack
+		o.labelSelectors[PartOfLabel] = stack
+	}
+	return o
+}
+
+// WithFederation makes the configuration built afterwards scrape the
+// /federate endpoint of the Prometheus of the stack, instead of receiving
+// the samples that Prometheus sends through remote write.
+func (o *OpenTelemetryCollectorBuilder) WithFederation() *OpenTelemetryCollectorBuilder {
+	o.federation = true
+	return o
+}
+
+// WithClusterDomain qualifies the address of the Prometheus federated by the
+// configuration built afterwards with the DNS domain of the cluster.
+func (o *OpenTelemetryCollectorBuilder) WithClusterDomain(clusterDomain string) *OpenTelemetryCollectorBuilder {
+	o.clusterDomain = clusterDomain
+	return o
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Deployment built afterwards.
+func (o *OpenTelemetryCollectorBuilder) WithImagePullSecrets(names ...string) *OpenTelemetryCollectorBuilder {
+	o.imagePullSecrets = append(o.imagePullSecrets, names...)
+	return o
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the Pods of the Deployment built afterwards.
+func (o *OpenTelemetryCollectorBuilder) WithScheduling(scheduling Scheduling) *OpenTelemetryCollectorBuilder {
+	o.scheduling = scheduling
+	return o
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (o *OpenTelemetryCollectorBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *OpenTelemetryCollectorBuilder {
+	o.ipFamilyPolicy = policy
+	return o
+}
+
+func (o *OpenTelemetryCollectorBuilder) WithServiceAccount() *OpenTelemetryCollectorBuilder {
+	o.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		ImagePullSecrets: podImagePullSecrets(o.imagePullSecrets),
+	}
+	return o
+}
+
+// WithConfig builds the ConfigMap holding the configuration of the
+// collector: a metrics pipeline from the Prometheus remote write receiver,
+// or from the Prometheus receiver federating Prometheus, to the OTLP
+// exporter.
+func (o *OpenTelemetryCollectorBuilder) WithConfig() *OpenTelemetryCollectorBuilder {
+	receiver := "prometheusremotewrite"
+	receiverConfig := map[string]any{
+		"endpoint": listenAddress(otelCollectorRemoteWritePort),
+	}
+	if o.federation {
+		receiver = "prometheus"
+		receiverConfig = map[string]any{
+			"config": map[string]any{
+				"scrape_configs": []any{
+					map[string]any{
+						"job_name":     "federate",
+						"honor_labels": true,
+						"metrics_path": "/federate",
+						"params": map[string]any{
+							"match[]": []string{`{__name__=~".+"}`},
+						},
+						"static_configs": []any{
+							map[string]any{
+								"targets": []string{fmt.Sprintf("%s:9090", ServiceHost(o.prometheusName, o.namespace, o.clusterDomain))},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// OTLP over HTTP is configured with a URL, OTLP over gRPC with an
+	// address.
+	exporter := "otlp"
+	if strings.HasPrefix(o.otlpEndpoint, "http://") || strings.HasPrefix(o.otlpEndpoint, "https://") {
+		exporter = "otlphttp"
+	}
+
+	config := map[string]any{
+		"extensions": map[string]any{
+			"health_check": map[string]any{
+				"endpoint": listenAddress(otelCollectorHealthPort),
+			},
+		},
+		"receivers": map[string]any{
+			receiver: receiverConfig,
+		},
+		"processors": map[string]any{
+			"memory_limiter": map[string]any{
+				"check_interval":         "1s",
+				"limit_percentage":       80,
+				"spike_limit_percentage": 25,
+			},
+			"batch": map[string]any{},
+		},
+		"exporters": map[string]any{
+			exporter: map[string]any{
+				"endpoint": o.otlpEndpoint,
+			},
+		},
+		"service": map[string]any{
+			"extensions": []string{"health_check"},
+			"telemetry": map[string]any{
+				"metrics": map[string]any{
+					"address": listenAddress(otelCollectorMetricsPort),
+				},
+			},
+			"pipelines": map[string]any{
+				"metrics": map[string]any{
+					"receivers":  []string{receiver},
+					"processors": []string{"memory_limiter", "batch"},
+					"exporters":  []string{exporter},
+				},
+			},
+		},
+	}
+
+	// The configuration only holds strings, numbers, lists and maps, which
+	// are always marshaled.
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		panic(fmt.Sprintf("builder: error while encoding the collector configuration: %v", err))
+	}
+
+	o.manifests.Config = &applyConfigCorev1.ConfigMapApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ConfigMap"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name + "-config"),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Data: map[string]string{
+			otelCollectorConfigKey: string(data),
+		},
+	}
+	return o
+}
+
+// WithDeployment builds the Deployment of the collector. It must be called
+// after WithServiceAccount and WithConfig.
+func (o *OpenTelemetryCollectorBuilder) WithDeployment() *OpenTelemetryCollectorBuilder {
+	ports := []applyConfigCorev1.ContainerPortApplyConfiguration{
+		{
+			Name:          ptr.To("metrics"),
+			ContainerPort: ptr.To(int32(otelCollectorMetricsPort)),
+		},
+		{
+			Name:          ptr.To("health"),
+			ContainerPort: ptr.To(int32(otelCollectorHealthPort)),
+		},
+	}
+	if !o.federation {
+		ports = append(ports, applyConfigCorev1.ContainerPortApplyConfiguration{
+			Name:          ptr.To("remote-write"),
+			ContainerPort: ptr.To(int32(otelCollectorRemoteWritePort)),
+		})
+	}
+
+	template := &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Labels: o.labelSelectors,
+		},
+		Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+			ServiceAccountName: o.manifests.ServiceAccount.Name,
+			ImagePullSecrets:   podImagePullSecrets(o.imagePullSecrets),
+			Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+				{
+					Name:           ptr.To("otel-collector"),
+					Image:          ptr.To(fmt.Sprintf("%s:%s", OpenTelemetryCollectorImage, o.version)),
+					Args:           []string{"--config=" + path.Join(otelCollectorConfigMountPath, otelCollectorConfigKey)},
+					Ports:          ports,
+					LivenessProbe:  httpGetProbe("/", "health"),
+					ReadinessProbe: httpGetProbe("/", "health"),
+					VolumeMounts: []applyConfigCorev1.VolumeMountApplyConfiguration{
+						{
+							Name:      ptr.To("config"),
+							MountPath: ptr.To(otelCollectorConfigMountPath),
+							ReadOnly:  ptr.To(true),
+						},
+					},
+					SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+						AllowPrivilegeEscalation: ptr.To(false),
+						Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+							Drop: []corev1.Capability{
+								"ALL",
+							},
+						},
+						ReadOnlyRootFilesystem: ptr.To(true),
+						RunAsUser:              ptr.To(int64(65534)),
+						RunAsNonRoot:           ptr.To(true),
+						RunAsGroup:             ptr.To(int64(65534)),
+						SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+							Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
+						},
+					},
+				},
+			},
+			Volumes: []applyConfigCorev1.VolumeApplyConfiguration{
+				{
+					Name: ptr.To("config"),
+					VolumeSourceApplyConfiguration: applyConfigCorev1.VolumeSourceApplyConfiguration{
+						ConfigMap: &applyConfigCorev1.ConfigMapVolumeSourceApplyConfiguration{
+							LocalObjectReferenceApplyConfiguration: applyConfigCorev1.LocalObjectReferenceApplyConfiguration{
+								Name: o.manifests.Config.Name,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	o.scheduling.schedulePod(template.Spec, o.labelSelectors)
+
+	o.manifests.Deployment = &applyCofongiAppsv1.DeploymentApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Deployment"),
+			APIVersion: ptr.To("apps/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &applyCofongiAppsv1.DeploymentSpecApplyConfiguration{
+			Replicas: ptr.To(int32(1)),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: o.labelSelectors,
+			},
+			Template: template,
+		},
+	}
+	return o
+}
+
+// WithService builds the Service exposing the metrics of the collector and,
+// unless it federates Prometheus, its remote write receiver.
+func (o *OpenTelemetryCollectorBuilder) WithService() *OpenTelemetryCollectorBuilder {
+	ports := []applyConfigCorev1.ServicePortApplyConfiguration{
+		{
+			Name:       ptr.To("metrics"),
+			Port:       ptr.To(int32(otelCollectorMetricsPort)),
+			TargetPort: ptr.To(intstr.FromString("metrics")),
+		},
+	}
+	if !o.federation {
+		ports = append(ports, applyConfigCorev1.ServicePortApplyConfiguration{
+			Name:       ptr.To("remote-write"),
+			Port:       ptr.To(int32(otelCollectorRemoteWritePort)),
+			TargetPort: ptr.To(intstr.FromString("remote-write")),
+		})
+	}
+
+	o.manifests.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			Ports:          ports,
+			Selector:       o.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(o.ipFamilyPolicy),
+		},
+	}
+	return o
+}
+
+// WithServiceMonitor scrapes the internal metrics of the collector, such as
+// the samples it failed to export.
+func (o *OpenTelemetryCollectorBuilder) WithServiceMonitor() *OpenTelemetryCollectorBuilder {
+	o.manifests.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: o.labelSelectors,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					Port: ptr.To("metrics"),
+				},
+			},
+		},
+	}
+	return o
+}
+
+func (o *OpenTelemetryCollectorBuilder) Build() OpenTelemetryCollectorManifests {
+	return o.manifests
+}
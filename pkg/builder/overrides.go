@@ -0,0 +1,161 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Override sets a field of the object of a component to a value, as an
+// escape hatch for the settings without a dedicated option.
+type Override struct {
+	Component string
+	// Path is the list of keys leading to the field in the object.
+	Path []string
+	// Value is the JSON value of the field. Objects are merged into the
+	// existing ones, other values replace them.
+	Value any
+}
+
+// ParseOverride parses an override in the <component>.<path>=<value> format,
+// such as prometheus.spec.retention=30d. The keys of the path are separated
+// by dots, a dot within a key being escaped with a backslash. The value is
+// decoded as YAML, so that numbers and booleans keep their types and objects
+// or lists can be given in the flow style.
+func ParseOverride(s string) (Override, error) {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return Override{}, fmt.Errorf("invalid override %q, expected <component>.<path>=<value>", s)
+	}
+
+	keys := splitPath(path)
+	if len(keys) < 2 || slices.Contains(keys, "") {
+		return Override{}, fmt.Errorf("invalid override %q, expected <component>.<path>=<value>", s)
+	}
+
+	var v any
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return Override{}, fmt.Errorf("invalid value of override %q: %w", s, err)
+	}
+
+	return Override{Component: keys[0], Path: keys[1:], Value: v}, nil
+}
+
+// ApplyOverrides applies the overrides of the component to the object, an
+// apply configuration, through its JSON representation.
+func ApplyOverrides(obj any, component string, overrides []Override) error {
+	var matching []Override
+	for _, o := range overrides {
+		if o.Component == component {
+			matching = append(matching, o)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("error while encoding %s: %w", component, err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("error while decoding %s: %w", component, err)
+	}
+
+	for _, o := range matching {
+		if err := setField(fields, o.Path, o.Value); err != nil {
+			return fmt.Errorf("error while overriding %s.%s: %v", component, strings.Join(o.Path, "."), err)
+		}
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("error while encoding %s: %w", component, err)
+	}
+
+	// Reset the object so that the fields removed by the overrides are too.
+	v := reflect.ValueOf(obj).Elem()
+	v.Set(reflect.Zero(v.Type()))
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return fmt.Errorf("invalid overrides of %s: %w", component, err)
+	}
+
+	return nil
+}
+
+// setField sets the field at path in the object, creating the intermediate
+// objects. An object value is merged into the existing object.
+func setField(obj map[string]any, path []string, value any) error {
+	for i, key := range path[:len(path)-1] {
+		next, found := obj[key]
+		if !found || next == nil {
+			next = map[string]any{}
+			obj[key] = next
+		}
+
+		m, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
+		}
+		obj = m
+	}
+
+	key := path[len(path)-1]
+	existing, isObject := obj[key].(map[string]any)
+	update, isObjectValue := value.(map[string]any)
+	if isObject && isObjectValue {
+		for k, v := range update {
+			if err := setField(existing, []string{k}, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	obj[key] = value
+	return nil
+}
+
+// splitPath splits a path on the dots which aren't escaped by a backslash.
+func splitPath(path string) []string {
+	var (
+		keys    []string
+		current strings.Builder
+	)
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(keys, current.String())
+}
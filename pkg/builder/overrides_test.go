@@ -0,0 +1,95 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseOverride(t *testing.T) {
+	for _, tc := range []struct {
+		override string
+		expected Override
+		err      bool
+	}{
+		{
+			override: "prometheus.spec.retention=30d",
+			expected: Override{Component: "prometheus", Path: []string{"spec", "retention"}, Value: "30d"},
+		},
+		{
+			override: "alertmanager.spec.replicas=3",
+			expected: Override{Component: "alertmanager", Path: []string{"spec", "replicas"}, Value: float64(3)},
+		},
+		{
+			override: `prometheus.spec.nodeSelector.kubernetes\.io/os=linux`,
+			expected: Override{Component: "prometheus", Path: []string{"spec", "nodeSelector", "kubernetes.io/os"}, Value: "linux"},
+		},
+		{
+			override: "prometheus.spec.externalLabels={cluster: eu-1}",
+			expected: Override{Component: "prometheus", Path: []string{"spec", "externalLabels"}, Value: map[string]any{"cluster": "eu-1"}},
+		},
+		{override: "prometheus.spec.retention", err: true},
+		{override: "prometheus=30d", err: true},
+		{override: "prometheus..retention=30d", err: true},
+	} {
+		t.Run(tc.override, func(t *testing.T) {
+			o, err := ParseOverride(tc.override)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, o)
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	parse := func(values ...string) []Override {
+		var overrides []Override
+		for _, v := range values {
+			o, err := ParseOverride(v)
+			require.NoError(t, err)
+			overrides = append(overrides, o)
+		}
+		return overrides
+	}
+
+	prometheus := NewPrometheus("monitoring").WithServiceAccount().WithPrometheus().Build().Prometheus
+	err := ApplyOverrides(prometheus, "prometheus", parse(
+		"prometheus.spec.retention=30d",
+		"prometheus.spec.replicas=3",
+		"prometheus.spec.serviceMonitorSelector={matchLabels: {team: a}}",
+		"alertmanager.spec.replicas=5",
+	))
+	require.NoError(t, err)
+
+	assert.Equal(t, "30d", string(*prometheus.Spec.Retention))
+	assert.Equal(t, ptr.To[int32](3), prometheus.Spec.Replicas)
+	// Objects are merged, the other fields are kept.
+	assert.Equal(t, map[string]string{"team": "a"}, prometheus.Spec.ServiceMonitorSelector.MatchLabels)
+	assert.Equal(t, "prometheus", *prometheus.Spec.ServiceAccountName)
+	assert.Equal(t, "prometheus", *prometheus.Name)
+
+	err = ApplyOverrides(prometheus, "prometheus", parse("prometheus.spec.retentionDays=30"))
+	require.Error(t, err)
+
+	err = ApplyOverrides(prometheus, "prometheus", parse("prometheus.spec.retention.days=30"))
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	monitoringv1api "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// ProbeBuilder builds a Probe of static targets, probed by a blackbox
+// exporter with one of its modules.
+type ProbeBuilder struct {
+	labels    map[string]string
+	namespace string
+	name      string
+	proberURL string
+	module    string
+	targets   []string
+	interval  monitoringv1api.Duration
+}
+
+// NewProbeBuilder returns a builder for a Probe of the targets through the
+// blackbox exporter at proberURL, a host:port address. The targets are
+// probed with BlackboxExporterDefaultModule unless WithModule is called.
+func NewProbeBuilder(namespace, name, proberURL string, targets ...string) *ProbeBuilder {
+	return &ProbeBuilder{
+		labels:    map[string]string{},
+		namespace: namespace,
+		name:      name,
+		proberURL: proberURL,
+		module:    BlackboxExporterDefaultModule,
+		targets:   targets,
+	}
+}
+
+// WithStack labels the Probe as part of the stack, so that the Prometheus
+// of the stack selects it.
+func (p *ProbeBuilder) WithStack(stack string) *ProbeBuilder {
+	if stack != "" {
+		p.labels[PartOfLabel] = stack
+	}
+	return p
+}
+
+// WithModule sets the module of the blackbox exporter probing the targets.
+func (p *ProbeBuilder) WithModule(module string) *ProbeBuilder {
+	p.module = module
+	return p
+}
+
+// WithInterval sets the interval between the probes, Prometheus using its
+// scrape interval when empty.
+func (p *ProbeBuilder) WithInterval(interval monitoringv1api.Duration) *ProbeBuilder {
+	p.interval = interval
+	return p
+}
+
+func (p *ProbeBuilder) Build() *monitoringv1.ProbeApplyConfiguration {
+	probe := &monitoringv1.ProbeApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Probe"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &monitoringv1.ProbeSpecApplyConfiguration{
+			ProberSpec: &monitoringv1.ProberSpecApplyConfiguration{
+				URL:  ptr.To(p.proberURL),
+				Path: ptr.To("/probe"),
+			},
+			Module: ptr.To(p.module),
+			Targets: &monitoringv1.ProbeTargetsApplyConfiguration{
+				StaticConfig: &monitoringv1.ProbeTargetStaticConfigApplyConfiguration{
+					Targets: p.targets,
+				},
+			},
+		},
+	}
+	if len(p.labels) > 0 {
+		probe.Labels = p.labels
+	}
+	if p.interval != "" {
+		probe.Spec.Interval = ptr.To(p.interval)
+	}
+	return probe
+}
@@ -0,0 +1,494 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+
+	monitoringv1api "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+// PrometheusImage is the image of Prometheus, tagged with the version pinned
+// by WithVersion.
+const PrometheusImage = "quay.io/prometheus/prometheus"
+
+type PrometheusBuilder struct {
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	stack            string
+	name             string
+	alertmanagerName string
+	replicas         int32
+	retention        string
+	resources        corev1.ResourceList
+	alerting         bool
+	daemonSet        bool
+	remoteWriteURLs  []string
+	version          string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifests        PrometheusManifests
+	// reloaderMonitoring exposes and scrapes the config-reloader sidecar.
+	reloaderMonitoring bool
+}
+
+type PrometheusManifests struct {
+	ServiceAccount     *applyConfigCorev1.ServiceAccountApplyConfiguration
+	ClusterRole        *applyConfigRbacv1.ClusterRoleApplyConfiguration
+	ClusterRoleBinding *applyConfigRbacv1.ClusterRoleBindingApplyConfiguration
+	Prometheus         *monitoringv1.PrometheusApplyConfiguration
+	PrometheusAgent    *monitoringv1alpha1.PrometheusAgentApplyConfiguration
+	Service            *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceMonitor     *monitoringv1.ServiceMonitorApplyConfiguration
+}
+
+func NewPrometheus(namespace string) *PrometheusBuilder {
+	return &PrometheusBuilder{
+		labels: map[string]string{
+			"prometheus": "prometheus",
+		},
+		labelSelectors: map[string]string{
+			"prometheus": "prometheus",
+		},
+		namespace:        namespace,
+		name:             "prometheus",
+		alertmanagerName: AlertManagerName,
+		replicas:         2,
+		alerting:         true,
+	}
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster. The Prometheus or
+// PrometheusAgent of the stack only selects the resources labeled as part
+// of the stack, and is reconciled by the operator of the stack.
+func (p *PrometheusBuilder) WithStack(stack string) *PrometheusBuilder {
+	p.stack = stack
+	p.name = stackObjectName(stack, "prometheus")
+	p.alertmanagerName = stackObjectName(stack, AlertManagerName)
+	p.labels["prometheus"] = p.name
+	p.labelSelectors["prometheus"] = p.name
+	if stack != "" {
+		p.labels[PartOfLabel] = stack
+	}
+	return p
+}
+
+// WithReplicas sets the number of replicas of the Prometheus or
+// PrometheusAgent built afterwards.
+func (p *PrometheusBuilder) WithReplicas(replicas int32) *PrometheusBuilder {
+	p.replicas = replicas
+	return p
+}
+
+// WithRetention sets the retention of the Prometheus built afterwards.
+func (p *PrometheusBuilder) WithRetention(retention string) *PrometheusBuilder {
+	p.retention = retention
+	return p
+}
+
+// WithResources sets the resource requests of the Prometheus or
+// PrometheusAgent built afterwards.
+func (p *PrometheusBuilder) WithResources(requests corev1.ResourceList) *PrometheusBuilder {
+	p.resources = requests
+	return p
+}
+
+// WithoutAlerting disables sending alerts to the Alertmanager of the stack,
+// when it isn't deployed.
+func (p *PrometheusBuilder) WithoutAlerting() *PrometheusBuilder {
+	p.alerting = false
+	return p
+}
+
+// WithDaemonSetMode runs the PrometheusAgent built afterwards as a DaemonSet,
+// each Pod scraping the PodMonitor targets of its own node. The ClusterRole
+// built afterwards is restricted to what such an agent needs.
+func (p *PrometheusBuilder) WithDaemonSetMode() *PrometheusBuilder {
+	p.daemonSet = true
+	return p
+}
+
+// WithRemoteWrite forwards the samples of the Prometheus or PrometheusAgent
+// built afterwards to the remote write endpoint. It can be called several
+// times to forward the samples to several endpoints.
+func (p *PrometheusBuilder) WithRemoteWrite(url string) *PrometheusBuilder {
+	p.remoteWriteURLs = append(p.remoteWriteURLs, url)
+	return p
+}
+
+// WithVersion pins the Prometheus version of the Prometheus or
+// PrometheusAgent built afterwards, along with the tag of its image. The
+// operator picks its default version when unset.
+func (p *PrometheusBuilder) WithVersion(version string) *PrometheusBuilder {
+	p.version = version
+	return p
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Prometheus or PrometheusAgent built afterwards, whose Pods then pull
+// their images from private registries.
+func (p *PrometheusBuilder) WithImagePullSecrets(names ...string) *PrometheusBuilder {
+	p.imagePullSecrets = append(p.imagePullSecrets, names...)
+	return p
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the Prometheus or PrometheusAgent replicas built
+// afterwards. The PrometheusAgent in DaemonSet mode runs one Pod per node
+// and only gets the priority class.
+func (p *PrometheusBuilder) WithScheduling(scheduling Scheduling) *PrometheusBuilder {
+	p.scheduling = scheduling
+	return p
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (p *PrometheusBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *PrometheusBuilder {
+	p.ipFamilyPolicy = policy
+	return p
+}
+
+func (p *PrometheusBuilder) WithServiceAccount() *PrometheusBuilder {
+	p.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		ImagePullSecrets: podImagePullSecrets(p.imagePullSecrets),
+	}
+	return p
+}
+
+func (p *PrometheusBuilder) WithClusterRole() *PrometheusBuilder {
+	p.manifests.ClusterRole = &applyConfigRbacv1.ClusterRoleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRole"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Rules: []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes", "nodes/metrics", "services", "endpoints", "pods"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"configmaps"},
+				Verbs:     []string{"get"},
+			},
+			{
+				APIGroups: []string{"networking.k8s.io"},
+				Resources: []string{"ingresses"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				NonResourceURLs: []string{"/metrics"},
+				Verbs:           []string{"get"},
+			},
+		},
+	}
+
+	if p.daemonSet {
+		p.manifests.ClusterRole.Rules = []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes", "nodes/metrics", "pods"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				NonResourceURLs: []string{"/metrics"},
+				Verbs:           []string{"get"},
+			},
+		}
+	}
+
+	return p
+}
+
+func (p *PrometheusBuilder) WithClusterRoleBinding() *PrometheusBuilder {
+	p.manifests.ClusterRoleBinding = &applyConfigRbacv1.ClusterRoleBindingApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRoleBinding"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+			APIGroup: ptr.To("rbac.authorization.k8s.io"),
+			Kind:     ptr.To("ClusterRole"),
+			Name:     p.manifests.ClusterRole.Name,
+		},
+		Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
+			{
+				Kind:      ptr.To("ServiceAccount"),
+				Name:      p.manifests.ServiceAccount.Name,
+				Namespace: ptr.To(p.namespace),
+			},
+		},
+	}
+	return p
+}
+
+func (p *PrometheusBuilder) WithPrometheus() *PrometheusBuilder {
+	p.manifests.Prometheus = &monitoringv1.PrometheusApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Prometheus"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:        ptr.To(p.name),
+			Labels:      p.labels,
+			Annotations: stackAnnotations(p.stack),
+			Namespace:   ptr.To(p.namespace),
+		},
+		Spec: &monitoringv1.PrometheusSpecApplyConfiguration{
+			CommonPrometheusFieldsApplyConfiguration: monitoringv1.CommonPrometheusFieldsApplyConfiguration{
+				ServiceAccountName:              p.manifests.ServiceAccount.Name,
+				ImagePullSecrets:                specImagePullSecrets(p.imagePullSecrets),
+				ServiceMonitorSelector:          stackSelector(p.stack),
+				ServiceMonitorNamespaceSelector: &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				PodMonitorSelector:              stackSelector(p.stack),
+				PodMonitorNamespaceSelector:     &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				ProbeSelector:                   stackSelector(p.stack),
+				ProbeNamespaceSelector:          &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				ScrapeConfigSelector:            stackSelector(p.stack),
+				ScrapeConfigNamespaceSelector:   &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				ImagePullPolicy:                 ptr.To(corev1.PullIfNotPresent),
+				Replicas:                        ptr.To(p.replicas),
+			},
+			RuleSelector:          stackSelector(p.stack),
+			RuleNamespaceSelector: &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+		},
+	}
+
+	if p.alerting {
+		p.manifests.Prometheus.Spec.Alerting = &monitoringv1.AlertingSpecApplyConfiguration{
+			Alertmanagers: []monitoringv1.AlertmanagerEndpointsApplyConfiguration{
+				{
+					Namespace: ptr.To(p.namespace),
+					Name:      ptr.To(p.alertmanagerName),
+					Port:      ptr.To(intstr.FromString("http-web")),
+				},
+			},
+		}
+	}
+
+	if len(p.remoteWriteURLs) > 0 {
+		p.manifests.Prometheus.Spec.RemoteWrite = p.remoteWrite()
+	}
+
+	if p.version != "" {
+		p.pinVersion(&p.manifests.Prometheus.Spec.CommonPrometheusFieldsApplyConfiguration)
+	}
+
+	p.schedule(&p.manifests.Prometheus.Spec.CommonPrometheusFieldsApplyConfiguration)
+
+	if p.retention != "" {
+		p.manifests.Prometheus.Spec.Retention = ptr.To(monitoringv1api.Duration(p.retention))
+	}
+
+	if p.resources != nil {
+		p.manifests.Prometheus.Spec.Resources = &corev1.ResourceRequirements{
+			Requests: p.resources,
+		}
+	}
+
+	return p
+}
+
+// WithPrometheusAgent builds a PrometheusAgent instead of a Prometheus, for
+// deployments which only forward samples through remote write.
+func (p *PrometheusBuilder) WithPrometheusAgent() *PrometheusBuilder {
+	p.manifests.PrometheusAgent = &monitoringv1alpha1.PrometheusAgentApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("PrometheusAgent"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1alpha1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:        ptr.To(p.name),
+			Labels:      p.labels,
+			Annotations: stackAnnotations(p.stack),
+			Namespace:   ptr.To(p.namespace),
+		},
+		Spec: &monitoringv1alpha1.PrometheusAgentSpecApplyConfiguration{
+			CommonPrometheusFieldsApplyConfiguration: monitoringv1.CommonPrometheusFieldsApplyConfiguration{
+				PodMetadata: &monitoringv1.EmbeddedObjectMetadataApplyConfiguration{
+					Labels: p.labelSelectors,
+				},
+				ServiceAccountName:              p.manifests.ServiceAccount.Name,
+				ImagePullSecrets:                specImagePullSecrets(p.imagePullSecrets),
+				ServiceMonitorSelector:          stackSelector(p.stack),
+				ServiceMonitorNamespaceSelector: &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				PodMonitorSelector:              stackSelector(p.stack),
+				PodMonitorNamespaceSelector:     &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				ProbeSelector:                   stackSelector(p.stack),
+				ProbeNamespaceSelector:          &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				ScrapeConfigSelector:            stackSelector(p.stack),
+				ScrapeConfigNamespaceSelector:   &applyConfigMetav1.LabelSelectorApplyConfiguration{},
+				ImagePullPolicy:                 ptr.To(corev1.PullIfNotPresent),
+				Replicas:                        ptr.To(p.replicas),
+			},
+		},
+	}
+
+	if p.daemonSet {
+		// In DaemonSet mode, the agent only supports PodMonitors and runs one
+		// Pod per node.
+		spec := p.manifests.PrometheusAgent.Spec
+		spec.Mode = ptr.To("DaemonSet")
+		spec.Replicas = nil
+		spec.ServiceMonitorSelector = nil
+		spec.ServiceMonitorNamespaceSelector = nil
+		spec.ProbeSelector = nil
+		spec.ProbeNamespaceSelector = nil
+		spec.ScrapeConfigSelector = nil
+		spec.ScrapeConfigNamespaceSelector = nil
+	}
+
+	if len(p.remoteWriteURLs) > 0 {
+		p.manifests.PrometheusAgent.Spec.RemoteWrite = p.remoteWrite()
+	}
+
+	if p.version != "" {
+		p.pinVersion(&p.manifests.PrometheusAgent.Spec.CommonPrometheusFieldsApplyConfiguration)
+	}
+
+	p.schedule(&p.manifests.PrometheusAgent.Spec.CommonPrometheusFieldsApplyConfiguration)
+
+	if p.resources != nil {
+		p.manifests.PrometheusAgent.Spec.Resources = &corev1.ResourceRequirements{
+			Requests: p.resources,
+		}
+	}
+
+	return p
+}
+
+func (p *PrometheusBuilder) remoteWrite() []monitoringv1.RemoteWriteSpecApplyConfiguration {
+	remoteWrite := make([]monitoringv1.RemoteWriteSpecApplyConfiguration, 0, len(p.remoteWriteURLs))
+	for _, url := range p.remoteWriteURLs {
+		remoteWrite = append(remoteWrite, monitoringv1.RemoteWriteSpecApplyConfiguration{URL: ptr.To(url)})
+	}
+	return remoteWrite
+}
+
+// pinVersion sets the version and the image of Prometheus, so that the tag of
+// the image always matches the version the operator generates the
+// configuration for.
+func (p *PrometheusBuilder) pinVersion(fields *monitoringv1.CommonPrometheusFieldsApplyConfiguration) {
+	fields.Version = ptr.To(p.version)
+	fields.Image = ptr.To(fmt.Sprintf("%s:v%s", PrometheusImage, p.version))
+}
+
+// schedule sets the scheduling settings of the replicas. The agent in
+// DaemonSet mode runs one Pod per node, spreading it is meaningless.
+func (p *PrometheusBuilder) schedule(fields *monitoringv1.CommonPrometheusFieldsApplyConfiguration) {
+	fields.PriorityClassName = p.scheduling.priorityClassName()
+	if p.daemonSet {
+		return
+	}
+
+	fields.Affinity = p.scheduling.affinity(p.labelSelectors)
+	fields.TopologySpreadConstraints = p.scheduling.prometheusTopologySpread(p.labelSelectors)
+}
+
+func (p *PrometheusBuilder) WithService() *PrometheusBuilder {
+	p.manifests.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			Ports: []applyConfigCorev1.ServicePortApplyConfiguration{
+				{
+					Name:       ptr.To("web"),
+					Port:       ptr.To(int32(9090)),
+					TargetPort: ptr.To(intstr.FromString("web")),
+				},
+			},
+			Selector:       p.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(p.ipFamilyPolicy),
+		},
+	}
+
+	if p.reloaderMonitoring {
+		p.manifests.Service.Spec.Ports = append(p.manifests.Service.Spec.Ports, reloaderServicePort())
+	}
+	return p
+}
+
+func (p *PrometheusBuilder) WithServiceMonitor() *PrometheusBuilder {
+	p.manifests.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: p.labelSelectors,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					HonorLabels: ptr.To(true),
+					Port:        ptr.To("web"),
+				},
+			},
+		},
+	}
+
+	if p.reloaderMonitoring {
+		p.manifests.ServiceMonitor.Spec.Endpoints = append(p.manifests.ServiceMonitor.Spec.Endpoints, reloaderEndpoint())
+	}
+	return p
+}
+
+func (p *PrometheusBuilder) Build() PrometheusManifests {
+	return p.manifests
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// podImagePullSecrets returns the references to the image pull secrets for
+// ServiceAccounts and Pod specs.
+func podImagePullSecrets(names []string) []applyConfigCorev1.LocalObjectReferenceApplyConfiguration {
+	if len(names) == 0 {
+		return nil
+	}
+
+	refs := make([]applyConfigCorev1.LocalObjectReferenceApplyConfiguration, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, applyConfigCorev1.LocalObjectReferenceApplyConfiguration{Name: ptr.To(name)})
+	}
+	return refs
+}
+
+// specImagePullSecrets returns the references to the image pull secrets for
+// the specs of the Prometheus, PrometheusAgent and Alertmanager resources.
+func specImagePullSecrets(names []string) []corev1.LocalObjectReference {
+	if len(names) == 0 {
+		return nil
+	}
+
+	refs := make([]corev1.LocalObjectReference, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, corev1.LocalObjectReference{Name: name})
+	}
+	return refs
+}
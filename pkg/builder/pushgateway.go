@@ -0,0 +1,283 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"time"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyCofongiAppsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	LatestPushgatewayVersion = "1.10.0"
+
+	// PushgatewayStaleGroupThreshold is the time since the last push after
+	// which a push group is considered stale, its batch job having stopped
+	// pushing. It leaves an hour of slack to the daily batch jobs.
+	PushgatewayStaleGroupThreshold = 25 * time.Hour
+)
+
+type PushgatewayBuilder struct {
+	labels           map[string]string
+	labelSelectors   map[string]string
+	namespace        string
+	name             string
+	version          string
+	imagePullSecrets []string
+	scheduling       Scheduling
+	ipFamilyPolicy   corev1.IPFamilyPolicy
+	manifests        PushgatewayManifests
+}
+
+type PushgatewayManifests struct {
+	ServiceAccount *applyConfigCorev1.ServiceAccountApplyConfiguration
+	Deployment     *applyCofongiAppsv1.DeploymentApplyConfiguration
+	Service        *applyConfigCorev1.ServiceApplyConfiguration
+	ServiceMonitor *monitoringv1.ServiceMonitorApplyConfiguration
+	PrometheusRule *monitoringv1.PrometheusRuleApplyConfiguration
+}
+
+// NewPushgatewayBuilder returns a builder for the Pushgateway receiving the
+// metrics of the batch jobs.
+func NewPushgatewayBuilder(namespace, version string) *PushgatewayBuilder {
+	return &PushgatewayBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name": "pushgateway",
+		},
+		labelSelectors: map[string]string{
+			"app.kubernetes.io/name": "pushgateway",
+		},
+		namespace: namespace,
+		name:      "pushgateway",
+		version:   version,
+	}
+}
+
+// WithStack names the objects built afterwards after the stack, so that
+// several stacks can run in the same cluster.
+func (p *PushgatewayBuilder) WithStack(stack string) *PushgatewayBuilder {
+	p.name = stackObjectName(stack, "pushgateway")
+	if stack != "" {
+		p.labels[PartOfLabel] = stack
+		p.labelSelectors[PartOfLabel] = stack
+	}
+	return p
+}
+
+// WithImagePullSecrets attaches image pull secrets to the ServiceAccount and
+// the Deployment built afterwards.
+func (p *PushgatewayBuilder) WithImagePullSecrets(names ...string) *PushgatewayBuilder {
+	p.imagePullSecrets = append(p.imagePullSecrets, names...)
+	return p
+}
+
+// WithScheduling sets the priority class, the topology spread and the
+// anti-affinity of the Pods of the Deployment built afterwards.
+func (p *PushgatewayBuilder) WithScheduling(scheduling Scheduling) *PushgatewayBuilder {
+	p.scheduling = scheduling
+	return p
+}
+
+// WithIPFamilyPolicy sets the IP family policy of the Service built
+// afterwards, such as PreferDualStack on dual-stack clusters. The Service
+// gets the policy of the cluster when empty.
+func (p *PushgatewayBuilder) WithIPFamilyPolicy(policy corev1.IPFamilyPolicy) *PushgatewayBuilder {
+	p.ipFamilyPolicy = policy
+	return p
+}
+
+func (p *PushgatewayBuilder) WithServiceAccount() *PushgatewayBuilder {
+	p.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		ImagePullSecrets: podImagePullSecrets(p.imagePullSecrets),
+	}
+	return p
+}
+
+// WithDeployment builds the Deployment of the Pushgateway, which keeps the
+// pushed metrics in memory: they are lost when the Pod restarts, and the
+// batch jobs push them again on their next run.
+func (p *PushgatewayBuilder) WithDeployment() *PushgatewayBuilder {
+	template := &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Labels: p.labelSelectors,
+		},
+		Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+			ServiceAccountName: p.manifests.ServiceAccount.Name,
+			ImagePullSecrets:   podImagePullSecrets(p.imagePullSecrets),
+			Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+				{
+					Name:  ptr.To("pushgateway"),
+					Image: ptr.To(fmt.Sprintf("quay.io/prometheus/pushgateway:v%s", p.version)),
+					Ports: []applyConfigCorev1.ContainerPortApplyConfiguration{
+						{
+							Name:          ptr.To("web"),
+							ContainerPort: ptr.To(int32(9091)),
+						},
+					},
+					LivenessProbe:  httpGetProbe("/-/healthy", "web"),
+					ReadinessProbe: httpGetProbe("/-/ready", "web"),
+					SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+						AllowPrivilegeEscalation: ptr.To(false),
+						Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+							Drop: []corev1.Capability{
+								"ALL",
+							},
+						},
+						ReadOnlyRootFilesystem: ptr.To(true),
+						RunAsUser:              ptr.To(int64(65534)),
+						RunAsNonRoot:           ptr.To(true),
+						RunAsGroup:             ptr.To(int64(65534)),
+						SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+							Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
+						},
+					},
+				},
+			},
+		},
+	}
+	p.scheduling.schedulePod(template.Spec, p.labelSelectors)
+
+	p.manifests.Deployment = &applyCofongiAppsv1.DeploymentApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Deployment"),
+			APIVersion: ptr.To("apps/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &applyCofongiAppsv1.DeploymentSpecApplyConfiguration{
+			Replicas: ptr.To(int32(1)),
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: p.labelSelectors,
+			},
+			Template: template,
+		},
+	}
+	return p
+}
+
+func (p *PushgatewayBuilder) WithService() *PushgatewayBuilder {
+	p.manifests.Service = &applyConfigCorev1.ServiceApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("Service"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &applyConfigCorev1.ServiceSpecApplyConfiguration{
+			Ports: []applyConfigCorev1.ServicePortApplyConfiguration{
+				{
+					Name:       ptr.To("web"),
+					Port:       ptr.To(int32(9091)),
+					TargetPort: ptr.To(intstr.FromString("web")),
+				},
+			},
+			Selector:       p.labelSelectors,
+			IPFamilyPolicy: serviceIPFamilyPolicy(p.ipFamilyPolicy),
+		},
+	}
+	return p
+}
+
+// WithServiceMonitor scrapes the Pushgateway honoring the labels of the
+// pushed metrics, so that the job and instance labels identify the batch
+// jobs rather than the Pushgateway.
+func (p *PushgatewayBuilder) WithServiceMonitor() *PushgatewayBuilder {
+	p.manifests.ServiceMonitor = &monitoringv1.ServiceMonitorApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceMonitor"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &monitoringv1.ServiceMonitorSpecApplyConfiguration{
+			Selector: &applyConfigMetav1.LabelSelectorApplyConfiguration{
+				MatchLabels: p.labelSelectors,
+			},
+			Endpoints: []monitoringv1.EndpointApplyConfiguration{
+				{
+					HonorLabels: ptr.To(true),
+					Port:        ptr.To("web"),
+				},
+			},
+		},
+	}
+	return p
+}
+
+// WithPrometheusRule builds the PrometheusRule alerting on the push groups
+// which haven't been pushed to for PushgatewayStaleGroupThreshold. The
+// Pushgateway keeps exposing the last pushed metrics of a batch job which
+// stopped running, which would otherwise go unnoticed. The series are
+// selected by the service label, the job and namespace labels being the ones
+// pushed.
+func (p *PushgatewayBuilder) WithPrometheusRule() *PushgatewayBuilder {
+	p.manifests.PrometheusRule = &monitoringv1.PrometheusRuleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("PrometheusRule"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(p.name),
+			Labels:    p.labels,
+			Namespace: ptr.To(p.namespace),
+		},
+		Spec: &monitoringv1.PrometheusRuleSpecApplyConfiguration{
+			Groups: []monitoringv1.RuleGroupApplyConfiguration{
+				{
+					Name: ptr.To("pushgateway"),
+					Rules: []monitoringv1.RuleApplyConfiguration{
+						alertingRule(
+							"PushgatewayGroupStale",
+							fmt.Sprintf(`time() - push_time_seconds{service=%q} > %d`, p.name, int(PushgatewayStaleGroupThreshold.Seconds())),
+							"5m",
+							"warning",
+							"The push group of job {{ $labels.job }} hasn't been pushed to for {{ $value | humanizeDuration }}.",
+						),
+					},
+				},
+			},
+		},
+	}
+	return p
+}
+
+func (p *PushgatewayBuilder) Build() PushgatewayManifests {
+	return p.manifests
+}
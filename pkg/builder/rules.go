@@ -0,0 +1,38 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	monitoringv1api "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// alertingRule returns an alerting rule with a severity label and a summary
+// annotation.
+func alertingRule(alert, expr, duration, severity, summary string) monitoringv1.RuleApplyConfiguration {
+	return monitoringv1.RuleApplyConfiguration{
+		Alert: ptr.To(alert),
+		Expr:  ptr.To(intstr.FromString(expr)),
+		For:   ptr.To(monitoringv1api.Duration(duration)),
+		Labels: map[string]string{
+			"severity": severity,
+		},
+		Annotations: map[string]string{
+			"summary": summary,
+		},
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// AntiAffinity keeps the replicas of a component away from each other's
+// nodes.
+type AntiAffinity string
+
+const (
+	// NoAntiAffinity lets the scheduler place the replicas freely.
+	NoAntiAffinity AntiAffinity = ""
+	// SoftAntiAffinity prefers placing the replicas on different nodes.
+	SoftAntiAffinity AntiAffinity = "soft"
+	// HardAntiAffinity requires placing the replicas on different nodes,
+	// leaving replicas pending when there are fewer nodes than replicas.
+	HardAntiAffinity AntiAffinity = "hard"
+)
+
+// ParseAntiAffinity returns the anti-affinity with the given name.
+func ParseAntiAffinity(s string) (AntiAffinity, error) {
+	switch a := AntiAffinity(s); a {
+	case NoAntiAffinity, SoftAntiAffinity, HardAntiAffinity:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown anti-affinity %s, must be one of: soft, hard", s)
+	}
+}
+
+// hostnameLabel is the node label the anti-affinity applies to.
+const hostnameLabel = "kubernetes.io/hostname"
+
+// Scheduling are the scheduling settings of the Pods of a component, so that
+// it survives node failures and keeps running under bin-packing pressure.
+type Scheduling struct {
+	// PriorityClassName is the PriorityClass of the Pods.
+	PriorityClassName string
+	// TopologySpreadKey spreads the replicas evenly across the values of
+	// this node label, such as topology.kubernetes.io/zone.
+	TopologySpreadKey string
+	// AntiAffinity places the replicas on different nodes.
+	AntiAffinity AntiAffinity
+}
+
+func (s Scheduling) priorityClassName() *string {
+	if s.PriorityClassName == "" {
+		return nil
+	}
+	return ptr.To(s.PriorityClassName)
+}
+
+// podTopologySpread returns the topology spread constraints of the Pods
+// matching the selector, for Pod specs.
+func (s Scheduling) podTopologySpread(selector map[string]string) []applyConfigCorev1.TopologySpreadConstraintApplyConfiguration {
+	if s.TopologySpreadKey == "" {
+		return nil
+	}
+
+	return []applyConfigCorev1.TopologySpreadConstraintApplyConfiguration{
+		{
+			MaxSkew:           ptr.To(int32(1)),
+			TopologyKey:       ptr.To(s.TopologySpreadKey),
+			WhenUnsatisfiable: ptr.To(corev1.ScheduleAnyway),
+			LabelSelector:     &applyConfigMetav1.LabelSelectorApplyConfiguration{MatchLabels: selector},
+		},
+	}
+}
+
+// prometheusTopologySpread returns the topology spread constraints of the
+// Pods matching the selector, for the Prometheus and PrometheusAgent specs.
+func (s Scheduling) prometheusTopologySpread(selector map[string]string) []monitoringv1.TopologySpreadConstraintApplyConfiguration {
+	if s.TopologySpreadKey == "" {
+		return nil
+	}
+
+	return []monitoringv1.TopologySpreadConstraintApplyConfiguration{
+		{
+			CoreV1TopologySpreadConstraintApplyConfiguration: monitoringv1.CoreV1TopologySpreadConstraintApplyConfiguration{
+				MaxSkew:           ptr.To(int32(1)),
+				TopologyKey:       ptr.To(s.TopologySpreadKey),
+				WhenUnsatisfiable: ptr.To(corev1.ScheduleAnyway),
+				LabelSelector:     &applyConfigMetav1.LabelSelectorApplyConfiguration{MatchLabels: selector},
+			},
+		},
+	}
+}
+
+// topologySpread returns the topology spread constraints of the Pods
+// matching the selector, for the Alertmanager spec.
+func (s Scheduling) topologySpread(selector map[string]string) []corev1.TopologySpreadConstraint {
+	if s.TopologySpreadKey == "" {
+		return nil
+	}
+
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       s.TopologySpreadKey,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: selector},
+		},
+	}
+}
+
+// podAffinity returns the anti-affinity of the Pods matching the selector,
+// for Pod specs.
+func (s Scheduling) podAffinity(selector map[string]string) *applyConfigCorev1.AffinityApplyConfiguration {
+	term := applyConfigCorev1.PodAffinityTerm().
+		WithTopologyKey(hostnameLabel).
+		WithLabelSelector(applyConfigMetav1.LabelSelector().WithMatchLabels(selector))
+
+	switch s.AntiAffinity {
+	case SoftAntiAffinity:
+		return applyConfigCorev1.Affinity().WithPodAntiAffinity(applyConfigCorev1.PodAntiAffinity().
+			WithPreferredDuringSchedulingIgnoredDuringExecution(applyConfigCorev1.WeightedPodAffinityTerm().
+				WithWeight(100).
+				WithPodAffinityTerm(term)))
+	case HardAntiAffinity:
+		return applyConfigCorev1.Affinity().WithPodAntiAffinity(applyConfigCorev1.PodAntiAffinity().
+			WithRequiredDuringSchedulingIgnoredDuringExecution(term))
+	default:
+		return nil
+	}
+}
+
+// affinity returns the anti-affinity of the Pods matching the selector, for
+// the Prometheus, PrometheusAgent and Alertmanager specs.
+func (s Scheduling) affinity(selector map[string]string) *corev1.Affinity {
+	term := corev1.PodAffinityTerm{
+		TopologyKey:   hostnameLabel,
+		LabelSelector: &metav1.LabelSelector{MatchLabels: selector},
+	}
+
+	switch s.AntiAffinity {
+	case SoftAntiAffinity:
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{Weight: 100, PodAffinityTerm: term},
+				},
+			},
+		}
+	case HardAntiAffinity:
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// schedulePod applies the scheduling settings to a Pod spec, the replicas
+// being the Pods matching the selector.
+func (s Scheduling) schedulePod(spec *applyConfigCorev1.PodSpecApplyConfiguration, selector map[string]string) {
+	spec.PriorityClassName = s.priorityClassName()
+	spec.Affinity = s.podAffinity(selector)
+	spec.TopologySpreadConstraints = s.podTopologySpread(selector)
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/client/applyconfiguration/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// reloaderPort is the port of the config-reloader sidecar which the operator
+// adds to the Prometheus, PrometheusAgent and Alertmanager Pods.
+const reloaderPort = "reloader-web"
+
+// WithSelfMonitoringRules builds the PrometheusRule alerting on the failures
+// of the operator, from the metrics scraped by its ServiceMonitor: failed
+// reconciliations and syncs, failing node syncs, and config-reloaders
+// failing to reload the configuration of the Pods in the namespace.
+func (o *OperatorBuilder) WithSelfMonitoringRules() *OperatorBuilder {
+	selector := fmt.Sprintf(`job=%q, namespace=%q`, o.name, o.namespace)
+	o.manifets.PrometheusRule = &monitoringv1.PrometheusRuleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("PrometheusRule"),
+			APIVersion: ptr.To("monitoring.coreos.com/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To(o.name),
+			Labels:    o.labels,
+			Namespace: ptr.To(o.namespace),
+		},
+		Spec: &monitoringv1.PrometheusRuleSpecApplyConfiguration{
+			Groups: []monitoringv1.RuleGroupApplyConfiguration{
+				{
+					Name: ptr.To("prometheus-operator"),
+					Rules: []monitoringv1.RuleApplyConfiguration{
+						alertingRule(
+							"PrometheusOperatorReconcileErrors",
+							fmt.Sprintf(`sum by (controller, namespace) (rate(prometheus_operator_reconcile_errors_total{%[1]s}[5m])) / sum by (controller, namespace) (rate(prometheus_operator_reconcile_operations_total{%[1]s}[5m])) > 0.1`, selector),
+							"10m",
+							"warning",
+							"{{ $value | humanizePercentage }} of the reconciliations of the {{ $labels.controller }} controller fail.",
+						),
+						alertingRule(
+							"PrometheusOperatorSyncFailed",
+							fmt.Sprintf(`min_over_time(prometheus_operator_syncs{status="failed", %s}[5m]) > 0`, selector),
+							"10m",
+							"warning",
+							"{{ $value }} objects of the {{ $labels.controller }} controller are in the failed state.",
+						),
+						alertingRule(
+							"PrometheusOperatorNodeSyncErrors",
+							fmt.Sprintf(`rate(prometheus_operator_node_syncs_failed_total{%[1]s}[5m]) > 0 or rate(prometheus_operator_node_address_lookup_errors_total{%[1]s}[5m]) > 0.1`, selector),
+							"10m",
+							"warning",
+							"The operator fails to sync the nodes into the kubelet Endpoints.",
+						),
+						alertingRule(
+							"PrometheusOperatorNotReady",
+							fmt.Sprintf(`min by (controller, namespace) (max_over_time(prometheus_operator_ready{%s}[5m])) == 0`, selector),
+							"5m",
+							"warning",
+							"The {{ $labels.controller }} controller isn't ready to reconcile resources.",
+						),
+						alertingRule(
+							"ConfigReloaderSidecarErrors",
+							fmt.Sprintf(`max_over_time(reloader_last_reload_successful{namespace=%q}[5m]) == 0`, o.namespace),
+							"10m",
+							"warning",
+							"The config-reloader of {{ $labels.pod }} fails to reload the configuration.",
+						),
+					},
+				},
+			},
+		},
+	}
+	return o
+}
+
+// WithReloaderMonitoring makes the Service and the ServiceMonitor built
+// afterwards expose and scrape the config-reloader sidecar.
+func (p *PrometheusBuilder) WithReloaderMonitoring() *PrometheusBuilder {
+	p.reloaderMonitoring = true
+	return p
+}
+
+// WithReloaderMonitoring makes the ServiceMonitor built afterwards scrape the
+// config-reloader sidecar.
+func (a *AlertManagerBuilder) WithReloaderMonitoring() *AlertManagerBuilder {
+	a.reloaderMonitoring = true
+	return a
+}
+
+// reloaderServicePort returns the Service port of the config-reloader.
+func reloaderServicePort() applyConfigCorev1.ServicePortApplyConfiguration {
+	return applyConfigCorev1.ServicePortApplyConfiguration{
+		Name:       ptr.To(reloaderPort),
+		Port:       ptr.To(int32(8080)),
+		TargetPort: ptr.To(intstr.FromString(reloaderPort)),
+	}
+}
+
+// reloaderEndpoint returns the ServiceMonitor endpoint of the
+// config-reloader.
+func reloaderEndpoint() monitoringv1.EndpointApplyConfiguration {
+	return monitoringv1.EndpointApplyConfiguration{
+		HonorLabels: ptr.To(true),
+		Port:        ptr.To(reloaderPort),
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"strings"
+
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+const (
+	// PartOfLabel is set on the objects of a named stack. The Prometheus and
+	// Alertmanager of a named stack only select the monitors, rules and
+	// AlertmanagerConfigs carrying this label with the stack name.
+	PartOfLabel = "app.kubernetes.io/part-of"
+	// ControllerIDAnnotation assigns the Prometheus and Alertmanager of a
+	// named stack to the operator of the same stack.
+	ControllerIDAnnotation = "operator.prometheus.io/controller-id"
+	// DefaultClusterDomain is the DNS domain of the Services of the clusters
+	// which don't set a custom one.
+	DefaultClusterDomain = "cluster.local"
+)
+
+// ServiceHost returns the DNS name of a Service in the cluster domain. The
+// name is relative to the search domains of the Pods when the cluster
+// domain is empty.
+func ServiceHost(name, namespace, clusterDomain string) string {
+	host := name + "." + namespace + ".svc"
+	if clusterDomain = strings.Trim(clusterDomain, "."); clusterDomain != "" {
+		host += "." + clusterDomain
+	}
+	return host
+}
+
+// stackObjectName returns the name of an object of the stack, prefixed by
+// the stack name unless it's the default stack.
+func stackObjectName(stack, name string) string {
+	if stack == "" {
+		return name
+	}
+	return stack + "-" + name
+}
+
+// stackSelector returns the selector of the resources of the stack, which
+// selects all resources for the default stack.
+func stackSelector(stack string) *applyConfigMetav1.LabelSelectorApplyConfiguration {
+	if stack == "" {
+		return &applyConfigMetav1.LabelSelectorApplyConfiguration{}
+	}
+	return &applyConfigMetav1.LabelSelectorApplyConfiguration{
+		MatchLabels: map[string]string{PartOfLabel: stack},
+	}
+}
+
+// stackAnnotations returns the annotations of the resources reconciled by
+// the operator of the stack.
+func stackAnnotations(stack string) map[string]string {
+	if stack == "" {
+		return nil
+	}
+	return map[string]string{ControllerIDAnnotation: stack}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStack(t *testing.T) {
+	prometheus := NewPrometheus("monitoring").
+		WithStack("team-a").
+		WithServiceAccount().
+		WithClusterRole().
+		WithClusterRoleBinding().
+		WithService().
+		WithPrometheus().
+		Build()
+
+	assert.Equal(t, "team-a-prometheus", *prometheus.Prometheus.Name)
+	assert.Equal(t, "team-a-prometheus", *prometheus.ClusterRole.Name)
+	assert.Equal(t, "team-a-prometheus", *prometheus.Prometheus.Spec.ServiceAccountName)
+	assert.Equal(t, map[string]string{"prometheus": "team-a-prometheus"}, prometheus.Service.Spec.Selector)
+	assert.Equal(t, map[string]string{PartOfLabel: "team-a"}, prometheus.Prometheus.Spec.ServiceMonitorSelector.MatchLabels)
+	assert.Equal(t, map[string]string{ControllerIDAnnotation: "team-a"}, prometheus.Prometheus.Annotations)
+	require.Len(t, prometheus.Prometheus.Spec.Alerting.Alertmanagers, 1)
+	assert.Equal(t, "team-a-alertmanager", *prometheus.Prometheus.Spec.Alerting.Alertmanagers[0].Name)
+
+	operator := NewOperator("monitoring", "0.75.1").
+		WithStack("team-a").
+		WithServiceAccount().
+		WithDeployment().
+		Build()
+
+	assert.Equal(t, "team-a-prometheus-operator", *operator.Deployment.Name)
+	assert.Equal(t, "team-a", operator.Deployment.Spec.Selector.MatchLabels[PartOfLabel])
+	assert.Contains(t, operator.Deployment.Spec.Template.Spec.Containers[0].Args, "--controller-id=team-a")
+}
+
+func TestWithoutStack(t *testing.T) {
+	prometheus := NewPrometheus("monitoring").
+		WithStack("").
+		WithServiceAccount().
+		WithPrometheus().
+		Build()
+
+	assert.Equal(t, "prometheus", *prometheus.Prometheus.Name)
+	assert.Empty(t, prometheus.Prometheus.Annotations)
+	assert.Empty(t, prometheus.Prometheus.Spec.ServiceMonitorSelector.MatchLabels)
+	assert.Equal(t, "alertmanager", *prometheus.Prometheus.Spec.Alerting.Alertmanagers[0].Name)
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	applyConfigBatchv1 "k8s.io/client-go/applyconfigurations/batch/v1"
+	applyConfigCorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	applyConfigMetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+// VerifierBuilder builds a CronJob running the analyzers of poctl on a
+// schedule, along with the read-only permissions they need.
+type VerifierBuilder struct {
+	labels    map[string]string
+	namespace string
+	image     string
+	schedule  string
+	manifests VerifierManifests
+}
+
+type VerifierManifests struct {
+	ServiceAccount     *applyConfigCorev1.ServiceAccountApplyConfiguration
+	ClusterRole        *applyConfigRbacv1.ClusterRoleApplyConfiguration
+	ClusterRoleBinding *applyConfigRbacv1.ClusterRoleBindingApplyConfiguration
+	CronJob            *applyConfigBatchv1.CronJobApplyConfiguration
+}
+
+func NewVerifier(namespace, image, schedule string) *VerifierBuilder {
+	return &VerifierBuilder{
+		labels: map[string]string{
+			"app.kubernetes.io/name":      "poctl-verifier",
+			"app.kubernetes.io/component": "verifier",
+		},
+		namespace: namespace,
+		image:     image,
+		schedule:  schedule,
+	}
+}
+
+func (v *VerifierBuilder) WithServiceAccount() *VerifierBuilder {
+	v.manifests.ServiceAccount = &applyConfigCorev1.ServiceAccountApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ServiceAccount"),
+			APIVersion: ptr.To("v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To("poctl-verifier"),
+			Labels:    v.labels,
+			Namespace: ptr.To(v.namespace),
+		},
+	}
+	return v
+}
+
+// WithClusterRole grants read access to the objects inspected by the
+// analyzers, and the creation of the Events reporting their findings. The
+// Secrets aren't readable, the analyzers skip the checks of their content
+// unless WithSecretsAccess grants it.
+func (v *VerifierBuilder) WithClusterRole() *VerifierBuilder {
+	v.manifests.ClusterRole = &applyConfigRbacv1.ClusterRoleApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRole"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:   ptr.To("poctl-verifier"),
+			Labels: v.labels,
+		},
+		Rules: []applyConfigRbacv1.PolicyRuleApplyConfiguration{
+			{
+				APIGroups: []string{"monitoring.coreos.com"},
+				Resources: []string{"*"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"namespaces", "nodes", "services", "serviceaccounts", "endpoints", "pods", "configmaps"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"discovery.k8s.io"},
+				Resources: []string{"endpointslices"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"apps"},
+				Resources: []string{"deployments", "statefulsets", "daemonsets"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"rbac.authorization.k8s.io"},
+				Resources: []string{"clusterroles", "clusterrolebindings", "roles", "rolebindings"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"networking.k8s.io"},
+				Resources: []string{"networkpolicies"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"apiextensions.k8s.io"},
+				Resources: []string{"customresourcedefinitions"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch"},
+			},
+		},
+	}
+	return v
+}
+
+// WithSecretsAccess grants the get verb on the Secrets, for the analyzers to
+// check the configuration Secrets of Alertmanager and the object storage
+// Secrets of Thanos. The Secrets aren't listed.
+func (v *VerifierBuilder) WithSecretsAccess() *VerifierBuilder {
+	v.manifests.ClusterRole.Rules = append(v.manifests.ClusterRole.Rules, applyConfigRbacv1.PolicyRuleApplyConfiguration{
+		APIGroups: []string{""},
+		Resources: []string{"secrets"},
+		Verbs:     []string{"get"},
+	})
+	return v
+}
+
+func (v *VerifierBuilder) WithClusterRoleBinding() *VerifierBuilder {
+	v.manifests.ClusterRoleBinding = &applyConfigRbacv1.ClusterRoleBindingApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("ClusterRoleBinding"),
+			APIVersion: ptr.To("rbac.authorization.k8s.io/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:   ptr.To("poctl-verifier"),
+			Labels: v.labels,
+		},
+		RoleRef: &applyConfigRbacv1.RoleRefApplyConfiguration{
+			APIGroup: ptr.To("rbac.authorization.k8s.io"),
+			Kind:     ptr.To("ClusterRole"),
+			Name:     v.manifests.ClusterRole.Name,
+		},
+		Subjects: []applyConfigRbacv1.SubjectApplyConfiguration{
+			{
+				Kind:      ptr.To("ServiceAccount"),
+				Name:      v.manifests.ServiceAccount.Name,
+				Namespace: ptr.To(v.namespace),
+			},
+		},
+	}
+	return v
+}
+
+// WithCronJob runs the verify command on the schedule, from a snapshot of
+// the cluster. Runs don't overlap, and a run with findings fails so that the
+// Job history shows it.
+func (v *VerifierBuilder) WithCronJob() *VerifierBuilder {
+	v.manifests.CronJob = &applyConfigBatchv1.CronJobApplyConfiguration{
+		TypeMetaApplyConfiguration: applyConfigMetav1.TypeMetaApplyConfiguration{
+			Kind:       ptr.To("CronJob"),
+			APIVersion: ptr.To("batch/v1"),
+		},
+		ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+			Name:      ptr.To("poctl-verifier"),
+			Labels:    v.labels,
+			Namespace: ptr.To(v.namespace),
+		},
+		Spec: &applyConfigBatchv1.CronJobSpecApplyConfiguration{
+			Schedule:                   ptr.To(v.schedule),
+			ConcurrencyPolicy:          ptr.To(batchv1.ForbidConcurrent),
+			SuccessfulJobsHistoryLimit: ptr.To(int32(1)),
+			FailedJobsHistoryLimit:     ptr.To(int32(3)),
+			JobTemplate: &applyConfigBatchv1.JobTemplateSpecApplyConfiguration{
+				Spec: &applyConfigBatchv1.JobSpecApplyConfiguration{
+					BackoffLimit: ptr.To(int32(0)),
+					Template: &applyConfigCorev1.PodTemplateSpecApplyConfiguration{
+						ObjectMetaApplyConfiguration: &applyConfigMetav1.ObjectMetaApplyConfiguration{
+							Labels: v.labels,
+						},
+						Spec: &applyConfigCorev1.PodSpecApplyConfiguration{
+							ServiceAccountName: v.manifests.ServiceAccount.Name,
+							RestartPolicy:      ptr.To(corev1.RestartPolicyNever),
+							Containers: []applyConfigCorev1.ContainerApplyConfiguration{
+								{
+									Name:  ptr.To("poctl"),
+									Image: ptr.To(v.image),
+									Args:  []string{"verify", "--log-format=json", "--snapshot"},
+									SecurityContext: &applyConfigCorev1.SecurityContextApplyConfiguration{
+										ReadOnlyRootFilesystem:   ptr.To(true),
+										AllowPrivilegeEscalation: ptr.To(false),
+										Capabilities: &applyConfigCorev1.CapabilitiesApplyConfiguration{
+											Drop: []corev1.Capability{"ALL"},
+										},
+									},
+								},
+							},
+							SecurityContext: &applyConfigCorev1.PodSecurityContextApplyConfiguration{
+								RunAsNonRoot: ptr.To(true),
+								RunAsUser:    ptr.To(int64(65534)),
+								SeccompProfile: &applyConfigCorev1.SeccompProfileApplyConfiguration{
+									Type: ptr.To(corev1.SeccompProfileTypeRuntimeDefault),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return v
+}
+
+// WithPushgateway pushes the metrics of the runs to the Pushgateway at the
+// URL, the CronJob can't be scraped.
+func (v *VerifierBuilder) WithPushgateway(url string) *VerifierBuilder {
+	container := &v.manifests.CronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	container.Args = append(container.Args, "--pushgateway-url="+url)
+	return v
+}
+
+func (v *VerifierBuilder) Build() VerifierManifests {
+	return v.manifests
+}
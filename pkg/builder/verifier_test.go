@@ -0,0 +1,137 @@
+// Copyright 2024 The prometheus-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	k8stesting "github.com/prometheus-operator/poctl/internal/k8sutil/testing"
+	"github.com/prometheus-operator/poctl/internal/verify"
+	"github.com/prometheus-operator/poctl/pkg/builder"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	applyConfigRbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+// TestVerifierClusterRoleCoversVerify runs the verify command against a
+// cluster exercising its analyzers, highly-available workloads and a
+// ServiceMonitor selecting a headless Service included, and checks that the
+// ClusterRole of the verifier grants every request it sends.
+func TestVerifierClusterRoleCoversVerify(t *testing.T) {
+	type request struct {
+		group, resource, verb string
+	}
+
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	record := func(action clienttesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		resource := action.GetResource().Resource
+		if sub := action.GetSubresource(); sub != "" {
+			resource += "/" + sub
+		}
+		requests = append(requests, request{action.GetResource().Group, resource, action.GetVerb()})
+		return false, nil, nil
+	}
+
+	labels := map[string]string{"app": "api"}
+	clientSets := k8stesting.NewFakeClientSets(
+		k8stesting.WithObjects(
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+				Name:      "prometheus-operator",
+				Namespace: "monitoring",
+				Labels:    map[string]string{"app.kubernetes.io/name": "prometheus-operator"},
+			}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "app", Labels: labels},
+				Spec: corev1.ServiceSpec{
+					ClusterIP: corev1.ClusterIPNone,
+					Ports:     []corev1.ServicePort{{Name: "metrics", Port: 8080}},
+				},
+			},
+			&monitoringv1.Prometheus{
+				ObjectMeta: metav1.ObjectMeta{Name: "k8s", Namespace: "monitoring"},
+				Spec: monitoringv1.PrometheusSpec{
+					CommonPrometheusFields: monitoringv1.CommonPrometheusFields{
+						ServiceAccountName: "prometheus",
+						Replicas:           ptr.To(int32(2)),
+					},
+				},
+			},
+			&monitoringv1alpha1.PrometheusAgent{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "monitoring"},
+			},
+			&monitoringv1.Alertmanager{
+				ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "monitoring"},
+				Spec:       monitoringv1.AlertmanagerSpec{Replicas: ptr.To(int32(2))},
+			},
+			&monitoringv1.ServiceMonitor{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "app"},
+				Spec: monitoringv1.ServiceMonitorSpec{
+					Selector:  metav1.LabelSelector{MatchLabels: labels},
+					Endpoints: []monitoringv1.Endpoint{{Port: "metrics"}},
+				},
+			},
+			&monitoringv1alpha1.ScrapeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "static", Namespace: "monitoring"},
+			},
+		),
+		k8stesting.WithKubeReactor("*", "*", record),
+		k8stesting.WithMonitoringReactor("*", "*", record),
+		k8stesting.WithAPIExtensionsReactor("*", "*", record),
+	)
+	clientSets.DClient.(*dynamicfake.FakeDynamicClient).PrependReactor("*", "*", record)
+
+	// The objects fail the analysis, only the requests matter.
+	_ = verify.Run(context.Background(), clientSets, nil)
+
+	rules := builder.NewVerifier("monitoring", "poctl:latest", "0 * * * *").
+		WithServiceAccount().
+		WithClusterRole().
+		WithSecretsAccess().
+		Build().
+		ClusterRole.Rules
+
+	allowed := func(r request) bool {
+		return slices.ContainsFunc(rules, func(rule applyConfigRbacv1.PolicyRuleApplyConfiguration) bool {
+			return slices.Contains(rule.APIGroups, r.group) &&
+				(slices.Contains(rule.Resources, r.resource) || slices.Contains(rule.Resources, "*")) &&
+				slices.Contains(rule.Verbs, r.verb)
+		})
+	}
+
+	for _, want := range []string{"nodes", "endpointslices"} {
+		assert.True(t, slices.ContainsFunc(requests, func(r request) bool { return r.resource == want }), "verify didn't read %s", want)
+	}
+	for _, r := range requests {
+		assert.True(t, allowed(r), "ClusterRole doesn't grant %s on %s in group %q", r.verb, r.resource, r.group)
+	}
+}